@@ -100,6 +100,7 @@ func (a *Artifetcher) Fetch(url, manifest string) (string, error) {
 
 		}
 	} else {
+		a.FileSystem.RemoveAll(unarchivedPath)
 		return "", UnsupportedFormatError{}
 	}
 
@@ -112,7 +113,7 @@ func (a *Artifetcher) Fetch(url, manifest string) (string, error) {
 // Returns a string to the unzipped application path and an error.
 func (a *Artifetcher) FetchArtifactFromRequest(body io.Reader, contentType string) (string, string, error) {
 
-	file, err := a.FileSystem.TempFile("", "deployadactyl-")
+	file, err := a.FileSystem.TempFile("", "deployadactyl-artifact-")
 	if err != nil {
 		return "", "", CreateTempFileError{err}
 	}
@@ -125,7 +126,7 @@ func (a *Artifetcher) FetchArtifactFromRequest(body io.Reader, contentType strin
 		return "", "", WriteResponseError{err}
 	}
 
-	unarchivedPath, err := a.FileSystem.TempDir("", "deployadactyl-")
+	unarchivedPath, err := a.FileSystem.TempDir("", "deployadactyl-unarchived-")
 	if err != nil {
 		return "", "", CreateTempDirectoryError{err}
 	}
@@ -138,12 +139,18 @@ func (a *Artifetcher) FetchArtifactFromRequest(body io.Reader, contentType strin
 		}
 	} else if contentType == "application/x-tar" || contentType == "application/x-gzip" {
 		err = a.Extractor.Untar(file.Name(), unarchivedPath, "")
+		if err != nil {
+			a.FileSystem.RemoveAll(unarchivedPath)
+			return "", "", NonProcessError{err}
+		}
 	} else {
+		a.FileSystem.RemoveAll(unarchivedPath)
 		return "", "", UnsupportedFormatError{}
 	}
 
 	manifest, err := a.FileSystem.ReadFile(unarchivedPath + "/manifest.yml")
 	if err != nil {
+		a.FileSystem.RemoveAll(unarchivedPath)
 		return "", "", err
 	}
 
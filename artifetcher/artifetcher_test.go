@@ -171,9 +171,25 @@ applications:
 				path, manifest, err := artifetcher.FetchArtifactFromRequest(body, "application/x-tar")
 				Expect(err).ToNot(HaveOccurred())
 
-				Expect(path).To(ContainSubstring("deployadactyl-"))
+				Expect(path).To(ContainSubstring("deployadactyl-unarchived-"))
 				Expect(manifest).To(ContainSubstring(expectManifest))
 			})
+
+			Context("when extractor fails", func() {
+				It("removes the unarchived directory and returns an error", func() {
+					extractor.UntarCall.Returns.Error = errors.New("test extract fail")
+
+					body, err := os.Open("./fixtures/deployadactyl-fixture.tar")
+					Expect(err).ToNot(HaveOccurred())
+
+					path, _, err := artifetcher.FetchArtifactFromRequest(body, "application/x-tar")
+					Expect(err).To(MatchError(NonProcessError{errors.New("test extract fail")}))
+					Expect(path).To(BeEmpty())
+
+					exists, _ := af.DirExists(extractor.UntarCall.Received.Destination)
+					Expect(exists).To(BeFalse())
+				})
+			})
 		})
 	})
 
@@ -0,0 +1,90 @@
+// Package cloudcontroller reads resources of the Cloud Controller V3 API through the cf curl of a courier.
+package cloudcontroller
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+)
+
+// Resource holds the fields of the Cloud Controller resources that are read. Each kind of resource
+// only sets some of them.
+type Resource struct {
+	GUID       string    `json:"guid"`
+	Name       string    `json:"name"`
+	CreatedAt  time.Time `json:"created_at"`
+	Host       string    `json:"host"`
+	Path       string    `json:"path"`
+	URL        string    `json:"url"`
+	Instances  uint64    `json:"instances"`
+	MemoryInMB uint64    `json:"memory_in_mb"`
+
+	Relationships struct {
+		Quota  Relationship `json:"quota"`
+		Space  Relationship `json:"space"`
+		Domain Relationship `json:"domain"`
+	} `json:"relationships"`
+
+	// Destinations are the applications a route is mapped to.
+	Destinations []struct {
+		App struct {
+			GUID string `json:"guid"`
+		} `json:"app"`
+	} `json:"destinations"`
+}
+
+// Relationship is a to-one relationship of a resource. Its data is null when there is none.
+type Relationship struct {
+	Data *struct {
+		GUID string `json:"guid"`
+	} `json:"data"`
+}
+
+// GUID returns the GUID of the related resource, or an empty string when there is none.
+func (r Relationship) GUID() string {
+	if r.Data == nil {
+		return ""
+	}
+	return r.Data.GUID
+}
+
+// List is a page of resources.
+type List struct {
+	Resources []Resource `json:"resources"`
+}
+
+// Get gets a path of the Cloud Controller API, such as /v3/spaces?names=dev, and decodes the response
+// into v. The first error the API responds with is returned as an error.
+func Get(courier I.Courier, path string, v interface{}) error {
+	body, err := courier.Curl(path)
+	if err != nil {
+		return err
+	}
+
+	var response struct {
+		Errors []struct {
+			Detail string `json:"detail"`
+		} `json:"errors"`
+	}
+	err = json.Unmarshal(body, &response)
+	if err != nil {
+		return err
+	}
+	if len(response.Errors) > 0 {
+		return errors.New(response.Errors[0].Detail)
+	}
+
+	return json.Unmarshal(body, v)
+}
+
+// Find returns the first resource of a list, or nil when the list is empty.
+func Find(courier I.Courier, path string) (*Resource, error) {
+	var list List
+	err := Get(courier, path, &list)
+	if err != nil || len(list.Resources) == 0 {
+		return nil, err
+	}
+	return &list.Resources[0], nil
+}
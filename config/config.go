@@ -9,6 +9,7 @@ import (
 
 	"github.com/cloudfoundry-incubator/candiedyaml"
 	"github.com/compozed/deployadactyl/controller/deployer/error_finder"
+	"github.com/compozed/deployadactyl/deploywindow"
 	"github.com/compozed/deployadactyl/geterrors"
 	"github.com/compozed/deployadactyl/interfaces"
 	s "github.com/compozed/deployadactyl/structs"
@@ -23,11 +24,33 @@ type Config struct {
 	Environments  map[string]s.Environment
 	Port          int
 	ErrorMatchers []interfaces.ErrorMatcher
+	Timeouts      s.Timeouts
+
+	// SecretsDirectory holds a file for every secret the credentials of user-provided services can reference.
+	SecretsDirectory string
+
+	// ScheduleFile keeps the scheduled deployments across restarts.
+	ScheduleFile string
+
+	// HistoryFile keeps the deployments that can be retried and promoted across restarts.
+	HistoryFile string
+
+	// TempFiles bounds the temporary files requests leave on the local disk.
+	TempFiles s.TempFiles
+
+	// Courier chooses how Cloud Foundry is driven: the cf CLI, the default, or the Cloud Controller API.
+	Courier string
 }
 
 type configYaml struct {
 	Environments       []s.Environment            `yaml:",flow"`
 	MatcherDescriptors []s.ErrorMatcherDescriptor `yaml:"error_matchers,flow"`
+	Timeouts           s.Timeouts
+	SecretsDirectory   string      `yaml:"secrets_directory"`
+	ScheduleFile       string      `yaml:"schedule_file"`
+	HistoryFile        string      `yaml:"history_file"`
+	TempFiles          s.TempFiles `yaml:"temp_files"`
+	Courier            string
 }
 
 type foundationYaml struct {
@@ -53,15 +76,25 @@ func Custom(getenv func(string) string, configPath string) (Config, error) {
 		return Config{}, err
 	}
 
-	errormatchers := getErrorMatchersFromConfig(foundationConfig)
+	errormatchers, err := getErrorMatchersFromConfig(foundationConfig)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config, err := createConfig(getenv, environments, errormatchers, foundationConfig.Timeouts)
 	if err != nil {
 		return Config{}, err
 	}
 
-	return createConfig(getenv, environments, errormatchers)
+	config.SecretsDirectory = foundationConfig.SecretsDirectory
+	config.ScheduleFile = foundationConfig.ScheduleFile
+	config.HistoryFile = foundationConfig.HistoryFile
+	config.TempFiles = foundationConfig.TempFiles
+	config.Courier = foundationConfig.Courier
+	return config, nil
 }
 
-func createConfig(getenv func(string) string, environments map[string]s.Environment, errormatchers []interfaces.ErrorMatcher) (Config, error) {
+func createConfig(getenv func(string) string, environments map[string]s.Environment, errormatchers []interfaces.ErrorMatcher, timeouts s.Timeouts) (Config, error) {
 	getter := geterrors.WrapFunc(getenv)
 
 	username := getter.Get("CF_USERNAME")
@@ -82,6 +115,7 @@ func createConfig(getenv func(string) string, environments map[string]s.Environm
 		Port:          port,
 		Environments:  environments,
 		ErrorMatchers: errormatchers,
+		Timeouts:      timeouts,
 	}
 	return config, nil
 }
@@ -100,7 +134,7 @@ func getPortFromEnv(getenv func(string) string) (int, error) {
 	return cfgPort, nil
 }
 
-func getErrorMatchersFromConfig(foundationConfig configYaml) []interfaces.ErrorMatcher {
+func getErrorMatchersFromConfig(foundationConfig configYaml) ([]interfaces.ErrorMatcher, error) {
 
 	matchers := make([]interfaces.ErrorMatcher, 0, 0)
 
@@ -108,12 +142,13 @@ func getErrorMatchersFromConfig(foundationConfig configYaml) []interfaces.ErrorM
 		factory := error_finder.ErrorMatcherFactory{}
 		for _, descriptor := range foundationConfig.MatcherDescriptors {
 			matcher, err := factory.CreateErrorMatcher(descriptor)
-			if err == nil {
-				matchers = append(matchers, matcher)
+			if err != nil {
+				return nil, InvalidErrorMatcherError{descriptor.Pattern, err}
 			}
+			matchers = append(matchers, matcher)
 		}
 	}
-	return matchers
+	return matchers, nil
 }
 
 func getEnvironmentsFromConfig(foundationConfig configYaml) (map[string]s.Environment, error) {
@@ -122,12 +157,113 @@ func getEnvironmentsFromConfig(foundationConfig configYaml) (map[string]s.Enviro
 		return nil, EnvironmentsNotSpecifiedError{}
 	}
 
+	if err := checkTimeouts(foundationConfig.Timeouts); err != nil {
+		return nil, err
+	}
+
+	if err := checkTempFiles(foundationConfig.TempFiles); err != nil {
+		return nil, err
+	}
+
+	if !s.IsCourier(foundationConfig.Courier) {
+		return nil, UnknownCourierError{foundationConfig.Courier}
+	}
+
 	environments := map[string]s.Environment{}
 	for _, environment := range foundationConfig.Environments {
-		if environment.Name == "" || environment.Foundations == nil || len(environment.Foundations) == 0 {
+		if environment.Name == "" || len(environment.FoundationDefinitions) == 0 {
 			return nil, MissingParameterError{}
 		}
 
+		environment.Foundations = make([]string, 0, len(environment.FoundationDefinitions))
+		for _, foundation := range environment.FoundationDefinitions {
+			if foundation.APIURL == "" {
+				return nil, MissingParameterError{}
+			}
+			environment.Foundations = append(environment.Foundations, foundation.APIURL)
+		}
+
+		for _, window := range environment.DeployWindows {
+			if _, err := deploywindow.ParseSchedule(window.Cron, window.Timezone); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, freeze := range environment.Freezes {
+			if _, err := deploywindow.ParseFreeze(freeze); err != nil {
+				return nil, err
+			}
+		}
+
+		if !s.IsStrategy(environment.Strategy) {
+			return nil, InvalidStrategyError{environment.Name, environment.Strategy}
+		}
+
+		if _, err := environment.Canary.GetBakeTime(); err != nil {
+			return nil, InvalidDurationError{"bake_time", err}
+		}
+
+		if interval, err := environment.Canary.GetHealthCheckInterval(); err != nil {
+			return nil, InvalidDurationError{"health_check_interval", err}
+		} else if interval <= 0 {
+			return nil, InvalidHealthCheckIntervalError{environment.Name, environment.Canary.HealthCheckInterval}
+		}
+
+		if _, err := environment.Rolling.GetPause(); err != nil {
+			return nil, InvalidDurationError{"pause", err}
+		}
+
+		if rollback := environment.Rolling.GetRollback(); rollback != s.RollbackAll && rollback != s.RollbackFailedWave {
+			return nil, InvalidRollbackPolicyError{rollback}
+		}
+
+		if !s.IsSuccessPolicy(environment.SuccessPolicy) {
+			return nil, InvalidSuccessPolicyError{environment.Name, environment.SuccessPolicy}
+		}
+
+		if environment.SuccessPolicy == s.SuccessPolicyMinSuccess && environment.MinSuccess < 1 {
+			return nil, InvalidMinSuccessError{environment.Name, environment.MinSuccess}
+		}
+
+		if environment.KeepPrevious < 0 {
+			return nil, InvalidKeepPreviousError{environment.Name, environment.KeepPrevious}
+		}
+
+		for _, hook := range environment.Hooks {
+			if reason := checkHook(hook); reason != "" {
+				return nil, InvalidHookError{environment.Name, hook.Name, reason}
+			}
+		}
+
+		for _, smokeTest := range environment.SmokeTests {
+			if reason := smokeTest.Check(); reason != "" {
+				return nil, InvalidSmokeTestError{environment.Name, smokeTest.Name, reason}
+			}
+		}
+
+		for _, service := range environment.UserProvidedServices {
+			if reason := service.Check(); reason != "" {
+				return nil, InvalidUserProvidedServiceError{environment.Name, service.Name, reason}
+			}
+		}
+
+		if reason := checkBake(environment.Bake); reason != "" {
+			return nil, InvalidBakeError{environment.Name, reason}
+		}
+
+		if reason := checkJanitor(environment.Janitor); reason != "" {
+			return nil, InvalidJanitorError{environment.Name, reason}
+		}
+
+		if len(environment.Timeouts.Commands) != 0 {
+			return nil, EnvironmentCommandTimeoutsError{environment.Name}
+		}
+
+		environment.Timeouts = foundationConfig.Timeouts.Merge(environment.Timeouts)
+		if err := checkTimeouts(environment.Timeouts); err != nil {
+			return nil, err
+		}
+
 		if environment.Instances < 1 {
 			environment.Instances = 1
 		}
@@ -138,6 +274,98 @@ func getEnvironmentsFromConfig(foundationConfig configYaml) (map[string]s.Enviro
 	return environments, nil
 }
 
+// checkHook returns why the hook is invalid, or an empty string when it is valid.
+func checkHook(hook s.Hook) string {
+	kinds := 0
+	for _, set := range []bool{hook.Task != "", hook.URL != "", len(hook.Command) != 0} {
+		if set {
+			kinds++
+		}
+	}
+
+	switch {
+	case hook.Name == "":
+		return "missing name"
+	case !s.IsHookPoint(hook.When):
+		return fmt.Sprintf("unknown hook point %q", hook.When)
+	case kinds != 1:
+		return "exactly one of task, url or command is required"
+	case hook.Task != "" && hook.When == s.HookBeforePush:
+		return "tasks cannot run before the push"
+	}
+
+	if _, err := hook.GetTimeout(); err != nil {
+		return fmt.Sprintf("invalid timeout: %s", err)
+	}
+	return ""
+}
+
+// checkBake returns why the bake is invalid, or an empty string when it is valid.
+func checkBake(bake s.Bake) string {
+	if _, err := bake.GetDuration(); err != nil {
+		return fmt.Sprintf("invalid duration: %s", err)
+	}
+	if interval, err := bake.GetInterval(); err != nil || interval <= 0 {
+		return fmt.Sprintf("invalid interval %q", bake.Interval)
+	}
+	if bake.MaxCrashes < 0 || bake.MaxRestarts < 0 {
+		return "max_crashes and max_restarts cannot be negative"
+	}
+	return ""
+}
+
+// checkJanitor returns why the janitor is invalid, or an empty string when it is valid.
+func checkJanitor(janitor s.Janitor) string {
+	if interval, err := janitor.GetInterval(); err != nil || interval < 0 {
+		return fmt.Sprintf("invalid interval %q", janitor.Interval)
+	}
+	if age, err := janitor.GetMinimumAge(); err != nil || age < 0 {
+		return fmt.Sprintf("invalid minimum_age %q", janitor.MinimumAge)
+	}
+	for _, space := range janitor.Spaces {
+		if space.Org == "" || space.Space == "" {
+			return "spaces need an org and a space"
+		}
+	}
+	return ""
+}
+
+func checkTempFiles(tempFiles s.TempFiles) error {
+	if age, err := tempFiles.GetMaxAge(); err != nil || age <= 0 {
+		return InvalidTempFilesError{fmt.Sprintf("invalid max_age %q", tempFiles.MaxAge)}
+	}
+	if interval, err := tempFiles.GetSweepInterval(); err != nil || interval <= 0 {
+		return InvalidTempFilesError{fmt.Sprintf("invalid sweep_interval %q", tempFiles.SweepInterval)}
+	}
+	if _, err := tempFiles.GetMinFreeSpace(); err != nil {
+		return InvalidTempFilesError{fmt.Sprintf("invalid min_free_space: %s", err)}
+	}
+	return nil
+}
+
+func checkTimeouts(timeouts s.Timeouts) error {
+	for subcommand := range timeouts.Commands {
+		if _, err := timeouts.GetCommand(subcommand); err != nil {
+			return InvalidDurationError{"timeout for command " + subcommand, err}
+		}
+	}
+
+	for phase := range timeouts.Phases {
+		if !s.IsPhase(phase) {
+			return InvalidPhaseError{phase}
+		}
+		if _, err := timeouts.GetPhase(phase); err != nil {
+			return InvalidDurationError{"timeout for phase " + phase, err}
+		}
+	}
+
+	if _, err := timeouts.GetRequest(); err != nil {
+		return InvalidDurationError{"request timeout", err}
+	}
+
+	return nil
+}
+
 func parseConfig(configPath string) (configYaml, error) {
 	file, err := ioutil.ReadFile(configPath)
 	if err != nil {
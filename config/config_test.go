@@ -3,11 +3,13 @@ package config_test
 import (
 	"io/ioutil"
 	"os"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
 	. "github.com/compozed/deployadactyl/config"
+	"github.com/compozed/deployadactyl/deploywindow"
 	S "github.com/compozed/deployadactyl/structs"
 
 	"github.com/compozed/deployadactyl/mocks"
@@ -95,16 +97,24 @@ var _ = Describe("Config", func() {
 
 		envMap = map[string]S.Environment{
 			"test": {
-				Name:         "Test",
-				Foundations:  []string{"api1.example.com", "api2.example.com"},
+				Name:        "Test",
+				Foundations: []string{"api1.example.com", "api2.example.com"},
+				FoundationDefinitions: []S.Foundation{
+					{APIURL: "api1.example.com"},
+					{APIURL: "api2.example.com"},
+				},
 				Domain:       "test.example.com",
 				SkipSSL:      true,
 				Instances:    3,
 				CustomParams: testCustomParams,
 			},
 			"prod": {
-				Name:         "Prod",
-				Foundations:  []string{"api3.example.com", "api4.example.com"},
+				Name:        "Prod",
+				Foundations: []string{"api3.example.com", "api4.example.com"},
+				FoundationDefinitions: []S.Foundation{
+					{APIURL: "api3.example.com"},
+					{APIURL: "api4.example.com"},
+				},
 				Domain:       "example.com",
 				SkipSSL:      false,
 				Instances:    1,
@@ -160,6 +170,265 @@ var _ = Describe("Config", func() {
 		})
 	})
 
+	Context("when foundations are given as mappings", func() {
+		It("returns the foundation definitions and their API URLs", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: production
+  domain: example.com
+  skip_ssl: true
+  foundations:
+  - https://api.plain.example.com
+  - name: east
+    api_url: https://api.east.example.com
+    apps_domain: apps.east.example.com
+    skip_ssl: false
+    credentials:
+      username: east-user
+      password: east-password
+    orgs:
+      my-org: my-east-org
+    spaces:
+      my-space: my-east-space
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			environment := config.Environments["production"]
+			Expect(environment.Foundations).To(Equal([]string{"https://api.plain.example.com", "https://api.east.example.com"}))
+
+			plain := environment.GetFoundation("https://api.plain.example.com")
+			Expect(plain.GetName()).To(Equal("https://api.plain.example.com"))
+			Expect(plain.GetSkipSSL(environment.SkipSSL)).To(BeTrue())
+
+			east := environment.GetFoundation("https://api.east.example.com")
+			Expect(east.GetName()).To(Equal("east"))
+			Expect(east.AppsDomain).To(Equal("apps.east.example.com"))
+			Expect(east.GetSkipSSL(environment.SkipSSL)).To(BeFalse())
+			Expect(*east.Credentials).To(Equal(S.FoundationCredentials{Username: "east-user", Password: "east-password"}))
+			Expect(east.GetOrg("my-org")).To(Equal("my-east-org"))
+			Expect(east.GetOrg("other-org")).To(Equal("other-org"))
+			Expect(east.GetSpace("my-space")).To(Equal("my-east-space"))
+		})
+
+		It("returns an error when a foundation has no api_url", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - name: east
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+
+			Expect(err).To(MatchError(MissingParameterError{}))
+		})
+	})
+
+	Context("when deploy windows and freezes are given", func() {
+		It("returns them on the environment", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - https://api.example.com
+  deploy_windows:
+  - cron: "* 9-16 * * 1-5"
+    timezone: America/Chicago
+  freezes:
+  - start: 2026-12-20
+    end: 2026-12-31
+    reason: holiday freeze
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			environment := config.Environments["production"]
+			Expect(environment.DeployWindows).To(Equal([]S.DeployWindow{{Cron: "* 9-16 * * 1-5", Timezone: "America/Chicago"}}))
+			Expect(environment.Freezes).To(Equal([]S.Freeze{{Start: "2026-12-20", End: "2026-12-31", Reason: "holiday freeze"}}))
+		})
+
+		It("returns an error when a deploy window is invalid", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - https://api.example.com
+  deploy_windows:
+  - cron: weekdays
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+
+			Expect(err).To(BeAssignableToTypeOf(deploywindow.InvalidScheduleError{}))
+		})
+	})
+
+	Context("when a strategy is given", func() {
+		It("returns the strategy and canary settings on the environment", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - https://api.example.com
+  strategy: canary
+  canary:
+    foundation: east
+    bake_time: 10m
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			environment := config.Environments["production"]
+			Expect(environment.Strategy).To(Equal(S.CanaryStrategy))
+			Expect(environment.Canary).To(Equal(S.Canary{Foundation: "east", BakeTime: "10m"}))
+		})
+
+		It("returns an error when the strategy is unknown", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - https://api.example.com
+  strategy: yolo
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+
+			Expect(err).To(MatchError(InvalidStrategyError{"production", "yolo"}))
+		})
+
+		It("combines the strategy with a cf push strategy", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - https://api.example.com
+  strategy: canary
+  cf_push_strategy: in-place
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.Environments["production"].Strategy).To(Equal("canary"))
+			Expect(config.Environments["production"].PushStrategy).To(Equal("in-place"))
+		})
+
+		It("returns an error when the canary health check interval is not positive", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - https://api.example.com
+  strategy: canary
+  canary:
+    health_check_interval: 0s
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+
+			Expect(err).To(MatchError(InvalidHealthCheckIntervalError{"production", "0s"}))
+		})
+	})
+
+	Context("when a success policy is given", func() {
+		It("returns the success policy on the environment", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - https://api1.example.com
+  - https://api2.example.com
+  - https://api3.example.com
+  success_policy: min_success
+  min_success: 2
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			environment := config.Environments["production"]
+			Expect(environment.SuccessPolicy).To(Equal(S.SuccessPolicyMinSuccess))
+			Expect(environment.RequiredSuccesses()).To(Equal(2))
+		})
+
+		It("returns an error when the success policy is unknown", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - https://api.example.com
+  success_policy: most
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+
+			Expect(err).To(MatchError(InvalidSuccessPolicyError{"production", "most"}))
+		})
+
+		It("returns an error when min_success is missing", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - https://api.example.com
+  success_policy: min_success
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+
+			Expect(err).To(MatchError(InvalidMinSuccessError{"production", 0}))
+		})
+	})
+
 	Context("when custom params are empty", func() {
 		It("should return a valid config with custom params nil", func() {
 			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
@@ -248,6 +517,444 @@ environments:
 		})
 	})
 
+	Context("when timeouts are present", func() {
+		It("merges the timeouts of each environment over the top-level timeouts", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+timeouts:
+  commands:
+    default: 5m
+    push: 15m
+  phases:
+    default: 20m
+  request: 1h
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  timeouts:
+    phases:
+      undo: 5m
+    request: 2h
+- name: test
+  foundations:
+  - api1.example.com
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.Timeouts.GetCommand("push")).To(Equal(15 * time.Minute))
+			Expect(config.Timeouts.GetCommand("login")).To(Equal(5 * time.Minute))
+
+			production := config.Environments["production"].Timeouts
+			Expect(production.GetPhase("undo")).To(Equal(5 * time.Minute))
+			Expect(production.GetPhase("execute")).To(Equal(20 * time.Minute))
+			Expect(production.GetRequest()).To(Equal(2 * time.Hour))
+			Expect(config.Environments["test"].Timeouts.GetRequest()).To(Equal(time.Hour))
+		})
+
+		It("returns an error when an environment sets command timeouts", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  timeouts:
+    commands:
+      push: 15m
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+
+			Expect(err).To(MatchError(EnvironmentCommandTimeoutsError{"production"}))
+		})
+
+		It("returns an error for an unknown phase", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+timeouts:
+  phases:
+    deploy: 10m
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+
+			Expect(err).To(MatchError(InvalidPhaseError{"deploy"}))
+		})
+	})
+
+	Context("when hooks are present", func() {
+		It("returns the hooks of the environment", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  hooks:
+  - name: migrate
+    when: before_cutover
+    task: rake db:migrate
+  - name: notify
+    when: after_success
+    command: [./notify.sh, --channel, deploys]
+    timeout: 30s
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.Environments["production"].Hooks).To(Equal([]S.Hook{
+				{Name: "migrate", When: S.HookBeforeCutover, Task: "rake db:migrate"},
+				{Name: "notify", When: S.HookAfterSuccess, Command: []string{"./notify.sh", "--channel", "deploys"}, Timeout: "30s"},
+			}))
+		})
+
+		It("returns an error for a task that runs before the push", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  hooks:
+  - name: migrate
+    when: before_push
+    task: rake db:migrate
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+
+			Expect(err).To(MatchError(InvalidHookError{"production", "migrate", "tasks cannot run before the push"}))
+		})
+	})
+
+	Context("when smoke tests are present", func() {
+		It("returns the smoke tests of the environment", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  smoke_tests:
+  - name: login
+    method: POST
+    path: /login
+    headers:
+      Content-Type: application/json
+    body: '{"user": "smoke"}'
+    expected_status: [200, 201]
+    assertions:
+    - json_path: $.token
+    - matches: welcome
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.Environments["production"].SmokeTests).To(Equal([]S.SmokeTest{
+				{
+					Name:           "login",
+					Method:         "POST",
+					Path:           "/login",
+					Headers:        map[string]string{"Content-Type": "application/json"},
+					Body:           `{"user": "smoke"}`,
+					ExpectedStatus: []int{200, 201},
+					Assertions: []S.SmokeTestAssertion{
+						{JSONPath: "$.token"},
+						{Matches: "welcome"},
+					},
+				},
+			}))
+		})
+
+		It("returns an error for a smoke test with an invalid regular expression", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  smoke_tests:
+  - name: home
+    path: /
+    assertions:
+    - matches: "welcome("
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+
+			Expect(err).To(BeAssignableToTypeOf(InvalidSmokeTestError{}))
+			Expect(err.(InvalidSmokeTestError).SmokeTest).To(Equal("home"))
+		})
+	})
+
+	Context("when user-provided services are present", func() {
+		It("returns the user-provided services of the environment and the secrets directory", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+secrets_directory: /var/run/secrets/deployadactyl
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  user_provided_services:
+  - name: payments-api
+    credentials:
+      url: https://payments.example.com
+      token: ((payments-token))
+  - name: splunk
+    syslog_drain_url: syslog-tls://logs.example.com:6514
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.SecretsDirectory).To(Equal("/var/run/secrets/deployadactyl"))
+
+			services := config.Environments["production"].UserProvidedServices
+			Expect(services).To(HaveLen(2))
+			Expect(services[0].Name).To(Equal("payments-api"))
+			Expect(services[0].GetCredentials()).To(MatchJSON(`{"url": "https://payments.example.com", "token": "((payments-token))"}`))
+			Expect(services[1]).To(Equal(S.UserProvidedService{Name: "splunk", SyslogDrainURL: "syslog-tls://logs.example.com:6514"}))
+		})
+
+		It("returns an error for a user-provided service without a name", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  user_provided_services:
+  - route_service_url: https://auth.example.com
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+
+			Expect(err).To(MatchError(InvalidUserProvidedServiceError{"production", "", "missing name"}))
+		})
+	})
+
+	Context("when a bake is present", func() {
+		It("returns the bake of the environment", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  bake:
+    duration: 10m
+    interval: 15s
+    max_crashes: 1
+    max_restarts: 2
+    health_check_endpoint: /health
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.Environments["production"].Bake).To(Equal(S.Bake{
+				Duration:            "10m",
+				Interval:            "15s",
+				MaxCrashes:          1,
+				MaxRestarts:         2,
+				HealthCheckEndpoint: "/health",
+			}))
+		})
+
+		It("returns an error for an invalid interval", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  bake:
+    duration: 10m
+    interval: 0s
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+
+			Expect(err).To(MatchError(InvalidBakeError{"production", `invalid interval "0s"`}))
+		})
+	})
+
+	Context("when temp files are configured", func() {
+		It("reads the temp files", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+temp_files:
+  max_age: 12h
+  sweep_interval: 30m
+  min_free_space: 2G
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.TempFiles).To(Equal(S.TempFiles{MaxAge: "12h", SweepInterval: "30m", MinFreeSpace: "2G"}))
+			Expect(config.TempFiles.GetMinFreeSpace()).To(Equal(uint64(2 << 30)))
+		})
+
+		It("returns an error for an invalid minimum free space", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+temp_files:
+  min_free_space: 2
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+
+			Expect(err).To(MatchError(InvalidTempFilesError{"invalid min_free_space: size 2 has no unit"}))
+		})
+	})
+
+	Context("when a courier is configured", func() {
+		It("reads the courier", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+courier: api
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.Courier).To(Equal(S.APICourier))
+		})
+
+		It("returns an error for an unknown courier", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+courier: grpc
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+
+			Expect(err).To(MatchError(UnknownCourierError{"grpc"}))
+		})
+	})
+
+	Context("when a janitor is configured", func() {
+		It("reads the janitor of the environment", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  janitor:
+    interval: 6h
+    minimum_age: 2h
+    spaces:
+    - org: payments
+      space: prod
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.Environments["production"].Janitor).To(Equal(S.Janitor{
+				Interval:   "6h",
+				MinimumAge: "2h",
+				Spaces:     []S.JanitorSpace{{Org: "payments", Space: "prod"}},
+			}))
+		})
+
+		It("returns an error for a space without an org", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  janitor:
+    spaces:
+    - space: prod
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+
+			Expect(err).To(MatchError(InvalidJanitorError{"production", "spaces need an org and a space"}))
+		})
+	})
+
 	Context("when no error matchers are present", func() {
 		It("has zero error matchers", func() {
 			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
@@ -301,4 +1008,27 @@ error_matchers:
 			Expect(config.ErrorMatchers[1].Descriptor()).To(Equal("another matcher: cd: 34: "))
 		})
 	})
+
+	Context("when an error matcher pattern does not compile", func() {
+		It("returns an error instead of dropping the matcher", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			testConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+error_matchers:
+- description: a matcher
+  pattern: a((
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+
+			Expect(err).To(BeAssignableToTypeOf(InvalidErrorMatcherError{}))
+			Expect(err.Error()).To(ContainSubstring(`invalid error matcher "a(("`))
+		})
+	})
 })
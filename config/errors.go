@@ -21,3 +21,173 @@ type ParseYamlError struct {
 func (e ParseYamlError) Error() string {
 	return fmt.Sprintf("cannot parse yaml file: %s", e.Err)
 }
+
+type InvalidErrorMatcherError struct {
+	Pattern string
+	Err     error
+}
+
+func (e InvalidErrorMatcherError) Error() string {
+	return fmt.Sprintf("invalid error matcher %q: %s", e.Pattern, e.Err)
+}
+
+type InvalidStrategyError struct {
+	Environment string
+	Strategy    string
+}
+
+func (e InvalidStrategyError) Error() string {
+	return fmt.Sprintf("unknown deployment strategy for environment %s: %s", e.Environment, e.Strategy)
+}
+
+type InvalidDurationError struct {
+	Key string
+	Err error
+}
+
+func (e InvalidDurationError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Key, e.Err)
+}
+
+type InvalidRollbackPolicyError struct {
+	Rollback string
+}
+
+func (e InvalidRollbackPolicyError) Error() string {
+	return fmt.Sprintf("unknown rollback policy: %s", e.Rollback)
+}
+
+type InvalidSuccessPolicyError struct {
+	Environment   string
+	SuccessPolicy string
+}
+
+func (e InvalidSuccessPolicyError) Error() string {
+	return fmt.Sprintf("unknown success policy for environment %s: %s", e.Environment, e.SuccessPolicy)
+}
+
+type InvalidMinSuccessError struct {
+	Environment string
+	MinSuccess  int
+}
+
+func (e InvalidMinSuccessError) Error() string {
+	return fmt.Sprintf("min_success for environment %s must be at least 1: %d", e.Environment, e.MinSuccess)
+}
+
+type InvalidKeepPreviousError struct {
+	Environment  string
+	KeepPrevious int
+}
+
+func (e InvalidKeepPreviousError) Error() string {
+	return fmt.Sprintf("keep_previous for environment %s cannot be negative: %d", e.Environment, e.KeepPrevious)
+}
+
+type InvalidHookError struct {
+	Environment string
+	Hook        string
+	Reason      string
+}
+
+func (e InvalidHookError) Error() string {
+	return fmt.Sprintf("invalid hook %s for environment %s: %s", e.Hook, e.Environment, e.Reason)
+}
+
+type InvalidSmokeTestError struct {
+	Environment string
+	SmokeTest   string
+	Reason      string
+}
+
+func (e InvalidSmokeTestError) Error() string {
+	return fmt.Sprintf("invalid smoke test %s for environment %s: %s", e.SmokeTest, e.Environment, e.Reason)
+}
+
+type InvalidUserProvidedServiceError struct {
+	Environment         string
+	UserProvidedService string
+	Reason              string
+}
+
+func (e InvalidUserProvidedServiceError) Error() string {
+	return fmt.Sprintf("invalid user-provided service %s for environment %s: %s", e.UserProvidedService, e.Environment, e.Reason)
+}
+
+type InvalidHealthCheckIntervalError struct {
+	Environment string
+	Interval    string
+}
+
+func (e InvalidHealthCheckIntervalError) Error() string {
+	return fmt.Sprintf("health_check_interval %s of environment %s must be positive", e.Interval, e.Environment)
+}
+
+type InvalidBakeError struct {
+	Environment string
+	Reason      string
+}
+
+func (e InvalidBakeError) Error() string {
+	return fmt.Sprintf("invalid bake for environment %s: %s", e.Environment, e.Reason)
+}
+
+type InvalidJanitorError struct {
+	Environment string
+	Reason      string
+}
+
+func (e InvalidJanitorError) Error() string {
+	return fmt.Sprintf("invalid janitor for environment %s: %s", e.Environment, e.Reason)
+}
+
+type InvalidTempFilesError struct {
+	Reason string
+}
+
+func (e InvalidTempFilesError) Error() string {
+	return fmt.Sprintf("invalid temp_files: %s", e.Reason)
+}
+
+type UnknownCourierError struct {
+	Courier string
+}
+
+func (e UnknownCourierError) Error() string {
+	return fmt.Sprintf("unknown courier %s: must be cli or api", e.Courier)
+}
+
+type InvalidPhaseError struct {
+	Phase string
+}
+
+func (e InvalidPhaseError) Error() string {
+	return fmt.Sprintf("unknown phase in timeouts: %s", e.Phase)
+}
+
+type EnvironmentCommandTimeoutsError struct {
+	Environment string
+}
+
+func (e EnvironmentCommandTimeoutsError) Error() string {
+	return fmt.Sprintf("command timeouts can only be set at the top level of the config, not for environment %s", e.Environment)
+}
+
+// ValidationError is a single problem found in a config file. Line is zero when the
+// problem cannot be attributed to a location in the file.
+type ValidationError struct {
+	Line    int
+	Field   string
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	switch {
+	case e.Line > 0 && e.Field != "":
+		return fmt.Sprintf("line %d: %s: %s", e.Line, e.Field, e.Message)
+	case e.Field != "":
+		return fmt.Sprintf("%s: %s", e.Field, e.Message)
+	default:
+		return e.Message
+	}
+}
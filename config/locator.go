@@ -0,0 +1,106 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// yamlLocator maps a field path, such as environments[1].foundations[0], to the
+// line it is declared on in a block style yaml document.
+type yamlLocator map[string]int
+
+type locatorEntry struct {
+	column int
+	path   string
+	isKey  bool
+	open   bool
+	items  int
+}
+
+func newYamlLocator(data []byte) yamlLocator {
+	locator := yamlLocator{}
+	stack := []*locatorEntry{{column: -1, isKey: true, open: true}}
+
+	for i, line := range strings.Split(string(data), "\n") {
+		content := strings.TrimSpace(line)
+		if content == "" || content == "---" || strings.HasPrefix(content, "#") {
+			continue
+		}
+		column := len(line) - len(strings.TrimLeft(line, " "))
+
+		if content == "-" || strings.HasPrefix(content, "- ") {
+			for len(stack) > 1 {
+				top := stack[len(stack)-1]
+				if top.column < column || (top.column == column && top.isKey && top.open) {
+					break
+				}
+				stack = stack[:len(stack)-1]
+			}
+
+			parent := stack[len(stack)-1]
+			item := &locatorEntry{column: column, path: fmt.Sprintf("%s[%d]", parent.path, parent.items)}
+			parent.items++
+			locator[item.path] = i + 1
+			stack = append(stack, item)
+
+			content = strings.TrimSpace(strings.TrimPrefix(content, "-"))
+			column += 2
+			if content == "" {
+				continue
+			}
+		} else {
+			for len(stack) > 1 && stack[len(stack)-1].column >= column {
+				stack = stack[:len(stack)-1]
+			}
+		}
+
+		key, value, ok := splitYamlKey(content)
+		if !ok {
+			continue
+		}
+
+		parent := stack[len(stack)-1]
+		path := key
+		if parent.path != "" {
+			path = parent.path + "." + key
+		}
+		locator[path] = i + 1
+		stack = append(stack, &locatorEntry{column: column, path: path, isKey: true, open: value == ""})
+	}
+
+	return locator
+}
+
+// Line returns the line of the closest declared ancestor of path, or zero if
+// none of it can be found.
+func (l yamlLocator) Line(path string) int {
+	for path != "" {
+		if line, ok := l[path]; ok {
+			return line
+		}
+
+		cut := strings.LastIndexAny(path, ".[")
+		if cut < 0 {
+			return 0
+		}
+		path = path[:cut]
+	}
+	return 0
+}
+
+func splitYamlKey(content string) (string, string, bool) {
+	if strings.HasPrefix(content, "[") || strings.HasPrefix(content, "{") {
+		return "", "", false
+	}
+
+	var key, value string
+	if strings.HasSuffix(content, ":") {
+		key = strings.TrimSuffix(content, ":")
+	} else if i := strings.Index(content, ": "); i > 0 {
+		key, value = content[:i], strings.TrimSpace(content[i+2:])
+	} else {
+		return "", "", false
+	}
+
+	return strings.Trim(strings.TrimSpace(key), `"'`), value, true
+}
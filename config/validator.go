@@ -0,0 +1,643 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry-incubator/candiedyaml"
+	"github.com/compozed/deployadactyl/deploywindow"
+	s "github.com/compozed/deployadactyl/structs"
+)
+
+// Validate parses the config file at configPath and checks it against the config schema without
+// requiring any of the environment variables needed to start the server.
+//
+// Returns every problem found, ordered by the line it appears on.
+func Validate(configPath string) ([]ValidationError, error) {
+	data, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return ValidateYaml(data), nil
+}
+
+// ValidateYaml checks a config yaml for unknown keys, missing or duplicate environments,
+// malformed foundation URLs, negative instances and error matchers that do not compile.
+func ValidateYaml(data []byte) []ValidationError {
+	var document interface{}
+	if err := candiedyaml.Unmarshal(data, &document); err != nil {
+		return []ValidationError{{Message: ParseYamlError{err}.Error()}}
+	}
+
+	v := &validator{locator: newYamlLocator(data)}
+	v.checkKeys(document, reflect.TypeOf(configYaml{}), "")
+
+	root, _ := document.(map[interface{}]interface{})
+	v.checkEnvironments(root["environments"])
+	v.checkErrorMatchers(root["error_matchers"])
+	v.checkTimeouts(root["timeouts"], "timeouts", true)
+	v.checkTempFiles(root["temp_files"], "temp_files")
+
+	if courier, ok := root["courier"].(string); ok && !s.IsCourier(courier) {
+		v.add("courier", "unknown courier %q", courier)
+	}
+
+	if len(v.errors) == 0 {
+		if _, err := parseYamlFromBody(data); err != nil {
+			v.add("", err.Error())
+		}
+	}
+
+	sort.SliceStable(v.errors, func(i, j int) bool { return v.errors[i].Line < v.errors[j].Line })
+	return v.errors
+}
+
+type validator struct {
+	locator yamlLocator
+	errors  []ValidationError
+}
+
+func (v *validator) add(path, format string, args ...interface{}) {
+	v.errors = append(v.errors, ValidationError{
+		Line:    v.locator.Line(path),
+		Field:   path,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// checkKeys walks a decoded yaml document alongside the type it is unmarshaled into
+// and reports any mapping key that does not correspond to a field.
+func (v *validator) checkKeys(node interface{}, t reflect.Type, path string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		mapping, ok := node.(map[interface{}]interface{})
+		if !ok {
+			return
+		}
+
+		fields := yamlFields(t)
+		for key, value := range mapping {
+			name := fmt.Sprint(key)
+			fieldPath := joinPath(path, name)
+
+			field, ok := fields[name]
+			if !ok {
+				v.add(fieldPath, "unknown key %q", name)
+				continue
+			}
+			v.checkKeys(value, field.Type, fieldPath)
+		}
+	case reflect.Slice:
+		sequence, ok := node.([]interface{})
+		if !ok {
+			return
+		}
+
+		for i, value := range sequence {
+			v.checkKeys(value, t.Elem(), fmt.Sprintf("%s[%d]", path, i))
+		}
+	}
+}
+
+func (v *validator) checkEnvironments(node interface{}) {
+	environments, ok := node.([]interface{})
+	if !ok || len(environments) == 0 {
+		v.add("environments", EnvironmentsNotSpecifiedError{}.Error())
+		return
+	}
+
+	names := map[string]string{}
+	for i, node := range environments {
+		path := fmt.Sprintf("environments[%d]", i)
+
+		environment, ok := node.(map[interface{}]interface{})
+		if !ok {
+			v.add(path, "environment must be a mapping")
+			continue
+		}
+
+		name, _ := environment["name"].(string)
+		if name == "" {
+			v.add(path, "missing required key \"name\"")
+		} else if previous, ok := names[strings.ToLower(name)]; ok {
+			v.add(path+".name", "duplicate environment name %q: also defined at line %d", name, v.locator.Line(previous))
+		} else {
+			names[strings.ToLower(name)] = path + ".name"
+		}
+
+		foundations := v.checkFoundations(environment["foundations"], path+".foundations")
+
+		if instances, ok := toInt(environment["instances"]); ok && instances < 0 {
+			v.add(path+".instances", "instances cannot be negative: %d", instances)
+		}
+
+		if keep, ok := toInt(environment["keep_previous"]); ok && keep < 0 {
+			v.add(path+".keep_previous", "keep_previous cannot be negative: %d", keep)
+		}
+
+		if strategy, ok := environment["strategy"].(string); ok && !s.IsStrategy(strategy) {
+			v.add(path+".strategy", "unknown deployment strategy %q", strategy)
+		}
+		if strategy, _ := environment["strategy"].(string); strategy != "" {
+			if pushStrategy, _ := environment["cf_push_strategy"].(string); pushStrategy != "" {
+				v.add(path+".cf_push_strategy", "cf_push_strategy %q cannot be combined with strategy %q", pushStrategy, strategy)
+			}
+		}
+		v.checkSuccessPolicy(environment, path)
+		v.checkCanary(environment["canary"], path+".canary")
+		v.checkRolling(environment["rolling"], path+".rolling", foundations)
+		v.checkTimeouts(environment["timeouts"], path+".timeouts", false)
+		v.checkHooks(environment["hooks"], path+".hooks")
+		v.checkSmokeTests(environment["smoke_tests"], path+".smoke_tests")
+		v.checkUserProvidedServices(environment["user_provided_services"], path+".user_provided_services")
+		v.checkBake(environment["bake"], path+".bake")
+		v.checkJanitor(environment["janitor"], path+".janitor")
+
+		v.checkDeployWindows(environment["deploy_windows"], path+".deploy_windows")
+		v.checkFreezes(environment["freezes"], path+".freezes")
+	}
+}
+
+// checkFoundations returns the names and API URLs the foundations can be referred to by.
+func (v *validator) checkFoundations(node interface{}, path string) map[string]bool {
+	known := map[string]bool{}
+
+	foundations, ok := node.([]interface{})
+	if !ok || len(foundations) == 0 {
+		v.add(path, "missing required key \"foundations\"")
+		return known
+	}
+
+	names := map[string]string{}
+	for i, foundation := range foundations {
+		foundationPath := fmt.Sprintf("%s[%d]", path, i)
+		urlPath := foundationPath
+
+		var foundationURL, name string
+		switch definition := foundation.(type) {
+		case string:
+			foundationURL = definition
+		case map[interface{}]interface{}:
+			urlPath = foundationPath + ".api_url"
+			foundationURL, _ = definition["api_url"].(string)
+			name, _ = definition["name"].(string)
+
+			if foundationURL == "" {
+				v.add(foundationPath, "missing required key \"api_url\"")
+				continue
+			}
+		default:
+			v.add(foundationPath, "foundation must be a URL or a mapping")
+			continue
+		}
+
+		known[foundationURL] = true
+		if name != "" {
+			known[name] = true
+		}
+
+		if err := checkFoundationURL(foundationURL); err != nil {
+			v.add(urlPath, "malformed foundation URL %q: %s", foundationURL, err)
+		}
+
+		if name == "" {
+			continue
+		}
+		if previous, ok := names[name]; ok {
+			v.add(foundationPath+".name", "duplicate foundation name %q: also defined at line %d", name, v.locator.Line(previous))
+		} else {
+			names[name] = foundationPath + ".name"
+		}
+	}
+
+	return known
+}
+
+func (v *validator) checkSuccessPolicy(environment map[interface{}]interface{}, path string) {
+	policy, _ := environment["success_policy"].(string)
+	if !s.IsSuccessPolicy(policy) {
+		v.add(path+".success_policy", "unknown success policy %q", policy)
+	}
+
+	minSuccess, ok := toInt(environment["min_success"])
+	if ok && minSuccess < 1 {
+		v.add(path+".min_success", "min_success must be at least 1: %d", minSuccess)
+	} else if !ok && policy == s.SuccessPolicyMinSuccess {
+		v.add(path, "missing required key \"min_success\" for success policy %q", policy)
+	}
+}
+
+func (v *validator) checkCanary(node interface{}, path string) {
+	canary, _ := node.(map[interface{}]interface{})
+
+	for _, key := range []string{"bake_time", "health_check_interval"} {
+		value, ok := canary[key]
+		if !ok {
+			continue
+		}
+
+		duration, err := time.ParseDuration(fmt.Sprint(value))
+		if err != nil {
+			v.add(path+"."+key, "invalid duration %q: %s", fmt.Sprint(value), err)
+		} else if key == "health_check_interval" && duration <= 0 {
+			v.add(path+"."+key, "health_check_interval must be positive: %q", fmt.Sprint(value))
+		}
+	}
+}
+
+// checkTimeouts checks the durations of timeouts. Command timeouts are only allowed at the top level.
+func (v *validator) checkTimeouts(node interface{}, path string, topLevel bool) {
+	timeouts, _ := node.(map[interface{}]interface{})
+
+	if commands, ok := timeouts["commands"].(map[interface{}]interface{}); ok {
+		if !topLevel {
+			v.add(path+".commands", "command timeouts can only be set at the top level")
+		}
+		for subcommand, value := range commands {
+			v.checkDuration(value, fmt.Sprintf("%s.commands.%s", path, subcommand))
+		}
+	}
+
+	if phases, ok := timeouts["phases"].(map[interface{}]interface{}); ok {
+		for phase, value := range phases {
+			if !s.IsPhase(fmt.Sprint(phase)) {
+				v.add(fmt.Sprintf("%s.phases.%s", path, phase), "unknown phase %q", fmt.Sprint(phase))
+				continue
+			}
+			v.checkDuration(value, fmt.Sprintf("%s.phases.%s", path, phase))
+		}
+	}
+
+	if value, ok := timeouts["request"]; ok {
+		v.checkDuration(value, path+".request")
+	}
+}
+
+func (v *validator) checkHooks(node interface{}, path string) {
+	hooks, _ := node.([]interface{})
+
+	for i, node := range hooks {
+		hookPath := fmt.Sprintf("%s[%d]", path, i)
+
+		hook, ok := node.(map[interface{}]interface{})
+		if !ok {
+			v.add(hookPath, "hook must be a mapping")
+			continue
+		}
+
+		if name, _ := hook["name"].(string); name == "" {
+			v.add(hookPath, "missing required key \"name\"")
+		}
+
+		when, _ := hook["when"].(string)
+		if !s.IsHookPoint(when) {
+			v.add(hookPath+".when", "unknown hook point %q", when)
+		}
+
+		kinds := 0
+		for _, key := range []string{"task", "url", "command"} {
+			if _, ok := hook[key]; ok {
+				kinds++
+			}
+		}
+		if kinds != 1 {
+			v.add(hookPath, "exactly one of task, url or command is required")
+		}
+
+		if _, ok := hook["task"]; ok && when == s.HookBeforePush {
+			v.add(hookPath+".task", "tasks cannot run before the push")
+		}
+
+		if value, ok := hook["timeout"]; ok {
+			v.checkDuration(value, hookPath+".timeout")
+		}
+	}
+}
+
+func (v *validator) checkUserProvidedServices(node interface{}, path string) {
+	services, _ := node.([]interface{})
+
+	names := map[string]string{}
+	for i, node := range services {
+		servicePath := fmt.Sprintf("%s[%d]", path, i)
+
+		service, ok := node.(map[interface{}]interface{})
+		if !ok {
+			v.add(servicePath, "user-provided service must be a mapping")
+			continue
+		}
+
+		name, _ := service["name"].(string)
+		if name == "" {
+			v.add(servicePath, "missing required key \"name\"")
+		} else if previous, ok := names[name]; ok {
+			v.add(servicePath+".name", "duplicate user-provided service %q: also defined at line %d", name, v.locator.Line(previous))
+		} else {
+			names[name] = servicePath + ".name"
+		}
+
+		if credentials, ok := service["credentials"]; ok {
+			if _, ok := credentials.(map[interface{}]interface{}); !ok {
+				v.add(servicePath+".credentials", "credentials must be a mapping")
+			}
+		}
+	}
+}
+
+func (v *validator) checkSmokeTests(node interface{}, path string) {
+	smokeTests, _ := node.([]interface{})
+
+	for i, node := range smokeTests {
+		smokeTestPath := fmt.Sprintf("%s[%d]", path, i)
+
+		smokeTest, ok := node.(map[interface{}]interface{})
+		if !ok {
+			v.add(smokeTestPath, "smoke test must be a mapping")
+			continue
+		}
+
+		if name, _ := smokeTest["name"].(string); name == "" {
+			v.add(smokeTestPath, "missing required key \"name\"")
+		}
+
+		statuses, _ := smokeTest["expected_status"].([]interface{})
+		for j, value := range statuses {
+			if status, ok := toInt(value); !ok || status < 100 || status > 599 {
+				v.add(fmt.Sprintf("%s.expected_status[%d]", smokeTestPath, j), "invalid status code %v", value)
+			}
+		}
+
+		assertions, _ := smokeTest["assertions"].([]interface{})
+		for j, node := range assertions {
+			assertionPath := fmt.Sprintf("%s.assertions[%d]", smokeTestPath, j)
+
+			assertion, ok := node.(map[interface{}]interface{})
+			if !ok {
+				v.add(assertionPath, "assertion must be a mapping")
+				continue
+			}
+
+			jsonPath, _ := assertion["json_path"].(string)
+			matches, _ := assertion["matches"].(string)
+			if jsonPath == "" && matches == "" {
+				v.add(assertionPath, "one of json_path or matches is required")
+			}
+			if _, ok := assertion["equals"]; ok && jsonPath == "" {
+				v.add(assertionPath+".equals", "equals needs a json_path")
+			}
+			if _, err := regexp.Compile(matches); err != nil {
+				v.add(assertionPath+".matches", "invalid regular expression %q: %s", matches, err)
+			}
+		}
+	}
+}
+
+func (v *validator) checkBake(node interface{}, path string) {
+	bake, _ := node.(map[interface{}]interface{})
+
+	for _, key := range []string{"duration", "interval"} {
+		if value, ok := bake[key]; ok {
+			v.checkDuration(value, path+"."+key)
+		}
+	}
+
+	for _, key := range []string{"max_crashes", "max_restarts"} {
+		if max, ok := toInt(bake[key]); ok && max < 0 {
+			v.add(path+"."+key, "%s cannot be negative: %d", key, max)
+		}
+	}
+}
+
+func (v *validator) checkJanitor(node interface{}, path string) {
+	janitor, _ := node.(map[interface{}]interface{})
+
+	for _, key := range []string{"interval", "minimum_age"} {
+		if value, ok := janitor[key]; ok {
+			v.checkDuration(value, path+"."+key)
+		}
+	}
+
+	spaces, _ := janitor["spaces"].([]interface{})
+	for i, node := range spaces {
+		space, _ := node.(map[interface{}]interface{})
+		spacePath := fmt.Sprintf("%s.spaces[%d]", path, i)
+		for _, key := range []string{"org", "space"} {
+			if value, _ := space[key].(string); value == "" {
+				v.add(spacePath, "missing required key %q", key)
+			}
+		}
+	}
+}
+
+func (v *validator) checkTempFiles(node interface{}, path string) {
+	tempFiles, _ := node.(map[interface{}]interface{})
+
+	for _, key := range []string{"max_age", "sweep_interval"} {
+		if value, ok := tempFiles[key]; ok {
+			v.checkDuration(value, path+"."+key)
+		}
+	}
+
+	if value, ok := tempFiles["min_free_space"]; ok {
+		if _, err := s.ParseSize(fmt.Sprint(value)); err != nil {
+			v.add(path+".min_free_space", "%s", err)
+		}
+	}
+}
+
+func (v *validator) checkDuration(value interface{}, path string) {
+	if _, err := time.ParseDuration(fmt.Sprint(value)); err != nil {
+		v.add(path, "invalid duration %q: %s", fmt.Sprint(value), err)
+	}
+}
+
+func (v *validator) checkRolling(node interface{}, path string, foundations map[string]bool) {
+	rolling, _ := node.(map[interface{}]interface{})
+	if rolling == nil {
+		return
+	}
+
+	if size, ok := toInt(rolling["max_parallel_foundations"]); ok && size < 0 {
+		v.add(path+".max_parallel_foundations", "max_parallel_foundations cannot be negative: %d", size)
+	}
+
+	if pause, ok := rolling["pause"]; ok {
+		if _, err := time.ParseDuration(fmt.Sprint(pause)); err != nil {
+			v.add(path+".pause", "invalid duration %q: %s", fmt.Sprint(pause), err)
+		}
+	}
+
+	if rollback, ok := rolling["rollback"]; ok && rollback != s.RollbackAll && rollback != s.RollbackFailedWave {
+		v.add(path+".rollback", "unknown rollback policy %q: must be %q or %q", fmt.Sprint(rollback), s.RollbackAll, s.RollbackFailedWave)
+	}
+
+	waves, _ := rolling["waves"].([]interface{})
+	for i, node := range waves {
+		wave, _ := node.([]interface{})
+		for j, foundation := range wave {
+			if !foundations[fmt.Sprint(foundation)] {
+				v.add(fmt.Sprintf("%s.waves[%d][%d]", path, i, j), "unknown foundation %q", fmt.Sprint(foundation))
+			}
+		}
+	}
+}
+
+func (v *validator) checkDeployWindows(node interface{}, path string) {
+	windows, _ := node.([]interface{})
+
+	for i, node := range windows {
+		windowPath := fmt.Sprintf("%s[%d]", path, i)
+
+		window, ok := node.(map[interface{}]interface{})
+		if !ok {
+			v.add(windowPath, "deploy window must be a mapping")
+			continue
+		}
+
+		cron, _ := window["cron"].(string)
+		if cron == "" {
+			v.add(windowPath, "missing required key \"cron\"")
+			continue
+		}
+
+		timezone, _ := window["timezone"].(string)
+		if _, err := deploywindow.ParseSchedule(cron, timezone); err != nil {
+			v.add(windowPath+".cron", err.Error())
+		}
+	}
+}
+
+func (v *validator) checkFreezes(node interface{}, path string) {
+	freezes, _ := node.([]interface{})
+
+	for i, node := range freezes {
+		freezePath := fmt.Sprintf("%s[%d]", path, i)
+
+		freeze, ok := node.(map[interface{}]interface{})
+		if !ok {
+			v.add(freezePath, "freeze must be a mapping")
+			continue
+		}
+
+		start, startOK := toTimeString(freeze["start"])
+		end, endOK := toTimeString(freeze["end"])
+		if !startOK || !endOK {
+			v.add(freezePath, "missing required keys \"start\" and \"end\"")
+			continue
+		}
+
+		reason, _ := freeze["reason"].(string)
+		if _, err := deploywindow.ParseFreeze(s.Freeze{Start: start, End: end, Reason: reason}); err != nil {
+			v.add(freezePath, err.Error())
+		}
+	}
+}
+
+func (v *validator) checkErrorMatchers(node interface{}) {
+	matchers, _ := node.([]interface{})
+
+	for i, node := range matchers {
+		path := fmt.Sprintf("error_matchers[%d]", i)
+
+		matcher, ok := node.(map[interface{}]interface{})
+		if !ok {
+			v.add(path, "error matcher must be a mapping")
+			continue
+		}
+
+		pattern, _ := matcher["pattern"].(string)
+		if pattern == "" {
+			v.add(path, "missing required key \"pattern\"")
+			continue
+		}
+
+		if _, err := regexp.Compile(pattern); err != nil {
+			v.add(path+".pattern", "invalid error matcher pattern: %s", err)
+		}
+	}
+}
+
+func checkFoundationURL(foundationURL string) error {
+	u, err := url.Parse(foundationURL)
+	if err != nil {
+		return err
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("scheme must be http or https")
+	}
+
+	if u.Host == "" {
+		return fmt.Errorf("host is missing")
+	}
+
+	return nil
+}
+
+func yamlFields(t reflect.Type) map[string]reflect.StructField {
+	fields := map[string]reflect.StructField{}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+
+		fields[name] = field
+	}
+
+	return fields
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func toInt(value interface{}) (int64, bool) {
+	switch n := value.(type) {
+	case int:
+		return int64(n), true
+	case int64:
+		return n, true
+	case float64:
+		return int64(n), true
+	}
+	return 0, false
+}
+
+// toTimeString returns a freeze start or end as it was written. The generic yaml decoder
+// resolves unquoted dates and timestamps to time.Time.
+func toTimeString(value interface{}) (string, bool) {
+	switch t := value.(type) {
+	case string:
+		return t, t != ""
+	case time.Time:
+		if t.Equal(t.Truncate(24 * time.Hour)) {
+			return t.Format("2006-01-02"), true
+		}
+		return t.Format(time.RFC3339), true
+	}
+	return "", false
+}
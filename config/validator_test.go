@@ -0,0 +1,447 @@
+package config_test
+
+import (
+	"io/ioutil"
+	"os"
+
+	. "github.com/compozed/deployadactyl/config"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Validate", func() {
+	const validateConfigPath = "./validate_test_config.yml"
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(validateConfigPath)).To(Succeed())
+	})
+
+	It("returns no problems for a valid config", func() {
+		problems := ValidateYaml([]byte(`---
+environments:
+- name: Test
+  domain: test.example.com
+  foundations:
+  - https://api1.example.com
+  - https://api2.example.com
+  skip_ssl: true
+  instances: 3
+  custom_params:
+    anything: goes
+error_matchers:
+- description: a matcher
+  pattern: ab+
+  solution: do something
+`))
+
+		Expect(problems).To(BeEmpty())
+	})
+
+	It("reports unknown keys with their location", func() {
+		problems := ValidateYaml([]byte(`---
+environments:
+- name: Test
+  foundations:
+  - https://api1.example.com
+  skip_sll: true
+error_matchers:
+- pattern: ab
+  soluton: typo
+`))
+
+		Expect(problems).To(ConsistOf(
+			ValidationError{Line: 6, Field: "environments[0].skip_sll", Message: `unknown key "skip_sll"`},
+			ValidationError{Line: 9, Field: "error_matchers[0].soluton", Message: `unknown key "soluton"`},
+		))
+	})
+
+	It("reports environment names that collide after lowercasing", func() {
+		problems := ValidateYaml([]byte(`---
+environments:
+- name: Prod
+  foundations:
+  - https://api1.example.com
+- name: prod
+  foundations:
+  - https://api2.example.com
+`))
+
+		Expect(problems).To(HaveLen(1))
+		Expect(problems[0].Line).To(Equal(6))
+		Expect(problems[0].Error()).To(Equal(`line 6: environments[1].name: duplicate environment name "prod": also defined at line 3`))
+	})
+
+	It("reports malformed foundation URLs", func() {
+		problems := ValidateYaml([]byte(`---
+environments:
+- name: Test
+  foundations:
+  - https://api1.example.com
+  - api2.example.com
+  - https://
+`))
+
+		Expect(problems).To(HaveLen(2))
+		Expect(problems[0].Line).To(Equal(6))
+		Expect(problems[0].Field).To(Equal("environments[0].foundations[1]"))
+		Expect(problems[1].Line).To(Equal(7))
+		Expect(problems[1].Field).To(Equal("environments[0].foundations[2]"))
+	})
+
+	It("validates foundations given as mappings", func() {
+		problems := ValidateYaml([]byte(`---
+environments:
+- name: Test
+  foundations:
+  - name: east
+    api_url: https://api.east.example.com
+    apps_domain: apps.east.example.com
+  - name: east
+    api_url: api.west.example.com
+    app_domain: typo
+  - name: north
+`))
+
+		Expect(problems).To(ConsistOf(
+			ValidationError{Line: 8, Field: "environments[0].foundations[1].name", Message: `duplicate foundation name "east": also defined at line 5`},
+			ValidationError{Line: 9, Field: "environments[0].foundations[1].api_url", Message: `malformed foundation URL "api.west.example.com": scheme must be http or https`},
+			ValidationError{Line: 10, Field: "environments[0].foundations[1].app_domain", Message: `unknown key "app_domain"`},
+			ValidationError{Line: 11, Field: "environments[0].foundations[2]", Message: `missing required key "api_url"`},
+		))
+	})
+
+	It("reports invalid error matcher regexes", func() {
+		problems := ValidateYaml([]byte(`---
+environments:
+- name: Test
+  foundations:
+  - https://api1.example.com
+error_matchers:
+- description: fine
+  pattern: ab
+- description: broken
+  pattern: a((
+`))
+
+		Expect(problems).To(HaveLen(1))
+		Expect(problems[0].Line).To(Equal(10))
+		Expect(problems[0].Field).To(Equal("error_matchers[1].pattern"))
+	})
+
+	It("reports negative instances", func() {
+		problems := ValidateYaml([]byte(`---
+environments:
+- name: Test
+  foundations:
+  - https://api1.example.com
+  instances: -1
+`))
+
+		Expect(problems).To(ConsistOf(
+			ValidationError{Line: 6, Field: "environments[0].instances", Message: "instances cannot be negative: -1"},
+		))
+	})
+
+	It("reports invalid deploy windows and freezes", func() {
+		problems := ValidateYaml([]byte(`---
+environments:
+- name: Prod
+  foundations:
+  - https://api1.example.com
+  deploy_windows:
+  - cron: "* 9-17 * * 1-5"
+    timezone: America/Chicago
+  - cron: "* 25 * * *"
+  freezes:
+  - start: 2026-12-20
+    end: 2026-12-31
+    reason: holidays
+  - start: 2026-12-31
+    end: 2026-12-20
+`))
+
+		Expect(problems).To(HaveLen(2))
+		Expect(problems[0].Line).To(Equal(9))
+		Expect(problems[0].Field).To(Equal("environments[0].deploy_windows[1].cron"))
+		Expect(problems[0].Message).To(ContainSubstring("hour field out of range 0-23"))
+		Expect(problems[1].Line).To(Equal(14))
+		Expect(problems[1].Message).To(ContainSubstring("freeze must end after it starts"))
+	})
+
+	It("reports unknown strategies and invalid canary durations", func() {
+		problems := ValidateYaml([]byte(`---
+environments:
+- name: Prod
+  foundations:
+  - https://api1.example.com
+  strategy: yolo
+  canary:
+    bake_time: 5 minutes
+    health_check_interval: 30s
+`))
+
+		Expect(problems).To(HaveLen(2))
+		Expect(problems[0]).To(Equal(ValidationError{Line: 6, Field: "environments[0].strategy", Message: `unknown deployment strategy "yolo"`}))
+		Expect(problems[1].Line).To(Equal(8))
+		Expect(problems[1].Field).To(Equal("environments[0].canary.bake_time"))
+	})
+
+	It("reports a canary health check interval that is not positive", func() {
+		problems := ValidateYaml([]byte(`---
+environments:
+- name: Prod
+  foundations:
+  - https://api1.example.com
+  strategy: canary
+  canary:
+    health_check_interval: 0s
+`))
+
+		Expect(problems).To(Equal([]ValidationError{
+			{Line: 8, Field: "environments[0].canary.health_check_interval", Message: `health_check_interval must be positive: "0s"`},
+		}))
+	})
+
+	It("reports a strategy combined with a cf push strategy", func() {
+		problems := ValidateYaml([]byte(`---
+environments:
+- name: Prod
+  foundations:
+  - https://api1.example.com
+  strategy: canary
+  cf_push_strategy: rolling
+`))
+
+		Expect(problems).To(Equal([]ValidationError{
+			{Line: 7, Field: "environments[0].cf_push_strategy", Message: `cf_push_strategy "rolling" cannot be combined with strategy "canary"`},
+		}))
+	})
+
+	It("reports invalid rolling settings", func() {
+		problems := ValidateYaml([]byte(`---
+environments:
+- name: Prod
+  foundations:
+  - https://api1.example.com
+  - name: east
+    api_url: https://api.east.example.com
+  strategy: rolling
+  rolling:
+    waves:
+    - [east, "https://api1.example.com"]
+    - [west]
+    pause: soon
+    rollback: some
+`))
+
+		Expect(problems).To(HaveLen(3))
+		Expect(problems[0].Field).To(Equal("environments[0].rolling.waves[1][0]"))
+		Expect(problems[0].Message).To(Equal(`unknown foundation "west"`))
+		Expect(problems[1].Field).To(Equal("environments[0].rolling.pause"))
+		Expect(problems[2].Field).To(Equal("environments[0].rolling.rollback"))
+	})
+
+	It("reports invalid success policies", func() {
+		problems := ValidateYaml([]byte(`---
+environments:
+- name: Prod
+  foundations:
+  - https://api1.example.com
+  success_policy: most
+- name: Test
+  foundations:
+  - https://api1.example.com
+  success_policy: min_success
+`))
+
+		Expect(problems).To(HaveLen(2))
+		Expect(problems[0]).To(Equal(ValidationError{Line: 6, Field: "environments[0].success_policy", Message: `unknown success policy "most"`}))
+		Expect(problems[1].Field).To(Equal("environments[1]"))
+		Expect(problems[1].Message).To(ContainSubstring(`missing required key "min_success"`))
+	})
+
+	It("reports invalid timeouts", func() {
+		problems := ValidateYaml([]byte(`---
+timeouts:
+  commands:
+    push: 15 minutes
+  phases:
+    deploy: 10m
+environments:
+- name: Prod
+  foundations:
+  - https://api1.example.com
+  timeouts:
+    commands:
+      login: 1m
+    request: 1h
+`))
+
+		Expect(problems).To(HaveLen(3))
+		Expect(problems[0].Line).To(Equal(4))
+		Expect(problems[0].Field).To(Equal("timeouts.commands.push"))
+		Expect(problems[1]).To(Equal(ValidationError{Line: 6, Field: "timeouts.phases.deploy", Message: `unknown phase "deploy"`}))
+		Expect(problems[2]).To(Equal(ValidationError{Line: 12, Field: "environments[0].timeouts.commands", Message: "command timeouts can only be set at the top level"}))
+	})
+
+	It("reports invalid hooks", func() {
+		problems := ValidateYaml([]byte(`---
+environments:
+- name: Prod
+  foundations:
+  - https://api1.example.com
+  hooks:
+  - name: migrate
+    when: before_push
+    task: rake db:migrate
+  - name: warmup
+    when: later
+    url: https://warmup.example.com
+  - name: both
+    when: after_success
+    url: https://notify.example.com
+    command: [notify.sh]
+`))
+
+		Expect(problems).To(HaveLen(3))
+		Expect(problems[0]).To(Equal(ValidationError{Line: 9, Field: "environments[0].hooks[0].task", Message: "tasks cannot run before the push"}))
+		Expect(problems[1]).To(Equal(ValidationError{Line: 11, Field: "environments[0].hooks[1].when", Message: `unknown hook point "later"`}))
+		Expect(problems[2].Field).To(Equal("environments[0].hooks[2]"))
+		Expect(problems[2].Message).To(Equal("exactly one of task, url or command is required"))
+	})
+
+	It("reports invalid smoke tests", func() {
+		problems := ValidateYaml([]byte(`---
+environments:
+- name: Prod
+  foundations:
+  - https://api1.example.com
+  smoke_tests:
+  - name: status
+    path: /status
+    expected_status: [200, 999]
+    assertions:
+    - json_path: $.status
+      equals: ok
+    - equals: ok
+  - path: /login
+`))
+
+		Expect(problems).To(HaveLen(4))
+		Expect(problems[0]).To(Equal(ValidationError{Line: 9, Field: "environments[0].smoke_tests[0].expected_status[1]", Message: "invalid status code 999"}))
+		Expect(problems[1].Field).To(Equal("environments[0].smoke_tests[0].assertions[1]"))
+		Expect(problems[1].Message).To(Equal("one of json_path or matches is required"))
+		Expect(problems[2].Field).To(Equal("environments[0].smoke_tests[0].assertions[1].equals"))
+		Expect(problems[3]).To(Equal(ValidationError{Line: 14, Field: "environments[0].smoke_tests[1]", Message: `missing required key "name"`}))
+	})
+
+	It("reports invalid user-provided services", func() {
+		problems := ValidateYaml([]byte(`---
+environments:
+- name: Prod
+  foundations:
+  - https://api1.example.com
+  user_provided_services:
+  - name: payments-api
+    credentials: ((payments))
+  - name: payments-api
+  - syslog_drain_url: syslog://logs.example.com
+`))
+
+		Expect(problems).To(HaveLen(3))
+		Expect(problems[0]).To(Equal(ValidationError{Line: 8, Field: "environments[0].user_provided_services[0].credentials", Message: "credentials must be a mapping"}))
+		Expect(problems[1]).To(Equal(ValidationError{Line: 9, Field: "environments[0].user_provided_services[1].name", Message: `duplicate user-provided service "payments-api": also defined at line 7`}))
+		Expect(problems[2]).To(Equal(ValidationError{Line: 10, Field: "environments[0].user_provided_services[2]", Message: `missing required key "name"`}))
+	})
+
+	It("reports an invalid bake", func() {
+		problems := ValidateYaml([]byte(`---
+environments:
+- name: Prod
+  foundations:
+  - https://api1.example.com
+  bake:
+    duration: 10 minutes
+    max_restarts: -1
+`))
+
+		Expect(problems).To(HaveLen(2))
+		Expect(problems[0].Line).To(Equal(7))
+		Expect(problems[0].Field).To(Equal("environments[0].bake.duration"))
+		Expect(problems[1]).To(Equal(ValidationError{Line: 8, Field: "environments[0].bake.max_restarts", Message: "max_restarts cannot be negative: -1"}))
+	})
+
+	It("reports invalid temp files", func() {
+		problems := ValidateYaml([]byte(`---
+temp_files:
+  max_age: a day
+  min_free_space: lots
+environments:
+- name: Prod
+  foundations:
+  - https://api1.example.com
+`))
+
+		Expect(problems).To(HaveLen(2))
+		Expect(problems[0].Field).To(Equal("temp_files.max_age"))
+		Expect(problems[1]).To(Equal(ValidationError{Line: 4, Field: "temp_files.min_free_space", Message: "size lots has no unit"}))
+	})
+
+	It("reports an unknown courier", func() {
+		problems := ValidateYaml([]byte(`---
+courier: grpc
+environments:
+- name: Prod
+  foundations:
+  - https://api1.example.com
+`))
+
+		Expect(problems).To(Equal([]ValidationError{{Line: 2, Field: "courier", Message: `unknown courier "grpc"`}}))
+	})
+
+	It("reports an invalid janitor", func() {
+		problems := ValidateYaml([]byte(`---
+environments:
+- name: Prod
+  foundations:
+  - https://api1.example.com
+  janitor:
+    interval: 6 hours
+    spaces:
+    - org: payments
+`))
+
+		Expect(problems).To(HaveLen(2))
+		Expect(problems[0].Line).To(Equal(7))
+		Expect(problems[0].Field).To(Equal("environments[0].janitor.interval"))
+		Expect(problems[1]).To(Equal(ValidationError{Line: 9, Field: "environments[0].janitor.spaces[0]", Message: `missing required key "space"`}))
+	})
+
+	It("reports missing required keys", func() {
+		problems := ValidateYaml([]byte(`---
+environments:
+- domain: example.com
+`))
+
+		Expect(problems).To(HaveLen(2))
+		Expect(problems[0].Message).To(Equal(`missing required key "name"`))
+		Expect(problems[1].Message).To(Equal(`missing required key "foundations"`))
+	})
+
+	It("reports yaml that cannot be parsed", func() {
+		problems := ValidateYaml([]byte("environments: [\n"))
+
+		Expect(problems).To(HaveLen(1))
+		Expect(problems[0].Message).To(ContainSubstring("cannot parse yaml file"))
+	})
+
+	It("reads the config from a file", func() {
+		Expect(ioutil.WriteFile(validateConfigPath, []byte("---\nfoo: bar\n"), 0644)).To(Succeed())
+
+		problems, err := Validate(validateConfigPath)
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(problems).To(ContainElement(ValidationError{Line: 2, Field: "foo", Message: `unknown key "foo"`}))
+	})
+})
@@ -1,10 +1,11 @@
 package constants
 
 const (
-	DeployStartEvent   = "deploy.start"
-	DeployFinishEvent  = "deploy.finish"
-	DeploySuccessEvent = "deploy.success"
-	DeployFailureEvent = "deploy.failure"
-	PushStartedEvent   = "push.started"
-	PushFinishedEvent  = "push.finished"
+	DeployStartEvent          = "deploy.start"
+	DeployFinishEvent         = "deploy.finish"
+	DeploySuccessEvent        = "deploy.success"
+	DeployFailureEvent        = "deploy.failure"
+	DeployPartialSuccessEvent = "deploy.partial.success"
+	PushStartedEvent          = "push.started"
+	PushFinishedEvent         = "push.finished"
 )
@@ -3,6 +3,7 @@ package controller
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -13,10 +14,13 @@ import (
 
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/compozed/deployadactyl/config"
+	"github.com/compozed/deployadactyl/janitor"
 	"github.com/compozed/deployadactyl/randomizer"
 	"github.com/compozed/deployadactyl/request"
+	"github.com/compozed/deployadactyl/schedule"
 	"github.com/gin-gonic/gin"
 )
 
@@ -28,9 +32,28 @@ type Controller struct {
 	RequestProcessorFactory RequestProcessorFactory
 	Config                  config.Config
 	ErrorFinder             I.ErrorFinder
+	Scheduler               I.Scheduler
+	Janitor                 I.Janitor
+
+	// Redactor masks the passwords and secrets of requests in the responses, along with the secrets it
+	// knows. Without it nothing is masked.
+	Redactor I.Redactor
 }
 
 func (c *Controller) PostRequestHandler(g *gin.Context) {
+	c.push(g, false)
+}
+
+// PromoteRequestHandler deploys the last successful deployment of an application in the environment
+// named by the promote field of the request body to the environment in the path. It is a push request
+// that has to promote.
+func (c *Controller) PromoteRequestHandler(g *gin.Context) {
+	c.push(g, true)
+}
+
+// push processes a push request. A promotion has to be a JSON request that names the environment to
+// promote from.
+func (c *Controller) push(g *gin.Context, promote bool) {
 	cfContext := I.CFContext{
 		Environment:  strings.ToLower(g.Param("environment")),
 		Organization: strings.ToLower(g.Param("org")),
@@ -43,11 +66,12 @@ func (c *Controller) PostRequestHandler(g *gin.Context) {
 		Username: user,
 		Password: pwd,
 	}
+	secrets := []string{pwd}
 
 	deploymentType := g.Request.Header.Get("Content-Type")
 
 	response := &bytes.Buffer{}
-	defer io.Copy(g.Writer, response)
+	defer func() { c.writeResponse(g, response, secrets) }()
 
 	bodyBuffer, _ := ioutil.ReadAll(g.Request.Body)
 
@@ -68,6 +92,21 @@ func (c *Controller) PostRequestHandler(g *gin.Context) {
 			g.Writer.WriteHeader(http.StatusBadRequest)
 			return
 		}
+	} else if promote {
+		response.Write([]byte("Invalid request body."))
+		g.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	secrets = append(secrets, postRequest.Secrets()...)
+
+	action := "deploy"
+	if promote {
+		if postRequest.Promote.Environment == "" {
+			response.Write([]byte("promote.environment is required."))
+			g.Writer.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		action = "promote"
 	}
 
 	postDeploymentRequest := request.PostDeploymentRequest{
@@ -81,11 +120,20 @@ func (c *Controller) PostRequestHandler(g *gin.Context) {
 	log := I.DeploymentLogger{Log: c.Log, UUID: postRequest.UUID}
 	log.Debugf("Request originated from: %+v", g.Request.RemoteAddr)
 
+	if c.schedule(g, I.ScheduledPush, postRequest.UUID, postRequest.Schedule, deployment, response) {
+		return
+	}
+
+	if output := c.stream(g, secrets); output != nil {
+		defer output.close()
+		postDeploymentRequest.Deployment.Output = output
+	}
+
 	deployResponse := c.RequestProcessorFactory(postRequest.UUID, postDeploymentRequest, response).Process()
 
 	if deployResponse.Error != nil {
 		g.Writer.WriteHeader(deployResponse.StatusCode)
-		fmt.Fprintf(response, "cannot deploy application: %s\n", deployResponse.Error)
+		fmt.Fprintf(response, "cannot %s application: %s\n", action, deployResponse.Error)
 		return
 	}
 
@@ -100,15 +148,15 @@ func (c *Controller) PutRequestHandler(g *gin.Context) {
 		Application:  strings.ToLower(g.Param("appName")),
 	}
 
-	response := &bytes.Buffer{}
-	defer io.Copy(g.Writer, response)
-
 	user, pwd, _ := g.Request.BasicAuth()
 	authorization := I.Authorization{
 		Username: user,
 		Password: pwd,
 	}
 
+	response := &bytes.Buffer{}
+	defer c.writeResponse(g, response, []string{pwd})
+
 	bodyBuffer, _ := ioutil.ReadAll(g.Request.Body)
 	g.Request.Body.Close()
 
@@ -139,6 +187,15 @@ func (c *Controller) PutRequestHandler(g *gin.Context) {
 	log := I.DeploymentLogger{Log: c.Log, UUID: putRequest.UUID}
 	log.Debugf("PUT Request originated from: %+v", g.Request.RemoteAddr)
 
+	if c.schedule(g, I.ScheduledStateChange, putRequest.UUID, putRequest.Schedule, deployment, response) {
+		return
+	}
+
+	if output := c.stream(g, []string{pwd}); output != nil {
+		defer output.close()
+		putDeploymentRequest.Deployment.Output = output
+	}
+
 	deployResponse := c.RequestProcessorFactory(putRequest.UUID, putDeploymentRequest, response).Process()
 	if deployResponse.Error != nil {
 		fmt.Fprintf(response, "cannot deploy application: %s\n", deployResponse.Error)
@@ -159,15 +216,15 @@ func (c *Controller) DeleteRequestHandler(g *gin.Context) {
 		Application:  strings.ToLower(g.Param("appName")),
 	}
 
-	response := &bytes.Buffer{}
-	defer io.Copy(g.Writer, response)
-
 	user, pwd, _ := g.Request.BasicAuth()
 	authorization := I.Authorization{
 		Username: user,
 		Password: pwd,
 	}
 
+	response := &bytes.Buffer{}
+	defer c.writeResponse(g, response, []string{pwd})
+
 	bodyBuffer, _ := ioutil.ReadAll(g.Request.Body)
 	g.Request.Body.Close()
 
@@ -191,6 +248,11 @@ func (c *Controller) DeleteRequestHandler(g *gin.Context) {
 		Request:    deleteRequest,
 	}
 
+	if output := c.stream(g, []string{pwd}); output != nil {
+		defer output.close()
+		deleteDeploymentRequest.Deployment.Output = output
+	}
+
 	deployResponse := c.RequestProcessorFactory(uuid, deleteDeploymentRequest, response).Process()
 	if deployResponse.Error != nil {
 		fmt.Fprintf(response, "cannot delete application: %s\n", deployResponse.Error)
@@ -198,3 +260,136 @@ func (c *Controller) DeleteRequestHandler(g *gin.Context) {
 
 	g.Writer.WriteHeader(deployResponse.StatusCode)
 }
+
+// GetScheduledHandler lists the deployments that the user scheduled in an environment and that have
+// not run yet.
+func (c *Controller) GetScheduledHandler(g *gin.Context) {
+	environment := strings.ToLower(g.Param("environment"))
+
+	user, _, ok := g.Request.BasicAuth()
+	if !ok || user == "" {
+		g.String(http.StatusUnauthorized, "cannot list scheduled deployments: basic auth is required\n")
+		return
+	}
+
+	g.JSON(http.StatusOK, c.Scheduler.List(environment, user))
+}
+
+// DeleteScheduledHandler cancels a deployment scheduled in an environment.
+func (c *Controller) DeleteScheduledHandler(g *gin.Context) {
+	environment := strings.ToLower(g.Param("environment"))
+	uuid := g.Param("uuid")
+
+	user, pwd, ok := g.Request.BasicAuth()
+	if !ok || user == "" {
+		g.String(http.StatusUnauthorized, "cannot cancel scheduled deployment: basic auth is required\n")
+		return
+	}
+	authorization := I.Authorization{
+		Username: user,
+		Password: pwd,
+	}
+
+	deployment, err := c.Scheduler.Cancel(environment, uuid, authorization)
+	switch err.(type) {
+	case nil:
+		g.JSON(http.StatusOK, deployment)
+	case schedule.ScheduledDeploymentNotFoundError:
+		g.String(http.StatusNotFound, "cannot cancel scheduled deployment: %s\n", err)
+	case schedule.CancelNotAuthorizedError:
+		g.String(http.StatusUnauthorized, "cannot cancel scheduled deployment: %s\n", err)
+	default:
+		g.String(http.StatusInternalServerError, "cannot cancel scheduled deployment: %s\n", err)
+	}
+}
+
+// JanitorHandler deletes the temporary applications that interrupted pushes left behind in an
+// environment and responds with the report of the janitor.
+func (c *Controller) JanitorHandler(g *gin.Context) {
+	environment := strings.ToLower(g.Param("environment"))
+
+	user, pwd, _ := g.Request.BasicAuth()
+	authorization := I.Authorization{
+		Username: user,
+		Password: pwd,
+	}
+
+	if env, ok := c.Config.Environments[environment]; ok && env.Authenticate && user == "" && pwd == "" {
+		g.String(http.StatusUnauthorized, "cannot sweep environment: basic auth is required for environment %s\n", environment)
+		return
+	}
+
+	report, err := c.Janitor.Sweep(environment, authorization)
+	switch err.(type) {
+	case nil:
+		g.JSON(http.StatusOK, report)
+	case janitor.EnvironmentNotFoundError:
+		g.String(http.StatusNotFound, "cannot sweep environment: %s\n", err)
+	case janitor.JanitorNotConfiguredError:
+		g.String(http.StatusBadRequest, "cannot sweep environment: %s\n", err)
+	case janitor.SweepInProgressError:
+		g.String(http.StatusConflict, "cannot sweep environment: %s\n", err)
+	default:
+		g.String(http.StatusInternalServerError, "cannot sweep environment: %s\n", err)
+	}
+}
+
+// schedule keeps a request that is scheduled at a later time to be run then, and writes the status
+// of the response.
+//
+// Returns false when the request is not scheduled and is to be run right away.
+func (c *Controller) schedule(g *gin.Context, kind, uuid string, requestSchedule request.Schedule, deployment I.Deployment, response *bytes.Buffer) bool {
+	scheduledAt, scheduled, err := requestSchedule.GetScheduledAt()
+	if !scheduled {
+		return false
+	}
+
+	if err == nil && !scheduledAt.After(time.Now()) {
+		err = errors.New("it is not in the future")
+	}
+	if err != nil {
+		g.Writer.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(response, "cannot schedule deployment: %s\n", InvalidScheduledAtError{requestSchedule.ScheduledAt, err})
+		return true
+	}
+
+	err = c.Scheduler.Schedule(I.ScheduledDeployment{
+		UUID:          uuid,
+		Kind:          kind,
+		ScheduledAt:   scheduledAt,
+		CFContext:     deployment.CFContext,
+		Authorization: deployment.Authorization,
+		ContentType:   deployment.Type,
+		Body:          *deployment.Body,
+		Authenticate:  c.Config.Environments[deployment.CFContext.Environment].Authenticate,
+	})
+	if err != nil {
+		switch err.(type) {
+		case schedule.DuplicateScheduledDeploymentError:
+			g.Writer.WriteHeader(http.StatusConflict)
+		case schedule.CredentialsRequiredError:
+			g.Writer.WriteHeader(http.StatusUnauthorized)
+		case schedule.UnschedulableContentTypeError:
+			g.Writer.WriteHeader(http.StatusBadRequest)
+		default:
+			g.Writer.WriteHeader(http.StatusInternalServerError)
+		}
+		fmt.Fprintf(response, "cannot schedule deployment: %s\n", err)
+		return true
+	}
+
+	g.Writer.WriteHeader(http.StatusAccepted)
+	fmt.Fprintf(response, "scheduled deployment %s at %s\n", uuid, scheduledAt.Format(time.RFC3339))
+	return true
+}
+
+// writeResponse writes the response of a handler with the secrets of its request masked.
+func (c *Controller) writeResponse(g *gin.Context, response *bytes.Buffer, secrets []string) {
+	defer finishStream(g)
+
+	if c.Redactor == nil {
+		io.Copy(g.Writer, response)
+		return
+	}
+	io.WriteString(g.Writer, c.Redactor.With(secrets...).Redact(response.String()))
+}
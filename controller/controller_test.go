@@ -12,13 +12,18 @@ import (
 	"os"
 
 	"strings"
+	"time"
 
 	"github.com/compozed/deployadactyl/config"
 	. "github.com/compozed/deployadactyl/controller"
 	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/janitor"
 	"github.com/compozed/deployadactyl/mocks"
 	"github.com/compozed/deployadactyl/randomizer"
+	"github.com/compozed/deployadactyl/redact"
 	"github.com/compozed/deployadactyl/request"
+	"github.com/compozed/deployadactyl/schedule"
+	S "github.com/compozed/deployadactyl/structs"
 	"github.com/gin-gonic/gin"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -32,6 +37,8 @@ var _ = Describe("Controller", func() {
 		eventManager     *mocks.EventManager
 		errorFinder      *mocks.ErrorFinder
 		requestProcessor *mocks.RequestProcessor
+		scheduler        *mocks.Scheduler
+		janitorMock      *mocks.Janitor
 
 		receivedBuffer  *bytes.Buffer
 		receivedUuid    string
@@ -70,11 +77,15 @@ var _ = Describe("Controller", func() {
 		}
 
 		errorFinder = &mocks.ErrorFinder{}
+		scheduler = &mocks.Scheduler{}
+		janitorMock = &mocks.Janitor{}
 		controller = &Controller{
 			Log: I.DefaultLogger(logBuffer, logging.DEBUG, "api_test"),
 			RequestProcessorFactory: requestFactory,
 			Config:                  config.Config{},
 			ErrorFinder:             errorFinder,
+			Scheduler:               scheduler,
+			Janitor:                 janitorMock,
 		}
 	})
 
@@ -271,6 +282,96 @@ var _ = Describe("Controller", func() {
 				Eventually(resp.Body).Should(ContainSubstring("deploy success"))
 			})
 		})
+
+		Context("when the Redactor is set", func() {
+			It("masks the password and the secrets of the request in the response", func() {
+				controller.Redactor = redact.New()
+				foundationURL = fmt.Sprintf("/v3/apps/%s/%s/%s/%s", environment, org, space, appName)
+
+				jsonBuffer = bytes.NewBufferString(`{
+"environment_variables": {"DB_PASSWORD": "correct-horse", "REGION": "east"},
+"secret_environment_variables": ["DB_PASSWORD"],
+"user_provided_services": [{"name": "db", "credentials": {"token": "s3cr3t-token"}}]}`)
+
+				req, _ := http.NewRequest("POST", foundationURL, jsonBuffer)
+				req.Header.Set("Content-Type", "application/json")
+				req.SetBasicAuth("deployer", "hunter2")
+
+				requestProcessor.ProcessCall.Returns.Response = I.DeployResponse{
+					StatusCode: http.StatusOK,
+				}
+				requestProcessor.ProcessCall.Writes = "login hunter2, db correct-horse, token s3cr3t-token, region east"
+
+				router.ServeHTTP(resp, req)
+
+				Eventually(resp.Code).Should(Equal(http.StatusOK))
+				Eventually(resp.Body).Should(ContainSubstring("login [REDACTED], db [REDACTED], token [REDACTED], region east"))
+			})
+
+			It("does not mask the secrets of a request in the response of another", func() {
+				controller.Redactor = redact.New()
+				foundationURL = fmt.Sprintf("/v3/apps/%s/%s/%s/%s", environment, org, space, appName)
+				requestProcessor.ProcessCall.Returns.Response = I.DeployResponse{StatusCode: http.StatusOK}
+				requestProcessor.ProcessCall.Writes = "login hunter2"
+
+				req, _ := http.NewRequest("POST", foundationURL, bytes.NewBufferString("{}"))
+				req.Header.Set("Content-Type", "application/json")
+				req.SetBasicAuth("deployer", "hunter2")
+				router.ServeHTTP(resp, req)
+				Expect(resp.Body.String()).To(ContainSubstring("login [REDACTED]"))
+
+				other := httptest.NewRecorder()
+				req, _ = http.NewRequest("POST", foundationURL, bytes.NewBufferString("{}"))
+				req.Header.Set("Content-Type", "application/json")
+				req.SetBasicAuth("deployer", "correct-horse")
+				router.ServeHTTP(other, req)
+				Expect(other.Body.String()).To(ContainSubstring("login hunter2"))
+			})
+
+		Context("when the client streams the response", func() {
+			BeforeEach(func() {
+				controller.Redactor = redact.New()
+				controller.RequestProcessorFactory = func(uuid string, received interface{}, output *bytes.Buffer) I.RequestProcessor {
+					receivedRequest = received
+					if live := received.(request.PostDeploymentRequest).Deployment.Output; live != nil {
+						fmt.Fprint(live, "east: [push] Staging app as hunter2...\n")
+					}
+
+					requestProcessor.Response = output
+					return requestProcessor
+				}
+				requestProcessor.ProcessCall.Returns.Response = I.DeployResponse{StatusCode: http.StatusInternalServerError, Error: errors.New("push failed")}
+				requestProcessor.ProcessCall.Writes = "Cloud Foundry Output\n"
+			})
+
+			It("writes the Cloud Foundry output as it arrives, then the response and its status in a trailer", func() {
+				foundationURL = fmt.Sprintf("/v3/apps/%s/%s/%s/%s?stream=true", environment, org, space, appName)
+
+				req, _ := http.NewRequest("POST", foundationURL, bytes.NewBufferString("{}"))
+				req.Header.Set("Content-Type", "application/json")
+				req.SetBasicAuth("deployer", "hunter2")
+				router.ServeHTTP(resp, req)
+
+				Expect(resp.Code).To(Equal(http.StatusOK))
+				Expect(resp.Flushed).To(BeTrue())
+				Expect(resp.Body.String()).To(HavePrefix("east: [push] Staging app as [REDACTED]...\nCloud Foundry Output\ncannot deploy application: push failed\n"))
+				Expect(resp.Header().Get("Trailer")).To(Equal(StatusTrailer))
+				Expect(resp.Header().Get(StatusTrailer)).To(Equal("500"))
+			})
+
+			It("does not stream unless the request asks for it", func() {
+				foundationURL = fmt.Sprintf("/v3/apps/%s/%s/%s/%s", environment, org, space, appName)
+
+				req, _ := http.NewRequest("POST", foundationURL, bytes.NewBufferString("{}"))
+				req.Header.Set("Content-Type", "application/json")
+				router.ServeHTTP(resp, req)
+
+				Expect(resp.Code).To(Equal(http.StatusInternalServerError))
+				Expect(resp.Body.String()).ToNot(ContainSubstring("[push]"))
+				Expect(resp.Header().Get("Trailer")).To(BeEmpty())
+			})
+		})
+		})
 	})
 
 	Describe("PutRequestHandler", func() {
@@ -437,4 +538,283 @@ var _ = Describe("Controller", func() {
 			})
 		})
 	})
+
+	Describe("PromoteRequestHandler", func() {
+		var (
+			router     *gin.Engine
+			resp       *httptest.ResponseRecorder
+			promoteURL string
+		)
+
+		BeforeEach(func() {
+			router = gin.New()
+			resp = httptest.NewRecorder()
+			promoteURL = fmt.Sprintf("/v3/promote/%s/%s/%s/%s", environment, org, space, appName)
+
+			router.POST("/v3/promote/:environment/:org/:space/:appName", controller.PromoteRequestHandler)
+		})
+
+		It("processes a push request that promotes the deployment", func() {
+			body := `{"uuid": "release", "promote": {"environment": "staging"}, "environment_variables": {"stage": "prod"}}`
+			req, _ := http.NewRequest("POST", promoteURL, bytes.NewBufferString(body))
+			req.Header.Set("Content-Type", "application/json")
+			requestProcessor.ProcessCall.Returns.Response = I.DeployResponse{StatusCode: http.StatusOK}
+
+			router.ServeHTTP(resp, req)
+
+			Expect(resp.Code).To(Equal(http.StatusOK))
+			Expect(receivedUuid).To(Equal("release"))
+
+			postRequest := receivedRequest.(request.PostDeploymentRequest)
+			Expect(postRequest.Type).To(Equal("application/json"))
+			Expect(postRequest.CFContext.Environment).To(Equal(environment))
+			Expect(postRequest.CFContext.Application).To(Equal(appName))
+			Expect(postRequest.Request.Promote.Environment).To(Equal("staging"))
+			Expect(postRequest.Request.EnvironmentVariables).To(Equal(map[string]string{"stage": "prod"}))
+		})
+
+		It("returns a Bad Request error when the environment to promote from is missing", func() {
+			req, _ := http.NewRequest("POST", promoteURL, bytes.NewBufferString(`{"promote": {}}`))
+			req.Header.Set("Content-Type", "application/json")
+
+			router.ServeHTTP(resp, req)
+
+			Expect(resp.Code).To(Equal(http.StatusBadRequest))
+			Expect(requestProcessor.ProcessCall.TimesCalled).To(Equal(0))
+		})
+
+		It("returns a Bad Request error when the body is not json", func() {
+			req, _ := http.NewRequest("POST", promoteURL, bytes.NewBufferString("artifact"))
+			req.Header.Set("Content-Type", "application/zip")
+
+			router.ServeHTTP(resp, req)
+
+			Expect(resp.Code).To(Equal(http.StatusBadRequest))
+			Expect(requestProcessor.ProcessCall.TimesCalled).To(Equal(0))
+		})
+
+		It("returns the error of the promotion", func() {
+			req, _ := http.NewRequest("POST", promoteURL, bytes.NewBufferString(`{"promote": {"environment": "staging"}}`))
+			req.Header.Set("Content-Type", "application/json")
+			requestProcessor.ProcessCall.Returns.Response = I.DeployResponse{
+				StatusCode: http.StatusNotFound,
+				Error:      errors.New("nothing to promote"),
+			}
+
+			router.ServeHTTP(resp, req)
+
+			Expect(resp.Code).To(Equal(http.StatusNotFound))
+			Expect(resp.Body.String()).To(ContainSubstring("cannot promote application: nothing to promote"))
+		})
+	})
+
+	Describe("scheduled deployments", func() {
+		var (
+			router *gin.Engine
+			resp   *httptest.ResponseRecorder
+			appURL string
+		)
+
+		BeforeEach(func() {
+			router = gin.New()
+			resp = httptest.NewRecorder()
+			appURL = fmt.Sprintf("/v3/apps/%s/%s/%s/%s", environment, org, space, appName)
+
+			router.POST("/v3/apps/:environment/:org/:space/:appName", controller.PostRequestHandler)
+			router.PUT("/v3/apps/:environment/:org/:space/:appName", controller.PutRequestHandler)
+			router.GET("/v3/scheduled/:environment", controller.GetScheduledHandler)
+			router.DELETE("/v3/scheduled/:environment/:uuid", controller.DeleteScheduledHandler)
+		})
+
+		It("schedules a push instead of processing it", func() {
+			scheduledAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+			body := []byte(fmt.Sprintf(`{"artifact_url": "the url", "uuid": "release", "scheduled_at": %q}`, scheduledAt.Format(time.RFC3339)))
+
+			req, _ := http.NewRequest("POST", appURL, bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.SetBasicAuth("deployer", "secret")
+
+			router.ServeHTTP(resp, req)
+
+			Expect(resp.Code).To(Equal(http.StatusAccepted))
+			Expect(resp.Body.String()).To(ContainSubstring("scheduled deployment release"))
+			Expect(requestProcessor.ProcessCall.TimesCalled).To(Equal(0))
+
+			Expect(scheduler.ScheduleCall.Received.Deployments).To(HaveLen(1))
+			deployment := scheduler.ScheduleCall.Received.Deployments[0]
+			Expect(deployment.UUID).To(Equal("release"))
+			Expect(deployment.Kind).To(Equal(I.ScheduledPush))
+			Expect(deployment.ScheduledAt.Equal(scheduledAt)).To(BeTrue())
+			Expect(deployment.CFContext.Application).To(Equal(appName))
+			Expect(deployment.Authorization).To(Equal(I.Authorization{Username: "deployer", Password: "secret"}))
+			Expect(deployment.Authenticate).To(BeFalse())
+			Expect(deployment.Body).To(Equal(body))
+		})
+
+		It("tells the scheduler whether the environment authenticates", func() {
+			controller.Config.Environments = map[string]S.Environment{environment: {Name: environment, Authenticate: true}}
+			body := fmt.Sprintf(`{"artifact_url": "the url", "scheduled_at": %q}`, time.Now().Add(time.Hour).Format(time.RFC3339))
+
+			req, _ := http.NewRequest("POST", appURL, bytes.NewBufferString(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.SetBasicAuth("deployer", "secret")
+
+			router.ServeHTTP(resp, req)
+
+			Expect(resp.Code).To(Equal(http.StatusAccepted))
+			Expect(scheduler.ScheduleCall.Received.Deployments[0].Authenticate).To(BeTrue())
+		})
+
+		It("schedules a state change", func() {
+			body := fmt.Sprintf(`{"state": "stopped", "scheduled_at": %q}`, time.Now().Add(time.Hour).Format(time.RFC3339))
+
+			req, _ := http.NewRequest("PUT", appURL, bytes.NewBufferString(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.SetBasicAuth("deployer", "secret")
+
+			router.ServeHTTP(resp, req)
+
+			Expect(resp.Code).To(Equal(http.StatusAccepted))
+			Expect(scheduler.ScheduleCall.Received.Deployments[0].Kind).To(Equal(I.ScheduledStateChange))
+			Expect(requestProcessor.ProcessCall.TimesCalled).To(Equal(0))
+		})
+
+		It("returns a Bad Request error for a time in the past", func() {
+			req, _ := http.NewRequest("POST", appURL, bytes.NewBufferString(`{"scheduled_at": "2020-01-01T06:00:00Z"}`))
+			req.Header.Set("Content-Type", "application/json")
+
+			router.ServeHTTP(resp, req)
+
+			Expect(resp.Code).To(Equal(http.StatusBadRequest))
+			Expect(resp.Body.String()).To(ContainSubstring("invalid scheduled_at 2020-01-01T06:00:00Z: it is not in the future"))
+			Expect(scheduler.ScheduleCall.Received.Deployments).To(BeEmpty())
+		})
+
+		It("returns a Bad Request error for a time that cannot be parsed", func() {
+			req, _ := http.NewRequest("POST", appURL, bytes.NewBufferString(`{"scheduled_at": "tomorrow at 6"}`))
+			req.Header.Set("Content-Type", "application/json")
+
+			router.ServeHTTP(resp, req)
+
+			Expect(resp.Code).To(Equal(http.StatusBadRequest))
+			Expect(requestProcessor.ProcessCall.TimesCalled).To(Equal(0))
+		})
+
+		It("returns Unauthorized when the scheduler requires credentials", func() {
+			scheduler.ScheduleCall.Returns.Error = schedule.CredentialsRequiredError{UUID: "release"}
+			body := fmt.Sprintf(`{"artifact_url": "the url", "scheduled_at": %q}`, time.Now().Add(time.Hour).Format(time.RFC3339))
+
+			req, _ := http.NewRequest("POST", appURL, bytes.NewBufferString(body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(resp, req)
+
+			Expect(resp.Code).To(Equal(http.StatusUnauthorized))
+			Expect(requestProcessor.ProcessCall.TimesCalled).To(Equal(0))
+		})
+
+		It("lists the deployments scheduled by the user in the environment", func() {
+			scheduler.ListCall.Returns.Deployments = []I.ScheduledDeployment{
+				{UUID: "release", Kind: I.ScheduledPush, Authorization: I.Authorization{Username: "deployer", Password: "secret"}},
+			}
+
+			req, _ := http.NewRequest("GET", "/v3/scheduled/"+environment, nil)
+			req.SetBasicAuth("deployer", "secret")
+			router.ServeHTTP(resp, req)
+
+			Expect(resp.Code).To(Equal(http.StatusOK))
+			Expect(resp.Body.String()).To(ContainSubstring(`"uuid":"release"`))
+			Expect(resp.Body.String()).ToNot(ContainSubstring("secret"))
+			Expect(scheduler.ListCall.Received.Environment).To(Equal(environment))
+			Expect(scheduler.ListCall.Received.Username).To(Equal("deployer"))
+		})
+
+		It("returns Unauthorized when listing without basic auth", func() {
+			req, _ := http.NewRequest("GET", "/v3/scheduled/"+environment, nil)
+			router.ServeHTTP(resp, req)
+
+			Expect(resp.Code).To(Equal(http.StatusUnauthorized))
+			Expect(resp.Body.String()).To(ContainSubstring("basic auth is required"))
+		})
+
+		It("cancels a scheduled deployment with the credentials of the request", func() {
+			scheduler.CancelCall.Returns.Deployment = I.ScheduledDeployment{UUID: "release"}
+
+			req, _ := http.NewRequest("DELETE", "/v3/scheduled/"+environment+"/release", nil)
+			req.SetBasicAuth("deployer", "secret")
+			router.ServeHTTP(resp, req)
+
+			Expect(resp.Code).To(Equal(http.StatusOK))
+			Expect(scheduler.CancelCall.Received.Environment).To(Equal(environment))
+			Expect(scheduler.CancelCall.Received.UUID).To(Equal("release"))
+			Expect(scheduler.CancelCall.Received.Authorization).To(Equal(I.Authorization{Username: "deployer", Password: "secret"}))
+		})
+
+		It("returns Unauthorized when cancelling without basic auth", func() {
+			req, _ := http.NewRequest("DELETE", "/v3/scheduled/"+environment+"/release", nil)
+			router.ServeHTTP(resp, req)
+
+			Expect(resp.Code).To(Equal(http.StatusUnauthorized))
+			Expect(scheduler.CancelCall.Received.UUID).To(BeEmpty())
+		})
+
+		It("returns Not Found when cancelling a deployment that is not scheduled", func() {
+			scheduler.CancelCall.Returns.Error = schedule.ScheduledDeploymentNotFoundError{UUID: "unknown"}
+
+			req, _ := http.NewRequest("DELETE", "/v3/scheduled/"+environment+"/unknown", nil)
+			req.SetBasicAuth("deployer", "secret")
+			router.ServeHTTP(resp, req)
+
+			Expect(resp.Code).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	Describe("JanitorHandler", func() {
+		var (
+			router *gin.Engine
+			resp   *httptest.ResponseRecorder
+		)
+
+		BeforeEach(func() {
+			router = gin.New()
+			resp = httptest.NewRecorder()
+
+			router.POST("/v3/janitor/:environment", controller.JanitorHandler)
+		})
+
+		It("sweeps the environment and responds with the report", func() {
+			janitorMock.SweepCall.Returns.Report = I.JanitorReport{
+				Environment: environment,
+				Deleted:     []I.OrphanedApplication{{Name: "app-new-build-abc", UUID: "abc"}},
+			}
+
+			req, _ := http.NewRequest("POST", "/v3/janitor/"+environment, nil)
+			req.SetBasicAuth("deployer", "secret")
+			router.ServeHTTP(resp, req)
+
+			Expect(resp.Code).To(Equal(http.StatusOK))
+			Expect(resp.Body.String()).To(ContainSubstring(`"name":"app-new-build-abc"`))
+			Expect(janitorMock.SweepCall.Received.Environment).To(Equal(environment))
+			Expect(janitorMock.SweepCall.Received.Authorization).To(Equal(I.Authorization{Username: "deployer", Password: "secret"}))
+		})
+
+		It("returns a Not Found error for an unknown environment", func() {
+			janitorMock.SweepCall.Returns.Error = janitor.EnvironmentNotFoundError{environment}
+
+			req, _ := http.NewRequest("POST", "/v3/janitor/"+environment, nil)
+			router.ServeHTTP(resp, req)
+
+			Expect(resp.Code).To(Equal(http.StatusNotFound))
+			Expect(resp.Body.String()).To(ContainSubstring("cannot sweep environment: environment not found"))
+		})
+
+		It("returns an Unauthorized error without credentials for an environment that authenticates", func() {
+			controller.Config.Environments = map[string]S.Environment{environment: {Name: environment, Authenticate: true}}
+
+			req, _ := http.NewRequest("POST", "/v3/janitor/"+environment, nil)
+			router.ServeHTTP(resp, req)
+
+			Expect(resp.Code).To(Equal(http.StatusUnauthorized))
+			Expect(janitorMock.SweepCall.Received.Environment).To(BeEmpty())
+		})
+	})
 })
@@ -22,6 +22,11 @@ func NewActor(action I.Action) actor {
 type actor struct {
 	Commands chan<- ActorCommand
 	Errs     <-chan error
+
+	// name is the name of the foundation the actor runs against, and baked whether its action
+	// is an I.BakedAction.
+	name  string
+	baked bool
 }
 
 type ActorCommand func(action I.Action) error
@@ -0,0 +1,78 @@
+package bluegreen
+
+import (
+	"strings"
+	"time"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+// bakeCutover watches the baked actors every interval until the bake window has passed. When any of
+// them turns out to be unhealthy, all of them are rolled back to what they replaced.
+func (bg BlueGreen) bakeCutover(actors []actor, bake S.Bake) error {
+	duration, _ := bake.GetDuration()
+	interval, _ := bake.GetInterval()
+
+	baked := make([]actor, 0, len(actors))
+	names := make([]string, 0, len(actors))
+	for _, a := range actors {
+		if a.baked {
+			baked = append(baked, a)
+			names = append(names, a.name)
+		}
+	}
+	if duration <= 0 || len(baked) == 0 {
+		return nil
+	}
+
+	bg.Log.Infof("baking %s for %s", strings.Join(names, ", "), duration)
+
+	since := time.Now()
+	deadline := since.Add(duration)
+	for {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			break
+		}
+
+		wait := interval
+		if remaining < wait {
+			wait = remaining
+		}
+		time.Sleep(wait)
+
+		errs := bg.commandsByActor(baked, func(action I.Action) error {
+			return action.(I.BakedAction).Bake(since)
+		})
+
+		bakeError := BakeError{}
+		for i, err := range errs {
+			if err != nil {
+				bakeError.Foundations = append(bakeError.Foundations, names[i])
+				bakeError.Errors = append(bakeError.Errors, err)
+			}
+		}
+		if len(bakeError.Errors) == 0 {
+			continue
+		}
+
+		bg.Log.Errorf("%s failed to bake: rolling back %s", strings.Join(bakeError.Foundations, ", "), strings.Join(names, ", "))
+
+		bakeError.RollbackErrors = bg.commands(baked, func(action I.Action) error {
+			return action.(I.BakedAction).RollBack()
+		})
+		return bakeError
+	}
+
+	bg.Log.Infof("finished baking %s", strings.Join(names, ", "))
+
+	finishErrors := bg.commands(baked, func(action I.Action) error {
+		return action.(I.BakedAction).FinishBake()
+	})
+	if len(finishErrors) != 0 {
+		return FinishBakeError{Errors: finishErrors}
+	}
+
+	return nil
+}
@@ -12,24 +12,43 @@ import (
 )
 
 // BlueGreen has a PushManager to creater pushers for blue green deployments.
-type BlueGreenConstructor func(log I.DeploymentLogger) I.BlueGreener
+type BlueGreenConstructor func(log I.DeploymentLogger, eventManager I.EventManager) I.BlueGreener
 
-func NewBlueGreen(log I.DeploymentLogger) I.BlueGreener {
+func NewBlueGreen(log I.DeploymentLogger, eventManager I.EventManager) I.BlueGreener {
 	return &BlueGreen{
-		Log: log,
+		Log:          log,
+		EventManager: eventManager,
 	}
 }
 
 type BlueGreen struct {
-	Log I.DeploymentLogger
+	Log          I.DeploymentLogger
+	EventManager I.EventManager
 }
 
 // Push will login to all the Cloud Foundry instances provided in the Config and then push the application to all the instances concurrently.
 // If the application fails to start in any of the instances it handles rolling back the application in every instance, unless it is the first deploy.
+// Phases that run longer than the timeouts of the environment fail with a PhaseTimeoutError or RequestTimeoutError and are rolled back the same way.
 func (bg BlueGreen) Execute(actionCreator I.ActionCreator, environment S.Environment, response io.ReadWriter) error {
 
 	actors := make([]actor, len(environment.Foundations))
 	buffers := make([]*bytes.Buffer, len(environment.Foundations))
+	names := make([]string, len(environment.Foundations))
+
+	for i, foundationURL := range environment.Foundations {
+		names[i] = environment.GetFoundation(foundationURL).GetName()
+	}
+
+	var waves [][]int
+	if environment.Strategy == S.RollingStrategy {
+		var err error
+		waves, err = rollingWaves(environment, names)
+		if err != nil {
+			return InitializationError{err}
+		}
+	}
+
+	deadline := requestDeadline(environment.Timeouts)
 
 	for i, foundationURL := range environment.Foundations {
 		buffers[i] = &bytes.Buffer{}
@@ -38,15 +57,29 @@ func (bg BlueGreen) Execute(actionCreator I.ActionCreator, environment S.Environ
 		if err != nil {
 			return InitializationError{err}
 		}
-		defer action.Finally()
+		timed := newTimedAction(action, environment.Timeouts, deadline)
+		defer timed.Finally()
 
-		actors[i] = NewActor(action)
+		actors[i] = NewActor(timed)
+		actors[i].name = names[i]
+		_, actors[i].baked = action.(I.BakedAction)
 		defer close(actors[i].Commands)
 	}
 
 	defer func() {
-		for _, buffer := range buffers {
+		waveOf := make(map[int]int)
+		for w, wave := range waves {
+			for _, i := range wave {
+				waveOf[i] = w + 1
+			}
+		}
+
+		for i, buffer := range buffers {
 			fmt.Fprintf(response, "\n%s Cloud Foundry Output %s\n", strings.Repeat("-", 19), strings.Repeat("-", 19))
+			fmt.Fprintf(response, "Foundation: %s\n", names[i])
+			if wave, ok := waveOf[i]; ok {
+				fmt.Fprintf(response, "Wave: %d of %d\n", wave, len(waves))
+			}
 			buffer.WriteTo(response)
 		}
 
@@ -60,6 +93,18 @@ func (bg BlueGreen) Execute(actionCreator I.ActionCreator, environment S.Environ
 		return actionCreator.InitiallyError(initLoginError)
 	}
 
+	switch environment.Strategy {
+	case S.CanaryStrategy:
+		return bg.executeCanary(actors, names, environment, actionCreator)
+	case S.RollingStrategy:
+		return bg.executeRolling(actors, names, waves, environment, actionCreator)
+	}
+
+	return bg.execute(actors, names, environment, actionCreator)
+}
+
+// execute runs every step of the action against all foundations at once.
+func (bg BlueGreen) execute(actors []actor, names []string, environment S.Environment, actionCreator I.ActionCreator) error {
 	loginErrors := bg.commands(actors, func(action I.Action) error {
 		return action.Initially()
 	})
@@ -68,6 +113,15 @@ func (bg BlueGreen) Execute(actionCreator I.ActionCreator, environment S.Environ
 		return actionCreator.InitiallyError(loginErrors)
 	}
 
+	err := bg.verify(actors)
+	if err != nil {
+		return err
+	}
+
+	if environment.RequiredSuccesses() < len(actors) {
+		return bg.executePartial(actors, names, environment, actionCreator)
+	}
+
 	actionErrors := bg.commands(actors, func(action I.Action) error {
 		return action.Execute()
 	})
@@ -84,6 +138,11 @@ func (bg BlueGreen) Execute(actionCreator I.ActionCreator, environment S.Environ
 		return bg.processErrors(actionErrors, actors, actionCreator)
 	}
 
+	return bg.success(actors, environment, actionCreator)
+}
+
+// success finishes the action on the actors and bakes them when the environment bakes.
+func (bg BlueGreen) success(actors []actor, environment S.Environment, actionCreator I.ActionCreator) error {
 	finishActionErrors := bg.commands(actors, func(action I.Action) error {
 		return action.Success()
 	})
@@ -91,6 +150,19 @@ func (bg BlueGreen) Execute(actionCreator I.ActionCreator, environment S.Environ
 		return actionCreator.SuccessError(finishActionErrors)
 	}
 
+	return bg.bakeCutover(actors, environment.Bake)
+}
+
+// verify runs the pre-flight checks of the action against all foundations once they are logged in,
+// so that nothing is pushed when any of them would fail.
+func (bg BlueGreen) verify(actors []actor) error {
+	verifyErrors := bg.commands(actors, func(action I.Action) error {
+		return action.Verify()
+	})
+	if len(verifyErrors) != 0 {
+		bg.Log.Errorf("pre-flight checks failed on %d foundations", len(verifyErrors))
+		return VerifyError{VerifyErrors: verifyErrors}
+	}
 	return nil
 }
 
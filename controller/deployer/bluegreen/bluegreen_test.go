@@ -2,6 +2,7 @@ package bluegreen_test
 
 import (
 	"errors"
+	"time"
 
 	. "github.com/compozed/deployadactyl/controller/deployer/bluegreen"
 	"github.com/compozed/deployadactyl/mocks"
@@ -20,8 +21,6 @@ import (
 var _ = Describe("Bluegreen", func() {
 
 	var (
-		appName            string
-		appPath            string
 		pushOutput         string
 		loginOutput        string
 		routeMappingOutput string
@@ -30,7 +29,6 @@ var _ = Describe("Bluegreen", func() {
 		log                interfaces.DeploymentLogger
 		blueGreen          BlueGreen
 		environment        S.Environment
-		deploymentInfo     S.DeploymentInfo
 		response           *Buffer
 		logBuffer          *Buffer
 		pushError          = errors.New("push error")
@@ -38,8 +36,6 @@ var _ = Describe("Bluegreen", func() {
 	)
 
 	BeforeEach(func() {
-		appName = "appName-" + randomizer.StringRunes(10)
-		appPath = "appPath-" + randomizer.StringRunes(10)
 		pushOutput = "pushOutput-" + randomizer.StringRunes(10)
 		loginOutput = "loginOutput-" + randomizer.StringRunes(10)
 		routeMappingOutput = "routeMappingOutput-" + randomizer.StringRunes(10)
@@ -52,8 +48,6 @@ var _ = Describe("Bluegreen", func() {
 		environment.Foundations = []string{randomizer.StringRunes(10), randomizer.StringRunes(10)}
 		environment.DisableRollback = false
 
-		deploymentInfo = S.DeploymentInfo{AppName: appName}
-
 		pusherCreator = &mocks.PushManager{}
 
 		pushers = nil
@@ -157,6 +151,31 @@ var _ = Describe("Bluegreen", func() {
 		})
 	})
 
+	Context("when the pre-flight checks fail on any foundation", func() {
+		It("returns a VerifyError without pushing to any foundation", func() {
+			verifyError := errors.New("stack cflinuxfs2 does not exist")
+			pushers[1].VerifyCall.Returns.Error = verifyError
+
+			err := blueGreen.Execute(pusherCreator, environment, response)
+
+			Expect(err).To(MatchError(VerifyError{VerifyErrors: []error{verifyError}}))
+			for _, pusher := range pushers {
+				Expect(pusher.ExecuteCall.TimesCalled).To(Equal(0))
+				Expect(pusher.UndoCall.TimesCalled).To(Equal(0))
+			}
+		})
+
+		It("does not deploy to the canary", func() {
+			environment.Strategy = S.CanaryStrategy
+			pushers[0].VerifyCall.Returns.Error = errors.New("org quota exceeded")
+
+			err := blueGreen.Execute(pusherCreator, environment, response)
+
+			Expect(err).To(BeAssignableToTypeOf(VerifyError{}))
+			Expect(pushers[0].ExecuteCall.TimesCalled).To(Equal(0))
+		})
+	})
+
 	Context("when all push commands are successful", func() {
 		It("can push an app to a single foundation", func() {
 			By("setting a single foundation")
@@ -342,6 +361,369 @@ var _ = Describe("Bluegreen", func() {
 		})
 	})
 
+	Context("when a phase runs longer than its timeout", func() {
+		It("fails the foundation with a timeout and rolls back every foundation", func() {
+			environment.Timeouts = S.Timeouts{Phases: map[string]string{"execute": "10ms"}}
+			pushers[1].ExecuteCall.Sleep = time.Second
+
+			err := blueGreen.Execute(pusherCreator, environment, response)
+
+			Expect(err).To(MatchError(PushError{[]error{PhaseTimeoutError{Phase: "execute", After: 10 * time.Millisecond}}}))
+			Expect(pushers[0].UndoCall.TimesCalled).To(Equal(1))
+			Expect(pushers[1].UndoCall.TimesCalled).To(Equal(1))
+			Expect(pushers[0].SuccessCall.TimesCalled).To(Equal(0))
+		})
+
+		It("interrupts the phase and waits for it to return before rolling back", func() {
+			environment.Timeouts = S.Timeouts{Phases: map[string]string{"execute": "10ms"}}
+			pushers[1].ExecuteCall.Sleep = time.Minute
+
+			start := time.Now()
+			err := blueGreen.Execute(pusherCreator, environment, response)
+
+			Expect(err).To(MatchError(PushError{[]error{PhaseTimeoutError{Phase: "execute", After: 10 * time.Millisecond}}}))
+			Expect(pushers[1].InterruptCall.TimesCalled).To(Equal(1))
+			Expect(pushers[1].UndoCall.TimesCalled).To(Equal(1))
+			Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+		})
+	})
+
+	Context("when the request runs longer than its timeout", func() {
+		It("fails the foundation with a request timeout and still rolls back", func() {
+			environment.Timeouts = S.Timeouts{Request: "10ms", Phases: map[string]string{"execute": "1m"}}
+			pushers[0].ExecuteCall.Sleep = time.Second
+
+			err := blueGreen.Execute(pusherCreator, environment, response)
+
+			Expect(err).To(MatchError(PushError{[]error{RequestTimeoutError{Phase: "execute", After: 10 * time.Millisecond}}}))
+			Expect(pushers[0].UndoCall.TimesCalled).To(Equal(1))
+			Expect(pushers[1].UndoCall.TimesCalled).To(Equal(1))
+		})
+	})
+
+	Context("when the environment bakes", func() {
+		BeforeEach(func() {
+			environment.Bake = S.Bake{Duration: "50ms", Interval: "10ms"}
+		})
+
+		It("bakes every foundation after the cutover until the bake window has passed", func() {
+			err := blueGreen.Execute(pusherCreator, environment, response)
+
+			Expect(err).ToNot(HaveOccurred())
+			for _, pusher := range pushers {
+				Expect(pusher.SuccessCall.TimesCalled).To(Equal(1))
+				Expect(pusher.BakeCall.TimesCalled).To(BeNumerically(">=", 4))
+				Expect(pusher.FinishBakeCall.TimesCalled).To(Equal(1))
+				Expect(pusher.RollBackCall.TimesCalled).To(Equal(0))
+			}
+			Eventually(logBuffer).Should(Say("baking %s, %s for 50ms", environment.Foundations[0], environment.Foundations[1]))
+		})
+
+		It("rolls every foundation back when one of them fails its bake", func() {
+			pushers[1].BakeCall.Returns.Errors = []error{nil, errors.New("2 of 2 instances are crashed or down")}
+
+			err := blueGreen.Execute(pusherCreator, environment, response)
+
+			Expect(err).To(MatchError(BakeError{
+				Foundations: []string{environment.Foundations[1]},
+				Errors:      []error{errors.New("2 of 2 instances are crashed or down")},
+			}))
+			Expect(err.Error()).To(ContainSubstring("rolled back to the previous version"))
+			for _, pusher := range pushers {
+				Expect(pusher.BakeCall.TimesCalled).To(Equal(2))
+				Expect(pusher.RollBackCall.TimesCalled).To(Equal(1))
+				Expect(pusher.FinishBakeCall.TimesCalled).To(Equal(0))
+				Expect(pusher.UndoCall.TimesCalled).To(Equal(0))
+			}
+		})
+
+		It("returns the rollback errors when rolling back fails", func() {
+			pushers[0].BakeCall.Returns.Errors = []error{errors.New("unhealthy")}
+			pushers[0].RollBackCall.Returns.Error = rollbackError
+
+			err := blueGreen.Execute(pusherCreator, environment, response)
+
+			Expect(err).To(MatchError(ContainSubstring("bake failed: %s: unhealthy: rollback failed: rollback error", environment.Foundations[0])))
+		})
+
+		It("does not bake when the push fails", func() {
+			pushers[0].ExecuteCall.Returns.Error = pushError
+
+			blueGreen.Execute(pusherCreator, environment, response)
+
+			Expect(pushers[0].BakeCall.TimesCalled).To(Equal(0))
+			Expect(pushers[1].BakeCall.TimesCalled).To(Equal(0))
+		})
+	})
+
+	Context("when the strategy is canary", func() {
+		BeforeEach(func() {
+			environment.Strategy = S.CanaryStrategy
+			environment.Canary = S.Canary{Foundation: environment.Foundations[1]}
+		})
+
+		It("deploys to the canary before the rest of the foundations", func() {
+			Expect(blueGreen.Execute(pusherCreator, environment, response)).To(Succeed())
+
+			for _, pusher := range pushers {
+				Expect(pusher.InitiallyCall.TimesCalled).To(BeNumerically(">=", 1))
+				Expect(pusher.ExecuteCall.TimesCalled).To(Equal(1))
+				Expect(pusher.PostExecuteCall.TimesCalled).To(Equal(1))
+				Expect(pusher.SuccessCall.TimesCalled).To(Equal(1))
+			}
+			Expect(logBuffer).To(Say("deploying to canary foundation " + environment.Foundations[1]))
+		})
+
+		It("only undoes the canary when the canary fails", func() {
+			pushers[1].ExecuteCall.Returns.Error = pushError
+
+			err := blueGreen.Execute(pusherCreator, environment, response)
+
+			Expect(err).To(MatchError(PushError{[]error{pushError}}))
+			Expect(pushers[0].ExecuteCall.TimesCalled).To(Equal(0))
+			Expect(pushers[0].UndoCall.TimesCalled).To(Equal(0))
+			Expect(pushers[1].UndoCall.TimesCalled).To(Equal(1))
+		})
+
+		It("health checks the canary while it bakes", func() {
+			environment.Canary.BakeTime = "30ms"
+			environment.Canary.HealthCheckInterval = "10ms"
+
+			Expect(blueGreen.Execute(pusherCreator, environment, response)).To(Succeed())
+
+			Expect(pushers[1].HealthCheckCall.TimesCalled).To(BeNumerically(">=", 2))
+			Expect(pushers[0].HealthCheckCall.TimesCalled).To(Equal(0))
+		})
+
+		It("only undoes the canary when it fails its health check while baking", func() {
+			environment.Canary.BakeTime = "20ms"
+			environment.Canary.HealthCheckInterval = "10ms"
+			pushers[1].HealthCheckCall.Returns.Error = errors.New("unhealthy")
+
+			err := blueGreen.Execute(pusherCreator, environment, response)
+
+			Expect(err).To(MatchError(PushError{[]error{CanaryHealthCheckError{Foundation: environment.Foundations[1], Err: errors.New("unhealthy")}}}))
+			Expect(pushers[0].ExecuteCall.TimesCalled).To(Equal(0))
+			Expect(pushers[0].UndoCall.TimesCalled).To(Equal(0))
+			Expect(pushers[1].UndoCall.TimesCalled).To(Equal(1))
+		})
+
+		It("undoes every foundation when the rest of the foundations fail", func() {
+			pushers[0].ExecuteCall.Returns.Error = pushError
+
+			err := blueGreen.Execute(pusherCreator, environment, response)
+
+			Expect(err).To(MatchError(PushError{[]error{pushError}}))
+			Expect(pushers[0].UndoCall.TimesCalled).To(Equal(1))
+			Expect(pushers[1].UndoCall.TimesCalled).To(Equal(1))
+		})
+
+		It("uses the first foundation when no canary is configured", func() {
+			environment.Canary = S.Canary{}
+			pushers[0].ExecuteCall.Returns.Error = pushError
+
+			blueGreen.Execute(pusherCreator, environment, response)
+
+			Expect(pushers[1].ExecuteCall.TimesCalled).To(Equal(0))
+		})
+
+		It("returns an error when the canary foundation does not exist", func() {
+			environment.Canary = S.Canary{Foundation: "nowhere"}
+
+			err := blueGreen.Execute(pusherCreator, environment, response)
+
+			Expect(err).To(MatchError(InitializationError{CanaryFoundationNotFoundError{"nowhere"}}))
+			Expect(pushers[0].ExecuteCall.TimesCalled).To(Equal(0))
+		})
+	})
+
+	Context("when the strategy is rolling", func() {
+		var eventManager *mocks.EventManager
+
+		BeforeEach(func() {
+			environment.Foundations = append(environment.Foundations, randomizer.StringRunes(10))
+			pusher := &mocks.Pusher{Response: response}
+			pushers = append(pushers, pusher)
+			pusherCreator.CreatePusherCall.Returns.Pushers = append(pusherCreator.CreatePusherCall.Returns.Pushers, pusher)
+			pusherCreator.CreatePusherCall.Returns.Error = append(pusherCreator.CreatePusherCall.Returns.Error, nil)
+
+			environment.Strategy = S.RollingStrategy
+			environment.Rolling = S.Rolling{MaxParallelFoundations: 2}
+
+			eventManager = &mocks.EventManager{}
+			blueGreen = BlueGreen{Log: log, EventManager: eventManager}
+		})
+
+		It("deploys the foundations in waves and shows the wave of each foundation", func() {
+			Expect(blueGreen.Execute(pusherCreator, environment, response)).To(Succeed())
+
+			for _, pusher := range pushers {
+				Expect(pusher.ExecuteCall.TimesCalled).To(Equal(1))
+				Expect(pusher.SuccessCall.TimesCalled).To(Equal(1))
+			}
+			Expect(response).To(Say("Foundation: " + environment.Foundations[0] + "\nWave: 1 of 2"))
+			Expect(response).To(Say("Foundation: " + environment.Foundations[1] + "\nWave: 1 of 2"))
+			Expect(response).To(Say("Foundation: " + environment.Foundations[2] + "\nWave: 2 of 2"))
+
+			Expect(eventManager.EmitEventCall.Received.Events).To(Equal([]interfaces.IEvent{
+				WaveStartedEvent{Wave: 1, Waves: 2, Foundations: environment.Foundations[:2], Environment: environment, Log: log},
+				WaveFinishedEvent{Wave: 1, Waves: 2, Foundations: environment.Foundations[:2], Environment: environment, Log: log},
+				WaveStartedEvent{Wave: 2, Waves: 2, Foundations: environment.Foundations[2:], Environment: environment, Log: log},
+				WaveFinishedEvent{Wave: 2, Waves: 2, Foundations: environment.Foundations[2:], Environment: environment, Log: log},
+			}))
+		})
+
+		It("deploys explicit wave groups and puts unlisted foundations in a last wave", func() {
+			environment.Rolling = S.Rolling{Waves: [][]string{{environment.Foundations[2]}}}
+			pushers[2].ExecuteCall.Returns.Error = pushError
+
+			err := blueGreen.Execute(pusherCreator, environment, response)
+
+			Expect(err).To(MatchError(PushError{[]error{pushError}}))
+			Expect(pushers[0].ExecuteCall.TimesCalled).To(Equal(0))
+			Expect(pushers[1].ExecuteCall.TimesCalled).To(Equal(0))
+			Expect(response).To(Say("Foundation: " + environment.Foundations[0] + "\nWave: 2 of 2"))
+		})
+
+		It("stops on the first failing wave and undoes every deployed foundation", func() {
+			pushers[2].PostExecuteCall.Returns.Error = pushError
+
+			err := blueGreen.Execute(pusherCreator, environment, response)
+
+			Expect(err).To(MatchError(PushError{[]error{pushError}}))
+			for _, pusher := range pushers {
+				Expect(pusher.UndoCall.TimesCalled).To(Equal(1))
+				Expect(pusher.SuccessCall.TimesCalled).To(Equal(0))
+			}
+			Expect(eventManager.EmitEventCall.Received.Events[3].(WaveFinishedEvent).Error).To(MatchError(PushError{[]error{pushError}}))
+		})
+
+		It("only undoes the failing wave when the rollback policy is failed-wave", func() {
+			environment.Rolling.Rollback = S.RollbackFailedWave
+			pushers[2].ExecuteCall.Returns.Error = pushError
+
+			err := blueGreen.Execute(pusherCreator, environment, response)
+
+			Expect(err).To(MatchError(PushError{[]error{pushError}}))
+			Expect(pushers[0].SuccessCall.TimesCalled).To(Equal(1))
+			Expect(pushers[0].UndoCall.TimesCalled).To(Equal(0))
+			Expect(pushers[2].UndoCall.TimesCalled).To(Equal(1))
+			Expect(pushers[2].SuccessCall.TimesCalled).To(Equal(0))
+		})
+
+		It("pauses between waves", func() {
+			environment.Rolling.Pause = "20ms"
+
+			Expect(blueGreen.Execute(pusherCreator, environment, response)).To(Succeed())
+
+			Expect(logBuffer).To(Say("pausing 20ms before wave 2"))
+		})
+
+		It("returns an error when a wave names an unknown foundation", func() {
+			environment.Rolling = S.Rolling{Waves: [][]string{{"nowhere"}}}
+
+			err := blueGreen.Execute(pusherCreator, environment, response)
+
+			Expect(err).To(MatchError(InitializationError{WaveFoundationNotFoundError{"nowhere"}}))
+			Expect(pusherCreator.CreatePusherCall.TimesCalled).To(Equal(0))
+		})
+	})
+
+	Context("when the success policy is quorum", func() {
+		var eventManager *mocks.EventManager
+
+		BeforeEach(func() {
+			environment.Foundations = append(environment.Foundations, randomizer.StringRunes(10))
+			pusher := &mocks.Pusher{Response: response}
+			pushers = append(pushers, pusher)
+			pusherCreator.CreatePusherCall.Returns.Pushers = append(pusherCreator.CreatePusherCall.Returns.Pushers, pusher)
+			pusherCreator.CreatePusherCall.Returns.Error = append(pusherCreator.CreatePusherCall.Returns.Error, nil)
+
+			environment.SuccessPolicy = S.SuccessPolicyQuorum
+
+			eventManager = &mocks.EventManager{}
+			blueGreen = BlueGreen{Log: log, EventManager: eventManager}
+		})
+
+		It("succeeds when every foundation succeeds", func() {
+			Expect(blueGreen.Execute(pusherCreator, environment, response)).To(Succeed())
+
+			for _, pusher := range pushers {
+				Expect(pusher.SuccessCall.TimesCalled).To(Equal(1))
+				Expect(pusher.UndoCall.TimesCalled).To(Equal(0))
+			}
+			Expect(eventManager.EmitEventCall.Received.Events).To(BeEmpty())
+		})
+
+		It("accepts the deployment and undoes only the failed foundation when a quorum succeeds", func() {
+			pushers[1].PostExecuteCall.Returns.Error = pushError
+
+			err := blueGreen.Execute(pusherCreator, environment, response)
+
+			expected := PartialSuccessError{
+				Succeeded:            []string{environment.Foundations[0], environment.Foundations[2]},
+				Failed:               []string{environment.Foundations[1]},
+				FailedFoundationURLs: []string{environment.Foundations[1]},
+				Errors:               []error{pushError},
+			}
+			Expect(err).To(Equal(expected))
+			Expect(pushers[1].UndoCall.TimesCalled).To(Equal(1))
+			Expect(pushers[1].SuccessCall.TimesCalled).To(Equal(0))
+			for _, pusher := range []*mocks.Pusher{pushers[0], pushers[2]} {
+				Expect(pusher.UndoCall.TimesCalled).To(Equal(0))
+				Expect(pusher.SuccessCall.TimesCalled).To(Equal(1))
+			}
+
+			Expect(eventManager.EmitEventCall.Received.Events).To(Equal([]interfaces.IEvent{
+				PartialSuccessEvent{
+					Environment:          environment,
+					Succeeded:            expected.Succeeded,
+					Failed:               expected.Failed,
+					FailedFoundationURLs: expected.FailedFoundationURLs,
+					Errors:               expected.Errors,
+					Log:                  log,
+				},
+			}))
+		})
+
+		It("does not post execute foundations that failed to execute", func() {
+			pushers[0].ExecuteCall.Returns.Error = pushError
+
+			err := blueGreen.Execute(pusherCreator, environment, response)
+
+			Expect(err).To(BeAssignableToTypeOf(PartialSuccessError{}))
+			Expect(pushers[0].PostExecuteCall.TimesCalled).To(Equal(0))
+			Expect(pushers[1].PostExecuteCall.TimesCalled).To(Equal(1))
+		})
+
+		It("undoes every foundation when fewer than a quorum succeed", func() {
+			pushers[0].ExecuteCall.Returns.Error = pushError
+			pushers[2].ExecuteCall.Returns.Error = pushError
+
+			err := blueGreen.Execute(pusherCreator, environment, response)
+
+			Expect(err).To(MatchError(PushError{[]error{pushError, pushError}}))
+			for _, pusher := range pushers {
+				Expect(pusher.UndoCall.TimesCalled).To(Equal(1))
+				Expect(pusher.SuccessCall.TimesCalled).To(Equal(0))
+			}
+			Expect(eventManager.EmitEventCall.Received.Events).To(BeEmpty())
+		})
+
+		It("requires min_success foundations when the success policy is min_success", func() {
+			environment.SuccessPolicy = S.SuccessPolicyMinSuccess
+			environment.MinSuccess = 1
+			pushers[0].ExecuteCall.Returns.Error = pushError
+			pushers[2].ExecuteCall.Returns.Error = pushError
+
+			err := blueGreen.Execute(pusherCreator, environment, response)
+
+			Expect(err).To(BeAssignableToTypeOf(PartialSuccessError{}))
+			Expect(err.(PartialSuccessError).Succeeded).To(Equal([]string{environment.Foundations[1]}))
+			Expect(pushers[1].SuccessCall.TimesCalled).To(Equal(1))
+		})
+	})
+
 	Describe("Stop", func() {
 		Context("when called", func() {
 			It("creates a stopper for each foundation", func() {
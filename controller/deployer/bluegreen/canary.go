@@ -0,0 +1,121 @@
+package bluegreen
+
+import (
+	"time"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+// executeCanary logs in to all foundations and then runs Execute and PostExecute against the canary
+// foundation alone. The canary is health checked until its bake time is over before the remaining
+// foundations are deployed. If the canary fails only the canary is undone.
+func (bg BlueGreen) executeCanary(actors []actor, names []string, environment S.Environment, actionCreator I.ActionCreator) error {
+	canaryIndex := -1
+	for i, foundationURL := range environment.Foundations {
+		if environment.Canary.Foundation == "" || environment.Canary.Foundation == foundationURL || environment.Canary.Foundation == names[i] {
+			canaryIndex = i
+			break
+		}
+	}
+	if canaryIndex < 0 {
+		return InitializationError{CanaryFoundationNotFoundError{environment.Canary.Foundation}}
+	}
+
+	bakeTime, err := environment.Canary.GetBakeTime()
+	if err != nil {
+		return InitializationError{err}
+	}
+	interval, err := environment.Canary.GetHealthCheckInterval()
+	if err != nil {
+		return InitializationError{err}
+	}
+
+	loginErrors := bg.commands(actors, func(action I.Action) error {
+		return action.Initially()
+	})
+	if len(loginErrors) != 0 {
+		return actionCreator.InitiallyError(loginErrors)
+	}
+
+	err = bg.verify(actors)
+	if err != nil {
+		return err
+	}
+
+	canary := []actor{actors[canaryIndex]}
+	rest := append(append([]actor{}, actors[:canaryIndex]...), actors[canaryIndex+1:]...)
+
+	bg.Log.Infof("deploying to canary foundation %s", names[canaryIndex])
+
+	actionErrors := bg.commands(canary, func(action I.Action) error {
+		return action.Execute()
+	})
+	if len(actionErrors) != 0 {
+		return bg.processErrors(actionErrors, canary, actionCreator)
+	}
+
+	actionErrors = bg.commands(canary, func(action I.Action) error {
+		return action.PostExecute()
+	})
+	if len(actionErrors) != 0 {
+		return bg.processErrors(actionErrors, canary, actionCreator)
+	}
+
+	err = bg.bake(canary[0], names[canaryIndex], bakeTime, interval)
+	if err != nil {
+		return bg.processErrors([]error{err}, canary, actionCreator)
+	}
+
+	bg.Log.Infof("canary foundation %s is healthy: deploying to the remaining foundations", names[canaryIndex])
+
+	actionErrors = bg.commands(rest, func(action I.Action) error {
+		return action.Execute()
+	})
+	if len(actionErrors) != 0 {
+		return bg.processErrors(actionErrors, actors, actionCreator)
+	}
+
+	actionErrors = bg.commands(rest, func(action I.Action) error {
+		return action.PostExecute()
+	})
+	if len(actionErrors) != 0 {
+		return bg.processErrors(actionErrors, actors, actionCreator)
+	}
+
+	return bg.success(actors, environment, actionCreator)
+}
+
+// bake health checks the canary every interval until bakeTime has passed.
+func (bg BlueGreen) bake(canary actor, name string, bakeTime, interval time.Duration) error {
+	if bakeTime <= 0 {
+		return nil
+	}
+
+	bg.Log.Infof("baking canary foundation %s for %s", name, bakeTime)
+
+	deadline := time.Now().Add(bakeTime)
+	for {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return nil
+		}
+
+		wait := interval
+		if remaining < wait {
+			wait = remaining
+		}
+		time.Sleep(wait)
+
+		errs := bg.commands([]actor{canary}, func(action I.Action) error {
+			if checked, ok := action.(I.HealthCheckedAction); ok {
+				return checked.HealthCheck()
+			}
+			return nil
+		})
+		if len(errs) != 0 {
+			bg.Log.Errorf("canary foundation %s failed its health check while baking", name)
+			return CanaryHealthCheckError{Foundation: name, Err: errs[0]}
+		}
+	}
+}
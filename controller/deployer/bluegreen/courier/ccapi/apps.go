@@ -0,0 +1,650 @@
+package ccapi
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry-incubator/candiedyaml"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+// routeKeys are the manifest attributes that choose the routes of an application. They are replaced by
+// the route of the hostname, like cf push -n does.
+var routeKeys = []string{"routes", "host", "hosts", "domain", "domains", "no-hostname", "random-route"}
+
+// Push applies the manifest.yml of the application directory, uploads the directory as a package,
+// stages it and restarts the application with the new droplet.
+//
+// The application of the manifest with the name of the application is pushed, or the only one when
+// none has the name. It gets the route of the hostname on the default domain of the org.
+func (c *Courier) Push(appName, appLocation, hostname string, instances uint16) ([]byte, error) {
+	return c.run("push", func(ctx context.Context, out io.Writer) error {
+		return c.push(ctx, out, appName, appLocation, hostname, instances, "", true)
+	})
+}
+
+// PushWithStrategy pushes like Push, but replaces the droplet of a running application with a
+// deployment of the strategy, such as rolling. A new application is started like Push starts it.
+func (c *Courier) PushWithStrategy(appName, appLocation, hostname string, instances uint16, strategy string) ([]byte, error) {
+	return c.run("push", func(ctx context.Context, out io.Writer) error {
+		return c.push(ctx, out, appName, appLocation, hostname, instances, strategy, true)
+	})
+}
+
+// PushWithoutStart applies the manifest and uploads the package like Push, but leaves the application
+// stopped. Start stages the package, so that the services bound in the meantime are seen by staging.
+func (c *Courier) PushWithoutStart(appName, appLocation, hostname string, instances uint16) ([]byte, error) {
+	return c.run("push", func(ctx context.Context, out io.Writer) error {
+		return c.push(ctx, out, appName, appLocation, hostname, instances, "", false)
+	})
+}
+
+func (c *Courier) push(ctx context.Context, out io.Writer, appName, appLocation, hostname string, instances uint16, strategy string, start bool) error {
+	fmt.Fprintf(out, "Pushing app %s to org %s / space %s as %s...\n", appName, c.org.Name, c.space.Name, c.username)
+
+	manifest, err := c.manifest(ctx, appName, appLocation, hostname, instances)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out, "Applying manifest...")
+	location, err := c.callWithBody(ctx, "POST", fmt.Sprintf("/v3/spaces/%s/actions/apply_manifest", c.space.GUID), "application/x-yaml", bytesBody(manifest), nil)
+	if err != nil {
+		return err
+	}
+	if err = c.waitForJob(ctx, "apply manifest", location); err != nil {
+		return err
+	}
+
+	app, err := c.app(ctx, appName)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out, "Uploading files...")
+	pkg, err := c.uploadPackage(ctx, app.GUID, appLocation)
+	if err != nil {
+		return err
+	}
+	if !start {
+		return nil
+	}
+
+	fmt.Fprintln(out, "Staging app...")
+	droplet, err := c.stage(ctx, pkg)
+	if err != nil {
+		return err
+	}
+
+	if strategy != "" && app.State == "STARTED" {
+		fmt.Fprintf(out, "Starting deployment with strategy %s...\n", strategy)
+		return c.deploy(ctx, app.GUID, droplet, strategy)
+	}
+
+	fmt.Fprintln(out, "Starting app...")
+	return c.startDroplet(ctx, app, droplet)
+}
+
+// manifest returns the manifest to apply for the application.
+func (c *Courier) manifest(ctx context.Context, appName, appLocation, hostname string, instances uint16) ([]byte, error) {
+	application := map[interface{}]interface{}{}
+
+	data, err := c.FileSystem.ReadFile(filepath.Join(appLocation, "manifest.yml"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		var manifest struct {
+			Applications []map[interface{}]interface{} `yaml:"applications"`
+		}
+		if err = candiedyaml.Unmarshal(data, &manifest); err != nil {
+			return nil, fmt.Errorf("cannot read the manifest of %s: %s", appName, err)
+		}
+
+		for _, candidate := range manifest.Applications {
+			if candidate["name"] == appName {
+				application = candidate
+			}
+		}
+		if len(application) == 0 && len(manifest.Applications) == 1 {
+			application = manifest.Applications[0]
+		}
+	}
+
+	noRoute := application["no-route"] == true
+	for _, key := range append(routeKeys, "path") {
+		delete(application, key)
+	}
+	if buildpack, ok := application["buildpack"]; ok {
+		delete(application, "buildpack")
+		if _, ok := application["buildpacks"]; !ok {
+			application["buildpacks"] = []interface{}{buildpack}
+		}
+	}
+
+	application["name"] = appName
+	application["instances"] = int(instances)
+
+	if hostname != "" && !noRoute {
+		var domain resource
+		_, err = c.call(ctx, "GET", fmt.Sprintf("/v3/organizations/%s/domains/default", c.org.GUID), nil, &domain)
+		if err != nil {
+			return nil, err
+		}
+		application["routes"] = []interface{}{
+			map[interface{}]interface{}{"route": hostname + "." + domain.Name},
+		}
+	}
+
+	return candiedyaml.Marshal(map[interface{}]interface{}{
+		"applications": []interface{}{application},
+	})
+}
+
+// uploadPackage uploads the application directory as the bits of a new package and waits until the
+// package is ready. The zip of the directory is written to a temporary file, so that it is not held in
+// memory and can be sent again when the token is refreshed.
+func (c *Courier) uploadPackage(ctx context.Context, appGUID, appLocation string) (string, error) {
+	var pkg resource
+	_, err := c.call(ctx, "POST", "/v3/packages", map[string]interface{}{
+		"type":          "bits",
+		"relationships": relationships("app", appGUID),
+	}, &pkg)
+	if err != nil {
+		return "", err
+	}
+
+	file, err := c.FileSystem.TempFile("", "deployadactyl-artifact-")
+	if err != nil {
+		return "", err
+	}
+	defer c.FileSystem.Remove(file.Name())
+
+	form := multipart.NewWriter(file)
+	part, err := form.CreateFormFile("bits", "application.zip")
+	if err == nil {
+		err = c.zip(appLocation, part)
+	}
+	if err == nil {
+		err = form.Close()
+	}
+	file.Close()
+	if err != nil {
+		return "", err
+	}
+
+	body := func() (io.Reader, error) { return c.FileSystem.Open(file.Name()) }
+	_, err = c.callWithBody(ctx, "POST", fmt.Sprintf("/v3/packages/%s/upload", pkg.GUID), form.FormDataContentType(), body, nil)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		_, err = c.call(ctx, "GET", "/v3/packages/"+pkg.GUID, nil, &pkg)
+		if err != nil {
+			return "", err
+		}
+
+		switch pkg.State {
+		case "READY":
+			return pkg.GUID, nil
+		case "FAILED", "EXPIRED":
+			return "", JobFailedError{"package upload", pkg.Error}
+		}
+
+		if err = c.sleep(ctx); err != nil {
+			return "", err
+		}
+	}
+}
+
+// zip writes the files of a directory to a zip archive, keeping their modes so that executables stay
+// executable. The files the .cfignore of the directory or the defaults of the cf CLI exclude are left
+// out.
+func (c *Courier) zip(directory string, w io.Writer) error {
+	ignore, err := readCFIgnore(c.FileSystem, directory)
+	if err != nil {
+		return err
+	}
+
+	archive := zip.NewWriter(w)
+
+	err = c.FileSystem.Walk(directory, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		name, err := filepath.Rel(directory, path)
+		if err != nil || name == "." {
+			return err
+		}
+		if ignore.ignored(filepath.ToSlash(name)) {
+			return nil
+		}
+
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(name)
+
+		if info.IsDir() {
+			header.Name += "/"
+			_, err = archive.CreateHeader(header)
+			return err
+		}
+
+		header.Method = zip.Deflate
+		writer, err := archive.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+
+		file, err := c.FileSystem.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		_, err = io.Copy(writer, file)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return archive.Close()
+}
+
+// stage builds a package and returns the droplet once it is staged.
+func (c *Courier) stage(ctx context.Context, packageGUID string) (string, error) {
+	var build resource
+	_, err := c.call(ctx, "POST", "/v3/builds", map[string]interface{}{
+		"package": map[string]string{"guid": packageGUID},
+	}, &build)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		switch build.State {
+		case "STAGED":
+			return build.Droplet.GUID, nil
+		case "FAILED":
+			return "", JobFailedError{"staging", build.Error}
+		}
+
+		if err = c.sleep(ctx); err != nil {
+			return "", err
+		}
+
+		_, err = c.call(ctx, "GET", "/v3/builds/"+build.GUID, nil, &build)
+		if err != nil {
+			return "", err
+		}
+	}
+}
+
+// startDroplet sets the current droplet of an application and restarts it.
+func (c *Courier) startDroplet(ctx context.Context, app resource, droplet string) error {
+	_, err := c.call(ctx, "PATCH", fmt.Sprintf("/v3/apps/%s/relationships/current_droplet", app.GUID), map[string]interface{}{
+		"data": map[string]string{"guid": droplet},
+	}, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.call(ctx, "POST", fmt.Sprintf("/v3/apps/%s/actions/restart", app.GUID), nil, nil)
+	if err != nil {
+		return err
+	}
+
+	return c.waitForInstances(ctx, app)
+}
+
+// deploy replaces the droplet of a running application with a deployment and waits until it is done.
+func (c *Courier) deploy(ctx context.Context, appGUID, droplet, strategy string) error {
+	var deployment resource
+	_, err := c.call(ctx, "POST", "/v3/deployments", map[string]interface{}{
+		"droplet":       map[string]string{"guid": droplet},
+		"strategy":      strategy,
+		"relationships": relationships("app", appGUID),
+	}, &deployment)
+	if err != nil {
+		return err
+	}
+
+	for deployment.Status.Value != "FINALIZED" {
+		if err = c.sleep(ctx); err != nil {
+			return err
+		}
+
+		_, err = c.call(ctx, "GET", "/v3/deployments/"+deployment.GUID, nil, &deployment)
+		if err != nil {
+			return err
+		}
+	}
+
+	if deployment.Status.Reason != "DEPLOYED" {
+		return JobFailedError{"deployment", strings.ToLower(deployment.Status.Reason)}
+	}
+	return nil
+}
+
+// waitForInstances waits until an instance of the web process runs. Returns an InstancesCrashedError
+// when every instance crashed. Right after a restart the process can report no instances yet, so it
+// waits for at least one.
+func (c *Courier) waitForInstances(ctx context.Context, app resource) error {
+	for {
+		instances, err := c.instances(ctx, app.GUID)
+		if err != nil {
+			return err
+		}
+
+		crashed := 0
+		for _, instance := range instances {
+			switch instance.State {
+			case "running":
+				return nil
+			case "crashed":
+				crashed++
+			}
+		}
+		if len(instances) > 0 && crashed == len(instances) {
+			return InstancesCrashedError{app.Name}
+		}
+
+		if err = c.sleep(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+// Rename renames an application.
+func (c *Courier) Rename(oldName, newName string) ([]byte, error) {
+	return c.run("rename", func(ctx context.Context, out io.Writer) error {
+		fmt.Fprintf(out, "Renaming app %s to %s in org %s / space %s as %s...\n", oldName, newName, c.org.Name, c.space.Name, c.username)
+
+		app, err := c.app(ctx, oldName)
+		if err != nil {
+			return err
+		}
+
+		_, err = c.call(ctx, "PATCH", "/v3/apps/"+app.GUID, map[string]string{"name": newName}, nil)
+		return err
+	})
+}
+
+// Delete deletes an application. An application that does not exist is not an error, like with cf delete -f.
+func (c *Courier) Delete(appName string) ([]byte, error) {
+	return c.run("delete", func(ctx context.Context, out io.Writer) error {
+		fmt.Fprintf(out, "Deleting app %s in org %s / space %s as %s...\n", appName, c.org.Name, c.space.Name, c.username)
+
+		app, err := c.app(ctx, appName)
+		if isNotFound(err) {
+			fmt.Fprintf(out, "App %s does not exist.\n", appName)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		location, err := c.call(ctx, "DELETE", "/v3/apps/"+app.GUID, nil, nil)
+		if err != nil {
+			return err
+		}
+		return c.waitForJob(ctx, "delete", location)
+	})
+}
+
+// Start starts an application and waits until an instance runs. An application pushed without
+// starting it has no droplet yet, so its latest package is staged first.
+func (c *Courier) Start(appName string) ([]byte, error) {
+	return c.run("start", func(ctx context.Context, out io.Writer) error {
+		fmt.Fprintf(out, "Starting app %s in org %s / space %s as %s...\n", appName, c.org.Name, c.space.Name, c.username)
+
+		app, err := c.app(ctx, appName)
+		if err != nil {
+			return err
+		}
+
+		_, err = c.call(ctx, "GET", fmt.Sprintf("/v3/apps/%s/droplets/current", app.GUID), nil, nil)
+		if isNotFound(err) {
+			fmt.Fprintln(out, "Staging app...")
+			pkg, err := c.find(ctx, "package of app", appName, fmt.Sprintf("/v3/apps/%s/packages?states=READY&order_by=-created_at&per_page=1", app.GUID))
+			if err != nil {
+				return err
+			}
+
+			droplet, err := c.stage(ctx, pkg.GUID)
+			if err != nil {
+				return err
+			}
+			return c.startDroplet(ctx, app, droplet)
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = c.call(ctx, "POST", fmt.Sprintf("/v3/apps/%s/actions/start", app.GUID), nil, nil)
+		if err != nil {
+			return err
+		}
+		return c.waitForInstances(ctx, app)
+	})
+}
+
+// Stop stops an application.
+func (c *Courier) Stop(appName string) ([]byte, error) {
+	return c.run("stop", func(ctx context.Context, out io.Writer) error {
+		fmt.Fprintf(out, "Stopping app %s in org %s / space %s as %s...\n", appName, c.org.Name, c.space.Name, c.username)
+
+		app, err := c.app(ctx, appName)
+		if err != nil {
+			return err
+		}
+
+		_, err = c.call(ctx, "POST", fmt.Sprintf("/v3/apps/%s/actions/stop", app.GUID), nil, nil)
+		return err
+	})
+}
+
+// Restage stages the latest package of an application again and restarts it with the new droplet.
+func (c *Courier) Restage(appName string) ([]byte, error) {
+	return c.run("restage", func(ctx context.Context, out io.Writer) error {
+		fmt.Fprintf(out, "Restaging app %s in org %s / space %s as %s...\n", appName, c.org.Name, c.space.Name, c.username)
+
+		app, err := c.app(ctx, appName)
+		if err != nil {
+			return err
+		}
+
+		pkg, err := c.find(ctx, "package of app", appName, fmt.Sprintf("/v3/apps/%s/packages?states=READY&order_by=-created_at&per_page=1", app.GUID))
+		if err != nil {
+			return err
+		}
+
+		droplet, err := c.stage(ctx, pkg.GUID)
+		if err != nil {
+			return err
+		}
+		return c.startDroplet(ctx, app, droplet)
+	})
+}
+
+// RunTask runs a task of an application and waits until it is done.
+func (c *Courier) RunTask(appName, command, name string) ([]byte, error) {
+	return c.run("run-task", func(ctx context.Context, out io.Writer) error {
+		fmt.Fprintf(out, "Creating task for app %s in org %s / space %s as %s...\n", appName, c.org.Name, c.space.Name, c.username)
+
+		app, err := c.app(ctx, appName)
+		if err != nil {
+			return err
+		}
+
+		var task resource
+		_, err = c.call(ctx, "POST", fmt.Sprintf("/v3/apps/%s/tasks", app.GUID), map[string]string{"command": command, "name": name}, &task)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Task %s has been submitted.\n", task.Name)
+
+		for {
+			switch task.State {
+			case "SUCCEEDED":
+				return nil
+			case "FAILED":
+				return JobFailedError{"task " + task.Name, task.Result.FailureReason}
+			}
+
+			if err = c.sleep(ctx); err != nil {
+				return err
+			}
+
+			_, err = c.call(ctx, "GET", "/v3/tasks/"+task.GUID, nil, &task)
+			if err != nil {
+				return err
+			}
+		}
+	})
+}
+
+// Logs returns the recent logs of an application from the log cache, oldest first.
+func (c *Courier) Logs(appName string) ([]byte, error) {
+	return c.run("logs", func(ctx context.Context, out io.Writer) error {
+		if c.logCache == "" {
+			return LogCacheNotAvailableError{}
+		}
+
+		app, err := c.app(ctx, appName)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(out, "Retrieving logs for app %s in org %s / space %s as %s...\n", appName, c.org.Name, c.space.Name, c.username)
+
+		var response struct {
+			Envelopes struct {
+				Batch []struct {
+					Timestamp  string            `json:"timestamp"`
+					InstanceID string            `json:"instance_id"`
+					Tags       map[string]string `json:"tags"`
+					Log        struct {
+						Payload string `json:"payload"`
+						Type    string `json:"type"`
+					} `json:"log"`
+				} `json:"batch"`
+			} `json:"envelopes"`
+		}
+		_, err = c.call(ctx, "GET", fmt.Sprintf("%s/api/v1/read/%s?envelope_types=LOG&descending=true&limit=1000", c.logCache, app.GUID), nil, &response)
+		if err != nil {
+			return err
+		}
+
+		batch := response.Envelopes.Batch
+		for i := len(batch) - 1; i >= 0; i-- {
+			envelope := batch[i]
+
+			nanoseconds, _ := strconv.ParseInt(envelope.Timestamp, 10, 64)
+			payload, err := base64.StdEncoding.DecodeString(envelope.Log.Payload)
+			if err != nil {
+				payload = []byte(envelope.Log.Payload)
+			}
+
+			stream := "OUT"
+			if envelope.Log.Type == "ERR" {
+				stream = "ERR"
+			}
+
+			fmt.Fprintf(out, "%s [%s/%s] %s %s\n",
+				time.Unix(0, nanoseconds).Format(time.RFC3339),
+				envelope.Tags["source_type"], envelope.InstanceID, stream,
+				strings.TrimRight(string(payload), "\n"))
+		}
+		return nil
+	})
+}
+
+// Exists returns whether the application exists in the targeted space.
+func (c *Courier) Exists(appName string) bool {
+	ctx, cancel := c.context("app")
+	defer cancel()
+
+	if c.client == nil {
+		return false
+	}
+	_, err := c.app(ctx, appName)
+	return err == nil
+}
+
+// AppInstances returns the state of every instance of the web process of an application, such as
+// running or crashed.
+func (c *Courier) AppInstances(appName string) ([]S.AppInstance, error) {
+	ctx, cancel := c.context("app")
+	defer cancel()
+
+	if c.client == nil {
+		return nil, NotLoggedInError{}
+	}
+
+	app, err := c.app(ctx, appName)
+	if err != nil {
+		return nil, c.timeout(ctx, "app", err)
+	}
+
+	instances, err := c.instances(ctx, app.GUID)
+	return instances, c.timeout(ctx, "app", err)
+}
+
+func (c *Courier) instances(ctx context.Context, appGUID string) ([]S.AppInstance, error) {
+	var stats struct {
+		Resources []struct {
+			Index  int    `json:"index"`
+			State  string `json:"state"`
+			Uptime int64  `json:"uptime"`
+		} `json:"resources"`
+	}
+	_, err := c.call(ctx, "GET", fmt.Sprintf("/v3/apps/%s/processes/web/stats", appGUID), nil, &stats)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	instances := []S.AppInstance{}
+	for _, stat := range stats.Resources {
+		instance := S.AppInstance{Index: stat.Index, State: strings.ToLower(stat.State)}
+		if stat.Uptime > 0 {
+			instance.Since = now.Add(-time.Duration(stat.Uptime) * time.Second)
+		}
+		instances = append(instances, instance)
+	}
+
+	sort.Slice(instances, func(i, j int) bool { return instances[i].Index < instances[j].Index })
+	return instances, nil
+}
+
+// relationships returns the to-one relationships of a request body from pairs of names and GUIDs.
+func relationships(namesAndGUIDs ...string) map[string]interface{} {
+	related := map[string]interface{}{}
+	for i := 0; i+1 < len(namesAndGUIDs); i += 2 {
+		related[namesAndGUIDs[i]] = map[string]interface{}{
+			"data": map[string]string{"guid": namesAndGUIDs[i+1]},
+		}
+	}
+	return related
+}
+
+func bytesBody(data []byte) func() (io.Reader, error) {
+	return func() (io.Reader, error) { return bytes.NewReader(data), nil }
+}
@@ -0,0 +1,525 @@
+// Package ccapi implements a courier that talks to the Cloud Controller V3 API and UAA over HTTP
+// instead of running the cf CLI.
+package ccapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/compozed/deployadactyl/controller/deployer/bluegreen/courier/executor"
+	I "github.com/compozed/deployadactyl/interfaces"
+	S "github.com/compozed/deployadactyl/structs"
+	"github.com/spf13/afero"
+)
+
+// DefaultPollInterval is how long the courier waits between looking at asynchronous operations such as
+// jobs, builds and deployments.
+const DefaultPollInterval = time.Second
+
+type CourierConstructor func(fileSystem *afero.Afero, timeouts S.Timeouts) I.Courier
+
+func NewCourier(fileSystem *afero.Afero, timeouts S.Timeouts) I.Courier {
+	return &Courier{
+		FileSystem:   fileSystem,
+		Timeouts:     timeouts,
+		PollInterval: DefaultPollInterval,
+	}
+}
+
+// Courier runs the Cloud Foundry operations of a deployment through the Cloud Controller V3 API.
+//
+// Login keeps the access token and the targeted org and space for the operations that follow, like the
+// cf CLI keeps them in its home directory. The output of each operation reads like the output of the cf
+// command it replaces, and each operation is bounded by the timeout of that command. A Courier is not
+// safe for concurrent use.
+type Courier struct {
+	FileSystem   *afero.Afero
+	Timeouts     S.Timeouts
+	PollInterval time.Duration
+
+	// Output receives the output of every operation line by line as it is written, tagged with the cf
+	// command the operation replaces, when it is set.
+	Output io.Writer
+
+	client       *http.Client
+	api          string
+	uaa          string
+	logCache     string
+	token        string
+	refreshToken string
+	username     string
+	org          resource
+	space        resource
+
+	// lock guards the cancel funcs of the operations that are running, so that Interrupt can cancel them.
+	lock    sync.Mutex
+	running map[int]context.CancelFunc
+	next    int
+}
+
+// resource holds the fields of the Cloud Controller resources the courier reads. Each kind of resource
+// only sets some of them.
+type resource struct {
+	GUID  string `json:"guid"`
+	Name  string `json:"name"`
+	State string `json:"state"`
+	Host  string `json:"host"`
+	Path  string `json:"path"`
+	URL   string `json:"url"`
+	Error string `json:"error"`
+
+	SyslogDrainURL  string `json:"syslog_drain_url"`
+	RouteServiceURL string `json:"route_service_url"`
+
+	LastOperation struct {
+		Type  string `json:"type"`
+		State string `json:"state"`
+	} `json:"last_operation"`
+
+	Destinations []struct {
+		GUID string `json:"guid"`
+		App  struct {
+			GUID string `json:"guid"`
+		} `json:"app"`
+	} `json:"destinations"`
+
+	Droplet struct {
+		GUID string `json:"guid"`
+	} `json:"droplet"`
+
+	Result struct {
+		FailureReason string `json:"failure_reason"`
+	} `json:"result"`
+
+	Status struct {
+		Value  string `json:"value"`
+		Reason string `json:"reason"`
+	} `json:"status"`
+}
+
+type list struct {
+	Resources []resource `json:"resources"`
+}
+
+type apiErrors struct {
+	Errors []struct {
+		Code   int    `json:"code"`
+		Title  string `json:"title"`
+		Detail string `json:"detail"`
+	} `json:"errors"`
+	Description string `json:"error_description"`
+}
+
+// Login finds the UAA of the Cloud Controller, requests an access token with the password of the user
+// and targets the org and space.
+func (c *Courier) Login(foundationURL, username, password, org, space string, skipSSL bool) ([]byte, error) {
+	return c.run("login", func(ctx context.Context, out io.Writer) error {
+		c.client = &http.Client{
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: skipSSL},
+			},
+		}
+		c.api = apiURL(foundationURL)
+		c.token, c.refreshToken = "", ""
+		c.username = username
+
+		fmt.Fprintf(out, "API endpoint: %s\n", c.api)
+
+		var root struct {
+			Links map[string]struct {
+				Href string `json:"href"`
+			} `json:"links"`
+		}
+		_, err := c.call(ctx, "GET", "/", nil, &root)
+		if err != nil {
+			return err
+		}
+
+		c.uaa = root.Links["uaa"].Href
+		if c.uaa == "" {
+			c.uaa = root.Links["login"].Href
+		}
+		c.logCache = root.Links["log_cache"].Href
+
+		fmt.Fprintln(out, "Authenticating...")
+		err = c.requestToken(ctx, url.Values{
+			"grant_type": {"password"},
+			"username":   {username},
+			"password":   {password},
+		})
+		if err != nil {
+			return err
+		}
+
+		c.org, err = c.find(ctx, "org", org, "/v3/organizations?names="+url.QueryEscape(org))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Targeted org %s\n", org)
+
+		c.space, err = c.find(ctx, "space", space, fmt.Sprintf("/v3/spaces?names=%s&organization_guids=%s", url.QueryEscape(space), c.org.GUID))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Targeted space %s\n", space)
+
+		return nil
+	})
+}
+
+// Curl gets a path of the Cloud Controller API, such as /v3/spaces?names=dev.
+//
+// Returns the response body. Errors of the API are in the body rather than in the error.
+func (c *Courier) Curl(path string) ([]byte, error) {
+	ctx, cancel := c.context("curl")
+	defer cancel()
+
+	if c.client == nil {
+		return nil, NotLoggedInError{}
+	}
+
+	_, body, err := c.send(ctx, "GET", c.api+path, "", nil)
+	if err != nil {
+		return nil, c.timeout(ctx, "curl", err)
+	}
+	return body, nil
+}
+
+// OAuthToken returns the access token of the logged in user with its bearer prefix.
+func (c *Courier) OAuthToken() (string, error) {
+	if c.token == "" {
+		return "", NotLoggedInError{}
+	}
+	return "bearer " + c.token, nil
+}
+
+// CleanUp does nothing. The courier keeps no files.
+func (c *Courier) CleanUp() error {
+	return nil
+}
+
+// Streaming sets the courier to write the output of its operations to the writer as it is written,
+// and returns it. The output is returned by the operations as well.
+func (c *Courier) Streaming(output io.Writer) I.Courier {
+	c.Output = output
+	return c
+}
+
+// run runs an operation within the timeout of the cf command it replaces. The output ends with OK, or
+// with FAILED and the error, like the output of the cf CLI.
+func (c *Courier) run(command string, operation func(ctx context.Context, out io.Writer) error) ([]byte, error) {
+	ctx, cancel := c.context(command)
+	defer cancel()
+
+	buffer := &bytes.Buffer{}
+	out := io.Writer(buffer)
+	if c.Output != nil {
+		lines := executor.NewLineWriter(c.Output, command)
+		defer lines.Flush()
+		out = io.MultiWriter(buffer, lines)
+	}
+
+	if c.client == nil && command != "login" {
+		fmt.Fprintf(out, "FAILED\n%s\n", NotLoggedInError{})
+		return buffer.Bytes(), NotLoggedInError{}
+	}
+
+	err := operation(ctx, out)
+	if err != nil {
+		err = c.timeout(ctx, command, err)
+		fmt.Fprintf(out, "FAILED\n%s\n", err)
+		return buffer.Bytes(), err
+	}
+
+	fmt.Fprintln(out, "OK")
+	return buffer.Bytes(), nil
+}
+
+// context returns the context of an operation, bounded by the timeout of its cf command. It is
+// canceled by Interrupt until the returned cancel func is called.
+func (c *Courier) context(command string) (context.Context, context.CancelFunc) {
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+
+	timeout, _ := c.Timeouts.GetCommand(command)
+	if timeout <= 0 {
+		ctx, cancel = context.WithCancel(context.Background())
+	} else {
+		ctx, cancel = context.WithTimeout(context.Background(), timeout)
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.running == nil {
+		c.running = map[int]context.CancelFunc{}
+	}
+	id := c.next
+	c.next++
+	c.running[id] = cancel
+
+	return ctx, func() {
+		c.lock.Lock()
+		delete(c.running, id)
+		c.lock.Unlock()
+		cancel()
+	}
+}
+
+// Interrupt cancels the operations that are running. They fail with an InterruptedError.
+func (c *Courier) Interrupt() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, cancel := range c.running {
+		cancel()
+	}
+}
+
+// timeout returns a RequestTimeoutError in place of the error of an operation that ran out of time,
+// and an InterruptedError in place of the error of one that was interrupted.
+func (c *Courier) timeout(ctx context.Context, command string, err error) error {
+	if ctx.Err() == context.Canceled {
+		return InterruptedError{command}
+	}
+	if ctx.Err() != context.DeadlineExceeded {
+		return err
+	}
+	after, _ := c.Timeouts.GetCommand(command)
+	return RequestTimeoutError{command, after}
+}
+
+// sleep waits for the poll interval, or returns the error of the context when it is done first.
+func (c *Courier) sleep(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(c.PollInterval):
+		return nil
+	}
+}
+
+// call sends a request with a JSON body to a path or URL of the Cloud Controller and decodes the JSON
+// response into out. Returns the Location header, which points to the job of an asynchronous operation.
+func (c *Courier) call(ctx context.Context, method, path string, in, out interface{}) (string, error) {
+	var body func() (io.Reader, error)
+	contentType := ""
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return "", err
+		}
+		body = bytesBody(data)
+		contentType = "application/json"
+	}
+	return c.callWithBody(ctx, method, path, contentType, body, out)
+}
+
+func (c *Courier) callWithBody(ctx context.Context, method, path, contentType string, body func() (io.Reader, error), out interface{}) (string, error) {
+	target := path
+	if !strings.HasPrefix(target, "http://") && !strings.HasPrefix(target, "https://") {
+		target = c.api + path
+	}
+
+	response, data, err := c.send(ctx, method, target, contentType, body)
+	if err != nil {
+		return "", err
+	}
+	if err = checkResponse(method, target, response, data); err != nil {
+		return "", err
+	}
+
+	if out != nil && len(data) > 0 {
+		if err = json.Unmarshal(data, out); err != nil {
+			return "", fmt.Errorf("cannot decode the response of %s %s: %s", method, target, err)
+		}
+	}
+	return response.Header.Get("Location"), nil
+}
+
+// send sends a request with the access token. When the token has expired, it is refreshed and the
+// request is sent again, so the body is a function that returns a new reader for every attempt.
+func (c *Courier) send(ctx context.Context, method, target, contentType string, body func() (io.Reader, error)) (*http.Response, []byte, error) {
+	for refreshed := false; ; refreshed = true {
+		var reader io.Reader
+		if body != nil {
+			var err error
+			if reader, err = body(); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		request, err := http.NewRequest(method, target, reader)
+		if err != nil {
+			return nil, nil, err
+		}
+		request = request.WithContext(ctx)
+		request.Header.Set("Accept", "application/json")
+		if contentType != "" {
+			request.Header.Set("Content-Type", contentType)
+		}
+		if c.token != "" {
+			request.Header.Set("Authorization", "bearer "+c.token)
+		}
+
+		response, err := c.client.Do(request)
+		if err != nil {
+			return nil, nil, err
+		}
+		data, err := ioutil.ReadAll(response.Body)
+		response.Body.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if response.StatusCode == http.StatusUnauthorized && !refreshed && c.refreshToken != "" {
+			err = c.requestToken(ctx, url.Values{"grant_type": {"refresh_token"}, "refresh_token": {c.refreshToken}})
+			if err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+
+		return response, data, nil
+	}
+}
+
+// requestToken requests an access token from UAA as the cf client, like the cf CLI does.
+func (c *Courier) requestToken(ctx context.Context, form url.Values) error {
+	target := c.uaa + "/oauth/token"
+
+	request, err := http.NewRequest("POST", target, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	request = request.WithContext(ctx)
+	request.SetBasicAuth("cf", "")
+	request.Header.Set("Accept", "application/json")
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	response, err := c.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	data, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+	if err = checkResponse("POST", target, response, data); err != nil {
+		return err
+	}
+
+	var token struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err = json.Unmarshal(data, &token); err != nil {
+		return fmt.Errorf("cannot decode the token from %s: %s", target, err)
+	}
+
+	c.token, c.refreshToken = token.AccessToken, token.RefreshToken
+	return nil
+}
+
+// waitForJob polls the job of an asynchronous operation until it is complete.
+func (c *Courier) waitForJob(ctx context.Context, operation, location string) error {
+	if location == "" {
+		return nil
+	}
+
+	for {
+		var job struct {
+			State string `json:"state"`
+			apiErrors
+		}
+		if _, err := c.call(ctx, "GET", location, nil, &job); err != nil {
+			return err
+		}
+
+		switch job.State {
+		case "COMPLETE":
+			return nil
+		case "FAILED":
+			reason := "unknown error"
+			if len(job.Errors) > 0 {
+				reason = job.Errors[0].Detail
+			}
+			return JobFailedError{operation, reason}
+		}
+
+		if err := c.sleep(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+// find returns the first resource of a list, or a NotFoundError when the list is empty.
+func (c *Courier) find(ctx context.Context, kind, name, path string) (resource, error) {
+	var resources list
+	if _, err := c.call(ctx, "GET", path, nil, &resources); err != nil {
+		return resource{}, err
+	}
+	if len(resources.Resources) == 0 {
+		return resource{}, NotFoundError{kind, name}
+	}
+	return resources.Resources[0], nil
+}
+
+func (c *Courier) app(ctx context.Context, appName string) (resource, error) {
+	return c.find(ctx, "app", appName, fmt.Sprintf("/v3/apps?names=%s&space_guids=%s", url.QueryEscape(appName), c.space.GUID))
+}
+
+func (c *Courier) serviceInstance(ctx context.Context, serviceName string) (resource, error) {
+	return c.find(ctx, "service instance", serviceName, fmt.Sprintf("/v3/service_instances?names=%s&space_guids=%s", url.QueryEscape(serviceName), c.space.GUID))
+}
+
+// checkResponse returns an APIError with the first error of the body when the status is an error.
+func checkResponse(method, target string, response *http.Response, data []byte) error {
+	if response.StatusCode < http.StatusBadRequest {
+		return nil
+	}
+
+	err := APIError{Method: method, URL: target, StatusCode: response.StatusCode, Detail: http.StatusText(response.StatusCode)}
+
+	var body apiErrors
+	if json.Unmarshal(data, &body) == nil {
+		if len(body.Errors) > 0 {
+			err.Code, err.Detail = body.Errors[0].Code, body.Errors[0].Detail
+		} else if body.Description != "" {
+			err.Detail = body.Description
+		}
+	}
+	return err
+}
+
+func isNotFound(err error) bool {
+	if _, ok := err.(NotFoundError); ok {
+		return true
+	}
+	apiErr, ok := err.(APIError)
+	return ok && apiErr.StatusCode == http.StatusNotFound
+}
+
+// apiURL adds the https scheme the cf CLI assumes to a foundation URL without one.
+func apiURL(foundationURL string) string {
+	foundationURL = strings.TrimSuffix(foundationURL, "/")
+	if !strings.Contains(foundationURL, "://") {
+		return "https://" + foundationURL
+	}
+	return foundationURL
+}
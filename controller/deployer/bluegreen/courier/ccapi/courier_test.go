@@ -0,0 +1,639 @@
+package ccapi_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/compozed/deployadactyl/controller/deployer/bluegreen/courier"
+	. "github.com/compozed/deployadactyl/controller/deployer/bluegreen/courier/ccapi"
+	I "github.com/compozed/deployadactyl/interfaces"
+	S "github.com/compozed/deployadactyl/structs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/afero"
+)
+
+type request struct {
+	Method      string
+	Path        string
+	Query       string
+	ContentType string
+	Body        []byte
+}
+
+// fakeCloudController serves the Cloud Controller, UAA and log cache from one httptest server.
+// Handlers are keyed by method and path, without the query.
+type fakeCloudController struct {
+	*httptest.Server
+
+	lock     sync.Mutex
+	handlers map[string]http.HandlerFunc
+	requests []request
+}
+
+func newFakeCloudController() *fakeCloudController {
+	f := &fakeCloudController{handlers: map[string]http.HandlerFunc{}}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+
+	f.handlers["GET /"] = respond(200, fmt.Sprintf(`{"links": {"uaa": {"href": "%s/uaa"}, "log_cache": {"href": "%s/log-cache"}}}`, f.URL, f.URL))
+	f.handlers["POST /uaa/oauth/token"] = respond(200, `{"access_token": "access-token", "refresh_token": "refresh-token"}`)
+	f.handlers["GET /v3/organizations"] = respond(200, `{"resources": [{"guid": "org-guid", "name": "my-org"}]}`)
+	f.handlers["GET /v3/spaces"] = respond(200, `{"resources": [{"guid": "space-guid", "name": "my-space"}]}`)
+	f.handlers["GET /v3/apps"] = respond(200, `{"resources": [{"guid": "app-guid", "name": "my-app", "state": "STARTED"}]}`)
+
+	return f
+}
+
+func (f *fakeCloudController) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+
+	f.lock.Lock()
+	f.requests = append(f.requests, request{r.Method, r.URL.Path, r.URL.RawQuery, r.Header.Get("Content-Type"), body})
+	handler, ok := f.handlers[r.Method+" "+r.URL.Path]
+	f.lock.Unlock()
+
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, `{"errors": [{"code": 10000, "title": "CF-NotFound", "detail": "Unknown request %s %s"}]}`, r.Method, r.URL.Path)
+		return
+	}
+	handler(w, r)
+}
+
+func (f *fakeCloudController) handle(route string, handler http.HandlerFunc) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.handlers[route] = handler
+}
+
+// received returns the requests to a method and path.
+func (f *fakeCloudController) received(method, path string) []request {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	var requests []request
+	for _, r := range f.requests {
+		if r.Method == method && r.Path == path {
+			requests = append(requests, r)
+		}
+	}
+	return requests
+}
+
+func respond(status int, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	}
+}
+
+// sequence responds with each of the handlers in turn and keeps responding with the last one.
+func sequence(handlers ...http.HandlerFunc) http.HandlerFunc {
+	var lock sync.Mutex
+	calls := 0
+	return func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		handler := handlers[calls]
+		if calls < len(handlers)-1 {
+			calls++
+		}
+		lock.Unlock()
+		handler(w, r)
+	}
+}
+
+func accepted(location string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", location)
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+var _ = Describe("Courier", func() {
+	var (
+		fake        *fakeCloudController
+		fileSystem  *afero.Afero
+		timeouts    S.Timeouts
+		cc          I.Courier
+		loginOutput []byte
+		loginErr    error
+	)
+
+	BeforeEach(func() {
+		fake = newFakeCloudController()
+		fileSystem = &afero.Afero{Fs: afero.NewMemMapFs()}
+		timeouts = S.Timeouts{}
+	})
+
+	JustBeforeEach(func() {
+		cc = NewCourier(fileSystem, timeouts)
+		cc.(*Courier).PollInterval = 0
+		loginOutput, loginErr = cc.Login(fake.URL, "my-user", "my-password", "my-org", "my-space", false)
+	})
+
+	AfterEach(func() {
+		fake.Close()
+	})
+
+	Describe("Login", func() {
+		It("requests a token from UAA and targets the org and space", func() {
+			Expect(loginErr).ToNot(HaveOccurred())
+			Expect(string(loginOutput)).To(ContainSubstring("API endpoint: " + fake.URL))
+			Expect(string(loginOutput)).To(ContainSubstring("Targeted org my-org\nTargeted space my-space\nOK"))
+
+			tokenRequests := fake.received("POST", "/uaa/oauth/token")
+			Expect(tokenRequests).To(HaveLen(1))
+			Expect(string(tokenRequests[0].Body)).To(ContainSubstring("grant_type=password"))
+			Expect(string(tokenRequests[0].Body)).To(ContainSubstring("username=my-user"))
+			Expect(fake.received("GET", "/v3/spaces")[0].Query).To(ContainSubstring("organization_guids=org-guid"))
+
+			token, err := cc.OAuthToken()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(token).To(Equal("bearer access-token"))
+		})
+
+		Context("when UAA rejects the credentials", func() {
+			BeforeEach(func() {
+				fake.handle("POST /uaa/oauth/token", respond(401, `{"error": "unauthorized", "error_description": "Bad credentials"}`))
+			})
+
+			It("returns the reason", func() {
+				Expect(loginErr).To(HaveOccurred())
+				Expect(loginErr.Error()).To(ContainSubstring("Bad credentials"))
+				Expect(string(loginOutput)).To(ContainSubstring("FAILED"))
+			})
+		})
+
+		Context("when the org does not exist", func() {
+			BeforeEach(func() {
+				fake.handle("GET /v3/organizations", respond(200, `{"resources": []}`))
+			})
+
+			It("returns a NotFoundError", func() {
+				Expect(loginErr).To(MatchError(NotFoundError{"org", "my-org"}))
+			})
+		})
+	})
+
+	Describe("requests", func() {
+		Context("when the access token has expired", func() {
+			BeforeEach(func() {
+				fake.handle("GET /v3/apps", sequence(
+					respond(401, `{"errors": [{"code": 1000, "detail": "Invalid Auth Token"}]}`),
+					respond(200, `{"resources": [{"guid": "app-guid", "name": "my-app"}]}`),
+				))
+			})
+
+			It("refreshes it and sends the request again", func() {
+				Expect(cc.Exists("my-app")).To(BeTrue())
+
+				tokenRequests := fake.received("POST", "/uaa/oauth/token")
+				Expect(tokenRequests).To(HaveLen(2))
+				Expect(string(tokenRequests[1].Body)).To(ContainSubstring("grant_type=refresh_token"))
+				Expect(fake.received("GET", "/v3/apps")).To(HaveLen(2))
+			})
+		})
+
+		Context("when the Cloud Controller fails", func() {
+			BeforeEach(func() {
+				fake.handle("POST /v3/apps/app-guid/actions/stop", respond(502, `{"errors": [{"code": 10001, "detail": "bad gateway"}]}`))
+			})
+
+			It("returns an error the default transient patterns match", func() {
+				out, err := cc.Stop("my-app")
+
+				Expect(err).To(MatchError("Server error, status code: 502, error code: 10001, message: bad gateway"))
+				Expect(courier.DefaultTransientPatterns[0].Match(out)).To(BeTrue())
+			})
+		})
+
+		Context("when an operation runs longer than the timeout of its command", func() {
+			BeforeEach(func() {
+				timeouts = S.Timeouts{Commands: map[string]string{"start": "50ms"}}
+				fake.handle("GET /v3/apps/app-guid/droplets/current", respond(200, `{"guid": "droplet-guid"}`))
+				fake.handle("POST /v3/apps/app-guid/actions/start", respond(200, `{}`))
+				fake.handle("GET /v3/apps/app-guid/processes/web/stats", respond(200, `{"resources": [{"index": 0, "state": "STARTING"}]}`))
+			})
+
+			It("returns a timeout error", func() {
+				_, err := cc.Start("my-app")
+
+				Expect(err).To(MatchError(RequestTimeoutError{"start", 50 * time.Millisecond}))
+				Expect(err.(I.TimeoutError).Timeout()).To(BeTrue())
+			})
+		})
+
+		Context("when the courier streams", func() {
+			BeforeEach(func() {
+				fake.handle("POST /v3/apps/app-guid/actions/stop", respond(200, `{}`))
+			})
+
+			It("writes the output of each operation tagged with its command as it is written", func() {
+				output := &bytes.Buffer{}
+				streaming := cc.(I.StreamingCourier).Streaming(output)
+
+				out, err := streaming.Stop("my-app")
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(output.String()).To(Equal("[stop] Stopping app my-app in org my-org / space my-space as my-user...\n[stop] OK\n"))
+				Expect(string(out)).To(Equal("Stopping app my-app in org my-org / space my-space as my-user...\nOK\n"))
+			})
+		})
+
+		Context("when the courier is interrupted", func() {
+			BeforeEach(func() {
+				fake.handle("GET /v3/apps/app-guid/droplets/current", respond(200, `{"guid": "droplet-guid"}`))
+				fake.handle("POST /v3/apps/app-guid/actions/start", respond(200, `{}`))
+				fake.handle("GET /v3/apps/app-guid/processes/web/stats", respond(200, `{"resources": [{"index": 0, "state": "STARTING"}]}`))
+			})
+
+			It("cancels the operations that are running", func() {
+				time.AfterFunc(50*time.Millisecond, cc.(I.Interruptible).Interrupt)
+
+				_, err := cc.Start("my-app")
+
+				Expect(err).To(MatchError(InterruptedError{"start"}))
+			})
+		})
+	})
+
+	Describe("Push", func() {
+		BeforeEach(func() {
+			fileSystem.WriteFile("/app/manifest.yml", []byte(`---
+applications:
+- name: my-app
+  memory: 256M
+  buildpack: java_buildpack
+  host: old-host
+  routes:
+  - route: custom.example.com
+`), 0644)
+			fileSystem.WriteFile("/app/app.jar", []byte("jar"), 0644)
+			fileSystem.WriteFile("/app/bin/run", []byte("#!/bin/sh"), 0755)
+
+			fake.handle("GET /v3/organizations/org-guid/domains/default", respond(200, `{"guid": "domain-guid", "name": "apps.example.com"}`))
+			fake.handle("POST /v3/spaces/space-guid/actions/apply_manifest", accepted(fake.URL+"/v3/jobs/manifest-job"))
+			fake.handle("GET /v3/jobs/manifest-job", sequence(
+				respond(200, `{"state": "PROCESSING"}`),
+				respond(200, `{"state": "COMPLETE"}`),
+			))
+			fake.handle("GET /v3/apps", respond(200, `{"resources": [{"guid": "app-guid", "name": "my-app", "state": "STOPPED"}]}`))
+			fake.handle("POST /v3/packages", respond(201, `{"guid": "package-guid", "state": "AWAITING_UPLOAD"}`))
+			fake.handle("POST /v3/packages/package-guid/upload", respond(200, `{"guid": "package-guid", "state": "PROCESSING_UPLOAD"}`))
+			fake.handle("GET /v3/packages/package-guid", respond(200, `{"guid": "package-guid", "state": "READY"}`))
+			fake.handle("POST /v3/builds", respond(201, `{"guid": "build-guid", "state": "STAGING"}`))
+			fake.handle("GET /v3/builds/build-guid", respond(200, `{"guid": "build-guid", "state": "STAGED", "droplet": {"guid": "droplet-guid"}}`))
+			fake.handle("PATCH /v3/apps/app-guid/relationships/current_droplet", respond(200, `{}`))
+			fake.handle("POST /v3/apps/app-guid/actions/restart", respond(200, `{}`))
+			fake.handle("GET /v3/apps/app-guid/processes/web/stats", sequence(
+				respond(200, `{"resources": [{"index": 0, "state": "STARTING"}]}`),
+				respond(200, `{"resources": [{"index": 0, "state": "RUNNING", "uptime": 5}]}`),
+			))
+		})
+
+		It("applies the manifest with the route of the hostname", func() {
+			out, err := cc.Push("my-app", "/app", "my-host", 2)
+			Expect(err).ToNot(HaveOccurred(), string(out))
+
+			applied := fake.received("POST", "/v3/spaces/space-guid/actions/apply_manifest")
+			Expect(applied).To(HaveLen(1))
+			Expect(applied[0].ContentType).To(Equal("application/x-yaml"))
+
+			manifest := string(applied[0].Body)
+			Expect(manifest).To(ContainSubstring("my-host.apps.example.com"))
+			Expect(manifest).To(ContainSubstring("java_buildpack"))
+			Expect(manifest).To(ContainSubstring("256M"))
+			Expect(manifest).ToNot(ContainSubstring("old-host"))
+			Expect(manifest).ToNot(ContainSubstring("custom.example.com"))
+			Expect(manifest).To(MatchRegexp(`instances: 2`))
+		})
+
+		It("uploads the application directory as a zip", func() {
+			_, err := cc.Push("my-app", "/app", "my-host", 2)
+			Expect(err).ToNot(HaveOccurred())
+
+			uploads := fake.received("POST", "/v3/packages/package-guid/upload")
+			Expect(uploads).To(HaveLen(1))
+
+			_, params, err := mime.ParseMediaType(uploads[0].ContentType)
+			Expect(err).ToNot(HaveOccurred())
+			part, err := multipart.NewReader(bytes.NewReader(uploads[0].Body), params["boundary"]).NextPart()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(part.FormName()).To(Equal("bits"))
+
+			bits, _ := ioutil.ReadAll(part)
+			archive, err := zip.NewReader(bytes.NewReader(bits), int64(len(bits)))
+			Expect(err).ToNot(HaveOccurred())
+
+			modes := map[string]os.FileMode{}
+			for _, file := range archive.File {
+				modes[file.Name] = file.Mode()
+			}
+			Expect(modes).To(HaveKey("app.jar"))
+			Expect(modes).To(HaveKeyWithValue("bin/run", os.FileMode(0755)))
+		})
+
+		It("leaves out the files the cf CLI leaves out and the ones the .cfignore excludes", func() {
+			fileSystem.WriteFile("/app/.git/HEAD", []byte("ref: refs/heads/master"), 0644)
+			fileSystem.WriteFile("/app/node_modules/left-pad/index.js", []byte("module.exports = {}"), 0644)
+			fileSystem.WriteFile("/app/logs/app.log", []byte("log"), 0644)
+			fileSystem.WriteFile("/app/logs/keep.log", []byte("log"), 0644)
+			fileSystem.WriteFile("/app/config/node_modules/tool.js", []byte("tool"), 0644)
+			fileSystem.WriteFile("/app/.cfignore", []byte("# local files\n/node_modules\nlogs/*.log\n!logs/keep.log\n"), 0644)
+
+			_, err := cc.Push("my-app", "/app", "my-host", 2)
+			Expect(err).ToNot(HaveOccurred())
+
+			upload := fake.received("POST", "/v3/packages/package-guid/upload")[0]
+			_, params, _ := mime.ParseMediaType(upload.ContentType)
+			part, _ := multipart.NewReader(bytes.NewReader(upload.Body), params["boundary"]).NextPart()
+			bits, _ := ioutil.ReadAll(part)
+			archive, err := zip.NewReader(bytes.NewReader(bits), int64(len(bits)))
+			Expect(err).ToNot(HaveOccurred())
+
+			var names []string
+			for _, file := range archive.File {
+				names = append(names, file.Name)
+			}
+			Expect(names).To(ContainElement("app.jar"))
+			Expect(names).To(ContainElement("logs/keep.log"))
+			Expect(names).To(ContainElement("config/node_modules/tool.js"))
+			Expect(names).ToNot(ContainElement("manifest.yml"))
+			Expect(names).ToNot(ContainElement(".cfignore"))
+			Expect(names).ToNot(ContainElement("logs/app.log"))
+			for _, name := range names {
+				Expect(name).ToNot(HavePrefix(".git"))
+				Expect(name).ToNot(HavePrefix("node_modules"))
+			}
+		})
+
+		It("stages the package and starts the droplet", func() {
+			out, err := cc.Push("my-app", "/app", "my-host", 2)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(string(fake.received("POST", "/v3/builds")[0].Body)).To(ContainSubstring(`"guid":"package-guid"`))
+			Expect(string(fake.received("PATCH", "/v3/apps/app-guid/relationships/current_droplet")[0].Body)).To(ContainSubstring(`"guid":"droplet-guid"`))
+			Expect(fake.received("POST", "/v3/apps/app-guid/actions/restart")).To(HaveLen(1))
+			Expect(fake.received("GET", "/v3/apps/app-guid/processes/web/stats")).To(HaveLen(2))
+			Expect(string(out)).To(HaveSuffix("OK\n"))
+		})
+
+		Context("when staging fails", func() {
+			BeforeEach(func() {
+				fake.handle("GET /v3/builds/build-guid", respond(200, `{"guid": "build-guid", "state": "FAILED", "error": "NoAppDetectedError"}`))
+			})
+
+			It("returns the error of the build", func() {
+				out, err := cc.Push("my-app", "/app", "my-host", 2)
+
+				Expect(err).To(MatchError(JobFailedError{"staging", "NoAppDetectedError"}))
+				Expect(string(out)).To(ContainSubstring("FAILED"))
+				Expect(fake.received("POST", "/v3/apps/app-guid/actions/restart")).To(BeEmpty())
+			})
+		})
+
+		Context("when the application has no instances yet after the restart", func() {
+			BeforeEach(func() {
+				fake.handle("GET /v3/apps/app-guid/processes/web/stats", sequence(
+					respond(200, `{"resources": []}`),
+					respond(200, `{"resources": [{"index": 0, "state": "RUNNING", "uptime": 5}]}`),
+				))
+			})
+
+			It("waits until an instance runs", func() {
+				_, err := cc.Push("my-app", "/app", "my-host", 2)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fake.received("GET", "/v3/apps/app-guid/processes/web/stats")).To(HaveLen(2))
+			})
+		})
+
+		Context("when every instance crashes", func() {
+			BeforeEach(func() {
+				fake.handle("GET /v3/apps/app-guid/processes/web/stats", respond(200, `{"resources": [{"index": 0, "state": "CRASHED"}, {"index": 1, "state": "CRASHED"}]}`))
+			})
+
+			It("returns an InstancesCrashedError", func() {
+				_, err := cc.Push("my-app", "/app", "my-host", 2)
+
+				Expect(err).To(MatchError(InstancesCrashedError{"my-app"}))
+			})
+		})
+
+		Context("without starting the application", func() {
+			It("uploads the package and leaves the application stopped", func() {
+				_, err := cc.PushWithoutStart("my-app", "/app", "my-host", 2)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fake.received("POST", "/v3/packages/package-guid/upload")).To(HaveLen(1))
+				Expect(fake.received("POST", "/v3/builds")).To(BeEmpty())
+				Expect(fake.received("POST", "/v3/apps/app-guid/actions/restart")).To(BeEmpty())
+			})
+
+			It("stages the package when the application is started", func() {
+				fake.handle("GET /v3/apps/app-guid/droplets/current", respond(404, `{"errors": [{"code": 10010, "detail": "Droplet not found"}]}`))
+				fake.handle("GET /v3/apps/app-guid/packages", respond(200, `{"resources": [{"guid": "package-guid", "state": "READY"}]}`))
+
+				_, err := cc.Start("my-app")
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(string(fake.received("POST", "/v3/builds")[0].Body)).To(ContainSubstring(`"guid":"package-guid"`))
+				Expect(fake.received("POST", "/v3/apps/app-guid/actions/restart")).To(HaveLen(1))
+			})
+		})
+
+		Context("with a strategy for a running application", func() {
+			BeforeEach(func() {
+				fake.handle("GET /v3/apps", respond(200, `{"resources": [{"guid": "app-guid", "name": "my-app", "state": "STARTED"}]}`))
+				fake.handle("POST /v3/deployments", respond(201, `{"guid": "deployment-guid", "status": {"value": "ACTIVE"}}`))
+				fake.handle("GET /v3/deployments/deployment-guid", respond(200, `{"guid": "deployment-guid", "status": {"value": "FINALIZED", "reason": "DEPLOYED"}}`))
+			})
+
+			It("deploys the droplet with the strategy", func() {
+				_, err := cc.PushWithStrategy("my-app", "/app", "my-host", 2, "rolling")
+				Expect(err).ToNot(HaveOccurred())
+
+				deployments := fake.received("POST", "/v3/deployments")
+				Expect(deployments).To(HaveLen(1))
+				Expect(string(deployments[0].Body)).To(ContainSubstring(`"strategy":"rolling"`))
+				Expect(string(deployments[0].Body)).To(ContainSubstring(`"guid":"droplet-guid"`))
+				Expect(fake.received("POST", "/v3/apps/app-guid/actions/restart")).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("Delete", func() {
+		It("deletes the application and waits for the job", func() {
+			fake.handle("DELETE /v3/apps/app-guid", accepted(fake.URL+"/v3/jobs/delete-job"))
+			fake.handle("GET /v3/jobs/delete-job", respond(200, `{"state": "COMPLETE"}`))
+
+			_, err := cc.Delete("my-app")
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fake.received("GET", "/v3/jobs/delete-job")).To(HaveLen(1))
+		})
+
+		It("returns the error of a failed job", func() {
+			fake.handle("DELETE /v3/apps/app-guid", accepted(fake.URL+"/v3/jobs/delete-job"))
+			fake.handle("GET /v3/jobs/delete-job", respond(200, `{"state": "FAILED", "errors": [{"detail": "broker went away"}]}`))
+
+			_, err := cc.Delete("my-app")
+
+			Expect(err).To(MatchError(JobFailedError{"delete", "broker went away"}))
+		})
+
+		It("does not fail when the application does not exist", func() {
+			fake.handle("GET /v3/apps", respond(200, `{"resources": []}`))
+
+			out, err := cc.Delete("my-app")
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(out)).To(ContainSubstring("App my-app does not exist."))
+		})
+	})
+
+	Describe("routes", func() {
+		BeforeEach(func() {
+			fake.handle("GET /v3/domains", respond(200, `{"resources": [{"guid": "domain-guid", "name": "example.com"}]}`))
+		})
+
+		It("creates a route that does not exist and maps it", func() {
+			fake.handle("GET /v3/routes", respond(200, `{"resources": [{"guid": "other-guid", "host": "my-host", "path": "/other"}]}`))
+			fake.handle("POST /v3/routes", respond(201, `{"guid": "route-guid"}`))
+			fake.handle("POST /v3/routes/route-guid/destinations", respond(200, `{}`))
+
+			_, err := cc.MapRouteWithPath("my-app", "example.com", "my-host", "api")
+			Expect(err).ToNot(HaveOccurred())
+
+			created := string(fake.received("POST", "/v3/routes")[0].Body)
+			Expect(created).To(ContainSubstring(`"host":"my-host"`))
+			Expect(created).To(ContainSubstring(`"path":"/api"`))
+			Expect(string(fake.received("POST", "/v3/routes/route-guid/destinations")[0].Body)).To(ContainSubstring(`"guid":"app-guid"`))
+		})
+
+		It("unmaps only the destinations of the application", func() {
+			fake.handle("GET /v3/routes", respond(200, `{"resources": [{"guid": "route-guid", "host": "my-host", "path": "", "destinations": [
+				{"guid": "destination-1", "app": {"guid": "app-guid"}},
+				{"guid": "destination-2", "app": {"guid": "other-app-guid"}}
+			]}]}`))
+			fake.handle("DELETE /v3/routes/route-guid/destinations/destination-1", respond(204, ``))
+
+			_, err := cc.UnmapRoute("my-app", "example.com", "my-host")
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fake.received("DELETE", "/v3/routes/route-guid/destinations/destination-1")).To(HaveLen(1))
+			Expect(fake.received("DELETE", "/v3/routes/route-guid/destinations/destination-2")).To(BeEmpty())
+		})
+
+		It("does not fail to delete a route that does not exist", func() {
+			fake.handle("GET /v3/routes", respond(200, `{"resources": []}`))
+
+			out, err := cc.DeleteRoute("example.com", "my-host")
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(out)).To(ContainSubstring("route 'my-host.example.com' does not exist"))
+		})
+
+		It("returns the domains of the org", func() {
+			fake.handle("GET /v3/organizations/org-guid/domains", respond(200, `{"resources": [{"name": "example.com"}, {"name": "apps.example.com"}]}`))
+
+			domains, err := cc.Domains()
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(domains).To(Equal([]string{"example.com", "apps.example.com"}))
+		})
+	})
+
+	Describe("services", func() {
+		It("creates a user-provided service with the credentials as an object", func() {
+			fake.handle("POST /v3/service_instances", respond(201, `{}`))
+
+			_, err := cc.Cups("my-service", `{"user": "admin"}`, "syslog://drain", "")
+			Expect(err).ToNot(HaveOccurred())
+
+			var body map[string]interface{}
+			Expect(json.Unmarshal(fake.received("POST", "/v3/service_instances")[0].Body, &body)).To(Succeed())
+			Expect(body["type"]).To(Equal("user-provided"))
+			Expect(body["name"]).To(Equal("my-service"))
+			Expect(body["credentials"]).To(Equal(map[string]interface{}{"user": "admin"}))
+			Expect(body["syslog_drain_url"]).To(Equal("syslog://drain"))
+			Expect(body).ToNot(HaveKey("route_service_url"))
+		})
+
+		It("rejects credentials that are not a JSON object", func() {
+			_, err := cc.Cups("my-service", "user,password", "", "")
+
+			Expect(err).To(BeAssignableToTypeOf(InvalidJSONObjectError{}))
+			Expect(fake.received("POST", "/v3/service_instances")).To(BeEmpty())
+		})
+
+		It("returns the last operation of a service instance as its status", func() {
+			fake.handle("GET /v3/service_instances", respond(200, `{"resources": [{"guid": "si-guid", "name": "my-db", "last_operation": {"type": "create", "state": "in progress"}}]}`))
+
+			status, err := cc.ServiceStatus("my-db")
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status).To(Equal("create in progress"))
+		})
+
+		It("returns the credentials and URLs of a user-provided service", func() {
+			fake.handle("GET /v3/service_instances", respond(200, `{"resources": [{"guid": "si-guid", "name": "my-service", "syslog_drain_url": "syslog://drain"}]}`))
+			fake.handle("GET /v3/service_instances/si-guid/credentials", respond(200, `{"user": "admin"}`))
+
+			service, err := cc.UserProvidedService("my-service")
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(service).To(Equal(S.UserProvidedService{
+				Name:           "my-service",
+				Credentials:    map[string]interface{}{"user": "admin"},
+				SyslogDrainURL: "syslog://drain",
+			}))
+		})
+	})
+
+	Describe("AppInstances", func() {
+		It("returns the lowercase state of every instance", func() {
+			fake.handle("GET /v3/apps/app-guid/processes/web/stats", respond(200, `{"resources": [
+				{"index": 1, "state": "CRASHED"},
+				{"index": 0, "state": "RUNNING", "uptime": 60}
+			]}`))
+
+			instances, err := cc.AppInstances("my-app")
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(instances).To(HaveLen(2))
+			Expect(instances[0].State).To(Equal("running"))
+			Expect(instances[0].Since).To(BeTemporally("~", time.Now().Add(-time.Minute), 5*time.Second))
+			Expect(instances[1].State).To(Equal("crashed"))
+		})
+	})
+
+	Describe("Logs", func() {
+		It("returns the recent logs from the log cache, oldest first", func() {
+			first := base64.StdEncoding.EncodeToString([]byte("starting"))
+			second := base64.StdEncoding.EncodeToString([]byte("listening"))
+			fake.handle("GET /log-cache/api/v1/read/app-guid", respond(200, fmt.Sprintf(`{"envelopes": {"batch": [
+				{"timestamp": "2000000000", "instance_id": "0", "tags": {"source_type": "APP/PROC/WEB"}, "log": {"payload": "%s", "type": "OUT"}},
+				{"timestamp": "1000000000", "instance_id": "0", "tags": {"source_type": "APP/PROC/WEB"}, "log": {"payload": "%s", "type": "ERR"}}
+			]}}`, second, first)))
+
+			out, err := cc.Logs("my-app")
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(out)).To(MatchRegexp(`\[APP/PROC/WEB/0\] ERR starting\n.*\[APP/PROC/WEB/0\] OUT listening\n`))
+			Expect(fake.received("GET", "/log-cache/api/v1/read/app-guid")[0].Query).To(ContainSubstring("envelope_types=LOG"))
+		})
+	})
+})
@@ -0,0 +1,95 @@
+package ccapi
+
+import (
+	"fmt"
+	"time"
+)
+
+// APIError is returned when the Cloud Controller or UAA responds with an error status.
+//
+// Server errors read like the ones of the cf CLI, so that the courier.DefaultTransientPatterns match them.
+type APIError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Code       int
+	Detail     string
+}
+
+func (e APIError) Error() string {
+	if e.StatusCode >= 500 {
+		return fmt.Sprintf("Server error, status code: %d, error code: %d, message: %s", e.StatusCode, e.Code, e.Detail)
+	}
+	return fmt.Sprintf("%s %s failed with status code %d: %s", e.Method, e.URL, e.StatusCode, e.Detail)
+}
+
+// RequestTimeoutError is returned when an operation ran longer than the timeout of its cf subcommand.
+type RequestTimeoutError struct {
+	Command string
+	After   time.Duration
+}
+
+func (e RequestTimeoutError) Error() string {
+	return fmt.Sprintf("%s timed out after %s", e.Command, e.After)
+}
+
+// Timeout reports that the error is a timeout.
+func (e RequestTimeoutError) Timeout() bool {
+	return true
+}
+
+// InterruptedError is returned when an operation was canceled by Interrupt.
+type InterruptedError struct {
+	Command string
+}
+
+func (e InterruptedError) Error() string {
+	return fmt.Sprintf("%s was interrupted", e.Command)
+}
+
+type NotLoggedInError struct{}
+
+func (e NotLoggedInError) Error() string {
+	return "not logged in to a Cloud Controller"
+}
+
+type NotFoundError struct {
+	Kind string
+	Name string
+}
+
+func (e NotFoundError) Error() string {
+	return fmt.Sprintf("%s %s not found", e.Kind, e.Name)
+}
+
+type JobFailedError struct {
+	Operation string
+	Reason    string
+}
+
+func (e JobFailedError) Error() string {
+	return fmt.Sprintf("%s failed: %s", e.Operation, e.Reason)
+}
+
+type InstancesCrashedError struct {
+	AppName string
+}
+
+func (e InstancesCrashedError) Error() string {
+	return fmt.Sprintf("every instance of %s crashed", e.AppName)
+}
+
+type InvalidJSONObjectError struct {
+	What string
+	Err  error
+}
+
+func (e InvalidJSONObjectError) Error() string {
+	return fmt.Sprintf("%s must be a JSON object: %s", e.What, e.Err)
+}
+
+type LogCacheNotAvailableError struct{}
+
+func (e LogCacheNotAvailableError) Error() string {
+	return "the Cloud Controller has no log cache"
+}
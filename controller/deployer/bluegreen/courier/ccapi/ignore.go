@@ -0,0 +1,91 @@
+package ccapi
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+// defaultIgnoreLines are the files the cf CLI leaves out of every upload, before the lines of the
+// .cfignore of the application directory.
+var defaultIgnoreLines = []string{".cfignore", ".DS_Store", ".git", ".gitignore", ".hg", ".svn", "_darcs", "manifest.yaml", "manifest.yml"}
+
+type ignorePattern struct {
+	pattern  string
+	anchored bool
+	exclude  bool
+}
+
+// cfIgnore decides which files of an application directory are left out of its package, the way the
+// cf CLI reads a .cfignore.
+type cfIgnore []ignorePattern
+
+// readCFIgnore returns the default exclusions followed by the lines of the .cfignore of the directory,
+// if it has one.
+func readCFIgnore(fileSystem *afero.Afero, directory string) (cfIgnore, error) {
+	lines := defaultIgnoreLines
+
+	content, err := fileSystem.ReadFile(filepath.Join(directory, ".cfignore"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		lines = append(append([]string{}, lines...), strings.Split(string(content), "\n")...)
+	}
+
+	var ignore cfIgnore
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		pattern := ignorePattern{exclude: true}
+		if strings.HasPrefix(line, "!") {
+			pattern.exclude = false
+			line = line[1:]
+		}
+		if strings.HasPrefix(line, "/") {
+			pattern.anchored = true
+		}
+		pattern.pattern = strings.Trim(path.Clean(line), "/")
+
+		ignore = append(ignore, pattern)
+	}
+	return ignore, nil
+}
+
+// ignored returns true when the file with the slash separated path relative to the application
+// directory is left out. A pattern matches a file or directory at any depth, or only at the top when
+// it starts with a slash, and everything inside the directories it matches. The last pattern that
+// matches wins, so that a pattern starting with ! brings back files an earlier one left out.
+func (ignore cfIgnore) ignored(name string) bool {
+	parts := strings.Split(name, "/")
+
+	result := false
+	for _, pattern := range ignore {
+		if pattern.matches(parts) {
+			result = pattern.exclude
+		}
+	}
+	return result
+}
+
+func (p ignorePattern) matches(parts []string) bool {
+	starts := len(parts)
+	if p.anchored {
+		starts = 1
+	}
+
+	for start := 0; start < starts; start++ {
+		for end := start + 1; end <= len(parts); end++ {
+			if matched, _ := path.Match(p.pattern, strings.Join(parts[start:end], "/")); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
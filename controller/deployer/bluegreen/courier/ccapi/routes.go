@@ -0,0 +1,185 @@
+package ccapi
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// MapRoute maps the route of a hostname on a domain to an application, creating the route when it
+// does not exist.
+func (c *Courier) MapRoute(appName, domain, hostname string) ([]byte, error) {
+	return c.mapRoute(appName, domain, hostname, "")
+}
+
+// MapRouteWithPath maps the route of a hostname and path on a domain to an application, creating the
+// route when it does not exist.
+func (c *Courier) MapRouteWithPath(appName, domain, hostname, path string) ([]byte, error) {
+	return c.mapRoute(appName, domain, hostname, path)
+}
+
+func (c *Courier) mapRoute(appName, domain, hostname, path string) ([]byte, error) {
+	return c.run("map-route", func(ctx context.Context, out io.Writer) error {
+		name := routeName(domain, hostname, path)
+
+		app, err := c.app(ctx, appName)
+		if err != nil {
+			return err
+		}
+
+		route, domainGUID, err := c.route(ctx, domain, hostname, path)
+		if isNotFound(err) && domainGUID != "" {
+			fmt.Fprintf(out, "Creating route %s for org %s / space %s as %s...\n", name, c.org.Name, c.space.Name, c.username)
+
+			body := map[string]interface{}{
+				"host":          hostname,
+				"relationships": relationships("space", c.space.GUID, "domain", domainGUID),
+			}
+			if path != "" {
+				body["path"] = "/" + strings.TrimPrefix(path, "/")
+			}
+			_, err = c.call(ctx, "POST", "/v3/routes", body, &route)
+		}
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(out, "Adding route %s to app %s in org %s / space %s as %s...\n", name, appName, c.org.Name, c.space.Name, c.username)
+
+		_, err = c.call(ctx, "POST", fmt.Sprintf("/v3/routes/%s/destinations", route.GUID), map[string]interface{}{
+			"destinations": []interface{}{
+				map[string]interface{}{"app": map[string]string{"guid": app.GUID}},
+			},
+		}, nil)
+		return err
+	})
+}
+
+// UnmapRoute removes the route of a hostname on a domain from an application.
+func (c *Courier) UnmapRoute(appName, domain, hostname string) ([]byte, error) {
+	return c.unmapRoute(appName, domain, hostname, "")
+}
+
+// UnmapRouteWithPath removes the route of a hostname and path on a domain from an application.
+func (c *Courier) UnmapRouteWithPath(appName, domain, hostname, path string) ([]byte, error) {
+	return c.unmapRoute(appName, domain, hostname, path)
+}
+
+func (c *Courier) unmapRoute(appName, domain, hostname, path string) ([]byte, error) {
+	return c.run("unmap-route", func(ctx context.Context, out io.Writer) error {
+		name := routeName(domain, hostname, path)
+		fmt.Fprintf(out, "Removing route %s from app %s in org %s / space %s as %s...\n", name, appName, c.org.Name, c.space.Name, c.username)
+
+		app, err := c.app(ctx, appName)
+		if err != nil {
+			return err
+		}
+
+		route, _, err := c.route(ctx, domain, hostname, path)
+		if isNotFound(err) {
+			fmt.Fprintf(out, "Route %s does not exist.\n", name)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, destination := range route.Destinations {
+			if destination.App.GUID != app.GUID {
+				continue
+			}
+			_, err = c.call(ctx, "DELETE", fmt.Sprintf("/v3/routes/%s/destinations/%s", route.GUID, destination.GUID), nil, nil)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteRoute deletes the route of a hostname on a domain. A route that does not exist is not an
+// error, like with cf delete-route -f.
+func (c *Courier) DeleteRoute(domain, hostname string) ([]byte, error) {
+	return c.deleteRoute(domain, hostname, "")
+}
+
+// DeleteRouteWithPath deletes the route of a hostname and path on a domain.
+func (c *Courier) DeleteRouteWithPath(domain, hostname, path string) ([]byte, error) {
+	return c.deleteRoute(domain, hostname, path)
+}
+
+func (c *Courier) deleteRoute(domain, hostname, path string) ([]byte, error) {
+	return c.run("delete-route", func(ctx context.Context, out io.Writer) error {
+		name := routeName(domain, hostname, path)
+		fmt.Fprintf(out, "Deleting route %s...\n", name)
+
+		route, _, err := c.route(ctx, domain, hostname, path)
+		if isNotFound(err) {
+			fmt.Fprintf(out, "Unable to delete, route '%s' does not exist.\n", name)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		location, err := c.call(ctx, "DELETE", "/v3/routes/"+route.GUID, nil, nil)
+		if err != nil {
+			return err
+		}
+		return c.waitForJob(ctx, "delete-route", location)
+	})
+}
+
+// Domains returns the names of the domains of the targeted org.
+func (c *Courier) Domains() ([]string, error) {
+	ctx, cancel := c.context("domains")
+	defer cancel()
+
+	if c.client == nil {
+		return nil, NotLoggedInError{}
+	}
+
+	var domains list
+	_, err := c.call(ctx, "GET", fmt.Sprintf("/v3/organizations/%s/domains?per_page=5000", c.org.GUID), nil, &domains)
+	if err != nil {
+		return nil, c.timeout(ctx, "domains", err)
+	}
+
+	names := make([]string, len(domains.Resources))
+	for i, domain := range domains.Resources {
+		names[i] = domain.Name
+	}
+	return names, nil
+}
+
+// route returns the route of a hostname and path on a domain and the GUID of the domain. Returns a
+// NotFoundError when the domain or the route does not exist.
+func (c *Courier) route(ctx context.Context, domain, hostname, path string) (resource, string, error) {
+	domainResource, err := c.find(ctx, "domain", domain, "/v3/domains?names="+url.QueryEscape(domain))
+	if err != nil {
+		return resource{}, "", err
+	}
+
+	var routes list
+	_, err = c.call(ctx, "GET", fmt.Sprintf("/v3/routes?domain_guids=%s&hosts=%s", domainResource.GUID, url.QueryEscape(hostname)), nil, &routes)
+	if err != nil {
+		return resource{}, domainResource.GUID, err
+	}
+
+	for _, route := range routes.Resources {
+		if strings.TrimPrefix(route.Path, "/") == strings.TrimPrefix(path, "/") {
+			return route, domainResource.GUID, nil
+		}
+	}
+	return resource{}, domainResource.GUID, NotFoundError{"route", routeName(domain, hostname, path)}
+}
+
+func routeName(domain, hostname, path string) string {
+	name := hostname + "." + domain
+	if path != "" {
+		name += "/" + strings.TrimPrefix(path, "/")
+	}
+	return name
+}
@@ -0,0 +1,276 @@
+package ccapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+// CreateService creates a managed service instance of a plan. The broker provisions it asynchronously;
+// ServiceStatus tells when it is done.
+func (c *Courier) CreateService(service, plan, name string) ([]byte, error) {
+	return c.createService(service, plan, name, "")
+}
+
+// CreateServiceWithParams creates a managed service instance with arbitrary parameters as a JSON object.
+func (c *Courier) CreateServiceWithParams(service, plan, name, params string) ([]byte, error) {
+	return c.createService(service, plan, name, params)
+}
+
+func (c *Courier) createService(service, plan, name, params string) ([]byte, error) {
+	return c.run("create-service", func(ctx context.Context, out io.Writer) error {
+		fmt.Fprintf(out, "Creating service instance %s in org %s / space %s as %s...\n", name, c.org.Name, c.space.Name, c.username)
+
+		offering, err := c.find(ctx, "service offering", service, "/v3/service_offerings?names="+url.QueryEscape(service))
+		if err != nil {
+			return err
+		}
+
+		servicePlan, err := c.find(ctx, "service plan", plan, fmt.Sprintf("/v3/service_plans?names=%s&service_offering_guids=%s", url.QueryEscape(plan), offering.GUID))
+		if err != nil {
+			return err
+		}
+
+		body := map[string]interface{}{
+			"type":          "managed",
+			"name":          name,
+			"relationships": relationships("space", c.space.GUID, "service_plan", servicePlan.GUID),
+		}
+		if params != "" {
+			parameters, err := jsonObject(params)
+			if err != nil {
+				return InvalidJSONObjectError{"the parameters of " + name, err}
+			}
+			body["parameters"] = parameters
+		}
+
+		_, err = c.call(ctx, "POST", "/v3/service_instances", body, nil)
+		return err
+	})
+}
+
+// ServiceStatus returns the type and state of the last operation on a service instance, such as
+// create in progress or create succeeded.
+func (c *Courier) ServiceStatus(serviceName string) (string, error) {
+	ctx, cancel := c.context("service")
+	defer cancel()
+
+	if c.client == nil {
+		return "", NotLoggedInError{}
+	}
+
+	instance, err := c.serviceInstance(ctx, serviceName)
+	if err != nil {
+		return "", c.timeout(ctx, "service", err)
+	}
+	return instance.LastOperation.Type + " " + instance.LastOperation.State, nil
+}
+
+// BindService binds a service instance to an application. An existing binding is not an error.
+func (c *Courier) BindService(appName, serviceName string) ([]byte, error) {
+	return c.run("bind-service", func(ctx context.Context, out io.Writer) error {
+		fmt.Fprintf(out, "Binding service %s to app %s in org %s / space %s as %s...\n", serviceName, appName, c.org.Name, c.space.Name, c.username)
+
+		app, instance, bindings, err := c.bindings(ctx, appName, serviceName)
+		if err != nil {
+			return err
+		}
+		if len(bindings.Resources) > 0 {
+			fmt.Fprintf(out, "App %s is already bound to %s.\n", appName, serviceName)
+			return nil
+		}
+
+		location, err := c.call(ctx, "POST", "/v3/service_credential_bindings", map[string]interface{}{
+			"type":          "app",
+			"relationships": relationships("app", app.GUID, "service_instance", instance.GUID),
+		}, nil)
+		if err != nil {
+			return err
+		}
+		return c.waitForJob(ctx, "bind-service", location)
+	})
+}
+
+// UnbindService removes the bindings of a service instance to an application.
+func (c *Courier) UnbindService(appName, serviceName string) ([]byte, error) {
+	return c.run("unbind-service", func(ctx context.Context, out io.Writer) error {
+		fmt.Fprintf(out, "Unbinding app %s from service %s in org %s / space %s as %s...\n", appName, serviceName, c.org.Name, c.space.Name, c.username)
+
+		_, _, bindings, err := c.bindings(ctx, appName, serviceName)
+		if err != nil {
+			return err
+		}
+		if len(bindings.Resources) == 0 {
+			fmt.Fprintf(out, "Binding between %s and %s did not exist\n", serviceName, appName)
+			return nil
+		}
+
+		for _, binding := range bindings.Resources {
+			location, err := c.call(ctx, "DELETE", "/v3/service_credential_bindings/"+binding.GUID, nil, nil)
+			if err != nil {
+				return err
+			}
+			if err = c.waitForJob(ctx, "unbind-service", location); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (c *Courier) bindings(ctx context.Context, appName, serviceName string) (resource, resource, list, error) {
+	var bindings list
+
+	app, err := c.app(ctx, appName)
+	if err != nil {
+		return app, resource{}, bindings, err
+	}
+
+	instance, err := c.serviceInstance(ctx, serviceName)
+	if err != nil {
+		return app, instance, bindings, err
+	}
+
+	_, err = c.call(ctx, "GET", fmt.Sprintf("/v3/service_credential_bindings?app_guids=%s&service_instance_guids=%s", app.GUID, instance.GUID), nil, &bindings)
+	return app, instance, bindings, err
+}
+
+// DeleteService deletes a service instance and waits until the broker has deprovisioned it. A service
+// instance that does not exist is not an error, like with cf delete-service -f.
+func (c *Courier) DeleteService(serviceName string) ([]byte, error) {
+	return c.run("delete-service", func(ctx context.Context, out io.Writer) error {
+		fmt.Fprintf(out, "Deleting service %s in org %s / space %s as %s...\n", serviceName, c.org.Name, c.space.Name, c.username)
+
+		instance, err := c.serviceInstance(ctx, serviceName)
+		if isNotFound(err) {
+			fmt.Fprintf(out, "Service %s does not exist.\n", serviceName)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		location, err := c.call(ctx, "DELETE", "/v3/service_instances/"+instance.GUID, nil, nil)
+		if err != nil {
+			return err
+		}
+		return c.waitForJob(ctx, "delete-service", location)
+	})
+}
+
+// Cups creates a user-provided service instance. The credentials are a JSON object. The credentials,
+// syslog drain URL and route service URL are only set when they are not empty.
+func (c *Courier) Cups(serviceName string, credentials string, syslogDrainURL string, routeServiceURL string) ([]byte, error) {
+	return c.run("cups", func(ctx context.Context, out io.Writer) error {
+		fmt.Fprintf(out, "Creating user provided service %s in org %s / space %s as %s...\n", serviceName, c.org.Name, c.space.Name, c.username)
+
+		body, err := userProvidedServiceBody(serviceName, credentials, syslogDrainURL, routeServiceURL)
+		if err != nil {
+			return err
+		}
+		body["type"] = "user-provided"
+		body["name"] = serviceName
+		body["relationships"] = relationships("space", c.space.GUID)
+
+		_, err = c.call(ctx, "POST", "/v3/service_instances", body, nil)
+		return err
+	})
+}
+
+// Uups updates a user-provided service instance. Only the attributes that are not empty are changed.
+func (c *Courier) Uups(serviceName string, credentials string, syslogDrainURL string, routeServiceURL string) ([]byte, error) {
+	return c.run("uups", func(ctx context.Context, out io.Writer) error {
+		fmt.Fprintf(out, "Updating user provided service %s in org %s / space %s as %s...\n", serviceName, c.org.Name, c.space.Name, c.username)
+
+		instance, err := c.serviceInstance(ctx, serviceName)
+		if err != nil {
+			return err
+		}
+
+		body, err := userProvidedServiceBody(serviceName, credentials, syslogDrainURL, routeServiceURL)
+		if err != nil {
+			return err
+		}
+
+		_, err = c.call(ctx, "PATCH", "/v3/service_instances/"+instance.GUID, body, nil)
+		return err
+	})
+}
+
+// UserProvidedService returns the credentials, syslog drain URL and route service URL of a
+// user-provided service instance.
+func (c *Courier) UserProvidedService(serviceName string) (S.UserProvidedService, error) {
+	ctx, cancel := c.context("service")
+	defer cancel()
+
+	if c.client == nil {
+		return S.UserProvidedService{}, NotLoggedInError{}
+	}
+
+	instance, err := c.serviceInstance(ctx, serviceName)
+	if err != nil {
+		return S.UserProvidedService{}, c.timeout(ctx, "service", err)
+	}
+
+	service := S.UserProvidedService{
+		Name:            serviceName,
+		SyslogDrainURL:  instance.SyslogDrainURL,
+		RouteServiceURL: instance.RouteServiceURL,
+	}
+	_, err = c.call(ctx, "GET", "/v3/service_instances/"+instance.GUID+"/credentials", nil, &service.Credentials)
+	if err != nil {
+		return S.UserProvidedService{}, c.timeout(ctx, "service", err)
+	}
+	return service, nil
+}
+
+// Services returns the names of the service instances of the targeted space.
+func (c *Courier) Services() ([]string, error) {
+	ctx, cancel := c.context("services")
+	defer cancel()
+
+	if c.client == nil {
+		return []string{}, NotLoggedInError{}
+	}
+
+	var instances list
+	_, err := c.call(ctx, "GET", fmt.Sprintf("/v3/service_instances?space_guids=%s&per_page=5000", c.space.GUID), nil, &instances)
+	if err != nil {
+		return []string{}, c.timeout(ctx, "services", err)
+	}
+
+	names := make([]string, len(instances.Resources))
+	for i, instance := range instances.Resources {
+		names[i] = instance.Name
+	}
+	return names, nil
+}
+
+func userProvidedServiceBody(serviceName, credentials, syslogDrainURL, routeServiceURL string) (map[string]interface{}, error) {
+	body := map[string]interface{}{}
+	if credentials != "" {
+		object, err := jsonObject(credentials)
+		if err != nil {
+			return nil, InvalidJSONObjectError{"the credentials of " + serviceName, err}
+		}
+		body["credentials"] = object
+	}
+	if syslogDrainURL != "" {
+		body["syslog_drain_url"] = syslogDrainURL
+	}
+	if routeServiceURL != "" {
+		body["route_service_url"] = routeServiceURL
+	}
+	return body, nil
+}
+
+// jsonObject parses a JSON object, such as the parameters of a service instance.
+func jsonObject(data string) (map[string]interface{}, error) {
+	object := map[string]interface{}{}
+	err := json.Unmarshal([]byte(data), &object)
+	return object, err
+}
@@ -2,13 +2,26 @@
 package courier
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	I "github.com/compozed/deployadactyl/interfaces"
+	S "github.com/compozed/deployadactyl/structs"
 	"github.com/go-errors/errors"
 )
 
+var (
+	appInstanceLine   = regexp.MustCompile(`^#(\d+)\s+(\S+)\s+(\S+)`)
+	serviceStatusLine = regexp.MustCompile(`(?im)^\s*status:\s*(.+?)\s*$`)
+)
+
 type CourierConstructor func(executor I.Executor) I.Courier
 
 func NewCourier(executor I.Executor) I.Courier {
@@ -20,98 +33,173 @@ func NewCourier(executor I.Executor) I.Courier {
 // Courier has an Executor to execute Cloud Foundry commands.
 type Courier struct {
 	Executor I.Executor
+
+	// Output receives the output of the commands that change a foundation, such as push, line by line
+	// as it arrives. Nothing is streamed when it is nil.
+	Output io.Writer
 }
 
-// Login runs the Cloud Foundry login command.
+// Streaming returns a copy of the courier that streams the output of its commands to the writer.
+func (c Courier) Streaming(output io.Writer) I.Courier {
+	c.Output = output
+	return c
+}
+
+// Login runs the Cloud Foundry api, auth and target commands. The credentials are passed to cf auth
+// in CF_USERNAME and CF_PASSWORD rather than as arguments, so that they do not show up in the process list.
 //
 // Returns the combined standard output and standard error.
 func (c Courier) Login(foundationURL, username, password, org, space string, skipSSL bool) ([]byte, error) {
-	var s string
+	args := []string{"api", foundationURL}
 	if skipSSL {
-		s = "--skip-ssl-validation"
+		args = append(args, "--skip-ssl-validation")
 	}
 
-	return c.Executor.Execute("login", "-a", foundationURL, "-u", username, "-p", password, "-o", org, "-s", space, s)
+	output, err := c.Executor.Execute(args...)
+	if err != nil {
+		return output, err
+	}
+
+	out, err := c.Executor.ExecuteWithEnvironment(map[string]string{"CF_USERNAME": username, "CF_PASSWORD": password}, "auth")
+	output = append(output, out...)
+	if err != nil {
+		return output, err
+	}
+
+	out, err = c.Executor.Execute("target", "-o", org, "-s", space)
+	return append(output, out...), err
 }
 
 func (c Courier) CreateService(service, plan, name string) ([]byte, error) {
-	return c.Executor.Execute("create-service", service, plan, name)
+	return c.execute("create-service", service, plan, name)
+}
+
+// CreateServiceWithParams runs the Cloud Foundry create-service command with arbitrary parameters as JSON.
+//
+// Returns the combined standard output and standard error.
+func (c Courier) CreateServiceWithParams(service, plan, name, params string) ([]byte, error) {
+	return c.execute("create-service", service, plan, name, "-c", params)
+}
+
+// ServiceStatus returns the status of the last operation on a service instance from cf service,
+// such as create in progress or create succeeded.
+func (c Courier) ServiceStatus(serviceName string) (string, error) {
+	output, err := c.Executor.Execute("service", serviceName)
+	if err != nil {
+		return "", fmt.Errorf("cf service %s failed: %s: %s", serviceName, err, strings.TrimSpace(string(output)))
+	}
+
+	match := serviceStatusLine.FindSubmatch(output)
+	if match == nil {
+		return "", fmt.Errorf("cf service %s has no status", serviceName)
+	}
+
+	return string(match[1]), nil
 }
 
 func (c Courier) BindService(appName, dbName string) ([]byte, error) {
-	return c.Executor.Execute("bind-service", appName, dbName)
+	return c.execute("bind-service", appName, dbName)
 }
 
 func (c Courier) UnbindService(appName, dbName string) ([]byte, error) {
-	return c.Executor.Execute("unbind-service", appName, dbName)
+	return c.execute("unbind-service", appName, dbName)
 }
 
 func (c Courier) DeleteService(serviceName string) ([]byte, error) {
-	return c.Executor.Execute("delete-service", serviceName, "-f")
+	return c.execute("delete-service", serviceName, "-f")
 }
 
 func (c Courier) Restage(appName string) ([]byte, error) {
-	return c.Executor.Execute("restage", appName)
+	return c.execute("restage", appName)
+}
+
+// RunTask runs the Cloud Foundry run-task command and waits for the task to finish.
+//
+// Returns the combined standard output and standard error.
+func (c Courier) RunTask(appName, command, name string) ([]byte, error) {
+	return c.execute("run-task", appName, "--command", command, "--name", name, "--wait")
 }
 
 func (c Courier) Start(appName string) ([]byte, error) {
-	return c.Executor.Execute("start", appName)
+	return c.execute("start", appName)
 }
 
 func (c Courier) Stop(appName string) ([]byte, error) {
-	return c.Executor.Execute("stop", appName)
+	return c.execute("stop", appName)
 }
 
 // Delete runs the Cloud Foundry delete command.
 // Returns the combined standard output and standard error.
 func (c Courier) Delete(appName string) ([]byte, error) {
-	return c.Executor.Execute("delete", appName, "-f")
+	return c.execute("delete", appName, "-f")
 }
 
 // Push runs the Cloud Foundry push command.
 //
 // Returns the combined standard output and standard error.
 func (c Courier) Push(appName, appLocation, hostname string, instances uint16) ([]byte, error) {
-	return c.Executor.ExecuteInDirectory(appLocation, "push", appName, "-i", fmt.Sprint(instances), "-n", hostname)
+	return c.executeInDirectory(appLocation, "push", appName, "-i", fmt.Sprint(instances), "-n", hostname)
+}
+
+// PushWithStrategy runs the Cloud Foundry push command with a deployment strategy such as rolling.
+//
+// Returns the combined standard output and standard error.
+func (c Courier) PushWithStrategy(appName, appLocation, hostname string, instances uint16, strategy string) ([]byte, error) {
+	return c.executeInDirectory(appLocation, "push", appName, "-i", fmt.Sprint(instances), "-n", hostname, "--strategy", strategy)
+}
+
+// PushWithoutStart runs the Cloud Foundry push command without starting the application, so that
+// services can be bound to it before it is staged.
+//
+// Returns the combined standard output and standard error.
+func (c Courier) PushWithoutStart(appName, appLocation, hostname string, instances uint16) ([]byte, error) {
+	return c.executeInDirectory(appLocation, "push", appName, "-i", fmt.Sprint(instances), "-n", hostname, "--no-start")
 }
 
 // Rename runs the Cloud Foundry rename command.
 //
 // Returns the combined standard output and standard error.
 func (c Courier) Rename(appName, newAppName string) ([]byte, error) {
-	return c.Executor.Execute("rename", appName, newAppName)
+	return c.execute("rename", appName, newAppName)
 }
 
 // MapRoute runs the Cloud Foundry map-route command and added path arguement
 //
 // Returns the combined standard output and standard error.
 func (c Courier) MapRouteWithPath(appName, domain, hostname, path string) ([]byte, error) {
-	return c.Executor.Execute("map-route", appName, domain, "-n", hostname, "--path", path)
+	return c.execute("map-route", appName, domain, "-n", hostname, "--path", path)
 }
 
 // MapRoute runs the Cloud Foundry map-route command.
 //
 // Returns the combined standard output and standard error.
 func (c Courier) MapRoute(appName, domain, hostname string) ([]byte, error) {
-	return c.Executor.Execute("map-route", appName, domain, "-n", hostname)
+	return c.execute("map-route", appName, domain, "-n", hostname)
 }
 
 // UnmapRoute runs the Cloud Foundry unmap-route command.
 //
 // Returns the combined standard output and standard error.
 func (c Courier) UnmapRouteWithPath(appName, domain, hostname, path string) ([]byte, error) {
-	return c.Executor.Execute("unmap-route", appName, domain, "-n", hostname, "--path", path)
+	return c.execute("unmap-route", appName, domain, "-n", hostname, "--path", path)
 }
 
 // UnmapRoute runs the Cloud Foundry unmap-route command.
 //
 // Returns the combined standard output and standard error.
 func (c Courier) UnmapRoute(appName, domain, hostname string) ([]byte, error) {
-	return c.Executor.Execute("unmap-route", appName, domain, "-n", hostname)
+	return c.execute("unmap-route", appName, domain, "-n", hostname)
 }
 
 func (c Courier) DeleteRoute(domain, hostname string) ([]byte, error) {
-	return c.Executor.Execute("delete-route", domain, "-n", hostname, "-f")
+	return c.execute("delete-route", domain, "-n", hostname, "-f")
+}
+
+// DeleteRouteWithPath runs the Cloud Foundry delete-route command for a route with a path.
+//
+// Returns the combined standard output and standard error.
+func (c Courier) DeleteRouteWithPath(domain, hostname, path string) ([]byte, error) {
+	return c.execute("delete-route", domain, "-n", hostname, "--path", path, "-f")
 }
 
 // Logs runs the Cloud Foundry logs command.
@@ -123,16 +211,121 @@ func (c Courier) Logs(appName string) ([]byte, error) {
 }
 
 // Cups runs the Cloud Foundry CUPS command to create user provided
-// services.
+// services. The credentials, syslog drain URL and route service URL are
+// only passed when they are not empty. The credentials are passed in a
+// file that only the user can read, so that they do not show up in the
+// arguments of the cf process.
 //
 // Returns the combined standard output and standard error.
-func (c Courier) Cups(appName string, body string) ([]byte, error) {
-	return c.Executor.Execute("cups", appName, "-p", body)
+func (c Courier) Cups(serviceName string, credentials string, syslogDrainURL string, routeServiceURL string) ([]byte, error) {
+	return c.userProvidedService("cups", serviceName, credentials, syslogDrainURL, routeServiceURL)
 }
 
 // Uups runs the Cloud Foundry UUPS command to update a user provided serivce
-func (c Courier) Uups(appName string, body string) ([]byte, error) {
-	return c.Executor.Execute("uups", appName, "-p", body)
+func (c Courier) Uups(serviceName string, credentials string, syslogDrainURL string, routeServiceURL string) ([]byte, error) {
+	return c.userProvidedService("uups", serviceName, credentials, syslogDrainURL, routeServiceURL)
+}
+
+// UserProvidedService returns the credentials, syslog drain URL and route service URL of a
+// user-provided service instance, from cf curl.
+func (c Courier) UserProvidedService(serviceName string) (S.UserProvidedService, error) {
+	output, err := c.Executor.Execute("service", serviceName, "--guid")
+	if err != nil {
+		return S.UserProvidedService{}, fmt.Errorf("cf service %s --guid failed: %s: %s", serviceName, err, strings.TrimSpace(string(output)))
+	}
+	guid := strings.TrimSpace(string(output))
+
+	var instance struct {
+		SyslogDrainURL  string `json:"syslog_drain_url"`
+		RouteServiceURL string `json:"route_service_url"`
+	}
+	err = c.curlJSON("/v3/service_instances/"+guid, &instance)
+	if err != nil {
+		return S.UserProvidedService{}, err
+	}
+
+	service := S.UserProvidedService{
+		Name:            serviceName,
+		SyslogDrainURL:  instance.SyslogDrainURL,
+		RouteServiceURL: instance.RouteServiceURL,
+	}
+	err = c.curlJSON("/v3/service_instances/"+guid+"/credentials", &service.Credentials)
+	if err != nil {
+		return S.UserProvidedService{}, err
+	}
+
+	return service, nil
+}
+
+// curlJSON reads the response body of a path of the Cloud Controller API into v.
+//
+// Returns the first error of the API when the body has any.
+func (c Courier) curlJSON(path string, v interface{}) error {
+	body, err := c.Curl(path)
+	if err != nil {
+		return err
+	}
+
+	var apiErrors struct {
+		Errors []struct {
+			Detail string `json:"detail"`
+		} `json:"errors"`
+	}
+	if json.Unmarshal(body, &apiErrors) == nil && len(apiErrors.Errors) > 0 {
+		return fmt.Errorf("cf curl %s failed: %s", path, apiErrors.Errors[0].Detail)
+	}
+
+	err = json.Unmarshal(body, v)
+	if err != nil {
+		return fmt.Errorf("cf curl %s returned an invalid body: %s", path, err)
+	}
+	return nil
+}
+
+// userProvidedService runs cups or uups with the credentials written to a temporary JSON file with
+// mode 0600, which is removed once the command is done.
+func (c Courier) userProvidedService(command, serviceName, credentials, syslogDrainURL, routeServiceURL string) ([]byte, error) {
+	args := []string{command, serviceName}
+	if credentials != "" {
+		file, err := ioutil.TempFile("", "credentials-*.json")
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(file.Name())
+
+		_, err = file.WriteString(credentials)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		args = append(args, "-p", file.Name())
+	}
+	if syslogDrainURL != "" {
+		args = append(args, "-l", syslogDrainURL)
+	}
+	if routeServiceURL != "" {
+		args = append(args, "-r", routeServiceURL)
+	}
+	return c.execute(args...)
+}
+
+// execute runs a command that changes the foundation, streaming its output when the courier has an Output.
+func (c Courier) execute(args ...string) ([]byte, error) {
+	if c.Output != nil {
+		return c.Executor.ExecuteStreaming(c.Output, "", args...)
+	}
+	return c.Executor.Execute(args...)
+}
+
+// executeInDirectory does the same thing as execute does, in a specific directory.
+func (c Courier) executeInDirectory(directory string, args ...string) ([]byte, error) {
+	if c.Output != nil {
+		return c.Executor.ExecuteStreaming(c.Output, directory, args...)
+	}
+	return c.Executor.ExecuteInDirectory(directory, args...)
 }
 
 // Exists checks to see whether the application name exists already.
@@ -157,6 +350,46 @@ func (c Courier) Domains() ([]string, error) {
 	return domains, err
 }
 
+// AppInstances returns the state of every instance of an application from cf app.
+//
+// Returns an error when an instance cannot be read or there are none, rather than instances without
+// their start time.
+func (c Courier) AppInstances(appName string) ([]S.AppInstance, error) {
+	output, err := c.Executor.Execute("app", appName)
+	if err != nil {
+		return nil, fmt.Errorf("cf app %s failed: %s: %s", appName, err, strings.TrimSpace(string(output)))
+	}
+
+	instances := []S.AppInstance{}
+	for _, line := range strings.Split(string(output), "\n") {
+		match := appInstanceLine.FindStringSubmatch(strings.TrimSpace(line))
+		if match == nil {
+			continue
+		}
+
+		index, _ := strconv.Atoi(match[1])
+		since, err := time.Parse(time.RFC3339, match[3])
+		if err != nil {
+			return nil, fmt.Errorf("cannot read instance #%d of %s from cf app: %s", index, appName, err)
+		}
+
+		instances = append(instances, S.AppInstance{Index: index, State: match[2], Since: since})
+	}
+
+	if len(instances) == 0 {
+		return nil, fmt.Errorf("cf app %s shows no instances: %s", appName, strings.TrimSpace(string(output)))
+	}
+
+	return instances, nil
+}
+
+// Interrupt kills the commands the Executor is running, when it can be interrupted.
+func (c Courier) Interrupt() {
+	if interruptible, ok := c.Executor.(I.Interruptible); ok {
+		interruptible.Interrupt()
+	}
+}
+
 // CleanUp removes the temporary directory created by the Executor.
 func (c Courier) CleanUp() error {
 	return c.Executor.CleanUp()
@@ -177,3 +410,23 @@ func (c Courier) Services() ([]string, error) {
 	}
 	return services, nil
 }
+
+// Curl runs cf curl against a path of the Cloud Controller API, such as /v3/spaces?names=dev.
+//
+// Returns the response body. Errors of the API are in the body rather than in the error.
+func (c Courier) Curl(path string) ([]byte, error) {
+	output, err := c.Executor.Execute("curl", path)
+	if err != nil {
+		return nil, fmt.Errorf("cf curl %s failed: %s: %s", path, err, strings.TrimSpace(string(output)))
+	}
+	return output, nil
+}
+
+// OAuthToken returns the access token of the logged in user from cf oauth-token, with its bearer prefix.
+func (c Courier) OAuthToken() (string, error) {
+	output, err := c.Executor.Execute("oauth-token")
+	if err != nil {
+		return "", fmt.Errorf("cf oauth-token failed: %s: %s", err, strings.TrimSpace(string(output)))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
@@ -1,18 +1,43 @@
 package courier_test
 
 import (
+	"bytes"
 	"fmt"
 	. "github.com/compozed/deployadactyl/controller/deployer/bluegreen/courier"
+	"io/ioutil"
 	"math/rand"
+	"os"
+	"time"
 
 	"errors"
 	"github.com/compozed/deployadactyl/interfaces"
 	"github.com/compozed/deployadactyl/mocks"
 	"github.com/compozed/deployadactyl/randomizer"
+	S "github.com/compozed/deployadactyl/structs"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
 
+// credentialsExecutor reads the file passed to cf with -p while the command runs, since it is removed
+// once the command is done.
+type credentialsExecutor struct {
+	*mocks.Executor
+	credentials []byte
+	mode        os.FileMode
+}
+
+func (e *credentialsExecutor) Execute(args ...string) ([]byte, error) {
+	for i, arg := range args {
+		if arg == "-p" && i+1 < len(args) {
+			e.credentials, _ = ioutil.ReadFile(args[i+1])
+			if info, err := os.Stat(args[i+1]); err == nil {
+				e.mode = info.Mode().Perm()
+			}
+		}
+	}
+	return e.Executor.Execute(args...)
+}
+
 var _ = Describe("Courier", func() {
 	var (
 		appName  string
@@ -32,50 +57,102 @@ var _ = Describe("Courier", func() {
 		}
 	})
 
-	Describe("Login", func() {
-		It("should get a valid Cloud Foundry login command", func() {
-			var (
-				foundationURL = "foundationURL-" + randomizer.StringRunes(10)
-				org           = "org-" + randomizer.StringRunes(10)
-				password      = "password-" + randomizer.StringRunes(10)
-				space         = "space-" + randomizer.StringRunes(10)
-				user          = "user-" + randomizer.StringRunes(10)
-				skipSSL       = false
-				expectedArgs  = []string{"login", "-a", foundationURL, "-u", user, "-p", password, "-o", org, "-s", space, ""}
-			)
+	Describe("PushWithStrategy", func() {
+		It("should get a valid Cloud Foundry push command with a deployment strategy", func() {
+			appLocation := "appLocation-" + randomizer.StringRunes(10)
+			expectedArgs := []string{"push", appName, "-i", "2", "-n", hostname, "--strategy", "rolling"}
 
-			executor.ExecuteCall.Returns.Output = []byte(output)
-			executor.ExecuteCall.Returns.Error = nil
+			executor.ExecuteInDirectoryCall.Returns.Output = []byte(output)
 
-			out, err := courier.Login(foundationURL, user, password, org, space, skipSSL)
+			out, err := courier.PushWithStrategy(appName, appLocation, hostname, 2, "rolling")
 			Expect(err).ToNot(HaveOccurred())
 
-			Expect(executor.ExecuteCall.Received.Args).To(Equal(expectedArgs))
+			Expect(executor.ExecuteInDirectoryCall.Received.AppLocation).To(Equal(appLocation))
+			Expect(executor.ExecuteInDirectoryCall.Received.Args).To(Equal(expectedArgs))
 			Expect(string(out)).To(Equal(output))
 		})
+	})
 
-		It("can skip ssl validation", func() {
-			var (
-				foundationURL = "foundationURL-" + randomizer.StringRunes(10)
-				org           = "org-" + randomizer.StringRunes(10)
-				password      = "password-" + randomizer.StringRunes(10)
-				space         = "space-" + randomizer.StringRunes(10)
-				user          = "user-" + randomizer.StringRunes(10)
-				skipSSL       = true
-				expectedArgs  = []string{"login", "-a", foundationURL, "-u", user, "-p", password, "-o", org, "-s", space, "--skip-ssl-validation"}
-			)
+	Describe("PushWithoutStart", func() {
+		It("should get a valid Cloud Foundry push command that does not start the application", func() {
+			appLocation := "appLocation-" + randomizer.StringRunes(10)
+			expectedArgs := []string{"push", appName, "-i", "2", "-n", hostname, "--no-start"}
 
-			executor.ExecuteCall.Returns.Output = []byte(output)
-			executor.ExecuteCall.Returns.Error = nil
+			executor.ExecuteInDirectoryCall.Returns.Output = []byte(output)
 
-			out, err := courier.Login(foundationURL, user, password, org, space, skipSSL)
+			out, err := courier.PushWithoutStart(appName, appLocation, hostname, 2)
 			Expect(err).ToNot(HaveOccurred())
 
-			Expect(executor.ExecuteCall.Received.Args).To(Equal(expectedArgs))
+			Expect(executor.ExecuteInDirectoryCall.Received.AppLocation).To(Equal(appLocation))
+			Expect(executor.ExecuteInDirectoryCall.Received.Args).To(Equal(expectedArgs))
 			Expect(string(out)).To(Equal(output))
 		})
 	})
 
+	Describe("Login", func() {
+		var (
+			foundationURL string
+			org           string
+			password      string
+			space         string
+			user          string
+		)
+
+		BeforeEach(func() {
+			foundationURL = "foundationURL-" + randomizer.StringRunes(10)
+			org = "org-" + randomizer.StringRunes(10)
+			password = "password-" + randomizer.StringRunes(10)
+			space = "space-" + randomizer.StringRunes(10)
+			user = "user-" + randomizer.StringRunes(10)
+		})
+
+		It("should target the api, authenticate and target the org and space", func() {
+			executor.ExecuteCall.Returns.Output = []byte(output)
+			executor.ExecuteWithEnvironmentCall.Returns.Output = []byte("authenticated ")
+
+			out, err := courier.Login(foundationURL, user, password, org, space, false)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteCall.Received.AllArgs).To(Equal([][]string{
+				{"api", foundationURL},
+				{"target", "-o", org, "-s", space},
+			}))
+			Expect(string(out)).To(Equal(output + "authenticated " + output))
+		})
+
+		It("should pass the credentials in the environment rather than as arguments", func() {
+			_, err := courier.Login(foundationURL, user, password, org, space, false)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteWithEnvironmentCall.Received.Args).To(Equal([]string{"auth"}))
+			Expect(executor.ExecuteWithEnvironmentCall.Received.Environment).To(Equal(map[string]string{
+				"CF_USERNAME": user,
+				"CF_PASSWORD": password,
+			}))
+			for _, args := range executor.ExecuteCall.Received.AllArgs {
+				Expect(args).ToNot(ContainElement(password))
+			}
+		})
+
+		It("can skip ssl validation", func() {
+			_, err := courier.Login(foundationURL, user, password, org, space, true)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteCall.Received.AllArgs[0]).To(Equal([]string{"api", foundationURL, "--skip-ssl-validation"}))
+		})
+
+		It("should not target when authentication fails", func() {
+			executor.ExecuteWithEnvironmentCall.Returns.Output = []byte("Credentials were rejected")
+			executor.ExecuteWithEnvironmentCall.Returns.Error = errors.New("exit status 1")
+
+			out, err := courier.Login(foundationURL, user, password, org, space, false)
+
+			Expect(err).To(MatchError("exit status 1"))
+			Expect(string(out)).To(ContainSubstring("Credentials were rejected"))
+			Expect(executor.ExecuteCall.Received.AllArgs).To(HaveLen(1))
+		})
+	})
+
 	Describe("starting an app", func() {
 		It("should send a valid Cloud Foundry start command", func() {
 			expectedArgs := []string{"start", appName}
@@ -140,6 +217,53 @@ var _ = Describe("Courier", func() {
 		})
 	})
 
+	Describe("streaming the output of commands", func() {
+		var stream *bytes.Buffer
+
+		BeforeEach(func() {
+			stream = &bytes.Buffer{}
+			courier = courier.(interfaces.StreamingCourier).Streaming(stream)
+		})
+
+		It("streams the output of push from the app directory", func() {
+			var (
+				appLocation  = "appLocation-" + randomizer.StringRunes(10)
+				expectedArgs = []string{"push", appName, "-i", "2", "-n", hostname}
+			)
+
+			executor.ExecuteStreamingCall.Returns.Output = []byte(output)
+
+			out, err := courier.Push(appName, appLocation, hostname, 2)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteStreamingCall.Received.Directory).To(Equal(appLocation))
+			Expect(executor.ExecuteStreamingCall.Received.Args).To(Equal(expectedArgs))
+			Expect(string(out)).To(Equal(output))
+			Expect(stream.String()).To(Equal(output))
+		})
+
+		It("streams the output of commands that change the foundation", func() {
+			executor.ExecuteStreamingCall.Returns.Output = []byte(output)
+
+			_, err := courier.Stop(appName)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteStreamingCall.Received.Directory).To(BeEmpty())
+			Expect(executor.ExecuteStreamingCall.Received.Args).To(Equal([]string{"stop", appName}))
+			Expect(executor.ExecuteCall.Received.Args).To(BeNil())
+		})
+
+		It("does not stream the output of queries", func() {
+			executor.ExecuteCall.Returns.Output = []byte("bearer token")
+
+			_, err := courier.OAuthToken()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteCall.Received.Args).To(Equal([]string{"oauth-token"}))
+			Expect(stream.String()).To(BeEmpty())
+		})
+	})
+
 	Describe("renaming an app", func() {
 		It("should get a valid Cloud Foundry rename command", func() {
 			var (
@@ -242,6 +366,23 @@ var _ = Describe("Courier", func() {
 			Expect(executor.ExecuteCall.Received.Args).To(Equal(expectedArgs))
 			Expect(string(out)).To(Equal(output))
 		})
+
+		It("should delete route with hostname, domain and path", func() {
+			var (
+				domain       = "domain-" + randomizer.StringRunes(10)
+				path         = "path-" + randomizer.StringRunes(10)
+				expectedArgs = []string{"delete-route", domain, "-n", hostname, "--path", path, "-f"}
+			)
+
+			executor.ExecuteCall.Returns.Output = []byte(output)
+			executor.ExecuteCall.Returns.Error = nil
+
+			out, err := courier.DeleteRouteWithPath(domain, hostname, path)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteCall.Received.Args).To(Equal(expectedArgs))
+			Expect(string(out)).To(Equal(output))
+		})
 	})
 
 	Describe("getting the logs for an application", func() {
@@ -275,23 +416,43 @@ var _ = Describe("Courier", func() {
 	Describe("creating user provided services", func() {
 		It("should get a valid Cloud Foundry Cups command", func() {
 			var (
-				hostName     = "hostName-" + randomizer.StringRunes(10)
-				address      = "address-" + randomizer.StringRunes(10)
-				body         = fmt.Sprintf("{%s:%s}", hostName, address)
-				expectedArgs = []string{"cups", appName, "-p", body}
+				hostName = "hostName-" + randomizer.StringRunes(10)
+				address  = "address-" + randomizer.StringRunes(10)
+				body     = fmt.Sprintf("{%s:%s}", hostName, address)
+				files    = &credentialsExecutor{Executor: executor}
 			)
+			courier = Courier{Executor: files}
 
 			executor.ExecuteCall.Returns.Output = []byte(output)
 			executor.ExecuteCall.Returns.Error = nil
 
-			out, err := courier.Cups(appName, body)
+			out, err := courier.Cups(appName, body, "", "")
 			Expect(err).ToNot(HaveOccurred())
 
-			Expect(executor.ExecuteCall.Received.Args).To(Equal(expectedArgs))
+			args := executor.ExecuteCall.Received.Args
+			Expect(args).To(HaveLen(4))
+			Expect(args[:3]).To(Equal([]string{"cups", appName, "-p"}))
+			Expect(args[3]).ToNot(ContainSubstring(address))
+			Expect(string(files.credentials)).To(Equal(body))
+			Expect(files.mode).To(Equal(os.FileMode(0600)))
+			Expect(args[3]).ToNot(BeAnExistingFile())
 			Expect(string(out)).To(Equal(output))
 		})
 	})
 
+	Describe("creating user provided services with a syslog drain and a route service", func() {
+		It("only passes the options that are set", func() {
+			expectedArgs := []string{"cups", appName, "-l", "syslog://logs.example.com", "-r", "https://auth.example.com"}
+
+			executor.ExecuteCall.Returns.Output = []byte(output)
+
+			_, err := courier.Cups(appName, "", "syslog://logs.example.com", "https://auth.example.com")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteCall.Received.Args).To(Equal(expectedArgs))
+		})
+	})
+
 	Describe("creating a service", func() {
 		It("should create the service", func() {
 			var (
@@ -312,6 +473,92 @@ var _ = Describe("Courier", func() {
 		})
 	})
 
+	Describe("creating a service with params", func() {
+		It("should pass the params as JSON", func() {
+			executor.ExecuteCall.Returns.Output = []byte(output)
+
+			out, err := courier.CreateServiceWithParams("p-mysql", "small", "db", `{"size":1}`)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteCall.Received.Args).To(Equal([]string{"create-service", "p-mysql", "small", "db", "-c", `{"size":1}`}))
+			Expect(string(out)).To(Equal(output))
+		})
+	})
+
+	Describe("ServiceStatus", func() {
+		It("returns the status of the last operation on the service instance", func() {
+			executor.ExecuteCall.Returns.Output = []byte(`Showing info of service db in org my-org / space my-space as admin...
+
+name:            db
+service:         p-mysql
+plan:            small
+
+Showing status of last operation from service db...
+
+status:    create in progress
+message:   provisioning
+`)
+
+			status, err := courier.ServiceStatus("db")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteCall.Received.Args).To(Equal([]string{"service", "db"}))
+			Expect(status).To(Equal("create in progress"))
+		})
+
+		It("returns an error when cf service fails", func() {
+			executor.ExecuteCall.Returns.Output = []byte("Service instance db not found")
+			executor.ExecuteCall.Returns.Error = errors.New("exit status 1")
+
+			_, err := courier.ServiceStatus("db")
+
+			Expect(err).To(MatchError(ContainSubstring("Service instance db not found")))
+		})
+
+		It("returns an error when there is no status", func() {
+			executor.ExecuteCall.Returns.Output = []byte("name: db")
+
+			_, err := courier.ServiceStatus("db")
+
+			Expect(err).To(MatchError("cf service db has no status"))
+		})
+	})
+
+	Describe("UserProvidedService", func() {
+		It("returns the credentials and URLs of the service instance", func() {
+			executor.ExecuteCall.Returns.Outputs = [][]byte{
+				[]byte("service-guid\n"),
+				[]byte(`{"guid": "service-guid", "syslog_drain_url": "syslog://drain", "route_service_url": ""}`),
+				[]byte(`{"user": "admin"}`),
+			}
+
+			service, err := courier.UserProvidedService("payments-api")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteCall.Received.AllArgs).To(Equal([][]string{
+				{"service", "payments-api", "--guid"},
+				{"curl", "/v3/service_instances/service-guid"},
+				{"curl", "/v3/service_instances/service-guid/credentials"},
+			}))
+			Expect(service).To(Equal(S.UserProvidedService{
+				Name:           "payments-api",
+				Credentials:    map[string]interface{}{"user": "admin"},
+				SyslogDrainURL: "syslog://drain",
+			}))
+		})
+
+		It("returns an error when the API does", func() {
+			executor.ExecuteCall.Returns.Outputs = [][]byte{
+				[]byte("service-guid\n"),
+				[]byte(`{"errors": [{"detail": "You are not authorized to perform the requested action"}]}`),
+			}
+
+			_, err := courier.UserProvidedService("payments-api")
+
+			Expect(err).To(MatchError("cf curl /v3/service_instances/service-guid failed: You are not authorized to perform the requested action"))
+		})
+	})
+
 	Describe("binding a service", func() {
 		It("should bind the service to the app", func() {
 			var (
@@ -389,17 +636,20 @@ var _ = Describe("Courier", func() {
 	Describe("updating user provided services", func() {
 		It("should get a valid Cloud Foundry Uups command", func() {
 			var (
-				hostName     = "hostName-" + randomizer.StringRunes(10)
-				address      = "address-" + randomizer.StringRunes(10)
-				body         = fmt.Sprintf("{%s:%s}", hostName, address)
-				expectedArgs = []string{"uups", appName, "-p", body}
+				hostName = "hostName-" + randomizer.StringRunes(10)
+				address  = "address-" + randomizer.StringRunes(10)
+				body     = fmt.Sprintf("{%s:%s}", hostName, address)
+				files    = &credentialsExecutor{Executor: executor}
 			)
+			courier = Courier{Executor: files}
+
 			executor.ExecuteCall.Returns.Output = []byte(output)
 			executor.ExecuteCall.Returns.Error = nil
 
-			out, err := courier.Uups(appName, body)
+			out, err := courier.Uups(appName, body, "", "")
 			Expect(err).ToNot(HaveOccurred())
-			Expect(executor.ExecuteCall.Received.Args).To(Equal(expectedArgs))
+			Expect(executor.ExecuteCall.Received.Args[:3]).To(Equal([]string{"uups", appName, "-p"}))
+			Expect(string(files.credentials)).To(Equal(body))
 			Expect(string(out)).To(Equal(output))
 		})
 	})
@@ -421,6 +671,59 @@ var _ = Describe("Courier", func() {
 		})
 	})
 
+	Describe("getting the instances of an app", func() {
+		It("parses the instances from cf app", func() {
+			executor.ExecuteCall.Returns.Output = []byte(`Showing health and status for app example in org org / space space as user...
+
+name:              example
+requested state:   started
+
+type:           web
+instances:      2/2
+     state     since                  cpu    memory        disk          details
+#0   running   2019-01-01T10:00:00Z   0.3%   300M of 1G    150M of 1G
+#1   crashed   2019-01-01T10:05:00Z   0.0%   0 of 1G       0 of 1G
+`)
+
+			instances, err := courier.AppInstances(appName)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteCall.Received.Args).To(Equal([]string{"app", appName}))
+			Expect(instances).To(Equal([]S.AppInstance{
+				{Index: 0, State: "running", Since: time.Date(2019, 1, 1, 10, 0, 0, 0, time.UTC)},
+				{Index: 1, State: "crashed", Since: time.Date(2019, 1, 1, 10, 5, 0, 0, time.UTC)},
+			}))
+		})
+
+		It("returns an error when an instance cannot be read", func() {
+			executor.ExecuteCall.Returns.Output = []byte(`
+     state     since                  cpu    memory        disk
+#0   running   2019-01-01 10:00:00 AM   0.0%   0 of 1G       0 of 1G
+`)
+
+			_, err := courier.AppInstances(appName)
+
+			Expect(err).To(MatchError(ContainSubstring("cannot read instance #0")))
+		})
+
+		It("returns an error when there are no instances", func() {
+			executor.ExecuteCall.Returns.Output = []byte("There are no running instances of this process.")
+
+			_, err := courier.AppInstances(appName)
+
+			Expect(err).To(MatchError(ContainSubstring("shows no instances")))
+		})
+
+		It("returns an error when cf app fails", func() {
+			executor.ExecuteCall.Returns.Output = []byte("App example not found")
+			executor.ExecuteCall.Returns.Error = errors.New("exit status 1")
+
+			_, err := courier.AppInstances(appName)
+
+			Expect(err).To(MatchError(ContainSubstring("App example not found")))
+		})
+	})
+
 	Describe("cleaning up executor directories", func() {
 		It("should be successful", func() {
 			executor.CleanUpCall.Returns.Error = nil
@@ -457,4 +760,36 @@ var _ = Describe("Courier", func() {
 			})
 		})
 	})
+	Describe("Curl", func() {
+		It("returns the response body of the path", func() {
+			executor.ExecuteCall.Returns.Output = []byte(`{"resources": []}`)
+
+			body, err := courier.Curl("/v3/stacks?names=cflinuxfs4")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteCall.Received.Args).To(Equal([]string{"curl", "/v3/stacks?names=cflinuxfs4"}))
+			Expect(string(body)).To(Equal(`{"resources": []}`))
+		})
+
+		It("returns an error when cf curl fails", func() {
+			executor.ExecuteCall.Returns.Output = []byte("Not logged in.")
+			executor.ExecuteCall.Returns.Error = errors.New("exit status 1")
+
+			_, err := courier.Curl("/v3/stacks")
+
+			Expect(err).To(MatchError("cf curl /v3/stacks failed: exit status 1: Not logged in."))
+		})
+	})
+
+	Describe("OAuthToken", func() {
+		It("returns the token of the logged in user", func() {
+			executor.ExecuteCall.Returns.Output = []byte("bearer a.b.c\n")
+
+			token, err := courier.OAuthToken()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteCall.Received.Args).To(Equal([]string{"oauth-token"}))
+			Expect(token).To(Equal("bearer a.b.c"))
+		})
+	})
 })
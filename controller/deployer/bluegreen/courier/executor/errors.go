@@ -0,0 +1,30 @@
+package executor
+
+import (
+	"fmt"
+	"time"
+)
+
+// CommandTimeoutError is returned when a Cloud Foundry command was killed because it ran longer than its timeout.
+type CommandTimeoutError struct {
+	Command string
+	After   time.Duration
+}
+
+func (e CommandTimeoutError) Error() string {
+	return fmt.Sprintf("cf %s timed out after %s", e.Command, e.After)
+}
+
+// Timeout reports that the error is a timeout.
+func (e CommandTimeoutError) Timeout() bool {
+	return true
+}
+
+// CommandInterruptedError is returned when a Cloud Foundry command was killed by Interrupt.
+type CommandInterruptedError struct {
+	Command string
+}
+
+func (e CommandInterruptedError) Error() string {
+	return fmt.Sprintf("cf %s was interrupted", e.Command)
+}
@@ -2,15 +2,22 @@
 package executor
 
 import (
+	"bytes"
+	"io"
 	"os"
 	"os/exec"
 	"strings"
+	"time"
 
+	S "github.com/compozed/deployadactyl/structs"
 	"github.com/spf13/afero"
 )
 
-// New returns a new Executor struct.
-func New(fileSystem *afero.Afero) (Executor, error) {
+// loginSubcommands are run by a login. They are bounded by the timeout of login unless they have their own.
+var loginSubcommands = map[string]bool{"api": true, "auth": true, "target": true}
+
+// New returns a new Executor struct. Commands are killed when they run longer than their timeout.
+func New(fileSystem *afero.Afero, timeouts S.Timeouts) (Executor, error) {
 	tempDir, err := fileSystem.TempDir("", "deployadactyl-executor-")
 	if err != nil {
 		return Executor{}, err
@@ -19,6 +26,8 @@ func New(fileSystem *afero.Afero) (Executor, error) {
 	return Executor{
 		fileSystem: fileSystem,
 		tempDir:    tempDir,
+		timeouts:   timeouts,
+		running:    newRunning(),
 	}, nil
 }
 
@@ -26,6 +35,8 @@ func New(fileSystem *afero.Afero) (Executor, error) {
 type Executor struct {
 	tempDir    string
 	fileSystem *afero.Afero
+	timeouts   S.Timeouts
+	running    *running
 }
 
 // Execute takes a slice of string args and runs them together against the cf command on the Cloud Foundry binary.
@@ -34,7 +45,7 @@ type Executor struct {
 func (e Executor) Execute(args ...string) ([]byte, error) {
 	command := exec.Command("cf", args...)
 	command.Env = setEnv(os.Environ(), "CF_HOME", e.tempDir)
-	return command.CombinedOutput()
+	return e.run(command, args, nil)
 }
 
 // ExecuteInDirectory does the same thing as Execute does, but does it in a specific directory.
@@ -44,7 +55,41 @@ func (e Executor) ExecuteInDirectory(directory string, args ...string) ([]byte,
 	command := exec.Command("cf", args...)
 	command.Env = setEnv(os.Environ(), "CF_HOME", e.tempDir)
 	command.Dir = directory
-	return command.CombinedOutput()
+	return e.run(command, args, nil)
+}
+
+// ExecuteWithEnvironment does the same thing as Execute does, with the variables added to the environment
+// of the command. Secrets such as CF_PASSWORD are passed this way so that they do not show up in the
+// process list.
+//
+// Returns the combined standard output and standard error.
+func (e Executor) ExecuteWithEnvironment(environment map[string]string, args ...string) ([]byte, error) {
+	command := exec.Command("cf", args...)
+	command.Env = setEnv(os.Environ(), "CF_HOME", e.tempDir)
+	for key, value := range environment {
+		command.Env = setEnv(command.Env, key, value)
+	}
+	return e.run(command, args, nil)
+}
+
+// ExecuteStreaming does the same thing as Execute does, in a specific directory unless it is empty, and
+// writes each line of the output to the writer as soon as the command prints it. Lines are tagged with
+// the subcommand, such as [push].
+//
+// Returns the combined standard output and standard error, like the other variants.
+func (e Executor) ExecuteStreaming(output io.Writer, directory string, args ...string) ([]byte, error) {
+	command := exec.Command("cf", args...)
+	command.Env = setEnv(os.Environ(), "CF_HOME", e.tempDir)
+	command.Dir = directory
+	return e.run(command, args, output)
+}
+
+// Interrupt kills the commands that are running, along with the processes they started. They return a
+// CommandInterruptedError. Commands run afterwards are not affected.
+func (e Executor) Interrupt() {
+	if e.running != nil {
+		e.running.interrupt()
+	}
 }
 
 // CleanUp removes the temporary directory of the Executor.
@@ -52,6 +97,72 @@ func (e Executor) CleanUp() error {
 	return e.fileSystem.RemoveAll(e.tempDir)
 }
 
+// run starts the command in its own process group and kills the whole group when the timeout of
+// its subcommand expires, so that processes started by the Cloud Foundry CLI are killed as well.
+//
+// Lines are also written to the stream as they arrive when there is one.
+//
+// Returns the combined standard output and standard error and a CommandTimeoutError when it timed out.
+func (e Executor) run(command *exec.Cmd, args []string, stream io.Writer) ([]byte, error) {
+	var subcommand string
+	if len(args) > 0 {
+		subcommand = args[0]
+	}
+
+	lines := NewLineWriter(stream, subcommand)
+
+	if _, ok := e.timeouts.Commands[subcommand]; !ok && loginSubcommands[subcommand] {
+		subcommand = "login"
+	}
+
+	timeout, _ := e.timeouts.GetCommand(subcommand)
+
+	output := &bytes.Buffer{}
+	command.Stdout = output
+	command.Stderr = output
+	if stream != nil {
+		defer lines.Flush()
+
+		writer := io.MultiWriter(output, lines)
+		command.Stdout = writer
+		command.Stderr = writer
+	}
+	setProcessGroup(command)
+
+	if err := command.Start(); err != nil {
+		return output.Bytes(), err
+	}
+
+	if e.running != nil {
+		e.running.add(command)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		err := command.Wait()
+		if e.running != nil && e.running.remove(command) {
+			err = CommandInterruptedError{Command: subcommand}
+		}
+		done <- err
+	}()
+
+	if timeout <= 0 {
+		return output.Bytes(), <-done
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-done:
+		return output.Bytes(), err
+	case <-timer.C:
+		killProcessGroup(command)
+		<-done
+		return output.Bytes(), CommandTimeoutError{Command: subcommand, After: timeout}
+	}
+}
+
 func setEnv(env []string, key, value string) []string {
 	keyValuePair := key + "=" + value
 
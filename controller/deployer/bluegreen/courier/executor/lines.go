@@ -0,0 +1,47 @@
+package executor
+
+import (
+	"bytes"
+	"io"
+)
+
+// LineWriter writes whole lines to its output, each in a single write and tagged with the subcommand
+// they are the output of. A line that is not finished yet is held back until its newline arrives or
+// the LineWriter is flushed.
+type LineWriter struct {
+	output  io.Writer
+	tag     string
+	pending []byte
+}
+
+// NewLineWriter returns a LineWriter that tags lines like [push].
+func NewLineWriter(output io.Writer, subcommand string) *LineWriter {
+	return &LineWriter{output: output, tag: "[" + subcommand + "] "}
+}
+
+func (w *LineWriter) Write(p []byte) (int, error) {
+	w.pending = append(w.pending, p...)
+
+	for {
+		end := bytes.IndexByte(w.pending, '\n')
+		if end < 0 {
+			return len(p), nil
+		}
+
+		w.writeLine(w.pending[:end+1])
+		w.pending = w.pending[end+1:]
+	}
+}
+
+// Flush writes the last line when the output did not end with a newline.
+func (w *LineWriter) Flush() {
+	if len(w.pending) > 0 {
+		w.writeLine(append(w.pending, '\n'))
+		w.pending = nil
+	}
+}
+
+// writeLine ignores the errors of the output, so that a failing stream never fails the command.
+func (w *LineWriter) writeLine(line []byte) {
+	w.output.Write(append([]byte(w.tag), line...))
+}
@@ -0,0 +1,12 @@
+//go:build windows
+// +build windows
+
+package executor
+
+import "os/exec"
+
+func setProcessGroup(command *exec.Cmd) {}
+
+func killProcessGroup(command *exec.Cmd) {
+	command.Process.Kill()
+}
@@ -0,0 +1,49 @@
+package executor
+
+import (
+	"os/exec"
+	"sync"
+)
+
+// running keeps the commands of an Executor that are running, so that they can be interrupted.
+type running struct {
+	lock        sync.Mutex
+	commands    map[*exec.Cmd]bool
+	interrupted map[*exec.Cmd]bool
+}
+
+func newRunning() *running {
+	return &running{
+		commands:    map[*exec.Cmd]bool{},
+		interrupted: map[*exec.Cmd]bool{},
+	}
+}
+
+func (r *running) add(command *exec.Cmd) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.commands[command] = true
+}
+
+// remove forgets the command once it exited, and reports whether it was interrupted.
+func (r *running) remove(command *exec.Cmd) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	interrupted := r.interrupted[command]
+	delete(r.commands, command)
+	delete(r.interrupted, command)
+	return interrupted
+}
+
+// interrupt kills the process group of every running command.
+func (r *running) interrupt() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for command := range r.commands {
+		r.interrupted[command] = true
+		killProcessGroup(command)
+	}
+}
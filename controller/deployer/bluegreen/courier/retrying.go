@@ -0,0 +1,342 @@
+package courier
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"time"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+// DefaultRetryKey sets the number of retries of every command that has none of its own.
+const DefaultRetryKey = "default"
+
+// DefaultTransientPatterns match Cloud Foundry output of failures that usually go away when the command is run again.
+var DefaultTransientPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`Server error, status code: 5\d\d`),
+	regexp.MustCompile(`(?i)502 Bad Gateway|503 Service Unavailable|504 Gateway Time-?out`),
+	regexp.MustCompile(`(?i)error refreshing oauth token|invalid auth token|token (has )?expired`),
+	regexp.MustCompile(`(?i)connection reset by peer|i/o timeout|TLS handshake timeout`),
+}
+
+// RetryPolicy decides which failed Cloud Foundry commands are run again and how long to wait in between.
+type RetryPolicy struct {
+	// Retries is the number of times a command is retried, keyed by cf subcommand such as push or
+	// map-route. The DefaultRetryKey entry applies to commands that are not listed.
+	Retries map[string]int
+
+	// Backoff is the wait before the first retry. It doubles with every retry up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+
+	// Transient matches the output or error of a failure that is worth retrying.
+	Transient []*regexp.Regexp
+}
+
+// DefaultRetryPolicy retries push and route mapping twice when they fail with one of the DefaultTransientPatterns.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		Retries: map[string]int{
+			"login":       2,
+			"push":        2,
+			"map-route":   2,
+			"unmap-route": 2,
+		},
+		Backoff:    5 * time.Second,
+		MaxBackoff: time.Minute,
+		Transient:  DefaultTransientPatterns,
+	}
+}
+
+func (p RetryPolicy) retries(command string) int {
+	if retries, ok := p.Retries[command]; ok {
+		return retries
+	}
+	return p.Retries[DefaultRetryKey]
+}
+
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	backoff := p.Backoff
+	for i := 1; i < retry; i++ {
+		backoff *= 2
+		if p.MaxBackoff > 0 && backoff >= p.MaxBackoff {
+			return p.MaxBackoff
+		}
+	}
+	return backoff
+}
+
+func (p RetryPolicy) isTransient(output []byte, err error) bool {
+	for _, pattern := range p.Transient {
+		if pattern.Match(output) || pattern.MatchString(err.Error()) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithRetries returns a CourierConstructor that decorates the couriers of constructor with a RetryingCourier.
+// It is opt-in: set it as the NewCourier of the CreatorModuleProvider.
+func WithRetries(policy RetryPolicy, log I.Logger, constructor CourierConstructor) CourierConstructor {
+	if constructor == nil {
+		constructor = NewCourier
+	}
+
+	return func(executor I.Executor) I.Courier {
+		return RetryingCourier{
+			Courier: constructor(executor),
+			Policy:  policy,
+			Log:     log,
+		}
+	}
+}
+
+// RetryingCourier runs the commands of a Courier again when they fail with a transient error.
+//
+// Every retry is logged and noted in the output of the command, so that it shows up in the response.
+type RetryingCourier struct {
+	Courier I.Courier
+	Policy  RetryPolicy
+	Log     I.Logger
+}
+
+func (c RetryingCourier) Login(foundationURL, username, password, org, space string, skipSSL bool) ([]byte, error) {
+	return c.retry("login", func() ([]byte, error) {
+		return c.Courier.Login(foundationURL, username, password, org, space, skipSSL)
+	})
+}
+
+func (c RetryingCourier) Delete(appName string) ([]byte, error) {
+	return c.retry("delete", func() ([]byte, error) { return c.Courier.Delete(appName) })
+}
+
+func (c RetryingCourier) Push(appName, appLocation, hostname string, instances uint16) ([]byte, error) {
+	return c.retry("push", func() ([]byte, error) {
+		return c.Courier.Push(appName, appLocation, hostname, instances)
+	})
+}
+
+func (c RetryingCourier) PushWithStrategy(appName, appLocation, hostname string, instances uint16, strategy string) ([]byte, error) {
+	return c.retry("push", func() ([]byte, error) {
+		return c.Courier.PushWithStrategy(appName, appLocation, hostname, instances, strategy)
+	})
+}
+
+func (c RetryingCourier) PushWithoutStart(appName, appLocation, hostname string, instances uint16) ([]byte, error) {
+	return c.retry("push", func() ([]byte, error) {
+		return c.Courier.PushWithoutStart(appName, appLocation, hostname, instances)
+	})
+}
+
+func (c RetryingCourier) Rename(oldName, newName string) ([]byte, error) {
+	return c.retry("rename", func() ([]byte, error) { return c.Courier.Rename(oldName, newName) })
+}
+
+func (c RetryingCourier) MapRoute(appName, domain, hostname string) ([]byte, error) {
+	return c.retry("map-route", func() ([]byte, error) { return c.Courier.MapRoute(appName, domain, hostname) })
+}
+
+func (c RetryingCourier) MapRouteWithPath(appName, domain, hostname, path string) ([]byte, error) {
+	return c.retry("map-route", func() ([]byte, error) {
+		return c.Courier.MapRouteWithPath(appName, domain, hostname, path)
+	})
+}
+
+func (c RetryingCourier) UnmapRoute(appName, domain, hostname string) ([]byte, error) {
+	return c.retry("unmap-route", func() ([]byte, error) { return c.Courier.UnmapRoute(appName, domain, hostname) })
+}
+
+func (c RetryingCourier) UnmapRouteWithPath(appName, domain, hostname, path string) ([]byte, error) {
+	return c.retry("unmap-route", func() ([]byte, error) {
+		return c.Courier.UnmapRouteWithPath(appName, domain, hostname, path)
+	})
+}
+
+func (c RetryingCourier) DeleteRoute(domain, hostname string) ([]byte, error) {
+	return c.retry("delete-route", func() ([]byte, error) { return c.Courier.DeleteRoute(domain, hostname) })
+}
+
+func (c RetryingCourier) DeleteRouteWithPath(domain, hostname, path string) ([]byte, error) {
+	return c.retry("delete-route", func() ([]byte, error) { return c.Courier.DeleteRouteWithPath(domain, hostname, path) })
+}
+
+func (c RetryingCourier) CreateService(service, plan, name string) ([]byte, error) {
+	return c.retry("create-service", func() ([]byte, error) { return c.Courier.CreateService(service, plan, name) })
+}
+
+func (c RetryingCourier) CreateServiceWithParams(service, plan, name, params string) ([]byte, error) {
+	return c.retry("create-service", func() ([]byte, error) { return c.Courier.CreateServiceWithParams(service, plan, name, params) })
+}
+
+func (c RetryingCourier) BindService(appName, serviceName string) ([]byte, error) {
+	return c.retry("bind-service", func() ([]byte, error) { return c.Courier.BindService(appName, serviceName) })
+}
+
+func (c RetryingCourier) UnbindService(appName, serviceName string) ([]byte, error) {
+	return c.retry("unbind-service", func() ([]byte, error) { return c.Courier.UnbindService(appName, serviceName) })
+}
+
+func (c RetryingCourier) DeleteService(serviceName string) ([]byte, error) {
+	return c.retry("delete-service", func() ([]byte, error) { return c.Courier.DeleteService(serviceName) })
+}
+
+// RunTask is not retried since the task may already have run when the command failed.
+func (c RetryingCourier) RunTask(appName, command, name string) ([]byte, error) {
+	return c.Courier.RunTask(appName, command, name)
+}
+
+func (c RetryingCourier) Start(appName string) ([]byte, error) {
+	return c.retry("start", func() ([]byte, error) { return c.Courier.Start(appName) })
+}
+
+func (c RetryingCourier) Stop(appName string) ([]byte, error) {
+	return c.retry("stop", func() ([]byte, error) { return c.Courier.Stop(appName) })
+}
+
+func (c RetryingCourier) Restage(appName string) ([]byte, error) {
+	return c.retry("restage", func() ([]byte, error) { return c.Courier.Restage(appName) })
+}
+
+func (c RetryingCourier) Logs(appName string) ([]byte, error) {
+	return c.retry("logs", func() ([]byte, error) { return c.Courier.Logs(appName) })
+}
+
+func (c RetryingCourier) Cups(serviceName string, credentials string, syslogDrainURL string, routeServiceURL string) ([]byte, error) {
+	return c.retry("cups", func() ([]byte, error) {
+		return c.Courier.Cups(serviceName, credentials, syslogDrainURL, routeServiceURL)
+	})
+}
+
+func (c RetryingCourier) Uups(serviceName string, credentials string, syslogDrainURL string, routeServiceURL string) ([]byte, error) {
+	return c.retry("uups", func() ([]byte, error) {
+		return c.Courier.Uups(serviceName, credentials, syslogDrainURL, routeServiceURL)
+	})
+}
+
+// UserProvidedService is retried on transient errors without noting the retries, since its output is parsed.
+func (c RetryingCourier) UserProvidedService(serviceName string) (S.UserProvidedService, error) {
+	var service S.UserProvidedService
+	_, err := c.attempt("service", func() ([]byte, error) {
+		var err error
+		service, err = c.Courier.UserProvidedService(serviceName)
+		return nil, err
+	}, false)
+	return service, err
+}
+
+// Exists is not retried since it cannot tell a transient failure from a missing application.
+func (c RetryingCourier) Exists(appName string) bool {
+	return c.Courier.Exists(appName)
+}
+
+// Domains is retried on transient errors without noting the retries, since its output is parsed.
+func (c RetryingCourier) Domains() ([]string, error) {
+	var domains []string
+	_, err := c.attempt("domains", func() ([]byte, error) {
+		var err error
+		domains, err = c.Courier.Domains()
+		return nil, err
+	}, false)
+	return domains, err
+}
+
+// Services is retried on transient errors without noting the retries, since its output is parsed.
+func (c RetryingCourier) Services() ([]string, error) {
+	var services []string
+	_, err := c.attempt("services", func() ([]byte, error) {
+		var err error
+		services, err = c.Courier.Services()
+		return nil, err
+	}, false)
+	return services, err
+}
+
+// ServiceStatus is retried on transient errors without noting the retries, since its output is parsed.
+func (c RetryingCourier) ServiceStatus(serviceName string) (string, error) {
+	var status string
+	_, err := c.attempt("service", func() ([]byte, error) {
+		var err error
+		status, err = c.Courier.ServiceStatus(serviceName)
+		return nil, err
+	}, false)
+	return status, err
+}
+
+// AppInstances is retried on transient errors without noting the retries, since its output is parsed.
+func (c RetryingCourier) AppInstances(appName string) ([]S.AppInstance, error) {
+	var instances []S.AppInstance
+	_, err := c.attempt("app", func() ([]byte, error) {
+		var err error
+		instances, err = c.Courier.AppInstances(appName)
+		return nil, err
+	}, false)
+	return instances, err
+}
+
+// Curl is retried on transient errors without noting the retries, since its output is parsed.
+func (c RetryingCourier) Curl(path string) ([]byte, error) {
+	return c.attempt("curl", func() ([]byte, error) {
+		return c.Courier.Curl(path)
+	}, false)
+}
+
+// OAuthToken is retried on transient errors without noting the retries, since its output is parsed.
+func (c RetryingCourier) OAuthToken() (string, error) {
+	var token string
+	_, err := c.attempt("oauth-token", func() ([]byte, error) {
+		var err error
+		token, err = c.Courier.OAuthToken()
+		return nil, err
+	}, false)
+	return token, err
+}
+
+func (c RetryingCourier) CleanUp() error {
+	return c.Courier.CleanUp()
+}
+
+// Interrupt interrupts the courier it decorates, when it can be interrupted.
+func (c RetryingCourier) Interrupt() {
+	if interruptible, ok := c.Courier.(I.Interruptible); ok {
+		interruptible.Interrupt()
+	}
+}
+
+// Streaming returns a copy of the RetryingCourier whose courier streams the output of its commands to
+// the writer, when it can.
+func (c RetryingCourier) Streaming(output io.Writer) I.Courier {
+	if streaming, ok := c.Courier.(I.StreamingCourier); ok {
+		c.Courier = streaming.Streaming(output)
+	}
+	return c
+}
+
+func (c RetryingCourier) retry(command string, do func() ([]byte, error)) ([]byte, error) {
+	return c.attempt(command, do, true)
+}
+
+// attempt runs do until it succeeds, fails with an error that is not transient or runs out of retries.
+// When note is set, the output of the failed attempts and a line for every retry are prepended to the output.
+func (c RetryingCourier) attempt(command string, do func() ([]byte, error), note bool) ([]byte, error) {
+	var notes []byte
+	retries := c.Policy.retries(command)
+
+	for retry := 1; ; retry++ {
+		output, err := do()
+		if err == nil || retry > retries || !c.Policy.isTransient(output, err) {
+			return append(notes, output...), err
+		}
+
+		backoff := c.Policy.backoff(retry)
+		c.Log.Errorf("cf %s failed with a transient error: %s: retrying in %s (retry %d of %d)", command, err, backoff, retry, retries)
+
+		if note {
+			notes = append(notes, output...)
+			notes = append(notes, fmt.Sprintf("\ncf %s failed with a transient error, retrying in %s (retry %d of %d)\n", command, backoff, retry, retries)...)
+		}
+
+		time.Sleep(backoff)
+	}
+}
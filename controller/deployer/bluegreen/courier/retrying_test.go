@@ -0,0 +1,116 @@
+package courier_test
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"time"
+
+	. "github.com/compozed/deployadactyl/controller/deployer/bluegreen/courier"
+	"github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/mocks"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	"github.com/op/go-logging"
+)
+
+var _ = Describe("RetryingCourier", func() {
+	var (
+		courier   *mocks.Courier
+		logBuffer *Buffer
+		retrying  RetryingCourier
+	)
+
+	BeforeEach(func() {
+		courier = &mocks.Courier{}
+		logBuffer = NewBuffer()
+
+		retrying = RetryingCourier{
+			Courier: courier,
+			Policy: RetryPolicy{
+				Retries:   map[string]int{"map-route": 2},
+				Backoff:   time.Millisecond,
+				Transient: DefaultTransientPatterns,
+			},
+			Log: interfaces.DefaultLogger(logBuffer, logging.DEBUG, "retrying_courier_test"),
+		}
+	})
+
+	Context("when a command fails with a transient error", func() {
+		It("retries it until it succeeds and notes the retries in the output", func() {
+			courier.MapRouteCall.Returns.Output = [][]byte{[]byte("Server error, status code: 502"), []byte("OK")}
+			courier.MapRouteCall.Returns.Error = []error{errors.New("exit status 1"), nil}
+
+			output, err := retrying.MapRoute("appName", "example.com", "hostname")
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(courier.MapRouteCall.TimesCalled).To(Equal(2))
+			Expect(string(output)).To(Equal("Server error, status code: 502\ncf map-route failed with a transient error, retrying in 1ms (retry 1 of 2)\nOK"))
+			Eventually(logBuffer).Should(Say("cf map-route failed with a transient error: exit status 1: retrying in 1ms"))
+		})
+
+		It("gives up once it runs out of retries", func() {
+			courier.MapRouteCall.Returns.Output = [][]byte{[]byte("502 Bad Gateway"), []byte("502 Bad Gateway"), []byte("502 Bad Gateway")}
+			courier.MapRouteCall.Returns.Error = []error{errors.New("exit status 1"), errors.New("exit status 1"), errors.New("exit status 1")}
+
+			_, err := retrying.MapRoute("appName", "example.com", "hostname")
+
+			Expect(err).To(MatchError("exit status 1"))
+			Expect(courier.MapRouteCall.TimesCalled).To(Equal(3))
+		})
+	})
+
+	Context("when a command fails with an error that is not transient", func() {
+		It("does not retry it", func() {
+			courier.MapRouteCall.Returns.Output = [][]byte{[]byte("Domain example.com not found")}
+			courier.MapRouteCall.Returns.Error = []error{errors.New("exit status 1")}
+
+			output, err := retrying.MapRoute("appName", "example.com", "hostname")
+
+			Expect(err).To(MatchError("exit status 1"))
+			Expect(string(output)).To(Equal("Domain example.com not found"))
+			Expect(courier.MapRouteCall.TimesCalled).To(Equal(1))
+		})
+	})
+
+	Context("when a command has no retries", func() {
+		It("does not retry it", func() {
+			courier.DomainsCall.Returns.Error = errors.New("Server error, status code: 503")
+
+			_, err := retrying.Domains()
+
+			Expect(err).To(HaveOccurred())
+			Expect(courier.DomainsCall.TimesCalled).To(Equal(1))
+		})
+	})
+
+	Describe("Streaming", func() {
+		It("streams the output of the courier it decorates", func() {
+			output := &bytes.Buffer{}
+			retrying.Courier = Courier{Executor: &mocks.Executor{}}
+
+			streaming := retrying.Streaming(output)
+
+			Expect(streaming.(RetryingCourier).Courier.(Courier).Output).To(BeIdenticalTo(output))
+		})
+
+		It("leaves couriers that cannot stream as they are", func() {
+			streaming := retrying.Streaming(&bytes.Buffer{})
+
+			Expect(streaming.(RetryingCourier).Courier).To(BeIdenticalTo(courier))
+		})
+	})
+
+	Describe("WithRetries", func() {
+		It("decorates the couriers of the constructor", func() {
+			policy := RetryPolicy{Transient: []*regexp.Regexp{regexp.MustCompile("flaky")}}
+			constructor := WithRetries(policy, retrying.Log, nil)
+
+			decorated := constructor(&mocks.Executor{})
+
+			Expect(decorated).To(BeAssignableToTypeOf(RetryingCourier{}))
+			Expect(decorated.(RetryingCourier).Courier).To(BeAssignableToTypeOf(Courier{}))
+		})
+	})
+})
@@ -3,6 +3,8 @@ package bluegreen
 import (
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 )
 
 type LoginError struct {
@@ -18,6 +20,21 @@ func (e LoginError) Code() string {
 	return "LoginError"
 }
 
+// VerifyError is returned when the pre-flight checks of the action fail on any foundation.
+// Nothing has been changed on the foundations when it is returned.
+type VerifyError struct {
+	VerifyErrors []error
+}
+
+func (e VerifyError) Error() string {
+	errs := makeErrorString(e.VerifyErrors)
+	return fmt.Sprintf("pre-flight checks failed: %s", errs)
+}
+
+func (e VerifyError) Code() string {
+	return "VerifyError"
+}
+
 type PushError struct {
 	PushErrors []error
 }
@@ -222,3 +239,148 @@ func (e RollbackDeleteError) Error() string {
 
 	return fmt.Sprintf("delete failed: %s: rollback failed: %s", startErrs, rollbackStartErrors)
 }
+
+type FinishRollbackToStandbyError struct {
+	FinishRollbackErrors []error
+}
+
+func (e FinishRollbackToStandbyError) Error() string {
+	finishRollbackErrors := makeErrorString(e.FinishRollbackErrors)
+
+	return fmt.Sprintf("finish rollback failed: %s", finishRollbackErrors)
+}
+
+type RollbackToStandbyError struct {
+	Errors []error
+}
+
+func (e RollbackToStandbyError) Error() string {
+	errs := makeErrorString(e.Errors)
+	return fmt.Sprintf("rollback failed: %s", errs)
+}
+
+func (e RollbackToStandbyError) Code() string {
+	return "RollbackToStandbyError"
+}
+
+type UndoRollbackToStandbyError struct {
+	RollbackErrors []error
+	UndoErrors     []error
+}
+
+func (e UndoRollbackToStandbyError) Error() string {
+	var (
+		rollbackErrs = makeErrorString(e.RollbackErrors)
+		undoErrs     = makeErrorString(e.UndoErrors)
+	)
+
+	return fmt.Sprintf("rollback failed: %s: undo failed: %s", rollbackErrs, undoErrs)
+}
+
+type CanaryFoundationNotFoundError struct {
+	Foundation string
+}
+
+func (e CanaryFoundationNotFoundError) Error() string {
+	return fmt.Sprintf("canary foundation not found: %s", e.Foundation)
+}
+
+type CanaryHealthCheckError struct {
+	Foundation string
+	Err        error
+}
+
+func (e CanaryHealthCheckError) Error() string {
+	return fmt.Sprintf("canary foundation %s failed its health check: %s", e.Foundation, e.Err)
+}
+
+type WaveFoundationNotFoundError struct {
+	Foundation string
+}
+
+func (e WaveFoundationNotFoundError) Error() string {
+	return fmt.Sprintf("foundation in rolling waves not found: %s", e.Foundation)
+}
+
+// InvalidEventTypeError is returned by event bindings given an event of another type. The
+// eventmanager package cannot be used for this here since it depends on bluegreen in its tests.
+type InvalidEventTypeError struct{}
+
+func (e InvalidEventTypeError) Error() string {
+	return "invalid event type"
+}
+
+// PartialSuccessError is returned when some foundations failed but enough succeeded for the success
+// policy of the environment. The failed foundations were left on the old version.
+type PartialSuccessError struct {
+	Succeeded            []string
+	Failed               []string
+	FailedFoundationURLs []string
+	Errors               []error
+}
+
+func (e PartialSuccessError) Error() string {
+	return fmt.Sprintf("partial success: failed on %s: %s", strings.Join(e.Failed, ", "), makeErrorString(e.Errors))
+}
+
+func (e PartialSuccessError) Code() string {
+	return "PartialSuccessError"
+}
+
+// PhaseTimeoutError is returned for a foundation when a phase of its action ran longer than its timeout.
+type PhaseTimeoutError struct {
+	Phase string
+	After time.Duration
+}
+
+func (e PhaseTimeoutError) Error() string {
+	return fmt.Sprintf("%s timed out after %s", e.Phase, e.After)
+}
+
+func (e PhaseTimeoutError) Timeout() bool {
+	return true
+}
+
+// RequestTimeoutError is returned for a foundation when the request ran out of time during a phase of its action.
+type RequestTimeoutError struct {
+	Phase string
+	After time.Duration
+}
+
+func (e RequestTimeoutError) Error() string {
+	return fmt.Sprintf("request timed out after %s during %s", e.After, e.Phase)
+}
+
+func (e RequestTimeoutError) Timeout() bool {
+	return true
+}
+
+type BakeError struct {
+	Foundations    []string
+	Errors         []error
+	RollbackErrors []error
+}
+
+func (e BakeError) Error() string {
+	failures := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		failures[i] = fmt.Sprintf("%s: %s", e.Foundations[i], err)
+	}
+
+	if len(e.RollbackErrors) != 0 {
+		return fmt.Sprintf("bake failed: %s: rollback failed: %s", strings.Join(failures, ", "), makeErrorString(e.RollbackErrors))
+	}
+	return fmt.Sprintf("bake failed: %s: rolled back to the previous version", strings.Join(failures, ", "))
+}
+
+func (e BakeError) Code() string {
+	return "BakeError"
+}
+
+type FinishBakeError struct {
+	Errors []error
+}
+
+func (e FinishBakeError) Error() string {
+	return fmt.Sprintf("could not remove the previous version after baking: %s", makeErrorString(e.Errors))
+}
@@ -0,0 +1,105 @@
+package bluegreen
+
+import (
+	"reflect"
+
+	"github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/structs"
+)
+
+type eventBinding struct {
+	etype   reflect.Type
+	handler func(event interface{}) error
+}
+
+func (s eventBinding) Accepts(event interface{}) bool {
+	return reflect.TypeOf(event) == s.etype
+}
+
+func (b eventBinding) Emit(event interface{}) error {
+	return b.handler(event)
+}
+
+// WaveStartedEvent is emitted before a wave of a rolling deployment is deployed.
+type WaveStartedEvent struct {
+	Wave        int
+	Waves       int
+	Foundations []string
+	Environment structs.Environment
+	Log         interfaces.DeploymentLogger
+}
+
+func (e WaveStartedEvent) Name() string {
+	return "WaveStartedEvent"
+}
+
+func NewWaveStartedEventBinding(handler func(event WaveStartedEvent) error) interfaces.Binding {
+	return eventBinding{
+		etype: reflect.TypeOf(WaveStartedEvent{}),
+		handler: func(gevent interface{}) error {
+			event, ok := gevent.(WaveStartedEvent)
+			if ok {
+				return handler(event)
+			} else {
+				return InvalidEventTypeError{}
+			}
+		},
+	}
+}
+
+// WaveFinishedEvent is emitted after a wave of a rolling deployment. Error is set when the wave failed.
+type WaveFinishedEvent struct {
+	Wave        int
+	Waves       int
+	Foundations []string
+	Environment structs.Environment
+	Error       error
+	Log         interfaces.DeploymentLogger
+}
+
+func (e WaveFinishedEvent) Name() string {
+	return "WaveFinishedEvent"
+}
+
+func NewWaveFinishedEventBinding(handler func(event WaveFinishedEvent) error) interfaces.Binding {
+	return eventBinding{
+		etype: reflect.TypeOf(WaveFinishedEvent{}),
+		handler: func(gevent interface{}) error {
+			event, ok := gevent.(WaveFinishedEvent)
+			if ok {
+				return handler(event)
+			} else {
+				return InvalidEventTypeError{}
+			}
+		},
+	}
+}
+
+// PartialSuccessEvent is emitted when a request is accepted even though some foundations failed.
+// FailedFoundationURLs can be used to retry just the failed foundations.
+type PartialSuccessEvent struct {
+	Environment          structs.Environment
+	Succeeded            []string
+	Failed               []string
+	FailedFoundationURLs []string
+	Errors               []error
+	Log                  interfaces.DeploymentLogger
+}
+
+func (e PartialSuccessEvent) Name() string {
+	return "PartialSuccessEvent"
+}
+
+func NewPartialSuccessEventBinding(handler func(event PartialSuccessEvent) error) interfaces.Binding {
+	return eventBinding{
+		etype: reflect.TypeOf(PartialSuccessEvent{}),
+		handler: func(gevent interface{}) error {
+			event, ok := gevent.(PartialSuccessEvent)
+			if ok {
+				return handler(event)
+			} else {
+				return InvalidEventTypeError{}
+			}
+		},
+	}
+}
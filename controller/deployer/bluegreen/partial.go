@@ -0,0 +1,109 @@
+package bluegreen
+
+import (
+	I "github.com/compozed/deployadactyl/interfaces"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+// executePartial runs the action against all foundations and accepts it when at least the number of
+// foundations required by the success policy of the environment succeed. Failed foundations are undone
+// so that they are left on the old version, and a PartialSuccessError names them.
+func (bg BlueGreen) executePartial(actors []actor, names []string, environment S.Environment, actionCreator I.ActionCreator) error {
+	errs := bg.commandsByActor(actors, func(action I.Action) error {
+		return action.Execute()
+	})
+
+	executed := make([]int, 0, len(actors))
+	for i, err := range errs {
+		if err == nil {
+			executed = append(executed, i)
+		}
+	}
+
+	postErrs := bg.commandsByActor(selectActors(actors, executed), func(action I.Action) error {
+		return action.PostExecute()
+	})
+	for j, err := range postErrs {
+		errs[executed[j]] = err
+	}
+
+	var (
+		succeeded, failed []int
+		actionErrors      []error
+	)
+	for i, err := range errs {
+		if err != nil {
+			failed = append(failed, i)
+			actionErrors = append(actionErrors, err)
+		} else {
+			succeeded = append(succeeded, i)
+		}
+	}
+
+	if len(failed) == 0 {
+		return bg.success(actors, environment, actionCreator)
+	}
+
+	required := environment.RequiredSuccesses()
+	if len(succeeded) < required {
+		bg.Log.Errorf("%d of %d foundations succeeded: %d required", len(succeeded), len(actors), required)
+		return bg.processErrors(actionErrors, actors, actionCreator)
+	}
+
+	bg.Log.Errorf("%d of %d foundations failed: accepting partial success and rolling back the failed foundations", len(failed), len(actors))
+
+	undoErrors := bg.commands(selectActors(actors, failed), func(action I.Action) error {
+		return action.Undo()
+	})
+	if len(undoErrors) != 0 {
+		bg.commands(selectActors(actors, succeeded), func(action I.Action) error {
+			return action.Undo()
+		})
+		return actionCreator.UndoError(actionErrors, undoErrors)
+	}
+
+	if err := bg.success(selectActors(actors, succeeded), environment, actionCreator); err != nil {
+		return err
+	}
+
+	partial := PartialSuccessError{Errors: actionErrors}
+	for _, i := range succeeded {
+		partial.Succeeded = append(partial.Succeeded, names[i])
+	}
+	for _, i := range failed {
+		partial.Failed = append(partial.Failed, names[i])
+		partial.FailedFoundationURLs = append(partial.FailedFoundationURLs, environment.Foundations[i])
+	}
+
+	bg.emit(PartialSuccessEvent{
+		Environment:          environment,
+		Succeeded:            partial.Succeeded,
+		Failed:               partial.Failed,
+		FailedFoundationURLs: partial.FailedFoundationURLs,
+		Errors:               actionErrors,
+		Log:                  bg.Log,
+	})
+
+	return partial
+}
+
+// commandsByActor runs doFunc on every actor and returns the error of each actor by its index.
+func (bg BlueGreen) commandsByActor(actors []actor, doFunc ActorCommand) []error {
+	for _, a := range actors {
+		a.Commands <- doFunc
+	}
+
+	errs := make([]error, len(actors))
+	for i, a := range actors {
+		errs[i] = <-a.Errs
+	}
+	return errs
+}
+
+func selectActors(actors []actor, indexes []int) []actor {
+	selected := make([]actor, len(indexes))
+	for j, i := range indexes {
+		selected[j] = actors[i]
+	}
+	return selected
+}
@@ -0,0 +1,153 @@
+package bluegreen
+
+import (
+	"strings"
+	"time"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+// executeRolling logs in to all foundations and then deploys the waves one after the other, pausing
+// between them. The rollout stops at the first wave that fails, which is undone together with the
+// earlier waves unless the rollback policy only undoes the failed wave.
+func (bg BlueGreen) executeRolling(actors []actor, names []string, waves [][]int, environment S.Environment, actionCreator I.ActionCreator) error {
+	pause, err := environment.Rolling.GetPause()
+	if err != nil {
+		return InitializationError{err}
+	}
+	policy := environment.Rolling.GetRollback()
+
+	loginErrors := bg.commands(actors, func(action I.Action) error {
+		return action.Initially()
+	})
+	if len(loginErrors) != 0 {
+		return actionCreator.InitiallyError(loginErrors)
+	}
+
+	err = bg.verify(actors)
+	if err != nil {
+		return err
+	}
+
+	deployed := make([]actor, 0, len(actors))
+	for w, wave := range waves {
+		if w > 0 && pause > 0 {
+			bg.Log.Infof("pausing %s before wave %d", pause, w+1)
+			time.Sleep(pause)
+		}
+
+		waveActors := make([]actor, len(wave))
+		waveNames := make([]string, len(wave))
+		for j, i := range wave {
+			waveActors[j] = actors[i]
+			waveNames[j] = names[i]
+		}
+		deployed = append(deployed, waveActors...)
+
+		bg.Log.Infof("deploying wave %d of %d: %s", w+1, len(waves), strings.Join(waveNames, ", "))
+		bg.emit(WaveStartedEvent{Wave: w + 1, Waves: len(waves), Foundations: waveNames, Environment: environment, Log: bg.Log})
+
+		actionErrors := bg.commands(waveActors, func(action I.Action) error {
+			return action.Execute()
+		})
+		if len(actionErrors) == 0 {
+			actionErrors = bg.commands(waveActors, func(action I.Action) error {
+				return action.PostExecute()
+			})
+		}
+
+		if len(actionErrors) != 0 {
+			bg.Log.Errorf("wave %d of %d failed: stopping rollout", w+1, len(waves))
+			bg.emit(WaveFinishedEvent{Wave: w + 1, Waves: len(waves), Foundations: waveNames, Environment: environment, Error: actionCreator.ExecuteError(actionErrors), Log: bg.Log})
+
+			if policy == S.RollbackFailedWave {
+				return bg.processErrors(actionErrors, waveActors, actionCreator)
+			}
+			return bg.processErrors(actionErrors, deployed, actionCreator)
+		}
+
+		if policy == S.RollbackFailedWave {
+			if err := bg.success(waveActors, environment, actionCreator); err != nil {
+				bg.emit(WaveFinishedEvent{Wave: w + 1, Waves: len(waves), Foundations: waveNames, Environment: environment, Error: err, Log: bg.Log})
+				return err
+			}
+		}
+
+		bg.emit(WaveFinishedEvent{Wave: w + 1, Waves: len(waves), Foundations: waveNames, Environment: environment, Log: bg.Log})
+	}
+
+	if policy == S.RollbackFailedWave {
+		return nil
+	}
+	return bg.success(actors, environment, actionCreator)
+}
+
+// rollingWaves groups the indexes of the foundations of environment into waves.
+func rollingWaves(environment S.Environment, names []string) ([][]int, error) {
+	rolling := environment.Rolling
+	waves := [][]int{}
+
+	if len(rolling.Waves) == 0 {
+		size := rolling.MaxParallelFoundations
+		if size < 1 {
+			size = 1
+		}
+
+		for start := 0; start < len(environment.Foundations); start += size {
+			wave := []int{}
+			for i := start; i < start+size && i < len(environment.Foundations); i++ {
+				wave = append(wave, i)
+			}
+			waves = append(waves, wave)
+		}
+		return waves, nil
+	}
+
+	assigned := make(map[int]bool)
+	for _, group := range rolling.Waves {
+		wave := []int{}
+		for _, foundation := range group {
+			index := -1
+			for i, foundationURL := range environment.Foundations {
+				if foundation == foundationURL || foundation == names[i] {
+					index = i
+					break
+				}
+			}
+			if index < 0 {
+				return nil, WaveFoundationNotFoundError{foundation}
+			}
+			if !assigned[index] {
+				assigned[index] = true
+				wave = append(wave, index)
+			}
+		}
+		if len(wave) > 0 {
+			waves = append(waves, wave)
+		}
+	}
+
+	rest := []int{}
+	for i := range environment.Foundations {
+		if !assigned[i] {
+			rest = append(rest, i)
+		}
+	}
+	if len(rest) > 0 {
+		waves = append(waves, rest)
+	}
+
+	return waves, nil
+}
+
+func (bg BlueGreen) emit(event I.IEvent) {
+	if bg.EventManager == nil {
+		return
+	}
+
+	bg.Log.Debugf("emitting a %s event", event.Name())
+	if err := bg.EventManager.EmitEvent(event); err != nil {
+		bg.Log.Error(err)
+	}
+}
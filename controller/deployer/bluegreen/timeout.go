@@ -0,0 +1,178 @@
+package bluegreen
+
+import (
+	"sync"
+	"time"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+// timedAction bounds every phase of an action by the timeout of the phase and by the deadline of
+// the request. Undo is only bounded by its phase timeout so that a request that ran out of time
+// can still be rolled back.
+//
+// A phase that times out is interrupted when the action is I.Interruptible, which kills the Cloud
+// Foundry commands it is running. No other phase, Undo and Finally included, starts until the phase
+// that timed out has returned, so that they never race its commands or remove their CF_HOME.
+type timedAction struct {
+	I.Action
+	timeouts S.Timeouts
+	deadline time.Time
+	timedOut *timedOutPhase
+}
+
+func newTimedAction(action I.Action, timeouts S.Timeouts, deadline time.Time) timedAction {
+	return timedAction{
+		Action:   action,
+		timeouts: timeouts,
+		deadline: deadline,
+		timedOut: &timedOutPhase{},
+	}
+}
+
+// timedOutPhase is closed when the last phase that timed out has returned.
+type timedOutPhase struct {
+	lock     sync.Mutex
+	returned chan struct{}
+}
+
+func (p *timedOutPhase) set(returned chan struct{}) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.returned = returned
+}
+
+// wait waits until the last phase that timed out has returned.
+func (p *timedOutPhase) wait() {
+	p.lock.Lock()
+	returned := p.returned
+	p.lock.Unlock()
+
+	if returned != nil {
+		<-returned
+	}
+}
+
+func (a timedAction) Initially() error {
+	return a.run("initially", a.Action.Initially, true)
+}
+
+func (a timedAction) Verify() error {
+	return a.run("verify", a.Action.Verify, true)
+}
+
+func (a timedAction) Execute() error {
+	return a.run("execute", a.Action.Execute, true)
+}
+
+func (a timedAction) PostExecute() error {
+	return a.run("post_execute", a.Action.PostExecute, true)
+}
+
+func (a timedAction) Success() error {
+	return a.run("success", a.Action.Success, true)
+}
+
+func (a timedAction) Undo() error {
+	return a.run("undo", a.Action.Undo, false)
+}
+
+// Finally is not bounded, but waits for a phase that timed out like every phase does.
+func (a timedAction) Finally() error {
+	a.timedOut.wait()
+	return a.Action.Finally()
+}
+
+// HealthCheck health checks the action if it is an I.HealthCheckedAction and succeeds otherwise.
+func (a timedAction) HealthCheck() error {
+	checked, ok := a.Action.(I.HealthCheckedAction)
+	if !ok {
+		return nil
+	}
+	return a.run("health_check", checked.HealthCheck, true)
+}
+
+// Bake bakes the action if it is an I.BakedAction and succeeds otherwise. It is bounded by the
+// health check timeout only, as the bake window is not part of the request.
+func (a timedAction) Bake(since time.Time) error {
+	baked, ok := a.Action.(I.BakedAction)
+	if !ok {
+		return nil
+	}
+	return a.run("health_check", func() error { return baked.Bake(since) }, false)
+}
+
+// RollBack is bounded by the undo timeout, like Undo.
+func (a timedAction) RollBack() error {
+	baked, ok := a.Action.(I.BakedAction)
+	if !ok {
+		return nil
+	}
+	return a.run("undo", baked.RollBack, false)
+}
+
+func (a timedAction) FinishBake() error {
+	baked, ok := a.Action.(I.BakedAction)
+	if !ok {
+		return nil
+	}
+	return a.run("success", baked.FinishBake, false)
+}
+
+func (a timedAction) run(phase string, do func() error, bounded bool) error {
+	a.timedOut.wait()
+
+	timeout, _ := a.timeouts.GetPhase(phase)
+
+	var err error = PhaseTimeoutError{Phase: phase, After: timeout}
+	if bounded && !a.deadline.IsZero() {
+		remaining := time.Until(a.deadline)
+		if remaining <= 0 {
+			return RequestTimeoutError{Phase: phase, After: a.requestTimeout()}
+		}
+		if timeout <= 0 || remaining < timeout {
+			timeout = remaining
+			err = RequestTimeoutError{Phase: phase, After: a.requestTimeout()}
+		}
+	}
+
+	if timeout <= 0 {
+		return do()
+	}
+
+	done := make(chan error, 1)
+	returned := make(chan struct{})
+	go func() {
+		done <- do()
+		close(returned)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case result := <-done:
+		return result
+	case <-timer.C:
+		a.timedOut.set(returned)
+		if interruptible, ok := a.Action.(I.Interruptible); ok {
+			interruptible.Interrupt()
+		}
+		return err
+	}
+}
+
+func (a timedAction) requestTimeout() time.Duration {
+	timeout, _ := a.timeouts.GetRequest()
+	return timeout
+}
+
+// requestDeadline returns when the request times out, or the zero time when it never does.
+func requestDeadline(timeouts S.Timeouts) time.Time {
+	timeout, _ := timeouts.GetRequest()
+	if timeout <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(timeout)
+}
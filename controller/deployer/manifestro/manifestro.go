@@ -1,13 +1,38 @@
 package manifestro
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
+
 	"github.com/cloudfoundry-incubator/candiedyaml"
+	S "github.com/compozed/deployadactyl/structs"
 )
 
 type manifestYaml struct {
-	Applications []struct {
-		Instances *uint16
-	}
+	Applications []Application
+}
+
+// Application is an application in a Cloud Foundry manifest.
+type Application struct {
+	Name      string
+	Instances *uint16
+
+	// ServiceInstances are created when they do not exist and bound to the application.
+	ServiceInstances []S.ServiceInstance `yaml:"service-instances"`
+
+	Memory     string
+	Buildpack  string
+	Buildpacks []string
+	Stack      string
+
+	// CustomRoutes are mapped to the application by the route mapper.
+	CustomRoutes []CustomRoute `yaml:"custom-routes"`
+}
+
+// CustomRoute is a route in the custom-routes of an application.
+type CustomRoute struct {
+	Route string
 }
 
 // GetInstances reads a Cloud Foundry manifest as a string and returns the number of instances
@@ -15,12 +40,103 @@ type manifestYaml struct {
 //
 // Returns a point to a uint16. If instances are not found or less than 1, it returns nil.
 func GetInstances(manifest string) *uint16 {
+	applications := GetApplications(manifest)
+	if applications == nil {
+		return nil
+	}
+
+	return applications[0].GetInstances()
+}
+
+// GetApplications reads a Cloud Foundry manifest as a string and returns its applications in order.
+//
+// Returns nil if the manifest cannot be parsed or has no applications.
+func GetApplications(manifest string) []Application {
 	var m manifestYaml
 
 	err := candiedyaml.Unmarshal([]byte(manifest), &m)
-	if err != nil || m.Applications == nil || m.Applications[0].Instances == nil || *m.Applications[0].Instances < 1 {
+	if err != nil || len(m.Applications) == 0 {
+		return nil
+	}
+
+	return m.Applications
+}
+
+// GetInstances returns the number of instances of the application, or nil if they are not set or less than 1.
+func (a Application) GetInstances() *uint16 {
+	if a.Instances == nil || *a.Instances < 1 {
 		return nil
 	}
+	return a.Instances
+}
+
+// GetMemory returns the memory of each instance of the application in megabytes, or 0 if it is not set.
+// The memory is a number followed by M, MB, G or GB, like cf push accepts it.
+func (a Application) GetMemory() (uint64, error) {
+	memory := strings.ToUpper(strings.TrimSpace(a.Memory))
+	if memory == "" {
+		return 0, nil
+	}
+
+	multiplier := uint64(1)
+	switch {
+	case strings.HasSuffix(memory, "GB"):
+		memory, multiplier = strings.TrimSuffix(memory, "GB"), 1024
+	case strings.HasSuffix(memory, "G"):
+		memory, multiplier = strings.TrimSuffix(memory, "G"), 1024
+	case strings.HasSuffix(memory, "MB"):
+		memory = strings.TrimSuffix(memory, "MB")
+	case strings.HasSuffix(memory, "M"):
+		memory = strings.TrimSuffix(memory, "M")
+	default:
+		return 0, fmt.Errorf("memory %s has no unit", a.Memory)
+	}
+
+	megabytes, err := strconv.ParseUint(strings.TrimSpace(memory), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid memory %s", a.Memory)
+	}
+	return megabytes * multiplier, nil
+}
+
+// GetBuildpacks returns the buildpack and the buildpacks of the application.
+func (a Application) GetBuildpacks() []string {
+	var buildpacks []string
+	if a.Buildpack != "" {
+		buildpacks = append(buildpacks, a.Buildpack)
+	}
+	return append(buildpacks, a.Buildpacks...)
+}
+
+// RenameApplications reads a Cloud Foundry manifest as a string and gives its applications the
+// names, in order. Everything else in the manifest is kept as it is.
+//
+// Returns the renamed manifest.
+func RenameApplications(manifest string, names []string) (string, error) {
+	var m map[string]interface{}
+
+	err := candiedyaml.Unmarshal([]byte(manifest), &m)
+	if err != nil {
+		return "", err
+	}
+
+	applications, ok := m["applications"].([]interface{})
+	if !ok || len(applications) != len(names) {
+		return "", fmt.Errorf("manifest does not have %d applications", len(names))
+	}
+
+	for i, application := range applications {
+		fields, ok := application.(map[interface{}]interface{})
+		if !ok {
+			return "", fmt.Errorf("application %d of the manifest is not a map", i)
+		}
+		fields["name"] = names[i]
+	}
+
+	renamed, err := candiedyaml.Marshal(m)
+	if err != nil {
+		return "", err
+	}
 
-	return m.Applications[0].Instances
+	return "---\n" + string(renamed), nil
 }
@@ -110,4 +110,128 @@ applications:
 			})
 		})
 	})
+
+	Describe("GetApplications", func() {
+		It("returns the applications in order", func() {
+			manifest := `
+applications:
+- name: api
+  instances: 2
+- name: worker`
+
+			result := GetApplications(manifest)
+
+			Expect(result).To(HaveLen(2))
+			Expect(result[0].Name).To(Equal("api"))
+			Expect(*result[0].GetInstances()).To(Equal(uint16(2)))
+			Expect(result[1].Name).To(Equal("worker"))
+			Expect(result[1].GetInstances()).To(BeNil())
+		})
+
+		It("returns the service instances of the applications", func() {
+			manifest := `
+applications:
+- name: api
+  service-instances:
+  - name: db
+    service: p-mysql
+    plan: small
+    timeout: 15m
+    params:
+      size: 1
+      tags: [a, b]`
+
+			result := GetApplications(manifest)
+
+			Expect(result[0].ServiceInstances).To(HaveLen(1))
+			Expect(result[0].ServiceInstances[0].Name).To(Equal("db"))
+			Expect(result[0].ServiceInstances[0].Service).To(Equal("p-mysql"))
+			Expect(result[0].ServiceInstances[0].Plan).To(Equal("small"))
+			Expect(result[0].ServiceInstances[0].Timeout).To(Equal("15m"))
+
+			params, err := result[0].ServiceInstances[0].GetParams()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(params).To(MatchJSON(`{"size": 1, "tags": ["a", "b"]}`))
+		})
+
+		It("returns nil when the manifest has no applications", func() {
+			Expect(GetApplications("")).To(BeNil())
+			Expect(GetApplications("applications: []")).To(BeNil())
+		})
+
+		It("returns what the pre-flight checks need", func() {
+			manifest := `
+applications:
+- name: api
+  memory: 1G
+  buildpack: java_buildpack
+  buildpacks: [nodejs_buildpack]
+  stack: cflinuxfs4
+  custom-routes:
+  - route: api.example.com/v1`
+
+			result := GetApplications(manifest)
+
+			memory, err := result[0].GetMemory()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(memory).To(Equal(uint64(1024)))
+			Expect(result[0].GetBuildpacks()).To(Equal([]string{"java_buildpack", "nodejs_buildpack"}))
+			Expect(result[0].Stack).To(Equal("cflinuxfs4"))
+			Expect(result[0].CustomRoutes).To(Equal([]CustomRoute{{Route: "api.example.com/v1"}}))
+		})
+	})
+
+	Describe("GetMemory", func() {
+		It("returns the memory in megabytes", func() {
+			for memory, megabytes := range map[string]uint64{"": 0, "512M": 512, "256mb": 256, "2G": 2048, "1GB": 1024} {
+				result, err := Application{Memory: memory}.GetMemory()
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result).To(Equal(megabytes), memory)
+			}
+		})
+
+		It("returns an error when the memory has no unit or is not a number", func() {
+			_, err := Application{Memory: "512"}.GetMemory()
+			Expect(err).To(MatchError("memory 512 has no unit"))
+
+			_, err = Application{Memory: "lotsM"}.GetMemory()
+			Expect(err).To(MatchError("invalid memory lotsM"))
+		})
+	})
+
+	Describe("RenameApplications", func() {
+		It("renames the applications and keeps everything else", func() {
+			manifest := `---
+applications:
+- name: api
+  instances: 2
+  custom-routes:
+  - route: api.example.com
+- name: worker
+  no-route: true
+`
+
+			result, err := RenameApplications(manifest, []string{"api-new", "worker-new"})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(result).To(HavePrefix("---\n"))
+			Expect(GetApplications(result)[0].Name).To(Equal("api-new"))
+			Expect(GetApplications(result)[1].Name).To(Equal("worker-new"))
+			Expect(result).To(ContainSubstring("route: api.example.com"))
+			Expect(result).To(ContainSubstring("no-route: true"))
+		})
+
+		It("returns an error when the number of names does not match", func() {
+			_, err := RenameApplications("applications:\n- name: api\n", []string{"a", "b"})
+
+			Expect(err).To(MatchError("manifest does not have 2 applications"))
+		})
+
+		It("returns an error when the manifest is not valid", func() {
+			_, err := RenameApplications("applications: [", []string{"a"})
+
+			Expect(err).To(HaveOccurred())
+		})
+	})
 })
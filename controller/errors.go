@@ -0,0 +1,12 @@
+package controller
+
+import "fmt"
+
+type InvalidScheduledAtError struct {
+	ScheduledAt string
+	Err         error
+}
+
+func (e InvalidScheduledAtError) Error() string {
+	return fmt.Sprintf("invalid scheduled_at %s: %s", e.ScheduledAt, e.Err)
+}
@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"io"
+	"strconv"
+	"sync"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/gin-gonic/gin"
+)
+
+// StatusTrailer is the trailer that holds the status of a streamed response. The status line of a
+// streamed response is sent before the deployment is done, so it is always 200 OK.
+const StatusTrailer = "X-Deployment-Status"
+
+// stream returns the writer that sends the Cloud Foundry output of the deployment to the client as it
+// arrives, when the request asks for it with stream=true, and nil otherwise. The output is masked with
+// the secrets of the request and is followed by the rest of the response once the deployment is done.
+//
+// The writer must be closed before the handler returns.
+func (c *Controller) stream(g *gin.Context, secrets []string) *liveOutput {
+	if g.Query("stream") != "true" {
+		return nil
+	}
+
+	g.Writer.Header().Set("Trailer", StatusTrailer)
+
+	output := &liveOutput{writer: g.Writer}
+	if c.Redactor != nil {
+		output.redactor = c.Redactor.With(secrets...)
+	}
+	return output
+}
+
+// finishStream sets the status trailer of a streamed response.
+func finishStream(g *gin.Context) {
+	if g.Writer.Header().Get("Trailer") == StatusTrailer {
+		g.Writer.Header().Set(StatusTrailer, strconv.Itoa(g.Writer.Status()))
+	}
+}
+
+// liveOutput writes to the client and flushes every write. The foundations of a deployment write to it
+// at the same time, so writes are serialized. Writes after it is closed are dropped, since the client
+// may be gone by then.
+type liveOutput struct {
+	lock     sync.Mutex
+	writer   gin.ResponseWriter
+	redactor I.Redactor
+	closed   bool
+}
+
+func (o *liveOutput) Write(p []byte) (int, error) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	if o.closed {
+		return len(p), nil
+	}
+
+	text := string(p)
+	if o.redactor != nil {
+		text = o.redactor.Redact(text)
+	}
+
+	io.WriteString(o.writer, text)
+	o.writer.Flush()
+
+	return len(p), nil
+}
+
+func (o *liveOutput) close() {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	o.closed = true
+}
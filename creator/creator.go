@@ -23,6 +23,7 @@ import (
 	"bytes"
 
 	"github.com/compozed/deployadactyl/controller/deployer/bluegreen/courier"
+	"github.com/compozed/deployadactyl/controller/deployer/bluegreen/courier/ccapi"
 	"github.com/compozed/deployadactyl/controller/deployer/bluegreen/courier/executor"
 	"github.com/compozed/deployadactyl/controller/deployer/error_finder"
 	"github.com/compozed/deployadactyl/controller/deployer/prechecker"
@@ -30,13 +31,22 @@ import (
 	"github.com/compozed/deployadactyl/eventmanager/handlers/envvar"
 	"github.com/compozed/deployadactyl/eventmanager/handlers/healthchecker"
 	"github.com/compozed/deployadactyl/eventmanager/handlers/routemapper"
+	"github.com/compozed/deployadactyl/history"
+	"github.com/compozed/deployadactyl/hooks"
 	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/janitor"
 	"github.com/compozed/deployadactyl/randomizer"
+	"github.com/compozed/deployadactyl/redact"
 	R "github.com/compozed/deployadactyl/request"
+	"github.com/compozed/deployadactyl/schedule"
+	"github.com/compozed/deployadactyl/secrets"
 	"github.com/compozed/deployadactyl/state"
 	"github.com/compozed/deployadactyl/state/delete"
+	"github.com/compozed/deployadactyl/state/rollback"
 	"github.com/compozed/deployadactyl/state/start"
 	"github.com/compozed/deployadactyl/state/stop"
+	S "github.com/compozed/deployadactyl/structs"
+	"github.com/compozed/deployadactyl/tempfiles"
 	"github.com/gin-gonic/gin"
 	"github.com/op/go-logging"
 	"github.com/spf13/afero"
@@ -46,6 +56,15 @@ import (
 const v2ENDPOINT = "/v2/deploy/:environment/:org/:space/:appName"
 const ENDPOINT = "/v3/apps/:environment/:org/:space/:appName"
 
+// PROMOTE_ENDPOINT deploys the last successful deployment of an application in another environment.
+const PROMOTE_ENDPOINT = "/v3/promote/:environment/:org/:space/:appName"
+
+// SCHEDULED_ENDPOINT lists the deployments scheduled in an environment and cancels one of them by its UUID.
+const SCHEDULED_ENDPOINT = "/v3/scheduled/:environment"
+
+// JANITOR_ENDPOINT deletes the temporary applications that interrupted pushes left behind in an environment.
+const JANITOR_ENDPOINT = "/v3/janitor/:environment"
+
 type InvalidRequestError struct{}
 
 func (e InvalidRequestError) Error() string {
@@ -70,35 +89,50 @@ func NewLogger() (I.Logger, error) {
 }
 
 type CreatorModuleProvider struct {
-	NewCourier                courier.CourierConstructor
-	NewPrechecker             prechecker.PrecheckerConstructor
-	NewFetcher                artifetcher.ArtifetcherConstructor
-	NewExtractor              extractor.ExtractorConstructor
-	NewEventManager           eventmanager.EventManagerConstructor
-	NewPushController         push.PushControllerConstructor
-	NewStartController        start.StartControllerConstructor
-	NewStopController         stop.StopControllerConstructor
-	NewDeleteController       delete.DeleteControllerConstructor
-	NewAuthResolver           state.AuthResolverConstructor
-	NewEnvResolver            state.EnvResolverConstructor
-	NewDeployer               deployer.DeployerConstructor
-	NewPushManager            push.PushManagerConstructor
-	NewStopManager            stop.StopManagerConstructor
-	NewStartManager           start.StartManagerConstructor
-	NewBlueGreen              bluegreen.BlueGreenConstructor
-	NewPushRequestProcessor   push.PushRequestProcessorConstructor
-	NewPushRequestCreator     PushRequestCreatorConstructor
-	NewStopRequestProcessor   stop.StopRequestProcessorConstructor
-	NewStopRequestCreator     StopRequestCreatorConstructor
-	NewStartRequestProcessor  start.StartRequestProcessorConstructor
-	NewStartRequestCreator    StartRequestCreatorConstructor
-	NewDeleteRequestProcessor delete.DeleteRequestProcessorConstructor
-	NewDeleteRequestCreator   DeleteRequestCreatorConstructor
-	NewDeleteManager          delete.DeleteManagerConstructor
-	NewConfig                 config.ConfigConstructor
-	NewLogger                 LoggerConstructor
-	NewHealthChecker          healthchecker.HealthCheckerConstructor
-	CLIChecker                func() error
+	NewCourier                  courier.CourierConstructor
+	NewAPICourier               ccapi.CourierConstructor
+	NewPrechecker               prechecker.PrecheckerConstructor
+	NewFetcher                  artifetcher.ArtifetcherConstructor
+	NewExtractor                extractor.ExtractorConstructor
+	NewEventManager             eventmanager.EventManagerConstructor
+	NewPushController           push.PushControllerConstructor
+	NewStartController          start.StartControllerConstructor
+	NewStopController           stop.StopControllerConstructor
+	NewRollbackController       rollback.RollbackControllerConstructor
+	NewDeleteController         delete.DeleteControllerConstructor
+	NewAuthResolver             state.AuthResolverConstructor
+	NewEnvResolver              state.EnvResolverConstructor
+	NewDeployer                 deployer.DeployerConstructor
+	NewPushManager              push.PushManagerConstructor
+	NewStopManager              stop.StopManagerConstructor
+	NewStartManager             start.StartManagerConstructor
+	NewRollbackManager          rollback.RollbackManagerConstructor
+	NewBlueGreen                bluegreen.BlueGreenConstructor
+	NewPushRequestProcessor     push.PushRequestProcessorConstructor
+	NewPushRequestCreator       PushRequestCreatorConstructor
+	NewStopRequestProcessor     stop.StopRequestProcessorConstructor
+	NewStopRequestCreator       StopRequestCreatorConstructor
+	NewStartRequestProcessor    start.StartRequestProcessorConstructor
+	NewStartRequestCreator      StartRequestCreatorConstructor
+	NewDeleteRequestProcessor   delete.DeleteRequestProcessorConstructor
+	NewRollbackRequestProcessor rollback.RollbackRequestProcessorConstructor
+	NewRollbackRequestCreator   RollbackRequestCreatorConstructor
+	NewDeleteRequestCreator     DeleteRequestCreatorConstructor
+	NewDeleteManager            delete.DeleteManagerConstructor
+	NewConfig                   config.ConfigConstructor
+	NewLogger                   LoggerConstructor
+	NewHealthChecker            healthchecker.HealthCheckerConstructor
+	NewHookRunner               hooks.HookRunnerConstructor
+	NewSecretStore              secrets.SecretStoreConstructor
+	NewScheduler                schedule.SchedulerConstructor
+	NewJanitor                  janitor.JanitorConstructor
+	NewTempFileSweeper          tempfiles.SweeperConstructor
+	NewDiskGuard                tempfiles.DiskGuardConstructor
+	CLIChecker                  func() error
+
+	// PushStrategies registers push strategies next to the default ones, replacing a default
+	// strategy of the same name.
+	PushStrategies push.PushStrategies
 }
 
 // Creator has a config, eventManager, logger and writer for creating dependencies.
@@ -109,6 +143,10 @@ type Creator struct {
 	fileSystem *afero.Afero
 	provider   CreatorModuleProvider
 	bindings   *eventmanager.EventBindings
+	history    I.DeploymentHistory
+	scheduler  I.Scheduler
+	janitor    I.Janitor
+	redactor   I.Redactor
 }
 
 // Default returns a default Creator and an Error [Deprecated].
@@ -139,19 +177,7 @@ func Custom(level string, configFilename string, provider CreatorModuleProvider)
 }
 
 func New(provider CreatorModuleProvider) (Creator, error) {
-	var err error
-	if provider.CLIChecker != nil {
-		err = provider.CLIChecker()
-	} else {
-		_, err = exec.LookPath("cf")
-	}
-
-	if err != nil {
-		return Creator{}, err
-	}
-
-	var logger I.Logger
-	logger, err = createNewLogger(provider)
+	logger, err := createNewLogger(provider)
 
 	if err != nil {
 		return Creator{}, err
@@ -167,14 +193,36 @@ func New(provider CreatorModuleProvider) (Creator, error) {
 		return Creator{}, err
 	}
 
-	return Creator{
-		cfg,
-		logger,
-		os.Stdout,
-		&afero.Afero{Fs: afero.NewOsFs()},
-		provider,
-		&eventmanager.EventBindings{},
-	}, nil
+	// The cf CLI is only needed when the courier runs it.
+	if cfg.Courier != S.APICourier {
+		if provider.CLIChecker != nil {
+			err = provider.CLIChecker()
+		} else {
+			_, err = exec.LookPath("cf")
+		}
+		if err != nil {
+			return Creator{}, err
+		}
+	}
+
+	// Every log line is redacted, so that the passwords of the config never show up in it. Requests
+	// get a redactor of their own that also masks their passwords and secrets.
+	redactor := redact.New(configSecrets(cfg)...)
+
+	creator := Creator{
+		config:     cfg,
+		logger:     redact.Logger{Log: logger, Redactor: redactor},
+		writer:     os.Stdout,
+		fileSystem: &afero.Afero{Fs: afero.NewOsFs()},
+		provider:   provider,
+		bindings:   &eventmanager.EventBindings{},
+		redactor:   redactor,
+	}
+	creator.history = history.NewDeploymentHistory(creator.fileSystem, cfg.HistoryFile, history.DefaultLimit, creator.logger)
+	creator.scheduler = creator.createScheduler()
+	creator.janitor = creator.createJanitor()
+
+	return creator, nil
 }
 
 func (c Creator) CreateNewLogger() I.Logger {
@@ -182,6 +230,23 @@ func (c Creator) CreateNewLogger() I.Logger {
 	return logger
 }
 
+// configSecrets returns the passwords of the config and the credentials of the user-provided services
+// of its environments.
+func configSecrets(cfg config.Config) []string {
+	secrets := []string{cfg.Password}
+	for _, environment := range cfg.Environments {
+		for _, foundation := range environment.FoundationDefinitions {
+			if foundation.Credentials != nil {
+				secrets = append(secrets, foundation.Credentials.Password)
+			}
+		}
+		for _, service := range environment.UserProvidedServices {
+			secrets = append(secrets, redact.Credentials(service.Credentials)...)
+		}
+	}
+	return secrets
+}
+
 func createNewLogger(provider CreatorModuleProvider) (I.Logger, error) {
 	var logger I.Logger
 	var err error
@@ -209,6 +274,10 @@ func (c Creator) CreateControllerHandler(controller I.Controller) *gin.Engine {
 	r.POST(ENDPOINT, controller.PostRequestHandler)
 	r.PUT(ENDPOINT, controller.PutRequestHandler)
 	r.DELETE(ENDPOINT, controller.DeleteRequestHandler)
+	r.POST(PROMOTE_ENDPOINT, controller.PromoteRequestHandler)
+	r.GET(SCHEDULED_ENDPOINT, controller.GetScheduledHandler)
+	r.DELETE(SCHEDULED_ENDPOINT+"/:uuid", controller.DeleteScheduledHandler)
+	r.POST(JANITOR_ENDPOINT, controller.JanitorHandler)
 
 	return r
 }
@@ -226,9 +295,17 @@ func (c Creator) CreateListener() net.Listener {
 	return ls
 }
 
-// CreateCourier returns a courier with an executor.
+// CreateCourier returns a courier with an executor, or a courier of the Cloud Controller API when
+// the config chooses the api courier.
 func (c Creator) CreateCourier() (I.Courier, error) {
-	ex, err := executor.New(c.CreateFileSystem())
+	if c.config.Courier == S.APICourier {
+		if c.provider.NewAPICourier != nil {
+			return c.provider.NewAPICourier(c.CreateFileSystem(), c.config.Timeouts), nil
+		}
+		return ccapi.NewCourier(c.CreateFileSystem(), c.config.Timeouts), nil
+	}
+
+	ex, err := c.createExecutor()
 	if err != nil {
 		return nil, err
 	}
@@ -240,6 +317,15 @@ func (c Creator) CreateCourier() (I.Courier, error) {
 	return courier.NewCourier(ex), nil
 }
 
+// createExecutor returns an executor of the cf CLI whose output has the known secrets masked.
+func (c Creator) createExecutor() (I.Executor, error) {
+	ex, err := executor.New(c.CreateFileSystem(), c.config.Timeouts)
+	if err != nil {
+		return nil, err
+	}
+	return redact.Executor{Executor: ex, Redactor: c.redactor}, nil
+}
+
 func (c Creator) GetLogger() I.Logger {
 	return c.logger
 }
@@ -267,13 +353,97 @@ func (c Creator) CreateHTTPClient() *http.Client {
 
 func (c Creator) CreateController() I.Controller {
 	return &controller.Controller{
-		Log: c.logger,
+		Log:                     c.logger,
 		RequestProcessorFactory: c.CreateRequestProcessor,
 		Config:                  c.CreateConfig(),
 		ErrorFinder:             c.createErrorFinder(),
+		Scheduler:               c.CreateScheduler(),
+		Janitor:                 c.CreateJanitor(),
+		Redactor:                c.redactor,
 	}
 }
 
+// CreateDeploymentHistory returns the deployments that are remembered across requests so that they can be retried.
+func (c Creator) CreateDeploymentHistory() I.DeploymentHistory {
+	return c.history
+}
+
+// CreateScheduler returns the scheduler that runs the deployments scheduled at a later time.
+func (c Creator) CreateScheduler() I.Scheduler {
+	return c.scheduler
+}
+
+func (c Creator) createScheduler() I.Scheduler {
+	if c.provider.NewScheduler != nil {
+		return c.provider.NewScheduler(c.CreateFileSystem(), c.config.ScheduleFile, c.logger, c.CreateRequestProcessor)
+	}
+	return schedule.NewScheduler(c.CreateFileSystem(), c.config.ScheduleFile, c.logger, c.CreateRequestProcessor)
+}
+
+// CreateJanitor returns the janitor that deletes the temporary applications interrupted pushes left behind.
+func (c Creator) CreateJanitor() I.Janitor {
+	return c.janitor
+}
+
+func (c Creator) createJanitor() I.Janitor {
+	if c.provider.NewJanitor != nil {
+		return c.provider.NewJanitor(c.CreateConfig(), c, c.CreateDeploymentHistory(), c.createEventManager, c.logger)
+	}
+	return janitor.NewJanitor(c.CreateConfig(), c, c.CreateDeploymentHistory(), c.createEventManager, c.logger)
+}
+
+// createEventManager returns an event manager with the bindings that are registered at the time.
+func (c Creator) createEventManager(logger I.DeploymentLogger) I.EventManager {
+	if c.provider.NewEventManager != nil {
+		return c.provider.NewEventManager(logger, c.GetEventBindings().GetBindings())
+	}
+	return eventmanager.NewEventManager(logger, c.GetEventBindings().GetBindings())
+}
+
+// CreateHookRunner returns a runner for the hooks of the environments.
+func (c Creator) CreateHookRunner() I.HookRunner {
+	if c.provider.NewHookRunner != nil {
+		return c.provider.NewHookRunner(&http.Client{})
+	}
+	return hooks.NewHookRunner(&http.Client{})
+}
+
+// CreateSecretStore returns the store of the secrets that user-provided services reference.
+func (c Creator) CreateSecretStore() I.SecretStore {
+	var store I.SecretStore
+	if c.provider.NewSecretStore != nil {
+		store = c.provider.NewSecretStore(c.CreateFileSystem(), c.config.SecretsDirectory)
+	} else {
+		store = secrets.NewSecretStore(c.CreateFileSystem(), c.config.SecretsDirectory)
+	}
+	return redact.SecretStore{Store: store, Redactor: c.redactor}
+}
+
+// CreateTempFileSweeper returns the sweeper of the temporary files requests leave on the local disk.
+func (c Creator) CreateTempFileSweeper() I.TempFileSweeper {
+	if c.provider.NewTempFileSweeper != nil {
+		return c.provider.NewTempFileSweeper(c.CreateFileSystem(), c.config.TempFiles, c.logger)
+	}
+	return tempfiles.NewSweeper(c.CreateFileSystem(), c.config.TempFiles, c.logger)
+}
+
+// CreateDiskGuard returns the guard that rejects new deployments when the local disk is running out of space.
+func (c Creator) CreateDiskGuard() I.DiskGuard {
+	if c.provider.NewDiskGuard != nil {
+		return c.provider.NewDiskGuard(c.config.TempFiles, c.logger)
+	}
+	return tempfiles.NewDiskGuard(c.config.TempFiles, c.logger)
+}
+
+// CreatePushStrategies returns the default push strategies and the ones registered with the provider.
+func (c Creator) CreatePushStrategies() push.PushStrategies {
+	strategies := push.DefaultPushStrategies()
+	for name, strategy := range c.provider.PushStrategies {
+		strategies[name] = strategy
+	}
+	return strategies
+}
+
 func (c Creator) CreateAuthResolver() I.AuthResolver {
 	if c.provider.NewAuthResolver != nil {
 		return c.provider.NewAuthResolver(c.CreateConfig())
@@ -319,6 +489,8 @@ func (c Creator) CreateRequestProcessor(uuid string, request interface{}, buffer
 }
 
 func (c Creator) CreateRequestCreator(uuid string, request interface{}, buffer *bytes.Buffer) (I.RequestCreator, error) {
+	c = c.forRequest(request)
+
 	post, ok := request.(R.PostDeploymentRequest)
 	if ok {
 		if c.provider.NewPushRequestCreator != nil {
@@ -338,6 +510,11 @@ func (c Creator) CreateRequestCreator(uuid string, request interface{}, buffer *
 				return c.provider.NewStartRequestCreator(c, uuid, put, buffer), nil
 			}
 			return NewStartRequestCreator(c, uuid, put, buffer), nil
+		} else if put.Request.State == R.RolledBackState {
+			if c.provider.NewRollbackRequestCreator != nil {
+				return c.provider.NewRollbackRequestCreator(c, uuid, put, buffer), nil
+			}
+			return NewRollbackRequestCreator(c, uuid, put, buffer), nil
 		}
 	}
 	delete, ok := request.(R.DeleteDeploymentRequest)
@@ -350,6 +527,30 @@ func (c Creator) CreateRequestCreator(uuid string, request interface{}, buffer *
 	return nil, InvalidRequestError{}
 }
 
+// forRequest returns the creator with a Redactor of its own that also knows the password and secrets
+// of the request, so that they are masked in its output and logs and forgotten after it.
+func (c Creator) forRequest(request interface{}) Creator {
+	if c.redactor == nil {
+		return c
+	}
+
+	var secrets []string
+	switch r := request.(type) {
+	case R.PostDeploymentRequest:
+		secrets = append([]string{r.Authorization.Password}, r.Request.Secrets()...)
+	case R.PutDeploymentRequest:
+		secrets = []string{r.Authorization.Password}
+	case R.DeleteDeploymentRequest:
+		secrets = []string{r.Authorization.Password}
+	}
+
+	c.redactor = c.redactor.With(secrets...)
+	if logger, ok := c.logger.(redact.Logger); ok {
+		c.logger = redact.Logger{Log: logger.Log, Redactor: c.redactor}
+	}
+	return c
+}
+
 func (c Creator) createSilentDeployer() I.Deployer {
 	return deployer.SilentDeployer{}
 }
@@ -11,12 +11,14 @@ import (
 	"io/ioutil"
 
 	"github.com/compozed/deployadactyl/config"
+	"github.com/compozed/deployadactyl/controller/deployer/bluegreen/courier/ccapi"
 	"github.com/compozed/deployadactyl/eventmanager/handlers/healthchecker"
 	I "github.com/compozed/deployadactyl/interfaces"
 	"github.com/compozed/deployadactyl/mocks"
 	"github.com/compozed/deployadactyl/request"
 	"github.com/compozed/deployadactyl/state"
 	"github.com/compozed/deployadactyl/state/push"
+	S "github.com/compozed/deployadactyl/structs"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/op/go-logging"
@@ -48,15 +50,38 @@ var _ = Describe("Creator", func() {
 	Describe("New", func() {
 		Context("if CLI Checker returns error", func() {
 			It("returns an error", func() {
-				provider := CreatorModuleProvider{CLIChecker: func() error {
-					return errors.New("this is a test error")
-				}}
+				provider := CreatorModuleProvider{
+					CLIChecker: func() error {
+						return errors.New("this is a test error")
+					},
+					NewConfig: func() (config.Config, error) {
+						return config.Config{}, nil
+					},
+				}
 
 				_, err := New(provider)
 
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(Equal("this is a test error"))
 			})
+
+			It("does not return it when the config chooses the api courier", func() {
+				provider := CreatorModuleProvider{
+					CLIChecker: func() error {
+						return errors.New("this is a test error")
+					},
+					NewConfig: func() (config.Config, error) {
+						return config.Config{Courier: S.APICourier}, nil
+					},
+				}
+
+				creator, err := New(provider)
+				Expect(err).ToNot(HaveOccurred())
+
+				courier, err := creator.CreateCourier()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(courier).To(BeAssignableToTypeOf(&ccapi.Courier{}))
+			})
 		})
 
 		Context("when Config constructor is provided", func() {
@@ -75,19 +100,33 @@ var _ = Describe("Creator", func() {
 		})
 
 		Context("when Config constructor is not provided", func() {
+			var workingDirectory, configDirectory string
+
+			BeforeEach(func() {
+				workingDirectory, _ = os.Getwd()
+				configDirectory, _ = ioutil.TempDir("", "creator-test-")
+				Expect(os.Chdir(configDirectory)).To(Succeed())
+			})
+
+			AfterEach(func() {
+				Expect(os.Chdir(workingDirectory)).To(Succeed())
+				os.RemoveAll(configDirectory)
+			})
+
 			It("should return with the default Config", func() {
 				os.Setenv("CF_USERNAME", "myusername")
 				os.Setenv("CF_PASSWORD", "mypassword")
 
-				config := `---
+				configYaml := `---
 environments:
  - name: my-env
    foundations:
     - https://my/foundation
 error_matchers:
- - description: a description`
+ - description: a description
+   pattern: a pattern`
 
-				ioutil.WriteFile("./config.yml", []byte(config), 0777)
+				Expect(ioutil.WriteFile(config.DefaultConfigPath, []byte(configYaml), 0600)).To(Succeed())
 
 				creator, err := New(CreatorModuleProvider{})
 
@@ -8,11 +8,11 @@ import (
 	"github.com/compozed/deployadactyl/controller/deployer"
 	"github.com/compozed/deployadactyl/controller/deployer/bluegreen"
 	"github.com/compozed/deployadactyl/controller/deployer/prechecker"
-	"github.com/compozed/deployadactyl/eventmanager"
 	I "github.com/compozed/deployadactyl/interfaces"
 	"github.com/compozed/deployadactyl/request"
 	"github.com/compozed/deployadactyl/state/delete"
 	"github.com/compozed/deployadactyl/state/push"
+	"github.com/compozed/deployadactyl/state/rollback"
 	"github.com/compozed/deployadactyl/state/start"
 	"github.com/compozed/deployadactyl/state/stop"
 	"github.com/compozed/deployadactyl/structs"
@@ -20,15 +20,9 @@ import (
 
 func newRequestCreator(c Creator, uuid string, b *bytes.Buffer) RequestCreator {
 	logger := I.DeploymentLogger{UUID: uuid, Log: c.GetLogger()}
-	var em I.EventManager
-	if c.provider.NewEventManager != nil {
-		em = c.provider.NewEventManager(logger, c.GetEventBindings().GetBindings())
-	} else {
-		em = eventmanager.NewEventManager(logger, c.GetEventBindings().GetBindings())
-	}
 	return RequestCreator{
 		Creator:      c,
-		EventManager: em,
+		EventManager: c.createEventManager(logger),
 		Buffer:       b,
 		Log:          logger,
 	}
@@ -50,9 +44,9 @@ func (r *RequestCreator) CreateDeployer() I.Deployer {
 
 func (r RequestCreator) CreateBlueGreener() I.BlueGreener {
 	if r.provider.NewBlueGreen != nil {
-		return r.provider.NewBlueGreen(r.Log)
+		return r.provider.NewBlueGreen(r.Log, r.CreateEventManager())
 	}
-	return bluegreen.NewBlueGreen(r.Log)
+	return bluegreen.NewBlueGreen(r.Log, r.CreateEventManager())
 }
 
 func (r RequestCreator) CreateFetcher() I.Fetcher {
@@ -103,16 +97,16 @@ func (r PushRequestCreator) CreateRequestProcessor() I.RequestProcessor {
 
 func (r PushRequestCreator) CreatePushController() request.PushController {
 	if r.provider.NewPushController != nil {
-		return r.provider.NewPushController(r.Log, r.CreateDeployer(), r.createSilentDeployer(), r.CreateEventManager(), r.createErrorFinder(), r, r.CreateAuthResolver(), r.CreateEnvResolver())
+		return r.provider.NewPushController(r.Log, r.CreateDeployer(), r.createSilentDeployer(), r.CreateEventManager(), r.createErrorFinder(), r, r.CreateAuthResolver(), r.CreateEnvResolver(), r.CreateDeploymentHistory(), r.CreatePushStrategies(), r.CreateSecretStore(), r.CreateDiskGuard(), r.redactor)
 	}
-	return push.NewPushController(r.Log, r.CreateDeployer(), r.createSilentDeployer(), r.CreateEventManager(), r.createErrorFinder(), r, r.CreateAuthResolver(), r.CreateEnvResolver())
+	return push.NewPushController(r.Log, r.CreateDeployer(), r.createSilentDeployer(), r.CreateEventManager(), r.createErrorFinder(), r, r.CreateAuthResolver(), r.CreateEnvResolver(), r.CreateDeploymentHistory(), r.CreatePushStrategies(), r.CreateSecretStore(), r.CreateDiskGuard(), r.redactor)
 }
 
 func (r PushRequestCreator) PushManager(deployEventData structs.DeployEventData, auth I.Authorization, env structs.Environment, envVars map[string]string) I.ActionCreator {
 	if r.provider.NewPushManager != nil {
-		return r.provider.NewPushManager(r.Creator, r.CreateEventManager(), r.Log, r.CreateFetcher(), deployEventData, r.CreateFileSystem(), r.Request.CFContext, auth, env, envVars, r.CreateHealthChecker(), r.CreateRouteMapper())
+		return r.provider.NewPushManager(r.Creator, r.CreateEventManager(), r.Log, r.CreateFetcher(), deployEventData, r.CreateFileSystem(), r.CreateFileSystem(), r.Request.CFContext, auth, env, envVars, r.CreateHealthChecker(), r.CreateRouteMapper(), r.CreatePushStrategies(), r.CreateHookRunner())
 	} else {
-		return push.NewPushManager(r.Creator, r.CreateEventManager(), r.Log, r.CreateFetcher(), deployEventData, r.CreateFileSystem(), r.Request.CFContext, auth, env, envVars, r.CreateHealthChecker(), r.CreateRouteMapper())
+		return push.NewPushManager(r.Creator, r.CreateEventManager(), r.Log, r.CreateFetcher(), deployEventData, r.CreateFileSystem(), r.CreateFileSystem(), r.Request.CFContext, auth, env, envVars, r.CreateHealthChecker(), r.CreateRouteMapper(), r.CreatePushStrategies(), r.CreateHookRunner())
 	}
 }
 
@@ -152,6 +146,41 @@ func (r StopRequestCreator) StopManager(deployEventData structs.DeployEventData)
 	}
 }
 
+type RollbackRequestCreatorConstructor func(creator Creator, uuid string, request request.PutDeploymentRequest, buffer *bytes.Buffer) I.RequestCreator
+
+func NewRollbackRequestCreator(creator Creator, uuid string, request request.PutDeploymentRequest, buffer *bytes.Buffer) I.RequestCreator {
+	return &RollbackRequestCreator{
+		RequestCreator: newRequestCreator(creator, uuid, buffer),
+		Request:        request,
+	}
+}
+
+type RollbackRequestCreator struct {
+	RequestCreator
+	Request request.PutDeploymentRequest
+}
+
+func (r RollbackRequestCreator) CreateRequestProcessor() I.RequestProcessor {
+	if r.provider.NewRollbackRequestProcessor != nil {
+		return r.provider.NewRollbackRequestProcessor(r.Log, r.CreateRollbackController(), r.Request, r.Buffer)
+	}
+	return rollback.NewRollbackRequestProcessor(r.Log, r.CreateRollbackController(), r.Request, r.Buffer)
+}
+
+func (r RollbackRequestCreator) CreateRollbackController() request.RollbackController {
+	if r.provider.NewRollbackController != nil {
+		return r.provider.NewRollbackController(r.Log, r.CreateDeployer(), r.CreateEventManager(), r.createErrorFinder(), r, r.CreateAuthResolver(), r.CreateEnvResolver())
+	}
+	return rollback.NewRollbackController(r.Log, r.CreateDeployer(), r.CreateEventManager(), r.createErrorFinder(), r, r.CreateAuthResolver(), r.CreateEnvResolver())
+}
+
+func (r RollbackRequestCreator) RollbackManager(deployEventData structs.DeployEventData) I.ActionCreator {
+	if r.provider.NewRollbackManager != nil {
+		return r.provider.NewRollbackManager(r.Creator, r.CreateEventManager(), r.Log, deployEventData)
+	}
+	return rollback.NewRollbackManager(r.Creator, r.CreateEventManager(), r.Log, deployEventData)
+}
+
 type StartRequestCreatorConstructor func(creator Creator, uuid string, request request.PutDeploymentRequest, buffer *bytes.Buffer) I.RequestCreator
 
 func NewStartRequestCreator(creator Creator, uuid string, request request.PutDeploymentRequest, buffer *bytes.Buffer) I.RequestCreator {
@@ -67,6 +67,7 @@ var _ = Describe("RequestCreator", func() {
 				Expect(concrete.Randomizer).ToNot(BeNil())
 				Expect(concrete.ErrorFinder).ToNot(BeNil())
 				Expect(concrete.Log.UUID).To(Equal("the uuid"))
+				Expect(concrete.EventManager).To(Equal(rc.EventManager))
 			})
 		})
 	})
@@ -77,7 +78,7 @@ var _ = Describe("RequestCreator", func() {
 				expected := &mocks.BlueGreener{}
 				creator := Creator{
 					provider: CreatorModuleProvider{
-						NewBlueGreen: func(logger I.DeploymentLogger) I.BlueGreener {
+						NewBlueGreen: func(logger I.DeploymentLogger, eventManager I.EventManager) I.BlueGreener {
 							return expected
 						},
 					},
@@ -325,7 +326,7 @@ var _ = Describe("RequestCreator", func() {
 					expected := &mocks.PushController{}
 					creator := Creator{
 						provider: CreatorModuleProvider{
-							NewPushController: func(log I.DeploymentLogger, deployer, silentDeployer I.Deployer, eventManager I.EventManager, errorFinder I.ErrorFinder, pushManagerFactory I.PushManagerFactory, authResolver I.AuthResolver, resolver I.EnvResolver) request.PushController {
+							NewPushController: func(log I.DeploymentLogger, deployer, silentDeployer I.Deployer, eventManager I.EventManager, errorFinder I.ErrorFinder, pushManagerFactory I.PushManagerFactory, authResolver I.AuthResolver, resolver I.EnvResolver, history I.DeploymentHistory, pushStrategies push.PushStrategies, secretStore I.SecretStore, diskGuard I.DiskGuard, redactor I.Redactor) request.PushController {
 								return expected
 							},
 						},
@@ -372,7 +373,7 @@ var _ = Describe("RequestCreator", func() {
 					expected := &mocks.PushManager{}
 					creator := Creator{
 						provider: CreatorModuleProvider{
-							NewPushManager: func(courierCreator I.CourierCreator, eventManager I.EventManager, log I.DeploymentLogger, fetcher I.Fetcher, deployEventData structs.DeployEventData, fileSystemCleaner push.FileSystemCleaner, cfContext I.CFContext, auth I.Authorization, environment structs.Environment, envVars map[string]string, checker healthchecker.HealthChecker, mapper routemapper.RouteMapper) I.ActionCreator {
+							NewPushManager: func(courierCreator I.CourierCreator, eventManager I.EventManager, log I.DeploymentLogger, fetcher I.Fetcher, deployEventData structs.DeployEventData, fileSystemCleaner push.FileSystemCleaner, fileSystem push.ManifestFileSystem, cfContext I.CFContext, auth I.Authorization, environment structs.Environment, envVars map[string]string, checker healthchecker.HealthChecker, mapper routemapper.RouteMapper, pushStrategies push.PushStrategies, hookRunner I.HookRunner) I.ActionCreator {
 								return expected
 							},
 						},
@@ -415,6 +416,7 @@ var _ = Describe("RequestCreator", func() {
 					Expect(concrete.Fetcher).ToNot(BeNil())
 					Expect(concrete.DeployEventData).ToNot(BeNil())
 					Expect(concrete.FileSystemCleaner).ToNot(BeNil())
+					Expect(concrete.FileSystem).ToNot(BeNil())
 					Expect(concrete.CFContext).To(Equal(rc.Request.Deployment.CFContext))
 					Expect(concrete.Auth).ToNot(BeNil())
 					Expect(concrete.Environment).ToNot(BeNil())
@@ -0,0 +1,159 @@
+package deploywindow
+
+import (
+	"errors"
+	"time"
+
+	"github.com/compozed/deployadactyl/structs"
+)
+
+const (
+	dateLayout = "2006-01-02"
+
+	// maxSteps bounds the search for the next open window when freezes and
+	// deploy windows keep deferring each other.
+	maxSteps = 1000
+)
+
+// Period is a parsed freeze.
+type Period struct {
+	Start  time.Time
+	End    time.Time
+	Reason string
+}
+
+// Contains returns whether t falls between the start and end of the period.
+func (p Period) Contains(t time.Time) bool {
+	return !t.Before(p.Start) && t.Before(p.End)
+}
+
+// ParseFreeze parses the start and end of a freeze. Dates cover the whole day in UTC.
+func ParseFreeze(freeze structs.Freeze) (Period, error) {
+	start, err := parseTime(freeze.Start, false)
+	if err != nil {
+		return Period{}, err
+	}
+
+	end, err := parseTime(freeze.End, true)
+	if err != nil {
+		return Period{}, err
+	}
+
+	if !end.After(start) {
+		return Period{}, InvalidFreezeError{Value: freeze.End, Err: errors.New("freeze must end after it starts")}
+	}
+
+	return Period{Start: start, End: end, Reason: freeze.Reason}, nil
+}
+
+// Lock describes why an environment does not accept deployments.
+type Lock struct {
+	Reason string
+
+	// NextWindow is when the environment opens again. It is zero when no
+	// opening could be found.
+	NextWindow time.Time
+}
+
+// Check returns a Lock when environment is frozen or outside of all of its deploy
+// windows at now. It returns nil when the environment is open.
+func Check(environment structs.Environment, now time.Time) (*Lock, error) {
+	schedules := make([]Schedule, 0, len(environment.DeployWindows))
+	for _, window := range environment.DeployWindows {
+		schedule, err := ParseSchedule(window.Cron, window.Timezone)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, schedule)
+	}
+
+	periods := make([]Period, 0, len(environment.Freezes))
+	for _, freeze := range environment.Freezes {
+		period, err := ParseFreeze(freeze)
+		if err != nil {
+			return nil, err
+		}
+		periods = append(periods, period)
+	}
+
+	var reason string
+	if period, ok := frozenAt(periods, now); ok {
+		reason = period.Reason
+		if reason == "" {
+			reason = "deployment freeze"
+		}
+	} else if !openAt(schedules, now) {
+		reason = "outside of the deploy windows"
+	} else {
+		return nil, nil
+	}
+
+	return &Lock{Reason: reason, NextWindow: nextWindow(schedules, periods, now)}, nil
+}
+
+func nextWindow(schedules []Schedule, periods []Period, t time.Time) time.Time {
+	for i := 0; i < maxSteps; i++ {
+		if period, ok := frozenAt(periods, t); ok {
+			t = period.End
+			continue
+		}
+
+		if len(schedules) == 0 {
+			return t
+		}
+
+		next, found := time.Time{}, false
+		for _, schedule := range schedules {
+			if candidate, ok := schedule.Next(t); ok && (!found || candidate.Before(next)) {
+				next, found = candidate, true
+			}
+		}
+
+		if !found {
+			return time.Time{}
+		}
+		if next.Equal(t) {
+			return t
+		}
+		t = next
+	}
+
+	return time.Time{}
+}
+
+func frozenAt(periods []Period, t time.Time) (Period, bool) {
+	for _, period := range periods {
+		if period.Contains(t) {
+			return period, true
+		}
+	}
+	return Period{}, false
+}
+
+func openAt(schedules []Schedule, t time.Time) bool {
+	if len(schedules) == 0 {
+		return true
+	}
+
+	for _, schedule := range schedules {
+		if schedule.Matches(t) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseTime(value string, end bool) (time.Time, error) {
+	if date, err := time.Parse(dateLayout, value); err == nil {
+		if end {
+			return date.AddDate(0, 0, 1), nil
+		}
+		return date, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, InvalidFreezeError{Value: value, Err: errors.New("must be a date (2006-01-02) or an RFC 3339 timestamp")}
+	}
+	return t, nil
+}
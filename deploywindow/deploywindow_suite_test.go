@@ -0,0 +1,13 @@
+package deploywindow_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestDeploywindow(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Deploywindow Suite")
+}
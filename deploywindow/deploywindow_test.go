@@ -0,0 +1,121 @@
+package deploywindow_test
+
+import (
+	"time"
+
+	. "github.com/compozed/deployadactyl/deploywindow"
+	"github.com/compozed/deployadactyl/structs"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Deploy windows", func() {
+	// 2026-10-19 is a Monday.
+	now := time.Date(2026, time.October, 19, 18, 0, 0, 0, time.UTC)
+
+	Describe("ParseFreeze", func() {
+		It("covers the whole end date", func() {
+			period, err := ParseFreeze(structs.Freeze{Start: "2026-12-20", End: "2026-12-31", Reason: "holidays"})
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(period.Start).To(Equal(time.Date(2026, time.December, 20, 0, 0, 0, 0, time.UTC)))
+			Expect(period.End).To(Equal(time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC)))
+			Expect(period.Reason).To(Equal("holidays"))
+		})
+
+		It("accepts RFC 3339 timestamps", func() {
+			period, err := ParseFreeze(structs.Freeze{Start: "2026-10-19T17:00:00Z", End: "2026-10-19T19:00:00-05:00"})
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(period.End).To(BeTemporally("==", time.Date(2026, time.October, 20, 0, 0, 0, 0, time.UTC)))
+		})
+
+		It("returns an error when a date cannot be parsed", func() {
+			_, err := ParseFreeze(structs.Freeze{Start: "next tuesday", End: "2026-12-31"})
+
+			Expect(err).To(BeAssignableToTypeOf(InvalidFreezeError{}))
+			Expect(err).To(MatchError(ContainSubstring(`invalid freeze "next tuesday"`)))
+		})
+
+		It("returns an error when the freeze ends before it starts", func() {
+			_, err := ParseFreeze(structs.Freeze{Start: "2026-12-31T10:00:00Z", End: "2026-12-31T09:00:00Z"})
+
+			Expect(err).To(MatchError(ContainSubstring("freeze must end after it starts")))
+		})
+	})
+
+	Describe("Check", func() {
+		It("returns nil for an environment without windows or freezes", func() {
+			lock, err := Check(structs.Environment{}, now)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lock).To(BeNil())
+		})
+
+		It("returns nil inside of a deploy window", func() {
+			environment := structs.Environment{DeployWindows: []structs.DeployWindow{{Cron: "* 17-18 * * 1-5"}}}
+
+			lock, err := Check(environment, now)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lock).To(BeNil())
+		})
+
+		It("returns a lock with the next window outside of the deploy windows", func() {
+			environment := structs.Environment{DeployWindows: []structs.DeployWindow{
+				{Cron: "* 9-16 * * 1-5"},
+				{Cron: "* 20-21 * * 1"},
+			}}
+
+			lock, err := Check(environment, now)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lock.Reason).To(Equal("outside of the deploy windows"))
+			Expect(lock.NextWindow).To(BeTemporally("==", time.Date(2026, time.October, 19, 20, 0, 0, 0, time.UTC)))
+		})
+
+		It("returns a lock with the reason of the freeze", func() {
+			environment := structs.Environment{Freezes: []structs.Freeze{{Start: "2026-10-19", End: "2026-10-20", Reason: "quarter end"}}}
+
+			lock, err := Check(environment, now)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lock.Reason).To(Equal("quarter end"))
+			Expect(lock.NextWindow).To(BeTemporally("==", time.Date(2026, time.October, 21, 0, 0, 0, 0, time.UTC)))
+		})
+
+		It("returns the first deploy window after the freeze", func() {
+			environment := structs.Environment{
+				DeployWindows: []structs.DeployWindow{{Cron: "0 9 * * 1-5"}},
+				Freezes: []structs.Freeze{
+					{Start: "2026-10-19", End: "2026-10-21"},
+					{Start: "2026-10-22T00:00:00Z", End: "2026-10-22T09:30:00Z"},
+				},
+			}
+
+			lock, err := Check(environment, now)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lock.Reason).To(Equal("deployment freeze"))
+			Expect(lock.NextWindow).To(BeTemporally("==", time.Date(2026, time.October, 23, 9, 0, 0, 0, time.UTC)))
+		})
+
+		It("returns a zero next window when the environment never opens", func() {
+			environment := structs.Environment{DeployWindows: []structs.DeployWindow{{Cron: "* * 30 2 *"}}}
+
+			lock, err := Check(environment, now)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lock.NextWindow.IsZero()).To(BeTrue())
+		})
+
+		It("returns an error when a deploy window is invalid", func() {
+			environment := structs.Environment{DeployWindows: []structs.DeployWindow{{Cron: "whenever"}}}
+
+			_, err := Check(environment, now)
+
+			Expect(err).To(BeAssignableToTypeOf(InvalidScheduleError{}))
+		})
+	})
+})
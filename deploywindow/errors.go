@@ -0,0 +1,21 @@
+package deploywindow
+
+import "fmt"
+
+type InvalidScheduleError struct {
+	Cron string
+	Err  error
+}
+
+func (e InvalidScheduleError) Error() string {
+	return fmt.Sprintf("invalid deploy window %q: %s", e.Cron, e.Err)
+}
+
+type InvalidFreezeError struct {
+	Value string
+	Err   error
+}
+
+func (e InvalidFreezeError) Error() string {
+	return fmt.Sprintf("invalid freeze %q: %s", e.Value, e.Err)
+}
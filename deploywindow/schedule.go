@@ -0,0 +1,179 @@
+// Package deploywindow decides whether an environment accepts deployments at a given time.
+package deploywindow
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// searchLimit is how far ahead Next looks for a matching minute.
+const searchLimit = 5
+
+type fieldBounds struct {
+	name     string
+	min, max int
+}
+
+var bounds = []fieldBounds{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// Schedule is a parsed cron expression. Every minute it matches is open.
+type Schedule struct {
+	minute, hour, dayOfMonth, month, dayOfWeek uint64
+
+	// anyDay is set when either day field is a wildcard, in which case both day
+	// fields have to match. Otherwise cron matches either of them.
+	anyDay bool
+
+	location *time.Location
+}
+
+// ParseSchedule parses a five field cron expression evaluated in the given IANA timezone.
+// An empty timezone means UTC.
+func ParseSchedule(cron, timezone string) (Schedule, error) {
+	fields := strings.Fields(cron)
+	if len(fields) != len(bounds) {
+		return Schedule{}, InvalidScheduleError{Cron: cron, Err: fmt.Errorf("expected %d fields, found %d", len(bounds), len(fields))}
+	}
+
+	sets := make([]uint64, len(fields))
+	for i, field := range fields {
+		set, err := parseField(field, bounds[i])
+		if err != nil {
+			return Schedule{}, InvalidScheduleError{Cron: cron, Err: err}
+		}
+		sets[i] = set
+	}
+
+	// Sunday can be written as either 0 or 7.
+	if sets[4]&(1<<7) != 0 {
+		sets[4] |= 1
+	}
+
+	location := time.UTC
+	if timezone != "" {
+		var err error
+		location, err = time.LoadLocation(timezone)
+		if err != nil {
+			return Schedule{}, InvalidScheduleError{Cron: cron, Err: err}
+		}
+	}
+
+	return Schedule{
+		minute:     sets[0],
+		hour:       sets[1],
+		dayOfMonth: sets[2],
+		month:      sets[3],
+		dayOfWeek:  sets[4],
+		anyDay:     strings.HasPrefix(fields[2], "*") || strings.HasPrefix(fields[4], "*"),
+		location:   location,
+	}, nil
+}
+
+// Matches returns whether t falls in a minute matched by the schedule.
+func (s Schedule) Matches(t time.Time) bool {
+	t = t.In(s.location)
+
+	return has(s.month, int(t.Month())) &&
+		s.matchesDay(t) &&
+		has(s.hour, t.Hour()) &&
+		has(s.minute, t.Minute())
+}
+
+// Next returns t if it is matched by the schedule, or otherwise the start of the
+// first matched minute after t. It returns false when nothing matches in the next
+// five years.
+func (s Schedule) Next(t time.Time) (time.Time, bool) {
+	if s.Matches(t) {
+		return t, true
+	}
+
+	t = t.In(s.location)
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, s.location)
+	limit := t.AddDate(searchLimit, 0, 0)
+
+	for t.Before(limit) {
+		switch {
+		case !has(s.month, int(t.Month())):
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, s.location)
+		case !s.matchesDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, s.location)
+		case !has(s.hour, t.Hour()):
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, s.location)
+		case !has(s.minute, t.Minute()):
+			t = t.Add(time.Minute)
+		default:
+			return t, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+func (s Schedule) matchesDay(t time.Time) bool {
+	dayOfMonth := has(s.dayOfMonth, t.Day())
+	dayOfWeek := has(s.dayOfWeek, int(t.Weekday()))
+
+	if s.anyDay {
+		return dayOfMonth && dayOfWeek
+	}
+	return dayOfMonth || dayOfWeek
+}
+
+// parseField parses a comma separated list of values, ranges and steps, such as 1-5 or */15.
+func parseField(field string, b fieldBounds) (uint64, error) {
+	var set uint64
+
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			rangePart = part[:i]
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step in %s field: %q", b.name, part)
+			}
+		}
+
+		low, high := b.min, b.max
+		if rangePart != "*" {
+			bounds := strings.SplitN(rangePart, "-", 2)
+
+			var err error
+			low, err = strconv.Atoi(bounds[0])
+			if err != nil {
+				return 0, fmt.Errorf("invalid value in %s field: %q", b.name, part)
+			}
+			high = low
+			if len(bounds) == 2 {
+				high, err = strconv.Atoi(bounds[1])
+				if err != nil {
+					return 0, fmt.Errorf("invalid value in %s field: %q", b.name, part)
+				}
+			} else if step != 1 {
+				high = b.max
+			}
+		}
+
+		if low < b.min || high > b.max || low > high {
+			return 0, fmt.Errorf("%s field out of range %d-%d: %q", b.name, b.min, b.max, part)
+		}
+
+		for value := low; value <= high; value += step {
+			set |= 1 << uint(value)
+		}
+	}
+
+	return set, nil
+}
+
+func has(set uint64, value int) bool {
+	return set&(1<<uint(value)) != 0
+}
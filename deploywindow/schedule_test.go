@@ -0,0 +1,111 @@
+package deploywindow_test
+
+import (
+	"time"
+
+	. "github.com/compozed/deployadactyl/deploywindow"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Schedule", func() {
+	// 2026-10-19 is a Monday.
+	monday := time.Date(2026, time.October, 19, 10, 30, 15, 0, time.UTC)
+
+	Describe("ParseSchedule", func() {
+		It("returns an error when the number of fields is wrong", func() {
+			_, err := ParseSchedule("* * * *", "")
+
+			Expect(err).To(MatchError(ContainSubstring("expected 5 fields, found 4")))
+		})
+
+		It("returns an error when a value is out of range", func() {
+			_, err := ParseSchedule("* 9-24 * * *", "")
+
+			Expect(err).To(MatchError(ContainSubstring("hour field out of range 0-23")))
+		})
+
+		It("returns an error when a value is not a number", func() {
+			_, err := ParseSchedule("* * * * mon", "")
+
+			Expect(err).To(BeAssignableToTypeOf(InvalidScheduleError{}))
+		})
+
+		It("returns an error when the timezone is unknown", func() {
+			_, err := ParseSchedule("* * * * *", "Mars/Olympus_Mons")
+
+			Expect(err).To(BeAssignableToTypeOf(InvalidScheduleError{}))
+		})
+	})
+
+	Describe("Matches", func() {
+		It("matches ranges, lists and steps", func() {
+			schedule, err := ParseSchedule("*/15 9-17 * * 1,3,5", "")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(schedule.Matches(monday.Add(-30 * time.Minute))).To(BeTrue())
+			Expect(schedule.Matches(monday.Add(5 * time.Minute))).To(BeFalse())
+			Expect(schedule.Matches(monday.AddDate(0, 0, 1).Add(-30 * time.Minute))).To(BeFalse())
+		})
+
+		It("treats 7 as Sunday", func() {
+			schedule, _ := ParseSchedule("* * * * 7", "")
+
+			Expect(schedule.Matches(monday.AddDate(0, 0, -1))).To(BeTrue())
+		})
+
+		It("matches either day field when both are restricted", func() {
+			schedule, _ := ParseSchedule("* * 1 * 1", "")
+
+			Expect(schedule.Matches(monday)).To(BeTrue())
+			Expect(schedule.Matches(time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC))).To(BeTrue())
+			Expect(schedule.Matches(monday.AddDate(0, 0, 1))).To(BeFalse())
+		})
+
+		It("evaluates the schedule in its timezone", func() {
+			schedule, err := ParseSchedule("* 9 * * *", "America/Chicago")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(schedule.Matches(time.Date(2026, time.October, 19, 14, 0, 0, 0, time.UTC))).To(BeTrue())
+			Expect(schedule.Matches(time.Date(2026, time.October, 19, 9, 0, 0, 0, time.UTC))).To(BeFalse())
+		})
+	})
+
+	Describe("Next", func() {
+		It("returns the given time when it matches", func() {
+			schedule, _ := ParseSchedule("* * * * *", "")
+
+			next, ok := schedule.Next(monday)
+
+			Expect(ok).To(BeTrue())
+			Expect(next).To(Equal(monday))
+		})
+
+		It("returns the start of the next matching minute", func() {
+			schedule, _ := ParseSchedule("0 9 * * 1-5", "")
+
+			next, ok := schedule.Next(monday)
+
+			Expect(ok).To(BeTrue())
+			Expect(next).To(BeTemporally("==", time.Date(2026, time.October, 20, 9, 0, 0, 0, time.UTC)))
+		})
+
+		It("skips to the next matching month", func() {
+			schedule, _ := ParseSchedule("30 6 1 2 *", "")
+
+			next, ok := schedule.Next(monday)
+
+			Expect(ok).To(BeTrue())
+			Expect(next).To(BeTemporally("==", time.Date(2027, time.February, 1, 6, 30, 0, 0, time.UTC)))
+		})
+
+		It("returns false when the schedule never matches", func() {
+			schedule, _ := ParseSchedule("* * 31 2 *", "")
+
+			_, ok := schedule.Next(monday)
+
+			Expect(ok).To(BeFalse())
+		})
+	})
+})
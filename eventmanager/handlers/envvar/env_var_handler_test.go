@@ -75,6 +75,35 @@ var _ = Describe("Env_Var_Handler", func() {
 		})
 	})
 
+	Context("when an envvarhandler is called with a multi-application manifest", func() {
+		It("keeps the paths of the applications", func() {
+
+			path := "/tmp"
+			eventHandler.FileSystem.MkdirAll(path, 0755)
+
+			ievent.AppPath = path
+			ievent.Manifest = `
+applications:
+- name: api
+  path: api
+- name: worker
+  path: worker`
+			ievent.EnvironmentVariables = map[string]string{"one": "one"}
+			ievent.CFContext = I.CFContext{
+				Application: "testApp",
+			}
+
+			Expect(eventHandler.ArtifactRetrievalSuccessEventHandler(ievent)).To(Succeed())
+
+			manifest, err := ReadManifest(path+"/manifest.yml", log, eventHandler.FileSystem)
+
+			Expect(err).To(BeNil())
+			Expect(manifest.Content.Applications[0].Path).To(Equal("api"))
+			Expect(manifest.Content.Applications[1].Path).To(Equal("worker"))
+			Expect(manifest.Content.Applications[1].Env).To(Equal(map[string]string{"one": "one"}))
+		})
+	})
+
 	Context("when an envvarhandler is called with bogus manifest in deploy info", func() {
 		It("it should be fail", func() {
 
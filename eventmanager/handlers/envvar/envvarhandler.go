@@ -30,10 +30,14 @@ func (handler Envvarhandler) ArtifactRetrievalSuccessEventHandler(event push.Art
 	//Add any Environment variables
 	addEnvResult, _ := m.AddEnvironmentVariables(event.EnvironmentVariables)
 
-	if m.Content.Applications[0].Path != "" || addEnvResult {
-
-		//Ensure path is empty. We are using a local/tmp file system with exploded contents for the deploy!
+	//Ensure path is empty. We are using a local/tmp file system with exploded contents for the deploy!
+	//The applications of a multi-application manifest keep their paths within the artifact.
+	if len(m.Content.Applications) == 1 && m.Content.Applications[0].Path != "" {
 		m.Content.Applications[0].Path = ""
+		addEnvResult = true
+	}
+
+	if addEnvResult {
 
 		//Re-Write the m
 		m.WriteManifest(event.AppPath, true)
@@ -107,16 +107,18 @@ func (m *Manifest) AddEnvVar(name string, value string) (err error) {
 		return err
 	}
 
-	vars := make(map[string]string)
-
 	if m.HasApplications() {
 
-		if m.Content.Applications[0].Env != nil {
-			vars = m.Content.Applications[0].Env
-		}
+		for i := range m.Content.Applications {
+			vars := make(map[string]string)
 
-		vars[name] = value
-		m.Content.Applications[0].Env = vars
+			if m.Content.Applications[i].Env != nil {
+				vars = m.Content.Applications[i].Env
+			}
+
+			vars[name] = value
+			m.Content.Applications[i].Env = vars
+		}
 	}
 
 	return err
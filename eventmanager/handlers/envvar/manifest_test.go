@@ -172,6 +172,22 @@ applications:
 		})
 	})
 
+	Context("when manifest has multiple applications", func() {
+		It("adds the env vars to every application", func() {
+			manifest, _ := CreateManifest("", `
+applications:
+- name: api
+- name: worker
+  env:
+    QUEUE: jobs`, filesystem, log)
+
+			manifest.AddEnvironmentVariables(map[string]string{"bubba": "gump"})
+
+			Expect(manifest.Content.Applications[0].Env).To(Equal(map[string]string{"bubba": "gump"}))
+			Expect(manifest.Content.Applications[1].Env).To(Equal(map[string]string{"QUEUE": "jobs", "bubba": "gump"}))
+		})
+	})
+
 	Context("when manifest is invalid", func() {
 		It("manifest has applications is false", func() {
 			manifest, _ := CreateManifest("", `bork`, filesystem, log)
@@ -2,6 +2,7 @@ package healthchecker
 
 import (
 	"fmt"
+	"strings"
 )
 
 type HealthCheckError struct {
@@ -23,6 +24,15 @@ health check failed:
 	)
 }
 
+type SmokeTestError struct {
+	Failed []string
+	Total  int
+}
+
+func (e SmokeTestError) Error() string {
+	return fmt.Sprintf("%d of %d smoke tests failed: %s", len(e.Failed), e.Total, strings.Join(e.Failed, ", "))
+}
+
 type MapRouteError struct {
 	AppName string
 	Domain  string
@@ -2,12 +2,15 @@ package healthchecker
 
 import (
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"regexp"
 	"strings"
 
 	I "github.com/compozed/deployadactyl/interfaces"
+	S "github.com/compozed/deployadactyl/structs"
 )
 
 type HealthCheckerConstructor func(oldURL, newURL, silentDeployURL, silentDeployEnvironment string, client I.Client) HealthChecker
@@ -48,38 +51,81 @@ type HealthCheckRequest struct {
 	FoundationUrl       string
 	TempAppWithUUID     string
 	UUID                string
+
+	// FoundationName is used in logs in place of FoundationUrl when it is set.
+	FoundationName string
+
+	// AppsDomain is the domain of the foundation that apps are routed on. When it is set
+	// it is used instead of deriving the domain from FoundationUrl.
+	AppsDomain string
+
+	// SmokeTests are run after the health check endpoint, while the temporary route is still mapped.
+	// Their results are written to Response.
+	SmokeTests []S.SmokeTest
+	Response   io.Writer
 }
 
 func (h HealthChecker) HealthChecker(healthCheckRequest HealthCheckRequest) error {
-	var (
-		newFoundationURL string
-		domain           string
-	)
+	foundation := healthCheckRequest.foundation()
 
 	h.Courier = healthCheckRequest.Courier
 
-	healthCheckRequest.Logger.Log.Debugf("%s %s: starting health check", healthCheckRequest.UUID, healthCheckRequest.FoundationUrl)
+	healthCheckRequest.Logger.Log.Debugf("%s %s: starting health check", healthCheckRequest.UUID, foundation)
 
-	if healthCheckRequest.Environment != h.SilentDeployEnvironment {
-		newFoundationURL = strings.Replace(healthCheckRequest.FoundationUrl, h.OldURL, h.NewURL, 1)
-		domain = regexp.MustCompile(fmt.Sprintf("%s.*", h.NewURL)).FindString(newFoundationURL)
-	} else {
-		newFoundationURL = strings.Replace(healthCheckRequest.FoundationUrl, h.OldURL, h.SilentDeployURL, 1)
-		domain = regexp.MustCompile(fmt.Sprintf("%s.*", h.SilentDeployURL)).FindString(newFoundationURL)
-	}
+	domain, newFoundationURL := h.route(healthCheckRequest, healthCheckRequest.TempAppWithUUID)
 
-	err := h.mapTemporaryRoute(healthCheckRequest.TempAppWithUUID, domain, healthCheckRequest.Logger, healthCheckRequest.UUID, healthCheckRequest.FoundationUrl)
+	err := h.mapTemporaryRoute(healthCheckRequest.TempAppWithUUID, domain, healthCheckRequest.Logger, healthCheckRequest.UUID, foundation)
 	if err != nil {
 		return err
 	}
 
 	// unmapTemporaryRoute will be called before deleteTemporaryRoute
-	defer h.deleteTemporaryRoute(healthCheckRequest.TempAppWithUUID, domain, healthCheckRequest.Logger, healthCheckRequest.UUID, healthCheckRequest.FoundationUrl)
-	defer h.unmapTemporaryRoute(healthCheckRequest.TempAppWithUUID, domain, healthCheckRequest.Logger, healthCheckRequest.UUID, healthCheckRequest.FoundationUrl)
+	defer h.deleteTemporaryRoute(healthCheckRequest.TempAppWithUUID, domain, healthCheckRequest.Logger, healthCheckRequest.UUID, foundation)
+	defer h.unmapTemporaryRoute(healthCheckRequest.TempAppWithUUID, domain, healthCheckRequest.Logger, healthCheckRequest.UUID, foundation)
+
+	if healthCheckRequest.HealthCheckEndpoint != "" {
+		err = h.Check(newFoundationURL, healthCheckRequest.HealthCheckEndpoint, healthCheckRequest.Logger, healthCheckRequest.UUID, foundation)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(healthCheckRequest.SmokeTests) == 0 {
+		return nil
+	}
+
+	response := healthCheckRequest.Response
+	if response == nil {
+		response = ioutil.Discard
+	}
+
+	return h.SmokeTest(newFoundationURL, healthCheckRequest.SmokeTests, response, healthCheckRequest.Logger, healthCheckRequest.UUID, foundation)
+}
 
-	newFoundationURL = strings.Replace(newFoundationURL, h.NewURL, fmt.Sprintf("%s.%s", healthCheckRequest.TempAppWithUUID, h.NewURL), 1)
+// CheckRoute checks the health check endpoint of the request on a route of hostname that is already
+// mapped, such as the route of an application after it replaced the existing one.
+func (h HealthChecker) CheckRoute(healthCheckRequest HealthCheckRequest, hostname string) error {
+	_, url := h.route(healthCheckRequest, hostname)
 
-	return h.Check(newFoundationURL, healthCheckRequest.HealthCheckEndpoint, healthCheckRequest.Logger, healthCheckRequest.UUID, healthCheckRequest.FoundationUrl)
+	return h.Check(url, healthCheckRequest.HealthCheckEndpoint, healthCheckRequest.Logger, healthCheckRequest.UUID, healthCheckRequest.foundation())
+}
+
+// route returns the apps domain of the foundation and the URL of hostname on it.
+func (h HealthChecker) route(healthCheckRequest HealthCheckRequest, hostname string) (string, string) {
+	if healthCheckRequest.AppsDomain != "" {
+		domain := healthCheckRequest.AppsDomain
+		return domain, fmt.Sprintf("%s://%s.%s", schemeOf(healthCheckRequest.FoundationUrl), hostname, domain)
+	}
+
+	appsURL := h.NewURL
+	if healthCheckRequest.Environment == h.SilentDeployEnvironment {
+		appsURL = h.SilentDeployURL
+	}
+
+	foundationURL := strings.Replace(healthCheckRequest.FoundationUrl, h.OldURL, appsURL, 1)
+	domain := regexp.MustCompile(fmt.Sprintf("%s.*", appsURL)).FindString(foundationURL)
+
+	return domain, strings.Replace(foundationURL, h.NewURL, fmt.Sprintf("%s.%s", hostname, h.NewURL), 1)
 }
 
 // Check takes a url and endpoint. It does an http.Get to get the response
@@ -144,3 +190,14 @@ func (h HealthChecker) unmapTemporaryRoute(tempAppWithUUID, domain string, log I
 
 	log.Infof("%s %s: finished health check", uuid, foundationUrl)
 }
+
+func (r HealthCheckRequest) foundation() string {
+	return S.FoundationName(r.FoundationName, r.FoundationUrl)
+}
+
+func schemeOf(foundationURL string) string {
+	if u, err := url.Parse(foundationURL); err == nil && u.Scheme != "" {
+		return u.Scheme
+	}
+	return "https"
+}
@@ -0,0 +1,168 @@
+package healthchecker
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+var jsonPathSegment = regexp.MustCompile(`^([^\[\]]*)((?:\[\d+\])*)$`)
+
+// SmokeTest sends every smoke test to the application at url and writes a result line for each
+// one to the response. All of them are run even when one fails.
+//
+// Returns a SmokeTestError naming the smoke tests that failed.
+func (h HealthChecker) SmokeTest(url string, smokeTests []S.SmokeTest, response io.Writer, log I.DeploymentLogger, uuid, foundation string) error {
+	var failed []string
+
+	for _, smokeTest := range smokeTests {
+		target := fmt.Sprintf("%s/%s", url, strings.TrimPrefix(smokeTest.Path, "/"))
+
+		log.Debugf("%s %s: running smoke test %s: %s %s", uuid, foundation, smokeTest.Name, smokeTest.GetMethod(), target)
+
+		status, err := h.runSmokeTest(target, smokeTest)
+		if err != nil {
+			log.Errorf("%s %s: smoke test %s failed: %s", uuid, foundation, smokeTest.Name, err)
+			fmt.Fprintf(response, "%s: smoke test %s failed: %s %s: %s\n", foundation, smokeTest.Name, smokeTest.GetMethod(), smokeTest.Path, err)
+			failed = append(failed, smokeTest.Name)
+			continue
+		}
+
+		log.Infof("%s %s: smoke test %s passed", uuid, foundation, smokeTest.Name)
+		fmt.Fprintf(response, "%s: smoke test %s passed: %s %s returned %d\n", foundation, smokeTest.Name, smokeTest.GetMethod(), smokeTest.Path, status)
+	}
+
+	if len(failed) != 0 {
+		return SmokeTestError{Failed: failed, Total: len(smokeTests)}
+	}
+	return nil
+}
+
+func (h HealthChecker) runSmokeTest(url string, smokeTest S.SmokeTest) (int, error) {
+	request, err := http.NewRequest(smokeTest.GetMethod(), url, strings.NewReader(smokeTest.Body))
+	if err != nil {
+		return 0, err
+	}
+	for name, value := range smokeTest.Headers {
+		request.Header.Set(name, value)
+	}
+
+	resp, err := h.Client.Do(request)
+	if err != nil {
+		return 0, ClientError{err}
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, err
+	}
+
+	if !expectedStatus(resp.StatusCode, smokeTest.GetExpectedStatus()) {
+		return resp.StatusCode, fmt.Errorf("unexpected status code %d, expected %v", resp.StatusCode, smokeTest.GetExpectedStatus())
+	}
+
+	for _, assertion := range smokeTest.Assertions {
+		err = checkAssertion(assertion, body)
+		if err != nil {
+			return resp.StatusCode, err
+		}
+	}
+
+	return resp.StatusCode, nil
+}
+
+func expectedStatus(status int, expected []int) bool {
+	for _, e := range expected {
+		if status == e {
+			return true
+		}
+	}
+	return false
+}
+
+func checkAssertion(assertion S.SmokeTestAssertion, body []byte) error {
+	if assertion.JSONPath == "" {
+		if !regexp.MustCompile(assertion.Matches).Match(body) {
+			return fmt.Errorf("body does not match %q", assertion.Matches)
+		}
+		return nil
+	}
+
+	var document interface{}
+	err := json.Unmarshal(body, &document)
+	if err != nil {
+		return fmt.Errorf("body is not JSON: %s", err)
+	}
+
+	value, ok := lookupJSONPath(document, assertion.JSONPath)
+	if !ok {
+		return fmt.Errorf("%s not found in body", assertion.JSONPath)
+	}
+
+	if assertion.Equals != nil && fmt.Sprint(value) != fmt.Sprint(assertion.Equals) {
+		return fmt.Errorf("%s is %v, expected %v", assertion.JSONPath, value, assertion.Equals)
+	}
+
+	if assertion.Matches != "" && !regexp.MustCompile(assertion.Matches).MatchString(jsonString(value)) {
+		return fmt.Errorf("%s is %v, which does not match %q", assertion.JSONPath, value, assertion.Matches)
+	}
+
+	return nil
+}
+
+// lookupJSONPath returns the value at a path of object keys and array indexes such as $.items[0].id.
+func lookupJSONPath(document interface{}, path string) (interface{}, bool) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	if path == "" {
+		return document, true
+	}
+
+	value := document
+	for _, segment := range strings.Split(path, ".") {
+		parts := jsonPathSegment.FindStringSubmatch(segment)
+		if parts == nil {
+			return nil, false
+		}
+
+		if parts[1] != "" {
+			object, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			if value, ok = object[parts[1]]; !ok {
+				return nil, false
+			}
+		}
+
+		for _, index := range strings.FieldsFunc(parts[2], func(r rune) bool { return r == '[' || r == ']' }) {
+			array, ok := value.([]interface{})
+			if !ok {
+				return nil, false
+			}
+			i, _ := strconv.Atoi(index)
+			if i >= len(array) {
+				return nil, false
+			}
+			value = array[i]
+		}
+	}
+
+	return value, true
+}
+
+func jsonString(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	encoded, _ := json.Marshal(value)
+	return string(encoded)
+}
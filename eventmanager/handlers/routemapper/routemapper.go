@@ -36,6 +36,9 @@ type RouteMapperRequest struct {
 	Application     string
 	UUID            string
 	FoundationUrl   string
+
+	// ApplicationIndex is the position of the application in the manifest. Its custom routes are the ones mapped.
+	ApplicationIndex int
 }
 
 func (r RouteMapper) CustomRouteMapper(request RouteMapperRequest) error {
@@ -57,17 +60,44 @@ func (r RouteMapper) CustomRouteMapper(request RouteMapperRequest) error {
 		return err
 	}
 
-	if m.Applications == nil || len(m.Applications[0].CustomRoutes) == 0 {
+	if len(m.Applications) <= request.ApplicationIndex || len(m.Applications[request.ApplicationIndex].CustomRoutes) == 0 {
 		log.Infof("%s %s: finished mapping routes: no routes to map", request.UUID, request.FoundationUrl)
 		return nil
 	}
 
-	log.Infof("%s %s: found %d routes in the manifest", request.UUID, request.FoundationUrl, len(m.Applications[0].CustomRoutes))
+	customRoutes := m.Applications[request.ApplicationIndex].CustomRoutes
+
+	log.Infof("%s %s: found %d routes in the manifest", request.UUID, request.FoundationUrl, len(customRoutes))
 
 	domains, _ := r.Courier.Domains()
 
 	log.Debugf("%s %s: mapping routes to %s", request.UUID, request.FoundationUrl, request.TempAppWithUUID)
-	return r.routeMapper(m, request.TempAppWithUUID, domains, request.Application, request.Logger, request.UUID, request.FoundationUrl)
+	return r.routeMapper(customRoutes, request.TempAppWithUUID, domains, request.Application, request.Logger, request.UUID, request.FoundationUrl)
+}
+
+// SplitRoute splits a custom route into the hostname, domain and path it is mapped with, using the
+// domains of the foundation. A route that is a domain of the foundation is mapped with the name of the
+// application as its hostname. ok is false when the route is not on any of the domains.
+func SplitRoute(route string, domains []string, appName string) (hostname, domain, path string, ok bool) {
+	if isRouteADomainInTheFoundation(route, domains) {
+		return appName, route, "", true
+	}
+
+	appNameAndDomain := strings.SplitN(route, ".", 2)
+	if len(appNameAndDomain) < 2 {
+		return "", "", "", false
+	}
+
+	if isRouteADomainInTheFoundation(appNameAndDomain[1], domains) {
+		return appNameAndDomain[0], appNameAndDomain[1], "", true
+	}
+
+	domainAndPath := strings.SplitN(appNameAndDomain[1], "/", 2)
+	if len(domainAndPath) == 2 && isRouteADomainInTheFoundation(domainAndPath[0], domains) {
+		return appNameAndDomain[0], domainAndPath[0], domainAndPath[1], true
+	}
+
+	return "", "", "", false
 }
 
 func isRouteADomainInTheFoundation(route string, domains []string) bool {
@@ -105,36 +135,25 @@ func (r RouteMapper) readManifest(manifest, appPath string, log I.DeploymentLogg
 // if the route does not include appname or path it will map the given domain to the given application by default
 // if the route has an app name it will remove the app name so it can map it with the given domain
 // if the route has an app name and a path it will remove the app name so it can map it with the given domain and the path as well
-func (r RouteMapper) routeMapper(manifest *manifest, tempAppWithUUID string, domains []string, appName string, log I.DeploymentLogger, uuid, foundationUrl string) error {
-	for _, route := range manifest.Applications[0].CustomRoutes {
-		var domainAndPath []string
-
-		appNameAndDomain := strings.SplitN(route.Route, ".", 2)
-
-		if len(appNameAndDomain) >= 2 {
-			domainAndPath = strings.SplitN(appNameAndDomain[1], "/", 2)
+func (r RouteMapper) routeMapper(customRoutes []route, tempAppWithUUID string, domains []string, appName string, log I.DeploymentLogger, uuid, foundationUrl string) error {
+	for _, route := range customRoutes {
+		hostname, domain, path, ok := SplitRoute(route.Route, domains, appName)
+		if !ok {
+			return InvalidRouteError{route.Route}
 		}
 
-		if isRouteADomainInTheFoundation(route.Route, domains) {
-			output, err := r.Courier.MapRoute(tempAppWithUUID, route.Route, appName)
+		if path == "" {
+			output, err := r.Courier.MapRoute(tempAppWithUUID, domain, hostname)
 			if err != nil {
 				log.Errorf("failed to map route: %s: %s", route.Route, string(output))
 				return MapRouteError{route.Route, output}
 			}
-		} else if len(appNameAndDomain) >= 2 && isRouteADomainInTheFoundation(appNameAndDomain[1], domains) {
-			output, err := r.Courier.MapRoute(tempAppWithUUID, appNameAndDomain[1], appNameAndDomain[0])
-			if err != nil {
-				log.Errorf("failed to map route: %s: %s", route.Route, string(output))
-				return MapRouteError{route.Route, output}
-			}
-		} else if domainAndPath != nil && isRouteADomainInTheFoundation(domainAndPath[0], domains) {
-			output, err := r.Courier.MapRouteWithPath(tempAppWithUUID, domainAndPath[0], appNameAndDomain[0], domainAndPath[1])
+		} else {
+			output, err := r.Courier.MapRouteWithPath(tempAppWithUUID, domain, hostname, path)
 			if err != nil {
 				log.Error(MapRouteError{route.Route, output})
 				return MapRouteError{route.Route, output}
 			}
-		} else {
-			return InvalidRouteError{route.Route}
 		}
 
 		log.Infof("%s %s: mapped route %s to %s", uuid, foundationUrl, route.Route, tempAppWithUUID)
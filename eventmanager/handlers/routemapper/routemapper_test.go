@@ -291,6 +291,42 @@ applications:
 		})
 	})
 
+	Context("when the manifest has more than one application", func() {
+		BeforeEach(func() {
+			routeMapperRequest.Manifest = fmt.Sprintf(`
+---
+applications:
+- name: api
+  custom-routes:
+  - route: %s0.%s
+- name: worker
+  custom-routes:
+  - route: %s1.%s`, randomHostName, randomDomain, randomHostName, randomDomain)
+
+			courier.DomainsCall.Returns.Domains = []string{randomDomain}
+		})
+
+		It("maps the routes of the application at the index", func() {
+			routeMapperRequest.ApplicationIndex = 1
+
+			err := routemapper.CustomRouteMapper(routeMapperRequest)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(courier.MapRouteCall.Received.AppName).To(Equal([]string{randomTemporaryAppName}))
+			Expect(courier.MapRouteCall.Received.Hostname).To(Equal([]string{randomHostName + "1"}))
+		})
+
+		It("maps no routes when there is no application at the index", func() {
+			routeMapperRequest.ApplicationIndex = 2
+
+			err := routemapper.CustomRouteMapper(routeMapperRequest)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(courier.MapRouteCall.Received.AppName).To(BeEmpty())
+			Eventually(logBuffer).Should(Say("no routes to map"))
+		})
+	})
+
 	Context("when a bad yaml is provided", func() {
 		It("returns an unmarshall error", func() {
 			routes := []string{
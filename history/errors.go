@@ -0,0 +1,30 @@
+package history
+
+import "fmt"
+
+type ReadHistoryError struct {
+	Path string
+	Err  error
+}
+
+func (e ReadHistoryError) Error() string {
+	return fmt.Sprintf("cannot read the deployment history from %s: %s", e.Path, e.Err)
+}
+
+type WriteHistoryError struct {
+	Path string
+	Err  error
+}
+
+func (e WriteHistoryError) Error() string {
+	return fmt.Sprintf("cannot write the deployment history to %s: %s", e.Path, e.Err)
+}
+
+type ArtifactNotFoundError struct {
+	UUID string
+	Err  error
+}
+
+func (e ArtifactNotFoundError) Error() string {
+	return fmt.Sprintf("cannot read the artifact of deployment %s: %s", e.UUID, e.Err)
+}
@@ -0,0 +1,325 @@
+// Package history remembers recent deployments so that they can be retried.
+package history
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/spf13/afero"
+)
+
+// DefaultPath is the file the deployments are kept in when the config does not name one.
+const DefaultPath = "./history.json"
+
+// MaxRunning is how long a deployment is taken to be running after it started. A deployment whose
+// server stopped before it finished is not taken to be running forever.
+const MaxRunning = 24 * time.Hour
+
+// DefaultLimit is the number of deployments remembered by default. Records only reference their
+// artifact, so they are small, but the artifacts of zip and tar uploads are kept next to the file
+// until their deployment is forgotten.
+const DefaultLimit = 500
+
+type DeploymentHistoryConstructor func(fileSystem *afero.Afero, path string, limit int, log I.Logger) I.DeploymentHistory
+
+func NewDeploymentHistory(fileSystem *afero.Afero, path string, limit int, log I.Logger) I.DeploymentHistory {
+	if path == "" {
+		path = DefaultPath
+	}
+	if limit < 1 {
+		limit = DefaultLimit
+	}
+
+	return &DeploymentHistory{
+		FileSystem: fileSystem,
+		Path:       path,
+		Limit:      limit,
+		Log:        log,
+	}
+}
+
+// DeploymentHistory keeps the most recent deployments in a file, so that they survive a restart and
+// are seen by every server that shares the file. When it is full the oldest deployment is forgotten,
+// unless it is the last one of its application that succeeded.
+//
+// Records reference their artifact by URL. The artifacts of zip and tar uploads are kept in
+// <path>.artifacts, one file per deployment, and are deleted with the record.
+//
+// The deployments that are running are kept in the file too, so that the temporary applications of
+// a deployment run by another server that shares the file are left alone.
+type DeploymentHistory struct {
+	FileSystem *afero.Afero
+	Path       string
+	Limit      int
+	Log        I.Logger
+
+	lock sync.Mutex
+}
+
+// historyFile is the content of the file: the deployments, the oldest first, and the deployments
+// that are running by their UUID.
+type historyFile struct {
+	Records []I.DeploymentRecord `json:"records"`
+	Running map[string]running   `json:"running,omitempty"`
+}
+
+// running is how many times a deployment was started and not finished yet, and when it last started.
+type running struct {
+	Count int       `json:"count"`
+	Since time.Time `json:"since"`
+}
+
+// Save remembers a deployment by its UUID, replacing an earlier record with the same UUID. The
+// artifact of a zip or tar upload is kept with it; it is nil for deployments of an artifact URL.
+func (h *DeploymentHistory) Save(record I.DeploymentRecord, artifact []byte) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	file := h.read()
+
+	record.ArtifactFile = ""
+	if artifact != nil {
+		artifactFile := filepath.Join(h.Path+".artifacts", record.UUID)
+
+		err := h.FileSystem.MkdirAll(filepath.Dir(artifactFile), 0700)
+		if err == nil {
+			err = h.FileSystem.WriteFile(artifactFile, artifact, 0600)
+		}
+		if err != nil {
+			return WriteHistoryError{h.Path, err}
+		}
+		record.ArtifactFile = artifactFile
+	}
+
+	replaced := false
+	for i := range file.Records {
+		if file.Records[i].UUID == record.UUID {
+			file.Records[i] = record
+			replaced = true
+		}
+	}
+	if !replaced {
+		file.Records = append(file.Records, record)
+	}
+
+	file.Records = h.limit(file.Records)
+
+	return h.write(file)
+}
+
+// limit forgets the oldest deployments beyond the limit. The deployment that was just saved and the
+// last deployment of every application that succeeded are kept, so that it can still be promoted and
+// the deployments promoted from it keep their lineage, even if that leaves more than the limit.
+func (h *DeploymentHistory) limit(records []I.DeploymentRecord) []I.DeploymentRecord {
+	excess := len(records) - h.Limit
+	if excess <= 0 {
+		return records
+	}
+
+	lastSucceeded := map[I.CFContext]string{}
+	for _, record := range records {
+		if record.Succeeded {
+			lastSucceeded[application(record.CFContext)] = record.UUID
+		}
+	}
+
+	kept := make([]I.DeploymentRecord, 0, len(records))
+	for i, record := range records {
+		if excess > 0 && i < len(records)-1 && lastSucceeded[application(record.CFContext)] != record.UUID {
+			h.forget(record)
+			excess--
+			continue
+		}
+		kept = append(kept, record)
+	}
+	return kept
+}
+
+// application returns the context without the fields that do not name the application.
+func application(cfContext I.CFContext) I.CFContext {
+	return I.CFContext{
+		Environment:  cfContext.Environment,
+		Organization: cfContext.Organization,
+		Space:        cfContext.Space,
+		Application:  cfContext.Application,
+	}
+}
+
+// forget deletes the artifact kept for a deployment that is no longer remembered.
+func (h *DeploymentHistory) forget(record I.DeploymentRecord) {
+	if record.ArtifactFile == "" {
+		return
+	}
+
+	err := h.FileSystem.Remove(record.ArtifactFile)
+	if err != nil && !os.IsNotExist(err) {
+		h.Log.Errorf("cannot delete the artifact of deployment %s: %s", record.UUID, err)
+	}
+}
+
+// Artifact returns the artifact of a zip or tar upload kept with the record, or nil when the
+// deployment referenced its artifact by URL.
+func (h *DeploymentHistory) Artifact(record I.DeploymentRecord) ([]byte, error) {
+	if record.ArtifactFile == "" {
+		return nil, nil
+	}
+
+	artifact, err := h.FileSystem.ReadFile(record.ArtifactFile)
+	if err != nil {
+		return nil, ArtifactNotFoundError{record.UUID, err}
+	}
+	return artifact, nil
+}
+
+// LastSucceeded returns the most recent deployment to the environment, org, space and application
+// of the context that succeeded, if one is still remembered.
+func (h *DeploymentHistory) LastSucceeded(cfContext I.CFContext) (I.DeploymentRecord, bool) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	records := h.read().Records
+	for i := len(records) - 1; i >= 0; i-- {
+		if records[i].Succeeded && sameApplication(records[i].CFContext, cfContext) {
+			return records[i], true
+		}
+	}
+	return I.DeploymentRecord{}, false
+}
+
+func sameApplication(a, b I.CFContext) bool {
+	return a.Environment == b.Environment && a.Organization == b.Organization &&
+		a.Space == b.Space && a.Application == b.Application
+}
+
+// Get returns the deployment with the given UUID, if it is still remembered.
+func (h *DeploymentHistory) Get(uuid string) (I.DeploymentRecord, bool) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	for _, record := range h.read().Records {
+		if record.UUID == uuid {
+			return record, true
+		}
+	}
+	return I.DeploymentRecord{}, false
+}
+
+// Started marks the deployment with the given UUID as running until Finished is called for it.
+func (h *DeploymentHistory) Started(uuid string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	file := h.read()
+	if file.Running == nil {
+		file.Running = map[string]running{}
+	}
+
+	started := file.Running[uuid]
+	started.Count++
+	started.Since = time.Now()
+	file.Running[uuid] = started
+
+	h.writeRunning(file)
+}
+
+// Finished marks the deployment with the given UUID as no longer running.
+func (h *DeploymentHistory) Finished(uuid string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	file := h.read()
+
+	started, ok := file.Running[uuid]
+	if !ok {
+		return
+	}
+	if started.Count <= 1 {
+		delete(file.Running, uuid)
+	} else {
+		started.Count--
+		file.Running[uuid] = started
+	}
+
+	h.writeRunning(file)
+}
+
+// Active returns true while the deployment with the given UUID is running, on this server or on
+// another one that shares the file, for at most MaxRunning. Running deployments are not limited
+// like the remembered ones.
+func (h *DeploymentHistory) Active(uuid string) bool {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	started, ok := h.read().Running[uuid]
+	return ok && started.Count > 0 && time.Since(started.Since) < MaxRunning
+}
+
+// writeRunning writes the file after the running deployments changed, forgetting the ones that
+// started more than MaxRunning ago. A deployment runs whether or not it can be marked as running,
+// so the error is only logged. It must be called with the lock held.
+func (h *DeploymentHistory) writeRunning(file historyFile) {
+	for uuid, started := range file.Running {
+		if time.Since(started.Since) >= MaxRunning {
+			delete(file.Running, uuid)
+		}
+	}
+
+	err := h.write(file)
+	if err != nil {
+		h.Log.Error(err)
+	}
+}
+
+// read returns the content of the file. The file is read on every call, so that the deployments
+// saved by other servers are seen. A file that cannot be read is logged and treated as empty; when
+// it is not valid JSON it is moved aside to <path>.invalid. It must be called with the lock held.
+func (h *DeploymentHistory) read() historyFile {
+	var file historyFile
+
+	content, err := h.FileSystem.ReadFile(h.Path)
+	if os.IsNotExist(err) {
+		return file
+	}
+	if err != nil {
+		h.Log.Error(ReadHistoryError{h.Path, err})
+		return file
+	}
+
+	err = json.Unmarshal(content, &file)
+	if err != nil {
+		h.Log.Error(ReadHistoryError{h.Path, err})
+		if err = h.FileSystem.Rename(h.Path, h.Path+".invalid"); err != nil {
+			h.Log.Error(err)
+		} else {
+			h.Log.Errorf("moved %s to %s.invalid", h.Path, h.Path)
+		}
+		return historyFile{}
+	}
+	return file
+}
+
+// write writes the content to a temporary file and moves it over the file, so that the file is
+// never left half written. It must be called with the lock held.
+func (h *DeploymentHistory) write(file historyFile) error {
+	content, err := json.Marshal(file)
+	if err != nil {
+		return WriteHistoryError{h.Path, err}
+	}
+
+	temp := h.Path + ".tmp"
+	err = h.FileSystem.WriteFile(temp, content, os.FileMode(0600))
+	if err != nil {
+		return WriteHistoryError{h.Path, err}
+	}
+
+	err = h.FileSystem.Rename(temp, h.Path)
+	if err != nil {
+		h.FileSystem.Remove(temp)
+		return WriteHistoryError{h.Path, err}
+	}
+	return nil
+}
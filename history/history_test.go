@@ -0,0 +1,204 @@
+package history_test
+
+import (
+	"encoding/base64"
+	"time"
+
+	. "github.com/compozed/deployadactyl/history"
+	I "github.com/compozed/deployadactyl/interfaces"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	"github.com/op/go-logging"
+	"github.com/spf13/afero"
+)
+
+var _ = Describe("DeploymentHistory", func() {
+	var (
+		logBuffer  *Buffer
+		log        I.Logger
+		fileSystem *afero.Afero
+	)
+
+	newHistory := func(limit int) I.DeploymentHistory {
+		return NewDeploymentHistory(fileSystem, "/history.json", limit, log)
+	}
+
+	BeforeEach(func() {
+		logBuffer = NewBuffer()
+		log = I.DefaultLogger(logBuffer, logging.DEBUG, "history_test")
+		fileSystem = &afero.Afero{Fs: afero.NewMemMapFs()}
+	})
+
+	It("returns a saved deployment by its uuid", func() {
+		history := newHistory(2)
+		record := I.DeploymentRecord{UUID: "abc", ArtifactURL: "https://example.com/app.zip", FailedFoundations: []string{"https://api.east.example.com"}}
+
+		Expect(history.Save(record, nil)).To(Succeed())
+
+		saved, ok := history.Get("abc")
+		Expect(ok).To(BeTrue())
+		Expect(saved).To(Equal(record))
+	})
+
+	It("does not return unknown deployments", func() {
+		_, ok := newHistory(2).Get("abc")
+
+		Expect(ok).To(BeFalse())
+	})
+
+	It("remembers the deployments across restarts", func() {
+		record := I.DeploymentRecord{UUID: "abc", ArtifactURL: "https://example.com/app.zip", Succeeded: true}
+		Expect(newHistory(2).Save(record, nil)).To(Succeed())
+
+		saved, ok := newHistory(2).Get("abc")
+		Expect(ok).To(BeTrue())
+		Expect(saved).To(Equal(record))
+
+		exists, _ := fileSystem.Exists("/history.json.tmp")
+		Expect(exists).To(BeFalse())
+	})
+
+	It("keeps the artifact of an upload next to the file instead of in the record", func() {
+		history := newHistory(2)
+
+		Expect(history.Save(I.DeploymentRecord{UUID: "abc", ContentType: "application/zip"}, []byte("artifact bytes"))).To(Succeed())
+
+		saved, _ := history.Get("abc")
+		Expect(saved.ArtifactFile).To(Equal("/history.json.artifacts/abc"))
+		Expect(history.Artifact(saved)).To(Equal([]byte("artifact bytes")))
+
+		content, _ := fileSystem.ReadFile("/history.json")
+		Expect(string(content)).ToNot(ContainSubstring("artifact bytes"))
+		Expect(string(content)).ToNot(ContainSubstring(base64.StdEncoding.EncodeToString([]byte("artifact bytes"))))
+	})
+
+	It("returns no artifact for a deployment of an artifact URL", func() {
+		Expect(newHistory(2).Artifact(I.DeploymentRecord{UUID: "abc", ArtifactURL: "https://example.com/app.zip"})).To(BeNil())
+	})
+
+	It("returns an error when the artifact is gone", func() {
+		_, err := newHistory(2).Artifact(I.DeploymentRecord{UUID: "abc", ArtifactFile: "/history.json.artifacts/abc"})
+
+		Expect(err).To(BeAssignableToTypeOf(ArtifactNotFoundError{}))
+	})
+
+	It("forgets the oldest deployment and its artifact when it is full", func() {
+		history := newHistory(2)
+
+		history.Save(I.DeploymentRecord{UUID: "one"}, []byte("one"))
+		history.Save(I.DeploymentRecord{UUID: "two"}, nil)
+		history.Save(I.DeploymentRecord{UUID: "one", Manifest: "updated"}, []byte("one"))
+		history.Save(I.DeploymentRecord{UUID: "three"}, nil)
+
+		_, ok := history.Get("one")
+		Expect(ok).To(BeFalse())
+		two, _ := history.Get("two")
+		Expect(two.UUID).To(Equal("two"))
+		_, ok = history.Get("three")
+		Expect(ok).To(BeTrue())
+
+		exists, _ := fileSystem.Exists("/history.json.artifacts/one")
+		Expect(exists).To(BeFalse())
+	})
+
+	It("keeps the last deployment of every application that succeeded when it is full", func() {
+		history := newHistory(2)
+		staging := I.CFContext{Environment: "staging", Organization: "org", Space: "space", Application: "app"}
+		prod := I.CFContext{Environment: "prod", Organization: "org", Space: "space", Application: "app"}
+
+		history.Save(I.DeploymentRecord{UUID: "one", CFContext: staging, Succeeded: true}, nil)
+		history.Save(I.DeploymentRecord{UUID: "two", CFContext: staging}, nil)
+		history.Save(I.DeploymentRecord{UUID: "three", CFContext: prod, Succeeded: true, PromotedFrom: "one"}, nil)
+		history.Save(I.DeploymentRecord{UUID: "four", CFContext: staging}, nil)
+
+		_, ok := history.Get("two")
+		Expect(ok).To(BeFalse())
+		_, ok = history.Get("four")
+		Expect(ok).To(BeTrue())
+
+		record, ok := history.LastSucceeded(staging)
+		Expect(ok).To(BeTrue())
+		Expect(record.UUID).To(Equal("one"))
+		record, ok = history.LastSucceeded(prod)
+		Expect(ok).To(BeTrue())
+		Expect(record.PromotedFrom).To(Equal("one"))
+	})
+
+	It("moves a file that is not valid JSON aside and starts over", func() {
+		fileSystem.WriteFile("/history.json", []byte("{not json"), 0600)
+		history := newHistory(2)
+
+		_, ok := history.Get("abc")
+		Expect(ok).To(BeFalse())
+		Expect(logBuffer).To(Say("cannot read the deployment history from /history.json"))
+
+		content, _ := fileSystem.ReadFile("/history.json.invalid")
+		Expect(string(content)).To(Equal("{not json"))
+
+		Expect(history.Save(I.DeploymentRecord{UUID: "abc"}, nil)).To(Succeed())
+		_, ok = history.Get("abc")
+		Expect(ok).To(BeTrue())
+	})
+
+	It("returns the last deployment of an application that succeeded", func() {
+		history := newHistory(4)
+		staging := I.CFContext{Environment: "staging", Organization: "org", Space: "space", Application: "app"}
+
+		history.Save(I.DeploymentRecord{UUID: "one", CFContext: staging, Succeeded: true}, nil)
+		history.Save(I.DeploymentRecord{UUID: "two", CFContext: staging, Succeeded: true}, nil)
+		history.Save(I.DeploymentRecord{UUID: "three", CFContext: staging}, nil)
+		history.Save(I.DeploymentRecord{UUID: "four", CFContext: I.CFContext{Environment: "prod", Organization: "org", Space: "space", Application: "app"}, Succeeded: true}, nil)
+
+		record, ok := history.LastSucceeded(staging)
+		Expect(ok).To(BeTrue())
+		Expect(record.UUID).To(Equal("two"))
+	})
+
+	It("does not return a deployment when none of the application succeeded", func() {
+		history := newHistory(2)
+		staging := I.CFContext{Environment: "staging", Organization: "org", Space: "space", Application: "app"}
+
+		history.Save(I.DeploymentRecord{UUID: "one", CFContext: staging}, nil)
+
+		_, ok := history.LastSucceeded(staging)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("knows which deployments are running", func() {
+		history := newHistory(2)
+
+		history.Started("one")
+		history.Started("two")
+		history.Finished("two")
+
+		Expect(history.Active("one")).To(BeTrue())
+		Expect(history.Active("two")).To(BeFalse())
+		Expect(history.Active("three")).To(BeFalse())
+	})
+
+	It("knows which deployments are running on another server that shares the file", func() {
+		newHistory(2).Started("one")
+
+		Expect(newHistory(2).Active("one")).To(BeTrue())
+	})
+
+	It("keeps the deployments it remembers when one starts and finishes", func() {
+		history := newHistory(2)
+		history.Save(I.DeploymentRecord{UUID: "one"}, nil)
+
+		history.Started("two")
+		history.Finished("two")
+
+		_, ok := history.Get("one")
+		Expect(ok).To(BeTrue())
+	})
+
+	It("does not take a deployment that started too long ago to be running", func() {
+		since := time.Now().Add(-MaxRunning - time.Minute).Format(time.RFC3339)
+		fileSystem.WriteFile("/history.json", []byte(`{"records": [], "running": {"one": {"count": 1, "since": "`+since+`"}}}`), 0600)
+
+		Expect(newHistory(2).Active("one")).To(BeFalse())
+	})
+})
@@ -0,0 +1,38 @@
+package hooks
+
+import (
+	"fmt"
+	"time"
+)
+
+// HookError is returned when a hook fails. It aborts the push.
+type HookError struct {
+	Hook string
+	When string
+	Err  error
+}
+
+func (e HookError) Error() string {
+	return fmt.Sprintf("%s hook %s failed: %s", e.When, e.Hook, e.Err)
+}
+
+type UnexpectedStatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e UnexpectedStatusError) Error() string {
+	return fmt.Sprintf("%s responded with status code %d", e.URL, e.StatusCode)
+}
+
+type TimeoutError struct {
+	After time.Duration
+}
+
+func (e TimeoutError) Error() string {
+	return fmt.Sprintf("timed out after %s", e.After)
+}
+
+func (e TimeoutError) Timeout() bool {
+	return true
+}
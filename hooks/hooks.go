@@ -0,0 +1,140 @@
+// Package hooks runs the hooks of an environment at defined points of a push.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"time"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+type HookRunnerConstructor func(client *http.Client) I.HookRunner
+
+func NewHookRunner(client *http.Client) I.HookRunner {
+	return &HookRunner{
+		Client: client,
+	}
+}
+
+// HookRunner runs Cloud Foundry task, HTTP and local command hooks.
+type HookRunner struct {
+	Client *http.Client
+}
+
+// Run runs the hook and writes its output to the response.
+//
+// Returns a HookError when the hook fails.
+func (r HookRunner) Run(hook S.Hook, hookContext S.HookContext, courier I.Courier, response io.Writer) error {
+	fmt.Fprintf(response, "running %s hook %s\n", hook.When, hook.Name)
+
+	var err error
+	switch {
+	case hook.Task != "":
+		err = r.runTask(hook, hookContext, courier, response)
+	case hook.URL != "":
+		err = r.call(hook, hookContext, response)
+	case len(hook.Command) != 0:
+		err = r.runCommand(hook, hookContext, response)
+	default:
+		err = fmt.Errorf("nothing to run")
+	}
+
+	if err != nil {
+		return HookError{Hook: hook.Name, When: hook.When, Err: err}
+	}
+	return nil
+}
+
+func (r HookRunner) runTask(hook S.Hook, hookContext S.HookContext, courier I.Courier, response io.Writer) error {
+	output, err := courier.RunTask(hookContext.Application, hook.Task, hook.Name+"-"+hookContext.UUID)
+	response.Write(output)
+	return err
+}
+
+func (r HookRunner) call(hook S.Hook, hookContext S.HookContext, response io.Writer) error {
+	body, err := json.Marshal(hookContext)
+	if err != nil {
+		return err
+	}
+
+	timeout, err := hook.GetTimeout()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	request, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(request.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	io.Copy(response, resp.Body)
+	fmt.Fprintln(response)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return UnexpectedStatusError{URL: hook.URL, StatusCode: resp.StatusCode}
+	}
+	return nil
+}
+
+// runCommand runs the command in its own process group and kills the whole group when the timeout
+// of the hook expires, so that the processes the command started are killed as well.
+func (r HookRunner) runCommand(hook S.Hook, hookContext S.HookContext, response io.Writer) error {
+	body, err := json.Marshal(hookContext)
+	if err != nil {
+		return err
+	}
+
+	timeout, err := hook.GetTimeout()
+	if err != nil {
+		return err
+	}
+
+	output := &bytes.Buffer{}
+	command := exec.Command(hook.Command[0], hook.Command[1:]...)
+	command.Stdin = bytes.NewReader(body)
+	command.Stdout = output
+	command.Stderr = output
+	setProcessGroup(command)
+
+	if err = command.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- command.Wait() }()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case err = <-done:
+	case <-timer.C:
+		killProcessGroup(command)
+		<-done
+		err = TimeoutError{After: timeout}
+	}
+
+	response.Write(output.Bytes())
+	return err
+}
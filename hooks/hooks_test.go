@@ -0,0 +1,151 @@
+package hooks_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/compozed/deployadactyl/hooks"
+	"github.com/compozed/deployadactyl/mocks"
+	S "github.com/compozed/deployadactyl/structs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("HookRunner", func() {
+	var (
+		runner      HookRunner
+		courier     *mocks.Courier
+		response    *Buffer
+		hookContext S.HookContext
+	)
+
+	BeforeEach(func() {
+		runner = HookRunner{Client: &http.Client{}}
+		courier = &mocks.Courier{}
+		response = NewBuffer()
+		hookContext = S.HookContext{
+			Hook:        "hook",
+			When:        S.HookBeforeCutover,
+			AppName:     "my-app",
+			Application: "my-app-new-build-1234",
+			UUID:        "1234",
+		}
+	})
+
+	Context("when the hook is a task", func() {
+		It("runs the task against the application of the context", func() {
+			courier.RunTaskCall.Returns.Output = []byte("task output")
+			hook := S.Hook{Name: "migrate", When: S.HookBeforeCutover, Task: "rake db:migrate"}
+
+			Expect(runner.Run(hook, hookContext, courier, response)).To(Succeed())
+
+			Expect(courier.RunTaskCall.Received.AppName).To(Equal([]string{"my-app-new-build-1234"}))
+			Expect(courier.RunTaskCall.Received.Command).To(Equal([]string{"rake db:migrate"}))
+			Expect(courier.RunTaskCall.Received.Name).To(Equal([]string{"migrate-1234"}))
+			Eventually(response).Should(Say("task output"))
+		})
+
+		It("returns a HookError when the task fails", func() {
+			courier.RunTaskCall.Returns.Error = errors.New("task failed")
+			hook := S.Hook{Name: "migrate", When: S.HookBeforeCutover, Task: "rake db:migrate"}
+
+			err := runner.Run(hook, hookContext, courier, response)
+
+			Expect(err).To(MatchError(HookError{Hook: "migrate", When: S.HookBeforeCutover, Err: errors.New("task failed")}))
+		})
+	})
+
+	Context("when the hook is an HTTP call", func() {
+		var (
+			server     *httptest.Server
+			received   S.HookContext
+			statusCode int
+		)
+
+		BeforeEach(func() {
+			received = S.HookContext{}
+			statusCode = http.StatusOK
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := ioutil.ReadAll(r.Body)
+				json.Unmarshal(body, &received)
+				w.WriteHeader(statusCode)
+				w.Write([]byte("warmed up"))
+			}))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("posts the context to the URL", func() {
+			hook := S.Hook{Name: "warmup", When: S.HookBeforeCutover, URL: server.URL}
+
+			Expect(runner.Run(hook, hookContext, courier, response)).To(Succeed())
+
+			Expect(received).To(Equal(hookContext))
+			Eventually(response).Should(Say("warmed up"))
+		})
+
+		It("returns a HookError when the response is not successful", func() {
+			statusCode = http.StatusInternalServerError
+			hook := S.Hook{Name: "warmup", When: S.HookBeforeCutover, URL: server.URL}
+
+			err := runner.Run(hook, hookContext, courier, response)
+
+			Expect(err).To(MatchError(HookError{Hook: "warmup", When: S.HookBeforeCutover, Err: UnexpectedStatusError{URL: server.URL, StatusCode: 500}}))
+		})
+
+		It("returns a HookError without calling the URL when the timeout is invalid", func() {
+			hook := S.Hook{Name: "warmup", When: S.HookBeforeCutover, URL: server.URL, Timeout: "soon"}
+
+			err := runner.Run(hook, hookContext, courier, response)
+
+			Expect(err).To(BeAssignableToTypeOf(HookError{}))
+			Expect(err.Error()).To(ContainSubstring(`invalid duration "soon"`))
+			Expect(received).To(Equal(S.HookContext{}))
+		})
+	})
+
+	Context("when the hook is a local command", func() {
+		It("passes the context on standard input", func() {
+			hook := S.Hook{Name: "echo", When: S.HookAfterSuccess, Command: []string{"cat"}}
+
+			Expect(runner.Run(hook, hookContext, courier, response)).To(Succeed())
+
+			Eventually(response).Should(Say(`"application":"my-app-new-build-1234"`))
+		})
+
+		It("returns a HookError when the command fails", func() {
+			hook := S.Hook{Name: "fail", When: S.HookAfterSuccess, Command: []string{"false"}}
+
+			err := runner.Run(hook, hookContext, courier, response)
+
+			Expect(err).To(BeAssignableToTypeOf(HookError{}))
+			Expect(err.Error()).To(ContainSubstring("after_success hook fail failed"))
+		})
+
+		It("returns a HookError when the command times out", func() {
+			hook := S.Hook{Name: "slow", When: S.HookAfterSuccess, Command: []string{"sleep", "5"}, Timeout: "10ms"}
+
+			err := runner.Run(hook, hookContext, courier, response)
+
+			Expect(err).To(MatchError(HookError{Hook: "slow", When: S.HookAfterSuccess, Err: TimeoutError{After: 10 * time.Millisecond}}))
+		})
+
+		It("kills the processes the command started when it times out", func() {
+			hook := S.Hook{Name: "slow", When: S.HookAfterSuccess, Command: []string{"sh", "-c", "sleep 5 & echo started; wait"}, Timeout: "100ms"}
+
+			started := time.Now()
+			err := runner.Run(hook, hookContext, courier, response)
+
+			Expect(err).To(MatchError(HookError{Hook: "slow", When: S.HookAfterSuccess, Err: TimeoutError{After: 100 * time.Millisecond}}))
+			Expect(time.Since(started)).To(BeNumerically("<", 2*time.Second))
+			Eventually(response).Should(Say("started"))
+		})
+	})
+})
@@ -2,6 +2,7 @@ package interfaces
 
 import (
 	"io"
+	"time"
 
 	S "github.com/compozed/deployadactyl/structs"
 )
@@ -16,6 +17,27 @@ type Action interface {
 	Finally() error
 }
 
+// HealthCheckedAction is an Action that can check the health of what it deployed.
+// Strategies that wait between foundations health check the deployed foundations while they wait.
+type HealthCheckedAction interface {
+	HealthCheck() error
+}
+
+// BakedAction is an Action that can be watched for a while after Success and rolled back to what it
+// replaced. It keeps what it replaced until FinishBake when the environment bakes.
+type BakedAction interface {
+	// Bake returns an error when what was deployed is unhealthy. since is when the bake started.
+	Bake(since time.Time) error
+	RollBack() error
+	FinishBake() error
+}
+
+// Interruptible kills the Cloud Foundry commands it is running, so that a phase that ran out of time
+// stops rather than keeps running in the background. Actions, couriers and executors can be Interruptible.
+type Interruptible interface {
+	Interrupt()
+}
+
 type ActionCreator interface {
 	SetUp() error
 	CleanUp()
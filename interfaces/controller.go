@@ -1,6 +1,8 @@
 package interfaces
 
 import (
+	"io"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -9,6 +11,10 @@ type Deployment struct {
 	Type          string
 	Authorization Authorization
 	CFContext     CFContext
+
+	// Output receives the Cloud Foundry output of the deployment as it arrives, when the client
+	// streams the response.
+	Output io.Writer
 }
 
 type Authorization struct {
@@ -29,5 +35,13 @@ type Controller interface {
 
 	PutRequestHandler(g *gin.Context)
 
+	PromoteRequestHandler(g *gin.Context)
+
 	DeleteRequestHandler(g *gin.Context)
+
+	GetScheduledHandler(g *gin.Context)
+
+	DeleteScheduledHandler(g *gin.Context)
+
+	JanitorHandler(g *gin.Context)
 }
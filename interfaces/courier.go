@@ -1,5 +1,11 @@
 package interfaces
 
+import (
+	"io"
+
+	S "github.com/compozed/deployadactyl/structs"
+)
+
 type CourierCreator interface {
 	CreateCourier() (Courier, error)
 }
@@ -9,24 +15,40 @@ type Courier interface {
 	Login(foundationURL, username, password, org, space string, skipSSL bool) ([]byte, error)
 	Delete(appName string) ([]byte, error)
 	Push(appName, appLocation, hostname string, instances uint16) ([]byte, error)
+	PushWithStrategy(appName, appLocation, hostname string, instances uint16, strategy string) ([]byte, error)
+	PushWithoutStart(appName, appLocation, hostname string, instances uint16) ([]byte, error)
 	Rename(oldName, newName string) ([]byte, error)
 	MapRoute(appName, domain, hostname string) ([]byte, error)
 	MapRouteWithPath(appName, domain, hostname, path string) ([]byte, error)
 	UnmapRoute(appName, domain, hostname string) ([]byte, error)
 	UnmapRouteWithPath(appName, domain, hostname, path string) ([]byte, error)
 	DeleteRoute(domain, hostname string) ([]byte, error)
+	DeleteRouteWithPath(domain, hostname, path string) ([]byte, error)
 	CreateService(service, plan, name string) ([]byte, error)
+	CreateServiceWithParams(service, plan, name, params string) ([]byte, error)
+	ServiceStatus(serviceName string) (string, error)
 	BindService(appName, serviceName string) ([]byte, error)
 	UnbindService(appName, serviceName string) ([]byte, error)
 	DeleteService(serviceName string) ([]byte, error)
 	Start(appName string) ([]byte, error)
 	Stop(appName string) ([]byte, error)
 	Restage(appName string) ([]byte, error)
+	RunTask(appName, command, name string) ([]byte, error)
 	Logs(appName string) ([]byte, error)
 	Exists(appName string) bool
-	Cups(appName string, body string) ([]byte, error)
-	Uups(appName string, body string) ([]byte, error)
+	Cups(serviceName string, credentials string, syslogDrainURL string, routeServiceURL string) ([]byte, error)
+	Uups(serviceName string, credentials string, syslogDrainURL string, routeServiceURL string) ([]byte, error)
+	UserProvidedService(serviceName string) (S.UserProvidedService, error)
 	Domains() ([]string, error)
+	AppInstances(appName string) ([]S.AppInstance, error)
 	CleanUp() error
 	Services() ([]string, error)
+	Curl(path string) ([]byte, error)
+	OAuthToken() (string, error)
+}
+
+// StreamingCourier is a Courier that can write the output of its commands line by line as it arrives,
+// rather than only return it once they are done.
+type StreamingCourier interface {
+	Streaming(output io.Writer) Courier
 }
@@ -0,0 +1,59 @@
+package interfaces
+
+import S "github.com/compozed/deployadactyl/structs"
+
+// DeploymentRecord is what is remembered about a push so that it can be run again.
+type DeploymentRecord struct {
+	UUID        string
+	CFContext   CFContext
+	ContentType string
+	ArtifactURL string
+
+	// ArtifactFile is where the history keeps the artifact of a zip or tar upload.
+	ArtifactFile string
+
+	Manifest string
+
+	// EnvironmentVariables are kept with their values, including the secret ones, so the history file
+	// holds those secrets and has to be protected like the config.
+	EnvironmentVariables map[string]string
+
+	// SecretEnvironmentVariables are the names of the environment variables whose values are masked.
+	SecretEnvironmentVariables []string
+
+	HealthCheckEndpoint  string
+	SmokeTests           []S.SmokeTest
+	ServiceInstances     []S.ServiceInstance
+	UserProvidedServices []S.UserProvidedService
+	Data                 map[string]interface{}
+
+	// Foundations are the API URLs of the foundations the push ran against.
+	Foundations []string
+
+	// FailedFoundations are the API URLs of the foundations the push did not succeed on.
+	FailedFoundations []string
+
+	// Succeeded is true when the push succeeded on every foundation it ran against.
+	Succeeded bool
+
+	// PromotedFrom is the UUID of the deployment the push was promoted from.
+	PromotedFrom string
+}
+
+type DeploymentHistory interface {
+	// Save remembers the deployment along with the artifact of a zip or tar upload, which is nil
+	// for deployments of an artifact URL.
+	Save(record DeploymentRecord, artifact []byte) error
+	Get(uuid string) (DeploymentRecord, bool)
+
+	// Artifact returns the artifact that was saved with the deployment.
+	Artifact(record DeploymentRecord) ([]byte, error)
+
+	// LastSucceeded returns the most recent deployment of the application that succeeded.
+	LastSucceeded(cfContext CFContext) (DeploymentRecord, bool)
+
+	// Started and Finished mark a deployment as running, so that its temporary applications are left alone.
+	Started(uuid string)
+	Finished(uuid string)
+	Active(uuid string) bool
+}
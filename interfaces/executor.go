@@ -1,8 +1,12 @@
 package interfaces
 
+import "io"
+
 // Executor interface.
 type Executor interface {
 	Execute(args ...string) ([]byte, error)
 	ExecuteInDirectory(directory string, args ...string) ([]byte, error)
+	ExecuteWithEnvironment(environment map[string]string, args ...string) ([]byte, error)
+	ExecuteStreaming(output io.Writer, directory string, args ...string) ([]byte, error)
 	CleanUp() error
 }
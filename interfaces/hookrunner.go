@@ -0,0 +1,12 @@
+package interfaces
+
+import (
+	"io"
+
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+// HookRunner runs the hooks of an environment against a single foundation.
+type HookRunner interface {
+	Run(hook S.Hook, context S.HookContext, courier Courier, response io.Writer) error
+}
@@ -0,0 +1,39 @@
+package interfaces
+
+// OrphanedApplication is a temporary application that a push left behind on a foundation.
+type OrphanedApplication struct {
+	Foundation string `json:"foundation"`
+	Org        string `json:"org"`
+	Space      string `json:"space"`
+	Name       string `json:"name"`
+
+	// UUID is the UUID of the deployment that pushed the application.
+	UUID string `json:"uuid"`
+
+	// Routes are the routes that were mapped only to the application, as hostname.domain/path.
+	Routes []string `json:"routes,omitempty"`
+
+	// Reason is why the application was skipped or could not be deleted.
+	Reason string `json:"reason,omitempty"`
+}
+
+// JanitorReport is the result of sweeping the spaces of an environment.
+type JanitorReport struct {
+	Environment string                `json:"environment"`
+	Deleted     []OrphanedApplication `json:"deleted"`
+	Skipped     []OrphanedApplication `json:"skipped"`
+	Failed      []OrphanedApplication `json:"failed"`
+
+	// Errors are the spaces of foundations that could not be swept.
+	Errors []string `json:"errors"`
+}
+
+// Janitor deletes the temporary applications and routes that interrupted pushes left behind.
+type Janitor interface {
+	// Start sweeps the environments that have an interval periodically.
+	Start()
+
+	// Sweep deletes the temporary applications of the environment that no running deployment
+	// belongs to. The configured user is used when the authorization is empty.
+	Sweep(environment string, authorization Authorization) (JanitorReport, error)
+}
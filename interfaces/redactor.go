@@ -0,0 +1,14 @@
+package interfaces
+
+// Redactor masks known secrets, such as passwords and service credentials, in the output and logs
+// of deployments.
+type Redactor interface {
+	// Add makes the secrets known to the redactor.
+	Add(secrets ...string)
+
+	// Redact returns the text with every known secret masked.
+	Redact(text string) string
+
+	// With returns a new Redactor that also knows the given secrets, such as the ones of a request.
+	With(secrets ...string) Redactor
+}
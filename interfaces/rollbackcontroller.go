@@ -0,0 +1,9 @@
+package interfaces
+
+import (
+	"github.com/compozed/deployadactyl/structs"
+)
+
+type RollbackManagerFactory interface {
+	RollbackManager(deployEventData structs.DeployEventData) ActionCreator
+}
@@ -0,0 +1,41 @@
+package interfaces
+
+import "time"
+
+// ScheduledPush and ScheduledStateChange are the kinds of requests that can be scheduled.
+const (
+	ScheduledPush        = "push"
+	ScheduledStateChange = "state_change"
+)
+
+// ScheduledDeployment is a push or state change request that is run at a later time.
+type ScheduledDeployment struct {
+	UUID        string    `json:"uuid"`
+	Kind        string    `json:"kind"`
+	ScheduledAt time.Time `json:"scheduled_at"`
+
+	CFContext     CFContext     `json:"cf_context"`
+	Authorization Authorization `json:"-"`
+	ContentType   string        `json:"content_type"`
+	Body          []byte        `json:"-"`
+
+	// Authenticate is true when the environment runs requests with the credentials they carry
+	// instead of the configured ones. The password is not kept across a restart, so a deployment of
+	// such an environment that is restored after one has NeedsCredentials set and is not run.
+	Authenticate     bool `json:"authenticate"`
+	NeedsCredentials bool `json:"needs_credentials,omitempty"`
+}
+
+// Scheduler keeps scheduled deployments, across restarts, and runs them when they are due.
+type Scheduler interface {
+	// Start runs the scheduled deployments that were kept before a restart when they are due.
+	Start() error
+	Schedule(deployment ScheduledDeployment) error
+
+	// List returns the deployments that a user scheduled in an environment.
+	List(environment, username string) []ScheduledDeployment
+
+	// Cancel removes a deployment scheduled in an environment. It can only be cancelled with the
+	// credentials it was scheduled with.
+	Cancel(environment, uuid string, authorization Authorization) (ScheduledDeployment, error)
+}
@@ -0,0 +1,8 @@
+package interfaces
+
+// SecretStore looks up the locally stored secrets that user-provided services reference. Every
+// environment has secrets of its own, so a request can only reference the secrets of the environment
+// it deploys to.
+type SecretStore interface {
+	Get(environment, name string) (string, error)
+}
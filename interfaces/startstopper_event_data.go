@@ -6,8 +6,9 @@ import (
 
 // PushEventData has a RequestBody and DeploymentInfo.
 type StartStopEventData struct {
-	FoundationURL string
-	Context       CFContext
-	Courier       interface{}
-	Response      io.ReadWriter
+	FoundationURL  string
+	FoundationName string
+	Context        CFContext
+	Courier        interface{}
+	Response       io.ReadWriter
 }
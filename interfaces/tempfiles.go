@@ -0,0 +1,15 @@
+package interfaces
+
+// TempFileSweeper removes the temporary files that requests left on the local disk.
+type TempFileSweeper interface {
+	// Start sweeps right away and then periodically.
+	Start()
+
+	// Sweep removes the stale temporary files and returns their paths.
+	Sweep() []string
+}
+
+// DiskGuard rejects new deployments when the local disk is running out of space.
+type DiskGuard interface {
+	Check() error
+}
@@ -0,0 +1,7 @@
+package interfaces
+
+// TimeoutError is an error caused by a command or a phase that ran longer than it was allowed to.
+type TimeoutError interface {
+	error
+	Timeout() bool
+}
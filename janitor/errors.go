@@ -0,0 +1,27 @@
+package janitor
+
+import "fmt"
+
+type EnvironmentNotFoundError struct {
+	Environment string
+}
+
+func (e EnvironmentNotFoundError) Error() string {
+	return fmt.Sprintf("environment not found: %s", e.Environment)
+}
+
+type JanitorNotConfiguredError struct {
+	Environment string
+}
+
+func (e JanitorNotConfiguredError) Error() string {
+	return fmt.Sprintf("no janitor spaces are configured for environment %s", e.Environment)
+}
+
+type SweepInProgressError struct {
+	Environment string
+}
+
+func (e SweepInProgressError) Error() string {
+	return fmt.Sprintf("environment %s is already being swept", e.Environment)
+}
@@ -0,0 +1,70 @@
+package janitor
+
+import (
+	"errors"
+	"reflect"
+
+	"github.com/compozed/deployadactyl/eventmanager"
+	I "github.com/compozed/deployadactyl/interfaces"
+)
+
+type eventBinding struct {
+	etype   reflect.Type
+	handler func(event interface{}) error
+}
+
+func (b eventBinding) Accepts(event interface{}) bool {
+	return reflect.TypeOf(event) == b.etype
+}
+
+func (b eventBinding) Emit(event interface{}) error {
+	return b.handler(event)
+}
+
+// OrphanedApplicationDeletedEvent is emitted for each temporary application the janitor deleted.
+type OrphanedApplicationDeletedEvent struct {
+	Environment string
+	Application I.OrphanedApplication
+	Log         I.DeploymentLogger
+}
+
+func (e OrphanedApplicationDeletedEvent) Name() string {
+	return "OrphanedApplicationDeletedEvent"
+}
+
+func NewOrphanedApplicationDeletedEventBinding(handler func(event OrphanedApplicationDeletedEvent) error) I.Binding {
+	return eventBinding{
+		etype: reflect.TypeOf(OrphanedApplicationDeletedEvent{}),
+		handler: func(gevent interface{}) error {
+			event, ok := gevent.(OrphanedApplicationDeletedEvent)
+			if ok {
+				return handler(event)
+			}
+			return eventmanager.InvalidEventType{errors.New("invalid event type")}
+		},
+	}
+}
+
+// SweepFinishedEvent is emitted with the report when the janitor finished sweeping an environment.
+type SweepFinishedEvent struct {
+	Environment string
+	Report      I.JanitorReport
+	Log         I.DeploymentLogger
+}
+
+func (e SweepFinishedEvent) Name() string {
+	return "SweepFinishedEvent"
+}
+
+func NewSweepFinishedEventBinding(handler func(event SweepFinishedEvent) error) I.Binding {
+	return eventBinding{
+		etype: reflect.TypeOf(SweepFinishedEvent{}),
+		handler: func(gevent interface{}) error {
+			event, ok := gevent.(SweepFinishedEvent)
+			if ok {
+				return handler(event)
+			}
+			return eventmanager.InvalidEventType{errors.New("invalid event type")}
+		},
+	}
+}
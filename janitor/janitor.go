@@ -0,0 +1,309 @@
+// Package janitor deletes the temporary applications and routes that interrupted pushes left behind.
+package janitor
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/compozed/deployadactyl/cloudcontroller"
+	"github.com/compozed/deployadactyl/config"
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/randomizer"
+	"github.com/compozed/deployadactyl/state"
+	"github.com/compozed/deployadactyl/state/push"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+type EventManagerFactory func(log I.DeploymentLogger) I.EventManager
+
+type JanitorConstructor func(config config.Config, courierCreator I.CourierCreator, history I.DeploymentHistory, eventManagerFactory EventManagerFactory, log I.Logger) I.Janitor
+
+func NewJanitor(config config.Config, courierCreator I.CourierCreator, history I.DeploymentHistory, eventManagerFactory EventManagerFactory, log I.Logger) I.Janitor {
+	return &Janitor{
+		Config:              config,
+		CourierCreator:      courierCreator,
+		History:             history,
+		EventManagerFactory: eventManagerFactory,
+		Log:                 log,
+	}
+}
+
+// Janitor sweeps the configured spaces of an environment on each of its foundations for applications
+// named like the temporary applications of a push, <app>-new-build-<uuid>. A crash, a kill or a failed
+// undo leaves them behind. They are deleted along with the routes that are mapped only to them, unless
+// the deployment with their UUID is still running or they were created less than the minimum age ago.
+//
+// Routes that are also mapped to other applications, such as the route of the running application,
+// are left alone.
+type Janitor struct {
+	Config              config.Config
+	CourierCreator      I.CourierCreator
+	History             I.DeploymentHistory
+	EventManagerFactory EventManagerFactory
+	Log                 I.Logger
+
+	lock     sync.Mutex
+	sweeping map[string]bool
+}
+
+// Start sweeps each environment that has a janitor with an interval every interval.
+func (j *Janitor) Start() {
+	for name, environment := range j.Config.Environments {
+		interval, err := environment.Janitor.GetInterval()
+		if err != nil || interval <= 0 || !environment.Janitor.Enabled() {
+			continue
+		}
+
+		j.Log.Infof("sweeping temporary applications of environment %s every %s", environment.Name, interval)
+		go j.sweepEvery(name, interval)
+	}
+}
+
+func (j *Janitor) sweepEvery(environment string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, err := j.Sweep(environment, I.Authorization{}); err != nil {
+			j.Log.Errorf("cannot sweep environment %s: %s", environment, err)
+		}
+	}
+}
+
+// Sweep deletes the temporary applications that were left behind in the spaces of the environment.
+// A space that cannot be swept on a foundation does not stop the others from being swept, and is
+// reported in the errors of the report.
+//
+// Returns an EnvironmentNotFoundError for an unknown environment, a JanitorNotConfiguredError when the
+// environment has no janitor spaces and a SweepInProgressError while it is already being swept.
+func (j *Janitor) Sweep(environmentName string, authorization I.Authorization) (I.JanitorReport, error) {
+	name := strings.ToLower(environmentName)
+
+	environment, ok := j.Config.Environments[name]
+	if !ok {
+		return I.JanitorReport{}, EnvironmentNotFoundError{environmentName}
+	}
+	if !environment.Janitor.Enabled() {
+		return I.JanitorReport{}, JanitorNotConfiguredError{environment.Name}
+	}
+
+	if !j.begin(name) {
+		return I.JanitorReport{}, SweepInProgressError{environment.Name}
+	}
+	defer j.end(name)
+
+	log := I.DeploymentLogger{Log: j.Log, UUID: randomizer.StringRunes(10)}
+	eventManager := j.EventManagerFactory(log)
+
+	report := I.JanitorReport{
+		Environment: environment.Name,
+		Deleted:     []I.OrphanedApplication{},
+		Skipped:     []I.OrphanedApplication{},
+		Failed:      []I.OrphanedApplication{},
+		Errors:      []string{},
+	}
+
+	log.Infof("sweeping temporary applications of environment %s", environment.Name)
+
+	for _, foundationURL := range environment.Foundations {
+		foundation := environment.GetFoundation(foundationURL)
+
+		for _, space := range environment.Janitor.Spaces {
+			err := j.sweepSpace(log, eventManager, environment, foundation, space, authorization, &report)
+			if err != nil {
+				log.Errorf("%s: cannot sweep %s/%s: %s", foundation.GetName(), space.Org, space.Space, err)
+				report.Errors = append(report.Errors, fmt.Sprintf("%s %s/%s: %s", foundation.GetName(), space.Org, space.Space, err))
+			}
+		}
+	}
+
+	log.Infof("swept environment %s: deleted %d, skipped %d and failed to delete %d temporary applications", environment.Name, len(report.Deleted), len(report.Skipped), len(report.Failed))
+
+	err := eventManager.EmitEvent(SweepFinishedEvent{Environment: environment.Name, Report: report, Log: log})
+	if err != nil {
+		log.Error(err)
+	}
+
+	return report, nil
+}
+
+func (j *Janitor) sweepSpace(log I.DeploymentLogger, eventManager I.EventManager, environment S.Environment, foundation S.Foundation, janitorSpace S.JanitorSpace, authorization I.Authorization, report *I.JanitorReport) error {
+	var (
+		org       = foundation.GetOrg(janitorSpace.Org)
+		spaceName = foundation.GetSpace(janitorSpace.Space)
+	)
+
+	if authorization.Username == "" && authorization.Password == "" {
+		authorization = I.Authorization{Username: j.Config.Username, Password: j.Config.Password}
+	}
+	if foundation.Credentials != nil && !environment.Authenticate {
+		authorization = I.Authorization{Username: foundation.Credentials.Username, Password: foundation.Credentials.Password}
+	}
+
+	courier, err := j.CourierCreator.CreateCourier()
+	if err != nil {
+		return err
+	}
+	defer courier.CleanUp()
+
+	out, err := courier.Login(foundation.APIURL, authorization.Username, authorization.Password, org, spaceName, foundation.GetSkipSSL(environment.SkipSSL))
+	if err != nil {
+		return state.LoginError{foundation.APIURL, out}
+	}
+
+	orgResource, err := cloudcontroller.Find(courier, "/v3/organizations?names="+url.QueryEscape(org))
+	if err != nil {
+		return err
+	}
+	if orgResource == nil {
+		return state.OrgNotFoundError{org}
+	}
+
+	space, err := cloudcontroller.Find(courier, fmt.Sprintf("/v3/spaces?names=%s&organization_guids=%s", url.QueryEscape(spaceName), orgResource.GUID))
+	if err != nil {
+		return err
+	}
+	if space == nil {
+		return state.SpaceNotFoundError{org, spaceName}
+	}
+
+	var apps cloudcontroller.List
+	err = cloudcontroller.Get(courier, fmt.Sprintf("/v3/apps?space_guids=%s&per_page=5000", space.GUID), &apps)
+	if err != nil {
+		return err
+	}
+
+	minimumAge, _ := environment.Janitor.GetMinimumAge()
+
+	for _, app := range apps.Resources {
+		uuid, ok := temporaryUUID(app.Name)
+		if !ok {
+			continue
+		}
+
+		orphan := I.OrphanedApplication{
+			Foundation: foundation.GetName(),
+			Org:        org,
+			Space:      spaceName,
+			Name:       app.Name,
+			UUID:       uuid,
+		}
+
+		if j.History != nil && j.History.Active(uuid) {
+			orphan.Reason = fmt.Sprintf("deployment %s is running", uuid)
+			report.Skipped = append(report.Skipped, orphan)
+			continue
+		}
+
+		if age := time.Since(app.CreatedAt); age < minimumAge {
+			orphan.Reason = fmt.Sprintf("created %s ago, less than %s", age.Round(time.Second), minimumAge)
+			report.Skipped = append(report.Skipped, orphan)
+			continue
+		}
+
+		j.deleteOrphan(log, eventManager, environment, courier, app, orphan, report)
+	}
+
+	return nil
+}
+
+// deleteOrphan deletes the application and then the routes that are not mapped to any other application.
+func (j *Janitor) deleteOrphan(log I.DeploymentLogger, eventManager I.EventManager, environment S.Environment, courier I.Courier, app cloudcontroller.Resource, orphan I.OrphanedApplication, report *I.JanitorReport) {
+	var routes cloudcontroller.List
+	err := cloudcontroller.Get(courier, fmt.Sprintf("/v3/apps/%s/routes", app.GUID), &routes)
+	if err != nil {
+		orphan.Reason = fmt.Sprintf("cannot get its routes: %s", err)
+		report.Failed = append(report.Failed, orphan)
+		return
+	}
+
+	log.Infof("%s: deleting temporary application %s", orphan.Foundation, orphan.Name)
+
+	out, err := courier.Delete(orphan.Name)
+	if err != nil {
+		log.Errorf("%s: cannot delete temporary application %s: %s", orphan.Foundation, orphan.Name, out)
+		orphan.Reason = fmt.Sprintf("cannot delete it: %s", strings.TrimSpace(string(out)))
+		report.Failed = append(report.Failed, orphan)
+		return
+	}
+
+	for _, route := range routes.Resources {
+		if route.Host == "" || !onlyMappedTo(route, app.GUID) {
+			continue
+		}
+
+		var (
+			domain = routeDomain(route)
+			path   = strings.TrimPrefix(route.Path, "/")
+			name   = route.Host + "." + domain + "/" + path
+		)
+
+		if path == "" {
+			out, err = courier.DeleteRoute(domain, route.Host)
+		} else {
+			out, err = courier.DeleteRouteWithPath(domain, route.Host, path)
+		}
+		if err != nil {
+			log.Errorf("%s: cannot delete route %s of %s: %s", orphan.Foundation, name, orphan.Name, out)
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: cannot delete route %s of %s: %s", orphan.Foundation, name, orphan.Name, strings.TrimSpace(string(out))))
+			continue
+		}
+		orphan.Routes = append(orphan.Routes, name)
+	}
+
+	log.Infof("%s: deleted temporary application %s", orphan.Foundation, orphan.Name)
+	report.Deleted = append(report.Deleted, orphan)
+
+	err = eventManager.EmitEvent(OrphanedApplicationDeletedEvent{Environment: environment.Name, Application: orphan, Log: log})
+	if err != nil {
+		log.Error(err)
+	}
+}
+
+func (j *Janitor) begin(environment string) bool {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	if j.sweeping == nil {
+		j.sweeping = map[string]bool{}
+	}
+	if j.sweeping[environment] {
+		return false
+	}
+	j.sweeping[environment] = true
+	return true
+}
+
+func (j *Janitor) end(environment string) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	delete(j.sweeping, environment)
+}
+
+// temporaryUUID returns the UUID of the deployment that pushed a temporary application.
+func temporaryUUID(name string) (string, bool) {
+	i := strings.LastIndex(name, push.TemporaryNameSuffix)
+	if i < 1 || i+len(push.TemporaryNameSuffix) == len(name) {
+		return "", false
+	}
+	return name[i+len(push.TemporaryNameSuffix):], true
+}
+
+func onlyMappedTo(route cloudcontroller.Resource, appGUID string) bool {
+	for _, destination := range route.Destinations {
+		if destination.App.GUID != appGUID {
+			return false
+		}
+	}
+	return true
+}
+
+// routeDomain returns the domain of a route from its URL, which is hostname.domain/path.
+func routeDomain(route cloudcontroller.Resource) string {
+	domain := strings.TrimPrefix(route.URL, route.Host+".")
+	return strings.TrimSuffix(domain, route.Path)
+}
@@ -0,0 +1,209 @@
+package janitor_test
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/compozed/deployadactyl/config"
+	"github.com/compozed/deployadactyl/history"
+	I "github.com/compozed/deployadactyl/interfaces"
+	. "github.com/compozed/deployadactyl/janitor"
+	"github.com/compozed/deployadactyl/mocks"
+	S "github.com/compozed/deployadactyl/structs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	"github.com/op/go-logging"
+	"github.com/spf13/afero"
+)
+
+var _ = Describe("Janitor", func() {
+	var (
+		courier        *mocks.Courier
+		courierCreator *mocks.CourierCreator
+		eventManager   *mocks.EventManager
+		deployments    I.DeploymentHistory
+		environment    S.Environment
+		janitor        I.Janitor
+	)
+
+	app := func(guid, name string, age time.Duration) string {
+		return fmt.Sprintf(`{"guid": %q, "name": %q, "created_at": %q}`, guid, name, time.Now().Add(-age).UTC().Format(time.RFC3339))
+	}
+
+	route := func(host, path string, apps ...string) string {
+		destinations := ""
+		for i, app := range apps {
+			if i > 0 {
+				destinations += ", "
+			}
+			destinations += fmt.Sprintf(`{"app": {"guid": %q}}`, app)
+		}
+		return fmt.Sprintf(`{"host": %q, "path": %q, "url": %q, "destinations": [%s]}`, host, path, host+".apps.example.com"+path, destinations)
+	}
+
+	BeforeEach(func() {
+		courier = &mocks.Courier{}
+		courierCreator = &mocks.CourierCreator{}
+		courierCreator.CreateCourierCall.Returns.Courier = courier
+		eventManager = &mocks.EventManager{}
+		deployments = history.NewDeploymentHistory(&afero.Afero{Fs: afero.NewMemMapFs()}, "/history.json", 10, I.DefaultLogger(NewBuffer(), logging.DEBUG, "janitor_test"))
+
+		environment = S.Environment{
+			Name:                  "Production",
+			Foundations:           []string{"https://api.east.example.com"},
+			FoundationDefinitions: []S.Foundation{{Name: "east", APIURL: "https://api.east.example.com", Orgs: map[string]string{"payments": "payments-east"}}},
+			Janitor: S.Janitor{
+				MinimumAge: "1h",
+				Spaces:     []S.JanitorSpace{{Org: "payments", Space: "prod"}},
+			},
+		}
+
+		courier.CurlCall.Returns.Bodies = map[string]string{
+			"/v3/organizations?names=payments-east":             `{"resources": [{"guid": "org-guid"}]}`,
+			"/v3/spaces?names=prod&organization_guids=org-guid": `{"resources": [{"guid": "space-guid"}]}`,
+			"/v3/apps?space_guids=space-guid&per_page=5000": `{"resources": [` +
+				app("app-guid", "payments", 48*time.Hour) + `, ` +
+				app("orphan-guid", "payments-new-build-crashed", 2*time.Hour) + `, ` +
+				app("running-guid", "payments-new-build-running", 2*time.Hour) + `, ` +
+				app("young-guid", "payments-new-build-young", time.Minute) + `]}`,
+			"/v3/apps/orphan-guid/routes": `{"resources": [` +
+				route("payments-new-build-crashed", "") + `, ` +
+				route("payments-new-build-crashed", "/health", "orphan-guid") + `, ` +
+				route("payments", "", "app-guid", "orphan-guid") + `]}`,
+		}
+
+		deployments.Started("running")
+	})
+
+	JustBeforeEach(func() {
+		cfg := config.Config{
+			Username:     "cf-user",
+			Password:     "cf-password",
+			Environments: map[string]S.Environment{"production": environment},
+		}
+		logger := I.DefaultLogger(NewBuffer(), logging.DEBUG, "janitor_test")
+		eventManagerFactory := func(log I.DeploymentLogger) I.EventManager { return eventManager }
+
+		janitor = NewJanitor(cfg, courierCreator, deployments, eventManagerFactory, logger)
+	})
+
+	It("deletes temporary applications and the routes that are mapped only to them", func() {
+		report, err := janitor.Sweep("production", I.Authorization{})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(courier.DeleteCall.Received.AppNames).To(Equal([]string{"payments-new-build-crashed"}))
+		Expect(courier.DeleteRouteCall.Received.Domain).To(Equal("apps.example.com"))
+		Expect(courier.DeleteRouteCall.Received.Hostname).To(Equal("payments-new-build-crashed"))
+		Expect(courier.DeleteRouteWithPathCall.Received.Routes).To(Equal([]string{"payments-new-build-crashed.apps.example.com/health"}))
+
+		Expect(report.Environment).To(Equal("Production"))
+		Expect(report.Deleted).To(Equal([]I.OrphanedApplication{{
+			Foundation: "east",
+			Org:        "payments-east",
+			Space:      "prod",
+			Name:       "payments-new-build-crashed",
+			UUID:       "crashed",
+			Routes:     []string{"payments-new-build-crashed.apps.example.com/", "payments-new-build-crashed.apps.example.com/health"},
+		}}))
+		Expect(report.Failed).To(BeEmpty())
+		Expect(report.Errors).To(BeEmpty())
+	})
+
+	It("skips temporary applications of running deployments and recent ones", func() {
+		report, err := janitor.Sweep("production", I.Authorization{})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(report.Skipped).To(HaveLen(2))
+		Expect(report.Skipped[0].Name).To(Equal("payments-new-build-running"))
+		Expect(report.Skipped[0].Reason).To(Equal("deployment running is running"))
+		Expect(report.Skipped[1].Name).To(Equal("payments-new-build-young"))
+		Expect(report.Skipped[1].Reason).To(ContainSubstring("less than 1h0m0s"))
+	})
+
+	It("deletes the temporary applications of deployments that finished", func() {
+		deployments.Finished("running")
+		courier.CurlCall.Returns.Bodies["/v3/apps/running-guid/routes"] = `{"resources": []}`
+
+		report, err := janitor.Sweep("production", I.Authorization{})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(courier.DeleteCall.Received.AppNames).To(ContainElement("payments-new-build-running"))
+		Expect(report.Deleted).To(HaveLen(2))
+	})
+
+	It("logs in to the mapped org with the configured user unless credentials are given", func() {
+		_, err := janitor.Sweep("production", I.Authorization{})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(courier.LoginCall.Received.FoundationURL).To(Equal("https://api.east.example.com"))
+		Expect(courier.LoginCall.Received.Username).To(Equal("cf-user"))
+		Expect(courier.LoginCall.Received.Org).To(Equal("payments-east"))
+		Expect(courier.LoginCall.Received.Space).To(Equal("prod"))
+
+		_, err = janitor.Sweep("production", I.Authorization{Username: "deployer", Password: "secret"})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(courier.LoginCall.Received.Username).To(Equal("deployer"))
+	})
+
+	It("emits an event for each deleted application and when it finishes", func() {
+		_, err := janitor.Sweep("production", I.Authorization{})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(eventManager.EmitEventCall.Received.Events).To(HaveLen(2))
+
+		deleted, ok := eventManager.EmitEventCall.Received.Events[0].(OrphanedApplicationDeletedEvent)
+		Expect(ok).To(BeTrue())
+		Expect(deleted.Environment).To(Equal("Production"))
+		Expect(deleted.Application.Name).To(Equal("payments-new-build-crashed"))
+
+		finished, ok := eventManager.EmitEventCall.Received.Events[1].(SweepFinishedEvent)
+		Expect(ok).To(BeTrue())
+		Expect(finished.Report.Deleted).To(HaveLen(1))
+	})
+
+	It("reports temporary applications that cannot be deleted", func() {
+		courier.DeleteCall.Returns.Output = []byte("app is locked")
+		courier.DeleteCall.Returns.Error = errors.New("exit status 1")
+
+		report, err := janitor.Sweep("production", I.Authorization{})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(report.Deleted).To(BeEmpty())
+		Expect(report.Failed).To(HaveLen(1))
+		Expect(report.Failed[0].Reason).To(Equal("cannot delete it: app is locked"))
+		Expect(courier.DeleteRouteCall.Received.Hostname).To(BeEmpty())
+	})
+
+	It("reports spaces that cannot be swept", func() {
+		courier.LoginCall.Returns.Output = []byte("bad credentials")
+		courier.LoginCall.Returns.Error = errors.New("exit status 1")
+
+		report, err := janitor.Sweep("production", I.Authorization{})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(report.Errors).To(Equal([]string{"east payments/prod: cannot login to https://api.east.example.com: bad credentials"}))
+		Expect(courier.DeleteCall.Received.AppNames).To(BeEmpty())
+	})
+
+	It("returns an error for an unknown environment", func() {
+		_, err := janitor.Sweep("staging", I.Authorization{})
+
+		Expect(err).To(MatchError(EnvironmentNotFoundError{"staging"}))
+	})
+
+	Context("when the environment has no janitor spaces", func() {
+		BeforeEach(func() {
+			environment.Janitor = S.Janitor{}
+		})
+
+		It("returns an error", func() {
+			_, err := janitor.Sweep("production", I.Authorization{})
+
+			Expect(err).To(MatchError(JanitorNotConfiguredError{"Production"}))
+			Expect(courierCreator.CreateCourierCall.TimesCalled).To(Equal(0))
+		})
+	})
+})
@@ -1,6 +1,10 @@
 package mocks
 
-import "net/http"
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
 
 // Client handmade mock for tests.
 type Client struct {
@@ -13,6 +17,17 @@ type Client struct {
 			Error    error
 		}
 	}
+	DoCall struct {
+		TimesCalled int
+		Received    struct {
+			Requests []*http.Request
+			Bodies   []string
+		}
+		Returns struct {
+			Responses []http.Response
+			Errors    []error
+		}
+	}
 }
 
 // Get mock method.
@@ -21,3 +36,24 @@ func (c *Client) Get(url string) (*http.Response, error) {
 
 	return &c.GetCall.Returns.Response, c.GetCall.Returns.Error
 }
+
+// Do mock method.
+func (c *Client) Do(request *http.Request) (*http.Response, error) {
+	defer func() { c.DoCall.TimesCalled++ }()
+
+	c.DoCall.Received.Requests = append(c.DoCall.Received.Requests, request)
+
+	var body []byte
+	if request.Body != nil {
+		body, _ = ioutil.ReadAll(request.Body)
+	}
+	c.DoCall.Received.Bodies = append(c.DoCall.Received.Bodies, string(body))
+
+	if len(c.DoCall.Returns.Errors) > c.DoCall.TimesCalled && c.DoCall.Returns.Errors[c.DoCall.TimesCalled] != nil {
+		return nil, c.DoCall.Returns.Errors[c.DoCall.TimesCalled]
+	}
+	if len(c.DoCall.Returns.Responses) > c.DoCall.TimesCalled {
+		return &c.DoCall.Returns.Responses[c.DoCall.TimesCalled], nil
+	}
+	return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+}
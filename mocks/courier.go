@@ -1,5 +1,11 @@
 package mocks
 
+import (
+	"fmt"
+
+	S "github.com/compozed/deployadactyl/structs"
+)
+
 // Courier handmade mock for tests.
 type Courier struct {
 	TimesCourierCalled int
@@ -20,7 +26,8 @@ type Courier struct {
 
 	StartCall struct {
 		Received struct {
-			AppName string
+			AppName  string
+			AppNames []string
 		}
 		Returns struct {
 			Output []byte
@@ -30,7 +37,8 @@ type Courier struct {
 
 	StopCall struct {
 		Received struct {
-			AppName string
+			AppName  string
+			AppNames []string
 		}
 		Returns struct {
 			Output []byte
@@ -40,7 +48,8 @@ type Courier struct {
 
 	DeleteCall struct {
 		Received struct {
-			AppName string
+			AppName  string
+			AppNames []string
 		}
 		Returns struct {
 			Output []byte
@@ -61,10 +70,50 @@ type Courier struct {
 		}
 	}
 
+	RunTaskCall struct {
+		Received struct {
+			AppName []string
+			Command []string
+			Name    []string
+		}
+		Returns struct {
+			Output []byte
+			Error  error
+		}
+	}
+
+	PushWithStrategyCall struct {
+		Received struct {
+			AppName   string
+			AppPath   string
+			Hostname  string
+			Instances uint16
+			Strategy  string
+		}
+		Returns struct {
+			Output []byte
+			Error  error
+		}
+	}
+
+	PushWithoutStartCall struct {
+		Received struct {
+			AppName   string
+			AppPath   string
+			Hostname  string
+			Instances uint16
+		}
+		Returns struct {
+			Output []byte
+			Error  error
+		}
+	}
+
 	RenameCall struct {
 		Received struct {
 			AppName          string
 			AppNameVenerable string
+			Renames          [][2]string
 		}
 		Returns struct {
 			Output []byte
@@ -134,11 +183,78 @@ type Courier struct {
 		}
 	}
 
+	DeleteRouteWithPathCall struct {
+		Received struct {
+			Domain   string
+			Hostname string
+			Path     string
+			Routes   []string
+		}
+		Returns struct {
+			Output []byte
+			Error  error
+		}
+	}
+
 	CreateServiceCall struct {
 		Received struct {
-			Service string
-			Plan    string
-			Name    string
+			Service []string
+			Plan    []string
+			Name    []string
+			Params  []string
+		}
+		Returns struct {
+			Output []byte
+			Error  error
+		}
+	}
+
+	ServiceStatusCall struct {
+		TimesCalled int
+		Received    struct {
+			ServiceName []string
+		}
+		Returns struct {
+			Statuses []string
+			Error    error
+		}
+	}
+
+	BindServiceCall struct {
+		Received struct {
+			AppName     []string
+			ServiceName []string
+		}
+		Returns struct {
+			Output []byte
+			Error  error
+		}
+	}
+
+	UnbindServiceCall struct {
+		Received struct {
+			AppName     []string
+			ServiceName []string
+		}
+		Returns struct {
+			Output []byte
+			Error  error
+		}
+	}
+
+	DeleteServiceCall struct {
+		Received struct {
+			ServiceName []string
+		}
+		Returns struct {
+			Output []byte
+			Error  error
+		}
+	}
+
+	RestageCall struct {
+		Received struct {
+			AppName []string
 		}
 		Returns struct {
 			Output []byte
@@ -152,13 +268,19 @@ type Courier struct {
 		}
 		Returns struct {
 			Bool bool
+
+			// Apps, when it is set, holds the applications that exist. Rename and Delete
+			// update it so that a sequence of calls can be followed.
+			Apps map[string]bool
 		}
 	}
 
 	CupsCall struct {
 		Received struct {
-			AppName string
-			Body    string
+			ServiceName     []string
+			Credentials     []string
+			SyslogDrainURL  []string
+			RouteServiceURL []string
 		}
 		Returns struct {
 			Output []byte
@@ -168,8 +290,10 @@ type Courier struct {
 
 	UupsCall struct {
 		Received struct {
-			AppName string
-			Body    string
+			ServiceName     []string
+			Credentials     []string
+			SyslogDrainURL  []string
+			RouteServiceURL []string
 		}
 		Returns struct {
 			Output []byte
@@ -177,6 +301,16 @@ type Courier struct {
 		}
 	}
 
+	UserProvidedServiceCall struct {
+		Received struct {
+			ServiceName []string
+		}
+		Returns struct {
+			Service S.UserProvidedService
+			Error   error
+		}
+	}
+
 	DomainsCall struct {
 		TimesCalled int
 		Returns     struct {
@@ -185,6 +319,17 @@ type Courier struct {
 		}
 	}
 
+	AppInstancesCall struct {
+		TimesCalled int
+		Received    struct {
+			AppName []string
+		}
+		Returns struct {
+			Instances [][]S.AppInstance
+			Error     []error
+		}
+	}
+
 	CleanUpCall struct {
 		Returns struct {
 			Error error
@@ -198,6 +343,25 @@ type Courier struct {
 			Error    error
 		}
 	}
+
+	CurlCall struct {
+		Received struct {
+			Path []string
+		}
+		Returns struct {
+			// Bodies are the responses by path. Paths that are not listed fail.
+			Bodies map[string]string
+			Error  error
+		}
+	}
+
+	OAuthTokenCall struct {
+		TimesCalled int
+		Returns     struct {
+			Token string
+			Error error
+		}
+	}
 }
 
 // Login mock method.
@@ -214,12 +378,14 @@ func (c *Courier) Login(foundationURL, username, password, org, space string, sk
 
 func (c *Courier) Start(appName string) ([]byte, error) {
 	c.StartCall.Received.AppName = appName
+	c.StartCall.Received.AppNames = append(c.StartCall.Received.AppNames, appName)
 
 	return c.StartCall.Returns.Output, c.StartCall.Returns.Error
 }
 
 func (c *Courier) Stop(appName string) ([]byte, error) {
 	c.StopCall.Received.AppName = appName
+	c.StopCall.Received.AppNames = append(c.StopCall.Received.AppNames, appName)
 
 	return c.StopCall.Returns.Output, c.StopCall.Returns.Error
 }
@@ -227,6 +393,11 @@ func (c *Courier) Stop(appName string) ([]byte, error) {
 // Delete mock method.
 func (c *Courier) Delete(appName string) ([]byte, error) {
 	c.DeleteCall.Received.AppName = appName
+	c.DeleteCall.Received.AppNames = append(c.DeleteCall.Received.AppNames, appName)
+
+	if c.ExistsCall.Returns.Apps != nil && c.DeleteCall.Returns.Error == nil {
+		delete(c.ExistsCall.Returns.Apps, appName)
+	}
 
 	return c.DeleteCall.Returns.Output, c.DeleteCall.Returns.Error
 }
@@ -241,10 +412,37 @@ func (c *Courier) Push(appName, appLocation, hostname string, instances uint16)
 	return c.PushCall.Returns.Output, c.PushCall.Returns.Error
 }
 
+// PushWithStrategy mock method.
+func (c *Courier) PushWithStrategy(appName, appLocation, hostname string, instances uint16, strategy string) ([]byte, error) {
+	c.PushWithStrategyCall.Received.AppName = appName
+	c.PushWithStrategyCall.Received.AppPath = appLocation
+	c.PushWithStrategyCall.Received.Hostname = hostname
+	c.PushWithStrategyCall.Received.Instances = instances
+	c.PushWithStrategyCall.Received.Strategy = strategy
+
+	return c.PushWithStrategyCall.Returns.Output, c.PushWithStrategyCall.Returns.Error
+}
+
+// PushWithoutStart mock method.
+func (c *Courier) PushWithoutStart(appName, appLocation, hostname string, instances uint16) ([]byte, error) {
+	c.PushWithoutStartCall.Received.AppName = appName
+	c.PushWithoutStartCall.Received.AppPath = appLocation
+	c.PushWithoutStartCall.Received.Hostname = hostname
+	c.PushWithoutStartCall.Received.Instances = instances
+
+	return c.PushWithoutStartCall.Returns.Output, c.PushWithoutStartCall.Returns.Error
+}
+
 // Rename mock method.
 func (c *Courier) Rename(appName, newAppName string) ([]byte, error) {
 	c.RenameCall.Received.AppName = appName
 	c.RenameCall.Received.AppNameVenerable = newAppName
+	c.RenameCall.Received.Renames = append(c.RenameCall.Received.Renames, [2]string{appName, newAppName})
+
+	if c.ExistsCall.Returns.Apps != nil && c.RenameCall.Returns.Error == nil {
+		c.ExistsCall.Returns.Apps[newAppName] = c.ExistsCall.Returns.Apps[appName]
+		delete(c.ExistsCall.Returns.Apps, appName)
+	}
 
 	return c.RenameCall.Returns.Output, c.RenameCall.Returns.Error
 }
@@ -315,6 +513,16 @@ func (c *Courier) DeleteRoute(domain, hostname string) ([]byte, error) {
 	return c.DeleteRouteCall.Returns.Output, c.DeleteRouteCall.Returns.Error
 }
 
+// DeleteRouteWithPath mock method. Routes receives each deleted route as hostname.domain/path.
+func (c *Courier) DeleteRouteWithPath(domain, hostname, path string) ([]byte, error) {
+	c.DeleteRouteWithPathCall.Received.Domain = domain
+	c.DeleteRouteWithPathCall.Received.Hostname = hostname
+	c.DeleteRouteWithPathCall.Received.Path = path
+	c.DeleteRouteWithPathCall.Received.Routes = append(c.DeleteRouteWithPathCall.Received.Routes, hostname+"."+domain+"/"+path)
+
+	return c.DeleteRouteWithPathCall.Returns.Output, c.DeleteRouteWithPathCall.Returns.Error
+}
+
 // Logs mock method.
 func (c *Courier) Logs(appName string) ([]byte, error) {
 	c.LogsCall.Received.AppName = appName
@@ -326,25 +534,45 @@ func (c *Courier) Logs(appName string) ([]byte, error) {
 func (c *Courier) Exists(appName string) bool {
 	c.ExistsCall.Received.AppName = appName
 
+	if c.ExistsCall.Returns.Apps != nil {
+		return c.ExistsCall.Returns.Apps[appName]
+	}
+
 	return c.ExistsCall.Returns.Bool
 }
 
 // Cups mock method
-func (c *Courier) Cups(appName string, body string) ([]byte, error) {
-	c.CupsCall.Received.AppName = appName
-	c.CupsCall.Received.Body = body
+func (c *Courier) Cups(serviceName string, credentials string, syslogDrainURL string, routeServiceURL string) ([]byte, error) {
+	c.CupsCall.Received.ServiceName = append(c.CupsCall.Received.ServiceName, serviceName)
+	c.CupsCall.Received.Credentials = append(c.CupsCall.Received.Credentials, credentials)
+	c.CupsCall.Received.SyslogDrainURL = append(c.CupsCall.Received.SyslogDrainURL, syslogDrainURL)
+	c.CupsCall.Received.RouteServiceURL = append(c.CupsCall.Received.RouteServiceURL, routeServiceURL)
 
 	return c.CupsCall.Returns.Output, c.CupsCall.Returns.Error
 }
 
 // Uups mock method
-func (c *Courier) Uups(appName string, body string) ([]byte, error) {
-	c.UupsCall.Received.AppName = appName
-	c.UupsCall.Received.Body = body
+func (c *Courier) Uups(serviceName string, credentials string, syslogDrainURL string, routeServiceURL string) ([]byte, error) {
+	c.UupsCall.Received.ServiceName = append(c.UupsCall.Received.ServiceName, serviceName)
+	c.UupsCall.Received.Credentials = append(c.UupsCall.Received.Credentials, credentials)
+	c.UupsCall.Received.SyslogDrainURL = append(c.UupsCall.Received.SyslogDrainURL, syslogDrainURL)
+	c.UupsCall.Received.RouteServiceURL = append(c.UupsCall.Received.RouteServiceURL, routeServiceURL)
 
 	return c.UupsCall.Returns.Output, c.UupsCall.Returns.Error
 }
 
+// UserProvidedService mock method. The service it returns has the name it receives when the
+// returned one has none.
+func (c *Courier) UserProvidedService(serviceName string) (S.UserProvidedService, error) {
+	c.UserProvidedServiceCall.Received.ServiceName = append(c.UserProvidedServiceCall.Received.ServiceName, serviceName)
+
+	service := c.UserProvidedServiceCall.Returns.Service
+	if service.Name == "" {
+		service.Name = serviceName
+	}
+	return service, c.UserProvidedServiceCall.Returns.Error
+}
+
 // Domains mock method.
 func (c *Courier) Domains() ([]string, error) {
 	defer func() { c.DomainsCall.TimesCalled++ }()
@@ -352,28 +580,90 @@ func (c *Courier) Domains() ([]string, error) {
 	return c.DomainsCall.Returns.Domains, c.DomainsCall.Returns.Error
 }
 
+// CreateService mock method. The params it receives are empty.
 func (c *Courier) CreateService(service, plan, name string) ([]byte, error) {
-	c.CreateServiceCall.Received.Name = name
-	c.CreateServiceCall.Received.Plan = plan
-	c.CreateServiceCall.Received.Service = service
+	return c.CreateServiceWithParams(service, plan, name, "")
+}
+
+// CreateServiceWithParams mock method.
+func (c *Courier) CreateServiceWithParams(service, plan, name, params string) ([]byte, error) {
+	c.CreateServiceCall.Received.Service = append(c.CreateServiceCall.Received.Service, service)
+	c.CreateServiceCall.Received.Plan = append(c.CreateServiceCall.Received.Plan, plan)
+	c.CreateServiceCall.Received.Name = append(c.CreateServiceCall.Received.Name, name)
+	c.CreateServiceCall.Received.Params = append(c.CreateServiceCall.Received.Params, params)
 
 	return c.CreateServiceCall.Returns.Output, c.CreateServiceCall.Returns.Error
 }
 
+// ServiceStatus mock method. The last of the returned statuses is repeated.
+func (c *Courier) ServiceStatus(serviceName string) (string, error) {
+	defer func() { c.ServiceStatusCall.TimesCalled++ }()
+
+	c.ServiceStatusCall.Received.ServiceName = append(c.ServiceStatusCall.Received.ServiceName, serviceName)
+
+	var status string
+	for i := 0; i <= c.ServiceStatusCall.TimesCalled && i < len(c.ServiceStatusCall.Returns.Statuses); i++ {
+		status = c.ServiceStatusCall.Returns.Statuses[i]
+	}
+	return status, c.ServiceStatusCall.Returns.Error
+}
+
+// BindService mock method.
 func (c *Courier) BindService(appName, serviceName string) ([]byte, error) {
-	panic("Mock not implemented.")
+	c.BindServiceCall.Received.AppName = append(c.BindServiceCall.Received.AppName, appName)
+	c.BindServiceCall.Received.ServiceName = append(c.BindServiceCall.Received.ServiceName, serviceName)
+
+	return c.BindServiceCall.Returns.Output, c.BindServiceCall.Returns.Error
 }
 
+// UnbindService mock method.
 func (c *Courier) UnbindService(appName, serviceName string) ([]byte, error) {
-	panic("Mock not implemented.")
+	c.UnbindServiceCall.Received.AppName = append(c.UnbindServiceCall.Received.AppName, appName)
+	c.UnbindServiceCall.Received.ServiceName = append(c.UnbindServiceCall.Received.ServiceName, serviceName)
+
+	return c.UnbindServiceCall.Returns.Output, c.UnbindServiceCall.Returns.Error
 }
 
+// DeleteService mock method.
 func (c *Courier) DeleteService(serviceName string) ([]byte, error) {
-	panic("Mock not implemented.")
+	c.DeleteServiceCall.Received.ServiceName = append(c.DeleteServiceCall.Received.ServiceName, serviceName)
+
+	return c.DeleteServiceCall.Returns.Output, c.DeleteServiceCall.Returns.Error
 }
 
+// Restage mock method.
 func (c *Courier) Restage(appName string) ([]byte, error) {
-	panic("Mock not implemented.")
+	c.RestageCall.Received.AppName = append(c.RestageCall.Received.AppName, appName)
+
+	return c.RestageCall.Returns.Output, c.RestageCall.Returns.Error
+}
+
+// RunTask mock method.
+func (c *Courier) RunTask(appName, command, name string) ([]byte, error) {
+	c.RunTaskCall.Received.AppName = append(c.RunTaskCall.Received.AppName, appName)
+	c.RunTaskCall.Received.Command = append(c.RunTaskCall.Received.Command, command)
+	c.RunTaskCall.Received.Name = append(c.RunTaskCall.Received.Name, name)
+
+	return c.RunTaskCall.Returns.Output, c.RunTaskCall.Returns.Error
+}
+
+// AppInstances mock method. The last of the returned instances and errors is repeated.
+func (c *Courier) AppInstances(appName string) ([]S.AppInstance, error) {
+	defer func() { c.AppInstancesCall.TimesCalled++ }()
+
+	c.AppInstancesCall.Received.AppName = append(c.AppInstancesCall.Received.AppName, appName)
+
+	var (
+		instances []S.AppInstance
+		err       error
+	)
+	for i := 0; i <= c.AppInstancesCall.TimesCalled && i < len(c.AppInstancesCall.Returns.Instances); i++ {
+		instances = c.AppInstancesCall.Returns.Instances[i]
+	}
+	for i := 0; i <= c.AppInstancesCall.TimesCalled && i < len(c.AppInstancesCall.Returns.Error); i++ {
+		err = c.AppInstancesCall.Returns.Error[i]
+	}
+	return instances, err
 }
 
 // CleanUp mock method.
@@ -385,3 +675,25 @@ func (c *Courier) Services() ([]string, error) {
 	c.ServicesCall.TimesCalled++
 	return c.ServicesCall.Returns.Services, c.ServicesCall.Returns.Error
 }
+
+// Curl mock method. It fails for paths that have no body.
+func (c *Courier) Curl(path string) ([]byte, error) {
+	c.CurlCall.Received.Path = append(c.CurlCall.Received.Path, path)
+
+	if c.CurlCall.Returns.Error != nil {
+		return nil, c.CurlCall.Returns.Error
+	}
+
+	body, ok := c.CurlCall.Returns.Bodies[path]
+	if !ok {
+		return nil, fmt.Errorf("unexpected cf curl %s", path)
+	}
+	return []byte(body), nil
+}
+
+// OAuthToken mock method.
+func (c *Courier) OAuthToken() (string, error) {
+	c.OAuthTokenCall.TimesCalled++
+
+	return c.OAuthTokenCall.Returns.Token, c.OAuthTokenCall.Returns.Error
+}
@@ -0,0 +1,21 @@
+package mocks
+
+import I "github.com/compozed/deployadactyl/interfaces"
+
+// CourierCreator handmade mock for tests.
+type CourierCreator struct {
+	CreateCourierCall struct {
+		TimesCalled int
+		Returns     struct {
+			Courier I.Courier
+			Error   error
+		}
+	}
+}
+
+// CreateCourier mock method.
+func (c *CourierCreator) CreateCourier() (I.Courier, error) {
+	c.CreateCourierCall.TimesCalled++
+
+	return c.CreateCourierCall.Returns.Courier, c.CreateCourierCall.Returns.Error
+}
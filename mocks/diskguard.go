@@ -0,0 +1,18 @@
+package mocks
+
+// DiskGuard handmade mock for tests.
+type DiskGuard struct {
+	CheckCall struct {
+		TimesCalled int
+		Returns     struct {
+			Error error
+		}
+	}
+}
+
+// Check mock method.
+func (g *DiskGuard) Check() error {
+	g.CheckCall.TimesCalled++
+
+	return g.CheckCall.Returns.Error
+}
@@ -1,14 +1,22 @@
 package mocks
 
+import "io"
+
 // Executor handmade mock for tests.
 type Executor struct {
 	ExecuteCall struct {
 		Received struct {
 			Args []string
+
+			// AllArgs are the arguments of every call, in order.
+			AllArgs [][]string
 		}
 		Returns struct {
 			Output []byte
 			Error  error
+
+			// Outputs are returned by the calls in order, when set, before Output.
+			Outputs [][]byte
 		}
 	}
 
@@ -23,6 +31,29 @@ type Executor struct {
 		}
 	}
 
+	ExecuteWithEnvironmentCall struct {
+		Received struct {
+			Environment map[string]string
+			Args        []string
+		}
+		Returns struct {
+			Output []byte
+			Error  error
+		}
+	}
+
+	ExecuteStreamingCall struct {
+		Received struct {
+			Output    io.Writer
+			Directory string
+			Args      []string
+		}
+		Returns struct {
+			Output []byte
+			Error  error
+		}
+	}
+
 	CleanUpCall struct {
 		Returns struct {
 			Error error
@@ -33,7 +64,11 @@ type Executor struct {
 // Execute mock method.
 func (e *Executor) Execute(args ...string) ([]byte, error) {
 	e.ExecuteCall.Received.Args = args
+	e.ExecuteCall.Received.AllArgs = append(e.ExecuteCall.Received.AllArgs, args)
 
+	if call := len(e.ExecuteCall.Received.AllArgs) - 1; call < len(e.ExecuteCall.Returns.Outputs) {
+		return e.ExecuteCall.Returns.Outputs[call], e.ExecuteCall.Returns.Error
+	}
 	return e.ExecuteCall.Returns.Output, e.ExecuteCall.Returns.Error
 }
 
@@ -45,6 +80,25 @@ func (e *Executor) ExecuteInDirectory(appLocation string, args ...string) ([]byt
 	return e.ExecuteInDirectoryCall.Returns.Output, e.ExecuteInDirectoryCall.Returns.Error
 }
 
+// ExecuteWithEnvironment mock method.
+func (e *Executor) ExecuteWithEnvironment(environment map[string]string, args ...string) ([]byte, error) {
+	e.ExecuteWithEnvironmentCall.Received.Environment = environment
+	e.ExecuteWithEnvironmentCall.Received.Args = args
+
+	return e.ExecuteWithEnvironmentCall.Returns.Output, e.ExecuteWithEnvironmentCall.Returns.Error
+}
+
+// ExecuteStreaming mock method. It writes the output it returns to the writer.
+func (e *Executor) ExecuteStreaming(output io.Writer, directory string, args ...string) ([]byte, error) {
+	e.ExecuteStreamingCall.Received.Output = output
+	e.ExecuteStreamingCall.Received.Directory = directory
+	e.ExecuteStreamingCall.Received.Args = args
+
+	output.Write(e.ExecuteStreamingCall.Returns.Output)
+
+	return e.ExecuteStreamingCall.Returns.Output, e.ExecuteStreamingCall.Returns.Error
+}
+
 // CleanUp mock method.
 func (e *Executor) CleanUp() error {
 	return e.CleanUpCall.Returns.Error
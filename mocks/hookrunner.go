@@ -0,0 +1,29 @@
+package mocks
+
+import (
+	"io"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+// HookRunner handmade mock for tests.
+type HookRunner struct {
+	RunCall struct {
+		Received struct {
+			Hooks    []S.Hook
+			Contexts []S.HookContext
+		}
+		Returns struct {
+			Errors map[string]error
+		}
+	}
+}
+
+// Run mock method. It returns the error set for the name of the hook.
+func (r *HookRunner) Run(hook S.Hook, context S.HookContext, courier I.Courier, response io.Writer) error {
+	r.RunCall.Received.Hooks = append(r.RunCall.Received.Hooks, hook)
+	r.RunCall.Received.Contexts = append(r.RunCall.Received.Contexts, context)
+
+	return r.RunCall.Returns.Errors[hook.Name]
+}
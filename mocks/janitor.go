@@ -0,0 +1,34 @@
+package mocks
+
+import I "github.com/compozed/deployadactyl/interfaces"
+
+// Janitor handmade mock for tests.
+type Janitor struct {
+	StartCall struct {
+		TimesCalled int
+	}
+
+	SweepCall struct {
+		Received struct {
+			Environment   string
+			Authorization I.Authorization
+		}
+		Returns struct {
+			Report I.JanitorReport
+			Error  error
+		}
+	}
+}
+
+// Start mock method.
+func (j *Janitor) Start() {
+	j.StartCall.TimesCalled++
+}
+
+// Sweep mock method.
+func (j *Janitor) Sweep(environment string, authorization I.Authorization) (I.JanitorReport, error) {
+	j.SweepCall.Received.Environment = environment
+	j.SweepCall.Received.Authorization = authorization
+
+	return j.SweepCall.Returns.Report, j.SweepCall.Returns.Error
+}
@@ -3,6 +3,8 @@ package mocks
 import (
 	"fmt"
 	"io"
+	"sync"
+	"time"
 )
 
 // Pusher handmade mock for tests.
@@ -20,7 +22,9 @@ type Pusher struct {
 	}
 
 	ExecuteCall struct {
-		Write struct {
+		TimesCalled int
+		Sleep       time.Duration
+		Write       struct {
 			Output string
 		}
 		Returns struct {
@@ -29,7 +33,8 @@ type Pusher struct {
 	}
 
 	PostExecuteCall struct {
-		Write struct {
+		TimesCalled int
+		Write       struct {
 			Output string
 		}
 		Returns struct {
@@ -44,13 +49,46 @@ type Pusher struct {
 	}
 
 	UndoCall struct {
-		Returns struct {
+		TimesCalled int
+		Returns     struct {
 			Error error
 		}
 	}
 
 	SuccessCall struct {
+		TimesCalled int
+		Returns     struct {
+			Error error
+		}
+	}
+
+	HealthCheckCall struct {
+		TimesCalled int
+		Returns     struct {
+			Error error
+		}
+	}
+
+	BakeCall struct {
+		TimesCalled int
+		Received    struct {
+			Since time.Time
+		}
 		Returns struct {
+			Errors []error
+		}
+	}
+
+	RollBackCall struct {
+		TimesCalled int
+		Returns     struct {
+			Error error
+		}
+	}
+
+	FinishBakeCall struct {
+		TimesCalled int
+		Returns     struct {
 			Error error
 		}
 	}
@@ -60,6 +98,13 @@ type Pusher struct {
 			Error error
 		}
 	}
+
+	InterruptCall struct {
+		TimesCalled int
+	}
+
+	interruptOnce sync.Once
+	interrupted   chan struct{}
 }
 
 // Login mock method.
@@ -72,13 +117,19 @@ func (p *Pusher) Initially() error {
 
 // Push mock method.
 func (p *Pusher) Execute() error {
+	p.ExecuteCall.TimesCalled++
 
 	fmt.Fprint(p.Response, p.ExecuteCall.Write.Output)
+	select {
+	case <-time.After(p.ExecuteCall.Sleep):
+	case <-p.interruptedChannel():
+	}
 
 	return p.ExecuteCall.Returns.Error
 }
 
 func (p *Pusher) PostExecute() error {
+	p.PostExecuteCall.TimesCalled++
 
 	fmt.Fprint(p.Response, p.PostExecuteCall.Write.Output)
 
@@ -91,15 +142,63 @@ func (p *Pusher) Verify() error {
 
 // FinishPush mock method.
 func (p *Pusher) Success() error {
+	p.SuccessCall.TimesCalled++
+
 	return p.SuccessCall.Returns.Error
 }
 
 // UndoPush mock method.
 func (p *Pusher) Undo() error {
+	p.UndoCall.TimesCalled++
+
 	return p.UndoCall.Returns.Error
 }
 
+// HealthCheck mock method.
+func (p *Pusher) HealthCheck() error {
+	p.HealthCheckCall.TimesCalled++
+
+	return p.HealthCheckCall.Returns.Error
+}
+
+// Bake mock method. It returns the error of the call by its index.
+func (p *Pusher) Bake(since time.Time) error {
+	defer func() { p.BakeCall.TimesCalled++ }()
+
+	p.BakeCall.Received.Since = since
+
+	if p.BakeCall.TimesCalled < len(p.BakeCall.Returns.Errors) {
+		return p.BakeCall.Returns.Errors[p.BakeCall.TimesCalled]
+	}
+	return nil
+}
+
+// RollBack mock method.
+func (p *Pusher) RollBack() error {
+	p.RollBackCall.TimesCalled++
+
+	return p.RollBackCall.Returns.Error
+}
+
+// FinishBake mock method.
+func (p *Pusher) FinishBake() error {
+	p.FinishBakeCall.TimesCalled++
+
+	return p.FinishBakeCall.Returns.Error
+}
+
 // CleanUp mock method.
 func (p *Pusher) Finally() error {
 	return p.FinallyCall.Returns.Error
 }
+
+// Interrupt mock method. It cuts the sleep of Execute short.
+func (p *Pusher) Interrupt() {
+	p.InterruptCall.TimesCalled++
+	close(p.interruptedChannel())
+}
+
+func (p *Pusher) interruptedChannel() chan struct{} {
+	p.interruptOnce.Do(func() { p.interrupted = make(chan struct{}) })
+	return p.interrupted
+}
@@ -0,0 +1,34 @@
+package mocks
+
+import (
+	"bytes"
+	"github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/request"
+)
+
+type RollbackController struct {
+	RollbackDeploymentCall struct {
+		Received struct {
+			Deployment request.PutDeploymentRequest
+			Response   *bytes.Buffer
+		}
+		Returns struct {
+			DeployResponse interfaces.DeployResponse
+		}
+		Writes string
+		Called bool
+	}
+}
+
+func (c *RollbackController) RollbackDeployment(deployment request.PutDeploymentRequest, response *bytes.Buffer) (deployResponse interfaces.DeployResponse) {
+	c.RollbackDeploymentCall.Called = true
+	c.RollbackDeploymentCall.Received.Deployment = deployment
+	c.RollbackDeploymentCall.Received.Deployment.Request = deployment.Request
+	c.RollbackDeploymentCall.Received.Response = response
+
+	if c.RollbackDeploymentCall.Writes != "" {
+		response.Write([]byte(c.RollbackDeploymentCall.Writes))
+	}
+
+	return c.RollbackDeploymentCall.Returns.DeployResponse
+}
@@ -0,0 +1,75 @@
+package mocks
+
+import I "github.com/compozed/deployadactyl/interfaces"
+
+// Scheduler handmade mock for tests.
+type Scheduler struct {
+	StartCall struct {
+		TimesCalled int
+		Returns     struct {
+			Error error
+		}
+	}
+
+	ScheduleCall struct {
+		Received struct {
+			Deployments []I.ScheduledDeployment
+		}
+		Returns struct {
+			Error error
+		}
+	}
+
+	ListCall struct {
+		Received struct {
+			Environment string
+			Username    string
+		}
+		Returns struct {
+			Deployments []I.ScheduledDeployment
+		}
+	}
+
+	CancelCall struct {
+		Received struct {
+			Environment   string
+			UUID          string
+			Authorization I.Authorization
+		}
+		Returns struct {
+			Deployment I.ScheduledDeployment
+			Error      error
+		}
+	}
+}
+
+// Start mock method.
+func (s *Scheduler) Start() error {
+	s.StartCall.TimesCalled++
+
+	return s.StartCall.Returns.Error
+}
+
+// Schedule mock method.
+func (s *Scheduler) Schedule(deployment I.ScheduledDeployment) error {
+	s.ScheduleCall.Received.Deployments = append(s.ScheduleCall.Received.Deployments, deployment)
+
+	return s.ScheduleCall.Returns.Error
+}
+
+// List mock method.
+func (s *Scheduler) List(environment, username string) []I.ScheduledDeployment {
+	s.ListCall.Received.Environment = environment
+	s.ListCall.Received.Username = username
+
+	return s.ListCall.Returns.Deployments
+}
+
+// Cancel mock method.
+func (s *Scheduler) Cancel(environment, uuid string, authorization I.Authorization) (I.ScheduledDeployment, error) {
+	s.CancelCall.Received.Environment = environment
+	s.CancelCall.Received.UUID = uuid
+	s.CancelCall.Received.Authorization = authorization
+
+	return s.CancelCall.Returns.Deployment, s.CancelCall.Returns.Error
+}
@@ -0,0 +1,28 @@
+package mocks
+
+import "fmt"
+
+// SecretStore handmade mock for tests.
+type SecretStore struct {
+	GetCall struct {
+		Received struct {
+			Environments []string
+			Names        []string
+		}
+		Returns struct {
+			Secrets map[string]string
+		}
+	}
+}
+
+// Get mock method. It returns the secret set for the name, or an error when there is none.
+func (s *SecretStore) Get(environment, name string) (string, error) {
+	s.GetCall.Received.Environments = append(s.GetCall.Received.Environments, environment)
+	s.GetCall.Received.Names = append(s.GetCall.Received.Names, name)
+
+	secret, ok := s.GetCall.Returns.Secrets[name]
+	if !ok {
+		return "", fmt.Errorf("cannot find secret %s of environment %s", name, environment)
+	}
+	return secret, nil
+}
@@ -0,0 +1,67 @@
+package redact
+
+import (
+	"io"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+)
+
+// Executor masks the known secrets in the output of every Cloud Foundry command, so that they reach
+// neither the response nor the event handlers.
+type Executor struct {
+	Executor I.Executor
+	Redactor I.Redactor
+}
+
+func (e Executor) Execute(args ...string) ([]byte, error) {
+	output, err := e.Executor.Execute(args...)
+	return e.redact(output), err
+}
+
+func (e Executor) ExecuteInDirectory(directory string, args ...string) ([]byte, error) {
+	output, err := e.Executor.ExecuteInDirectory(directory, args...)
+	return e.redact(output), err
+}
+
+func (e Executor) ExecuteWithEnvironment(environment map[string]string, args ...string) ([]byte, error) {
+	output, err := e.Executor.ExecuteWithEnvironment(environment, args...)
+	return e.redact(output), err
+}
+
+// ExecuteStreaming masks the secrets in every line before it is written. The executor writes whole
+// lines, so that a secret is never split across writes.
+func (e Executor) ExecuteStreaming(output io.Writer, directory string, args ...string) ([]byte, error) {
+	out, err := e.Executor.ExecuteStreaming(writer{output, e.Redactor}, directory, args...)
+	return e.redact(out), err
+}
+
+// Interrupt interrupts the executor it wraps when it can be interrupted.
+func (e Executor) Interrupt() {
+	if interruptible, ok := e.Executor.(I.Interruptible); ok {
+		interruptible.Interrupt()
+	}
+}
+
+func (e Executor) CleanUp() error {
+	return e.Executor.CleanUp()
+}
+
+func (e Executor) redact(output []byte) []byte {
+	if output == nil {
+		return nil
+	}
+	return []byte(e.Redactor.Redact(string(output)))
+}
+
+// writer masks the known secrets in everything written to it.
+type writer struct {
+	output   io.Writer
+	redactor I.Redactor
+}
+
+func (w writer) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.output, w.redactor.Redact(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
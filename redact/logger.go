@@ -0,0 +1,48 @@
+package redact
+
+import (
+	"fmt"
+	"strings"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+)
+
+// Logger masks the known secrets in every message before it is logged. Deployment loggers that wrap
+// it mask them as well.
+type Logger struct {
+	Log      I.Logger
+	Redactor I.Redactor
+}
+
+func (l Logger) Error(args ...interface{}) {
+	l.Log.Error(l.redact(args))
+}
+
+func (l Logger) Errorf(format string, args ...interface{}) {
+	l.Log.Errorf("%s", l.Redactor.Redact(fmt.Sprintf(format, args...)))
+}
+
+func (l Logger) Debug(args ...interface{}) {
+	l.Log.Debug(l.redact(args))
+}
+
+func (l Logger) Debugf(format string, args ...interface{}) {
+	l.Log.Debugf("%s", l.Redactor.Redact(fmt.Sprintf(format, args...)))
+}
+
+func (l Logger) Info(args ...interface{}) {
+	l.Log.Info(l.redact(args))
+}
+
+func (l Logger) Infof(format string, args ...interface{}) {
+	l.Log.Infof("%s", l.Redactor.Redact(fmt.Sprintf(format, args...)))
+}
+
+func (l Logger) Fatal(args ...interface{}) {
+	l.Log.Fatal(l.redact(args))
+}
+
+// redact joins the arguments with spaces, like the default logger does, and masks the secrets.
+func (l Logger) redact(args []interface{}) string {
+	return l.Redactor.Redact(strings.TrimSuffix(fmt.Sprintln(args...), "\n"))
+}
@@ -0,0 +1,18 @@
+package redact
+
+import I "github.com/compozed/deployadactyl/interfaces"
+
+// SecretStore makes every secret it looks up known to the redactor, so that the resolved credentials
+// of user-provided services are masked.
+type SecretStore struct {
+	Store    I.SecretStore
+	Redactor I.Redactor
+}
+
+func (s SecretStore) Get(environment, name string) (string, error) {
+	secret, err := s.Store.Get(environment, name)
+	if err == nil {
+		s.Redactor.Add(secret)
+	}
+	return secret, err
+}
@@ -14,6 +14,8 @@ type DeleteRequest struct {
 	State string                 `json:"state"`
 	Data  map[string]interface{} `json:"data"`
 	UUID  string                 `json:"uuid"`
+
+	FreezeOverride
 }
 
 type DeleteDeploymentRequest struct {
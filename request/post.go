@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"errors"
 	"github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/redact"
+	"github.com/compozed/deployadactyl/structs"
 )
 
 type PushController interface {
@@ -17,6 +19,52 @@ type PostRequest struct {
 	HealthCheckEndpoint  string                 `json:"health_check_endpoint"`
 	Data                 map[string]interface{} `json:"data"`
 	UUID                 string                 `json:"uuid"`
+
+	// SecretEnvironmentVariables names the environment variables whose values are secret. Their values
+	// are masked in the response and the logs.
+	SecretEnvironmentVariables []string `json:"secret_environment_variables"`
+
+	// Strategy overrides the deployment strategy of the environment.
+	Strategy string `json:"strategy"`
+
+	// PushStrategy overrides the push strategy of the environment.
+	PushStrategy string `json:"cf_push_strategy"`
+
+	// SmokeTests replace the smoke tests of the environment.
+	SmokeTests []structs.SmokeTest `json:"smoke_tests"`
+
+	// ServiceInstances are created when they do not exist and bound to the application.
+	ServiceInstances []structs.ServiceInstance `json:"service_instances"`
+
+	// UserProvidedServices are created or updated and bound to the application. Their credentials
+	// can reference locally stored secrets as ((name)).
+	UserProvidedServices []structs.UserProvidedService `json:"user_provided_services"`
+
+	// Retry runs a previous deployment again, against only some of its foundations.
+	Retry Retry `json:"retry"`
+
+	// Promote deploys a deployment that succeeded in another environment.
+	Promote Promotion `json:"promote"`
+
+	// PromotedFrom is the UUID of the deployment the request promotes, or that the deployment it
+	// retries promoted. It cannot be set through the API.
+	PromotedFrom string `json:"-"`
+
+	FreezeOverride
+	Schedule
+}
+
+// Secrets returns the values of the secret environment variables and the secrets in the credentials
+// of the user-provided services of the request.
+func (r PostRequest) Secrets() []string {
+	var secrets []string
+	for _, name := range r.SecretEnvironmentVariables {
+		secrets = append(secrets, r.EnvironmentVariables[name])
+	}
+	for _, service := range r.UserProvidedServices {
+		secrets = append(secrets, redact.Credentials(service.Credentials)...)
+	}
+	return secrets
 }
 
 type PostDeploymentRequest struct {
@@ -63,3 +111,29 @@ func (r PostDeploymentRequest) SetRequest(request interface{}) (interfaces.Reque
 	r.Request = postRequest
 	return r, nil
 }
+
+// FreezeOverride lets a request through to an environment that is frozen or outside of its
+// deploy windows. The justification is required and is recorded in the emitted events.
+type FreezeOverride struct {
+	OverrideFreeze bool   `json:"override_freeze"`
+	Justification  string `json:"justification"`
+}
+
+// Retry names a previous deployment by its UUID. The same artifact, manifest and environment
+// variables are pushed to the listed foundations, or to the ones the deployment failed on when
+// none are listed. Foundations can be given by name or API URL.
+type Retry struct {
+	UUID        string   `json:"uuid"`
+	Foundations []string `json:"foundations"`
+}
+
+// Promotion names an environment the application succeeded in. Its artifact, manifest and environment
+// variables are deployed again, with the environment variables, health check, smoke tests, service
+// instances and data of the request as overrides. Org and Space default to the ones deployed to.
+// The last deployment that succeeded is promoted, unless one is named by its UUID.
+type Promotion struct {
+	Environment string `json:"environment"`
+	Org         string `json:"org"`
+	Space       string `json:"space"`
+	UUID        string `json:"uuid"`
+}
@@ -14,10 +14,21 @@ type StopController interface {
 	StopDeployment(request PutDeploymentRequest, response *bytes.Buffer) (deployResponse interfaces.DeployResponse)
 }
 
+// RollbackController swaps an application back to the standby kept from its previous version.
+type RollbackController interface {
+	RollbackDeployment(request PutDeploymentRequest, response *bytes.Buffer) (deployResponse interfaces.DeployResponse)
+}
+
+// RolledBackState is the state of a put request that rolls an application back.
+const RolledBackState = "rolled-back"
+
 type PutRequest struct {
 	State string                 `json:"state"`
 	Data  map[string]interface{} `json:"data"`
 	UUID  string                 `json:"uuid"`
+
+	FreezeOverride
+	Schedule
 }
 
 type PutDeploymentRequest struct {
@@ -0,0 +1,22 @@
+package request
+
+import "time"
+
+// Schedule defers a request to a later time instead of running it right away.
+type Schedule struct {
+	// ScheduledAt is an RFC 3339 time, such as 2026-10-20T06:00:00-05:00, the request is run at.
+	ScheduledAt string `json:"scheduled_at"`
+}
+
+// GetScheduledAt returns the time the request is scheduled at, and false when it is not scheduled.
+func (s Schedule) GetScheduledAt() (time.Time, bool, error) {
+	if s.ScheduledAt == "" {
+		return time.Time{}, false, nil
+	}
+
+	scheduledAt, err := time.Parse(time.RFC3339, s.ScheduledAt)
+	if err != nil {
+		return time.Time{}, true, err
+	}
+	return scheduledAt, true, nil
+}
@@ -0,0 +1,84 @@
+package schedule
+
+import "fmt"
+
+type ScheduledDeploymentNotFoundError struct {
+	UUID string
+}
+
+func (e ScheduledDeploymentNotFoundError) Error() string {
+	return fmt.Sprintf("cannot find scheduled deployment %s", e.UUID)
+}
+
+type CancelNotAuthorizedError struct {
+	UUID string
+}
+
+func (e CancelNotAuthorizedError) Error() string {
+	return fmt.Sprintf("not authorized to cancel scheduled deployment %s: it can only be cancelled with the credentials it was scheduled with", e.UUID)
+}
+
+type DuplicateScheduledDeploymentError struct {
+	UUID string
+}
+
+func (e DuplicateScheduledDeploymentError) Error() string {
+	return fmt.Sprintf("deployment %s is already scheduled", e.UUID)
+}
+
+type UnknownKindError struct {
+	Kind string
+}
+
+func (e UnknownKindError) Error() string {
+	return fmt.Sprintf("unknown kind of scheduled deployment: %s", e.Kind)
+}
+
+type ReadScheduleError struct {
+	Path string
+	Err  error
+}
+
+func (e ReadScheduleError) Error() string {
+	return fmt.Sprintf("cannot read scheduled deployments from %s: %s", e.Path, e.Err)
+}
+
+type WriteScheduleError struct {
+	Path string
+	Err  error
+}
+
+func (e WriteScheduleError) Error() string {
+	return fmt.Sprintf("cannot write scheduled deployments to %s: %s", e.Path, e.Err)
+}
+
+type CredentialsRequiredError struct {
+	UUID string
+}
+
+func (e CredentialsRequiredError) Error() string {
+	return fmt.Sprintf("cannot schedule deployment %s: basic auth is required to schedule a deployment in an environment that authenticates", e.UUID)
+}
+
+type CredentialsLostError struct {
+	UUID        string
+	Environment string
+}
+
+func (e CredentialsLostError) Error() string {
+	return fmt.Sprintf("skipped scheduled deployment %s: environment %s authenticates and the password it was scheduled with was lost in a restart, so it has to be scheduled again", e.UUID, e.Environment)
+}
+
+type UnschedulableContentTypeError struct {
+	ContentType string
+}
+
+func (e UnschedulableContentTypeError) Error() string {
+	return fmt.Sprintf("cannot schedule a request of type %s: only application/json requests that reference their artifact by artifact_url can be scheduled", e.ContentType)
+}
+
+type MissingUUIDError struct{}
+
+func (e MissingUUIDError) Error() string {
+	return "it has no uuid"
+}
@@ -0,0 +1,384 @@
+// Package schedule runs push and state change requests at the time they were scheduled at.
+package schedule
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/request"
+	"github.com/spf13/afero"
+)
+
+// DefaultPath is the file the scheduled deployments are kept in when the config does not name one.
+const DefaultPath = "./scheduled.json"
+
+type RequestProcessorFactory func(uuid string, request interface{}, buffer *bytes.Buffer) I.RequestProcessor
+
+type SchedulerConstructor func(fileSystem *afero.Afero, path string, log I.Logger, requestProcessorFactory RequestProcessorFactory) I.Scheduler
+
+func NewScheduler(fileSystem *afero.Afero, path string, log I.Logger, requestProcessorFactory RequestProcessorFactory) I.Scheduler {
+	if path == "" {
+		path = DefaultPath
+	}
+
+	return &Scheduler{
+		FileSystem:              fileSystem,
+		Path:                    path,
+		Log:                     log,
+		RequestProcessorFactory: requestProcessorFactory,
+	}
+}
+
+// Scheduler keeps the scheduled deployments in a file so that they survive a restart, and runs each
+// of them through the request processor of its kind when it is due. Deployments that became due
+// while the server was down are run as soon as it starts.
+//
+// Only requests that reference their artifact by URL can be scheduled. The file keeps the request
+// and the username it was scheduled by, with a salted hash of the password to check cancellations
+// against; the password itself is only kept in memory. A deployment restored after a restart runs
+// with the configured credentials, or, in an environment that authenticates, is not run at all.
+type Scheduler struct {
+	FileSystem              *afero.Afero
+	Path                    string
+	Log                     I.Logger
+	RequestProcessorFactory RequestProcessorFactory
+
+	lock        sync.Mutex
+	deployments map[string]I.ScheduledDeployment
+	credentials map[string]credential
+	timers      map[string]*time.Timer
+}
+
+// storedDeployment is a scheduled deployment as it is kept in the file, along with the request and
+// the credential that are not listed.
+type storedDeployment struct {
+	I.ScheduledDeployment
+	Credential credential      `json:"credential"`
+	Request    json.RawMessage `json:"request"`
+}
+
+// credential is the user a deployment was scheduled by, and a salted hash of their password.
+type credential struct {
+	Username string `json:"username"`
+	Salt     string `json:"salt"`
+	Hash     string `json:"hash"`
+}
+
+func newCredential(authorization I.Authorization) (credential, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return credential{}, err
+	}
+
+	c := credential{Username: authorization.Username, Salt: hex.EncodeToString(salt)}
+	c.Hash = c.hash(authorization.Password)
+	return c, nil
+}
+
+func (c credential) hash(password string) string {
+	sum := sha256.Sum256([]byte(c.Salt + password))
+	return hex.EncodeToString(sum[:])
+}
+
+// matches returns true when the authorization is the one the credential was made from.
+func (c credential) matches(authorization I.Authorization) bool {
+	return c.Username != "" &&
+		c.Username == authorization.Username &&
+		subtle.ConstantTimeCompare([]byte(c.Hash), []byte(c.hash(authorization.Password))) == 1
+}
+
+// Start reads the scheduled deployments from the file and sets them to run when they are due.
+//
+// A file that cannot be read does not keep the server from starting: the error is logged and, when
+// the file is not valid JSON, it is moved aside to <path>.invalid. Entries that cannot be restored
+// are logged and skipped.
+func (s *Scheduler) Start() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.deployments = map[string]I.ScheduledDeployment{}
+	s.credentials = map[string]credential{}
+	s.timers = map[string]*time.Timer{}
+
+	exists, err := s.FileSystem.Exists(s.Path)
+	if err != nil {
+		s.Log.Error(ReadScheduleError{s.Path, err})
+		return nil
+	}
+	if !exists {
+		return nil
+	}
+
+	content, err := s.FileSystem.ReadFile(s.Path)
+	if err != nil {
+		s.Log.Error(ReadScheduleError{s.Path, err})
+		return nil
+	}
+
+	var entries []json.RawMessage
+	err = json.Unmarshal(content, &entries)
+	if err != nil {
+		s.Log.Error(ReadScheduleError{s.Path, err})
+		if err = s.FileSystem.Rename(s.Path, s.Path+".invalid"); err != nil {
+			s.Log.Error(err)
+		} else {
+			s.Log.Errorf("moved %s to %s.invalid", s.Path, s.Path)
+		}
+		return nil
+	}
+
+	for i, entry := range entries {
+		var stored storedDeployment
+		err = json.Unmarshal(entry, &stored)
+		if err == nil && stored.UUID == "" {
+			err = MissingUUIDError{}
+		}
+		if err != nil {
+			s.Log.Errorf("skipped scheduled deployment %d of %s: %s", i, s.Path, err)
+			continue
+		}
+
+		deployment := stored.ScheduledDeployment
+		deployment.Body = stored.Request
+		deployment.NeedsCredentials = deployment.Authenticate
+
+		s.deployments[deployment.UUID] = deployment
+		s.credentials[deployment.UUID] = stored.Credential
+		s.arm(deployment)
+
+		if deployment.NeedsCredentials {
+			s.Log.Errorf("restored scheduled %s %s of %s: it will not run, since environment %s authenticates and the password it was scheduled with is not kept", deployment.Kind, deployment.UUID, deployment.CFContext.Application, deployment.CFContext.Environment)
+		} else {
+			s.Log.Infof("restored scheduled %s %s of %s: it runs with the configured credentials", deployment.Kind, deployment.UUID, deployment.CFContext.Application)
+		}
+	}
+
+	s.Log.Infof("restored %d scheduled deployments from %s", len(s.deployments), s.Path)
+
+	return nil
+}
+
+// Schedule keeps the deployment and sets it to run when it is due.
+//
+// Returns a CredentialsRequiredError when it has no username in an environment that authenticates,
+// an UnschedulableContentTypeError when its request is not JSON, and a
+// DuplicateScheduledDeploymentError when a deployment with its UUID is already scheduled.
+func (s *Scheduler) Schedule(deployment I.ScheduledDeployment) error {
+	if deployment.Kind != I.ScheduledPush && deployment.Kind != I.ScheduledStateChange {
+		return UnknownKindError{deployment.Kind}
+	}
+	if deployment.Authenticate && deployment.Authorization.Username == "" {
+		return CredentialsRequiredError{deployment.UUID}
+	}
+	if deployment.ContentType != "application/json" {
+		return UnschedulableContentTypeError{deployment.ContentType}
+	}
+
+	storedCredential, err := newCredential(deployment.Authorization)
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.deployments == nil {
+		s.deployments = map[string]I.ScheduledDeployment{}
+		s.credentials = map[string]credential{}
+		s.timers = map[string]*time.Timer{}
+	}
+
+	if _, ok := s.deployments[deployment.UUID]; ok {
+		return DuplicateScheduledDeploymentError{deployment.UUID}
+	}
+
+	s.deployments[deployment.UUID] = deployment
+	s.credentials[deployment.UUID] = storedCredential
+	err = s.save()
+	if err != nil {
+		delete(s.deployments, deployment.UUID)
+		delete(s.credentials, deployment.UUID)
+		return err
+	}
+
+	s.arm(deployment)
+
+	s.Log.Infof("scheduled %s %s of %s at %s", deployment.Kind, deployment.UUID, deployment.CFContext.Application, deployment.ScheduledAt.Format(time.RFC3339))
+
+	return nil
+}
+
+// List returns the deployments that the user scheduled in the environment and that have not run
+// yet, the next one first.
+func (s *Scheduler) List(environment, username string) []I.ScheduledDeployment {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	deployments := []I.ScheduledDeployment{}
+	for uuid, deployment := range s.deployments {
+		if deployment.CFContext.Environment == environment && s.credentials[uuid].Username == username {
+			deployments = append(deployments, deployment)
+		}
+	}
+
+	sort.Slice(deployments, func(i, j int) bool {
+		return deployments[i].ScheduledAt.Before(deployments[j].ScheduledAt)
+	})
+	return deployments
+}
+
+// Cancel removes the deployment with the given UUID from the environment so that it does not run.
+//
+// Returns a ScheduledDeploymentNotFoundError when it is not scheduled in the environment, or has
+// already run, and a CancelNotAuthorizedError when it was scheduled with other credentials.
+func (s *Scheduler) Cancel(environment, uuid string, authorization I.Authorization) (I.ScheduledDeployment, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	deployment, ok := s.deployments[uuid]
+	if !ok || deployment.CFContext.Environment != environment {
+		return I.ScheduledDeployment{}, ScheduledDeploymentNotFoundError{uuid}
+	}
+
+	if !s.credentials[uuid].matches(authorization) {
+		return I.ScheduledDeployment{}, CancelNotAuthorizedError{uuid}
+	}
+
+	if timer, ok := s.timers[uuid]; ok {
+		timer.Stop()
+		delete(s.timers, uuid)
+	}
+	delete(s.deployments, uuid)
+	delete(s.credentials, uuid)
+
+	s.Log.Infof("cancelled scheduled %s %s of %s", deployment.Kind, uuid, deployment.CFContext.Application)
+
+	return deployment, s.save()
+}
+
+// arm sets the deployment to run when it is due. It must be called with the lock held.
+func (s *Scheduler) arm(deployment I.ScheduledDeployment) {
+	wait := time.Until(deployment.ScheduledAt)
+	if wait < 0 {
+		wait = 0
+	}
+
+	s.timers[deployment.UUID] = time.AfterFunc(wait, func() { s.run(deployment.UUID) })
+}
+
+// run removes the deployment from the schedule and processes it like a request made at that time.
+func (s *Scheduler) run(uuid string) {
+	s.lock.Lock()
+	deployment, ok := s.deployments[uuid]
+	if ok {
+		delete(s.deployments, uuid)
+		delete(s.credentials, uuid)
+		delete(s.timers, uuid)
+		if err := s.save(); err != nil {
+			s.Log.Error(err)
+		}
+	}
+	s.lock.Unlock()
+
+	if !ok {
+		return
+	}
+
+	log := I.DeploymentLogger{Log: s.Log, UUID: uuid}
+	if deployment.NeedsCredentials {
+		log.Error(CredentialsLostError{uuid, deployment.CFContext.Environment})
+		return
+	}
+	log.Infof("running scheduled %s of %s", deployment.Kind, deployment.CFContext.Application)
+
+	descriptor, err := requestDescriptor(deployment)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	response := &bytes.Buffer{}
+	deployResponse := s.RequestProcessorFactory(uuid, descriptor, response).Process()
+
+	log.Debugf("output of scheduled %s:\n%s", deployment.Kind, response.String())
+	if deployResponse.Error != nil {
+		log.Errorf("scheduled %s finished with status %d: %s", deployment.Kind, deployResponse.StatusCode, deployResponse.Error)
+		return
+	}
+	log.Infof("scheduled %s finished with status %d", deployment.Kind, deployResponse.StatusCode)
+}
+
+// save writes the scheduled deployments to a temporary file and moves it over the file, so that the
+// file is never left half written. It must be called with the lock held.
+func (s *Scheduler) save() error {
+	stored := make([]storedDeployment, 0, len(s.deployments))
+	for uuid, deployment := range s.deployments {
+		stored = append(stored, storedDeployment{deployment, s.credentials[uuid], deployment.Body})
+	}
+
+	sort.Slice(stored, func(i, j int) bool {
+		return stored[i].ScheduledAt.Before(stored[j].ScheduledAt)
+	})
+
+	content, err := json.Marshal(stored)
+	if err != nil {
+		return WriteScheduleError{s.Path, err}
+	}
+
+	temp := s.Path + ".tmp"
+	err = s.FileSystem.WriteFile(temp, content, os.FileMode(0600))
+	if err != nil {
+		return WriteScheduleError{s.Path, err}
+	}
+
+	err = s.FileSystem.Rename(temp, s.Path)
+	if err != nil {
+		s.FileSystem.Remove(temp)
+		return WriteScheduleError{s.Path, err}
+	}
+	return nil
+}
+
+// requestDescriptor builds the request the deployment was scheduled with, as the controller would.
+func requestDescriptor(deployment I.ScheduledDeployment) (I.RequestDescriptor, error) {
+	body := deployment.Body
+
+	base := I.Deployment{
+		Body:          &body,
+		Type:          deployment.ContentType,
+		Authorization: deployment.Authorization,
+		CFContext:     deployment.CFContext,
+	}
+
+	switch deployment.Kind {
+	case I.ScheduledPush:
+		postRequest := request.PostRequest{}
+		if err := json.Unmarshal(body, &postRequest); err != nil {
+			return nil, err
+		}
+		postRequest.UUID = deployment.UUID
+		postRequest.ScheduledAt = ""
+
+		return request.PostDeploymentRequest{Deployment: base, Request: postRequest}, nil
+	case I.ScheduledStateChange:
+		putRequest := request.PutRequest{}
+		if err := json.Unmarshal(body, &putRequest); err != nil {
+			return nil, err
+		}
+		putRequest.UUID = deployment.UUID
+		putRequest.ScheduledAt = ""
+
+		return request.PutDeploymentRequest{Deployment: base, Request: putRequest}, nil
+	}
+
+	return nil, UnknownKindError{deployment.Kind}
+}
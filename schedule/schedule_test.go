@@ -0,0 +1,351 @@
+package schedule_test
+
+import (
+	"bytes"
+	"os"
+	"sync"
+	"time"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/config"
+	"github.com/compozed/deployadactyl/mocks"
+	"github.com/compozed/deployadactyl/request"
+	. "github.com/compozed/deployadactyl/schedule"
+	"github.com/compozed/deployadactyl/state"
+	S "github.com/compozed/deployadactyl/structs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	"github.com/op/go-logging"
+	"github.com/spf13/afero"
+)
+
+var _ = Describe("Scheduler", func() {
+	var (
+		logBuffer = NewBuffer()
+		log       = I.DefaultLogger(logBuffer, logging.DEBUG, "schedule_test")
+
+		fileSystem       *afero.Afero
+		requestProcessor *mocks.RequestProcessor
+		scheduler        I.Scheduler
+
+		lock             sync.Mutex
+		receivedUUIDs    []string
+		receivedRequests []interface{}
+
+		cfContext     I.CFContext
+		authorization I.Authorization
+	)
+
+	newScheduler := func() I.Scheduler {
+		factory := func(uuid string, request interface{}, response *bytes.Buffer) I.RequestProcessor {
+			lock.Lock()
+			defer lock.Unlock()
+
+			receivedUUIDs = append(receivedUUIDs, uuid)
+			receivedRequests = append(receivedRequests, request)
+			return requestProcessor
+		}
+		return NewScheduler(fileSystem, "/scheduled.json", log, factory)
+	}
+
+	scheduled := func(uuid string, scheduledAt time.Time) I.ScheduledDeployment {
+		return I.ScheduledDeployment{
+			UUID:          uuid,
+			Kind:          I.ScheduledPush,
+			ScheduledAt:   scheduledAt,
+			CFContext:     cfContext,
+			Authorization: authorization,
+			ContentType:   "application/json",
+			Body:          []byte(`{"artifact_url": "https://example.com/app.jar"}`),
+		}
+	}
+
+	processed := func() []string {
+		lock.Lock()
+		defer lock.Unlock()
+
+		return append([]string{}, receivedUUIDs...)
+	}
+
+	BeforeEach(func() {
+		fileSystem = &afero.Afero{Fs: afero.NewMemMapFs()}
+		requestProcessor = &mocks.RequestProcessor{}
+		requestProcessor.ProcessCall.Returns.Response = I.DeployResponse{StatusCode: 200}
+		receivedUUIDs = nil
+		receivedRequests = nil
+
+		cfContext = I.CFContext{Environment: "production", Organization: "org", Space: "space", Application: "app"}
+		authorization = I.Authorization{Username: "deployer", Password: "secret"}
+
+		scheduler = newScheduler()
+		Expect(scheduler.Start()).To(Succeed())
+	})
+
+	It("runs a push through the request processor when it is due", func() {
+		body := []byte(`{"artifact_url": "https://example.com/app.jar", "scheduled_at": "2026-10-20T06:00:00Z"}`)
+
+		Expect(scheduler.Schedule(I.ScheduledDeployment{
+			UUID:          "release",
+			Kind:          I.ScheduledPush,
+			ScheduledAt:   time.Now().Add(200 * time.Millisecond),
+			CFContext:     cfContext,
+			Authorization: authorization,
+			ContentType:   "application/json",
+			Body:          body,
+		})).To(Succeed())
+
+		Expect(processed()).To(BeEmpty())
+		Eventually(processed).Should(Equal([]string{"release"}))
+
+		Expect(receivedRequests[0]).To(Equal(request.PostDeploymentRequest{
+			Deployment: I.Deployment{
+				Body:          &body,
+				Type:          "application/json",
+				Authorization: authorization,
+				CFContext:     cfContext,
+			},
+			Request: request.PostRequest{ArtifactUrl: "https://example.com/app.jar", UUID: "release"},
+		}))
+		Eventually(func() int { return len(scheduler.List("production", "deployer")) }).Should(Equal(0))
+		Eventually(logBuffer).Should(Say("scheduled push finished with status 200"))
+	})
+
+	It("runs a state change through the request processor when it is due", func() {
+		Expect(scheduler.Schedule(I.ScheduledDeployment{
+			UUID:          "stop",
+			Kind:          I.ScheduledStateChange,
+			ScheduledAt:   time.Now().Add(10 * time.Millisecond),
+			CFContext:     cfContext,
+			Authorization: authorization,
+			ContentType:   "application/json",
+			Body:          []byte(`{"state": "stopped"}`),
+		})).To(Succeed())
+
+		Eventually(processed).Should(Equal([]string{"stop"}))
+		Expect(receivedRequests[0].(request.PutDeploymentRequest).Request.State).To(Equal("stopped"))
+	})
+
+	It("lists the deployments the user scheduled in the environment, the next one first", func() {
+		Expect(scheduler.Schedule(scheduled("later", time.Now().Add(2*time.Hour)))).To(Succeed())
+		Expect(scheduler.Schedule(scheduled("sooner", time.Now().Add(time.Hour)))).To(Succeed())
+
+		elsewhere := scheduled("elsewhere", time.Now().Add(time.Hour))
+		elsewhere.CFContext.Environment = "staging"
+		Expect(scheduler.Schedule(elsewhere)).To(Succeed())
+
+		someoneElse := scheduled("someone-else", time.Now().Add(time.Hour))
+		someoneElse.Authorization = I.Authorization{Username: "someone", Password: "else"}
+		Expect(scheduler.Schedule(someoneElse)).To(Succeed())
+
+		deployments := scheduler.List("production", "deployer")
+		Expect(deployments).To(HaveLen(2))
+		Expect(deployments[0].UUID).To(Equal("sooner"))
+		Expect(deployments[1].UUID).To(Equal("later"))
+	})
+
+	It("refuses to schedule the same deployment twice", func() {
+		deployment := scheduled("release", time.Now().Add(time.Hour))
+
+		Expect(scheduler.Schedule(deployment)).To(Succeed())
+		Expect(scheduler.Schedule(deployment)).To(MatchError(DuplicateScheduledDeploymentError{"release"}))
+	})
+
+	It("refuses to schedule a deployment without credentials in an environment that authenticates", func() {
+		deployment := scheduled("release", time.Now().Add(time.Hour))
+		deployment.Authorization = I.Authorization{}
+		deployment.Authenticate = true
+
+		Expect(scheduler.Schedule(deployment)).To(MatchError(CredentialsRequiredError{"release"}))
+		Expect(fileSystem.Exists("/scheduled.json")).To(BeFalse())
+	})
+
+	It("schedules a deployment without credentials in an environment that does not authenticate", func() {
+		deployment := scheduled("release", time.Now().Add(50*time.Millisecond))
+		deployment.Authorization = I.Authorization{}
+
+		Expect(scheduler.Schedule(deployment)).To(Succeed())
+
+		Eventually(processed).Should(Equal([]string{"release"}))
+	})
+
+	It("refuses to schedule a request that carries its artifact", func() {
+		deployment := scheduled("release", time.Now().Add(time.Hour))
+		deployment.ContentType = "application/zip"
+		deployment.Body = []byte("artifact")
+
+		Expect(scheduler.Schedule(deployment)).To(MatchError(UnschedulableContentTypeError{"application/zip"}))
+	})
+
+	Describe("cancelling", func() {
+		BeforeEach(func() {
+			Expect(scheduler.Schedule(scheduled("release", time.Now().Add(50*time.Millisecond)))).To(Succeed())
+		})
+
+		It("keeps the deployment from running", func() {
+			deployment, err := scheduler.Cancel("production", "release", authorization)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deployment.UUID).To(Equal("release"))
+
+			Consistently(processed, 100*time.Millisecond).Should(BeEmpty())
+			Expect(scheduler.List("production", "deployer")).To(BeEmpty())
+		})
+
+		It("requires the credentials the deployment was scheduled with", func() {
+			_, err := scheduler.Cancel("production", "release", I.Authorization{Username: "someone", Password: "else"})
+			Expect(err).To(MatchError(CancelNotAuthorizedError{"release"}))
+
+			_, err = scheduler.Cancel("production", "release", I.Authorization{Username: "deployer", Password: "guess"})
+			Expect(err).To(MatchError(CancelNotAuthorizedError{"release"}))
+
+			Expect(scheduler.List("production", "deployer")).To(HaveLen(1))
+		})
+
+		It("returns an error for a deployment that is not scheduled in the environment", func() {
+			_, err := scheduler.Cancel("staging", "release", authorization)
+
+			Expect(err).To(MatchError(ScheduledDeploymentNotFoundError{"release"}))
+			Expect(scheduler.List("production", "deployer")).To(HaveLen(1))
+		})
+
+		It("returns an error for a deployment that is not scheduled", func() {
+			_, err := scheduler.Cancel("production", "unknown", authorization)
+
+			Expect(err).To(MatchError(ScheduledDeploymentNotFoundError{"unknown"}))
+		})
+	})
+
+	Describe("restarting", func() {
+		It("keeps the scheduled deployments in a file only its owner can read", func() {
+			Expect(scheduler.Schedule(scheduled("release", time.Now().Add(time.Hour)))).To(Succeed())
+
+			info, err := fileSystem.Stat("/scheduled.json")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+			Expect(fileSystem.Exists("/scheduled.json.tmp")).To(BeFalse())
+		})
+
+		It("does not keep the password in the file", func() {
+			Expect(scheduler.Schedule(scheduled("release", time.Now().Add(time.Hour)))).To(Succeed())
+
+			content, err := fileSystem.ReadFile("/scheduled.json")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(content)).To(ContainSubstring("deployer"))
+			Expect(string(content)).ToNot(ContainSubstring("secret"))
+		})
+
+		It("restores the scheduled deployments", func() {
+			deployment := scheduled("release", time.Now().Add(time.Hour))
+			Expect(scheduler.Schedule(deployment)).To(Succeed())
+
+			restarted := newScheduler()
+			Expect(restarted.Start()).To(Succeed())
+
+			deployments := restarted.List("production", "deployer")
+			Expect(deployments).To(HaveLen(1))
+			Expect(deployments[0].CFContext).To(Equal(cfContext))
+			Expect(deployments[0].Authorization).To(Equal(I.Authorization{}))
+			Expect(deployments[0].NeedsCredentials).To(BeFalse())
+			Expect(deployments[0].Body).To(MatchJSON(deployment.Body))
+
+			_, err := restarted.Cancel("production", "release", I.Authorization{Username: "deployer", Password: "guess"})
+			Expect(err).To(MatchError(CancelNotAuthorizedError{"release"}))
+			_, err = restarted.Cancel("production", "release", authorization)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("runs the deployments that became due while it was down", func() {
+			Expect(fileSystem.WriteFile("/scheduled.json", []byte(`[{
+				"uuid": "missed",
+				"kind": "push",
+				"scheduled_at": "2026-01-01T06:00:00Z",
+				"content_type": "application/json",
+				"credential": {"username": "deployer"},
+				"request": {"artifact_url": "https://example.com/app.jar"}
+			}]`), 0600)).To(Succeed())
+
+			restarted := newScheduler()
+			Expect(restarted.Start()).To(Succeed())
+
+			Eventually(processed).Should(Equal([]string{"missed"}))
+			Expect(receivedRequests[0].(request.PostDeploymentRequest).Request.ArtifactUrl).To(Equal("https://example.com/app.jar"))
+		})
+
+		It("runs a restored deployment with the configured credentials", func() {
+			Expect(fileSystem.WriteFile("/scheduled.json", []byte(`[{
+				"uuid": "release",
+				"kind": "push",
+				"scheduled_at": "2026-01-01T06:00:00Z",
+				"cf_context": {"environment": "production"},
+				"content_type": "application/json",
+				"authenticate": false,
+				"credential": {"username": "deployer"},
+				"request": {"artifact_url": "https://example.com/app.jar"}
+			}]`), 0600)).To(Succeed())
+
+			restarted := newScheduler()
+			Expect(restarted.Start()).To(Succeed())
+
+			Eventually(processed).Should(ContainElement("release"))
+
+			lock.Lock()
+			descriptor := receivedRequests[len(receivedRequests)-1].(request.PostDeploymentRequest)
+			lock.Unlock()
+
+			resolver := state.NewAuthResolver(config.Config{Username: "cf-user", Password: "cf-password"})
+			resolved, err := resolver.Resolve(descriptor.Deployment.Authorization, S.Environment{Name: "production"}, I.DeploymentLogger{Log: log})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resolved).To(Equal(I.Authorization{Username: "cf-user", Password: "cf-password"}))
+		})
+
+		It("does not run a restored deployment of an environment that authenticates", func() {
+			Expect(fileSystem.WriteFile("/scheduled.json", []byte(`[{
+				"uuid": "missed",
+				"kind": "push",
+				"scheduled_at": "2026-01-01T06:00:00Z",
+				"cf_context": {"environment": "production"},
+				"content_type": "application/json",
+				"authenticate": true,
+				"credential": {"username": "deployer"},
+				"request": {"artifact_url": "https://example.com/app.jar"}
+			}]`), 0600)).To(Succeed())
+
+			restarted := newScheduler()
+			Expect(restarted.Start()).To(Succeed())
+
+			Consistently(processed, 100*time.Millisecond).ShouldNot(ContainElement("missed"))
+			Eventually(logBuffer).Should(Say("skipped scheduled deployment missed: environment production authenticates"))
+			Expect(restarted.List("production", "deployer")).To(BeEmpty())
+		})
+
+		It("skips the entries that cannot be restored", func() {
+			Expect(fileSystem.WriteFile("/scheduled.json", []byte(`[
+				{"uuid": "later", "kind": "push", "scheduled_at": "2999-01-01T06:00:00Z", "cf_context": {"environment": "production"}, "credential": {"username": "deployer"}, "request": {}},
+				{"uuid": "broken", "scheduled_at": "tomorrow"},
+				{"kind": "push"}
+			]`), 0600)).To(Succeed())
+
+			restarted := newScheduler()
+			Expect(restarted.Start()).To(Succeed())
+
+			deployments := restarted.List("production", "deployer")
+			Expect(deployments).To(HaveLen(1))
+			Expect(deployments[0].UUID).To(Equal("later"))
+			Eventually(logBuffer).Should(Say("skipped scheduled deployment 1 of /scheduled.json"))
+		})
+
+		It("starts without the deployments of a file that cannot be read, and moves the file aside", func() {
+			Expect(fileSystem.WriteFile("/scheduled.json", []byte(`[{`), 0600)).To(Succeed())
+
+			restarted := newScheduler()
+			Expect(restarted.Start()).To(Succeed())
+
+			Expect(restarted.List("production", "deployer")).To(BeEmpty())
+			Eventually(logBuffer).Should(Say("cannot read scheduled deployments from /scheduled.json"))
+
+			content, err := fileSystem.ReadFile("/scheduled.json.invalid")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(content)).To(Equal(`[{`))
+		})
+	})
+})
@@ -0,0 +1,28 @@
+package secrets
+
+import "fmt"
+
+type SecretNotFoundError struct {
+	Environment string
+	Name        string
+}
+
+func (e SecretNotFoundError) Error() string {
+	return fmt.Sprintf("cannot find secret %s of environment %s", e.Name, e.Environment)
+}
+
+type InvalidSecretNameError struct {
+	Name string
+}
+
+func (e InvalidSecretNameError) Error() string {
+	return fmt.Sprintf("invalid secret name: %s", e.Name)
+}
+
+type NoSecretStoreError struct {
+	Name string
+}
+
+func (e NoSecretStoreError) Error() string {
+	return fmt.Sprintf("cannot resolve secret %s: no secrets directory is configured", e.Name)
+}
@@ -0,0 +1,138 @@
+// Package secrets resolves the references to locally stored secrets in the credentials of user-provided services.
+package secrets
+
+import (
+	"path"
+	"regexp"
+	"strings"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+	S "github.com/compozed/deployadactyl/structs"
+	"github.com/spf13/afero"
+)
+
+var reference = regexp.MustCompile(`\(\(\s*([^()\s]+)\s*\)\)`)
+
+type SecretStoreConstructor func(fileSystem *afero.Afero, directory string) I.SecretStore
+
+func NewSecretStore(fileSystem *afero.Afero, directory string) I.SecretStore {
+	return FileStore{
+		FileSystem: fileSystem,
+		Directory:  directory,
+	}
+}
+
+// FileStore keeps every secret in a file of its own name in a subdirectory of Directory named after
+// its environment, such as one mounted from a credential manager. Trailing newlines are not part of
+// the secret.
+type FileStore struct {
+	FileSystem *afero.Afero
+	Directory  string
+}
+
+// Get returns the secret of the environment with the given name.
+//
+// Returns a SecretNotFoundError when the environment has no such secret.
+func (s FileStore) Get(environment, name string) (string, error) {
+	if s.Directory == "" {
+		return "", NoSecretStoreError{name}
+	}
+
+	if !validName(environment) {
+		return "", InvalidSecretNameError{environment}
+	}
+	if !validName(name) {
+		return "", InvalidSecretNameError{name}
+	}
+
+	secret, err := s.FileSystem.ReadFile(path.Join(s.Directory, environment, name))
+	if err != nil {
+		return "", SecretNotFoundError{environment, name}
+	}
+
+	return strings.TrimRight(string(secret), "\r\n"), nil
+}
+
+// validName returns false for names that are empty or would leave the directory they are looked up in.
+func validName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\`)
+}
+
+// Resolve returns the user-provided service with every ((name)) in the strings of its credentials
+// replaced by the secret of the environment with that name. The service it is given is left as it is.
+func Resolve(store I.SecretStore, environment string, service S.UserProvidedService) (S.UserProvidedService, error) {
+	if len(service.Credentials) == 0 {
+		return service, nil
+	}
+
+	credentials, err := resolveValue(store, environment, service.Credentials)
+	if err != nil {
+		return service, err
+	}
+
+	service.Credentials = credentials.(map[string]interface{})
+	return service, nil
+}
+
+func resolveValue(store I.SecretStore, environment string, value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		return resolveString(store, environment, v)
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			resolved, err := resolveValue(store, environment, item)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = resolved
+		}
+		return m, nil
+	case map[interface{}]interface{}:
+		m := make(map[interface{}]interface{}, len(v))
+		for key, item := range v {
+			resolved, err := resolveValue(store, environment, item)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = resolved
+		}
+		return m, nil
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, item := range v {
+			resolved, err := resolveValue(store, environment, item)
+			if err != nil {
+				return nil, err
+			}
+			s[i] = resolved
+		}
+		return s, nil
+	}
+	return value, nil
+}
+
+func resolveString(store I.SecretStore, environment, value string) (string, error) {
+	var resolveErr error
+
+	resolved := reference.ReplaceAllStringFunc(value, func(match string) string {
+		if resolveErr != nil {
+			return match
+		}
+
+		name := reference.FindStringSubmatch(match)[1]
+		if store == nil {
+			resolveErr = NoSecretStoreError{name}
+			return match
+		}
+
+		secret, err := store.Get(environment, name)
+		if err != nil {
+			resolveErr = err
+			return match
+		}
+		return secret
+	})
+
+	return resolved, resolveErr
+}
@@ -2,10 +2,12 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 
+	"github.com/compozed/deployadactyl/config"
 	"github.com/compozed/deployadactyl/creator"
 	"github.com/compozed/deployadactyl/interfaces"
 	"github.com/compozed/deployadactyl/state/push"
@@ -22,9 +24,14 @@ func main() {
 	var (
 		config               = flag.String("config", defaultConfigFilePath, "location of the config file")
 		envVarHandlerEnabled = flag.Bool("env", false, "enable environment variable handling")
+		validate             = flag.Bool("validate", false, "validate the config file and exit without starting the server")
 	)
 	flag.Parse()
 
+	if *validate {
+		os.Exit(validateConfig(*config))
+	}
+
 	level := os.Getenv(logLevelEnvVarName)
 	if level == "" {
 		level = defaultLogLevel
@@ -51,6 +58,14 @@ func main() {
 		eventBindings.AddBinding(push.NewArtifactRetrievalSuccessEventBinding(envVarHandler.ArtifactRetrievalSuccessEventHandler))
 	}
 
+	err = c.CreateScheduler().Start()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	c.CreateJanitor().Start()
+	c.CreateTempFileSweeper().Start()
+
 	l := c.CreateListener()
 	controller := c.CreateController()
 
@@ -63,3 +78,22 @@ func main() {
 		log.Fatal(err)
 	}
 }
+
+func validateConfig(configPath string) int {
+	problems, err := config.Validate(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cannot read %s: %s\n", configPath, err)
+		return 1
+	}
+
+	if len(problems) == 0 {
+		fmt.Printf("%s is valid\n", configPath)
+		return 0
+	}
+
+	for _, problem := range problems {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", configPath, problem)
+	}
+	fmt.Fprintf(os.Stderr, "%s has %d problem(s)\n", configPath, len(problems))
+	return 1
+}
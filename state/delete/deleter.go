@@ -7,17 +7,19 @@ import (
 
 	I "github.com/compozed/deployadactyl/interfaces"
 	"github.com/compozed/deployadactyl/state"
+	S "github.com/compozed/deployadactyl/structs"
 )
 
 type Deleter struct {
-	Courier       I.Courier
-	CFContext     I.CFContext
-	Authorization I.Authorization
-	EventManager  I.EventManager
-	Response      io.ReadWriter
-	Log           I.DeploymentLogger
-	FoundationURL string
-	AppName       string
+	Courier        I.Courier
+	CFContext      I.CFContext
+	Authorization  I.Authorization
+	EventManager   I.EventManager
+	Response       io.ReadWriter
+	Log            I.DeploymentLogger
+	FoundationURL  string
+	FoundationName string
+	AppName        string
 }
 
 func (s Deleter) Verify() error {
@@ -28,6 +30,11 @@ func (s Deleter) Success() error {
 	return nil
 }
 
+// Interrupt kills the Cloud Foundry commands of a phase that ran out of time.
+func (s Deleter) Interrupt() {
+	state.Interrupt(s.Courier)
+}
+
 func (s Deleter) Finally() error {
 	return nil
 }
@@ -54,6 +61,9 @@ func (s Deleter) Initially() error {
 	s.Response.Write(output)
 	if err != nil {
 		s.Log.Errorf("could not login to %s", s.FoundationURL)
+		if timeout, ok := err.(I.TimeoutError); ok && timeout.Timeout() {
+			return err
+		}
 		return state.LoginError{s.FoundationURL, output}
 	}
 
@@ -65,20 +75,20 @@ func (s Deleter) Initially() error {
 func (s Deleter) Execute() error {
 
 	if s.Courier.Exists(s.AppName) != true {
-		s.Log.Errorf("failed to delete app on foundation %s: application doesn't exist", s.FoundationURL)
+		s.Log.Errorf("failed to delete app on foundation %s: application doesn't exist", s.foundationName())
 		return state.ExistsError{ApplicationName: s.AppName}
 	}
 
-	s.Log.Infof("%s: deleting app %s", s.FoundationURL, s.AppName)
+	s.Log.Infof("%s: deleting app %s", s.foundationName(), s.AppName)
 
 	output, err := s.Courier.Delete(s.AppName)
 	if err != nil {
-		s.Log.Errorf("failed to delete app on foundation %s: %s", s.FoundationURL, err.Error())
+		s.Log.Errorf("failed to delete app on foundation %s: %s", s.foundationName(), err.Error())
 		return state.DeleteError{ApplicationName: s.AppName, Out: output}
 	}
 	s.Response.Write(output)
 
-	s.Log.Infof("%s: successfully deleted app %s", s.FoundationURL, s.AppName)
+	s.Log.Infof("%s: successfully deleted app %s", s.foundationName(), s.AppName)
 
 	return nil
 }
@@ -89,7 +99,11 @@ func (s Deleter) PostExecute() error {
 
 func (s Deleter) Undo() error {
 	s.Response.Write([]byte(fmt.Sprintf("delete feature is unable to rollback: %s", s.AppName)))
-	s.Log.Infof("%s: delete feature is unable to rollback: %s", s.FoundationURL, s.AppName)
+	s.Log.Infof("%s: delete feature is unable to rollback: %s", s.foundationName(), s.AppName)
 
 	return nil
 }
+
+func (s Deleter) foundationName() string {
+	return S.FoundationName(s.FoundationName, s.FoundationURL)
+}
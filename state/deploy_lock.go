@@ -0,0 +1,69 @@
+package state
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/compozed/deployadactyl/deploywindow"
+	"github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/request"
+	"github.com/compozed/deployadactyl/structs"
+)
+
+// DeployLockChecker refuses requests to environments that are frozen or outside of their deploy windows.
+type DeployLockChecker struct {
+	EventManager interfaces.EventManager
+	Log          interfaces.DeploymentLogger
+
+	// Now defaults to time.Now.
+	Now func() time.Time
+}
+
+// Check returns an error and the status code to respond with when a request to environment has to be refused.
+//
+// A locked environment refuses requests with 423 Locked unless the request overrides the lock with a
+// justification, in which case a DeployLockOverriddenEvent is emitted and the request is let through.
+func (c DeployLockChecker) Check(environment structs.Environment, cf interfaces.CFContext, auth interfaces.Authorization, override request.FreezeOverride, response io.ReadWriter) (int, error) {
+	now := time.Now
+	if c.Now != nil {
+		now = c.Now
+	}
+
+	lock, err := deploywindow.Check(environment, now())
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if lock == nil {
+		return http.StatusOK, nil
+	}
+
+	if !override.OverrideFreeze {
+		c.Log.Infof("refusing request to locked environment %s: %s", cf.Environment, lock.Reason)
+		return http.StatusLocked, DeployLockedError{Environment: cf.Environment, Reason: lock.Reason, NextWindow: lock.NextWindow}
+	}
+
+	if override.Justification == "" {
+		return http.StatusBadRequest, MissingJustificationError{}
+	}
+
+	c.Log.Infof("overriding lock on environment %s (%s) by %s: %s", cf.Environment, lock.Reason, auth.Username, override.Justification)
+	fmt.Fprintf(response, "Overriding lock on environment %s (%s): %s\n", cf.Environment, lock.Reason, override.Justification)
+
+	err = c.EventManager.EmitEvent(DeployLockOverriddenEvent{
+		CFContext:     cf,
+		Authorization: auth,
+		Environment:   environment,
+		Reason:        lock.Reason,
+		Justification: override.Justification,
+		Response:      response,
+		Log:           c.Log,
+	})
+	if err != nil {
+		c.Log.Error(err)
+		return http.StatusInternalServerError, err
+	}
+
+	return http.StatusOK, nil
+}
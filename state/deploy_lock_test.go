@@ -0,0 +1,98 @@
+package state_test
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/mocks"
+	"github.com/compozed/deployadactyl/request"
+	. "github.com/compozed/deployadactyl/state"
+	"github.com/compozed/deployadactyl/structs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/op/go-logging"
+)
+
+var _ = Describe("Deploy Lock Checker", func() {
+	var (
+		eventManager *mocks.EventManager
+		checker      DeployLockChecker
+		environment  structs.Environment
+		cf           interfaces.CFContext
+		auth         interfaces.Authorization
+		response     *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		eventManager = &mocks.EventManager{}
+		checker = DeployLockChecker{
+			EventManager: eventManager,
+			Log:          interfaces.DeploymentLogger{Log: interfaces.DefaultLogger(gbytes.NewBuffer(), logging.DEBUG, "deploy_lock_test")},
+			Now:          func() time.Time { return time.Date(2026, time.December, 24, 12, 0, 0, 0, time.UTC) },
+		}
+		environment = structs.Environment{
+			Name:    "prod",
+			Freezes: []structs.Freeze{{Start: "2026-12-20", End: "2026-12-31", Reason: "holiday freeze"}},
+		}
+		cf = interfaces.CFContext{Environment: "prod"}
+		auth = interfaces.Authorization{Username: "bob"}
+		response = &bytes.Buffer{}
+	})
+
+	It("lets requests through to an open environment", func() {
+		environment.Freezes = nil
+
+		_, err := checker.Check(environment, cf, auth, request.FreezeOverride{}, response)
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(eventManager.EmitEventCall.TimesCalled).To(Equal(0))
+	})
+
+	It("refuses requests to a locked environment with the reason and next window", func() {
+		statusCode, err := checker.Check(environment, cf, auth, request.FreezeOverride{}, response)
+
+		Expect(statusCode).To(Equal(http.StatusLocked))
+		Expect(err).To(Equal(DeployLockedError{
+			Environment: "prod",
+			Reason:      "holiday freeze",
+			NextWindow:  time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC),
+		}))
+		Expect(err.Error()).To(Equal("environment prod is locked: holiday freeze: next deploy window opens at 2027-01-01T00:00:00Z"))
+	})
+
+	It("requires a justification to override the lock", func() {
+		statusCode, err := checker.Check(environment, cf, auth, request.FreezeOverride{OverrideFreeze: true}, response)
+
+		Expect(statusCode).To(Equal(http.StatusBadRequest))
+		Expect(err).To(Equal(MissingJustificationError{}))
+	})
+
+	It("emits the justification when the lock is overridden", func() {
+		override := request.FreezeOverride{OverrideFreeze: true, Justification: "INC-1234 hotfix"}
+
+		_, err := checker.Check(environment, cf, auth, override, response)
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.String()).To(ContainSubstring("Overriding lock on environment prod (holiday freeze): INC-1234 hotfix"))
+
+		Expect(eventManager.EmitEventCall.TimesCalled).To(Equal(1))
+		event := eventManager.EmitEventCall.Received.Events[0].(DeployLockOverriddenEvent)
+		Expect(event.CFContext).To(Equal(cf))
+		Expect(event.Authorization).To(Equal(auth))
+		Expect(event.Reason).To(Equal("holiday freeze"))
+		Expect(event.Justification).To(Equal("INC-1234 hotfix"))
+	})
+
+	It("returns an error when the override event fails", func() {
+		eventManager.EmitEventCall.Returns.Error = []error{errors.New("a test error")}
+
+		statusCode, err := checker.Check(environment, cf, auth, request.FreezeOverride{OverrideFreeze: true, Justification: "hotfix"}, response)
+
+		Expect(statusCode).To(Equal(http.StatusInternalServerError))
+		Expect(err).To(MatchError("a test error"))
+	})
+})
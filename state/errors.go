@@ -1,6 +1,9 @@
 package state
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 type CloudFoundryGetLogsError struct {
 	CfTaskErr error
@@ -131,3 +134,188 @@ type ExistsError struct {
 func (e ExistsError) Error() string {
 	return fmt.Sprintf("app %s doesn't exist", e.ApplicationName)
 }
+
+type DeployLockedError struct {
+	Environment string
+	Reason      string
+	NextWindow  time.Time
+}
+
+func (e DeployLockedError) Error() string {
+	if e.NextWindow.IsZero() {
+		return fmt.Sprintf("environment %s is locked: %s: no upcoming deploy window", e.Environment, e.Reason)
+	}
+	return fmt.Sprintf("environment %s is locked: %s: next deploy window opens at %s", e.Environment, e.Reason, e.NextWindow.Format(time.RFC3339))
+}
+
+type MissingJustificationError struct{}
+
+func (e MissingJustificationError) Error() string {
+	return "a justification is required to override a deployment freeze"
+}
+
+type StandbyNotFoundError struct {
+	ApplicationName string
+}
+
+func (e StandbyNotFoundError) Error() string {
+	return fmt.Sprintf("cannot roll back %s: no previous version was kept", e.ApplicationName)
+}
+
+type UnhealthyApplicationError struct {
+	ApplicationName string
+	Reason          string
+}
+
+func (e UnhealthyApplicationError) Error() string {
+	return fmt.Sprintf("%s is unhealthy: %s", e.ApplicationName, e.Reason)
+}
+
+type CutoverError struct {
+	Err            error
+	RollBackErrors []error
+}
+
+func (e CutoverError) Error() string {
+	if len(e.RollBackErrors) == 0 {
+		return fmt.Sprintf("cannot cut over the applications of the manifest, the ones already cut over were rolled back: %s", e.Err)
+	}
+	return fmt.Sprintf("cannot cut over the applications of the manifest: %s: cannot roll back the ones already cut over: %v", e.Err, e.RollBackErrors)
+}
+
+type ManifestApplicationError struct {
+	Index  int
+	Reason string
+}
+
+func (e ManifestApplicationError) Error() string {
+	if e.Index == 0 {
+		return fmt.Sprintf("cannot deploy the applications of the manifest: %s", e.Reason)
+	}
+	return fmt.Sprintf("cannot deploy application %d of the manifest: %s", e.Index, e.Reason)
+}
+
+type ListServicesError struct {
+	Err error
+}
+
+func (e ListServicesError) Error() string {
+	return fmt.Sprintf("cannot list service instances: %s", e.Err)
+}
+
+type CreateServiceError struct {
+	ServiceName string
+	Out         []byte
+}
+
+func (e CreateServiceError) Error() string {
+	return fmt.Sprintf("cannot create service instance %s: %s", e.ServiceName, string(e.Out))
+}
+
+type UserProvidedServiceError struct {
+	ServiceName string
+	Out         []byte
+}
+
+func (e UserProvidedServiceError) Error() string {
+	return fmt.Sprintf("cannot create or update user-provided service %s: %s", e.ServiceName, string(e.Out))
+}
+
+type ServiceProvisioningError struct {
+	ServiceName string
+	Reason      string
+}
+
+func (e ServiceProvisioningError) Error() string {
+	return fmt.Sprintf("service instance %s was not provisioned: %s", e.ServiceName, e.Reason)
+}
+
+type BindServiceError struct {
+	ApplicationName string
+	ServiceName     string
+	Out             []byte
+}
+
+func (e BindServiceError) Error() string {
+	return fmt.Sprintf("cannot bind %s to %s: %s", e.ServiceName, e.ApplicationName, string(e.Out))
+}
+
+type DeleteServiceError struct {
+	ServiceName string
+	Out         []byte
+}
+
+func (e DeleteServiceError) Error() string {
+	return fmt.Sprintf("cannot delete service instance %s: %s", e.ServiceName, string(e.Out))
+}
+
+type CloudControllerError struct {
+	Path string
+	Err  error
+}
+
+func (e CloudControllerError) Error() string {
+	return fmt.Sprintf("cannot get %s from the cloud controller: %s", e.Path, e.Err)
+}
+
+type OrgNotFoundError struct {
+	Org string
+}
+
+func (e OrgNotFoundError) Error() string {
+	return fmt.Sprintf("org %s does not exist", e.Org)
+}
+
+type SpaceNotFoundError struct {
+	Org   string
+	Space string
+}
+
+func (e SpaceNotFoundError) Error() string {
+	return fmt.Sprintf("space %s does not exist in org %s", e.Space, e.Org)
+}
+
+type PushNotAuthorizedError struct {
+	Username string
+	Org      string
+	Space    string
+}
+
+func (e PushNotAuthorizedError) Error() string {
+	return fmt.Sprintf("%s cannot push to %s/%s: it is not a space developer", e.Username, e.Org, e.Space)
+}
+
+type InsufficientMemoryError struct {
+	Quota     string
+	Name      string
+	Required  uint64
+	Available uint64
+}
+
+func (e InsufficientMemoryError) Error() string {
+	return fmt.Sprintf("the %s quota of %s has %dM of memory left but the push needs %dM", e.Quota, e.Name, e.Available, e.Required)
+}
+
+type RouteOwnedByAnotherSpaceError struct {
+	Route string
+}
+
+func (e RouteOwnedByAnotherSpaceError) Error() string {
+	return fmt.Sprintf("route %s belongs to another space", e.Route)
+}
+
+type BuildpackNotFoundError struct {
+	Buildpack string
+}
+
+func (e BuildpackNotFoundError) Error() string {
+	return fmt.Sprintf("buildpack %s does not exist", e.Buildpack)
+}
+
+type StackNotFoundError struct {
+	Stack string
+}
+
+func (e StackNotFoundError) Error() string {
+	return fmt.Sprintf("stack %s does not exist", e.Stack)
+}
@@ -0,0 +1,53 @@
+package state
+
+import (
+	"io"
+	"reflect"
+
+	"github.com/compozed/deployadactyl/eventmanager"
+	"github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/structs"
+	"github.com/go-errors/errors"
+)
+
+type eventBinding struct {
+	etype   reflect.Type
+	handler func(event interface{}) error
+}
+
+func (s eventBinding) Accepts(event interface{}) bool {
+	return reflect.TypeOf(event) == s.etype
+}
+
+func (b eventBinding) Emit(event interface{}) error {
+	return b.handler(event)
+}
+
+// DeployLockOverriddenEvent is emitted when a request is let through to a frozen environment.
+type DeployLockOverriddenEvent struct {
+	CFContext     interfaces.CFContext
+	Authorization interfaces.Authorization
+	Environment   structs.Environment
+	Reason        string
+	Justification string
+	Response      io.ReadWriter
+	Log           interfaces.DeploymentLogger
+}
+
+func (e DeployLockOverriddenEvent) Name() string {
+	return "DeployLockOverriddenEvent"
+}
+
+func NewDeployLockOverriddenEventBinding(handler func(event DeployLockOverriddenEvent) error) interfaces.Binding {
+	return eventBinding{
+		etype: reflect.TypeOf(DeployLockOverriddenEvent{}),
+		handler: func(gevent interface{}) error {
+			event, ok := gevent.(DeployLockOverriddenEvent)
+			if ok {
+				return handler(event)
+			} else {
+				return eventmanager.InvalidEventType{errors.New("invalid event type")}
+			}
+		},
+	}
+}
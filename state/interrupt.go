@@ -0,0 +1,10 @@
+package state
+
+import I "github.com/compozed/deployadactyl/interfaces"
+
+// Interrupt kills the Cloud Foundry commands the courier is running, when it can be interrupted.
+func Interrupt(courier I.Courier) {
+	if interruptible, ok := courier.(I.Interruptible); ok {
+		interruptible.Interrupt()
+	}
+}
@@ -0,0 +1,278 @@
+package push
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"github.com/compozed/deployadactyl/controller/deployer/manifestro"
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/state"
+)
+
+// ManifestFileSystem reads and rewrites the manifest of a multi-application push.
+type ManifestFileSystem interface {
+	ReadFile(filename string) ([]byte, error)
+	WriteFile(filename string, data []byte, perm os.FileMode) error
+}
+
+// ApplicationsPusher pushes every application of a multi-application manifest to a foundation as one
+// unit. Each application has its own action, made by the push strategy, and they share the courier of
+// the foundation. When one of them fails, all of the applications that were pushed are undone.
+//
+// The first action is the application named in the request. It logs in and cleans up for all of them.
+type ApplicationsPusher struct {
+	Actions []I.Action
+
+	// Bakes is whether the environment bakes the applications after they are cut over. When it does
+	// not, the standbys kept at cutover are finished as soon as every application is cut over.
+	Bakes bool
+
+	executed int
+}
+
+// Initially logs in to the foundation once for every application.
+func (a *ApplicationsPusher) Initially() error {
+	return a.Actions[0].Initially()
+}
+
+// Verify runs the pre-flight checks of every application. The quotas have to leave room for all of
+// them together, so the memory they need is added up and checked once.
+func (a *ApplicationsPusher) Verify() error {
+	var required uint64
+	for _, action := range a.Actions {
+		err := action.Verify()
+		if err != nil {
+			return err
+		}
+
+		if verifier, ok := action.(memoryVerifier); ok {
+			memory, err := verifier.requiredMemory()
+			if err != nil {
+				return err
+			}
+			required += memory
+		}
+	}
+
+	if verifier, ok := a.Actions[0].(memoryVerifier); ok {
+		return verifier.verifyTotalMemory(required)
+	}
+	return nil
+}
+
+// Execute pushes the applications in the order of the manifest, stopping at the first one that fails.
+func (a *ApplicationsPusher) Execute() error {
+	for i, action := range a.Actions {
+		a.executed = i + 1
+
+		err := action.Execute()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HealthCheck checks the health of every application that can be health checked.
+func (a *ApplicationsPusher) HealthCheck() error {
+	for _, action := range a.Actions {
+		if checked, ok := action.(I.HealthCheckedAction); ok {
+			err := checked.HealthCheck()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// PostExecute maps the routes of every application.
+func (a *ApplicationsPusher) PostExecute() error {
+	for _, action := range a.Actions {
+		err := action.PostExecute()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Success cuts every application over to its new build. When one of them fails, the ones that were
+// already cut over are rolled back to the standbys they kept, so that the applications of the manifest
+// are not left on different versions.
+func (a *ApplicationsPusher) Success() error {
+	for i, action := range a.Actions {
+		err := action.Success()
+		if err != nil {
+			return state.CutoverError{Err: err, RollBackErrors: a.rollBack(a.Actions[:i])}
+		}
+	}
+
+	if a.Bakes {
+		return nil
+	}
+	return a.FinishBake()
+}
+
+// Undo undoes every application that was pushed, in reverse order, even when undoing one of them fails.
+//
+// Returns the first error.
+func (a *ApplicationsPusher) Undo() error {
+	var undoErr error
+
+	for i := a.executed - 1; i >= 0; i-- {
+		err := a.Actions[i].Undo()
+		if err != nil && undoErr == nil {
+			undoErr = err
+		}
+	}
+	return undoErr
+}
+
+// Interrupt interrupts every application that can be interrupted. They share a courier, so the first
+// one that can interrupts them all.
+func (a *ApplicationsPusher) Interrupt() {
+	for _, action := range a.Actions {
+		if interruptible, ok := action.(I.Interruptible); ok {
+			interruptible.Interrupt()
+			return
+		}
+	}
+}
+
+// Finally cleans up once for every application.
+func (a *ApplicationsPusher) Finally() error {
+	return a.Actions[0].Finally()
+}
+
+// Bake checks every application that can be baked, stopping at the first one that is unhealthy.
+func (a *ApplicationsPusher) Bake(since time.Time) error {
+	for _, action := range a.Actions {
+		if baked, ok := action.(I.BakedAction); ok {
+			err := baked.Bake(since)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// RollBack rolls every application back to its previous version, even when rolling one of them back fails.
+//
+// Returns the first error.
+func (a *ApplicationsPusher) RollBack() error {
+	rollBackErrs := a.rollBack(a.Actions)
+	if len(rollBackErrs) != 0 {
+		return rollBackErrs[0]
+	}
+	return nil
+}
+
+// rollBack rolls the actions back in reverse order and returns the errors.
+func (a *ApplicationsPusher) rollBack(actions []I.Action) []error {
+	var rollBackErrs []error
+
+	for i := len(actions) - 1; i >= 0; i-- {
+		if baked, ok := actions[i].(I.BakedAction); ok {
+			err := baked.RollBack()
+			if err != nil {
+				rollBackErrs = append(rollBackErrs, err)
+			}
+		}
+	}
+	return rollBackErrs
+}
+
+// FinishBake finishes the bake of every application.
+func (a *ApplicationsPusher) FinishBake() error {
+	for _, action := range a.Actions {
+		if baked, ok := action.(I.BakedAction); ok {
+			err := baked.FinishBake()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// companion returns a pusher for another application of the manifest. It is pushed under its own name
+// with the instances and service instances from the manifest. Hooks, health checks, smoke tests and
+// user-provided services are only run for and bound to the application named in the request.
+func (p Pusher) companion(index int, application manifestro.Application) *Pusher {
+	companion := p
+	companion.ApplicationIndex = index
+	companion.DeploymentInfo.AppName = application.Name
+	companion.DeploymentInfo.Instances = p.Environment.Instances
+	if instances := application.GetInstances(); instances != nil {
+		companion.DeploymentInfo.Instances = *instances
+	}
+	companion.DeploymentInfo.HealthCheckEndpoint = ""
+	companion.DeploymentInfo.SmokeTests = nil
+	companion.Environment.Hooks = nil
+	companion.Environment.Bake.HealthCheckEndpoint = ""
+	companion.DeploymentInfo.ServiceInstances = application.ServiceInstances
+	companion.DeploymentInfo.UserProvidedServices = nil
+	companion.CreatedServiceInstances = &[]string{}
+	companion.UpdatedUserProvidedServices = nil
+
+	return &companion
+}
+
+// prepareManifest renames the applications of a multi-application manifest in the application path
+// to the names they are pushed under, so that cf push picks each of them out of the manifest by name.
+// The first application takes the name in the request. The others keep their own names, with the
+// temporary suffix when they are pushed blue-green.
+func (a PushManager) prepareManifest(appPath, manifest string) error {
+	applications := manifestro.GetApplications(manifest)
+	if len(applications) < 2 {
+		return nil
+	}
+
+	var (
+		info  = a.DeployEventData.DeploymentInfo
+		names = []string{info.AppName}
+		seen  = map[string]bool{info.AppName: true}
+	)
+
+	for i, application := range applications[1:] {
+		if application.Name == "" {
+			return state.ManifestApplicationError{Index: i + 1, Reason: "missing name"}
+		}
+		if seen[application.Name] {
+			return state.ManifestApplicationError{Index: i + 1, Reason: fmt.Sprintf("%s is deployed more than once", application.Name)}
+		}
+		seen[application.Name] = true
+		names = append(names, application.Name)
+	}
+
+	if a.Environment.PushStrategy == "" || a.Environment.PushStrategy == BlueGreenPushStrategy {
+		for i := range names {
+			names[i] = names[i] + TemporaryNameSuffix + info.UUID
+		}
+	}
+
+	manifestPath := path.Join(appPath, "manifest.yml")
+
+	content, err := a.FileSystem.ReadFile(manifestPath)
+	if err != nil {
+		content = []byte(manifest)
+	}
+
+	renamed, err := manifestro.RenameApplications(string(content), names)
+	if err != nil {
+		return state.ManifestApplicationError{Reason: err.Error()}
+	}
+
+	err = a.FileSystem.WriteFile(manifestPath, []byte(renamed), 0600)
+	if err != nil {
+		return state.ManifestApplicationError{Reason: err.Error()}
+	}
+
+	a.Logger.Infof("deploying %d applications from the manifest: %v", len(names), names)
+
+	return nil
+}
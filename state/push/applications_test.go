@@ -0,0 +1,139 @@
+package push_test
+
+import (
+	"errors"
+	"time"
+
+	"github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/mocks"
+	"github.com/compozed/deployadactyl/state"
+	. "github.com/compozed/deployadactyl/state/push"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("ApplicationsPusher", func() {
+	var (
+		api          *mocks.Pusher
+		worker       *mocks.Pusher
+		scheduler    *mocks.Pusher
+		applications *ApplicationsPusher
+	)
+
+	BeforeEach(func() {
+		api = &mocks.Pusher{Response: NewBuffer()}
+		worker = &mocks.Pusher{Response: NewBuffer()}
+		scheduler = &mocks.Pusher{Response: NewBuffer()}
+
+		applications = &ApplicationsPusher{Actions: []interfaces.Action{api, worker, scheduler}}
+	})
+
+	It("logs in and cleans up once for every application", func() {
+		Expect(applications.Initially()).To(Succeed())
+		Expect(applications.Finally()).To(Succeed())
+
+		Expect(api.InitiallyCall.TimesCalled).To(Equal(1))
+		Expect(worker.InitiallyCall.TimesCalled).To(Equal(0))
+		Expect(scheduler.InitiallyCall.TimesCalled).To(Equal(0))
+	})
+
+	It("pushes, maps the routes of and cuts over every application", func() {
+		Expect(applications.Execute()).To(Succeed())
+		Expect(applications.PostExecute()).To(Succeed())
+		Expect(applications.Success()).To(Succeed())
+
+		for _, pusher := range []*mocks.Pusher{api, worker, scheduler} {
+			Expect(pusher.ExecuteCall.TimesCalled).To(Equal(1))
+			Expect(pusher.PostExecuteCall.TimesCalled).To(Equal(1))
+			Expect(pusher.SuccessCall.TimesCalled).To(Equal(1))
+		}
+	})
+
+	It("finishes the standbys once every application is cut over when the environment does not bake", func() {
+		Expect(applications.Success()).To(Succeed())
+
+		for _, pusher := range []*mocks.Pusher{api, worker, scheduler} {
+			Expect(pusher.FinishBakeCall.TimesCalled).To(Equal(1))
+		}
+	})
+
+	It("keeps the standbys for the bake when the environment bakes", func() {
+		applications.Bakes = true
+
+		Expect(applications.Success()).To(Succeed())
+
+		for _, pusher := range []*mocks.Pusher{api, worker, scheduler} {
+			Expect(pusher.FinishBakeCall.TimesCalled).To(Equal(0))
+		}
+	})
+
+	Context("when an application fails to cut over", func() {
+		It("rolls back the applications that were already cut over", func() {
+			worker.SuccessCall.Returns.Error = errors.New("rename failed")
+
+			Expect(applications.Success()).To(MatchError(state.CutoverError{Err: errors.New("rename failed")}))
+
+			Expect(scheduler.SuccessCall.TimesCalled).To(Equal(0))
+			Expect(api.RollBackCall.TimesCalled).To(Equal(1))
+			Expect(worker.RollBackCall.TimesCalled).To(Equal(0))
+			Expect(scheduler.RollBackCall.TimesCalled).To(Equal(0))
+			Expect(api.FinishBakeCall.TimesCalled).To(Equal(0))
+		})
+
+		It("returns the errors of the applications that cannot be rolled back", func() {
+			worker.SuccessCall.Returns.Error = errors.New("rename failed")
+			api.RollBackCall.Returns.Error = errors.New("start failed")
+
+			Expect(applications.Success()).To(Equal(state.CutoverError{Err: errors.New("rename failed"), RollBackErrors: []error{errors.New("start failed")}}))
+		})
+	})
+
+	Context("when an application fails to push", func() {
+		It("stops and undoes only the applications that were pushed", func() {
+			worker.ExecuteCall.Returns.Error = errors.New("push failed")
+
+			Expect(applications.Execute()).To(MatchError("push failed"))
+			Expect(scheduler.ExecuteCall.TimesCalled).To(Equal(0))
+
+			Expect(applications.Undo()).To(Succeed())
+
+			Expect(api.UndoCall.TimesCalled).To(Equal(1))
+			Expect(worker.UndoCall.TimesCalled).To(Equal(1))
+			Expect(scheduler.UndoCall.TimesCalled).To(Equal(0))
+		})
+	})
+
+	Context("when undoing an application fails", func() {
+		It("undoes the others and returns the error", func() {
+			Expect(applications.Execute()).To(Succeed())
+			worker.UndoCall.Returns.Error = errors.New("undo failed")
+
+			Expect(applications.Undo()).To(MatchError("undo failed"))
+
+			Expect(api.UndoCall.TimesCalled).To(Equal(1))
+			Expect(scheduler.UndoCall.TimesCalled).To(Equal(1))
+		})
+	})
+
+	Context("when an application fails its bake", func() {
+		It("rolls back every application", func() {
+			worker.BakeCall.Returns.Errors = []error{errors.New("unhealthy")}
+
+			Expect(applications.Bake(time.Now())).To(MatchError("unhealthy"))
+			Expect(applications.RollBack()).To(Succeed())
+
+			for _, pusher := range []*mocks.Pusher{api, worker, scheduler} {
+				Expect(pusher.RollBackCall.TimesCalled).To(Equal(1))
+			}
+		})
+	})
+
+	It("finishes the bake of every application", func() {
+		Expect(applications.FinishBake()).To(Succeed())
+
+		for _, pusher := range []*mocks.Pusher{api, worker, scheduler} {
+			Expect(pusher.FinishBakeCall.TimesCalled).To(Equal(1))
+		}
+	})
+})
@@ -0,0 +1,170 @@
+package push
+
+import (
+	"fmt"
+	"time"
+
+	H "github.com/compozed/deployadactyl/eventmanager/handlers/healthchecker"
+	"github.com/compozed/deployadactyl/state"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+// Bake checks the instances of the application and the health check endpoint on its route. It fails
+// when the application has no instances, when more instances are crashed or down, or have restarted
+// since the bake started, than the bake of the environment allows, or when the health check fails.
+func (p Pusher) Bake(since time.Time) error {
+	var (
+		appName = p.DeploymentInfo.AppName
+		bake    = p.Environment.Bake
+	)
+
+	instances, err := p.Courier.AppInstances(appName)
+	if err != nil {
+		p.Log.Errorf("%s: could not get the instances of %s: %s", p.foundationName(), appName, err)
+		return err
+	}
+
+	crashed, restarted := 0, 0
+	for _, instance := range instances {
+		if instance.State == "crashed" || instance.State == "down" {
+			crashed++
+		}
+		if instance.Since.After(since) {
+			restarted++
+		}
+	}
+
+	switch {
+	case len(instances) == 0:
+		err = state.UnhealthyApplicationError{ApplicationName: appName, Reason: "no instances are running"}
+	case crashed > bake.MaxCrashes:
+		err = state.UnhealthyApplicationError{ApplicationName: appName, Reason: fmt.Sprintf("%d of %d instances are crashed or down", crashed, len(instances))}
+	case restarted > bake.MaxRestarts:
+		err = state.UnhealthyApplicationError{ApplicationName: appName, Reason: fmt.Sprintf("%d of %d instances restarted while baking", restarted, len(instances))}
+	default:
+		err = p.checkRoute(bake)
+	}
+
+	if err != nil {
+		p.Log.Errorf("%s: %s failed its bake: %s", p.foundationName(), appName, err)
+		fmt.Fprintf(p.Response, "%s failed its bake: %s\n", appName, err)
+		return err
+	}
+
+	p.Log.Debugf("%s: %s is healthy: %d instances", p.foundationName(), appName, len(instances))
+
+	return nil
+}
+
+func (p Pusher) checkRoute(bake S.Bake) error {
+	endpoint := bake.HealthCheckEndpoint
+	if endpoint == "" {
+		endpoint = p.DeploymentInfo.HealthCheckEndpoint
+	}
+	if endpoint == "" {
+		return nil
+	}
+
+	healthCheckRequest := H.HealthCheckRequest{
+		HealthCheckEndpoint: endpoint,
+		Courier:             p.Courier,
+		Logger:              p.Log,
+		Environment:         p.DeploymentInfo.Environment,
+		FoundationUrl:       p.FoundationURL,
+		FoundationName:      p.foundationName(),
+		AppsDomain:          p.AppsDomain,
+		UUID:                p.DeploymentInfo.UUID,
+	}
+
+	return p.HealthChecker.CheckRoute(healthCheckRequest, p.DeploymentInfo.AppName)
+}
+
+// RollBack swaps the application back to the previous version that was kept as a standby while it
+// baked. The previous version is started and given the application name and load balanced route,
+// the application that failed its bake is deleted and the user-provided services the push updated
+// are restored.
+func (p Pusher) RollBack() error {
+	var (
+		appName = p.DeploymentInfo.AppName
+		standby = S.StandbyName(appName, 1)
+		failed  = appName + TemporaryNameSuffix + p.DeploymentInfo.UUID
+	)
+
+	if p.Environment.DisableRollback {
+		p.Log.Errorf("%s: %s failed its bake, not rolled back due to DisabledRollback=true", p.foundationName(), appName)
+		return p.FinishBake()
+	}
+
+	if !p.Courier.Exists(standby) {
+		p.Log.Errorf("%s: %s did not previously exist: not rolling back", p.foundationName(), appName)
+		return nil
+	}
+
+	p.Log.Errorf("%s: rolling back %s to the previous version", p.foundationName(), appName)
+
+	out, err := p.Courier.Start(standby)
+	p.Response.Write(out)
+	if err != nil {
+		p.Log.Errorf("%s: could not start %s", p.foundationName(), standby)
+		return state.StartError{ApplicationName: standby, Out: out}
+	}
+
+	if p.DeploymentInfo.Domain != "" {
+		out, err = p.Courier.MapRoute(standby, p.DeploymentInfo.Domain, appName)
+		if err != nil {
+			p.Log.Errorf("%s: could not map %s to %s", p.foundationName(), standby, p.DeploymentInfo.Domain)
+			return state.MapRouteError{out}
+		}
+	}
+
+	err = p.renameApplication(appName, failed)
+	if err != nil {
+		return err
+	}
+
+	err = p.renameApplication(standby, appName)
+	if err != nil {
+		return err
+	}
+
+	if p.DeploymentInfo.Domain != "" {
+		out, err = p.Courier.UnmapRoute(failed, p.DeploymentInfo.Domain, appName)
+		if err != nil {
+			p.Log.Errorf("%s: could not unmap %s from %s", p.foundationName(), failed, p.DeploymentInfo.Domain)
+			return state.UnmapRouteError{failed, out}
+		}
+	}
+
+	err = p.deleteApplication(failed)
+	if err != nil {
+		return err
+	}
+
+	for generation := 2; p.Courier.Exists(S.StandbyName(appName, generation)); generation++ {
+		err = p.renameApplication(S.StandbyName(appName, generation), S.StandbyName(appName, generation-1))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = p.restoreUserProvidedServices()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(p.Response, "rolled back %s to the previous version\n", appName)
+
+	return nil
+}
+
+// FinishBake deletes the previous version once the application has baked, unless the environment
+// keeps previous versions.
+func (p Pusher) FinishBake() error {
+	standby := S.StandbyName(p.DeploymentInfo.AppName, 1)
+
+	if p.Environment.KeepPrevious > 0 || !p.Courier.Exists(standby) {
+		return nil
+	}
+
+	return p.deleteApplication(standby)
+}
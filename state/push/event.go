@@ -32,6 +32,9 @@ type DeployStartedEvent struct {
 	Response    io.ReadWriter
 	Data        map[string]interface{}
 	Log         interfaces.DeploymentLogger
+
+	// PromotedFrom is the UUID of the deployment the deploy was promoted from, if it was promoted.
+	PromotedFrom string
 }
 
 func (d DeployStartedEvent) Name() string {
@@ -142,6 +145,40 @@ func NewDeployFailureEventBinding(handler func(event DeployFailureEvent) error)
 	}
 }
 
+// DeployPartialSuccessEvent is emitted instead of a DeployFailureEvent when the deployment succeeded on
+// some of the foundations and failed on the others.
+type DeployPartialSuccessEvent struct {
+	CFContext   interfaces.CFContext
+	Body        io.Reader
+	ContentType string
+	Environment structs.Environment
+	Auth        interfaces.Authorization
+	Response    io.ReadWriter
+	Data        map[string]interface{}
+	Succeeded   []string
+	Failed      []string
+	Error       error
+	Log         interfaces.DeploymentLogger
+}
+
+func (d DeployPartialSuccessEvent) Name() string {
+	return "DeployPartialSuccessEvent"
+}
+
+func NewDeployPartialSuccessEventBinding(handler func(event DeployPartialSuccessEvent) error) interfaces.Binding {
+	return eventBinding{
+		etype: reflect.TypeOf(DeployPartialSuccessEvent{}),
+		handler: func(gevent interface{}) error {
+			event, ok := gevent.(DeployPartialSuccessEvent)
+			if ok {
+				return handler(event)
+			} else {
+				return eventmanager.InvalidEventType{errors.New("invalid event type")}
+			}
+		},
+	}
+}
+
 type PushStartedEvent struct {
 	CFContext            interfaces.CFContext
 	Body                 io.Reader
@@ -180,6 +217,7 @@ type PushFinishedEvent struct {
 	Response            io.ReadWriter
 	AppPath             string
 	FoundationURL       string
+	FoundationName      string
 	TempAppWithUUID     string
 	Manifest            string
 	Data                map[string]interface{}
@@ -0,0 +1,39 @@
+package push
+
+import S "github.com/compozed/deployadactyl/structs"
+
+// runHooks runs the hooks of the environment that run at the given point of the push against
+// application, stopping at the first one that fails.
+func (p Pusher) runHooks(when, application string) error {
+	for _, hook := range p.Environment.Hooks {
+		if hook.When != when {
+			continue
+		}
+
+		p.Log.Infof("%s: running %s hook %s", p.foundationName(), when, hook.Name)
+
+		err := p.HookRunner.Run(hook, p.hookContext(hook, application), p.Courier, p.Response)
+		if err != nil {
+			p.Log.Errorf("%s: %s", p.foundationName(), err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p Pusher) hookContext(hook S.Hook, application string) S.HookContext {
+	return S.HookContext{
+		Hook:          hook.Name,
+		When:          hook.When,
+		Environment:   p.Environment.Name,
+		Foundation:    p.foundationName(),
+		FoundationURL: p.FoundationURL,
+		Org:           p.DeploymentInfo.Org,
+		Space:         p.DeploymentInfo.Space,
+		AppName:       p.DeploymentInfo.AppName,
+		Application:   application,
+		UUID:          p.DeploymentInfo.UUID,
+		ArtifactURL:   p.DeploymentInfo.ArtifactURL,
+	}
+}
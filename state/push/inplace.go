@@ -0,0 +1,92 @@
+package push
+
+import S "github.com/compozed/deployadactyl/structs"
+
+// InPlacePusher pushes over the existing application instead of pushing a temporary application
+// next to it. It is used by the in-place and rolling push strategies.
+//
+// There is no previous version to go back to, so Undo leaves the application as it is. With the
+// rolling strategy Cloud Foundry keeps the old instances running until the new ones are healthy.
+type InPlacePusher struct {
+	*Pusher
+
+	// Strategy is passed to cf push as its --strategy. A plain cf push is run when it is empty.
+	Strategy string
+}
+
+// Execute pushes the application over the existing one. Its service instances are bound to the
+// existing application before the push, so that the push stages and starts it with them.
+func (p InPlacePusher) Execute() error {
+	err := p.runHooks(S.HookBeforePush, p.DeploymentInfo.AppName)
+	if err != nil {
+		return err
+	}
+
+	err = p.createServiceInstances()
+	if err != nil {
+		return err
+	}
+
+	err = p.createUserProvidedServices()
+	if err != nil {
+		return err
+	}
+
+	services := p.serviceNames()
+	if len(services) == 0 || !p.Courier.Exists(p.DeploymentInfo.AppName) {
+		return p.pushWithServices(p.DeploymentInfo.AppName, p.AppPath, p.Strategy)
+	}
+
+	err = p.bindServices(p.DeploymentInfo.AppName, services)
+	if err != nil {
+		return err
+	}
+
+	return p.pushApplication(p.DeploymentInfo.AppName, p.AppPath, p.Strategy, true)
+}
+
+// HealthCheck does nothing: the health checker maps and deletes a route named after the application,
+// which would delete the route of the application itself when it is pushed in place. Smoke tests are
+// skipped for the same reason.
+func (p InPlacePusher) HealthCheck() error {
+	if p.DeploymentInfo.HealthCheckEndpoint != "" || len(p.DeploymentInfo.SmokeTests) != 0 {
+		p.Log.Infof("%s: skipping the health check and smoke tests of %s: they are not supported by in-place pushes", p.foundationName(), p.DeploymentInfo.AppName)
+	}
+	return nil
+}
+
+// PostExecute maps the routes of the manifest and the load balanced domain to the application.
+// The before_cutover hooks are skipped: the application is pushed over and serves traffic before
+// they could run, so there is no cutover for them to come before.
+func (p InPlacePusher) PostExecute() error {
+	for _, hook := range p.Environment.Hooks {
+		if hook.When == S.HookBeforeCutover {
+			p.Log.Infof("%s: skipping %s hook %s: it is not supported by in-place pushes", p.foundationName(), hook.When, hook.Name)
+		}
+	}
+
+	return p.mapRoutes(p.DeploymentInfo.AppName)
+}
+
+// Success only runs the after_success hooks since the application already has its name and routes.
+func (p InPlacePusher) Success() error {
+	return p.runHooks(S.HookAfterSuccess, p.DeploymentInfo.AppName)
+}
+
+// Undo does nothing since there is no previous version of the application left to go back to.
+// The service instances the push created are kept, as the application may already be bound to them.
+func (p InPlacePusher) Undo() error {
+	p.Log.Errorf("%s: %s was pushed in place and cannot be rolled back", p.foundationName(), p.DeploymentInfo.AppName)
+	return nil
+}
+
+// RollBack does nothing, like Undo. Deployments that bake are rejected unless they use the blue-green
+// push strategy, so it is only called by strategies that wrap the InPlacePusher.
+func (p InPlacePusher) RollBack() error {
+	return p.Undo()
+}
+
+// FinishBake does nothing since no previous version was kept.
+func (p InPlacePusher) FinishBake() error {
+	return nil
+}
@@ -0,0 +1,126 @@
+package push_test
+
+import (
+	"errors"
+
+	"github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/mocks"
+	"github.com/compozed/deployadactyl/randomizer"
+	. "github.com/compozed/deployadactyl/state/push"
+	S "github.com/compozed/deployadactyl/structs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	"github.com/op/go-logging"
+)
+
+var _ = Describe("InPlacePusher", func() {
+	var (
+		courier   *mocks.Courier
+		pusher    *InPlacePusher
+		logBuffer *Buffer
+		appName   string
+	)
+
+	BeforeEach(func() {
+		courier = &mocks.Courier{}
+		logBuffer = NewBuffer()
+		appName = "appName-" + randomizer.StringRunes(10)
+
+		pusher = &InPlacePusher{
+			Pusher: &Pusher{
+				Courier:        courier,
+				DeploymentInfo: S.DeploymentInfo{AppName: appName, Instances: 2, UUID: randomizer.StringRunes(10)},
+				Response:       NewBuffer(),
+				Log:            interfaces.DeploymentLogger{Log: interfaces.DefaultLogger(logBuffer, logging.DEBUG, "inplace_test")},
+				FoundationURL:  "https://api.example.com",
+				AppPath:        "appPath",
+			},
+		}
+	})
+
+	Describe("Execute", func() {
+		It("pushes over the existing application", func() {
+			Expect(pusher.Execute()).To(Succeed())
+
+			Expect(courier.PushCall.Received.AppName).To(Equal(appName))
+			Expect(courier.PushCall.Received.AppPath).To(Equal("appPath"))
+			Expect(courier.PushCall.Received.Instances).To(Equal(uint16(2)))
+		})
+
+		It("pushes with the cf push strategy", func() {
+			pusher.Strategy = "rolling"
+
+			Expect(pusher.Execute()).To(Succeed())
+
+			Expect(courier.PushWithStrategyCall.Received.AppName).To(Equal(appName))
+			Expect(courier.PushWithStrategyCall.Received.Strategy).To(Equal("rolling"))
+			Expect(courier.PushCall.Received.AppName).To(BeEmpty())
+		})
+
+		It("returns an error when the push fails", func() {
+			courier.PushCall.Returns.Error = errors.New("push error")
+
+			Expect(pusher.Execute()).ToNot(Succeed())
+		})
+
+		Context("when service instances are requested", func() {
+			BeforeEach(func() {
+				pusher.Strategy = "rolling"
+				pusher.DeploymentInfo.ServiceInstances = []S.ServiceInstance{{Name: "db", Service: "p-mysql", Plan: "small"}}
+				courier.ServicesCall.Returns.Services = []string{"db"}
+			})
+
+			It("binds them to the existing application before pushing over it", func() {
+				courier.ExistsCall.Returns.Bool = true
+
+				Expect(pusher.Execute()).To(Succeed())
+
+				Expect(courier.BindServiceCall.Received.AppName).To(Equal([]string{appName}))
+				Expect(courier.PushWithStrategyCall.Received.Strategy).To(Equal("rolling"))
+				Expect(courier.PushWithoutStartCall.Received.AppName).To(BeEmpty())
+				Expect(courier.RestageCall.Received.AppName).To(BeEmpty())
+			})
+
+			It("pushes a new application without starting it and starts it once they are bound", func() {
+				Expect(pusher.Execute()).To(Succeed())
+
+				Expect(courier.PushWithoutStartCall.Received.AppName).To(Equal(appName))
+				Expect(courier.BindServiceCall.Received.AppName).To(Equal([]string{appName}))
+				Expect(courier.StartCall.Received.AppName).To(Equal(appName))
+				Expect(courier.PushWithStrategyCall.Received.AppName).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("PostExecute", func() {
+		It("skips the before_cutover hooks since the application already serves traffic", func() {
+			hookRunner := &mocks.HookRunner{}
+			pusher.HookRunner = hookRunner
+			pusher.Environment.Hooks = []S.Hook{{Name: "migrate", When: S.HookBeforeCutover, Task: "rake db:migrate"}}
+
+			Expect(pusher.PostExecute()).To(Succeed())
+
+			Expect(hookRunner.RunCall.Received.Hooks).To(BeEmpty())
+			Eventually(logBuffer).Should(Say("skipping before_cutover hook migrate: it is not supported by in-place pushes"))
+		})
+	})
+
+	Describe("Success", func() {
+		It("does not rename or delete the application", func() {
+			Expect(pusher.Success()).To(Succeed())
+
+			Expect(courier.RenameCall.Received.Renames).To(BeEmpty())
+			Expect(courier.DeleteCall.Received.AppNames).To(BeEmpty())
+		})
+	})
+
+	Describe("Undo", func() {
+		It("leaves the application as it is", func() {
+			Expect(pusher.Undo()).To(Succeed())
+
+			Expect(courier.DeleteCall.Received.AppNames).To(BeEmpty())
+			Eventually(logBuffer).Should(Say("was pushed in place and cannot be rolled back"))
+		})
+	})
+})
@@ -7,19 +7,22 @@ import (
 	"io/ioutil"
 	"net/http"
 	"os"
+	"strings"
 
 	"github.com/compozed/deployadactyl/constants"
 	"github.com/compozed/deployadactyl/controller/deployer"
 	"github.com/compozed/deployadactyl/controller/deployer/bluegreen"
 	I "github.com/compozed/deployadactyl/interfaces"
 	"github.com/compozed/deployadactyl/request"
+	"github.com/compozed/deployadactyl/secrets"
+	"github.com/compozed/deployadactyl/state"
 	"github.com/compozed/deployadactyl/structs"
 	"github.com/go-errors/errors"
 )
 
-type PushControllerConstructor func(log I.DeploymentLogger, deployer, silentDeployer I.Deployer, eventManager I.EventManager, errorFinder I.ErrorFinder, pushManagerFactory I.PushManagerFactory, resolver I.AuthResolver, envResolver I.EnvResolver) request.PushController
+type PushControllerConstructor func(log I.DeploymentLogger, deployer, silentDeployer I.Deployer, eventManager I.EventManager, errorFinder I.ErrorFinder, pushManagerFactory I.PushManagerFactory, resolver I.AuthResolver, envResolver I.EnvResolver, history I.DeploymentHistory, pushStrategies PushStrategies, secretStore I.SecretStore, diskGuard I.DiskGuard, redactor I.Redactor) request.PushController
 
-func NewPushController(l I.DeploymentLogger, d, sd I.Deployer, em I.EventManager, ef I.ErrorFinder, pmf I.PushManagerFactory, resolver I.AuthResolver, envResolver I.EnvResolver) request.PushController {
+func NewPushController(l I.DeploymentLogger, d, sd I.Deployer, em I.EventManager, ef I.ErrorFinder, pmf I.PushManagerFactory, resolver I.AuthResolver, envResolver I.EnvResolver, history I.DeploymentHistory, pushStrategies PushStrategies, secretStore I.SecretStore, diskGuard I.DiskGuard, redactor I.Redactor) request.PushController {
 	return &PushController{
 		Deployer:           d,
 		SilentDeployer:     sd,
@@ -29,6 +32,11 @@ func NewPushController(l I.DeploymentLogger, d, sd I.Deployer, em I.EventManager
 		Log:                l,
 		AuthResolver:       resolver,
 		EnvResolver:        envResolver,
+		History:            history,
+		PushStrategies:     pushStrategies,
+		SecretStore:        secretStore,
+		DiskGuard:          diskGuard,
+		Redactor:           redactor,
 	}
 }
 
@@ -40,6 +48,114 @@ func (e MissingParameterError) Error() string {
 	return e.Err.Error()
 }
 
+type InvalidStrategyError struct {
+	Strategy string
+}
+
+func (e InvalidStrategyError) Error() string {
+	return fmt.Sprintf("unknown deployment strategy: %s", e.Strategy)
+}
+
+type BakeNotSupportedError struct {
+	PushStrategy string
+}
+
+func (e BakeNotSupportedError) Error() string {
+	return fmt.Sprintf("cannot bake with the %s push strategy: there is no previous version to roll back to", e.PushStrategy)
+}
+
+type InvalidSmokeTestError struct {
+	SmokeTest string
+	Reason    string
+}
+
+func (e InvalidSmokeTestError) Error() string {
+	return fmt.Sprintf("invalid smoke test %s: %s", e.SmokeTest, e.Reason)
+}
+
+type InvalidServiceInstanceError struct {
+	ServiceInstance string
+	Reason          string
+}
+
+func (e InvalidServiceInstanceError) Error() string {
+	return fmt.Sprintf("invalid service instance %s: %s", e.ServiceInstance, e.Reason)
+}
+
+type InvalidUserProvidedServiceError struct {
+	UserProvidedService string
+	Reason              string
+}
+
+func (e InvalidUserProvidedServiceError) Error() string {
+	return fmt.Sprintf("invalid user-provided service %s: %s", e.UserProvidedService, e.Reason)
+}
+
+type DeploymentNotFoundError struct {
+	UUID string
+}
+
+func (e DeploymentNotFoundError) Error() string {
+	return fmt.Sprintf("cannot find deployment to retry: %s", e.UUID)
+}
+
+type RetryMismatchError struct {
+	UUID string
+}
+
+func (e RetryMismatchError) Error() string {
+	return fmt.Sprintf("deployment %s was not made to this application", e.UUID)
+}
+
+type NothingToRetryError struct {
+	UUID string
+}
+
+func (e NothingToRetryError) Error() string {
+	return fmt.Sprintf("deployment %s did not fail on any foundation", e.UUID)
+}
+
+type InvalidPromotionError struct {
+	Reason string
+}
+
+func (e InvalidPromotionError) Error() string {
+	return fmt.Sprintf("cannot promote: %s", e.Reason)
+}
+
+type NothingToPromoteError struct {
+	CFContext I.CFContext
+}
+
+func (e NothingToPromoteError) Error() string {
+	return fmt.Sprintf("no deployment of %s to %s/%s/%s has succeeded", e.CFContext.Application, e.CFContext.Environment, e.CFContext.Organization, e.CFContext.Space)
+}
+
+type PromotionMismatchError struct {
+	UUID      string
+	CFContext I.CFContext
+}
+
+func (e PromotionMismatchError) Error() string {
+	return fmt.Sprintf("deployment %s was not made to %s in %s/%s/%s", e.UUID, e.CFContext.Application, e.CFContext.Environment, e.CFContext.Organization, e.CFContext.Space)
+}
+
+type DeploymentNotSucceededError struct {
+	UUID string
+}
+
+func (e DeploymentNotSucceededError) Error() string {
+	return fmt.Sprintf("deployment %s did not succeed and cannot be promoted", e.UUID)
+}
+
+type UnknownFoundationError struct {
+	Foundation string
+}
+
+func (e UnknownFoundationError) Error() string {
+	return fmt.Sprintf("unknown foundation: %s", e.Foundation)
+}
+
 type PushController struct {
 	Deployer           I.Deployer
 	SilentDeployer     I.Deployer
@@ -49,12 +165,66 @@ type PushController struct {
 	PushManagerFactory I.PushManagerFactory
 	AuthResolver       I.AuthResolver
 	EnvResolver        I.EnvResolver
+	History            I.DeploymentHistory
+	PushStrategies     PushStrategies
+	SecretStore        I.SecretStore
+	DiskGuard          I.DiskGuard
+
+	// Redactor masks the secrets of the request in its output and logs. The ones a retry or promotion
+	// takes over from the history are added to it.
+	Redactor I.Redactor
 }
 
 // PUSH specific
 func (c *PushController) RunDeployment(deployment request.PostDeploymentRequest, response *bytes.Buffer) (deployResponse I.DeployResponse) {
 	cf := deployment.CFContext
 
+	if c.DiskGuard != nil {
+		if err := c.DiskGuard.Check(); err != nil {
+			c.Log.Error(err)
+			fmt.Fprintln(response, err.Error())
+			return I.DeployResponse{
+				StatusCode: http.StatusInsufficientStorage,
+				Error:      err,
+			}
+		}
+	}
+
+	var retryFoundations []string
+	if deployment.Request.Retry.UUID != "" {
+		var (
+			statusCode int
+			err        error
+		)
+		deployment, retryFoundations, statusCode, err = c.retry(deployment)
+		if err != nil {
+			c.Log.Error(err)
+			return I.DeployResponse{
+				StatusCode: statusCode,
+				Error:      err,
+			}
+		}
+	}
+
+	if deployment.Request.Promote.Environment != "" {
+		var (
+			statusCode int
+			err        error
+		)
+		deployment, statusCode, err = c.promote(deployment, response)
+		if err != nil {
+			c.Log.Error(err)
+			return I.DeployResponse{
+				StatusCode: statusCode,
+				Error:      err,
+			}
+		}
+	}
+
+	if c.Redactor != nil {
+		c.Redactor.Add(deployment.Request.Secrets()...)
+	}
+
 	if deployment.Type == "application/json" && deployment.Request.ArtifactUrl == "" {
 		c.Log.Error("artifact url is missing from request")
 		return I.DeployResponse{
@@ -78,11 +248,18 @@ func (c *PushController) RunDeployment(deployment request.PostDeploymentRequest,
 		EnvironmentVariables: deployment.Request.EnvironmentVariables,
 		HealthCheckEndpoint:  deployment.Request.HealthCheckEndpoint,
 		Data:                 deployment.Request.Data,
+		PromotedFrom:         deployment.Request.PromotedFrom,
+		Output:               deployment.Output,
 	}
 
 	c.Log.Debugf("Starting deploy of %s with UUID %s", cf.Application, deploymentInfo.UUID)
 	c.Log.Debug("building deploymentInfo")
 
+	if c.History != nil {
+		c.History.Started(deploymentInfo.UUID)
+		defer c.History.Finished(deploymentInfo.UUID)
+	}
+
 	body := ioutil.NopCloser(bytes.NewBuffer(*deployment.Body))
 	if deployment.Type == "application/json" || deployment.Type == "application/zip" || deployment.Type == "application/x-tar" || deployment.Type == "application/x-gzip" {
 		deploymentInfo.ContentType = deployment.Type
@@ -110,6 +287,83 @@ func (c *PushController) RunDeployment(deployment request.PostDeploymentRequest,
 		}
 	}
 
+	lockChecker := state.DeployLockChecker{EventManager: c.EventManager, Log: c.Log}
+	statusCode, err := lockChecker.Check(environment, cf, auth, deployment.Request.FreezeOverride, response)
+	if err != nil {
+		return I.DeployResponse{
+			StatusCode: statusCode,
+			Error:      err,
+		}
+	}
+
+	if retryFoundations != nil {
+		environment, err = retryEnvironment(environment, retryFoundations)
+		if err != nil {
+			return I.DeployResponse{
+				StatusCode: http.StatusBadRequest,
+				Error:      err,
+			}
+		}
+	}
+
+	if deployment.Request.Strategy != "" {
+		if !structs.IsStrategy(deployment.Request.Strategy) {
+			return I.DeployResponse{
+				StatusCode: http.StatusBadRequest,
+				Error:      InvalidStrategyError{deployment.Request.Strategy},
+			}
+		}
+		environment.Strategy = deployment.Request.Strategy
+	}
+
+	if deployment.Request.PushStrategy != "" {
+		environment.PushStrategy = deployment.Request.PushStrategy
+	}
+	if _, err := c.PushStrategies.Get(environment.PushStrategy); err != nil {
+		return I.DeployResponse{
+			StatusCode: http.StatusBadRequest,
+			Error:      err,
+		}
+	}
+	if environment.Bake.Enabled() && environment.PushStrategy != "" && environment.PushStrategy != BlueGreenPushStrategy {
+		return I.DeployResponse{
+			StatusCode: http.StatusBadRequest,
+			Error:      BakeNotSupportedError{environment.PushStrategy},
+		}
+	}
+
+	deploymentInfo.SmokeTests = environment.SmokeTests
+	if deployment.Request.SmokeTests != nil {
+		for _, smokeTest := range deployment.Request.SmokeTests {
+			if reason := smokeTest.Check(); reason != "" {
+				return I.DeployResponse{
+					StatusCode: http.StatusBadRequest,
+					Error:      InvalidSmokeTestError{smokeTest.Name, reason},
+				}
+			}
+		}
+		deploymentInfo.SmokeTests = deployment.Request.SmokeTests
+	}
+
+	for _, serviceInstance := range deployment.Request.ServiceInstances {
+		if reason := serviceInstance.Check(); reason != "" {
+			return I.DeployResponse{
+				StatusCode: http.StatusBadRequest,
+				Error:      InvalidServiceInstanceError{serviceInstance.Name, reason},
+			}
+		}
+	}
+	deploymentInfo.ServiceInstances = deployment.Request.ServiceInstances
+
+	deploymentInfo.UserProvidedServices, err = c.userProvidedServices(cf.Environment, environment.UserProvidedServices, deployment.Request.UserProvidedServices)
+	if err != nil {
+		c.Log.Error(err)
+		return I.DeployResponse{
+			StatusCode: http.StatusBadRequest,
+			Error:      err,
+		}
+	}
+
 	deploymentInfo.Username = auth.Username
 	deploymentInfo.Password = auth.Password
 	deploymentInfo.Domain = environment.Domain
@@ -145,6 +399,8 @@ func (c *PushController) RunDeployment(deployment request.PostDeploymentRequest,
 		ArtifactURL: deploymentInfo.ArtifactURL,
 		Data:        deploymentInfo.Data,
 		Log:         c.Log,
+
+		PromotedFrom: deploymentInfo.PromotedFrom,
 	})
 	if err != nil {
 		c.Log.Error(err)
@@ -177,9 +433,290 @@ func (c *PushController) RunDeployment(deployment request.PostDeploymentRequest,
 
 	deployResponse = *<-reqChannel1
 
+	c.saveDeployment(deployment, deploymentInfo, environment, deployResponse.Error)
+
 	return deployResponse
 }
 
+// retry replaces the artifact, manifest and environment variables of the request with the ones
+// of the deployment it retries and returns the foundations to push to.
+func (c *PushController) retry(deployment request.PostDeploymentRequest) (request.PostDeploymentRequest, []string, int, error) {
+	retry := deployment.Request.Retry
+
+	var (
+		record I.DeploymentRecord
+		ok     bool
+	)
+	if c.History != nil {
+		record, ok = c.History.Get(retry.UUID)
+	}
+	if !ok {
+		return deployment, nil, http.StatusNotFound, DeploymentNotFoundError{retry.UUID}
+	}
+
+	cf := deployment.CFContext
+	if record.CFContext.Environment != cf.Environment || record.CFContext.Organization != cf.Organization ||
+		record.CFContext.Space != cf.Space || record.CFContext.Application != cf.Application {
+		return deployment, nil, http.StatusBadRequest, RetryMismatchError{retry.UUID}
+	}
+
+	foundations := retry.Foundations
+	if len(foundations) == 0 {
+		foundations = record.FailedFoundations
+	}
+	if len(foundations) == 0 {
+		return deployment, nil, http.StatusBadRequest, NothingToRetryError{retry.UUID}
+	}
+
+	c.Log.Infof("retrying deployment %s on %s", retry.UUID, strings.Join(foundations, ", "))
+
+	body, err := c.History.Artifact(record)
+	if err != nil {
+		return deployment, nil, http.StatusInternalServerError, err
+	}
+	deployment.Type = record.ContentType
+	deployment.Body = &body
+	deployment.Request.ArtifactUrl = record.ArtifactURL
+	deployment.Request.Manifest = record.Manifest
+	deployment.Request.EnvironmentVariables = record.EnvironmentVariables
+	deployment.Request.SecretEnvironmentVariables = record.SecretEnvironmentVariables
+	deployment.Request.HealthCheckEndpoint = record.HealthCheckEndpoint
+	deployment.Request.SmokeTests = record.SmokeTests
+	deployment.Request.ServiceInstances = record.ServiceInstances
+	deployment.Request.UserProvidedServices = record.UserProvidedServices
+	deployment.Request.Data = record.Data
+	deployment.Request.PromotedFrom = record.PromotedFrom
+
+	return deployment, foundations, http.StatusOK, nil
+}
+
+// promote replaces the artifact, manifest and environment variables of the request with the ones of
+// the deployment it promotes. The environment variables and data of the request are merged over the
+// ones of the deployment, and its health check endpoint and service instances replace them when set.
+func (c *PushController) promote(deployment request.PostDeploymentRequest, response io.Writer) (request.PostDeploymentRequest, int, error) {
+	promotion := deployment.Request.Promote
+
+	if deployment.Request.Retry.UUID != "" {
+		return deployment, http.StatusBadRequest, InvalidPromotionError{"a request cannot both retry and promote"}
+	}
+	if deployment.Request.ArtifactUrl != "" || deployment.Request.Manifest != "" {
+		return deployment, http.StatusBadRequest, InvalidPromotionError{"the artifact and manifest of a promotion cannot be overridden"}
+	}
+
+	cf := deployment.CFContext
+	from := I.CFContext{
+		Environment:  strings.ToLower(promotion.Environment),
+		Organization: strings.ToLower(promotion.Org),
+		Space:        strings.ToLower(promotion.Space),
+		Application:  cf.Application,
+	}
+	if from.Organization == "" {
+		from.Organization = cf.Organization
+	}
+	if from.Space == "" {
+		from.Space = cf.Space
+	}
+
+	if from.Environment == cf.Environment && from.Organization == cf.Organization && from.Space == cf.Space {
+		return deployment, http.StatusBadRequest, InvalidPromotionError{"the application cannot be promoted to where it is deployed from"}
+	}
+
+	var (
+		record I.DeploymentRecord
+		ok     bool
+	)
+	if c.History != nil && promotion.UUID != "" {
+		record, ok = c.History.Get(promotion.UUID)
+		if !ok {
+			return deployment, http.StatusNotFound, DeploymentNotFoundError{promotion.UUID}
+		}
+		if record.CFContext.Environment != from.Environment || record.CFContext.Organization != from.Organization ||
+			record.CFContext.Space != from.Space || record.CFContext.Application != from.Application {
+			return deployment, http.StatusBadRequest, PromotionMismatchError{promotion.UUID, from}
+		}
+		if !record.Succeeded {
+			return deployment, http.StatusBadRequest, DeploymentNotSucceededError{promotion.UUID}
+		}
+	} else if c.History != nil {
+		record, ok = c.History.LastSucceeded(from)
+	}
+	if !ok {
+		return deployment, http.StatusNotFound, NothingToPromoteError{from}
+	}
+
+	c.Log.Infof("promoting deployment %s of %s from %s to %s", record.UUID, cf.Application, from.Environment, cf.Environment)
+	fmt.Fprintf(response, "promoting deployment %s from %s\n", record.UUID, from.Environment)
+
+	body, err := c.History.Artifact(record)
+	if err != nil {
+		return deployment, http.StatusInternalServerError, err
+	}
+	deployment.Type = record.ContentType
+	deployment.Body = &body
+	deployment.Request.ArtifactUrl = record.ArtifactURL
+	deployment.Request.Manifest = record.Manifest
+	deployment.Request.EnvironmentVariables, deployment.Request.SecretEnvironmentVariables = mergeEnvironmentVariables(
+		record.EnvironmentVariables, record.SecretEnvironmentVariables,
+		deployment.Request.EnvironmentVariables, deployment.Request.SecretEnvironmentVariables)
+	deployment.Request.Data = mergeData(record.Data, deployment.Request.Data)
+	deployment.Request.PromotedFrom = record.UUID
+
+	if deployment.Request.HealthCheckEndpoint == "" {
+		deployment.Request.HealthCheckEndpoint = record.HealthCheckEndpoint
+	}
+	if deployment.Request.ServiceInstances == nil {
+		deployment.Request.ServiceInstances = record.ServiceInstances
+	}
+
+	return deployment, http.StatusOK, nil
+}
+
+// mergeEnvironmentVariables merges the overrides over the base environment variables. A variable stays
+// secret when either side names it secret, so that a value the overrides replace is masked as well.
+func mergeEnvironmentVariables(base map[string]string, baseSecrets []string, overrides map[string]string, overrideSecrets []string) (map[string]string, []string) {
+	var secretNames []string
+	seen := map[string]bool{}
+	for _, name := range append(append([]string{}, baseSecrets...), overrideSecrets...) {
+		if !seen[name] {
+			seen[name] = true
+			secretNames = append(secretNames, name)
+		}
+	}
+
+	if base == nil && overrides == nil {
+		return nil, secretNames
+	}
+
+	merged := map[string]string{}
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range overrides {
+		merged[key] = value
+	}
+	return merged, secretNames
+}
+
+func mergeData(base, overrides map[string]interface{}) map[string]interface{} {
+	if base == nil && overrides == nil {
+		return nil
+	}
+
+	merged := map[string]interface{}{}
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range overrides {
+		merged[key] = value
+	}
+	return merged
+}
+
+// userProvidedServices returns the user-provided services of the environment, replaced by the ones
+// of the request with the same name, followed by the other ones of the request. The secrets of the
+// environment their credentials reference are resolved, so the request only ever holds the references.
+func (c *PushController) userProvidedServices(environmentName string, environmentServices, requestServices []structs.UserProvidedService) ([]structs.UserProvidedService, error) {
+	var (
+		services []structs.UserProvidedService
+		index    = map[string]int{}
+	)
+
+	for _, service := range append(environmentServices, requestServices...) {
+		if reason := service.Check(); reason != "" {
+			return nil, InvalidUserProvidedServiceError{service.Name, reason}
+		}
+
+		if i, ok := index[service.Name]; ok {
+			services[i] = service
+			continue
+		}
+		index[service.Name] = len(services)
+		services = append(services, service)
+	}
+
+	for i, service := range services {
+		resolved, err := secrets.Resolve(c.SecretStore, environmentName, service)
+		if err != nil {
+			return nil, InvalidUserProvidedServiceError{service.Name, err.Error()}
+		}
+		services[i] = resolved
+	}
+
+	return services, nil
+}
+
+// retryEnvironment narrows the environment down to the given foundations. They are pushed to
+// all at once, as a canary or wave configured for the whole environment may not be among them.
+func retryEnvironment(environment structs.Environment, foundations []string) (structs.Environment, error) {
+	selected := map[string]bool{}
+	for _, foundation := range foundations {
+		found := false
+		for _, foundationURL := range environment.Foundations {
+			if foundation == foundationURL || foundation == environment.GetFoundation(foundationURL).Name {
+				selected[foundationURL] = true
+				found = true
+			}
+		}
+
+		if !found {
+			return environment, UnknownFoundationError{foundation}
+		}
+	}
+
+	subset := make([]string, 0, len(selected))
+	for _, foundationURL := range environment.Foundations {
+		if selected[foundationURL] {
+			subset = append(subset, foundationURL)
+		}
+	}
+
+	environment.Foundations = subset
+	environment.Strategy = structs.BlueGreenStrategy
+
+	return environment, nil
+}
+
+// saveDeployment remembers the deployment and the foundations it failed on so that it can be retried.
+func (c *PushController) saveDeployment(deployment request.PostDeploymentRequest, deploymentInfo *structs.DeploymentInfo, environment structs.Environment, err error) {
+	if c.History == nil {
+		return
+	}
+
+	record := I.DeploymentRecord{
+		UUID:                       deploymentInfo.UUID,
+		CFContext:                  deployment.CFContext,
+		ContentType:                deploymentInfo.ContentType,
+		ArtifactURL:                deploymentInfo.ArtifactURL,
+		Manifest:                   deploymentInfo.Manifest,
+		EnvironmentVariables:       deploymentInfo.EnvironmentVariables,
+		SecretEnvironmentVariables: deployment.Request.SecretEnvironmentVariables,
+		HealthCheckEndpoint:        deploymentInfo.HealthCheckEndpoint,
+		SmokeTests:                 deployment.Request.SmokeTests,
+		ServiceInstances:           deployment.Request.ServiceInstances,
+		UserProvidedServices:       deployment.Request.UserProvidedServices,
+		Data:                       deployment.Request.Data,
+		Foundations:                environment.Foundations,
+		Succeeded:                  err == nil,
+		PromotedFrom:               deploymentInfo.PromotedFrom,
+	}
+
+	if partial, ok := err.(bluegreen.PartialSuccessError); ok {
+		record.FailedFoundations = partial.FailedFoundationURLs
+	} else if err != nil {
+		record.FailedFoundations = environment.Foundations
+	}
+
+	var artifact []byte
+	if deploymentInfo.ContentType != "application/json" {
+		artifact = *deployment.Body
+	}
+
+	saveErr := c.History.Save(record, artifact)
+	if saveErr != nil {
+		c.Log.Errorf("cannot remember deployment %s: %s", deploymentInfo.UUID, saveErr)
+	}
+}
+
 func (c *PushController) emitDeployFinish(deployEventData *structs.DeployEventData, response io.ReadWriter, cf I.CFContext, auth I.Authorization, environment structs.Environment, deployResponse *I.DeployResponse, deploymentLogger I.DeploymentLogger) {
 	deploymentLogger.Debugf("emitting a %s event", constants.DeployFinishEvent)
 	finishErr := c.EventManager.Emit(I.Event{Type: constants.DeployFinishEvent, Data: deployEventData})
@@ -212,8 +749,15 @@ func (c *PushController) emitDeployFinish(deployEventData *structs.DeployEventDa
 }
 
 func (c PushController) emitDeploySuccessOrFailure(deployEventData *structs.DeployEventData, response io.ReadWriter, cf I.CFContext, auth I.Authorization, environment structs.Environment, deployResponse *I.DeployResponse, deploymentLogger I.DeploymentLogger) {
+	partial, isPartial := deployResponse.Error.(bluegreen.PartialSuccessError)
+
 	deployEvent := I.Event{Type: constants.DeploySuccessEvent, Data: deployEventData}
-	if deployResponse.Error != nil {
+	if isPartial {
+		c.printPartialSuccess(response, partial, deployEventData.DeploymentInfo.UUID)
+
+		deployEvent.Type = constants.DeployPartialSuccessEvent
+		deployEvent.Error = deployResponse.Error
+	} else if deployResponse.Error != nil {
 		c.printErrors(response, &deployResponse.Error)
 
 		deployEvent.Type = constants.DeployFailureEvent
@@ -228,7 +772,21 @@ func (c PushController) emitDeploySuccessOrFailure(deployEventData *structs.Depl
 	}
 
 	var event I.IEvent
-	if deployResponse.Error != nil {
+	if isPartial {
+		event = DeployPartialSuccessEvent{
+			CFContext:   cf,
+			Auth:        auth,
+			Body:        deployEventData.RequestBody,
+			ContentType: deployEventData.DeploymentInfo.ContentType,
+			Environment: environment,
+			Response:    deployEventData.Response,
+			Data:        deployEventData.DeploymentInfo.Data,
+			Succeeded:   partial.Succeeded,
+			Failed:      partial.Failed,
+			Error:       deployResponse.Error,
+			Log:         c.Log,
+		}
+	} else if deployResponse.Error != nil {
 		event = DeployFailureEvent{
 			CFContext:   cf,
 			Auth:        auth,
@@ -263,6 +821,28 @@ func (c PushController) emitDeploySuccessOrFailure(deployEventData *structs.Depl
 
 }
 
+// printPartialSuccess prints the foundations the deployment succeeded and failed on, and how to retry
+// the failed ones. The error finder is not run: the errors of the failed foundations are already known.
+func (c PushController) printPartialSuccess(response io.ReadWriter, err bluegreen.PartialSuccessError, uuid string) {
+	fmt.Fprintln(response)
+	fmt.Fprintln(response, "<conveyor-error>")
+	fmt.Fprintln(response, "********** Partial Deployment Detected **********")
+	fmt.Fprintln(response, "****")
+	fmt.Fprintln(response)
+	fmt.Fprintln(response, "Deployed to: "+strings.Join(err.Succeeded, ", "))
+	fmt.Fprintln(response, "Not deployed to: "+strings.Join(err.Failed, ", "))
+	fmt.Fprintln(response)
+	for _, foundationErr := range err.Errors {
+		fmt.Fprintln(response, "Error: "+foundationErr.Error())
+	}
+	fmt.Fprintln(response)
+	fmt.Fprintf(response, "Retry the foundations it was not deployed to with {\"retry\": {\"uuid\": \"%s\"}}\n", uuid)
+	fmt.Fprintln(response)
+	fmt.Fprintln(response, "****")
+	fmt.Fprintln(response, "*************************************************")
+	fmt.Fprintln(response, "</conveyor-error>")
+}
+
 func (c PushController) printErrors(response io.ReadWriter, err *error) {
 	tempBuffer := bytes.Buffer{}
 	tempBuffer.ReadFrom(response)
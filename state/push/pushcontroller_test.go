@@ -13,9 +13,11 @@ import (
 	D "github.com/compozed/deployadactyl/controller/deployer"
 	"github.com/compozed/deployadactyl/controller/deployer/bluegreen"
 	"github.com/compozed/deployadactyl/controller/deployer/error_finder"
+	"github.com/compozed/deployadactyl/history"
 	I "github.com/compozed/deployadactyl/interfaces"
 	"github.com/compozed/deployadactyl/mocks"
 	"github.com/compozed/deployadactyl/randomizer"
+	"github.com/compozed/deployadactyl/redact"
 	"github.com/compozed/deployadactyl/request"
 	"github.com/compozed/deployadactyl/state"
 	"github.com/compozed/deployadactyl/state/push"
@@ -25,6 +27,7 @@ import (
 	. "github.com/onsi/gomega"
 	. "github.com/onsi/gomega/gbytes"
 	"github.com/op/go-logging"
+	"github.com/spf13/afero"
 )
 
 var _ = Describe("RunDeployment", func() {
@@ -294,6 +297,25 @@ var _ = Describe("RunDeployment", func() {
 		})
 	})
 
+	Context("when the local disk is running out of space", func() {
+		It("rejects the deployment with an Insufficient Storage error", func() {
+			diskGuard := &mocks.DiskGuard{}
+			diskGuard.CheckCall.Returns.Error = errors.New("insufficient disk space")
+			controller.DiskGuard = diskGuard
+
+			deployment.CFContext = I.CFContext{Environment: environment, Organization: org, Space: space, Application: appName}
+			deployment.Type = "application/zip"
+			response := &bytes.Buffer{}
+
+			deployResponse := controller.RunDeployment(request.PostDeploymentRequest{Deployment: deployment}, response)
+
+			Expect(deployResponse.StatusCode).To(Equal(http.StatusInsufficientStorage))
+			Expect(deployResponse.Error).To(MatchError("insufficient disk space"))
+			Expect(response.String()).To(ContainSubstring("insufficient disk space"))
+			Expect(deployer.DeployCall.Called).To(Equal(0))
+		})
+	})
+
 	Context("when SILENT_DEPLOY_ENVIRONMENT is true", func() {
 		It("channel resolves true when no errors occur", func() {
 			deployment.CFContext.Environment = environment
@@ -563,6 +585,626 @@ var _ = Describe("RunDeployment", func() {
 					})
 				})
 
+				Context("when a strategy is requested", func() {
+					BeforeEach(func() {
+						deployment.CFContext.Environment = environment
+						deployment.Type = "application/zip"
+					})
+
+					It("deploys with the requested strategy", func() {
+						postDeploymentRequest := request.PostDeploymentRequest{
+							Deployment: deployment,
+							Request:    request.PostRequest{Strategy: structs.CanaryStrategy},
+						}
+
+						controller.RunDeployment(postDeploymentRequest, response)
+
+						Expect(deployer.DeployCall.Received.Env.Strategy).To(Equal(structs.CanaryStrategy))
+					})
+
+					It("returns an error with StatusBadRequest when the strategy is unknown", func() {
+						postDeploymentRequest := request.PostDeploymentRequest{
+							Deployment: deployment,
+							Request:    request.PostRequest{Strategy: "yolo"},
+						}
+
+						deploymentResponse := controller.RunDeployment(postDeploymentRequest, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusBadRequest))
+						Expect(deploymentResponse.Error).To(MatchError(push.InvalidStrategyError{"yolo"}))
+						Expect(deployer.DeployCall.Called).To(Equal(0))
+					})
+
+					It("deploys with the requested push strategy", func() {
+						postDeploymentRequest := request.PostDeploymentRequest{
+							Deployment: deployment,
+							Request:    request.PostRequest{PushStrategy: push.InPlacePushStrategy},
+						}
+
+						controller.RunDeployment(postDeploymentRequest, response)
+
+						Expect(deployer.DeployCall.Received.Env.PushStrategy).To(Equal(push.InPlacePushStrategy))
+					})
+
+					It("deploys a canary with the in-place push strategy", func() {
+						postDeploymentRequest := request.PostDeploymentRequest{
+							Deployment: deployment,
+							Request:    request.PostRequest{Strategy: structs.CanaryStrategy, PushStrategy: push.InPlacePushStrategy},
+						}
+
+						deploymentResponse := controller.RunDeployment(postDeploymentRequest, response)
+
+						Expect(deploymentResponse.Error).ToNot(HaveOccurred())
+						Expect(deployer.DeployCall.Received.Env.Strategy).To(Equal(structs.CanaryStrategy))
+						Expect(deployer.DeployCall.Received.Env.PushStrategy).To(Equal(push.InPlacePushStrategy))
+					})
+
+					It("deploys in waves with the in-place push strategy of the environment", func() {
+						wavesEnvironment := envResolver.Config.Environments[environment]
+						wavesEnvironment.PushStrategy = push.InPlacePushStrategy
+						envResolver.Config.Environments[environment] = wavesEnvironment
+
+						postDeploymentRequest := request.PostDeploymentRequest{
+							Deployment: deployment,
+							Request:    request.PostRequest{Strategy: structs.RollingStrategy},
+						}
+
+						deploymentResponse := controller.RunDeployment(postDeploymentRequest, response)
+
+						Expect(deploymentResponse.Error).ToNot(HaveOccurred())
+						Expect(deployer.DeployCall.Received.Env.Strategy).To(Equal(structs.RollingStrategy))
+						Expect(deployer.DeployCall.Received.Env.PushStrategy).To(Equal(push.InPlacePushStrategy))
+					})
+
+					It("returns an error with StatusBadRequest when an in-place push strategy is requested for an environment that bakes", func() {
+						bakingEnvironment := envResolver.Config.Environments[environment]
+						bakingEnvironment.Bake = structs.Bake{Duration: "10m"}
+						envResolver.Config.Environments[environment] = bakingEnvironment
+
+						postDeploymentRequest := request.PostDeploymentRequest{
+							Deployment: deployment,
+							Request:    request.PostRequest{PushStrategy: push.RollingPushStrategy},
+						}
+
+						deploymentResponse := controller.RunDeployment(postDeploymentRequest, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusBadRequest))
+						Expect(deploymentResponse.Error).To(MatchError(push.BakeNotSupportedError{push.RollingPushStrategy}))
+						Expect(deployer.DeployCall.Called).To(Equal(0))
+					})
+
+					It("returns an error with StatusBadRequest when the push strategy is unknown", func() {
+						postDeploymentRequest := request.PostDeploymentRequest{
+							Deployment: deployment,
+							Request:    request.PostRequest{PushStrategy: "yolo"},
+						}
+
+						deploymentResponse := controller.RunDeployment(postDeploymentRequest, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusBadRequest))
+						Expect(deploymentResponse.Error).To(MatchError(push.UnknownPushStrategyError{"yolo"}))
+						Expect(deployer.DeployCall.Called).To(Equal(0))
+					})
+				})
+
+				Context("when smoke tests are configured", func() {
+					BeforeEach(func() {
+						deployment.CFContext.Environment = environment
+						deployment.Type = "application/zip"
+
+						envResolver.Config.Environments[environment] = structs.Environment{
+							SmokeTests: []structs.SmokeTest{{Name: "home", Path: "/"}},
+						}
+					})
+
+					It("runs the smoke tests of the environment", func() {
+						postDeploymentRequest := request.PostDeploymentRequest{
+							Deployment: deployment,
+							Request:    request.PostRequest{},
+						}
+
+						controller.RunDeployment(postDeploymentRequest, response)
+
+						Expect(deployer.DeployCall.Received.DeploymentInfo.SmokeTests).To(Equal([]structs.SmokeTest{{Name: "home", Path: "/"}}))
+					})
+
+					It("replaces them with the smoke tests of the request", func() {
+						smokeTests := []structs.SmokeTest{{Name: "login", Method: "POST", Path: "/login", ExpectedStatus: []int{201}}}
+						postDeploymentRequest := request.PostDeploymentRequest{
+							Deployment: deployment,
+							Request:    request.PostRequest{SmokeTests: smokeTests},
+						}
+
+						controller.RunDeployment(postDeploymentRequest, response)
+
+						Expect(deployer.DeployCall.Received.DeploymentInfo.SmokeTests).To(Equal(smokeTests))
+					})
+
+					It("returns an error with StatusBadRequest when a smoke test of the request is invalid", func() {
+						postDeploymentRequest := request.PostDeploymentRequest{
+							Deployment: deployment,
+							Request:    request.PostRequest{SmokeTests: []structs.SmokeTest{{Name: "login", ExpectedStatus: []int{42}}}},
+						}
+
+						deploymentResponse := controller.RunDeployment(postDeploymentRequest, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusBadRequest))
+						Expect(deploymentResponse.Error).To(MatchError(push.InvalidSmokeTestError{"login", "invalid expected status 42"}))
+						Expect(deployer.DeployCall.Called).To(Equal(0))
+					})
+				})
+
+				Context("when service instances are requested", func() {
+					BeforeEach(func() {
+						deployment.CFContext.Environment = environment
+						deployment.Type = "application/zip"
+
+						envResolver.Config.Environments[environment] = structs.Environment{}
+					})
+
+					It("passes them to the deployer", func() {
+						serviceInstances := []structs.ServiceInstance{{Name: "db", Service: "p-mysql", Plan: "small"}}
+						postDeploymentRequest := request.PostDeploymentRequest{
+							Deployment: deployment,
+							Request:    request.PostRequest{ServiceInstances: serviceInstances},
+						}
+
+						controller.RunDeployment(postDeploymentRequest, response)
+
+						Expect(deployer.DeployCall.Received.DeploymentInfo.ServiceInstances).To(Equal(serviceInstances))
+					})
+
+					It("returns an error with StatusBadRequest when a service instance is invalid", func() {
+						postDeploymentRequest := request.PostDeploymentRequest{
+							Deployment: deployment,
+							Request:    request.PostRequest{ServiceInstances: []structs.ServiceInstance{{Name: "db", Service: "p-mysql"}}},
+						}
+
+						deploymentResponse := controller.RunDeployment(postDeploymentRequest, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusBadRequest))
+						Expect(deploymentResponse.Error).To(MatchError(push.InvalidServiceInstanceError{"db", "missing plan"}))
+						Expect(deployer.DeployCall.Called).To(Equal(0))
+					})
+				})
+
+				Context("when user-provided services are requested", func() {
+					var secretStore *mocks.SecretStore
+
+					BeforeEach(func() {
+						deployment.CFContext.Environment = environment
+						deployment.Type = "application/zip"
+
+						secretStore = &mocks.SecretStore{}
+						secretStore.GetCall.Returns.Secrets = map[string]string{"payments-token": "s3cr3t"}
+						controller.SecretStore = secretStore
+
+						envResolver.Config.Environments[environment] = structs.Environment{
+							UserProvidedServices: []structs.UserProvidedService{
+								{Name: "payments-api", Credentials: map[string]interface{}{"url": "https://payments.example.com"}},
+								{Name: "splunk", SyslogDrainURL: "syslog-tls://logs.example.com:6514"},
+							},
+						}
+					})
+
+					It("replaces the ones of the environment with the same name and resolves their secrets", func() {
+						postDeploymentRequest := request.PostDeploymentRequest{
+							Deployment: deployment,
+							Request: request.PostRequest{UserProvidedServices: []structs.UserProvidedService{
+								{Name: "payments-api", Credentials: map[string]interface{}{"token": "((payments-token))"}},
+								{Name: "auth", RouteServiceURL: "https://auth.example.com"},
+							}},
+						}
+
+						controller.RunDeployment(postDeploymentRequest, response)
+
+						Expect(deployer.DeployCall.Received.DeploymentInfo.UserProvidedServices).To(Equal([]structs.UserProvidedService{
+							{Name: "payments-api", Credentials: map[string]interface{}{"token": "s3cr3t"}},
+							{Name: "splunk", SyslogDrainURL: "syslog-tls://logs.example.com:6514"},
+							{Name: "auth", RouteServiceURL: "https://auth.example.com"},
+						}))
+						Expect(postDeploymentRequest.Request.UserProvidedServices[0].Credentials["token"]).To(Equal("((payments-token))"))
+						Expect(secretStore.GetCall.Received.Environments).To(Equal([]string{environment}))
+					})
+
+					It("returns an error with StatusBadRequest when a secret cannot be found", func() {
+						postDeploymentRequest := request.PostDeploymentRequest{
+							Deployment: deployment,
+							Request: request.PostRequest{UserProvidedServices: []structs.UserProvidedService{
+								{Name: "payments-api", Credentials: map[string]interface{}{"token": "((missing))"}},
+							}},
+						}
+
+						deploymentResponse := controller.RunDeployment(postDeploymentRequest, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusBadRequest))
+						Expect(deploymentResponse.Error).To(MatchError(push.InvalidUserProvidedServiceError{"payments-api", "cannot find secret missing of environment " + environment}))
+						Expect(deployer.DeployCall.Called).To(Equal(0))
+					})
+
+					It("returns an error with StatusBadRequest when a user-provided service has no name", func() {
+						postDeploymentRequest := request.PostDeploymentRequest{
+							Deployment: deployment,
+							Request:    request.PostRequest{UserProvidedServices: []structs.UserProvidedService{{SyslogDrainURL: "syslog://logs.example.com"}}},
+						}
+
+						deploymentResponse := controller.RunDeployment(postDeploymentRequest, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusBadRequest))
+						Expect(deploymentResponse.Error).To(MatchError(push.InvalidUserProvidedServiceError{"", "missing name"}))
+					})
+				})
+
+				Context("when a previous deployment is retried", func() {
+					var foundations []structs.Foundation
+
+					BeforeEach(func() {
+						deployment.CFContext.Environment = environment
+						deployment.Type = "application/json"
+
+						foundations = []structs.Foundation{
+							{Name: "east", APIURL: "https://api.east.example.com"},
+							{Name: "west", APIURL: "https://api.west.example.com"},
+							{Name: "north", APIURL: "https://api.north.example.com"},
+						}
+						envResolver.Config.Environments[environment] = structs.Environment{
+							Strategy:              structs.CanaryStrategy,
+							FoundationDefinitions: foundations,
+							Foundations:           []string{foundations[0].APIURL, foundations[1].APIURL, foundations[2].APIURL},
+						}
+
+						controller.History = history.NewDeploymentHistory(&afero.Afero{Fs: afero.NewMemMapFs()}, "/history.json", 10, controller.Log.Log)
+
+						deployer.DeployCall.Returns.StatusCode = http.StatusMultiStatus
+						deployer.DeployCall.Returns.Error = bluegreen.PartialSuccessError{FailedFoundationURLs: []string{foundations[1].APIURL}}
+
+						controller.RunDeployment(request.PostDeploymentRequest{
+							Deployment: deployment,
+							Request: request.PostRequest{
+								ArtifactUrl:          "https://example.com/artifact.zip",
+								Manifest:             "manifest",
+								EnvironmentVariables: map[string]string{"key": "value"},
+							},
+						}, response)
+
+						deployer.DeployCall.Returns.StatusCode = http.StatusOK
+						deployer.DeployCall.Returns.Error = nil
+					})
+
+					It("pushes the same artifact to the foundations it failed on", func() {
+						deploymentResponse := controller.RunDeployment(request.PostDeploymentRequest{
+							Deployment: deployment,
+							Request:    request.PostRequest{Retry: request.Retry{UUID: uuid}},
+						}, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusOK))
+						Expect(deployer.DeployCall.Called).To(Equal(2))
+						Expect(deployer.DeployCall.Received.Env.Foundations).To(Equal([]string{foundations[1].APIURL}))
+						Expect(deployer.DeployCall.Received.Env.Strategy).To(Equal(structs.BlueGreenStrategy))
+						Expect(deployer.DeployCall.Received.DeploymentInfo.ArtifactURL).To(Equal("https://example.com/artifact.zip"))
+						Expect(deployer.DeployCall.Received.DeploymentInfo.Manifest).To(Equal("manifest"))
+						Expect(deployer.DeployCall.Received.DeploymentInfo.EnvironmentVariables).To(Equal(map[string]string{"key": "value"}))
+					})
+
+					It("pushes the artifact that was uploaded again", func() {
+						upload := []byte("zip bytes")
+						deployer.DeployCall.Returns.StatusCode = http.StatusInternalServerError
+						deployer.DeployCall.Returns.Error = errors.New("push failed")
+						controller.Log.UUID = "upload-" + uuid
+						controller.RunDeployment(request.PostDeploymentRequest{
+							Deployment: I.Deployment{Body: &upload, CFContext: deployment.CFContext, Type: "application/zip"},
+						}, response)
+						controller.Log.UUID = uuid
+						deployer.DeployCall.Returns.StatusCode = http.StatusOK
+						deployer.DeployCall.Returns.Error = nil
+
+						deploymentResponse := controller.RunDeployment(request.PostDeploymentRequest{
+							Deployment: deployment,
+							Request:    request.PostRequest{Retry: request.Retry{UUID: "upload-" + uuid}},
+						}, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusOK))
+						Expect(deployer.DeployCall.Received.DeploymentInfo.ContentType).To(Equal("application/zip"))
+						Expect(ioutil.ReadAll(deployer.DeployCall.Received.DeploymentInfo.Body)).To(Equal(upload))
+					})
+
+					It("masks the secret environment variables of the deployment it retries", func() {
+						deployer.DeployCall.Returns.StatusCode = http.StatusInternalServerError
+						deployer.DeployCall.Returns.Error = errors.New("push failed")
+						controller.Log.UUID = "secret-" + uuid
+						controller.RunDeployment(request.PostDeploymentRequest{
+							Deployment: deployment,
+							Request: request.PostRequest{
+								ArtifactUrl:                "https://example.com/artifact.zip",
+								EnvironmentVariables:       map[string]string{"DB_PASSWORD": "correct-horse"},
+								SecretEnvironmentVariables: []string{"DB_PASSWORD"},
+							},
+						}, response)
+						controller.Log.UUID = uuid
+						deployer.DeployCall.Returns.StatusCode = http.StatusOK
+						deployer.DeployCall.Returns.Error = nil
+
+						redactor := redact.New()
+						controller.Redactor = redactor
+
+						deploymentResponse := controller.RunDeployment(request.PostDeploymentRequest{
+							Deployment: deployment,
+							Request:    request.PostRequest{Retry: request.Retry{UUID: "secret-" + uuid}},
+						}, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusOK))
+						Expect(deployer.DeployCall.Received.DeploymentInfo.EnvironmentVariables).To(Equal(map[string]string{"DB_PASSWORD": "correct-horse"}))
+						Expect(redactor.Redact("DB_PASSWORD=correct-horse")).To(Equal("DB_PASSWORD=" + redact.Mask))
+
+						record, ok := controller.History.Get(uuid)
+						Expect(ok).To(BeTrue())
+						Expect(record.SecretEnvironmentVariables).To(Equal([]string{"DB_PASSWORD"}))
+					})
+
+					It("pushes to the listed foundations", func() {
+						controller.RunDeployment(request.PostDeploymentRequest{
+							Deployment: deployment,
+							Request:    request.PostRequest{Retry: request.Retry{UUID: uuid, Foundations: []string{"north", foundations[0].APIURL}}},
+						}, response)
+
+						Expect(deployer.DeployCall.Received.Env.Foundations).To(Equal([]string{foundations[0].APIURL, foundations[2].APIURL}))
+					})
+
+					It("returns an error with StatusNotFound when the deployment is unknown", func() {
+						deploymentResponse := controller.RunDeployment(request.PostDeploymentRequest{
+							Deployment: deployment,
+							Request:    request.PostRequest{Retry: request.Retry{UUID: "unknown"}},
+						}, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusNotFound))
+						Expect(deploymentResponse.Error).To(MatchError(push.DeploymentNotFoundError{"unknown"}))
+						Expect(deployer.DeployCall.Called).To(Equal(1))
+					})
+
+					It("returns an error with StatusBadRequest when the deployment was made to another application", func() {
+						deployment.CFContext.Application = "other-" + appName
+
+						deploymentResponse := controller.RunDeployment(request.PostDeploymentRequest{
+							Deployment: deployment,
+							Request:    request.PostRequest{Retry: request.Retry{UUID: uuid}},
+						}, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusBadRequest))
+						Expect(deploymentResponse.Error).To(MatchError(push.RetryMismatchError{uuid}))
+					})
+
+					It("returns an error with StatusBadRequest when a listed foundation is unknown", func() {
+						deploymentResponse := controller.RunDeployment(request.PostDeploymentRequest{
+							Deployment: deployment,
+							Request:    request.PostRequest{Retry: request.Retry{UUID: uuid, Foundations: []string{"south"}}},
+						}, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusBadRequest))
+						Expect(deploymentResponse.Error).To(MatchError(push.UnknownFoundationError{"south"}))
+						Expect(deployer.DeployCall.Called).To(Equal(1))
+					})
+
+					It("returns an error with StatusBadRequest when the deployment did not fail", func() {
+						controller.RunDeployment(request.PostDeploymentRequest{
+							Deployment: deployment,
+							Request:    request.PostRequest{Retry: request.Retry{UUID: uuid}},
+						}, response)
+
+						deploymentResponse := controller.RunDeployment(request.PostDeploymentRequest{
+							Deployment: deployment,
+							Request:    request.PostRequest{Retry: request.Retry{UUID: uuid}},
+						}, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusBadRequest))
+						Expect(deploymentResponse.Error).To(MatchError(push.NothingToRetryError{uuid}))
+					})
+				})
+
+				Context("when a deployment is promoted", func() {
+					var (
+						staging     I.CFContext
+						stagingUUID string
+					)
+
+					BeforeEach(func() {
+						deployment.CFContext = I.CFContext{Environment: "prod", Organization: "org", Space: "space", Application: "app"}
+						deployment.Type = "application/json"
+
+						staging = deployment.CFContext
+						staging.Environment = "staging"
+						stagingUUID = "staging-" + randomizer.StringRunes(10)
+
+						envResolver.Config.Environments["staging"] = structs.Environment{}
+						envResolver.Config.Environments["prod"] = structs.Environment{}
+
+						controller.History = history.NewDeploymentHistory(&afero.Afero{Fs: afero.NewMemMapFs()}, "/history.json", 10, controller.Log.Log)
+						controller.Log.UUID = stagingUUID
+
+						controller.RunDeployment(request.PostDeploymentRequest{
+							Deployment: I.Deployment{Body: deployment.Body, CFContext: staging, Type: "application/json"},
+							Request: request.PostRequest{
+								ArtifactUrl:          "https://example.com/artifact.zip",
+								Manifest:             "manifest",
+								EnvironmentVariables: map[string]string{"key": "value", "stage": "staging"},
+							},
+						}, response)
+
+						controller.Log.UUID = uuid
+						deployer.DeployCall.Returns.StatusCode = http.StatusOK
+					})
+
+					It("pushes the artifact, manifest and environment variables that succeeded", func() {
+						deploymentResponse := controller.RunDeployment(request.PostDeploymentRequest{
+							Deployment: deployment,
+							Request: request.PostRequest{
+								Promote:              request.Promotion{Environment: "staging"},
+								EnvironmentVariables: map[string]string{"stage": "prod"},
+							},
+						}, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusOK))
+						Expect(deployer.DeployCall.Called).To(Equal(2))
+						Expect(deployer.DeployCall.Received.DeploymentInfo.Environment).To(Equal("prod"))
+						Expect(deployer.DeployCall.Received.DeploymentInfo.ArtifactURL).To(Equal("https://example.com/artifact.zip"))
+						Expect(deployer.DeployCall.Received.DeploymentInfo.Manifest).To(Equal("manifest"))
+						Expect(deployer.DeployCall.Received.DeploymentInfo.EnvironmentVariables).To(Equal(map[string]string{"key": "value", "stage": "prod"}))
+						Expect(response.String()).To(ContainSubstring("promoting deployment " + stagingUUID + " from staging"))
+					})
+
+					It("records the deployment it was promoted from", func() {
+						controller.RunDeployment(request.PostDeploymentRequest{
+							Deployment: deployment,
+							Request:    request.PostRequest{Promote: request.Promotion{Environment: "staging"}},
+						}, response)
+
+						Expect(deployer.DeployCall.Received.DeploymentInfo.PromotedFrom).To(Equal(stagingUUID))
+
+						record, ok := controller.History.Get(uuid)
+						Expect(ok).To(BeTrue())
+						Expect(record.PromotedFrom).To(Equal(stagingUUID))
+						Expect(record.Succeeded).To(BeTrue())
+					})
+
+					It("masks the secret environment variables of the promoted deployment and of the request", func() {
+						controller.Log.UUID = "secret-" + stagingUUID
+						controller.RunDeployment(request.PostDeploymentRequest{
+							Deployment: I.Deployment{Body: deployment.Body, CFContext: staging, Type: "application/json"},
+							Request: request.PostRequest{
+								ArtifactUrl:                "https://example.com/artifact.zip",
+								EnvironmentVariables:       map[string]string{"DB_PASSWORD": "staging-horse"},
+								SecretEnvironmentVariables: []string{"DB_PASSWORD"},
+							},
+						}, response)
+						controller.Log.UUID = uuid
+
+						redactor := redact.New()
+						controller.Redactor = redactor
+
+						controller.RunDeployment(request.PostDeploymentRequest{
+							Deployment: deployment,
+							Request: request.PostRequest{
+								Promote:                    request.Promotion{Environment: "staging"},
+								EnvironmentVariables:       map[string]string{"API_TOKEN": "prod-token"},
+								SecretEnvironmentVariables: []string{"API_TOKEN"},
+							},
+						}, response)
+
+						Expect(redactor.Redact("staging-horse prod-token")).To(Equal(redact.Mask + " " + redact.Mask))
+
+						record, ok := controller.History.Get(uuid)
+						Expect(ok).To(BeTrue())
+						Expect(record.SecretEnvironmentVariables).To(Equal([]string{"DB_PASSWORD", "API_TOKEN"}))
+					})
+
+					It("promotes the deployment named by its uuid", func() {
+						deploymentResponse := controller.RunDeployment(request.PostDeploymentRequest{
+							Deployment: deployment,
+							Request:    request.PostRequest{Promote: request.Promotion{Environment: "staging", UUID: stagingUUID}},
+						}, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusOK))
+						Expect(deployer.DeployCall.Received.DeploymentInfo.PromotedFrom).To(Equal(stagingUUID))
+					})
+
+					It("returns an error with StatusBadRequest when the named deployment did not succeed", func() {
+						deployer.DeployCall.Returns.StatusCode = http.StatusInternalServerError
+						deployer.DeployCall.Returns.Error = errors.New("push failed")
+						controller.Log.UUID = "failed-" + stagingUUID
+						controller.RunDeployment(request.PostDeploymentRequest{
+							Deployment: I.Deployment{Body: deployment.Body, CFContext: staging, Type: "application/json"},
+							Request:    request.PostRequest{ArtifactUrl: "https://example.com/artifact.zip"},
+						}, response)
+						controller.Log.UUID = uuid
+
+						deploymentResponse := controller.RunDeployment(request.PostDeploymentRequest{
+							Deployment: deployment,
+							Request:    request.PostRequest{Promote: request.Promotion{Environment: "staging", UUID: "failed-" + stagingUUID}},
+						}, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusBadRequest))
+						Expect(deploymentResponse.Error).To(MatchError(push.DeploymentNotSucceededError{"failed-" + stagingUUID}))
+						Expect(deployer.DeployCall.Called).To(Equal(2))
+					})
+
+					It("returns an error with StatusNotFound when no deployment succeeded in the environment", func() {
+						envResolver.Config.Environments["qa"] = structs.Environment{}
+
+						deploymentResponse := controller.RunDeployment(request.PostDeploymentRequest{
+							Deployment: deployment,
+							Request:    request.PostRequest{Promote: request.Promotion{Environment: "qa"}},
+						}, response)
+
+						qa := deployment.CFContext
+						qa.Environment = "qa"
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusNotFound))
+						Expect(deploymentResponse.Error).To(MatchError(push.NothingToPromoteError{qa}))
+						Expect(deployer.DeployCall.Called).To(Equal(1))
+					})
+
+					It("returns an error with StatusBadRequest when the artifact is overridden", func() {
+						deploymentResponse := controller.RunDeployment(request.PostDeploymentRequest{
+							Deployment: deployment,
+							Request: request.PostRequest{
+								Promote:     request.Promotion{Environment: "staging"},
+								ArtifactUrl: "https://example.com/other.zip",
+							},
+						}, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusBadRequest))
+						Expect(deploymentResponse.Error).To(BeAssignableToTypeOf(push.InvalidPromotionError{}))
+					})
+
+					It("returns an error with StatusBadRequest when the application is promoted to its own environment", func() {
+						deploymentResponse := controller.RunDeployment(request.PostDeploymentRequest{
+							Deployment: deployment,
+							Request:    request.PostRequest{Promote: request.Promotion{Environment: "PROD"}},
+						}, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusBadRequest))
+						Expect(deploymentResponse.Error).To(BeAssignableToTypeOf(push.InvalidPromotionError{}))
+					})
+				})
+
+				Context("when the environment is frozen", func() {
+					BeforeEach(func() {
+						deployment.CFContext.Environment = environment
+						deployment.Type = "application/zip"
+
+						envResolver.Config.Environments[environment] = structs.Environment{
+							Freezes: []structs.Freeze{{Start: "2000-01-01", End: "2999-12-31", Reason: "forever freeze"}},
+						}
+					})
+
+					It("returns an error with StatusLocked", func() {
+						postDeploymentRequest := request.PostDeploymentRequest{
+							Deployment: deployment,
+							Request:    request.PostRequest{},
+						}
+
+						deploymentResponse := controller.RunDeployment(postDeploymentRequest, response)
+
+						Expect(deploymentResponse.StatusCode).To(Equal(http.StatusLocked))
+						Expect(deploymentResponse.Error).To(BeAssignableToTypeOf(state.DeployLockedError{}))
+						Expect(deploymentResponse.Error.Error()).To(ContainSubstring("forever freeze"))
+						Expect(deployer.DeployCall.Called).To(Equal(0))
+					})
+
+					It("deploys and emits the justification when the freeze is overridden", func() {
+						postDeploymentRequest := request.PostDeploymentRequest{
+							Deployment: deployment,
+							Request: request.PostRequest{
+								FreezeOverride: request.FreezeOverride{OverrideFreeze: true, Justification: "emergency fix"},
+							},
+						}
+
+						controller.RunDeployment(postDeploymentRequest, response)
+
+						Expect(eventManager.EmitEventCall.Received.Events[0]).To(BeAssignableToTypeOf(state.DeployLockOverriddenEvent{}))
+						Expect(eventManager.EmitEventCall.Received.Events[0].(state.DeployLockOverriddenEvent).Justification).To(Equal("emergency fix"))
+						Expect(deployer.DeployCall.Called).To(Equal(1))
+					})
+				})
+
 				Context("when Authorization has values", func() {
 					It("logs checking auth", func() {
 						deployment.CFContext.Environment = environment
@@ -1456,6 +2098,51 @@ var _ = Describe("RunDeployment", func() {
 					})
 				})
 
+				Context("deploy.partial.success event", func() {
+					BeforeEach(func() {
+						deployment.CFContext.Environment = environment
+						deployment.Type = "application/zip"
+
+						deployer.DeployCall.Returns.StatusCode = http.StatusMultiStatus
+						deployer.DeployCall.Returns.Error = bluegreen.PartialSuccessError{
+							Succeeded:            []string{"east"},
+							Failed:               []string{"west"},
+							FailedFoundationURLs: []string{"https://api.west.example.com"},
+							Errors:               []error{errors.New("west failed")},
+						}
+					})
+
+					It("calls Emit with a deploy.partial.success event instead of a deploy.failure event", func() {
+						controller.RunDeployment(request.PostDeploymentRequest{Deployment: deployment}, response)
+
+						Expect(eventManager.EmitCall.Received.Events[1].Type).Should(Equal(constants.DeployPartialSuccessEvent))
+					})
+
+					It("calls EmitEvent with the foundations it succeeded and failed on", func() {
+						controller.RunDeployment(request.PostDeploymentRequest{Deployment: deployment}, response)
+
+						event := eventManager.EmitEventCall.Received.Events[1].(push.DeployPartialSuccessEvent)
+						Expect(event.Succeeded).To(Equal([]string{"east"}))
+						Expect(event.Failed).To(Equal([]string{"west"}))
+						Expect(event.CFContext.Environment).To(Equal(environment))
+					})
+
+					It("prints the foundations and how to retry instead of looking for errors in the logs", func() {
+						errorFinder.FindErrorsCall.Returns.Errors = []I.LogMatchedError{error_finder.CreateLogMatchedError("a description", []string{"some details"}, "a solution", "a code")}
+
+						controller.RunDeployment(request.PostDeploymentRequest{Deployment: deployment}, response)
+
+						responseBytes, _ := ioutil.ReadAll(response)
+						Expect(string(responseBytes)).To(ContainSubstring("Partial Deployment Detected"))
+						Expect(string(responseBytes)).To(ContainSubstring("Deployed to: east"))
+						Expect(string(responseBytes)).To(ContainSubstring("Not deployed to: west"))
+						Expect(string(responseBytes)).To(ContainSubstring("Error: west failed"))
+						Expect(string(responseBytes)).To(ContainSubstring(`"retry": {"uuid": "`))
+						Expect(string(responseBytes)).ToNot(ContainSubstring("Deployment Failure Detected"))
+						Expect(string(responseBytes)).ToNot(ContainSubstring("a description"))
+					})
+				})
+
 				It("prints found errors to the response", func() {
 					deployment.CFContext.Environment = environment
 					deployment.Type = "application/zip"
@@ -4,6 +4,7 @@ package push
 import (
 	"fmt"
 	"io"
+	"time"
 
 	H "github.com/compozed/deployadactyl/eventmanager/handlers/healthchecker"
 	R "github.com/compozed/deployadactyl/eventmanager/handlers/routemapper"
@@ -25,6 +26,8 @@ type Pusher struct {
 	Response       io.ReadWriter
 	Log            I.DeploymentLogger
 	FoundationURL  string
+	FoundationName string
+	AppsDomain     string
 	AppPath        string
 	Environment    S.Environment
 	Fetcher        I.Fetcher
@@ -32,6 +35,31 @@ type Pusher struct {
 	Auth           I.Authorization
 	HealthChecker  H.HealthChecker
 	RouteMapper    R.RouteMapper
+	HookRunner     I.HookRunner
+
+	// ApplicationIndex is the position of the application in a multi-application manifest.
+	ApplicationIndex int
+
+	// CreatedServiceInstances collects the service instances the push created on the foundation,
+	// so that Undo can delete them.
+	CreatedServiceInstances *[]string
+
+	// UpdatedUserProvidedServices collects the user-provided services the push updated, as they were
+	// before, so that Undo can restore them.
+	UpdatedUserProvidedServices *[]S.UserProvidedService
+
+	// KeepStandby keeps the previous version as a standby at cutover even when the environment neither
+	// keeps previous versions nor bakes, so that the applications of a multi-application push that
+	// were already cut over can be rolled back when another one fails.
+	KeepStandby bool
+
+	// SharedQuota leaves the memory check of Verify to the ApplicationsPusher, which checks the memory
+	// of every application of a multi-application push against the quotas at once.
+	SharedQuota bool
+
+	// ServicePollInterval is how often the status of a service instance is checked while it is
+	// provisioned. S.DefaultServicePollInterval is used when it is zero.
+	ServicePollInterval time.Duration
 }
 
 // Login will login to a Cloud Foundry instance.
@@ -56,6 +84,9 @@ func (p Pusher) Initially() error {
 	p.Response.Write(output)
 	if err != nil {
 		p.Log.Errorf("could not login to %s", p.FoundationURL)
+		if timeout, ok := err.(I.TimeoutError); ok && timeout.Timeout() {
+			return err
+		}
 		return state.LoginError{p.FoundationURL, output}
 	}
 
@@ -70,11 +101,6 @@ func (p Pusher) Initially() error {
 // It will map a load balanced domain if provided in the config.yml.
 //
 // Returns Cloud Foundry logs if there is an error.
-
-func (p Pusher) Verify() error {
-	return nil
-}
-
 func (p Pusher) Execute() error {
 
 	var (
@@ -82,34 +108,66 @@ func (p Pusher) Execute() error {
 		err             error
 	)
 
-	err = p.pushApplication(tempAppWithUUID, p.AppPath)
+	err = p.runHooks(S.HookBeforePush, p.DeploymentInfo.AppName)
 	if err != nil {
 		return err
 	}
 
-	if p.DeploymentInfo.HealthCheckEndpoint != "" {
-		healthCheckRequest := H.HealthCheckRequest{
-			HealthCheckEndpoint: p.DeploymentInfo.HealthCheckEndpoint,
-			Courier:             p.Courier,
-			Logger:              p.Log,
-			Environment:         p.DeploymentInfo.Environment,
-			FoundationUrl:       p.FoundationURL,
-			TempAppWithUUID:     tempAppWithUUID,
-			UUID:                p.DeploymentInfo.UUID,
-		}
+	err = p.createServiceInstances()
+	if err != nil {
+		return err
+	}
 
-		err = p.HealthChecker.HealthChecker(healthCheckRequest)
-		if err != nil {
-			return err
-		}
+	err = p.createUserProvidedServices()
+	if err != nil {
+		return err
 	}
 
-	return nil
+	err = p.pushWithServices(tempAppWithUUID, p.AppPath, "")
+	if err != nil {
+		return err
+	}
+
+	return p.HealthCheck()
+}
+
+// HealthCheck checks the health check endpoint of the newly pushed application and runs its smoke tests,
+// if any were requested. The smoke test results are written to the response of the foundation.
+func (p Pusher) HealthCheck() error {
+	if p.DeploymentInfo.HealthCheckEndpoint == "" && len(p.DeploymentInfo.SmokeTests) == 0 {
+		return nil
+	}
+
+	healthCheckRequest := H.HealthCheckRequest{
+		HealthCheckEndpoint: p.DeploymentInfo.HealthCheckEndpoint,
+		Courier:             p.Courier,
+		Logger:              p.Log,
+		Environment:         p.DeploymentInfo.Environment,
+		FoundationUrl:       p.FoundationURL,
+		FoundationName:      p.foundationName(),
+		AppsDomain:          p.AppsDomain,
+		TempAppWithUUID:     p.DeploymentInfo.AppName + TemporaryNameSuffix + p.DeploymentInfo.UUID,
+		UUID:                p.DeploymentInfo.UUID,
+		SmokeTests:          p.DeploymentInfo.SmokeTests,
+		Response:            p.Response,
+	}
+
+	return p.HealthChecker.HealthChecker(healthCheckRequest)
 }
 
 func (p Pusher) PostExecute() error {
 	tempAppWithUUID := p.DeploymentInfo.AppName + TemporaryNameSuffix + p.DeploymentInfo.UUID
 
+	err := p.mapRoutes(tempAppWithUUID)
+	if err != nil {
+		return err
+	}
+
+	return p.runHooks(S.HookBeforeCutover, tempAppWithUUID)
+}
+
+// mapRoutes maps the routes of the manifest and the load balanced domain to the pushed application.
+func (p Pusher) mapRoutes(tempAppWithUUID string) error {
 	routeMapperRequest := R.RouteMapperRequest{
 		Logger:          p.Log,
 		Courier:         p.Courier,
@@ -119,6 +177,8 @@ func (p Pusher) PostExecute() error {
 		Application:     p.DeploymentInfo.AppName,
 		UUID:            p.DeploymentInfo.UUID,
 		FoundationUrl:   p.FoundationURL,
+
+		ApplicationIndex: p.ApplicationIndex,
 	}
 
 	err := p.RouteMapper.CustomRouteMapper(routeMapperRequest)
@@ -136,43 +196,58 @@ func (p Pusher) PostExecute() error {
 	return nil
 }
 
-// FinishPush will delete the original application if it existed. It will always
-// rename the the newly pushed application to the appName.
+// FinishPush will delete the original application if it existed, or keep it as a stopped
+// standby when the environment keeps previous versions or bakes. It will always
+// rename the the newly pushed application to the appName. The after_success hooks run afterwards.
 func (p Pusher) Success() error {
+	err := p.cutover()
+	if err != nil {
+		return err
+	}
+
+	return p.runHooks(S.HookAfterSuccess, p.DeploymentInfo.AppName)
+}
+
+func (p Pusher) cutover() error {
 	if p.Courier.Exists(p.DeploymentInfo.AppName) {
 		err := p.unMapLoadBalancedRoute()
 		if err != nil {
 			return err
 		}
 
-		err = p.deleteApplication(p.DeploymentInfo.AppName)
+		if p.Environment.KeepPrevious > 0 || p.Environment.Bake.Enabled() || p.KeepStandby {
+			err = p.keepAsStandby()
+		} else {
+			err = p.deleteApplication(p.DeploymentInfo.AppName)
+		}
 		if err != nil {
 			return err
 		}
 	}
 
-	err := p.renameNewBuildToOriginalAppName()
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return p.renameNewBuildToOriginalAppName()
 }
 
 // UndoPush is only called when a Push fails. If it is not the first deployment, UndoPush will
 // delete the temporary application that was pushed.
 // If is the first deployment, UndoPush will rename the failed push to have the appName.
+// The service instances the push created are deleted and the user-provided services it updated are
+// restored either way.
 func (p Pusher) Undo() error {
 
 	tempAppWithUUID := p.DeploymentInfo.AppName + TemporaryNameSuffix + p.DeploymentInfo.UUID
 	if p.Environment.DisableRollback {
-		p.Log.Errorf("%s: Failed to deploy, deployment not rolled back due to DisabledRollback=true", p.FoundationURL)
+		p.Log.Errorf("%s: Failed to deploy, deployment not rolled back due to DisabledRollback=true", p.foundationName())
 
-		return p.Success()
+		return p.cutover()
 	} else {
+		serviceErr := p.deleteCreatedServiceInstances(tempAppWithUUID)
+		if err := p.restoreUserProvidedServices(); err != nil && serviceErr == nil {
+			serviceErr = err
+		}
 
 		if p.Courier.Exists(p.DeploymentInfo.AppName) {
-			p.Log.Errorf("%s: rolling back deploy of %s", p.FoundationURL, tempAppWithUUID)
+			p.Log.Errorf("%s: rolling back deploy of %s", p.foundationName(), tempAppWithUUID)
 
 			err := p.deleteApplication(tempAppWithUUID)
 			if err != nil {
@@ -180,15 +255,21 @@ func (p Pusher) Undo() error {
 			}
 
 		} else {
-			p.Log.Errorf("%s: app %s did not previously exist: not rolling back", p.FoundationURL, p.DeploymentInfo.AppName)
+			p.Log.Errorf("%s: app %s did not previously exist: not rolling back", p.foundationName(), p.DeploymentInfo.AppName)
 
 			err := p.renameNewBuildToOriginalAppName()
 			if err != nil {
 				return err
 			}
 		}
+
+		return serviceErr
 	}
-	return nil
+}
+
+// Interrupt kills the Cloud Foundry commands of a phase that ran out of time.
+func (p Pusher) Interrupt() {
+	state.Interrupt(p.Courier)
 }
 
 // CleanUp removes the temporary directory created by the Executor.
@@ -196,9 +277,10 @@ func (p Pusher) Finally() error {
 	return p.Courier.CleanUp()
 }
 
-func (p Pusher) pushApplication(appName, appPath string) error {
-	p.Log.Debugf("%s: pushing app %s to %s", p.FoundationURL, appName, p.DeploymentInfo.Domain)
-	p.Log.Debugf("%s: tempdir for app %s: %s", p.FoundationURL, appName, appPath)
+// pushApplication pushes the application with the cf push strategy, or with a plain cf push when it is empty.
+func (p Pusher) pushApplication(appName, appPath, strategy string, start bool) error {
+	p.Log.Debugf("%s: pushing app %s to %s", p.foundationName(), appName, p.DeploymentInfo.Domain)
+	p.Log.Debugf("%s: tempdir for app %s: %s", p.foundationName(), appName, appPath)
 
 	var (
 		pushOutput          []byte
@@ -210,35 +292,45 @@ func (p Pusher) pushApplication(appName, appPath string) error {
 	defer func() { p.Response.Write(cloudFoundryLogs) }()
 	defer func() { p.Response.Write(pushOutput) }()
 
-	pushOutput, err = p.Courier.Push(appName, appPath, p.DeploymentInfo.AppName, p.DeploymentInfo.Instances)
-	p.Log.Infof("%s: push output from Cloud Foundry: \n%s", p.FoundationURL, pushOutput)
+	switch {
+	case !start:
+		pushOutput, err = p.Courier.PushWithoutStart(appName, appPath, p.DeploymentInfo.AppName, p.DeploymentInfo.Instances)
+	case strategy == "":
+		pushOutput, err = p.Courier.Push(appName, appPath, p.DeploymentInfo.AppName, p.DeploymentInfo.Instances)
+	default:
+		pushOutput, err = p.Courier.PushWithStrategy(appName, appPath, p.DeploymentInfo.AppName, p.DeploymentInfo.Instances, strategy)
+	}
+	p.Log.Infof("%s: push output from Cloud Foundry: \n%s", p.foundationName(), pushOutput)
 	if err != nil {
-		defer func() { p.Log.Errorf("%s: logs from %s: \n%s", p.FoundationURL, appName, cloudFoundryLogs) }()
+		defer func() { p.Log.Errorf("%s: logs from %s: \n%s", p.foundationName(), appName, cloudFoundryLogs) }()
 
 		cloudFoundryLogs, cloudFoundryLogsErr = p.Courier.Logs(appName)
 		if cloudFoundryLogsErr != nil {
 			return state.CloudFoundryGetLogsError{err, cloudFoundryLogsErr}
 		}
 
+		if timeout, ok := err.(I.TimeoutError); ok && timeout.Timeout() {
+			return err
+		}
 		return state.PushError{}
 	}
 
-	p.Log.Infof("%s: successfully deployed new build %s", p.FoundationURL, appName)
+	p.Log.Infof("%s: successfully deployed new build %s", p.foundationName(), appName)
 
 	return nil
 }
 
 func (p Pusher) mapTempAppToLoadBalancedDomain(appName string) error {
-	p.Log.Debugf("%s: mapping route for %s to %s", p.FoundationURL, p.DeploymentInfo.AppName, p.DeploymentInfo.Domain)
+	p.Log.Debugf("%s: mapping route for %s to %s", p.foundationName(), p.DeploymentInfo.AppName, p.DeploymentInfo.Domain)
 
 	out, err := p.Courier.MapRoute(appName, p.DeploymentInfo.Domain, p.DeploymentInfo.AppName)
-	p.Log.Infof("%s: mapping output from Cloud Foundry: \n%s", p.FoundationURL, out)
+	p.Log.Infof("%s: mapping output from Cloud Foundry: \n%s", p.foundationName(), out)
 	if err != nil {
-		p.Log.Errorf("%s: could not map %s to %s", p.FoundationURL, p.DeploymentInfo.AppName, p.DeploymentInfo.Domain)
+		p.Log.Errorf("%s: could not map %s to %s", p.foundationName(), p.DeploymentInfo.AppName, p.DeploymentInfo.Domain)
 		return state.MapRouteError{out}
 	}
 
-	p.Log.Infof("%s: application route created: %s.%s", p.FoundationURL, p.DeploymentInfo.AppName, p.DeploymentInfo.Domain)
+	p.Log.Infof("%s: application route created: %s.%s", p.foundationName(), p.DeploymentInfo.AppName, p.DeploymentInfo.Domain)
 
 	fmt.Fprintf(p.Response, "application route created: %s.%s", p.DeploymentInfo.AppName, p.DeploymentInfo.Domain)
 
@@ -247,49 +339,49 @@ func (p Pusher) mapTempAppToLoadBalancedDomain(appName string) error {
 
 func (p Pusher) unMapLoadBalancedRoute() error {
 	if p.DeploymentInfo.Domain != "" {
-		p.Log.Debugf("%s: unmapping route %s", p.FoundationURL, p.DeploymentInfo.AppName)
+		p.Log.Debugf("%s: unmapping route %s", p.foundationName(), p.DeploymentInfo.AppName)
 
 		out, err := p.Courier.UnmapRoute(p.DeploymentInfo.AppName, p.DeploymentInfo.Domain, p.DeploymentInfo.AppName)
-		p.Log.Infof("%s: unmapping output from Cloud Foundry: \n%s", p.FoundationURL, out)
+		p.Log.Infof("%s: unmapping output from Cloud Foundry: \n%s", p.foundationName(), out)
 		if err != nil {
-			p.Log.Errorf("%s: could not unmap %s", p.FoundationURL, p.DeploymentInfo.AppName)
+			p.Log.Errorf("%s: could not unmap %s", p.foundationName(), p.DeploymentInfo.AppName)
 			return state.UnmapRouteError{p.DeploymentInfo.AppName, out}
 		}
 
-		p.Log.Infof("%s: unmapped route %s", p.FoundationURL, p.DeploymentInfo.AppName)
+		p.Log.Infof("%s: unmapped route %s", p.foundationName(), p.DeploymentInfo.AppName)
 	}
 
 	return nil
 }
 
 func (p Pusher) deleteApplication(appName string) error {
-	p.Log.Debugf("%s: deleting %s", p.FoundationURL, appName)
+	p.Log.Debugf("%s: deleting %s", p.foundationName(), appName)
 
 	out, err := p.Courier.Delete(appName)
-	p.Log.Infof("%s: deletion output from Cloud Foundry: \n%s", p.FoundationURL, out)
+	p.Log.Infof("%s: deletion output from Cloud Foundry: \n%s", p.foundationName(), out)
 	if err != nil {
-		p.Log.Errorf("%s: could not delete %s", p.FoundationURL, appName)
-		p.Log.Errorf("%s: deletion error %s", p.FoundationURL, err.Error())
-		p.Log.Errorf("%s: deletion output", p.FoundationURL, string(out))
+		p.Log.Errorf("%s: could not delete %s", p.foundationName(), appName)
+		p.Log.Errorf("%s: deletion error %s", p.foundationName(), err.Error())
+		p.Log.Errorf("%s: deletion output", p.foundationName(), string(out))
 		return state.DeleteApplicationError{appName, out}
 	}
 
-	p.Log.Infof("%s: deleted %s", p.FoundationURL, appName)
+	p.Log.Infof("%s: deleted %s", p.foundationName(), appName)
 
 	return nil
 }
 
 func (p Pusher) renameNewBuildToOriginalAppName() error {
-	p.Log.Debugf("%s: renaming %s to %s", p.FoundationURL, p.DeploymentInfo.AppName+TemporaryNameSuffix+p.DeploymentInfo.UUID, p.DeploymentInfo.AppName)
+	p.Log.Debugf("%s: renaming %s to %s", p.foundationName(), p.DeploymentInfo.AppName+TemporaryNameSuffix+p.DeploymentInfo.UUID, p.DeploymentInfo.AppName)
 
 	out, err := p.Courier.Rename(p.DeploymentInfo.AppName+TemporaryNameSuffix+p.DeploymentInfo.UUID, p.DeploymentInfo.AppName)
-	p.Log.Infof("%s: rename output from Cloud Foundry: \n%s", p.FoundationURL, out)
+	p.Log.Infof("%s: rename output from Cloud Foundry: \n%s", p.foundationName(), out)
 	if err != nil {
-		p.Log.Errorf("%s: could not rename %s to %s", p.FoundationURL, p.DeploymentInfo.AppName+TemporaryNameSuffix+p.DeploymentInfo.UUID, p.DeploymentInfo.AppName)
+		p.Log.Errorf("%s: could not rename %s to %s", p.foundationName(), p.DeploymentInfo.AppName+TemporaryNameSuffix+p.DeploymentInfo.UUID, p.DeploymentInfo.AppName)
 		return state.RenameError{p.DeploymentInfo.AppName + TemporaryNameSuffix + p.DeploymentInfo.UUID, out}
 	}
 
-	p.Log.Infof("%s: renamed %s to %s", p.FoundationURL, p.DeploymentInfo.AppName+TemporaryNameSuffix+p.DeploymentInfo.UUID, p.DeploymentInfo.AppName)
+	p.Log.Infof("%s: renamed %s to %s", p.foundationName(), p.DeploymentInfo.AppName+TemporaryNameSuffix+p.DeploymentInfo.UUID, p.DeploymentInfo.AppName)
 
 	return nil
 }
@@ -298,7 +390,7 @@ func (p Pusher) mapTemporaryRoute(tempAppWithUUID, domain string, log I.Deployme
 	log.Debugf("mapping temporary route %s.%s", tempAppWithUUID, domain)
 
 	out, err := p.Courier.MapRoute(tempAppWithUUID, domain, tempAppWithUUID)
-	p.Log.Infof("%s: mapping output from Cloud Foundry: \n%s", p.FoundationURL, out)
+	p.Log.Infof("%s: mapping output from Cloud Foundry: \n%s", p.foundationName(), out)
 	if err != nil {
 		log.Errorf("failed to map temporary route: %s", out)
 		return state.MapRouteError{out}
@@ -312,7 +404,7 @@ func (p Pusher) deleteTemporaryRoute(tempAppWithUUID, domain string, log I.Deplo
 	log.Debugf("deleting temporary route %s.%s", tempAppWithUUID, domain)
 
 	out, err := p.Courier.DeleteRoute(domain, tempAppWithUUID)
-	p.Log.Infof("%s: route deletion output from Cloud Foundry: \n%s", p.FoundationURL, out)
+	p.Log.Infof("%s: route deletion output from Cloud Foundry: \n%s", p.foundationName(), out)
 	if err != nil {
 		log.Errorf("failed to delete temporary route: %s", out)
 		return state.MapRouteError{out}
@@ -327,7 +419,7 @@ func (p Pusher) unmapTemporaryRoute(tempAppWithUUID, domain string, log I.Deploy
 	log.Debugf("unmapping temporary route %s.%s", tempAppWithUUID, domain)
 
 	out, err := p.Courier.UnmapRoute(tempAppWithUUID, domain, tempAppWithUUID)
-	p.Log.Infof("%s: unmapping output from Cloud Foundry: \n%s", p.FoundationURL, out)
+	p.Log.Infof("%s: unmapping output from Cloud Foundry: \n%s", p.foundationName(), out)
 	if err != nil {
 		log.Errorf("failed to unmap temporary route: %s", out)
 	} else {
@@ -336,3 +428,7 @@ func (p Pusher) unmapTemporaryRoute(tempAppWithUUID, domain string, log I.Deploy
 
 	log.Infof("finished health check")
 }
+
+func (p Pusher) foundationName() string {
+	return S.FoundationName(p.FoundationName, p.FoundationURL)
+}
@@ -1,10 +1,16 @@
 package push_test
 
 import (
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"math/rand"
+	"net/http"
+	"strings"
+	"time"
 
+	"github.com/compozed/deployadactyl/controller/deployer/bluegreen/courier/executor"
 	"github.com/compozed/deployadactyl/mocks"
 	"github.com/compozed/deployadactyl/randomizer"
 	. "github.com/compozed/deployadactyl/state/push"
@@ -189,6 +195,13 @@ applications:
 
 				Eventually(logBuffer).Should(Say(fmt.Sprintf("could not login to %s", randomFoundationURL)))
 			})
+
+			It("returns the timeout when login timed out", func() {
+				timeout := executor.CommandTimeoutError{Command: "login", After: time.Minute}
+				courier.LoginCall.Returns.Error = timeout
+
+				Expect(pusher.Initially()).To(MatchError(timeout))
+			})
 		})
 	})
 
@@ -238,6 +251,14 @@ applications:
 					Eventually(logBuffer).Should(Say("logs from"))
 				})
 
+				It("returns the timeout when the push timed out", func() {
+					fetcher.FetchCall.Returns.AppPath = randomAppPath
+					timeout := executor.CommandTimeoutError{Command: "push", After: time.Minute}
+					courier.PushCall.Returns.Error = timeout
+
+					Expect(pusher.Execute()).To(MatchError(timeout))
+				})
+
 				Context("when the courier log call fails", func() {
 					It("returns an error", func() {
 						fetcher.FetchCall.Returns.AppPath = randomAppPath
@@ -559,6 +580,75 @@ applications:
 			})
 		})
 
+		Context("when the environment keeps previous versions", func() {
+			BeforeEach(func() {
+				pusher.Environment.KeepPrevious = 2
+			})
+
+			It("stops the original application and keeps it as a standby", func() {
+				courier.ExistsCall.Returns.Apps = map[string]bool{randomAppName: true, tempAppWithUUID: true}
+
+				Expect(pusher.Success()).To(Succeed())
+
+				Expect(courier.DeleteCall.Received.AppNames).To(BeEmpty())
+				Expect(courier.StopCall.Received.AppNames).To(Equal([]string{randomAppName}))
+				Expect(courier.RenameCall.Received.Renames).To(Equal([][2]string{
+					{randomAppName, randomAppName + "-previous"},
+					{tempAppWithUUID, randomAppName},
+				}))
+			})
+
+			It("moves earlier standbys down a generation and deletes the ones it does not keep", func() {
+				courier.ExistsCall.Returns.Apps = map[string]bool{
+					randomAppName:                 true,
+					tempAppWithUUID:               true,
+					randomAppName + "-previous":   true,
+					randomAppName + "-previous-2": true,
+					randomAppName + "-previous-3": true,
+				}
+
+				Expect(pusher.Success()).To(Succeed())
+
+				Expect(courier.DeleteCall.Received.AppNames).To(Equal([]string{randomAppName + "-previous-3", randomAppName + "-previous-2"}))
+				Expect(courier.RenameCall.Received.Renames).To(Equal([][2]string{
+					{randomAppName + "-previous", randomAppName + "-previous-2"},
+					{randomAppName, randomAppName + "-previous"},
+					{tempAppWithUUID, randomAppName},
+				}))
+				Expect(courier.ExistsCall.Returns.Apps).To(Equal(map[string]bool{
+					randomAppName:                 true,
+					randomAppName + "-previous":   true,
+					randomAppName + "-previous-2": true,
+				}))
+			})
+
+			It("returns an error when the original application cannot be stopped", func() {
+				courier.ExistsCall.Returns.Apps = map[string]bool{randomAppName: true}
+				courier.StopCall.Returns.Output = []byte("stop output")
+				courier.StopCall.Returns.Error = errors.New("stop error")
+
+				err := pusher.Success()
+
+				Expect(err).To(MatchError(state.StopError{ApplicationName: randomAppName, Out: []byte("stop output")}))
+				Expect(courier.RenameCall.Received.Renames).To(BeEmpty())
+			})
+		})
+
+		Context("when the pusher keeps a standby for a multi-application push", func() {
+			It("keeps the original application as a standby", func() {
+				pusher.KeepStandby = true
+				courier.ExistsCall.Returns.Apps = map[string]bool{randomAppName: true, tempAppWithUUID: true}
+
+				Expect(pusher.Success()).To(Succeed())
+
+				Expect(courier.DeleteCall.Received.AppNames).To(BeEmpty())
+				Expect(courier.RenameCall.Received.Renames).To(Equal([][2]string{
+					{randomAppName, randomAppName + "-previous"},
+					{tempAppWithUUID, randomAppName},
+				}))
+			})
+		})
+
 		Context("When renameNewBuildToOriginalAppName is called", func() {
 			It("should write the foundation URL to the log", func() {
 				courier.ExistsCall.Returns.Bool = true
@@ -678,8 +768,634 @@ applications:
 	})
 
 	Describe("Verify", func() {
-		It("returns nil", func() {
-			Expect(pusher.Verify()).To(BeNil())
+		var (
+			orgPath    string
+			spacePath  string
+			rolesPath  string
+			userClaims string
+		)
+
+		token := func(claims string) string {
+			return "bearer e30." + base64.RawURLEncoding.EncodeToString([]byte(claims)) + ".signature"
+		}
+
+		BeforeEach(func() {
+			orgPath = "/v3/organizations?names=" + randomOrg
+			spacePath = "/v3/spaces?names=" + randomSpace + "&organization_guids=org-guid"
+			rolesPath = "/v3/roles?types=space_developer&space_guids=space-guid&user_guids=user-guid"
+			userClaims = `{"user_id": "user-guid", "scope": ["cloud_controller.read", "cloud_controller.write"]}`
+
+			pusher.DeploymentInfo.Instances = 2
+			pusher.DeploymentInfo.Manifest = fmt.Sprintf(`
+applications:
+- name: example
+  memory: 512M
+  buildpack: java_buildpack
+  stack: cflinuxfs4
+  custom-routes:
+  - route: api.%s0/v1`, randomDomain)
+
+			courier.OAuthTokenCall.Returns.Token = token(userClaims)
+			courier.CurlCall.Returns.Bodies = map[string]string{
+				orgPath:   `{"resources": [{"guid": "org-guid", "relationships": {"quota": {"data": {"guid": "org-quota-guid"}}}}]}`,
+				spacePath: `{"resources": [{"guid": "space-guid", "relationships": {"quota": {"data": null}}}]}`,
+				rolesPath: `{"resources": [{"guid": "role-guid"}]}`,
+
+				"/v3/organizations/org-guid/usage_summary":      `{"usage_summary": {"started_instances": 2, "memory_in_mb": 1024}}`,
+				"/v3/organization_quotas/org-quota-guid":        `{"apps": {"total_memory_in_mb": 4096}}`,
+				"/v3/domains?names=" + randomDomain + "0":       `{"resources": [{"guid": "domain-guid"}]}`,
+				"/v3/routes?domain_guids=domain-guid&hosts=api": `{"resources": [{"host": "api", "path": "/v1", "relationships": {"space": {"data": {"guid": "space-guid"}}}}]}`,
+				"/v3/buildpacks?names=java_buildpack":           `{"resources": [{"guid": "buildpack-guid"}]}`,
+				"/v3/stacks?names=cflinuxfs4":                   `{"resources": [{"guid": "stack-guid"}]}`,
+			}
+		})
+
+		It("succeeds when every check passes", func() {
+			Expect(pusher.Verify()).To(Succeed())
+
+			Expect(courier.PushCall.Received.AppName).To(BeEmpty())
+			Eventually(logBuffer).Should(Say("pre-flight checks passed for %s", randomAppName))
+		})
+
+		It("returns an error when the org does not exist", func() {
+			courier.CurlCall.Returns.Bodies[orgPath] = `{"resources": []}`
+
+			Expect(pusher.Verify()).To(MatchError(state.OrgNotFoundError{randomOrg}))
+		})
+
+		It("returns an error when the space does not exist", func() {
+			courier.CurlCall.Returns.Bodies[spacePath] = `{"resources": []}`
+
+			Expect(pusher.Verify()).To(MatchError(state.SpaceNotFoundError{randomOrg, randomSpace}))
+		})
+
+		It("returns an error when the user is not a developer of the space", func() {
+			courier.CurlCall.Returns.Bodies[rolesPath] = `{"resources": []}`
+
+			Expect(pusher.Verify()).To(MatchError(state.PushNotAuthorizedError{randomUsername, randomOrg, randomSpace}))
+		})
+
+		It("does not look up the roles of an admin", func() {
+			delete(courier.CurlCall.Returns.Bodies, rolesPath)
+			courier.OAuthTokenCall.Returns.Token = token(`{"user_id": "user-guid", "scope": ["cloud_controller.admin"]}`)
+
+			Expect(pusher.Verify()).To(Succeed())
+		})
+
+		It("returns an error when the org quota does not leave enough memory next to the running instances", func() {
+			courier.CurlCall.Returns.Bodies["/v3/organizations/org-guid/usage_summary"] = `{"usage_summary": {"memory_in_mb": 3584}}`
+
+			Expect(pusher.Verify()).To(MatchError(state.InsufficientMemoryError{"org", randomOrg, 1024, 512}))
+		})
+
+		It("returns an error when the space quota does not leave enough memory", func() {
+			courier.CurlCall.Returns.Bodies[spacePath] = `{"resources": [{"guid": "space-guid", "relationships": {"quota": {"data": {"guid": "space-quota-guid"}}}}]}`
+			courier.CurlCall.Returns.Bodies["/v3/space_quotas/space-quota-guid"] = `{"apps": {"total_memory_in_mb": 1536}}`
+			courier.CurlCall.Returns.Bodies["/v3/apps?space_guids=space-guid&states=STARTED&per_page=5000"] = `{"resources": [{"guid": "app-1"}, {"guid": "app-2"}]}`
+			courier.CurlCall.Returns.Bodies["/v3/processes?app_guids=app-1,app-2&per_page=5000"] = `{"resources": [{"instances": 1, "memory_in_mb": 512}, {"instances": 2, "memory_in_mb": 256}]}`
+
+			Expect(pusher.Verify()).To(MatchError(state.InsufficientMemoryError{"space", randomSpace, 1024, 512}))
+
+			courier.CurlCall.Returns.Bodies["/v3/space_quotas/space-quota-guid"] = `{"apps": {"total_memory_in_mb": 1024}}`
+
+			Expect(pusher.Verify()).To(MatchError(state.InsufficientMemoryError{"space", randomSpace, 1024, 0}))
+		})
+
+		It("requests the processes of the space in batches", func() {
+			var guids, resources []string
+			for i := 1; i <= 51; i++ {
+				guids = append(guids, fmt.Sprintf("app-%d", i))
+				resources = append(resources, fmt.Sprintf(`{"guid": "app-%d"}`, i))
+			}
+			courier.CurlCall.Returns.Bodies[spacePath] = `{"resources": [{"guid": "space-guid", "relationships": {"quota": {"data": {"guid": "space-quota-guid"}}}}]}`
+			courier.CurlCall.Returns.Bodies["/v3/space_quotas/space-quota-guid"] = `{"apps": {"total_memory_in_mb": 1536}}`
+			courier.CurlCall.Returns.Bodies["/v3/apps?space_guids=space-guid&states=STARTED&per_page=5000"] = `{"resources": [` + strings.Join(resources, ", ") + `]}`
+			courier.CurlCall.Returns.Bodies["/v3/processes?app_guids="+strings.Join(guids[:50], ",")+"&per_page=5000"] = `{"resources": [{"instances": 1, "memory_in_mb": 256}]}`
+			courier.CurlCall.Returns.Bodies["/v3/processes?app_guids=app-51&per_page=5000"] = `{"resources": [{"instances": 1, "memory_in_mb": 512}]}`
+
+			Expect(pusher.Verify()).To(MatchError(state.InsufficientMemoryError{"space", randomSpace, 1024, 768}))
+		})
+
+		It("leaves the memory check of a multi-application push to the applications pusher", func() {
+			courier.CurlCall.Returns.Bodies["/v3/organizations/org-guid/usage_summary"] = `{"usage_summary": {"memory_in_mb": 2048}}`
+			pusher.SharedQuota = true
+			worker := pusher
+			worker.DeploymentInfo.Instances = 3
+
+			Expect(pusher.Verify()).To(Succeed())
+			Expect(worker.Verify()).To(Succeed())
+
+			applications := &ApplicationsPusher{Actions: []interfaces.Action{&pusher, &worker}}
+
+			Expect(applications.Verify()).To(MatchError(state.InsufficientMemoryError{"org", randomOrg, 2560, 2048}))
+		})
+
+		It("does not check quotas without a memory limit", func() {
+			courier.CurlCall.Returns.Bodies["/v3/organization_quotas/org-quota-guid"] = `{"apps": {"total_memory_in_mb": null}}`
+			courier.CurlCall.Returns.Bodies["/v3/organizations/org-guid/usage_summary"] = `{"usage_summary": {"memory_in_mb": 1000000}}`
+
+			Expect(pusher.Verify()).To(Succeed())
+		})
+
+		It("returns an error when a custom route belongs to another space", func() {
+			courier.CurlCall.Returns.Bodies["/v3/routes?domain_guids=domain-guid&hosts=api"] = `{"resources": [
+				{"host": "api", "path": "", "relationships": {"space": {"data": {"guid": "other-space-guid"}}}},
+				{"host": "api", "path": "/v1", "relationships": {"space": {"data": {"guid": "other-space-guid"}}}}
+			]}`
+
+			Expect(pusher.Verify()).To(MatchError(state.RouteOwnedByAnotherSpaceError{"api." + randomDomain + "0/v1"}))
+		})
+
+		It("returns an error when a custom route is not on a domain of the foundation", func() {
+			pusher.DeploymentInfo.Manifest = "applications:\n- name: example\n  custom-routes:\n  - route: api.unknown.com\n"
+
+			Expect(pusher.Verify()).To(MatchError(routemapper.InvalidRouteError{"api.unknown.com"}))
+		})
+
+		It("returns an error when the buildpack does not exist", func() {
+			courier.CurlCall.Returns.Bodies["/v3/buildpacks?names=java_buildpack"] = `{"resources": []}`
+
+			Expect(pusher.Verify()).To(MatchError(state.BuildpackNotFoundError{"java_buildpack"}))
+		})
+
+		It("does not look up buildpacks given by their URL", func() {
+			pusher.DeploymentInfo.Manifest = "applications:\n- name: example\n  buildpacks:\n  - https://github.com/cloudfoundry/go-buildpack.git\n"
+
+			Expect(pusher.Verify()).To(Succeed())
+		})
+
+		It("returns an error when the stack does not exist", func() {
+			courier.CurlCall.Returns.Bodies["/v3/stacks?names=cflinuxfs4"] = `{"resources": []}`
+
+			Expect(pusher.Verify()).To(MatchError(state.StackNotFoundError{"cflinuxfs4"}))
+		})
+
+		It("returns the errors of the cloud controller", func() {
+			courier.CurlCall.Returns.Bodies[orgPath] = `{"errors": [{"code": 10002, "title": "CF-NotAuthenticated", "detail": "Authentication error"}]}`
+
+			err := pusher.Verify()
+
+			Expect(err).To(BeAssignableToTypeOf(state.CloudControllerError{}))
+			Expect(err.Error()).To(ContainSubstring("Authentication error"))
+		})
+
+		It("only needs memory for one more instance when an existing application is pushed with the rolling strategy", func() {
+			courier.CurlCall.Returns.Bodies["/v3/organizations/org-guid/usage_summary"] = `{"usage_summary": {"memory_in_mb": 3500}}`
+			courier.ExistsCall.Returns.Bool = true
+
+			Expect(pusher.Verify()).ToNot(Succeed())
+			Expect((&InPlacePusher{Pusher: &pusher, Strategy: "rolling"}).Verify()).To(Succeed())
+		})
+	})
+
+	Describe("Hooks", func() {
+		var hookRunner *mocks.HookRunner
+
+		BeforeEach(func() {
+			hookRunner = &mocks.HookRunner{}
+			pusher.HookRunner = hookRunner
+			pusher.DeploymentInfo.Domain = ""
+			pusher.DeploymentInfo.HealthCheckEndpoint = ""
+			pusher.DeploymentInfo.Manifest = ""
+			pusher.Environment.Name = "production"
+			pusher.Environment.Hooks = []S.Hook{
+				{Name: "check", When: S.HookBeforePush, URL: "https://check.example.com"},
+				{Name: "migrate", When: S.HookBeforeCutover, Task: "rake db:migrate"},
+				{Name: "notify", When: S.HookAfterSuccess, Command: []string{"notify.sh"}},
+			}
+		})
+
+		It("runs each hook at its point of the push against the right application", func() {
+			Expect(pusher.Execute()).To(Succeed())
+			Expect(hookRunner.RunCall.Received.Hooks).To(HaveLen(1))
+			Expect(hookRunner.RunCall.Received.Contexts[0].Application).To(Equal(randomAppName))
+
+			Expect(pusher.PostExecute()).To(Succeed())
+			Expect(hookRunner.RunCall.Received.Hooks).To(HaveLen(2))
+			Expect(hookRunner.RunCall.Received.Hooks[1].Name).To(Equal("migrate"))
+			Expect(hookRunner.RunCall.Received.Contexts[1].Application).To(Equal(tempAppWithUUID))
+			Expect(hookRunner.RunCall.Received.Contexts[1].Environment).To(Equal("production"))
+			Expect(hookRunner.RunCall.Received.Contexts[1].UUID).To(Equal(randomUUID))
+
+			Expect(pusher.Success()).To(Succeed())
+			Expect(hookRunner.RunCall.Received.Hooks).To(HaveLen(3))
+			Expect(hookRunner.RunCall.Received.Contexts[2].Application).To(Equal(randomAppName))
+		})
+
+		It("does not push when a before_push hook fails", func() {
+			hookRunner.RunCall.Returns.Errors = map[string]error{"check": errors.New("check failed")}
+
+			Expect(pusher.Execute()).To(MatchError("check failed"))
+			Expect(courier.PushCall.Received.AppName).To(BeEmpty())
+		})
+
+		It("fails PostExecute when a before_cutover hook fails", func() {
+			hookRunner.RunCall.Returns.Errors = map[string]error{"migrate": errors.New("migration failed")}
+
+			Expect(pusher.PostExecute()).To(MatchError("migration failed"))
+		})
+
+		It("does not run after_success hooks when a failed push is kept because rollback is disabled", func() {
+			pusher.Environment.DisableRollback = true
+
+			Expect(pusher.Undo()).To(Succeed())
+			Expect(hookRunner.RunCall.Received.Hooks).To(BeEmpty())
+		})
+	})
+
+	Describe("Smoke tests", func() {
+		BeforeEach(func() {
+			pusher.FoundationURL = "https://api.cf.example.com"
+			pusher.AppsDomain = "apps.example.com"
+			pusher.DeploymentInfo.HealthCheckEndpoint = ""
+			pusher.DeploymentInfo.SmokeTests = []S.SmokeTest{
+				{
+					Name:    "login",
+					Method:  "post",
+					Path:    "/login",
+					Headers: map[string]string{"Content-Type": "application/json"},
+					Body:    `{"user": "smoke"}`,
+					Assertions: []S.SmokeTestAssertion{
+						{JSONPath: "$.user.roles[1]", Equals: "admin"},
+						{JSONPath: "$.attempts", Equals: 1},
+					},
+				},
+				{
+					Name:           "missing",
+					Path:           "missing",
+					ExpectedStatus: []int{404},
+					Assertions:     []S.SmokeTestAssertion{{Matches: "(?i)not found"}},
+				},
+			}
+
+			client.DoCall.Returns.Responses = []http.Response{
+				{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(`{"user": {"roles": ["user", "admin"]}, "attempts": 1}`))},
+				{StatusCode: 404, Body: ioutil.NopCloser(strings.NewReader("Not Found"))},
+			}
+		})
+
+		It("sends every smoke test to the temporary route of the new application", func() {
+			Expect(pusher.HealthCheck()).To(Succeed())
+
+			Expect(client.DoCall.TimesCalled).To(Equal(2))
+			Expect(client.DoCall.Received.Requests[0].Method).To(Equal("POST"))
+			Expect(client.DoCall.Received.Requests[0].URL.String()).To(Equal(fmt.Sprintf("https://%s.apps.example.com/login", tempAppWithUUID)))
+			Expect(client.DoCall.Received.Requests[0].Header.Get("Content-Type")).To(Equal("application/json"))
+			Expect(client.DoCall.Received.Bodies[0]).To(Equal(`{"user": "smoke"}`))
+			Expect(client.DoCall.Received.Requests[1].Method).To(Equal("GET"))
+			Expect(client.DoCall.Received.Requests[1].URL.String()).To(Equal(fmt.Sprintf("https://%s.apps.example.com/missing", tempAppWithUUID)))
+
+			Expect(courier.MapRouteCall.Received.AppName).To(ContainElement(tempAppWithUUID))
+			Expect(courier.DeleteRouteCall.Received.Hostname).To(Equal(tempAppWithUUID))
+		})
+
+		It("writes the result of every smoke test to the response", func() {
+			Expect(pusher.HealthCheck()).To(Succeed())
+
+			Eventually(response).Should(Say("https://api.cf.example.com: smoke test login passed: POST /login returned 200"))
+			Eventually(response).Should(Say("https://api.cf.example.com: smoke test missing passed: GET missing returned 404"))
+		})
+
+		It("runs every smoke test and fails naming the ones that failed", func() {
+			client.DoCall.Returns.Responses[0].Body = ioutil.NopCloser(strings.NewReader(`{"user": {"roles": ["user"]}, "attempts": 1}`))
+			client.DoCall.Returns.Responses = append(client.DoCall.Returns.Responses[:1], http.Response{StatusCode: 500, Body: ioutil.NopCloser(strings.NewReader("oops"))})
+
+			err := pusher.HealthCheck()
+
+			Expect(err).To(MatchError(healthchecker.SmokeTestError{Failed: []string{"login", "missing"}, Total: 2}))
+			Eventually(response).Should(Say(`smoke test login failed: POST /login: \$.user.roles\[1\] not found in body`))
+			Eventually(response).Should(Say(`smoke test missing failed: GET missing: unexpected status code 500, expected \[404\]`))
+		})
+
+		It("fails when a value does not equal the expected one", func() {
+			client.DoCall.Returns.Responses[0].Body = ioutil.NopCloser(strings.NewReader(`{"user": {"roles": ["user", "admin"]}, "attempts": 2}`))
+
+			Expect(pusher.HealthCheck()).To(MatchError(healthchecker.SmokeTestError{Failed: []string{"login"}, Total: 2}))
+			Eventually(response).Should(Say(`\$.attempts is 2, expected 1`))
+		})
+
+		It("does not run smoke tests when there are none", func() {
+			pusher.DeploymentInfo.SmokeTests = nil
+
+			Expect(pusher.HealthCheck()).To(Succeed())
+			Expect(client.DoCall.TimesCalled).To(Equal(0))
+			Expect(courier.MapRouteCall.Received.AppName).To(BeEmpty())
+		})
+	})
+
+	Describe("Bake", func() {
+		var since time.Time
+
+		BeforeEach(func() {
+			since = time.Now()
+			pusher.FoundationURL = "https://api.cf.example.com"
+			pusher.AppsDomain = "apps.example.com"
+			pusher.Environment.Bake = S.Bake{Duration: "10m", MaxRestarts: 1}
+			courier.AppInstancesCall.Returns.Instances = [][]S.AppInstance{{
+				{Index: 0, State: "running", Since: since.Add(-time.Minute)},
+				{Index: 1, State: "running", Since: since.Add(-time.Minute)},
+			}}
+		})
+
+		It("keeps the original application as a standby when the environment bakes", func() {
+			courier.ExistsCall.Returns.Apps = map[string]bool{randomAppName: true, tempAppWithUUID: true}
+
+			Expect(pusher.Success()).To(Succeed())
+
+			Expect(courier.DeleteCall.Received.AppNames).To(BeEmpty())
+			Expect(courier.StopCall.Received.AppNames).To(Equal([]string{randomAppName}))
+			Expect(courier.ExistsCall.Returns.Apps).To(HaveKey(randomAppName + "-previous"))
+		})
+
+		It("succeeds when the application is healthy", func() {
+			Expect(pusher.Bake(since)).To(Succeed())
+
+			Expect(courier.AppInstancesCall.Received.AppName).To(Equal([]string{randomAppName}))
+			Expect(client.GetCall.Received.URL).To(Equal(fmt.Sprintf("https://%s.apps.example.com/%s", randomAppName, randomEndpoint)))
+		})
+
+		It("fails when an instance is crashed", func() {
+			courier.AppInstancesCall.Returns.Instances[0][1].State = "crashed"
+
+			err := pusher.Bake(since)
+
+			Expect(err).To(MatchError(state.UnhealthyApplicationError{ApplicationName: randomAppName, Reason: "1 of 2 instances are crashed or down"}))
+			Eventually(response).Should(Say(randomAppName + " failed its bake"))
+		})
+
+		It("fails when the application has no instances", func() {
+			courier.AppInstancesCall.Returns.Instances = [][]S.AppInstance{{}}
+
+			err := pusher.Bake(since)
+
+			Expect(err).To(MatchError(state.UnhealthyApplicationError{ApplicationName: randomAppName, Reason: "no instances are running"}))
+		})
+
+		It("fails when more instances restarted than allowed", func() {
+			courier.AppInstancesCall.Returns.Instances[0][0].Since = since.Add(time.Second)
+			Expect(pusher.Bake(since)).To(Succeed())
+
+			courier.AppInstancesCall.Returns.Instances[0][1].Since = since.Add(time.Second)
+			Expect(pusher.Bake(since)).To(MatchError(state.UnhealthyApplicationError{ApplicationName: randomAppName, Reason: "2 of 2 instances restarted while baking"}))
+		})
+
+		It("fails when the health check of the route fails", func() {
+			client.GetCall.Returns.Response.StatusCode = 500
+			client.GetCall.Returns.Response.Body = ioutil.NopCloser(strings.NewReader("oops"))
+
+			Expect(pusher.Bake(since)).To(BeAssignableToTypeOf(healthchecker.HealthCheckError{}))
+		})
+
+		Describe("RollBack", func() {
+			BeforeEach(func() {
+				courier.ExistsCall.Returns.Apps = map[string]bool{
+					randomAppName:                 true,
+					randomAppName + "-previous":   true,
+					randomAppName + "-previous-2": true,
+				}
+			})
+
+			It("swaps the application back to the previous version and deletes the one that failed", func() {
+				Expect(pusher.RollBack()).To(Succeed())
+
+				Expect(courier.StartCall.Received.AppNames).To(Equal([]string{randomAppName + "-previous"}))
+				Expect(courier.MapRouteCall.Received.AppName).To(Equal([]string{randomAppName + "-previous"}))
+				Expect(courier.MapRouteCall.Received.Domain).To(Equal([]string{randomDomain}))
+				Expect(courier.RenameCall.Received.Renames).To(Equal([][2]string{
+					{randomAppName, tempAppWithUUID},
+					{randomAppName + "-previous", randomAppName},
+					{randomAppName + "-previous-2", randomAppName + "-previous"},
+				}))
+				Expect(courier.UnmapRouteCall.Received.AppName).To(Equal(tempAppWithUUID))
+				Expect(courier.DeleteCall.Received.AppNames).To(Equal([]string{tempAppWithUUID}))
+				Expect(courier.ExistsCall.Returns.Apps).To(Equal(map[string]bool{
+					randomAppName:               true,
+					randomAppName + "-previous": true,
+				}))
+			})
+
+			It("returns an error when the previous version cannot be started", func() {
+				courier.StartCall.Returns.Output = []byte("start output")
+				courier.StartCall.Returns.Error = errors.New("start error")
+
+				err := pusher.RollBack()
+
+				Expect(err).To(MatchError(state.StartError{ApplicationName: randomAppName + "-previous", Out: []byte("start output")}))
+				Expect(courier.RenameCall.Received.Renames).To(BeEmpty())
+			})
+
+			It("only removes the previous version when rollback is disabled", func() {
+				pusher.Environment.DisableRollback = true
+
+				Expect(pusher.RollBack()).To(Succeed())
+
+				Expect(courier.StartCall.Received.AppNames).To(BeEmpty())
+				Expect(courier.DeleteCall.Received.AppNames).To(Equal([]string{randomAppName + "-previous"}))
+			})
+		})
+
+		Describe("FinishBake", func() {
+			It("deletes the previous version when the environment does not keep previous versions", func() {
+				courier.ExistsCall.Returns.Apps = map[string]bool{randomAppName: true, randomAppName + "-previous": true}
+
+				Expect(pusher.FinishBake()).To(Succeed())
+
+				Expect(courier.DeleteCall.Received.AppNames).To(Equal([]string{randomAppName + "-previous"}))
+			})
+
+			It("keeps it when the environment keeps previous versions", func() {
+				pusher.Environment.KeepPrevious = 1
+				courier.ExistsCall.Returns.Apps = map[string]bool{randomAppName: true, randomAppName + "-previous": true}
+
+				Expect(pusher.FinishBake()).To(Succeed())
+
+				Expect(courier.DeleteCall.Received.AppNames).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("Service instances", func() {
+		var created *[]string
+
+		BeforeEach(func() {
+			created = &[]string{}
+			pusher.CreatedServiceInstances = created
+			pusher.ServicePollInterval = time.Millisecond
+			pusher.DeploymentInfo.HealthCheckEndpoint = ""
+			pusher.DeploymentInfo.ServiceInstances = []S.ServiceInstance{
+				{Name: "existing", Service: "p-redis", Plan: "shared"},
+				{Name: "db", Service: "p-mysql", Plan: "small", Params: map[string]interface{}{"size": 1}},
+			}
+
+			courier.ServicesCall.Returns.Services = []string{"existing"}
+			courier.ServiceStatusCall.Returns.Statuses = []string{"create in progress", "create succeeded"}
+		})
+
+		It("creates the missing instances and binds all of them to the new application", func() {
+			Expect(pusher.Execute()).To(Succeed())
+
+			Expect(courier.CreateServiceCall.Received.Name).To(Equal([]string{"db"}))
+			Expect(courier.CreateServiceCall.Received.Service).To(Equal([]string{"p-mysql"}))
+			Expect(courier.CreateServiceCall.Received.Params).To(Equal([]string{`{"size":1}`}))
+			Expect(courier.ServiceStatusCall.Received.ServiceName).To(Equal([]string{"db", "db"}))
+			Expect(*created).To(Equal([]string{"db"}))
+
+			Expect(courier.BindServiceCall.Received.AppName).To(Equal([]string{tempAppWithUUID, tempAppWithUUID}))
+			Expect(courier.BindServiceCall.Received.ServiceName).To(Equal([]string{"existing", "db"}))
+			Expect(courier.RestageCall.Received.AppName).To(BeEmpty())
+			Expect(response).To(Say("created service instance db"))
+		})
+
+		It("does not push when the provisioning of an instance fails", func() {
+			courier.ServiceStatusCall.Returns.Statuses = []string{"create failed"}
+
+			err := pusher.Execute()
+
+			Expect(err).To(MatchError(state.ServiceProvisioningError{ServiceName: "db", Reason: "create failed"}))
+			Expect(courier.PushCall.Received.AppName).To(BeEmpty())
+			Expect(*created).To(Equal([]string{"db"}))
+		})
+
+		It("returns an error when binding an instance fails", func() {
+			courier.BindServiceCall.Returns.Output = []byte("bind failed")
+			courier.BindServiceCall.Returns.Error = errors.New("exit status 1")
+
+			err := pusher.Execute()
+
+			Expect(err).To(MatchError(state.BindServiceError{ApplicationName: tempAppWithUUID, ServiceName: "existing", Out: []byte("bind failed")}))
+			Expect(courier.StartCall.Received.AppName).To(BeEmpty())
+		})
+
+		It("pushes the new application without starting it and starts it once the instances are bound", func() {
+			Expect(pusher.Execute()).To(Succeed())
+
+			Expect(courier.PushWithoutStartCall.Received.AppName).To(Equal(tempAppWithUUID))
+			Expect(courier.PushCall.Received.AppName).To(BeEmpty())
+			Expect(courier.StartCall.Received.AppName).To(Equal(tempAppWithUUID))
+		})
+
+		It("returns an error when the new application does not start", func() {
+			courier.StartCall.Returns.Output = []byte("staging failed")
+			courier.StartCall.Returns.Error = errors.New("exit status 1")
+
+			err := pusher.Execute()
+
+			Expect(err).To(MatchError(state.StartError{ApplicationName: tempAppWithUUID, Out: []byte("staging failed")}))
+		})
+
+		Context("when the push is undone", func() {
+			BeforeEach(func() {
+				*created = []string{"db"}
+				courier.ExistsCall.Returns.Bool = true
+			})
+
+			It("deletes the instances the push created", func() {
+				Expect(pusher.Undo()).To(Succeed())
+
+				Expect(courier.UnbindServiceCall.Received.AppName).To(Equal([]string{tempAppWithUUID}))
+				Expect(courier.DeleteServiceCall.Received.ServiceName).To(Equal([]string{"db"}))
+				Expect(courier.DeleteCall.Received.AppNames).To(Equal([]string{tempAppWithUUID}))
+				Expect(*created).To(BeEmpty())
+			})
+
+			It("still deletes the new application when deleting an instance fails", func() {
+				courier.DeleteServiceCall.Returns.Output = []byte("has bindings")
+				courier.DeleteServiceCall.Returns.Error = errors.New("exit status 1")
+
+				err := pusher.Undo()
+
+				Expect(err).To(MatchError(state.DeleteServiceError{ServiceName: "db", Out: []byte("has bindings")}))
+				Expect(courier.DeleteCall.Received.AppNames).To(Equal([]string{tempAppWithUUID}))
+			})
+
+			It("keeps the instances when rollback is disabled", func() {
+				pusher.Environment.DisableRollback = true
+
+				pusher.Undo()
+
+				Expect(courier.DeleteServiceCall.Received.ServiceName).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("User-provided services", func() {
+		var (
+			created *[]string
+			updated *[]S.UserProvidedService
+		)
+
+		BeforeEach(func() {
+			created = &[]string{}
+			updated = &[]S.UserProvidedService{}
+			pusher.CreatedServiceInstances = created
+			pusher.UpdatedUserProvidedServices = updated
+			pusher.DeploymentInfo.HealthCheckEndpoint = ""
+			pusher.DeploymentInfo.UserProvidedServices = []S.UserProvidedService{
+				{Name: "payments-api", Credentials: map[string]interface{}{"token": "s3cr3t"}},
+				{Name: "splunk", SyslogDrainURL: "syslog-tls://logs.example.com:6514"},
+			}
+
+			courier.ServicesCall.Returns.Services = []string{"payments-api"}
+		})
+
+		It("updates the existing services, creates the missing ones and binds all of them to the new application", func() {
+			Expect(pusher.Execute()).To(Succeed())
+
+			Expect(courier.UupsCall.Received.ServiceName).To(Equal([]string{"payments-api"}))
+			Expect(courier.UupsCall.Received.Credentials).To(Equal([]string{`{"token":"s3cr3t"}`}))
+			Expect(courier.CupsCall.Received.ServiceName).To(Equal([]string{"splunk"}))
+			Expect(courier.CupsCall.Received.Credentials).To(Equal([]string{""}))
+			Expect(courier.CupsCall.Received.SyslogDrainURL).To(Equal([]string{"syslog-tls://logs.example.com:6514"}))
+			Expect(*created).To(Equal([]string{"splunk"}))
+
+			Expect(courier.BindServiceCall.Received.ServiceName).To(Equal([]string{"payments-api", "splunk"}))
+			Expect(courier.StartCall.Received.AppName).To(Equal(tempAppWithUUID))
+			Expect(response).To(Say("created user-provided service splunk"))
+		})
+
+		It("does not push when a service cannot be created", func() {
+			courier.CupsCall.Returns.Output = []byte("not authorized")
+			courier.CupsCall.Returns.Error = errors.New("exit status 1")
+
+			err := pusher.Execute()
+
+			Expect(err).To(MatchError(state.UserProvidedServiceError{ServiceName: "splunk", Out: []byte("not authorized")}))
+			Expect(courier.PushCall.Received.AppName).To(BeEmpty())
+		})
+
+		It("deletes the services the push created when it is undone", func() {
+			Expect(pusher.Execute()).To(Succeed())
+			courier.ExistsCall.Returns.Bool = true
+
+			Expect(pusher.Undo()).To(Succeed())
+
+			Expect(courier.DeleteServiceCall.Received.ServiceName).To(Equal([]string{"splunk"}))
+		})
+
+		It("restores the services the push updated when it is undone", func() {
+			courier.UserProvidedServiceCall.Returns.Service = S.UserProvidedService{
+				Credentials:    map[string]interface{}{"token": "previous"},
+				SyslogDrainURL: "syslog://previous",
+			}
+			Expect(pusher.Execute()).To(Succeed())
+			courier.ExistsCall.Returns.Bool = true
+
+			Expect(pusher.Undo()).To(Succeed())
+
+			Expect(courier.UserProvidedServiceCall.Received.ServiceName).To(Equal([]string{"payments-api"}))
+			Expect(courier.UupsCall.Received.ServiceName).To(Equal([]string{"payments-api", "payments-api"}))
+			Expect(courier.UupsCall.Received.Credentials[1]).To(Equal(`{"token":"previous"}`))
+			Expect(courier.UupsCall.Received.SyslogDrainURL[1]).To(Equal("syslog://previous"))
+			Expect(*updated).To(BeEmpty())
+		})
+
+		It("does not update a service it cannot read", func() {
+			courier.UserProvidedServiceCall.Returns.Error = errors.New("not authorized")
+
+			err := pusher.Execute()
+
+			Expect(err).To(MatchError(state.UserProvidedServiceError{ServiceName: "payments-api", Out: []byte("not authorized")}))
+			Expect(courier.UupsCall.Received.ServiceName).To(BeEmpty())
 		})
 	})
 })
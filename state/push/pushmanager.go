@@ -33,9 +33,9 @@ Thanks for using Deployadactyl! Please push down pull up on your lap bar and exi
 
 `
 
-type PushManagerConstructor func(courierCreator I.CourierCreator, eventManager I.EventManager, log I.DeploymentLogger, fetcher I.Fetcher, deployEventData S.DeployEventData, fileSystemCleaner FileSystemCleaner, cfContext I.CFContext, auth I.Authorization, environment S.Environment, envVars map[string]string, healthChecker H.HealthChecker, routeMapper R.RouteMapper) I.ActionCreator
+type PushManagerConstructor func(courierCreator I.CourierCreator, eventManager I.EventManager, log I.DeploymentLogger, fetcher I.Fetcher, deployEventData S.DeployEventData, fileSystemCleaner FileSystemCleaner, fileSystem ManifestFileSystem, cfContext I.CFContext, auth I.Authorization, environment S.Environment, envVars map[string]string, healthChecker H.HealthChecker, routeMapper R.RouteMapper, pushStrategies PushStrategies, hookRunner I.HookRunner) I.ActionCreator
 
-func NewPushManager(c I.CourierCreator, em I.EventManager, log I.DeploymentLogger, f I.Fetcher, ded S.DeployEventData, fcs FileSystemCleaner, cf I.CFContext, auth I.Authorization, env S.Environment, envVars map[string]string, healthChecker H.HealthChecker, routeMapper R.RouteMapper) I.ActionCreator {
+func NewPushManager(c I.CourierCreator, em I.EventManager, log I.DeploymentLogger, f I.Fetcher, ded S.DeployEventData, fcs FileSystemCleaner, fs ManifestFileSystem, cf I.CFContext, auth I.Authorization, env S.Environment, envVars map[string]string, healthChecker H.HealthChecker, routeMapper R.RouteMapper, pushStrategies PushStrategies, hookRunner I.HookRunner) I.ActionCreator {
 	return &PushManager{
 		CourierCreator:       c,
 		EventManager:         em,
@@ -43,12 +43,15 @@ func NewPushManager(c I.CourierCreator, em I.EventManager, log I.DeploymentLogge
 		Fetcher:              f,
 		DeployEventData:      ded,
 		FileSystemCleaner:    fcs,
+		FileSystem:           fs,
 		CFContext:            cf,
 		Auth:                 auth,
 		Environment:          env,
 		EnvironmentVariables: envVars,
 		HealthChecker:        healthChecker,
 		RouteMapper:          routeMapper,
+		PushStrategies:       pushStrategies,
+		HookRunner:           hookRunner,
 	}
 }
 
@@ -63,12 +66,15 @@ type PushManager struct {
 	Fetcher              I.Fetcher
 	DeployEventData      S.DeployEventData
 	FileSystemCleaner    FileSystemCleaner
+	FileSystem           ManifestFileSystem
 	CFContext            I.CFContext
 	Auth                 I.Authorization
 	Environment          S.Environment
 	EnvironmentVariables map[string]string
 	HealthChecker        H.HealthChecker
 	RouteMapper          R.RouteMapper
+	PushStrategies       PushStrategies
+	HookRunner           I.HookRunner
 }
 
 func (a *PushManager) SetUp() error {
@@ -175,6 +181,18 @@ func (a *PushManager) SetUp() error {
 		return deployer.EventError{Type: event.Name(), Err: err}
 	}
 
+	err = a.manifestServiceInstances(manifestString)
+	if err != nil {
+		a.Logger.Error(err)
+		return err
+	}
+
+	err = a.prepareManifest(appPath, manifestString)
+	if err != nil {
+		a.Logger.Error(err)
+		return err
+	}
+
 	a.DeployEventData.DeploymentInfo.Manifest = manifestString
 	a.DeployEventData.DeploymentInfo.AppPath = appPath
 	a.DeployEventData.DeploymentInfo.Instances = *instances
@@ -218,6 +236,23 @@ func (a PushManager) OnStart() error {
 
 func (a PushManager) OnFinish(env S.Environment, response io.ReadWriter, err error) I.DeployResponse {
 	if err != nil {
+		if _, ok := err.(bluegreen.PartialSuccessError); ok {
+			a.Logger.Errorf("partially deployed application %s: %s", a.DeployEventData.DeploymentInfo.AppName, err)
+			fmt.Fprintf(response, "\nYour application was not deployed to all foundations: %s\n\n", err)
+			return I.DeployResponse{
+				StatusCode: http.StatusMultiStatus,
+				Error:      err,
+			}
+		}
+
+		if _, ok := err.(bluegreen.VerifyError); ok {
+			a.Logger.Errorf("pre-flight checks failed for application %s: %s", a.DeployEventData.DeploymentInfo.AppName, err)
+			return I.DeployResponse{
+				StatusCode: http.StatusBadRequest,
+				Error:      err,
+			}
+		}
+
 		if env.DisableRollback {
 			a.Logger.Errorf("DisabledRollback %t, returning status %d and err %s", env.DisableRollback, http.StatusOK, err)
 			return I.DeployResponse{
@@ -249,6 +284,10 @@ func (a PushManager) CleanUp() {
 }
 
 func (a PushManager) Create(environment S.Environment, response io.ReadWriter, foundationURL string) (I.Action, error) {
+	pushStrategy, err := a.PushStrategies.Get(environment.PushStrategy)
+	if err != nil {
+		return &Pusher{}, err
+	}
 
 	courier, err := a.CourierCreator.CreateCourier()
 	if err != nil {
@@ -256,13 +295,27 @@ func (a PushManager) Create(environment S.Environment, response io.ReadWriter, f
 		return &Pusher{}, state.CourierCreationError{Err: err}
 	}
 
+	foundation := environment.GetFoundation(foundationURL)
+	courier = state.Streaming(courier, a.Logger, foundation.GetName(), a.DeployEventData.DeploymentInfo.Output)
+
+	deploymentInfo := *a.DeployEventData.DeploymentInfo
+	deploymentInfo.Org = foundation.GetOrg(deploymentInfo.Org)
+	deploymentInfo.Space = foundation.GetSpace(deploymentInfo.Space)
+	deploymentInfo.SkipSSL = foundation.GetSkipSSL(deploymentInfo.SkipSSL)
+	if foundation.Credentials != nil && !environment.Authenticate {
+		deploymentInfo.Username = foundation.Credentials.Username
+		deploymentInfo.Password = foundation.Credentials.Password
+	}
+
 	p := &Pusher{
 		Courier:        courier,
-		DeploymentInfo: *a.DeployEventData.DeploymentInfo,
+		DeploymentInfo: deploymentInfo,
 		EventManager:   a.EventManager,
 		Response:       response,
 		Log:            a.Logger,
 		FoundationURL:  foundationURL,
+		FoundationName: foundation.GetName(),
+		AppsDomain:     foundation.AppsDomain,
 		AppPath:        a.DeployEventData.DeploymentInfo.AppPath,
 		Environment:    environment,
 		Fetcher:        a.Fetcher,
@@ -270,9 +323,26 @@ func (a PushManager) Create(environment S.Environment, response io.ReadWriter, f
 		Auth:           a.Auth,
 		HealthChecker:  a.HealthChecker,
 		RouteMapper:    a.RouteMapper,
+		HookRunner:     a.HookRunner,
+
+		CreatedServiceInstances:     &[]string{},
+		UpdatedUserProvidedServices: &[]S.UserProvidedService{},
+	}
+
+	applications := manifestro.GetApplications(deploymentInfo.Manifest)
+	if len(applications) < 2 {
+		return pushStrategy(p), nil
+	}
+
+	p.KeepStandby = true
+	p.SharedQuota = true
+
+	actions := []I.Action{pushStrategy(p)}
+	for i, application := range applications[1:] {
+		actions = append(actions, pushStrategy(p.companion(i+1, application)))
 	}
 
-	return p, nil
+	return &ApplicationsPusher{Actions: actions, Bakes: environment.Bake.Enabled()}, nil
 }
 
 func (a PushManager) InitiallyError(initiallyErrors []error) error {
@@ -10,9 +10,12 @@ import (
 
 	"github.com/compozed/deployadactyl/constants"
 	"github.com/compozed/deployadactyl/controller/deployer"
+	"github.com/compozed/deployadactyl/controller/deployer/bluegreen"
+	"github.com/compozed/deployadactyl/controller/deployer/manifestro"
 	"github.com/compozed/deployadactyl/interfaces"
 	"github.com/compozed/deployadactyl/mocks"
 	"github.com/compozed/deployadactyl/randomizer"
+	"github.com/compozed/deployadactyl/state"
 	. "github.com/compozed/deployadactyl/state/push"
 	"github.com/compozed/deployadactyl/structs"
 	"github.com/go-errors/errors"
@@ -467,6 +470,77 @@ applications:
 
 	})
 
+	Describe("Setup with a multi-application manifest", func() {
+		var (
+			af       *afero.Afero
+			manifest string
+		)
+
+		BeforeEach(func() {
+			af = &afero.Afero{Fs: afero.NewMemMapFs()}
+			af.MkdirAll("appPath", 0755)
+			pusherCreator.FileSystem = af
+
+			manifest = `---
+applications:
+- name: api
+  custom-routes:
+  - route: api.example.com
+- name: worker
+  instances: 3
+`
+			af.WriteFile("appPath/manifest.yml", []byte(manifest), 0600)
+			fetcher.FetchCall.Returns.AppPath = "appPath"
+
+			pusherCreator.DeployEventData.DeploymentInfo = &structs.DeploymentInfo{
+				AppName:     "my-app",
+				UUID:        "the-uuid",
+				Manifest:    base64.StdEncoding.EncodeToString([]byte(manifest)),
+				ContentType: "application/json",
+			}
+		})
+
+		It("renames the applications in the manifest file to their temporary names", func() {
+			Expect(pusherCreator.SetUp()).To(Succeed())
+
+			content, _ := af.ReadFile("appPath/manifest.yml")
+			applications := manifestro.GetApplications(string(content))
+			Expect(applications[0].Name).To(Equal("my-app-new-build-the-uuid"))
+			Expect(applications[1].Name).To(Equal("worker-new-build-the-uuid"))
+			Expect(string(content)).To(ContainSubstring("route: api.example.com"))
+			Expect(pusherCreator.DeployEventData.DeploymentInfo.Manifest).To(Equal(manifest))
+		})
+
+		It("renames the applications to their own names when they are pushed in place", func() {
+			pusherCreator.Environment.PushStrategy = RollingPushStrategy
+
+			Expect(pusherCreator.SetUp()).To(Succeed())
+
+			content, _ := af.ReadFile("appPath/manifest.yml")
+			applications := manifestro.GetApplications(string(content))
+			Expect(applications[0].Name).To(Equal("my-app"))
+			Expect(applications[1].Name).To(Equal("worker"))
+		})
+
+		It("returns an error when an application is deployed more than once", func() {
+			manifest = "applications:\n- name: api\n- name: my-app\n"
+			pusherCreator.DeployEventData.DeploymentInfo.Manifest = base64.StdEncoding.EncodeToString([]byte(manifest))
+
+			err := pusherCreator.SetUp()
+
+			Expect(err).To(MatchError(state.ManifestApplicationError{Index: 1, Reason: "my-app is deployed more than once"}))
+		})
+
+		It("returns an error when an application has no name", func() {
+			manifest = "applications:\n- name: api\n- instances: 2\n"
+			pusherCreator.DeployEventData.DeploymentInfo.Manifest = base64.StdEncoding.EncodeToString([]byte(manifest))
+
+			err := pusherCreator.SetUp()
+
+			Expect(err).To(MatchError(state.ManifestApplicationError{Index: 1, Reason: "missing name"}))
+		})
+	})
+
 	Describe("OnStart", func() {
 		Context("push.started Emit", func() {
 			It("emits a push.started event", func() {
@@ -602,6 +676,165 @@ applications:
 
 	})
 
+	Describe("Create", func() {
+		var courierCreator *mocks.CourierCreator
+
+		BeforeEach(func() {
+			courierCreator = &mocks.CourierCreator{}
+			courierCreator.CreateCourierCall.Returns.Courier = &mocks.Courier{}
+			pusherCreator.CourierCreator = courierCreator
+
+			*pusherCreator.DeployEventData.DeploymentInfo = structs.DeploymentInfo{
+				AppName:  "my-app",
+				Org:      "my-org",
+				Space:    "my-space",
+				Username: "config-user",
+				Password: "config-password",
+				SkipSSL:  true,
+			}
+		})
+
+		It("returns a Pusher for a foundation given as a bare URL", func() {
+			env := structs.Environment{Foundations: []string{"https://api.example.com"}}
+
+			action, err := pusherCreator.Create(env, response, "https://api.example.com")
+			Expect(err).ToNot(HaveOccurred())
+
+			pusher := action.(*Pusher)
+			Expect(pusher.FoundationURL).To(Equal("https://api.example.com"))
+			Expect(pusher.FoundationName).To(Equal("https://api.example.com"))
+			Expect(pusher.DeploymentInfo.Org).To(Equal("my-org"))
+			Expect(pusher.DeploymentInfo.Space).To(Equal("my-space"))
+			Expect(pusher.DeploymentInfo.Username).To(Equal("config-user"))
+			Expect(pusher.DeploymentInfo.SkipSSL).To(BeTrue())
+		})
+
+		It("applies the foundation definition", func() {
+			skipSSL := false
+			env := structs.Environment{
+				Foundations: []string{"https://api.east.example.com"},
+				FoundationDefinitions: []structs.Foundation{{
+					Name:        "east",
+					APIURL:      "https://api.east.example.com",
+					AppsDomain:  "apps.east.example.com",
+					SkipSSL:     &skipSSL,
+					Credentials: &structs.FoundationCredentials{Username: "east-user", Password: "east-password"},
+					Orgs:        map[string]string{"my-org": "east-org"},
+					Spaces:      map[string]string{"my-space": "east-space"},
+				}},
+			}
+
+			action, err := pusherCreator.Create(env, response, "https://api.east.example.com")
+			Expect(err).ToNot(HaveOccurred())
+
+			pusher := action.(*Pusher)
+			Expect(pusher.FoundationName).To(Equal("east"))
+			Expect(pusher.AppsDomain).To(Equal("apps.east.example.com"))
+			Expect(pusher.DeploymentInfo.Org).To(Equal("east-org"))
+			Expect(pusher.DeploymentInfo.Space).To(Equal("east-space"))
+			Expect(pusher.DeploymentInfo.Username).To(Equal("east-user"))
+			Expect(pusher.DeploymentInfo.Password).To(Equal("east-password"))
+			Expect(pusher.DeploymentInfo.SkipSSL).To(BeFalse())
+			Expect(pusherCreator.DeployEventData.DeploymentInfo.Org).To(Equal("my-org"))
+		})
+
+		It("does not override the credentials of an authenticated environment", func() {
+			env := structs.Environment{
+				Authenticate: true,
+				FoundationDefinitions: []structs.Foundation{{
+					APIURL:      "https://api.east.example.com",
+					Credentials: &structs.FoundationCredentials{Username: "east-user", Password: "east-password"},
+				}},
+			}
+
+			action, _ := pusherCreator.Create(env, response, "https://api.east.example.com")
+
+			Expect(action.(*Pusher).DeploymentInfo.Username).To(Equal("config-user"))
+		})
+
+		It("returns the action of the push strategy of the environment", func() {
+			env := structs.Environment{Foundations: []string{"https://api.example.com"}, PushStrategy: RollingPushStrategy}
+
+			action, err := pusherCreator.Create(env, response, "https://api.example.com")
+			Expect(err).ToNot(HaveOccurred())
+
+			inPlacePusher := action.(*InPlacePusher)
+			Expect(inPlacePusher.Strategy).To(Equal("rolling"))
+			Expect(inPlacePusher.FoundationURL).To(Equal("https://api.example.com"))
+		})
+
+		Context("when the manifest has more than one application", func() {
+			BeforeEach(func() {
+				pusherCreator.DeployEventData.DeploymentInfo.Instances = 2
+				pusherCreator.DeployEventData.DeploymentInfo.HealthCheckEndpoint = "/health"
+				pusherCreator.DeployEventData.DeploymentInfo.Manifest = `---
+applications:
+- name: api
+- name: worker
+  instances: 3
+- name: scheduler
+`
+			})
+
+			It("returns an action for every application", func() {
+				env := structs.Environment{Foundations: []string{"https://api.example.com"}, Instances: 1, Hooks: []structs.Hook{{Name: "migrate"}}}
+
+				action, err := pusherCreator.Create(env, response, "https://api.example.com")
+				Expect(err).ToNot(HaveOccurred())
+
+				applications := action.(*ApplicationsPusher)
+				Expect(applications.Actions).To(HaveLen(3))
+
+				api := applications.Actions[0].(*Pusher)
+				Expect(api.DeploymentInfo.AppName).To(Equal("my-app"))
+				Expect(api.DeploymentInfo.Instances).To(Equal(uint16(2)))
+				Expect(api.DeploymentInfo.HealthCheckEndpoint).To(Equal("/health"))
+				Expect(api.Environment.Hooks).To(HaveLen(1))
+				Expect(api.ApplicationIndex).To(Equal(0))
+				Expect(api.SharedQuota).To(BeTrue())
+
+				worker := applications.Actions[1].(*Pusher)
+				Expect(worker.DeploymentInfo.AppName).To(Equal("worker"))
+				Expect(worker.DeploymentInfo.Instances).To(Equal(uint16(3)))
+				Expect(worker.DeploymentInfo.HealthCheckEndpoint).To(BeEmpty())
+				Expect(worker.Environment.Hooks).To(BeEmpty())
+				Expect(worker.ApplicationIndex).To(Equal(1))
+				Expect(worker.Courier).To(BeIdenticalTo(api.Courier))
+				Expect(worker.SharedQuota).To(BeTrue())
+
+				scheduler := applications.Actions[2].(*Pusher)
+				Expect(scheduler.DeploymentInfo.AppName).To(Equal("scheduler"))
+				Expect(scheduler.DeploymentInfo.Instances).To(Equal(uint16(1)))
+				Expect(scheduler.ApplicationIndex).To(Equal(2))
+			})
+
+			It("uses the push strategy for every application", func() {
+				env := structs.Environment{Foundations: []string{"https://api.example.com"}, PushStrategy: InPlacePushStrategy}
+
+				action, err := pusherCreator.Create(env, response, "https://api.example.com")
+				Expect(err).ToNot(HaveOccurred())
+
+				for _, application := range action.(*ApplicationsPusher).Actions {
+					Expect(application).To(BeAssignableToTypeOf(&InPlacePusher{}))
+				}
+			})
+		})
+
+		It("uses the push strategies registered with it", func() {
+			custom := &mocks.Pusher{}
+			pusherCreator.PushStrategies = PushStrategies{"custom": func(pusher *Pusher) interfaces.Action { return custom }}
+			env := structs.Environment{Foundations: []string{"https://api.example.com"}, PushStrategy: "custom"}
+
+			action, err := pusherCreator.Create(env, response, "https://api.example.com")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(action).To(BeIdenticalTo(custom))
+
+			env.PushStrategy = InPlacePushStrategy
+			_, err = pusherCreator.Create(env, response, "https://api.example.com")
+			Expect(err).To(MatchError(UnknownPushStrategyError{InPlacePushStrategy}))
+		})
+	})
+
 	Describe("CleanUp", func() {
 		It("deletes all temp artifacts", func() {
 			path := randomizer.StringRunes(10)
@@ -647,6 +880,29 @@ applications:
 					Eventually(string(logBytes)).Should(ContainSubstring("DisabledRollback true, returning status"))
 				})
 			})
+			Context("and only some foundations failed", func() {
+				It("returns StatusMultiStatus", func() {
+					env := structs.Environment{DisableRollback: true}
+					err := bluegreen.PartialSuccessError{Failed: []string{"east"}, Errors: []error{errors.New("a test error")}}
+
+					resp := pusherCreator.OnFinish(env, response, err)
+
+					Expect(resp.StatusCode).To(Equal(http.StatusMultiStatus))
+					Expect(resp.Error).To(Equal(err))
+					Eventually(response).Should(Say("Your application was not deployed to all foundations: partial success: failed on east"))
+				})
+			})
+			Context("and the pre-flight checks failed", func() {
+				It("returns StatusBadRequest", func() {
+					env := structs.Environment{DisableRollback: true}
+					err := bluegreen.VerifyError{VerifyErrors: []error{errors.New("stack cflinuxfs2 does not exist")}}
+
+					resp := pusherCreator.OnFinish(env, response, err)
+
+					Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+					Expect(resp.Error).To(Equal(err))
+				})
+			})
 			Context("and DisableRollback is false", func() {
 				Context("and error is a login failure", func() {
 					It("returns StatusBadRequest", func() {
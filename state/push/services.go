@@ -0,0 +1,321 @@
+package push
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/compozed/deployadactyl/controller/deployer/manifestro"
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/state"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+// createServiceInstances creates the service instances of the push that do not exist in the space
+// and waits until they are provisioned. The ones it creates are collected in CreatedServiceInstances.
+func (p Pusher) createServiceInstances() error {
+	if len(p.DeploymentInfo.ServiceInstances) == 0 {
+		return nil
+	}
+
+	existing, err := p.Courier.Services()
+	if err != nil {
+		p.Log.Errorf("%s: %s", p.foundationName(), err)
+		return state.ListServicesError{Err: err}
+	}
+
+	for _, instance := range p.DeploymentInfo.ServiceInstances {
+		if contains(existing, instance.Name) {
+			p.Log.Debugf("%s: service instance %s already exists", p.foundationName(), instance.Name)
+			continue
+		}
+
+		p.Log.Debugf("%s: creating service instance %s of %s %s", p.foundationName(), instance.Name, instance.Service, instance.Plan)
+
+		params, _ := instance.GetParams()
+
+		var out []byte
+		if params == "" {
+			out, err = p.Courier.CreateService(instance.Service, instance.Plan, instance.Name)
+		} else {
+			out, err = p.Courier.CreateServiceWithParams(instance.Service, instance.Plan, instance.Name, params)
+		}
+		p.Response.Write(out)
+		if err != nil {
+			p.Log.Errorf("%s: could not create service instance %s", p.foundationName(), instance.Name)
+			return state.CreateServiceError{ServiceName: instance.Name, Out: out}
+		}
+
+		if p.CreatedServiceInstances != nil {
+			*p.CreatedServiceInstances = append(*p.CreatedServiceInstances, instance.Name)
+		}
+
+		err = p.waitForServiceInstance(instance)
+		if err != nil {
+			p.Log.Errorf("%s: %s", p.foundationName(), err)
+			return err
+		}
+
+		p.Log.Infof("%s: created service instance %s", p.foundationName(), instance.Name)
+		fmt.Fprintf(p.Response, "created service instance %s\n", instance.Name)
+	}
+
+	return nil
+}
+
+// createUserProvidedServices creates the user-provided services of the push that do not exist in the
+// space and updates the ones that do. The ones it creates are collected in CreatedServiceInstances,
+// and the previous versions of the ones it updates in UpdatedUserProvidedServices, so that undoing
+// the push restores them.
+func (p Pusher) createUserProvidedServices() error {
+	if len(p.DeploymentInfo.UserProvidedServices) == 0 {
+		return nil
+	}
+
+	existing, err := p.Courier.Services()
+	if err != nil {
+		p.Log.Errorf("%s: %s", p.foundationName(), err)
+		return state.ListServicesError{Err: err}
+	}
+
+	for _, service := range p.DeploymentInfo.UserProvidedServices {
+		credentials, _ := service.GetCredentials()
+
+		if contains(existing, service.Name) {
+			p.Log.Debugf("%s: updating user-provided service %s", p.foundationName(), service.Name)
+
+			previous, err := p.Courier.UserProvidedService(service.Name)
+			if err != nil {
+				p.Log.Errorf("%s: could not read user-provided service %s: %s", p.foundationName(), service.Name, err)
+				return state.UserProvidedServiceError{ServiceName: service.Name, Out: []byte(err.Error())}
+			}
+
+			out, err := p.Courier.Uups(service.Name, credentials, service.SyslogDrainURL, service.RouteServiceURL)
+			p.Response.Write(out)
+			if err != nil {
+				p.Log.Errorf("%s: could not update user-provided service %s", p.foundationName(), service.Name)
+				return state.UserProvidedServiceError{ServiceName: service.Name, Out: out}
+			}
+
+			if p.UpdatedUserProvidedServices != nil {
+				*p.UpdatedUserProvidedServices = append(*p.UpdatedUserProvidedServices, previous)
+			}
+
+			p.Log.Infof("%s: updated user-provided service %s", p.foundationName(), service.Name)
+			continue
+		}
+
+		p.Log.Debugf("%s: creating user-provided service %s", p.foundationName(), service.Name)
+
+		out, err := p.Courier.Cups(service.Name, credentials, service.SyslogDrainURL, service.RouteServiceURL)
+		p.Response.Write(out)
+		if err != nil {
+			p.Log.Errorf("%s: could not create user-provided service %s", p.foundationName(), service.Name)
+			return state.UserProvidedServiceError{ServiceName: service.Name, Out: out}
+		}
+
+		if p.CreatedServiceInstances != nil {
+			*p.CreatedServiceInstances = append(*p.CreatedServiceInstances, service.Name)
+		}
+
+		p.Log.Infof("%s: created user-provided service %s", p.foundationName(), service.Name)
+		fmt.Fprintf(p.Response, "created user-provided service %s\n", service.Name)
+	}
+
+	return nil
+}
+
+// waitForServiceInstance checks the status of the service instance until it has been provisioned,
+// its provisioning failed or its timeout has passed.
+func (p Pusher) waitForServiceInstance(instance S.ServiceInstance) error {
+	timeout, _ := instance.GetTimeout()
+	deadline := time.Now().Add(timeout)
+
+	for {
+		status, err := p.Courier.ServiceStatus(instance.Name)
+		if err != nil {
+			return state.ServiceProvisioningError{ServiceName: instance.Name, Reason: err.Error()}
+		}
+
+		switch {
+		case strings.HasSuffix(status, "succeeded"):
+			return nil
+		case strings.HasSuffix(status, "failed"):
+			return state.ServiceProvisioningError{ServiceName: instance.Name, Reason: status}
+		case time.Now().After(deadline):
+			return state.ServiceProvisioningError{ServiceName: instance.Name, Reason: fmt.Sprintf("still %s after %s", status, timeout)}
+		}
+
+		p.Log.Debugf("%s: waiting for service instance %s: %s", p.foundationName(), instance.Name, status)
+		time.Sleep(p.servicePollInterval())
+	}
+}
+
+// serviceNames returns the names of the service instances and user-provided services of the push.
+func (p Pusher) serviceNames() []string {
+	var names []string
+	for _, instance := range p.DeploymentInfo.ServiceInstances {
+		names = append(names, instance.Name)
+	}
+	for _, service := range p.DeploymentInfo.UserProvidedServices {
+		names = append(names, service.Name)
+	}
+	return names
+}
+
+// pushWithServices pushes a new application with the service instances and user-provided services of
+// the push bound to it, so that it sees them in VCAP_SERVICES. When there are any, the application is
+// pushed without starting it, bound to them and then started, so that it is staged once and never runs
+// without its services.
+func (p Pusher) pushWithServices(appName, appPath, strategy string) error {
+	names := p.serviceNames()
+	if len(names) == 0 {
+		return p.pushApplication(appName, appPath, strategy, true)
+	}
+
+	err := p.pushApplication(appName, appPath, strategy, false)
+	if err != nil {
+		return err
+	}
+
+	err = p.bindServices(appName, names)
+	if err != nil {
+		return err
+	}
+
+	p.Log.Debugf("%s: starting %s", p.foundationName(), appName)
+
+	out, err := p.Courier.Start(appName)
+	p.Response.Write(out)
+	if err != nil {
+		p.Log.Errorf("%s: could not start %s", p.foundationName(), appName)
+		if timeout, ok := err.(I.TimeoutError); ok && timeout.Timeout() {
+			return err
+		}
+		return state.StartError{ApplicationName: appName, Out: out}
+	}
+
+	p.Log.Infof("%s: successfully deployed new build %s", p.foundationName(), appName)
+
+	return nil
+}
+
+// bindServices binds the services to the application.
+func (p Pusher) bindServices(appName string, names []string) error {
+	for _, name := range names {
+		p.Log.Debugf("%s: binding %s to %s", p.foundationName(), name, appName)
+
+		out, err := p.Courier.BindService(appName, name)
+		p.Response.Write(out)
+		if err != nil {
+			p.Log.Errorf("%s: could not bind %s to %s", p.foundationName(), name, appName)
+			return state.BindServiceError{ApplicationName: appName, ServiceName: name, Out: out}
+		}
+	}
+
+	p.Log.Infof("%s: bound %d service instances to %s", p.foundationName(), len(names), appName)
+
+	return nil
+}
+
+// deleteCreatedServiceInstances unbinds the service instances the push created from the application
+// and deletes them, even when deleting one of them fails.
+//
+// Returns the first error.
+func (p Pusher) deleteCreatedServiceInstances(appName string) error {
+	if p.CreatedServiceInstances == nil {
+		return nil
+	}
+
+	var deleteErr error
+
+	created := *p.CreatedServiceInstances
+	for i := len(created) - 1; i >= 0; i-- {
+		p.Log.Errorf("%s: deleting service instance %s created by the deploy", p.foundationName(), created[i])
+
+		p.Courier.UnbindService(appName, created[i])
+
+		out, err := p.Courier.DeleteService(created[i])
+		if err != nil && deleteErr == nil {
+			p.Log.Errorf("%s: could not delete service instance %s", p.foundationName(), created[i])
+			deleteErr = state.DeleteServiceError{ServiceName: created[i], Out: out}
+		}
+	}
+
+	*p.CreatedServiceInstances = nil
+
+	return deleteErr
+}
+
+// restoreUserProvidedServices puts back the credentials, syslog drain URL and route service URL that
+// the user-provided services the push updated had before, even when restoring one of them fails.
+//
+// Returns the first error.
+func (p Pusher) restoreUserProvidedServices() error {
+	if p.UpdatedUserProvidedServices == nil {
+		return nil
+	}
+
+	var restoreErr error
+
+	updated := *p.UpdatedUserProvidedServices
+	for i := len(updated) - 1; i >= 0; i-- {
+		previous := updated[i]
+		p.Log.Errorf("%s: restoring user-provided service %s updated by the deploy", p.foundationName(), previous.Name)
+
+		credentials, err := previous.GetCredentials()
+		if credentials == "" {
+			credentials = "{}"
+		}
+
+		var out []byte
+		if err == nil {
+			out, err = p.Courier.Uups(previous.Name, credentials, previous.SyslogDrainURL, previous.RouteServiceURL)
+		}
+		if err != nil && restoreErr == nil {
+			p.Log.Errorf("%s: could not restore user-provided service %s", p.foundationName(), previous.Name)
+			restoreErr = state.UserProvidedServiceError{ServiceName: previous.Name, Out: out}
+		}
+	}
+
+	*p.UpdatedUserProvidedServices = nil
+
+	return restoreErr
+}
+
+func (p Pusher) servicePollInterval() time.Duration {
+	if p.ServicePollInterval > 0 {
+		return p.ServicePollInterval
+	}
+	return S.DefaultServicePollInterval
+}
+
+// manifestServiceInstances checks the service instances of every application in the manifest and
+// adds the ones of the first application to the push.
+func (a PushManager) manifestServiceInstances(manifest string) error {
+	applications := manifestro.GetApplications(manifest)
+
+	for _, application := range applications {
+		for _, instance := range application.ServiceInstances {
+			if reason := instance.Check(); reason != "" {
+				return InvalidServiceInstanceError{instance.Name, reason}
+			}
+		}
+	}
+
+	if len(applications) > 0 {
+		info := a.DeployEventData.DeploymentInfo
+		info.ServiceInstances = append(info.ServiceInstances, applications[0].ServiceInstances...)
+	}
+
+	return nil
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
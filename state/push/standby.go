@@ -0,0 +1,66 @@
+package push
+
+import (
+	"github.com/compozed/deployadactyl/state"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+// keepAsStandby stops the original application and renames it to <app>-previous so that it
+// can be rolled back to. Earlier standbys move down a generation and the ones beyond the
+// number of previous versions kept by the environment are deleted. One is always kept, so that
+// an environment that bakes can roll back to it.
+func (p Pusher) keepAsStandby() error {
+	var (
+		appName = p.DeploymentInfo.AppName
+		keep    = p.Environment.KeepPrevious
+	)
+	if keep < 1 {
+		keep = 1
+	}
+
+	standbys := 0
+	for p.Courier.Exists(S.StandbyName(appName, standbys+1)) {
+		standbys++
+	}
+
+	for generation := standbys; generation >= keep; generation-- {
+		err := p.deleteApplication(S.StandbyName(appName, generation))
+		if err != nil {
+			return err
+		}
+	}
+
+	if standbys > keep-1 {
+		standbys = keep - 1
+	}
+	for generation := standbys; generation >= 1; generation-- {
+		err := p.renameApplication(S.StandbyName(appName, generation), S.StandbyName(appName, generation+1))
+		if err != nil {
+			return err
+		}
+	}
+
+	p.Log.Debugf("%s: stopping %s to keep it as a standby", p.foundationName(), appName)
+
+	out, err := p.Courier.Stop(appName)
+	if err != nil {
+		p.Log.Errorf("%s: could not stop %s: %s", p.foundationName(), appName, out)
+		return state.StopError{ApplicationName: appName, Out: out}
+	}
+
+	return p.renameApplication(appName, S.StandbyName(appName, 1))
+}
+
+func (p Pusher) renameApplication(appName, newAppName string) error {
+	p.Log.Debugf("%s: renaming %s to %s", p.foundationName(), appName, newAppName)
+
+	out, err := p.Courier.Rename(appName, newAppName)
+	if err != nil {
+		p.Log.Errorf("%s: could not rename %s to %s: %s", p.foundationName(), appName, newAppName, out)
+		return state.RenameError{appName, out}
+	}
+
+	p.Log.Infof("%s: renamed %s to %s", p.foundationName(), appName, newAppName)
+
+	return nil
+}
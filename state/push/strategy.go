@@ -0,0 +1,60 @@
+package push
+
+import (
+	"fmt"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+)
+
+const (
+	// BlueGreenPushStrategy pushes a temporary application next to the existing one, moves the routes
+	// over to it and then deletes the existing application. It is the default push strategy.
+	BlueGreenPushStrategy = "blue-green"
+
+	// InPlacePushStrategy runs a plain cf push over the existing application.
+	InPlacePushStrategy = "in-place"
+
+	// RollingPushStrategy runs cf push with the native rolling deployment strategy of Cloud Foundry.
+	RollingPushStrategy = "rolling"
+)
+
+// PushStrategyConstructor returns the action that pushes to a single foundation the way the strategy does.
+// The pusher is set up for the foundation and has everything the push needs.
+type PushStrategyConstructor func(pusher *Pusher) I.Action
+
+// PushStrategies is a registry of push strategies by name.
+type PushStrategies map[string]PushStrategyConstructor
+
+// DefaultPushStrategies returns a registry with the blue-green, in-place and rolling push strategies.
+func DefaultPushStrategies() PushStrategies {
+	return PushStrategies{
+		BlueGreenPushStrategy: func(pusher *Pusher) I.Action { return pusher },
+		InPlacePushStrategy:   func(pusher *Pusher) I.Action { return &InPlacePusher{Pusher: pusher} },
+		RollingPushStrategy:   func(pusher *Pusher) I.Action { return &InPlacePusher{Pusher: pusher, Strategy: "rolling"} },
+	}
+}
+
+// Get returns the push strategy with the given name. An empty name selects BlueGreenPushStrategy
+// and a nil registry has the DefaultPushStrategies.
+func (s PushStrategies) Get(name string) (PushStrategyConstructor, error) {
+	if s == nil {
+		s = DefaultPushStrategies()
+	}
+	if name == "" {
+		name = BlueGreenPushStrategy
+	}
+
+	constructor, ok := s[name]
+	if !ok {
+		return nil, UnknownPushStrategyError{name}
+	}
+	return constructor, nil
+}
+
+type UnknownPushStrategyError struct {
+	PushStrategy string
+}
+
+func (e UnknownPushStrategyError) Error() string {
+	return fmt.Sprintf("unknown push strategy: %s", e.PushStrategy)
+}
@@ -0,0 +1,409 @@
+package push
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/compozed/deployadactyl/cloudcontroller"
+	"github.com/compozed/deployadactyl/controller/deployer/manifestro"
+	R "github.com/compozed/deployadactyl/eventmanager/handlers/routemapper"
+	"github.com/compozed/deployadactyl/state"
+)
+
+// DefaultMemory is the memory in megabytes Cloud Foundry gives each instance of an application
+// whose manifest does not set it.
+const DefaultMemory = 1024
+
+const adminScope = "cloud_controller.admin"
+
+// processesPerRequest is how many applications the processes of the space are requested for at once,
+// so that the URL stays short in spaces with many applications.
+const processesPerRequest = 50
+
+// memoryVerifier is a push that can leave its memory check to the ApplicationsPusher.
+type memoryVerifier interface {
+	requiredMemory() (uint64, error)
+	verifyTotalMemory(required uint64) error
+}
+
+type ccQuota struct {
+	Apps struct {
+		TotalMemoryInMB *uint64 `json:"total_memory_in_mb"`
+	} `json:"apps"`
+}
+
+type ccUsageSummary struct {
+	UsageSummary struct {
+		MemoryInMB uint64 `json:"memory_in_mb"`
+	} `json:"usage_summary"`
+}
+
+// Verify runs the pre-flight checks of the push against the foundation after login. The org and space
+// must exist and the user must be able to push to the space. The quotas of the org and space must leave
+// enough memory for the new instances next to the ones that are running. The custom routes must not
+// belong to another space, and the buildpacks and stack of the manifest must exist.
+//
+// Nothing is changed on the foundation, so a failed check needs no undo.
+func (p Pusher) Verify() error {
+	return p.verify(p.DeploymentInfo.Instances)
+}
+
+// Verify runs the pre-flight checks of Pusher. Pushing in place only needs memory for the instances
+// that Cloud Foundry starts next to the old ones: none for a plain cf push and one at a time for the
+// rolling strategy. A new application needs memory for all of its instances.
+func (p InPlacePusher) Verify() error {
+	return p.verify(p.instancesToStart())
+}
+
+func (p InPlacePusher) instancesToStart() uint16 {
+	if !p.Courier.Exists(p.DeploymentInfo.AppName) {
+		return p.DeploymentInfo.Instances
+	}
+	if p.Strategy != "" {
+		return 1
+	}
+	return 0
+}
+
+func (p Pusher) requiredMemory() (uint64, error) {
+	return p.memory(p.DeploymentInfo.Instances)
+}
+
+func (p InPlacePusher) requiredMemory() (uint64, error) {
+	return p.memory(p.instancesToStart())
+}
+
+func (p Pusher) verify(instances uint16) error {
+	var (
+		info        = p.DeploymentInfo
+		application = p.application()
+	)
+
+	p.Log.Debugf("%s: running pre-flight checks for %s", p.foundationName(), info.AppName)
+
+	org, space, err := p.orgAndSpace()
+	if err != nil {
+		return err
+	}
+
+	err = p.verifySpaceDeveloper(space.GUID)
+	if err != nil {
+		return err
+	}
+
+	if !p.SharedQuota {
+		required, err := p.memory(instances)
+		if err != nil {
+			return err
+		}
+
+		err = p.verifyMemory(org, space, required)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = p.verifyRoutes(space.GUID, application)
+	if err != nil {
+		return err
+	}
+
+	for _, buildpack := range application.GetBuildpacks() {
+		if buildpack == "default" || buildpack == "null" || strings.Contains(buildpack, "://") {
+			continue
+		}
+
+		found, err := p.findResource("/v3/buildpacks?names=" + url.QueryEscape(buildpack))
+		if err != nil {
+			return err
+		}
+		if found == nil {
+			return state.BuildpackNotFoundError{buildpack}
+		}
+	}
+
+	if application.Stack != "" {
+		found, err := p.findResource("/v3/stacks?names=" + url.QueryEscape(application.Stack))
+		if err != nil {
+			return err
+		}
+		if found == nil {
+			return state.StackNotFoundError{application.Stack}
+		}
+	}
+
+	p.Log.Infof("%s: pre-flight checks passed for %s", p.foundationName(), info.AppName)
+
+	return nil
+}
+
+// application returns the application of the manifest that is pushed.
+func (p Pusher) application() manifestro.Application {
+	if applications := manifestro.GetApplications(p.DeploymentInfo.Manifest); len(applications) > p.ApplicationIndex {
+		return applications[p.ApplicationIndex]
+	}
+	return manifestro.Application{}
+}
+
+// orgAndSpace returns the org and space of the push.
+func (p Pusher) orgAndSpace() (cloudcontroller.Resource, cloudcontroller.Resource, error) {
+	info := p.DeploymentInfo
+
+	org, err := p.findResource("/v3/organizations?names=" + url.QueryEscape(info.Org))
+	if err != nil {
+		return cloudcontroller.Resource{}, cloudcontroller.Resource{}, err
+	}
+	if org == nil {
+		return cloudcontroller.Resource{}, cloudcontroller.Resource{}, state.OrgNotFoundError{info.Org}
+	}
+
+	space, err := p.findResource(fmt.Sprintf("/v3/spaces?names=%s&organization_guids=%s", url.QueryEscape(info.Space), org.GUID))
+	if err != nil {
+		return cloudcontroller.Resource{}, cloudcontroller.Resource{}, err
+	}
+	if space == nil {
+		return cloudcontroller.Resource{}, cloudcontroller.Resource{}, state.SpaceNotFoundError{info.Org, info.Space}
+	}
+
+	return *org, *space, nil
+}
+
+// verifySpaceDeveloper checks that the logged in user is an admin or a developer of the space.
+// Tokens that are not issued to a user, such as client credentials, are not checked.
+func (p Pusher) verifySpaceDeveloper(spaceGUID string) error {
+	token, err := p.Courier.OAuthToken()
+	if err != nil {
+		return state.CloudControllerError{"the oauth token", err}
+	}
+
+	claims, err := tokenClaims(token)
+	if err != nil {
+		return state.CloudControllerError{"the oauth token", err}
+	}
+	if claims.UserID == "" || contains(claims.Scope, adminScope) {
+		return nil
+	}
+
+	role, err := p.findResource(fmt.Sprintf("/v3/roles?types=space_developer&space_guids=%s&user_guids=%s", spaceGUID, url.QueryEscape(claims.UserID)))
+	if err != nil {
+		return err
+	}
+	if role == nil {
+		return state.PushNotAuthorizedError{p.DeploymentInfo.Username, p.DeploymentInfo.Org, p.DeploymentInfo.Space}
+	}
+	return nil
+}
+
+// memory returns the memory in megabytes the instances of the application need.
+func (p Pusher) memory(instances uint16) (uint64, error) {
+	memory, err := p.application().GetMemory()
+	if err != nil {
+		return 0, state.ManifestApplicationError{Index: p.ApplicationIndex, Reason: err.Error()}
+	}
+	if memory == 0 {
+		memory = DefaultMemory
+	}
+	return memory * uint64(instances), nil
+}
+
+// verifyTotalMemory checks that the quotas of the org and the space leave room for the memory the
+// applications of a multi-application push need together.
+func (p Pusher) verifyTotalMemory(required uint64) error {
+	if required == 0 {
+		return nil
+	}
+
+	org, space, err := p.orgAndSpace()
+	if err != nil {
+		return err
+	}
+	return p.verifyMemory(org, space, required)
+}
+
+// verifyMemory checks that the quotas of the org and the space leave room for the required memory.
+func (p Pusher) verifyMemory(org, space cloudcontroller.Resource, required uint64) error {
+	if required == 0 {
+		return nil
+	}
+
+	if quotaGUID := org.Relationships.Quota.GUID(); quotaGUID != "" {
+		var usage ccUsageSummary
+		err := p.cloudController(fmt.Sprintf("/v3/organizations/%s/usage_summary", org.GUID), &usage)
+		if err != nil {
+			return err
+		}
+
+		err = p.verifyQuota("/v3/organization_quotas/"+quotaGUID, "org", p.DeploymentInfo.Org, usage.UsageSummary.MemoryInMB, required)
+		if err != nil {
+			return err
+		}
+	}
+
+	if quotaGUID := space.Relationships.Quota.GUID(); quotaGUID != "" {
+		used, err := p.spaceMemory(space.GUID)
+		if err != nil {
+			return err
+		}
+
+		err = p.verifyQuota("/v3/space_quotas/"+quotaGUID, "space", p.DeploymentInfo.Space, used, required)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p Pusher) verifyQuota(path, kind, name string, used, required uint64) error {
+	var quota ccQuota
+	err := p.cloudController(path, &quota)
+	if err != nil {
+		return err
+	}
+
+	total := quota.Apps.TotalMemoryInMB
+	if total == nil {
+		return nil
+	}
+
+	var available uint64
+	if *total > used {
+		available = *total - used
+	}
+	if required > available {
+		return state.InsufficientMemoryError{kind, name, required, available}
+	}
+	return nil
+}
+
+// spaceMemory returns the memory in megabytes of the started applications of the space. Their
+// processes are requested processesPerRequest applications at a time.
+func (p Pusher) spaceMemory(spaceGUID string) (uint64, error) {
+	var apps cloudcontroller.List
+	err := p.cloudController(fmt.Sprintf("/v3/apps?space_guids=%s&states=STARTED&per_page=5000", spaceGUID), &apps)
+	if err != nil || len(apps.Resources) == 0 {
+		return 0, err
+	}
+
+	guids := make([]string, len(apps.Resources))
+	for i, app := range apps.Resources {
+		guids[i] = app.GUID
+	}
+
+	var used uint64
+	for start := 0; start < len(guids); start += processesPerRequest {
+		end := start + processesPerRequest
+		if end > len(guids) {
+			end = len(guids)
+		}
+
+		var processes cloudcontroller.List
+		err = p.cloudController(fmt.Sprintf("/v3/processes?app_guids=%s&per_page=5000", strings.Join(guids[start:end], ",")), &processes)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, process := range processes.Resources {
+			used += process.MemoryInMB * process.Instances
+		}
+	}
+	return used, nil
+}
+
+// verifyRoutes checks that none of the custom routes of the application exist in another space.
+// Routes that are not on a domain of the foundation are rejected like the route mapper rejects them.
+func (p Pusher) verifyRoutes(spaceGUID string, application manifestro.Application) error {
+	if len(application.CustomRoutes) == 0 {
+		return nil
+	}
+
+	domains, err := p.Courier.Domains()
+	if err != nil {
+		return state.CloudControllerError{"the domains", err}
+	}
+
+	for _, route := range application.CustomRoutes {
+		hostname, domainName, path, ok := R.SplitRoute(route.Route, domains, p.DeploymentInfo.AppName)
+		if !ok {
+			return R.InvalidRouteError{route.Route}
+		}
+
+		domain, err := p.findResource("/v3/domains?names=" + url.QueryEscape(domainName))
+		if err != nil {
+			return err
+		}
+		if domain == nil {
+			continue
+		}
+
+		var routes cloudcontroller.List
+		err = p.cloudController(fmt.Sprintf("/v3/routes?domain_guids=%s&hosts=%s", domain.GUID, url.QueryEscape(hostname)), &routes)
+		if err != nil {
+			return err
+		}
+
+		for _, existing := range routes.Resources {
+			if strings.TrimPrefix(existing.Path, "/") != path {
+				continue
+			}
+			if existing.Relationships.Space.GUID() != spaceGUID {
+				return state.RouteOwnedByAnotherSpaceError{route.Route}
+			}
+		}
+	}
+
+	return nil
+}
+
+// findResource returns the first resource of a list from the cloud controller, or nil if it is empty.
+func (p Pusher) findResource(path string) (*cloudcontroller.Resource, error) {
+	var list cloudcontroller.List
+	err := p.cloudController(path, &list)
+	if err != nil || len(list.Resources) == 0 {
+		return nil, err
+	}
+	return &list.Resources[0], nil
+}
+
+// cloudController gets a path of the Cloud Controller API and decodes the response into v.
+func (p Pusher) cloudController(path string, v interface{}) error {
+	err := cloudcontroller.Get(p.Courier, path, v)
+	if err != nil {
+		p.Log.Errorf("%s: cannot get %s: %s", p.foundationName(), path, err)
+		return state.CloudControllerError{path, err}
+	}
+	return nil
+}
+
+type claims struct {
+	UserID string   `json:"user_id"`
+	Scope  []string `json:"scope"`
+}
+
+// tokenClaims decodes the claims of a bearer token without checking its signature. The token comes
+// from the cf CLI right after login, so it is only read to find out who is logged in.
+func tokenClaims(token string) (claims, error) {
+	var c claims
+
+	token = strings.TrimSpace(token)
+	if len(token) > 7 && strings.EqualFold(token[:7], "bearer ") {
+		token = token[7:]
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return c, errors.New("token is not a JWT")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return c, err
+	}
+
+	err = json.Unmarshal(payload, &c)
+	return c, err
+}
@@ -0,0 +1,133 @@
+package rollback
+
+import (
+	"io"
+	"reflect"
+
+	"github.com/compozed/deployadactyl/eventmanager"
+	"github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/structs"
+	"github.com/go-errors/errors"
+)
+
+type eventBinding struct {
+	etype   reflect.Type
+	handler func(event interface{}) error
+}
+
+func (s eventBinding) Accepts(event interface{}) bool {
+	return reflect.TypeOf(event) == s.etype
+}
+
+func (b eventBinding) Emit(event interface{}) error {
+	return b.handler(event)
+}
+
+type RollbackStartedEvent struct {
+	CFContext     interfaces.CFContext
+	Data          map[string]interface{}
+	Authorization interfaces.Authorization
+	Environment   structs.Environment
+	Response      io.ReadWriter
+	Log           interfaces.DeploymentLogger
+}
+
+func (e RollbackStartedEvent) Name() string {
+	return "RollbackStartedEvent"
+}
+
+func NewRollbackStartedEventBinding(handler func(event RollbackStartedEvent) error) interfaces.Binding {
+	return eventBinding{
+		etype: reflect.TypeOf(RollbackStartedEvent{}),
+		handler: func(gevent interface{}) error {
+			event, ok := gevent.(RollbackStartedEvent)
+			if ok {
+				return handler(event)
+			} else {
+				return eventmanager.InvalidEventType{errors.New("invalid event type")}
+			}
+		},
+	}
+}
+
+type RollbackSuccessEvent struct {
+	CFContext     interfaces.CFContext
+	Data          map[string]interface{}
+	Authorization interfaces.Authorization
+	Environment   structs.Environment
+	Response      io.ReadWriter
+	Log           interfaces.DeploymentLogger
+}
+
+func (e RollbackSuccessEvent) Name() string {
+	return "RollbackSuccessEvent"
+}
+
+func NewRollbackSuccessEventBinding(handler func(event RollbackSuccessEvent) error) interfaces.Binding {
+	return eventBinding{
+		etype: reflect.TypeOf(RollbackSuccessEvent{}),
+		handler: func(gevent interface{}) error {
+			event, ok := gevent.(RollbackSuccessEvent)
+			if ok {
+				return handler(event)
+			} else {
+				return eventmanager.InvalidEventType{errors.New("invalid event type")}
+			}
+		},
+	}
+}
+
+type RollbackFailureEvent struct {
+	CFContext     interfaces.CFContext
+	Data          map[string]interface{}
+	Authorization interfaces.Authorization
+	Environment   structs.Environment
+	Error         error
+	Response      io.ReadWriter
+	Log           interfaces.DeploymentLogger
+}
+
+func (e RollbackFailureEvent) Name() string {
+	return "RollbackFailureEvent"
+}
+
+func NewRollbackFailureEventBinding(handler func(event RollbackFailureEvent) error) interfaces.Binding {
+	return eventBinding{
+		etype: reflect.TypeOf(RollbackFailureEvent{}),
+		handler: func(gevent interface{}) error {
+			event, ok := gevent.(RollbackFailureEvent)
+			if ok {
+				return handler(event)
+			} else {
+				return eventmanager.InvalidEventType{errors.New("invalid event type")}
+			}
+		},
+	}
+}
+
+type RollbackFinishedEvent struct {
+	CFContext     interfaces.CFContext
+	Data          map[string]interface{}
+	Authorization interfaces.Authorization
+	Environment   structs.Environment
+	Response      io.ReadWriter
+	Log           interfaces.DeploymentLogger
+}
+
+func (e RollbackFinishedEvent) Name() string {
+	return "RollbackFinishedEvent"
+}
+
+func NewRollbackFinishedEventBinding(handler func(event RollbackFinishedEvent) error) interfaces.Binding {
+	return eventBinding{
+		etype: reflect.TypeOf(RollbackFinishedEvent{}),
+		handler: func(gevent interface{}) error {
+			event, ok := gevent.(RollbackFinishedEvent)
+			if ok {
+				return handler(event)
+			} else {
+				return eventmanager.InvalidEventType{errors.New("invalid event type")}
+			}
+		},
+	}
+}
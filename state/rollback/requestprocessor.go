@@ -0,0 +1,30 @@
+package rollback
+
+import (
+	"bytes"
+
+	"github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/request"
+)
+
+type RollbackRequestProcessorConstructor func(log interfaces.DeploymentLogger, controller request.RollbackController, request request.PutDeploymentRequest, buffer *bytes.Buffer) interfaces.RequestProcessor
+
+func NewRollbackRequestProcessor(log interfaces.DeploymentLogger, rc request.RollbackController, request request.PutDeploymentRequest, buffer *bytes.Buffer) interfaces.RequestProcessor {
+	return &RollbackRequestProcessor{
+		RollbackController: rc,
+		Request:            request,
+		Response:           buffer,
+		Log:                log,
+	}
+}
+
+type RollbackRequestProcessor struct {
+	RollbackController request.RollbackController
+	Request            request.PutDeploymentRequest
+	Response           *bytes.Buffer
+	Log                interfaces.DeploymentLogger
+}
+
+func (c RollbackRequestProcessor) Process() interfaces.DeployResponse {
+	return c.RollbackController.RollbackDeployment(c.Request, c.Response)
+}
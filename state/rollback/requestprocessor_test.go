@@ -0,0 +1,43 @@
+package rollback_test
+
+import (
+	"bytes"
+
+	"github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/mocks"
+	"github.com/compozed/deployadactyl/request"
+	. "github.com/compozed/deployadactyl/state/rollback"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RollbackRequestProcessor", func() {
+
+	Describe("Process", func() {
+		It("calls RollbackDeployment with the Request and Response", func() {
+			rollbackController := &mocks.RollbackController{}
+
+			processor := RollbackRequestProcessor{
+				RollbackController: rollbackController,
+				Request: request.PutDeploymentRequest{
+					Deployment: interfaces.Deployment{
+						CFContext: interfaces.CFContext{
+							Environment:  "the environment",
+							Space:        "the space",
+							Organization: "the org",
+							Application:  "the app",
+						},
+					},
+					Request: request.PutRequest{State: request.RolledBackState},
+				},
+				Response: bytes.NewBuffer([]byte("foobar")),
+			}
+
+			processor.Process()
+
+			Expect(rollbackController.RollbackDeploymentCall.Received.Deployment).To(Equal(processor.Request))
+			Expect(rollbackController.RollbackDeploymentCall.Received.Response).To(Equal(processor.Response))
+		})
+	})
+})
@@ -0,0 +1,216 @@
+// Package rollback swaps applications back to the previous version kept as a stopped standby.
+package rollback
+
+import (
+	"io"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/state"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+// ReplacedNameSuffix is used to rename the application that is being rolled back from while
+// its standby takes over its name.
+const ReplacedNameSuffix = "-rolled-back-"
+
+// Rollbacker swaps an application with its standby on a single foundation. The standby is
+// started and renamed to the application name, so nothing is fetched or staged again. The
+// application it replaces is stopped and becomes the new standby.
+type Rollbacker struct {
+	Courier        I.Courier
+	CFContext      I.CFContext
+	Authorization  I.Authorization
+	EventManager   I.EventManager
+	Response       io.ReadWriter
+	Log            I.DeploymentLogger
+	FoundationURL  string
+	FoundationName string
+	AppName        string
+	Domain         string
+	UUID           string
+}
+
+func (r Rollbacker) Verify() error {
+	return nil
+}
+
+// Login will login to a Cloud Foundry instance.
+func (r Rollbacker) Initially() error {
+	r.Log.Debugf(
+		`logging into cloud foundry with parameters:
+		foundation URL: %+v
+		username: %+v
+		org: %+v
+		space: %+v`,
+		r.FoundationURL, r.Authorization.Username, r.CFContext.Organization, r.CFContext.Space,
+	)
+
+	output, err := r.Courier.Login(
+		r.FoundationURL,
+		r.Authorization.Username,
+		r.Authorization.Password,
+		r.CFContext.Organization,
+		r.CFContext.Space,
+		r.CFContext.SkipSSL,
+	)
+	r.Response.Write(output)
+	if err != nil {
+		r.Log.Errorf("could not login to %s", r.FoundationURL)
+		if timeout, ok := err.(I.TimeoutError); ok && timeout.Timeout() {
+			return err
+		}
+		return state.LoginError{r.FoundationURL, output}
+	}
+
+	r.Log.Infof("logged into cloud foundry %s", r.FoundationURL)
+
+	return nil
+}
+
+// Execute starts the standby and gives it the application name and routes.
+func (r Rollbacker) Execute() error {
+	standby := S.StandbyName(r.AppName, 1)
+
+	if !r.Courier.Exists(r.AppName) {
+		r.Log.Errorf("%s: failed to roll back: application %s doesn't exist", r.foundationName(), r.AppName)
+		return state.ExistsError{ApplicationName: r.AppName}
+	}
+
+	if !r.Courier.Exists(standby) {
+		r.Log.Errorf("%s: failed to roll back: standby %s doesn't exist", r.foundationName(), standby)
+		return state.StandbyNotFoundError{ApplicationName: r.AppName}
+	}
+
+	r.Log.Infof("%s: starting standby %s", r.foundationName(), standby)
+
+	output, err := r.Courier.Start(standby)
+	r.Response.Write(output)
+	if err != nil {
+		r.Log.Errorf("%s: could not start %s", r.foundationName(), standby)
+		return state.StartError{ApplicationName: standby, Out: output}
+	}
+
+	if r.Domain != "" {
+		output, err = r.Courier.MapRoute(standby, r.Domain, r.AppName)
+		if err != nil {
+			r.Log.Errorf("%s: could not map %s to %s", r.foundationName(), standby, r.Domain)
+			return state.MapRouteError{output}
+		}
+	}
+
+	err = r.rename(r.AppName, r.replacedName())
+	if err != nil {
+		return err
+	}
+
+	return r.rename(standby, r.AppName)
+}
+
+func (r Rollbacker) PostExecute() error {
+	return nil
+}
+
+// Success stops the application that was rolled back from and keeps it as the standby, so that
+// the rollback can itself be rolled back.
+func (r Rollbacker) Success() error {
+	replaced := r.replacedName()
+
+	if r.Domain != "" {
+		output, err := r.Courier.UnmapRoute(replaced, r.Domain, r.AppName)
+		if err != nil {
+			r.Log.Errorf("%s: could not unmap %s from %s", r.foundationName(), replaced, r.Domain)
+			return state.UnmapRouteError{replaced, output}
+		}
+	}
+
+	r.Log.Infof("%s: stopping %s", r.foundationName(), replaced)
+
+	output, err := r.Courier.Stop(replaced)
+	r.Response.Write(output)
+	if err != nil {
+		return state.StopError{ApplicationName: replaced, Out: output}
+	}
+
+	err = r.rename(replaced, S.StandbyName(r.AppName, 1))
+	if err != nil {
+		return err
+	}
+
+	r.Log.Infof("%s: successfully rolled back %s", r.foundationName(), r.AppName)
+
+	return nil
+}
+
+// Undo puts the application and its standby back the way they were before Execute.
+func (r Rollbacker) Undo() error {
+	var (
+		standby  = S.StandbyName(r.AppName, 1)
+		replaced = r.replacedName()
+	)
+
+	r.Log.Errorf("%s: undoing rollback of %s", r.foundationName(), r.AppName)
+
+	if r.Courier.Exists(replaced) {
+		if r.Courier.Exists(r.AppName) {
+			err := r.rename(r.AppName, standby)
+			if err != nil {
+				return err
+			}
+		}
+
+		err := r.rename(replaced, r.AppName)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !r.Courier.Exists(standby) {
+		return nil
+	}
+
+	if r.Domain != "" {
+		output, err := r.Courier.UnmapRoute(standby, r.Domain, r.AppName)
+		if err != nil {
+			return state.UnmapRouteError{standby, output}
+		}
+	}
+
+	output, err := r.Courier.Stop(standby)
+	if err != nil {
+		return state.StopError{ApplicationName: standby, Out: output}
+	}
+
+	return nil
+}
+
+// Interrupt kills the Cloud Foundry commands of a phase that ran out of time.
+func (r Rollbacker) Interrupt() {
+	state.Interrupt(r.Courier)
+}
+
+// CleanUp removes the temporary directory created by the Executor.
+func (r Rollbacker) Finally() error {
+	return r.Courier.CleanUp()
+}
+
+func (r Rollbacker) rename(appName, newAppName string) error {
+	r.Log.Debugf("%s: renaming %s to %s", r.foundationName(), appName, newAppName)
+
+	output, err := r.Courier.Rename(appName, newAppName)
+	if err != nil {
+		r.Log.Errorf("%s: could not rename %s to %s", r.foundationName(), appName, newAppName)
+		return state.RenameError{appName, output}
+	}
+
+	r.Log.Infof("%s: renamed %s to %s", r.foundationName(), appName, newAppName)
+
+	return nil
+}
+
+func (r Rollbacker) replacedName() string {
+	return r.AppName + ReplacedNameSuffix + r.UUID
+}
+
+func (r Rollbacker) foundationName() string {
+	return S.FoundationName(r.FoundationName, r.FoundationURL)
+}
@@ -0,0 +1,154 @@
+package rollback_test
+
+import (
+	"errors"
+
+	"github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/mocks"
+	"github.com/compozed/deployadactyl/randomizer"
+	"github.com/compozed/deployadactyl/state"
+	. "github.com/compozed/deployadactyl/state/rollback"
+	"github.com/op/go-logging"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("Rollbacker", func() {
+	var (
+		rollbacker Rollbacker
+		courier    *mocks.Courier
+
+		appName       string
+		standby       string
+		replaced      string
+		domain        string
+		uuid          string
+		foundationURL string
+		response      *Buffer
+		logBuffer     *Buffer
+	)
+
+	BeforeEach(func() {
+		courier = &mocks.Courier{}
+
+		appName = "appName-" + randomizer.StringRunes(10)
+		domain = "domain-" + randomizer.StringRunes(10)
+		uuid = randomizer.StringRunes(10)
+		foundationURL = "foundationURL-" + randomizer.StringRunes(10)
+		standby = appName + "-previous"
+		replaced = appName + ReplacedNameSuffix + uuid
+
+		response = NewBuffer()
+		logBuffer = NewBuffer()
+
+		courier.ExistsCall.Returns.Apps = map[string]bool{appName: true, standby: true}
+
+		rollbacker = Rollbacker{
+			Courier:       courier,
+			CFContext:     interfaces.CFContext{Organization: "org", Space: "space", Application: appName},
+			Authorization: interfaces.Authorization{Username: "username", Password: "password"},
+			EventManager:  &mocks.EventManager{},
+			Response:      response,
+			Log:           interfaces.DeploymentLogger{Log: interfaces.DefaultLogger(logBuffer, logging.DEBUG, "rollbacker_test")},
+			FoundationURL: foundationURL,
+			AppName:       appName,
+			Domain:        domain,
+			UUID:          uuid,
+		}
+	})
+
+	Describe("Initially", func() {
+		It("logs in to the foundation", func() {
+			Expect(rollbacker.Initially()).To(Succeed())
+
+			Expect(courier.LoginCall.Received.FoundationURL).To(Equal(foundationURL))
+			Expect(courier.LoginCall.Received.Username).To(Equal("username"))
+			Expect(courier.LoginCall.Received.Org).To(Equal("org"))
+		})
+
+		It("returns an error when login fails", func() {
+			courier.LoginCall.Returns.Output = []byte("login output")
+			courier.LoginCall.Returns.Error = errors.New("login error")
+
+			Expect(rollbacker.Initially()).To(MatchError(state.LoginError{foundationURL, []byte("login output")}))
+		})
+	})
+
+	Describe("Execute", func() {
+		It("starts the standby and gives it the application name and route", func() {
+			Expect(rollbacker.Execute()).To(Succeed())
+
+			Expect(courier.StartCall.Received.AppNames).To(Equal([]string{standby}))
+			Expect(courier.MapRouteCall.Received.AppName).To(Equal([]string{standby}))
+			Expect(courier.MapRouteCall.Received.Domain).To(Equal([]string{domain}))
+			Expect(courier.MapRouteCall.Received.Hostname).To(Equal([]string{appName}))
+			Expect(courier.RenameCall.Received.Renames).To(Equal([][2]string{
+				{appName, replaced},
+				{standby, appName},
+			}))
+			Expect(courier.PushCall.Received.AppName).To(BeEmpty())
+		})
+
+		It("returns an error when there is no standby", func() {
+			delete(courier.ExistsCall.Returns.Apps, standby)
+
+			Expect(rollbacker.Execute()).To(MatchError(state.StandbyNotFoundError{ApplicationName: appName}))
+			Expect(courier.StartCall.Received.AppNames).To(BeEmpty())
+		})
+
+		It("returns an error when the application does not exist", func() {
+			delete(courier.ExistsCall.Returns.Apps, appName)
+
+			Expect(rollbacker.Execute()).To(MatchError(state.ExistsError{ApplicationName: appName}))
+		})
+
+		It("returns an error when the standby cannot be started", func() {
+			courier.StartCall.Returns.Output = []byte("start output")
+			courier.StartCall.Returns.Error = errors.New("start error")
+
+			Expect(rollbacker.Execute()).To(MatchError(state.StartError{ApplicationName: standby, Out: []byte("start output")}))
+			Expect(courier.RenameCall.Received.Renames).To(BeEmpty())
+		})
+	})
+
+	Describe("Success", func() {
+		It("stops the replaced application and keeps it as the standby", func() {
+			Expect(rollbacker.Execute()).To(Succeed())
+			Expect(rollbacker.Success()).To(Succeed())
+
+			Expect(courier.UnmapRouteCall.Received.AppName).To(Equal(replaced))
+			Expect(courier.StopCall.Received.AppNames).To(Equal([]string{replaced}))
+			Expect(courier.RenameCall.Received.Renames[2]).To(Equal([2]string{replaced, standby}))
+			Expect(courier.ExistsCall.Returns.Apps).To(Equal(map[string]bool{appName: true, standby: true}))
+			Expect(logBuffer).To(Say("successfully rolled back %s", appName))
+		})
+	})
+
+	Describe("Undo", func() {
+		It("puts the application and the standby back", func() {
+			Expect(rollbacker.Execute()).To(Succeed())
+			Expect(rollbacker.Undo()).To(Succeed())
+
+			Expect(courier.RenameCall.Received.Renames[2:]).To(Equal([][2]string{
+				{appName, standby},
+				{replaced, appName},
+			}))
+			Expect(courier.UnmapRouteCall.Received.AppName).To(Equal(standby))
+			Expect(courier.StopCall.Received.AppNames).To(Equal([]string{standby}))
+			Expect(courier.ExistsCall.Returns.Apps).To(Equal(map[string]bool{appName: true, standby: true}))
+		})
+
+		It("stops the standby when it was started but not renamed", func() {
+			courier.RenameCall.Returns.Error = errors.New("rename error")
+			Expect(rollbacker.Execute()).ToNot(Succeed())
+
+			courier.RenameCall.Returns.Error = nil
+			Expect(rollbacker.Undo()).To(Succeed())
+
+			Expect(courier.StopCall.Received.AppNames).To(Equal([]string{standby}))
+			Expect(courier.ExistsCall.Returns.Apps).To(Equal(map[string]bool{appName: true, standby: true}))
+		})
+	})
+})
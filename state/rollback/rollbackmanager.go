@@ -0,0 +1,135 @@
+package rollback
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+
+	"github.com/compozed/deployadactyl/controller/deployer/bluegreen"
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/state"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+const successfulRollback = `Your rollback was successful! (^_^)b
+
+`
+
+type RollbackManagerConstructor func(courierCreator I.CourierCreator, eventManager I.EventManager, log I.DeploymentLogger, deployEventData S.DeployEventData) I.ActionCreator
+
+func NewRollbackManager(c I.CourierCreator, em I.EventManager, log I.DeploymentLogger, ded S.DeployEventData) I.ActionCreator {
+	return &RollbackManager{
+		CourierCreator:  c,
+		EventManager:    em,
+		Log:             log,
+		DeployEventData: ded,
+	}
+}
+
+type RollbackManager struct {
+	CourierCreator  I.CourierCreator
+	EventManager    I.EventManager
+	Log             I.DeploymentLogger
+	DeployEventData S.DeployEventData
+}
+
+func (a RollbackManager) Logger() I.DeploymentLogger {
+	return a.Log
+}
+
+func (a RollbackManager) SetUp() error {
+	return nil
+}
+
+func (a RollbackManager) OnStart() error {
+	return nil
+}
+
+func (a RollbackManager) OnFinish(env S.Environment, response io.ReadWriter, err error) I.DeployResponse {
+	if err != nil {
+		fmt.Fprintf(response, "\nYour application was not successfully rolled back on all foundations: %s\n\n", err.Error())
+		if _, ok := err.(bluegreen.PartialSuccessError); ok {
+			return I.DeployResponse{
+				StatusCode: http.StatusMultiStatus,
+				Error:      err,
+			}
+		}
+		if matched, _ := regexp.MatchString("login failed", err.Error()); matched {
+			return I.DeployResponse{
+				StatusCode: http.StatusBadRequest,
+				Error:      err,
+			}
+		}
+
+		return I.DeployResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      err,
+		}
+	}
+
+	a.Log.Infof("successfully rolled back application %s", a.DeployEventData.DeploymentInfo.AppName)
+	fmt.Fprintf(response, "\n%s", successfulRollback)
+
+	return I.DeployResponse{StatusCode: http.StatusOK}
+}
+
+func (a RollbackManager) CleanUp() {}
+
+func (a RollbackManager) Create(environment S.Environment, response io.ReadWriter, foundationURL string) (I.Action, error) {
+	courier, err := a.CourierCreator.CreateCourier()
+	if err != nil {
+		a.Log.Error(err)
+		return &Rollbacker{}, state.CourierCreationError{Err: err}
+	}
+
+	foundation := environment.GetFoundation(foundationURL)
+	courier = state.Streaming(courier, a.Log, foundation.GetName(), a.DeployEventData.DeploymentInfo.Output)
+	info := a.DeployEventData.DeploymentInfo
+
+	username, password := info.Username, info.Password
+	if foundation.Credentials != nil && !environment.Authenticate {
+		username, password = foundation.Credentials.Username, foundation.Credentials.Password
+	}
+
+	r := &Rollbacker{
+		Courier: courier,
+		CFContext: I.CFContext{
+			Environment:  environment.Name,
+			Organization: foundation.GetOrg(info.Org),
+			Space:        foundation.GetSpace(info.Space),
+			Application:  info.AppName,
+			SkipSSL:      foundation.GetSkipSSL(info.SkipSSL),
+		},
+		Authorization: I.Authorization{
+			Username: username,
+			Password: password,
+		},
+		EventManager:   a.EventManager,
+		Response:       response,
+		Log:            a.Log,
+		FoundationURL:  foundationURL,
+		FoundationName: foundation.GetName(),
+		AppName:        info.AppName,
+		Domain:         info.Domain,
+		UUID:           info.UUID,
+	}
+
+	return r, nil
+}
+
+func (a RollbackManager) InitiallyError(initiallyErrors []error) error {
+	return bluegreen.LoginError{LoginErrors: initiallyErrors}
+}
+
+func (a RollbackManager) ExecuteError(executeErrors []error) error {
+	return bluegreen.RollbackToStandbyError{Errors: executeErrors}
+}
+
+func (a RollbackManager) UndoError(executeErrors, undoErrors []error) error {
+	return bluegreen.UndoRollbackToStandbyError{RollbackErrors: executeErrors, UndoErrors: undoErrors}
+}
+
+func (a RollbackManager) SuccessError(successErrors []error) error {
+	return bluegreen.FinishRollbackToStandbyError{FinishRollbackErrors: successErrors}
+}
@@ -0,0 +1,98 @@
+package rollback_test
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/compozed/deployadactyl/controller/deployer/bluegreen"
+	"github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/mocks"
+	"github.com/compozed/deployadactyl/state"
+	. "github.com/compozed/deployadactyl/state/rollback"
+	S "github.com/compozed/deployadactyl/structs"
+	"github.com/op/go-logging"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("RollbackManager", func() {
+	var (
+		manager        RollbackManager
+		courierCreator *mocks.CourierCreator
+		response       *Buffer
+	)
+
+	BeforeEach(func() {
+		courierCreator = &mocks.CourierCreator{}
+		courierCreator.CreateCourierCall.Returns.Courier = &mocks.Courier{}
+		response = NewBuffer()
+
+		manager = RollbackManager{
+			CourierCreator: courierCreator,
+			Log:            interfaces.DeploymentLogger{Log: interfaces.DefaultLogger(NewBuffer(), logging.DEBUG, "rollbackmanager_test")},
+			DeployEventData: S.DeployEventData{DeploymentInfo: &S.DeploymentInfo{
+				AppName:  "app",
+				Org:      "org",
+				Space:    "space",
+				Domain:   "example.com",
+				UUID:     "uuid",
+				Username: "username",
+				Password: "password",
+			}},
+		}
+	})
+
+	Describe("Create", func() {
+		It("creates a rollbacker for the foundation", func() {
+			environment := S.Environment{
+				Name:                  "prod",
+				FoundationDefinitions: []S.Foundation{{Name: "east", APIURL: "https://api.east.example.com", Spaces: map[string]string{"space": "east-space"}}},
+			}
+
+			action, err := manager.Create(environment, response, "https://api.east.example.com")
+
+			Expect(err).ToNot(HaveOccurred())
+			rollbacker := action.(*Rollbacker)
+			Expect(rollbacker.AppName).To(Equal("app"))
+			Expect(rollbacker.Domain).To(Equal("example.com"))
+			Expect(rollbacker.UUID).To(Equal("uuid"))
+			Expect(rollbacker.FoundationName).To(Equal("east"))
+			Expect(rollbacker.CFContext.Space).To(Equal("east-space"))
+			Expect(rollbacker.Authorization.Username).To(Equal("username"))
+		})
+
+		It("returns an error when the courier cannot be created", func() {
+			courierCreator.CreateCourierCall.Returns.Error = errors.New("courier error")
+
+			_, err := manager.Create(S.Environment{}, response, "https://api.east.example.com")
+
+			Expect(err).To(MatchError(state.CourierCreationError{Err: errors.New("courier error")}))
+		})
+	})
+
+	Describe("OnFinish", func() {
+		It("returns StatusOK and writes success to the output", func() {
+			deployResponse := manager.OnFinish(S.Environment{}, response, nil)
+
+			Expect(deployResponse.StatusCode).To(Equal(http.StatusOK))
+			Expect(response).To(Say("Your rollback was successful!"))
+		})
+
+		It("returns StatusMultiStatus when only some foundations were rolled back", func() {
+			err := bluegreen.PartialSuccessError{Failed: []string{"east"}}
+
+			Expect(manager.OnFinish(S.Environment{}, response, err).StatusCode).To(Equal(http.StatusMultiStatus))
+		})
+
+		It("returns StatusInternalServerError when the rollback failed", func() {
+			err := bluegreen.RollbackToStandbyError{Errors: []error{errors.New("no standby")}}
+
+			deployResponse := manager.OnFinish(S.Environment{}, response, err)
+
+			Expect(deployResponse.StatusCode).To(Equal(http.StatusInternalServerError))
+			Expect(response).To(Say("not successfully rolled back on all foundations: rollback failed: no standby"))
+		})
+	})
+})
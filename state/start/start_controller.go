@@ -11,6 +11,7 @@ import (
 	"github.com/compozed/deployadactyl/controller/deployer/bluegreen"
 	I "github.com/compozed/deployadactyl/interfaces"
 	"github.com/compozed/deployadactyl/request"
+	"github.com/compozed/deployadactyl/state"
 	"github.com/compozed/deployadactyl/structs"
 )
 
@@ -65,6 +66,15 @@ func (c *StartController) StartDeployment(deployment request.PutDeploymentReques
 		}
 	}
 
+	lockChecker := state.DeployLockChecker{EventManager: c.EventManager, Log: c.Log}
+	statusCode, err := lockChecker.Check(environment, cf, auth, deployment.Request.FreezeOverride, response)
+	if err != nil {
+		return I.DeployResponse{
+			StatusCode: statusCode,
+			Error:      err,
+		}
+	}
+
 	deploymentInfo := &structs.DeploymentInfo{
 		Org:          cf.Organization,
 		Space:        cf.Space,
@@ -77,6 +87,7 @@ func (c *StartController) StartDeployment(deployment request.PutDeploymentReques
 		Username:     auth.Username,
 		Password:     auth.Password,
 		Data:         deployment.Request.Data,
+		Output:       deployment.Output,
 	}
 
 	defer c.emitStartFinish(response, c.Log, cf, &auth, &environment, deployment.Request.Data, &deployResponse)
@@ -5,18 +5,20 @@ import (
 
 	I "github.com/compozed/deployadactyl/interfaces"
 	"github.com/compozed/deployadactyl/state"
+	S "github.com/compozed/deployadactyl/structs"
 )
 
 type Starter struct {
-	Courier       I.Courier
-	CFContext     I.CFContext
-	Authorization I.Authorization
-	EventManager  I.EventManager
-	Response      io.ReadWriter
-	Log           I.DeploymentLogger
-	FoundationURL string
-	AppName       string
-	Data          map[string]interface{}
+	Courier        I.Courier
+	CFContext      I.CFContext
+	Authorization  I.Authorization
+	EventManager   I.EventManager
+	Response       io.ReadWriter
+	Log            I.DeploymentLogger
+	FoundationURL  string
+	FoundationName string
+	AppName        string
+	Data           map[string]interface{}
 }
 
 func (s Starter) Verify() error {
@@ -27,6 +29,11 @@ func (s Starter) Success() error {
 	return nil
 }
 
+// Interrupt kills the Cloud Foundry commands of a phase that ran out of time.
+func (s Starter) Interrupt() {
+	state.Interrupt(s.Courier)
+}
+
 func (s Starter) Finally() error {
 	return nil
 }
@@ -53,6 +60,9 @@ func (s Starter) Initially() error {
 	s.Response.Write(output)
 	if err != nil {
 		s.Log.Errorf("could not login to %s", s.FoundationURL)
+		if timeout, ok := err.(I.TimeoutError); ok && timeout.Timeout() {
+			return err
+		}
 		return state.LoginError{s.FoundationURL, output}
 	}
 
@@ -64,20 +74,20 @@ func (s Starter) Initially() error {
 func (s Starter) Execute() error {
 
 	if s.Courier.Exists(s.AppName) != true {
-		s.Log.Errorf("failed to start app on foundation %s: application doesn't exist", s.FoundationURL)
+		s.Log.Errorf("failed to start app on foundation %s: application doesn't exist", s.foundationName())
 		return state.ExistsError{ApplicationName: s.AppName}
 	}
 
-	s.Log.Infof("%s: starting app %s", s.FoundationURL, s.AppName)
+	s.Log.Infof("%s: starting app %s", s.foundationName(), s.AppName)
 
 	output, err := s.Courier.Start(s.AppName)
 	if err != nil {
-		s.Log.Errorf("failed to start app on foundation %s: %s", s.FoundationURL, err.Error())
+		s.Log.Errorf("failed to start app on foundation %s: %s", s.foundationName(), err.Error())
 		return state.StartError{ApplicationName: s.AppName, Out: output}
 	}
 	s.Response.Write(output)
 
-	s.Log.Infof("%s: successfully started app %s", s.FoundationURL, s.AppName)
+	s.Log.Infof("%s: successfully started app %s", s.foundationName(), s.AppName)
 
 	return nil
 }
@@ -92,7 +102,7 @@ func (s Starter) Undo() error {
 		return state.ExistsError{ApplicationName: s.AppName}
 	}
 
-	s.Log.Infof("%s: stopping app %s", s.FoundationURL, s.AppName)
+	s.Log.Infof("%s: stopping app %s", s.foundationName(), s.AppName)
 
 	output, err := s.Courier.Stop(s.AppName)
 	if err != nil {
@@ -100,7 +110,11 @@ func (s Starter) Undo() error {
 	}
 	s.Response.Write(output)
 
-	s.Log.Infof("%s: successfully restopped app %s", s.FoundationURL, s.AppName)
+	s.Log.Infof("%s: successfully restopped app %s", s.foundationName(), s.AppName)
 
 	return nil
 }
+
+func (s Starter) foundationName() string {
+	return S.FoundationName(s.FoundationName, s.FoundationURL)
+}
@@ -46,6 +46,12 @@ func (a StartManager) OnStart() error {
 func (a StartManager) OnFinish(env S.Environment, response io.ReadWriter, err error) I.DeployResponse {
 	if err != nil {
 		fmt.Fprintf(response, "\nYour application was not successfully started on all foundations: %s\n\n", err.Error())
+		if _, ok := err.(bluegreen.PartialSuccessError); ok {
+			return I.DeployResponse{
+				StatusCode: http.StatusMultiStatus,
+				Error:      err,
+			}
+		}
 		if matched, _ := regexp.MatchString("login failed", err.Error()); matched {
 			return I.DeployResponse{
 				StatusCode: http.StatusBadRequest,
@@ -72,25 +78,36 @@ func (a StartManager) Create(environment S.Environment, response io.ReadWriter,
 		a.Logger.Error(err)
 		return &Starter{}, state.CourierCreationError{Err: err}
 	}
+
+	foundation := environment.GetFoundation(foundationURL)
+	courier = state.Streaming(courier, a.Logger, foundation.GetName(), a.DeployEventData.DeploymentInfo.Output)
+	info := a.DeployEventData.DeploymentInfo
+
+	username, password := info.Username, info.Password
+	if foundation.Credentials != nil && !environment.Authenticate {
+		username, password = foundation.Credentials.Username, foundation.Credentials.Password
+	}
+
 	p := &Starter{
 		Courier: courier,
 		CFContext: I.CFContext{
 			Environment:  environment.Name,
-			Organization: a.DeployEventData.DeploymentInfo.Org,
-			Space:        a.DeployEventData.DeploymentInfo.Space,
-			Application:  a.DeployEventData.DeploymentInfo.AppName,
-			SkipSSL:      a.DeployEventData.DeploymentInfo.SkipSSL,
+			Organization: foundation.GetOrg(info.Org),
+			Space:        foundation.GetSpace(info.Space),
+			Application:  info.AppName,
+			SkipSSL:      foundation.GetSkipSSL(info.SkipSSL),
 		},
 		Authorization: I.Authorization{
-			Username: a.DeployEventData.DeploymentInfo.Username,
-			Password: a.DeployEventData.DeploymentInfo.Password,
+			Username: username,
+			Password: password,
 		},
-		EventManager:  a.EventManager,
-		Response:      response,
-		Log:           a.Logger,
-		FoundationURL: foundationURL,
-		AppName:       a.DeployEventData.DeploymentInfo.AppName,
-		Data:          a.DeployEventData.DeploymentInfo.Data,
+		EventManager:   a.EventManager,
+		Response:       response,
+		Log:            a.Logger,
+		FoundationURL:  foundationURL,
+		FoundationName: foundation.GetName(),
+		AppName:        info.AppName,
+		Data:           a.DeployEventData.DeploymentInfo.Data,
 	}
 
 	return p, nil
@@ -10,6 +10,7 @@ import (
 	"github.com/compozed/deployadactyl/controller/deployer/bluegreen"
 	I "github.com/compozed/deployadactyl/interfaces"
 	"github.com/compozed/deployadactyl/request"
+	"github.com/compozed/deployadactyl/state"
 	"github.com/compozed/deployadactyl/structs"
 )
 
@@ -61,6 +62,15 @@ func (c *StopController) StopDeployment(deployment request.PutDeploymentRequest,
 		}
 	}
 
+	lockChecker := state.DeployLockChecker{EventManager: c.EventManager, Log: c.Log}
+	statusCode, err := lockChecker.Check(environment, cf, auth, deployment.Request.FreezeOverride, response)
+	if err != nil {
+		return I.DeployResponse{
+			StatusCode: statusCode,
+			Error:      err,
+		}
+	}
+
 	deploymentInfo := &structs.DeploymentInfo{
 		Org:          cf.Organization,
 		Space:        cf.Space,
@@ -73,6 +83,7 @@ func (c *StopController) StopDeployment(deployment request.PutDeploymentRequest,
 		Username:     auth.Username,
 		Password:     auth.Password,
 		Data:         deployment.Request.Data,
+		Output:       deployment.Output,
 	}
 
 	defer c.emitStopFinish(response, c.Log, cf, &auth, &environment, deployment.Request.Data, &deployResponse)
@@ -94,6 +94,40 @@ var _ = Describe("Stopmanager", func() {
 			})
 		})
 
+		Context("when the foundation has a definition", func() {
+			It("should use the foundation name, credentials and org and space mapping", func() {
+				skipSSL := true
+				env := structs.Environment{
+					Name: "myEnv",
+					FoundationDefinitions: []structs.Foundation{{
+						Name:        "east",
+						APIURL:      "https://api.east.example.com",
+						SkipSSL:     &skipSSL,
+						Credentials: &structs.FoundationCredentials{Username: "east-user", Password: "east-password"},
+						Orgs:        map[string]string{"myOrg": "eastOrg"},
+						Spaces:      map[string]string{"mySpace": "eastSpace"},
+					}},
+				}
+				*stopManager.(stop.StopManager).DeployEventData.DeploymentInfo = structs.DeploymentInfo{
+					AppName:  "myApp",
+					Org:      "myOrg",
+					Space:    "mySpace",
+					Username: "bob",
+					Password: "password",
+				}
+
+				stopper, _ := stopManager.Create(env, response, "https://api.east.example.com")
+
+				stopperData := stopper.(*stop.Stopper)
+				Expect(stopperData.FoundationName).Should(Equal("east"))
+				Expect(stopperData.CFContext.Organization).Should(Equal("eastOrg"))
+				Expect(stopperData.CFContext.Space).Should(Equal("eastSpace"))
+				Expect(stopperData.CFContext.SkipSSL).Should(BeTrue())
+				Expect(stopperData.Authorization.Username).Should(Equal("east-user"))
+				Expect(stopperData.Authorization.Password).Should(Equal("east-password"))
+			})
+		})
+
 		Context("when courier build failed", func() {
 			It("should return an error", func() {
 				creator.CourierCreatorFn = func() (interfaces.Courier, error) {
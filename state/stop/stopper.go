@@ -5,17 +5,19 @@ import (
 
 	I "github.com/compozed/deployadactyl/interfaces"
 	"github.com/compozed/deployadactyl/state"
+	S "github.com/compozed/deployadactyl/structs"
 )
 
 type Stopper struct {
-	Courier       I.Courier
-	CFContext     I.CFContext
-	Authorization I.Authorization
-	EventManager  I.EventManager
-	Response      io.ReadWriter
-	Log           I.DeploymentLogger
-	FoundationURL string
-	AppName       string
+	Courier        I.Courier
+	CFContext      I.CFContext
+	Authorization  I.Authorization
+	EventManager   I.EventManager
+	Response       io.ReadWriter
+	Log            I.DeploymentLogger
+	FoundationURL  string
+	FoundationName string
+	AppName        string
 }
 
 func (s Stopper) Verify() error {
@@ -26,6 +28,11 @@ func (s Stopper) Success() error {
 	return nil
 }
 
+// Interrupt kills the Cloud Foundry commands of a phase that ran out of time.
+func (s Stopper) Interrupt() {
+	state.Interrupt(s.Courier)
+}
+
 func (s Stopper) Finally() error {
 	return nil
 }
@@ -52,6 +59,9 @@ func (s Stopper) Initially() error {
 	s.Response.Write(output)
 	if err != nil {
 		s.Log.Errorf("could not login to %s", s.FoundationURL)
+		if timeout, ok := err.(I.TimeoutError); ok && timeout.Timeout() {
+			return err
+		}
 		return state.LoginError{s.FoundationURL, output}
 	}
 
@@ -63,20 +73,20 @@ func (s Stopper) Initially() error {
 func (s Stopper) Execute() error {
 
 	if s.Courier.Exists(s.AppName) != true {
-		s.Log.Errorf("failed to stop app on foundation %s: application doesn't exist", s.FoundationURL)
+		s.Log.Errorf("failed to stop app on foundation %s: application doesn't exist", s.foundationName())
 		return state.ExistsError{ApplicationName: s.AppName}
 	}
 
-	s.Log.Infof("%s: stopping app %s", s.FoundationURL, s.AppName)
+	s.Log.Infof("%s: stopping app %s", s.foundationName(), s.AppName)
 
 	output, err := s.Courier.Stop(s.AppName)
 	if err != nil {
-		s.Log.Errorf("failed to stop app on foundation %s: %s", s.FoundationURL, err.Error())
+		s.Log.Errorf("failed to stop app on foundation %s: %s", s.foundationName(), err.Error())
 		return state.StopError{ApplicationName: s.AppName, Out: output}
 	}
 	s.Response.Write(output)
 
-	s.Log.Infof("%s: successfully stopped app %s", s.FoundationURL, s.AppName)
+	s.Log.Infof("%s: successfully stopped app %s", s.foundationName(), s.AppName)
 
 	return nil
 }
@@ -91,7 +101,7 @@ func (s Stopper) Undo() error {
 		return nil
 	}
 
-	s.Log.Infof("%s: starting app %s", s.FoundationURL, s.AppName)
+	s.Log.Infof("%s: starting app %s", s.foundationName(), s.AppName)
 
 	output, err := s.Courier.Start(s.AppName)
 	if err != nil {
@@ -99,7 +109,11 @@ func (s Stopper) Undo() error {
 	}
 	s.Response.Write(output)
 
-	s.Log.Infof("%s: successfully restarted app %s", s.FoundationURL, s.AppName)
+	s.Log.Infof("%s: successfully restarted app %s", s.foundationName(), s.AppName)
 
 	return nil
 }
+
+func (s Stopper) foundationName() string {
+	return S.FoundationName(s.FoundationName, s.FoundationURL)
+}
@@ -0,0 +1,38 @@
+package state
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+)
+
+// Streaming returns the courier that logs the output of its commands line by line as it arrives, so
+// that the progress of a long push shows up in the logs while it runs. The lines are written to the
+// output too when it is not nil, so that a client that streams the response sees them as well.
+// Couriers that cannot stream are returned as they are.
+func Streaming(courier I.Courier, log I.DeploymentLogger, foundationName string, output io.Writer) I.Courier {
+	if streaming, ok := courier.(I.StreamingCourier); ok {
+		return streaming.Streaming(OutputLog{Log: log, FoundationName: foundationName, Output: output})
+	}
+	return courier
+}
+
+// OutputLog logs every line of Cloud Foundry output written to it, tagged with the foundation, and
+// writes it to the output when there is one.
+type OutputLog struct {
+	Log            I.DeploymentLogger
+	FoundationName string
+	Output         io.Writer
+}
+
+func (o OutputLog) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		o.Log.Infof("%s: %s", o.FoundationName, line)
+		if o.Output != nil {
+			fmt.Fprintf(o.Output, "%s: %s\n", o.FoundationName, line)
+		}
+	}
+	return len(p), nil
+}
@@ -0,0 +1,67 @@
+package state_test
+
+import (
+	"fmt"
+
+	"github.com/compozed/deployadactyl/controller/deployer/bluegreen/courier"
+	"github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/mocks"
+	. "github.com/compozed/deployadactyl/state"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/op/go-logging"
+)
+
+var _ = Describe("Streaming", func() {
+	var (
+		logBuffer *gbytes.Buffer
+		log       interfaces.DeploymentLogger
+	)
+
+	BeforeEach(func() {
+		logBuffer = gbytes.NewBuffer()
+		log = interfaces.DeploymentLogger{Log: interfaces.DefaultLogger(logBuffer, logging.DEBUG, "stream_test"), UUID: "uuid1234"}
+	})
+
+	It("logs the output of the commands of the courier tagged with the foundation", func() {
+		executor := &mocks.Executor{}
+		executor.ExecuteStreamingCall.Returns.Output = []byte("[push] Staging app...\n[push] Uploading droplet...\n")
+
+		streaming := Streaming(courier.Courier{Executor: executor}, log, "east", nil)
+		_, err := streaming.Push("appName", "/tmp/app", "hostname", 1)
+
+		Expect(err).ToNot(HaveOccurred())
+		Eventually(logBuffer).Should(gbytes.Say(`east: \[push\] Staging app\.\.\.`))
+		Eventually(logBuffer).Should(gbytes.Say(`east: \[push\] Uploading droplet\.\.\.`))
+	})
+
+	It("writes the output of the commands of the courier tagged with the foundation to the output", func() {
+		executor := &mocks.Executor{}
+		executor.ExecuteStreamingCall.Returns.Output = []byte("[push] Staging app...\n[push] Uploading droplet...\n")
+		output := gbytes.NewBuffer()
+
+		streaming := Streaming(courier.Courier{Executor: executor}, log, "east", output)
+		_, err := streaming.Push("appName", "/tmp/app", "hostname", 1)
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(output.Contents())).To(Equal("east: [push] Staging app...\neast: [push] Uploading droplet...\n"))
+	})
+
+	It("returns couriers that cannot stream as they are", func() {
+		c := &mocks.Courier{}
+
+		Expect(Streaming(c, log, "east", gbytes.NewBuffer())).To(BeIdenticalTo(c))
+	})
+
+	Describe("OutputLog", func() {
+		It("logs every line written to it", func() {
+			output := OutputLog{Log: log, FoundationName: "west"}
+
+			fmt.Fprint(output, "first\nsecond\n")
+
+			Eventually(logBuffer).Should(gbytes.Say("west: first"))
+			Eventually(logBuffer).Should(gbytes.Say("west: second"))
+		})
+	})
+})
@@ -0,0 +1,56 @@
+package structs
+
+import "time"
+
+// DefaultBakeInterval is how often applications are checked while they bake when no interval is configured.
+const DefaultBakeInterval = 30 * time.Second
+
+// Bake watches an application for a while after it replaced the existing one. The previous version
+// is kept as a stopped standby until the bake completes, and every foundation is rolled back to it
+// when the application breaches one of the thresholds on any foundation.
+type Bake struct {
+	// Duration and Interval are durations such as 10m or 30s. There is no bake without a Duration.
+	Duration string
+	Interval string
+
+	// MaxCrashes is the number of instances that may be crashed or down at once.
+	MaxCrashes int `yaml:"max_crashes"`
+
+	// MaxRestarts is the number of instances that may restart during the bake.
+	MaxRestarts int `yaml:"max_restarts"`
+
+	// HealthCheckEndpoint is checked on the route of the application. It defaults to the
+	// health check endpoint of the request.
+	HealthCheckEndpoint string `yaml:"health_check_endpoint"`
+}
+
+// GetDuration returns how long applications bake after the cutover.
+func (b Bake) GetDuration() (time.Duration, error) {
+	if b.Duration == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(b.Duration)
+}
+
+// GetInterval returns how often applications are checked while they bake.
+func (b Bake) GetInterval() (time.Duration, error) {
+	if b.Interval == "" {
+		return DefaultBakeInterval, nil
+	}
+	return time.ParseDuration(b.Interval)
+}
+
+// Enabled returns whether applications bake after the cutover.
+func (b Bake) Enabled() bool {
+	duration, err := b.GetDuration()
+	return err == nil && duration > 0
+}
+
+// AppInstance is an instance of an application as reported by cf app.
+type AppInstance struct {
+	Index int
+	State string
+
+	// Since is when the instance entered its state. It is zero when cf app did not report it.
+	Since time.Time
+}
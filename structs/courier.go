@@ -0,0 +1,18 @@
+package structs
+
+const (
+	// CLICourier runs the cf CLI for every Cloud Foundry operation. It is the default courier.
+	CLICourier = "cli"
+
+	// APICourier talks to the Cloud Controller V3 API and UAA over HTTP and needs no cf CLI.
+	APICourier = "api"
+)
+
+// IsCourier returns whether courier is the name of a courier. An empty courier selects the default.
+func IsCourier(courier string) bool {
+	switch courier {
+	case "", CLICourier, APICourier:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,20 @@
+package structs
+
+// DeployWindow is a recurring period in which an environment accepts deployments.
+//
+// Cron is a five field cron expression (minute hour day-of-month month day-of-week);
+// every minute it matches is open for deployments. Timezone is an IANA location name
+// and defaults to UTC.
+type DeployWindow struct {
+	Cron     string
+	Timezone string
+}
+
+// Freeze is a blackout period in which an environment does not accept deployments.
+//
+// Start and End are either dates (2006-01-02), which cover the whole day, or RFC 3339 timestamps.
+type Freeze struct {
+	Start  string
+	End    string
+	Reason string
+}
@@ -22,10 +22,20 @@ type DeploymentInfo struct {
 	AppPath              string
 	ContentType          string
 	Body                 io.Reader
-	EnvironmentVariables map[string]string `json:"environment_variables"`
-	HealthCheckEndpoint  string            `json:"health_check_endpoint"`
+	EnvironmentVariables map[string]string     `json:"environment_variables"`
+	HealthCheckEndpoint  string                `json:"health_check_endpoint"`
+	SmokeTests           []SmokeTest           `json:"smoke_tests"`
+	ServiceInstances     []ServiceInstance     `json:"service_instances"`
+	UserProvidedServices []UserProvidedService `json:"user_provided_services"`
 	CustomParams         map[string]interface{}
 
+	// Output receives the Cloud Foundry output of the deployment line by line as it arrives, when the
+	// client streams the response.
+	Output io.Writer `json:"-"`
+
+	// PromotedFrom is the UUID of the deployment in another environment this one was promoted from.
+	PromotedFrom string `json:"promoted_from"`
+
 	// Generic map used for users to provide their own deployment properties in JSON format.
 	Data map[string]interface{} `json:"data"`
 }
@@ -2,13 +2,74 @@ package structs
 
 // Environment is representation of a single environment configuration.
 type Environment struct {
-	Name             string
-	Domain           string
-	Foundations      []string `yaml:",flow"`
+	Name   string
+	Domain string
+
+	// Foundations is the list of foundation API URLs. When loaded from the config it is
+	// populated from FoundationDefinitions.
+	Foundations           []string     `yaml:"-"`
+	FoundationDefinitions []Foundation `yaml:"foundations"`
+
 	Authenticate     bool
 	SkipSSL          bool `yaml:"skip_ssl"`
 	Instances        uint16
 	DisableRollback  bool                   `yaml:"rollback_disabled"`
 	CustomParams     map[string]interface{} `yaml:"custom_params"`
 	AllowInvalidUser bool                   `yaml:"allow_invalid_user"`
+
+	// DeployWindows restricts requests to the given recurring windows. An environment
+	// without deploy windows is always open unless it is frozen.
+	DeployWindows []DeployWindow `yaml:"deploy_windows"`
+	Freezes       []Freeze
+
+	// Strategy selects how requests are rolled out to the foundations. See IsStrategy.
+	Strategy string
+	Canary   Canary
+	Rolling  Rolling
+
+	// PushStrategy selects how cf pushes an application to each foundation, such as blue-green,
+	// in-place or rolling. It is looked up in the push strategies registered with the creator.
+	// It cannot be combined with Strategy, which takes some of the same names for other things.
+	PushStrategy string `yaml:"cf_push_strategy"`
+
+	// Hooks are run on each foundation at defined points of a push. See Hook.
+	Hooks []Hook
+
+	// SmokeTests are run against the temporary route of the pushed application on each foundation
+	// before its routes are mapped. The smoke tests of a request replace them.
+	SmokeTests []SmokeTest `yaml:"smoke_tests"`
+
+	// UserProvidedServices are created or updated on each foundation and bound to the pushed
+	// application. A user-provided service of the same name in a request replaces one of them.
+	UserProvidedServices []UserProvidedService `yaml:"user_provided_services"`
+
+	// SuccessPolicy decides how many foundations have to succeed. See RequiredSuccesses.
+	SuccessPolicy string `yaml:"success_policy"`
+	MinSuccess    int    `yaml:"min_success"`
+
+	// KeepPrevious is the number of previous versions of an application that are kept as
+	// stopped standbys when it is pushed. They are deleted when it is zero.
+	KeepPrevious int `yaml:"keep_previous"`
+
+	// Bake watches the application on each foundation after the cutover and rolls every foundation
+	// back when it turns out to be unhealthy.
+	Bake Bake
+
+	// Janitor deletes temporary applications that interrupted pushes left behind.
+	Janitor Janitor
+
+	// Timeouts of the phases and of the whole request. They are merged over the top-level
+	// timeouts of the config, which are the only place command timeouts can be set.
+	Timeouts Timeouts
+}
+
+// GetFoundation returns the definition of the foundation with the given API URL.
+// Foundations that were given as a bare URL only have their APIURL set.
+func (e Environment) GetFoundation(foundationURL string) Foundation {
+	for _, foundation := range e.FoundationDefinitions {
+		if foundation.APIURL == foundationURL {
+			return foundation
+		}
+	}
+	return Foundation{APIURL: foundationURL}
 }
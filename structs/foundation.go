@@ -0,0 +1,82 @@
+package structs
+
+import "github.com/cloudfoundry-incubator/candiedyaml"
+
+// Foundation is a single Cloud Foundry instance in an Environment.
+// In the config it can be given either as a bare API URL or as a mapping.
+type Foundation struct {
+	Name        string
+	APIURL      string                 `yaml:"api_url"`
+	AppsDomain  string                 `yaml:"apps_domain"`
+	SkipSSL     *bool                  `yaml:"skip_ssl"`
+	Credentials *FoundationCredentials `yaml:"credentials"`
+
+	// Orgs and Spaces map the org and space names used in a request to the
+	// names they have on this foundation.
+	Orgs   map[string]string
+	Spaces map[string]string
+}
+
+// FoundationCredentials overrides the configured Cloud Foundry user for a single foundation.
+type FoundationCredentials struct {
+	Username string
+	Password string
+}
+
+// UnmarshalYAML allows a foundation to be declared as a plain API URL string.
+func (f *Foundation) UnmarshalYAML(tag string, value interface{}) error {
+	if pointer, ok := value.(*interface{}); ok {
+		value = *pointer
+	}
+
+	if apiURL, ok := value.(string); ok {
+		*f = Foundation{APIURL: apiURL}
+		return nil
+	}
+
+	data, err := candiedyaml.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	type plainFoundation Foundation
+	return candiedyaml.Unmarshal(data, (*plainFoundation)(f))
+}
+
+// GetName returns the name of the foundation, falling back to its API URL.
+func (f Foundation) GetName() string {
+	return FoundationName(f.Name, f.APIURL)
+}
+
+// FoundationName returns the name a foundation is logged by: its name, or its API URL when it has none.
+func FoundationName(name, apiURL string) string {
+	if name != "" {
+		return name
+	}
+	return apiURL
+}
+
+// GetOrg returns the name org has on this foundation.
+func (f Foundation) GetOrg(org string) string {
+	if mapped, ok := f.Orgs[org]; ok && mapped != "" {
+		return mapped
+	}
+	return org
+}
+
+// GetSpace returns the name space has on this foundation.
+func (f Foundation) GetSpace(space string) string {
+	if mapped, ok := f.Spaces[space]; ok && mapped != "" {
+		return mapped
+	}
+	return space
+}
+
+// GetSkipSSL returns whether to skip ssl validation for this foundation, falling back to
+// the value configured for its environment.
+func (f Foundation) GetSkipSSL(environmentSkipSSL bool) bool {
+	if f.SkipSSL != nil {
+		return *f.SkipSSL
+	}
+	return environmentSkipSSL
+}
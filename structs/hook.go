@@ -0,0 +1,73 @@
+package structs
+
+import "time"
+
+const (
+	// HookBeforePush hooks run on each foundation before the application is pushed.
+	HookBeforePush = "before_push"
+
+	// HookBeforeCutover hooks run on each foundation after the new application is pushed and
+	// its routes are mapped, before the routes are moved over from the existing application.
+	HookBeforeCutover = "before_cutover"
+
+	// HookAfterSuccess hooks run on each foundation once the new application has replaced the existing one.
+	HookAfterSuccess = "after_success"
+)
+
+// DefaultHookTimeout is how long an HTTP or local command hook may run when it has no timeout.
+const DefaultHookTimeout = 5 * time.Minute
+
+// Hook is something run at a defined point of a push. It is exactly one of a Cloud Foundry task,
+// an HTTP call or a local command.
+type Hook struct {
+	Name string
+
+	// When is one of HookBeforePush, HookBeforeCutover or HookAfterSuccess.
+	When string
+
+	// Task is run with cf run-task against the pushed application. Tasks cannot run before the push.
+	Task string
+
+	// URL is called with a POST of the HookContext as JSON. Any status other than 2xx fails the hook.
+	URL string
+
+	// Command is run locally with the HookContext as JSON on its standard input.
+	Command []string
+
+	// Timeout is a duration such as 90s for URL and Command hooks. Tasks are bounded by the
+	// run-task command timeout instead.
+	Timeout string
+}
+
+// IsHookPoint returns whether when is one of the points of a push hooks can run at.
+func IsHookPoint(when string) bool {
+	switch when {
+	case HookBeforePush, HookBeforeCutover, HookAfterSuccess:
+		return true
+	}
+	return false
+}
+
+// GetTimeout returns how long an HTTP or local command hook may run.
+func (h Hook) GetTimeout() (time.Duration, error) {
+	if h.Timeout == "" {
+		return DefaultHookTimeout, nil
+	}
+	return time.ParseDuration(h.Timeout)
+}
+
+// HookContext describes the push a hook runs for. Application is the application the hook is run
+// against: the temporary application before the cutover and AppName after it.
+type HookContext struct {
+	Hook          string `json:"hook"`
+	When          string `json:"when"`
+	Environment   string `json:"environment"`
+	Foundation    string `json:"foundation"`
+	FoundationURL string `json:"foundation_url"`
+	Org           string `json:"org"`
+	Space         string `json:"space"`
+	AppName       string `json:"app_name"`
+	Application   string `json:"application"`
+	UUID          string `json:"uuid"`
+	ArtifactURL   string `json:"artifact_url"`
+}
@@ -0,0 +1,48 @@
+package structs
+
+import "time"
+
+// DefaultJanitorMinimumAge is how old a temporary application has to be before the janitor deletes it
+// when no minimum age is configured.
+const DefaultJanitorMinimumAge = time.Hour
+
+// Janitor deletes the temporary applications and routes that interrupted pushes left behind in the
+// spaces of an environment.
+type Janitor struct {
+	// Interval is how often the spaces are swept, such as 6h. They are only swept on request without it.
+	Interval string
+
+	// MinimumAge keeps temporary applications that were created recently, such as 2h. Temporary
+	// applications of running deployments are never deleted.
+	MinimumAge string `yaml:"minimum_age"`
+
+	Spaces []JanitorSpace
+}
+
+// JanitorSpace is a space swept by the janitor. The org and space are mapped to their names on each
+// foundation like the ones of a request.
+type JanitorSpace struct {
+	Org   string
+	Space string
+}
+
+// GetInterval returns how often the spaces are swept, or zero when they are only swept on request.
+func (j Janitor) GetInterval() (time.Duration, error) {
+	if j.Interval == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(j.Interval)
+}
+
+// GetMinimumAge returns how old a temporary application has to be before it is deleted.
+func (j Janitor) GetMinimumAge() (time.Duration, error) {
+	if j.MinimumAge == "" {
+		return DefaultJanitorMinimumAge, nil
+	}
+	return time.ParseDuration(j.MinimumAge)
+}
+
+// Enabled returns whether the janitor has spaces to sweep.
+func (j Janitor) Enabled() bool {
+	return len(j.Spaces) > 0
+}
@@ -8,6 +8,7 @@ import (
 type PushEventData struct {
 	AppPath         string
 	FoundationURL   string
+	FoundationName  string
 	TempAppWithUUID string
 
 	DeploymentInfo *DeploymentInfo
@@ -0,0 +1,134 @@
+package structs
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// DefaultServiceTimeout is how long the provisioning of a service instance is waited on when it has no timeout.
+const DefaultServiceTimeout = 10 * time.Minute
+
+// DefaultServicePollInterval is how often the status of a service instance is checked while it is provisioned.
+const DefaultServicePollInterval = 5 * time.Second
+
+// ServiceInstance is a service instance an application needs. It is created with cf create-service
+// when it does not exist in the space and bound to the newly pushed application.
+type ServiceInstance struct {
+	Name    string `json:"name"`
+	Service string `json:"service"`
+	Plan    string `json:"plan"`
+
+	// Params are the arbitrary parameters passed to the service broker as JSON when the instance is created.
+	Params map[string]interface{} `json:"params"`
+
+	// Timeout is a duration such as 15m the provisioning of the instance is waited on.
+	Timeout string `json:"timeout"`
+}
+
+// GetTimeout returns how long the provisioning of the service instance is waited on.
+func (s ServiceInstance) GetTimeout() (time.Duration, error) {
+	if s.Timeout == "" {
+		return DefaultServiceTimeout, nil
+	}
+	return time.ParseDuration(s.Timeout)
+}
+
+// GetParams returns the parameters of the service instance as JSON, or an empty string when it has none.
+func (s ServiceInstance) GetParams() (string, error) {
+	if len(s.Params) == 0 {
+		return "", nil
+	}
+
+	params, err := json.Marshal(jsonValue(s.Params))
+	if err != nil {
+		return "", err
+	}
+	return string(params), nil
+}
+
+// Check returns why the service instance is invalid, or an empty string when it is valid.
+func (s ServiceInstance) Check() string {
+	switch {
+	case s.Name == "":
+		return "missing name"
+	case s.Service == "":
+		return "missing service"
+	case s.Plan == "":
+		return "missing plan"
+	}
+
+	if timeout, err := s.GetTimeout(); err != nil || timeout <= 0 {
+		return fmt.Sprintf("invalid timeout %s", s.Timeout)
+	}
+
+	if _, err := s.GetParams(); err != nil {
+		return fmt.Sprintf("invalid params: %s", err)
+	}
+
+	return ""
+}
+
+// jsonValue turns the maps YAML decodes into, which have interface{} keys, into maps JSON can encode.
+func jsonValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[fmt.Sprint(key)] = jsonValue(item)
+		}
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[key] = jsonValue(item)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, item := range v {
+			s[i] = jsonValue(item)
+		}
+		return s
+	}
+	return value
+}
+
+// UserProvidedService is a user-provided service instance an application needs. It is created with
+// cf cups when it does not exist in the space, updated with cf uups when it does, and bound to the
+// newly pushed application.
+//
+// Strings in the credentials can reference secrets as ((name)). They are resolved from the secret
+// store before the push, so that the secrets never appear in the request.
+type UserProvidedService struct {
+	Name            string                 `json:"name"`
+	Credentials     map[string]interface{} `json:"credentials"`
+	SyslogDrainURL  string                 `json:"syslog_drain_url" yaml:"syslog_drain_url"`
+	RouteServiceURL string                 `json:"route_service_url" yaml:"route_service_url"`
+}
+
+// GetCredentials returns the credentials of the service as JSON, or an empty string when it has none.
+func (s UserProvidedService) GetCredentials() (string, error) {
+	if len(s.Credentials) == 0 {
+		return "", nil
+	}
+
+	credentials, err := json.Marshal(jsonValue(s.Credentials))
+	if err != nil {
+		return "", err
+	}
+	return string(credentials), nil
+}
+
+// Check returns why the user-provided service is invalid, or an empty string when it is valid.
+func (s UserProvidedService) Check() string {
+	if s.Name == "" {
+		return "missing name"
+	}
+
+	if _, err := s.GetCredentials(); err != nil {
+		return fmt.Sprintf("invalid credentials: %s", err)
+	}
+
+	return ""
+}
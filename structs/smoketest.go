@@ -0,0 +1,80 @@
+package structs
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// SmokeTest is an HTTP request sent to the temporary route of a newly pushed application before
+// its routes are mapped. The push fails when the response does not have one of ExpectedStatus or
+// fails one of its Assertions.
+type SmokeTest struct {
+	Name string `json:"name"`
+
+	// Method defaults to GET.
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers"`
+	Body    string            `json:"body"`
+
+	// ExpectedStatus defaults to 200.
+	ExpectedStatus []int `yaml:"expected_status" json:"expected_status"`
+
+	Assertions []SmokeTestAssertion `json:"assertions"`
+}
+
+// SmokeTestAssertion checks the body of the response to a SmokeTest.
+//
+// Without a JSONPath, Matches is a regular expression the whole body has to match. With a
+// JSONPath such as $.status or $.items[0].id, the body is parsed as JSON and the value at the
+// path has to exist, equal Equals and match Matches when they are set.
+type SmokeTestAssertion struct {
+	JSONPath string      `yaml:"json_path" json:"json_path"`
+	Equals   interface{} `json:"equals"`
+	Matches  string      `json:"matches"`
+}
+
+// GetMethod returns the HTTP method of the smoke test.
+func (t SmokeTest) GetMethod() string {
+	if t.Method == "" {
+		return http.MethodGet
+	}
+	return strings.ToUpper(t.Method)
+}
+
+// GetExpectedStatus returns the status codes the response may have.
+func (t SmokeTest) GetExpectedStatus() []int {
+	if len(t.ExpectedStatus) == 0 {
+		return []int{http.StatusOK}
+	}
+	return t.ExpectedStatus
+}
+
+// Check returns why the smoke test is invalid, or an empty string when it is valid.
+func (t SmokeTest) Check() string {
+	if t.Name == "" {
+		return "missing name"
+	}
+
+	for _, status := range t.ExpectedStatus {
+		if status < 100 || status > 599 {
+			return fmt.Sprintf("invalid expected status %d", status)
+		}
+	}
+
+	for i, assertion := range t.Assertions {
+		if assertion.JSONPath == "" && assertion.Matches == "" {
+			return fmt.Sprintf("assertion %d needs a json_path or matches", i)
+		}
+		if assertion.JSONPath == "" && assertion.Equals != nil {
+			return fmt.Sprintf("assertion %d needs a json_path for equals", i)
+		}
+		if _, err := regexp.Compile(assertion.Matches); err != nil {
+			return fmt.Sprintf("assertion %d has an invalid regular expression: %s", i, err)
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,16 @@
+package structs
+
+import "fmt"
+
+// StandbySuffix is appended to the name of an application to name the previous version
+// that is kept as a stopped standby.
+const StandbySuffix = "-previous"
+
+// StandbyName returns the name of a standby of an application. Generation 1 is the most
+// recent previous version and is named <app>-previous; older ones are <app>-previous-2 and so on.
+func StandbyName(appName string, generation int) string {
+	if generation <= 1 {
+		return appName + StandbySuffix
+	}
+	return fmt.Sprintf("%s%s-%d", appName, StandbySuffix, generation)
+}
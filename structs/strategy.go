@@ -0,0 +1,97 @@
+package structs
+
+import "time"
+
+const (
+	// BlueGreenStrategy pushes to every foundation at once. It is the default strategy.
+	BlueGreenStrategy = "blue-green"
+
+	// CanaryStrategy deploys to a single canary foundation and bakes it before
+	// deploying to the rest of the foundations.
+	CanaryStrategy = "canary"
+
+	// RollingStrategy deploys the foundations in waves, one wave after the other.
+	RollingStrategy = "rolling"
+)
+
+const (
+	// RollbackAll undoes every foundation deployed so far when a wave fails. Foundations are
+	// only finished once every wave succeeded. It is the default rollback policy.
+	RollbackAll = "all"
+
+	// RollbackFailedWave only undoes the wave that failed. Every wave is finished as soon as it
+	// succeeds, so earlier waves keep the new version.
+	RollbackFailedWave = "failed-wave"
+)
+
+// DefaultCanaryHealthCheckInterval is how often a canary is health checked while it bakes.
+const DefaultCanaryHealthCheckInterval = 30 * time.Second
+
+// IsStrategy returns whether strategy is the name of a deployment strategy.
+// An empty strategy selects the default.
+func IsStrategy(strategy string) bool {
+	switch strategy {
+	case "", BlueGreenStrategy, CanaryStrategy, RollingStrategy:
+		return true
+	}
+	return false
+}
+
+// Canary configures the canary strategy.
+type Canary struct {
+	// Foundation is the name or API URL of the canary foundation. It defaults to
+	// the first foundation of the environment.
+	Foundation string
+
+	// BakeTime and HealthCheckInterval are durations such as 5m or 30s.
+	BakeTime            string `yaml:"bake_time"`
+	HealthCheckInterval string `yaml:"health_check_interval"`
+}
+
+// GetBakeTime returns how long the canary bakes before the rest of the foundations are deployed.
+func (c Canary) GetBakeTime() (time.Duration, error) {
+	if c.BakeTime == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(c.BakeTime)
+}
+
+// GetHealthCheckInterval returns how often the canary is health checked while it bakes.
+func (c Canary) GetHealthCheckInterval() (time.Duration, error) {
+	if c.HealthCheckInterval == "" {
+		return DefaultCanaryHealthCheckInterval, nil
+	}
+	return time.ParseDuration(c.HealthCheckInterval)
+}
+
+// Rolling configures the rolling strategy.
+//
+// Waves lists groups of foundation names or API URLs; foundations that are not listed are
+// deployed in a last wave. Without waves the foundations are deployed in order,
+// MaxParallelFoundations at a time.
+type Rolling struct {
+	MaxParallelFoundations int `yaml:"max_parallel_foundations"`
+	Waves                  [][]string
+
+	// Pause is a duration such as 1m to wait between waves.
+	Pause string
+
+	// Rollback is either RollbackAll or RollbackFailedWave.
+	Rollback string
+}
+
+// GetPause returns how long to wait between waves.
+func (r Rolling) GetPause() (time.Duration, error) {
+	if r.Pause == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(r.Pause)
+}
+
+// GetRollback returns the rollback policy, defaulting to RollbackAll.
+func (r Rolling) GetRollback() string {
+	if r.Rollback == "" {
+		return RollbackAll
+	}
+	return r.Rollback
+}
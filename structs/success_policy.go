@@ -0,0 +1,38 @@
+package structs
+
+const (
+	// SuccessPolicyAll requires every foundation to succeed. It is the default policy.
+	SuccessPolicyAll = "all"
+
+	// SuccessPolicyQuorum accepts a deployment when more than half of the foundations succeed.
+	SuccessPolicyQuorum = "quorum"
+
+	// SuccessPolicyMinSuccess accepts a deployment when at least MinSuccess foundations succeed.
+	SuccessPolicyMinSuccess = "min_success"
+)
+
+// IsSuccessPolicy returns whether policy is the name of a success policy.
+// An empty policy selects the default.
+func IsSuccessPolicy(policy string) bool {
+	switch policy {
+	case "", SuccessPolicyAll, SuccessPolicyQuorum, SuccessPolicyMinSuccess:
+		return true
+	}
+	return false
+}
+
+// RequiredSuccesses returns how many foundations have to succeed for a request to the
+// environment to be accepted.
+func (e Environment) RequiredSuccesses() int {
+	foundations := len(e.Foundations)
+
+	switch e.SuccessPolicy {
+	case SuccessPolicyQuorum:
+		return foundations/2 + 1
+	case SuccessPolicyMinSuccess:
+		if e.MinSuccess > 0 && e.MinSuccess < foundations {
+			return e.MinSuccess
+		}
+	}
+	return foundations
+}
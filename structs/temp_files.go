@@ -0,0 +1,81 @@
+package structs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTempFilesMaxAge is how old a temporary file has to be before it is swept when no maximum
+// age is configured. It is longer than a request is expected to run.
+const DefaultTempFilesMaxAge = 24 * time.Hour
+
+// DefaultTempFilesSweepInterval is how often temporary files are swept when no interval is configured.
+const DefaultTempFilesSweepInterval = time.Hour
+
+// TempFiles bounds the temporary artifacts and Cloud Foundry homes that requests leave on the local disk.
+type TempFiles struct {
+	// MaxAge and SweepInterval are durations such as 24h or 30m.
+	MaxAge        string `yaml:"max_age"`
+	SweepInterval string `yaml:"sweep_interval"`
+
+	// MinFreeSpace is the free disk space, such as 2G or 512M, below which new deployments are rejected.
+	// Deployments are not rejected without it.
+	MinFreeSpace string `yaml:"min_free_space"`
+}
+
+// GetMaxAge returns how old a temporary file has to be before it is swept.
+func (t TempFiles) GetMaxAge() (time.Duration, error) {
+	if t.MaxAge == "" {
+		return DefaultTempFilesMaxAge, nil
+	}
+	return time.ParseDuration(t.MaxAge)
+}
+
+// GetSweepInterval returns how often temporary files are swept.
+func (t TempFiles) GetSweepInterval() (time.Duration, error) {
+	if t.SweepInterval == "" {
+		return DefaultTempFilesSweepInterval, nil
+	}
+	return time.ParseDuration(t.SweepInterval)
+}
+
+// GetMinFreeSpace returns the free disk space in bytes below which new deployments are rejected,
+// or zero when they are not.
+func (t TempFiles) GetMinFreeSpace() (uint64, error) {
+	if t.MinFreeSpace == "" {
+		return 0, nil
+	}
+	return ParseSize(t.MinFreeSpace)
+}
+
+var sizeUnits = []struct {
+	suffix string
+	bytes  uint64
+}{
+	{"TB", 1 << 40}, {"T", 1 << 40},
+	{"GB", 1 << 30}, {"G", 1 << 30},
+	{"MB", 1 << 20}, {"M", 1 << 20},
+	{"KB", 1 << 10}, {"K", 1 << 10},
+	{"B", 1},
+}
+
+// ParseSize parses a size such as 2G, 512MB or 100K into bytes.
+func ParseSize(size string) (uint64, error) {
+	value := strings.ToUpper(strings.TrimSpace(size))
+
+	for _, unit := range sizeUnits {
+		if !strings.HasSuffix(value, unit.suffix) {
+			continue
+		}
+
+		number, err := strconv.ParseUint(strings.TrimSpace(strings.TrimSuffix(value, unit.suffix)), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid size %s", size)
+		}
+		return number * unit.bytes, nil
+	}
+
+	return 0, fmt.Errorf("size %s has no unit", size)
+}
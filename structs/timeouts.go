@@ -0,0 +1,95 @@
+package structs
+
+import "time"
+
+// DefaultTimeoutKey sets the timeout of every command or phase that has none of its own.
+const DefaultTimeoutKey = "default"
+
+// DefaultCommandTimeout is how long a Cloud Foundry command may run when no command timeout is configured.
+const DefaultCommandTimeout = 30 * time.Minute
+
+// Phases are the action phases that can be given a timeout.
+var Phases = []string{"initially", "verify", "execute", "post_execute", "success", "undo", "health_check"}
+
+// Timeouts bounds how long Cloud Foundry commands, action phases and whole requests may run.
+//
+// Commands and Phases map a cf subcommand such as push or map-route, or a phase such as
+// execute, to a duration such as 90s or 15m. The DefaultTimeoutKey entry applies to the ones
+// that are not listed. A duration of zero never times out.
+type Timeouts struct {
+	Commands map[string]string
+	Phases   map[string]string
+	Request  string
+}
+
+// GetCommand returns how long the cf subcommand may run before it is killed.
+func (t Timeouts) GetCommand(subcommand string) (time.Duration, error) {
+	if _, ok := t.Commands[subcommand]; !ok {
+		if _, ok := t.Commands[DefaultTimeoutKey]; !ok {
+			return DefaultCommandTimeout, nil
+		}
+	}
+	return lookupTimeout(t.Commands, subcommand)
+}
+
+// GetPhase returns how long the phase of an action may run against a single foundation.
+func (t Timeouts) GetPhase(phase string) (time.Duration, error) {
+	return lookupTimeout(t.Phases, phase)
+}
+
+// GetRequest returns how long all of the phases of a request may run together.
+func (t Timeouts) GetRequest() (time.Duration, error) {
+	if t.Request == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(t.Request)
+}
+
+// Merge returns the timeouts with the phases and request timeout of override taking precedence.
+func (t Timeouts) Merge(override Timeouts) Timeouts {
+	merged := Timeouts{
+		Commands: t.Commands,
+		Phases:   t.Phases,
+		Request:  t.Request,
+	}
+
+	if len(override.Phases) != 0 {
+		merged.Phases = map[string]string{}
+	}
+	for phase, timeout := range t.Phases {
+		merged.Phases[phase] = timeout
+	}
+	for phase, timeout := range override.Phases {
+		merged.Phases[phase] = timeout
+	}
+
+	if override.Request != "" {
+		merged.Request = override.Request
+	}
+
+	return merged
+}
+
+func lookupTimeout(timeouts map[string]string, key string) (time.Duration, error) {
+	timeout, ok := timeouts[key]
+	if !ok {
+		timeout = timeouts[DefaultTimeoutKey]
+	}
+	if timeout == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(timeout)
+}
+
+// IsPhase returns whether phase is one of Phases or the DefaultTimeoutKey.
+func IsPhase(phase string) bool {
+	if phase == DefaultTimeoutKey {
+		return true
+	}
+	for _, p := range Phases {
+		if p == phase {
+			return true
+		}
+	}
+	return false
+}
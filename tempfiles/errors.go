@@ -0,0 +1,19 @@
+package tempfiles
+
+import "fmt"
+
+type InsufficientDiskSpaceError struct {
+	Dir      string
+	Free     uint64
+	Required uint64
+}
+
+func (e InsufficientDiskSpaceError) Error() string {
+	return fmt.Sprintf("insufficient disk space in %s: %dM free, %dM required", e.Dir, e.Free>>20, e.Required>>20)
+}
+
+type FreeSpaceUnsupportedError struct{}
+
+func (e FreeSpaceUnsupportedError) Error() string {
+	return "free disk space cannot be read on this platform"
+}
@@ -0,0 +1,9 @@
+//go:build windows
+// +build windows
+
+package tempfiles
+
+// FreeSpace is not supported on windows, so deployments are never rejected for lack of disk space.
+func FreeSpace(dir string) (uint64, error) {
+	return 0, FreeSpaceUnsupportedError{}
+}
@@ -0,0 +1,140 @@
+// Package tempfiles sweeps the temporary files that requests leave on the local disk and rejects new
+// deployments when the disk is running out of space.
+package tempfiles
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+	S "github.com/compozed/deployadactyl/structs"
+	"github.com/spf13/afero"
+)
+
+// Prefixes are the prefixes of the temporary artifacts, unarchived applications and Cloud Foundry homes
+// that requests create in the temporary directory.
+var Prefixes = []string{"deployadactyl-artifact-", "deployadactyl-unarchived-", "deployadactyl-executor-"}
+
+type SweeperConstructor func(fileSystem *afero.Afero, tempFiles S.TempFiles, log I.Logger) I.TempFileSweeper
+
+func NewSweeper(fileSystem *afero.Afero, tempFiles S.TempFiles, log I.Logger) I.TempFileSweeper {
+	maxAge, _ := tempFiles.GetMaxAge()
+	interval, _ := tempFiles.GetSweepInterval()
+
+	return &Sweeper{
+		FileSystem: fileSystem,
+		Dir:        os.TempDir(),
+		MaxAge:     maxAge,
+		Interval:   interval,
+		Log:        log,
+	}
+}
+
+// Sweeper removes the temporary files and directories of requests that are older than MaxAge. They are
+// normally removed when a request finishes, but are left behind when the server dies during a request.
+type Sweeper struct {
+	FileSystem *afero.Afero
+	Dir        string
+	MaxAge     time.Duration
+	Interval   time.Duration
+	Log        I.Logger
+}
+
+// Start sweeps the files that were left behind before the server started, and then sweeps every interval.
+func (s *Sweeper) Start() {
+	s.Sweep()
+
+	if s.Interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(s.Interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			s.Sweep()
+		}
+	}()
+}
+
+// Sweep removes the temporary files that were last modified more than MaxAge ago. Files that cannot be
+// removed are logged and left for the next sweep.
+func (s *Sweeper) Sweep() []string {
+	entries, err := s.FileSystem.ReadDir(s.Dir)
+	if err != nil {
+		s.Log.Errorf("cannot sweep temporary files in %s: %s", s.Dir, err)
+		return nil
+	}
+
+	removed := []string{}
+	for _, entry := range entries {
+		if !hasPrefix(entry.Name()) || time.Since(entry.ModTime()) < s.MaxAge {
+			continue
+		}
+
+		path := filepath.Join(s.Dir, entry.Name())
+		if err := s.FileSystem.RemoveAll(path); err != nil {
+			s.Log.Errorf("cannot remove temporary file %s: %s", path, err)
+			continue
+		}
+		removed = append(removed, path)
+	}
+
+	if len(removed) > 0 {
+		s.Log.Infof("removed %d stale temporary files from %s", len(removed), s.Dir)
+	}
+	return removed
+}
+
+func hasPrefix(name string) bool {
+	for _, prefix := range Prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+type DiskGuardConstructor func(tempFiles S.TempFiles, log I.Logger) I.DiskGuard
+
+func NewDiskGuard(tempFiles S.TempFiles, log I.Logger) I.DiskGuard {
+	minFree, _ := tempFiles.GetMinFreeSpace()
+
+	return DiskGuard{
+		Dir:       os.TempDir(),
+		MinFree:   minFree,
+		FreeSpace: FreeSpace,
+		Log:       log,
+	}
+}
+
+// DiskGuard checks that the temporary directory, where artifacts are downloaded and unarchived,
+// has at least MinFree bytes free. There is no check when MinFree is zero.
+type DiskGuard struct {
+	Dir       string
+	MinFree   uint64
+	FreeSpace func(dir string) (uint64, error)
+	Log       I.Logger
+}
+
+// Check returns an InsufficientDiskSpaceError when there is less free space than MinFree. Deployments
+// are not rejected when the free space cannot be read.
+func (g DiskGuard) Check() error {
+	if g.MinFree == 0 {
+		return nil
+	}
+
+	free, err := g.FreeSpace(g.Dir)
+	if err != nil {
+		g.Log.Errorf("cannot read free disk space of %s: %s", g.Dir, err)
+		return nil
+	}
+
+	if free < g.MinFree {
+		return InsufficientDiskSpaceError{g.Dir, free, g.MinFree}
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+package tempfiles_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestTempfiles(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Tempfiles Suite")
+}
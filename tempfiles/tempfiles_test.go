@@ -0,0 +1,89 @@
+package tempfiles_test
+
+import (
+	"errors"
+	"time"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+	. "github.com/compozed/deployadactyl/tempfiles"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	"github.com/op/go-logging"
+	"github.com/spf13/afero"
+)
+
+var _ = Describe("Sweeper", func() {
+	var (
+		fileSystem *afero.Afero
+		sweeper    *Sweeper
+	)
+
+	create := func(name string, age time.Duration) {
+		Expect(fileSystem.MkdirAll("/tmp/"+name+"/.cf", 0755)).To(Succeed())
+		modified := time.Now().Add(-age)
+		Expect(fileSystem.Chtimes("/tmp/"+name, modified, modified)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		fileSystem = &afero.Afero{Fs: afero.NewMemMapFs()}
+		sweeper = &Sweeper{
+			FileSystem: fileSystem,
+			Dir:        "/tmp",
+			MaxAge:     time.Hour,
+			Log:        I.DefaultLogger(NewBuffer(), logging.DEBUG, "tempfiles_test"),
+		}
+	})
+
+	It("removes the temporary files of requests that are older than the maximum age", func() {
+		create("deployadactyl-artifact-123", 2*time.Hour)
+		create("deployadactyl-unarchived-456", 2*time.Hour)
+		create("deployadactyl-executor-789", 2*time.Hour)
+		create("deployadactyl-executor-new", time.Minute)
+		create("something-else", 2*time.Hour)
+
+		removed := sweeper.Sweep()
+
+		Expect(removed).To(ConsistOf("/tmp/deployadactyl-artifact-123", "/tmp/deployadactyl-unarchived-456", "/tmp/deployadactyl-executor-789"))
+		Expect(fileSystem.DirExists("/tmp/deployadactyl-executor-new")).To(BeTrue())
+		Expect(fileSystem.DirExists("/tmp/something-else")).To(BeTrue())
+		Expect(fileSystem.DirExists("/tmp/deployadactyl-artifact-123")).To(BeFalse())
+	})
+})
+
+var _ = Describe("DiskGuard", func() {
+	var guard DiskGuard
+
+	BeforeEach(func() {
+		guard = DiskGuard{
+			Dir:     "/tmp",
+			MinFree: 2 << 30,
+			Log:     I.DefaultLogger(NewBuffer(), logging.DEBUG, "tempfiles_test"),
+		}
+	})
+
+	It("returns an error when there is less free space than the minimum", func() {
+		guard.FreeSpace = func(dir string) (uint64, error) { return 1 << 30, nil }
+
+		Expect(guard.Check()).To(MatchError(InsufficientDiskSpaceError{"/tmp", 1 << 30, 2 << 30}))
+	})
+
+	It("does not return an error when there is enough free space", func() {
+		guard.FreeSpace = func(dir string) (uint64, error) { return 3 << 30, nil }
+
+		Expect(guard.Check()).To(Succeed())
+	})
+
+	It("does not return an error when the free space cannot be read", func() {
+		guard.FreeSpace = func(dir string) (uint64, error) { return 0, errors.New("not supported") }
+
+		Expect(guard.Check()).To(Succeed())
+	})
+
+	It("reads the free space of a directory", func() {
+		free, err := FreeSpace(".")
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(free).To(BeNumerically(">", 0))
+	})
+})